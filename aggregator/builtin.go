@@ -17,9 +17,11 @@ const (
 	StdDev      = functions.StdDev
 	Median      = functions.Median
 	Percentile  = functions.Percentile
+	IQR         = functions.IQR
 	WindowStart = functions.WindowStart
 	WindowEnd   = functions.WindowEnd
 	Collect     = functions.Collect
+	ArrayAgg    = functions.ArrayAgg
 	FirstValue  = functions.FirstValue
 	LastValue   = functions.LastValue
 	MergeAgg    = functions.MergeAgg
@@ -27,6 +29,33 @@ const (
 	Deduplicate = functions.Deduplicate
 	Var         = functions.Var
 	VarS        = functions.VarS
+	ValueCounts = functions.ValueCounts
+	// Approximate distinct count
+	ApproxCountDistinct = functions.ApproxCountDistinct
+	// Exact distinct count
+	CountDistinct = functions.CountDistinct
+	// String concatenation with separator
+	StringAgg = functions.StringAgg
+	// Window watermark
+	WindowWatermark = functions.WindowWatermark
+	// Window fill ratio
+	WindowFillRatio = functions.WindowFillRatio
+	// Window bounds as RFC3339 strings
+	WindowStartISO = functions.WindowStartISO
+	WindowEndISO   = functions.WindowEndISO
+	// Signal statistics
+	ConsecutiveDiffMedian = functions.ConsecutiveDiffMedian
+	TimeInState           = functions.TimeInState
+	TrimmedMean           = functions.TrimmedMean
+	TopK                  = functions.TopK
+	Histogram             = functions.Histogram
+	WeightedAvg           = functions.WeightedAvg
+	WindowDelta           = functions.WindowDelta
+	WindowRate            = functions.WindowRate
+	Rate                  = functions.Rate
+	// Extremum with its source row
+	MaxRow = functions.MaxRow
+	MinRow = functions.MinRow
 	// Analytical functions
 	Lag        = functions.Lag
 	Latest     = functions.Latest
@@ -37,14 +37,18 @@ Supported aggregation functions (re-exported from functions package):
 	// Mathematical aggregations
 	Sum, Count, Avg, Max, Min
 	StdDev, StdDevS, Var, VarS
-	Median, Percentile
+	Median, Percentile, IQR
+	ConsecutiveDiffMedian, TimeInState, TrimmedMean, TopK, Histogram, WeightedAvg
+	WindowDelta, WindowRate, Rate
 
 	// Collection aggregations
-	Collect, LastValue, MergeAgg
-	Deduplicate
+	Collect, ArrayAgg, LastValue, MergeAgg
+	Deduplicate, ValueCounts, CountDistinct, ApproxCountDistinct, StringAgg
+	MaxRow, MinRow
 
 	// Window aggregations
-	WindowStart, WindowEnd
+	WindowStart, WindowEnd, WindowWatermark, WindowFillRatio,
+	WindowStartISO, WindowEndISO
 
 	// Analytical functions
 	Lag, Latest, ChangedCol, HadChanged
@@ -2,9 +2,11 @@ package aggregator
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/utils/cast"
@@ -31,6 +33,71 @@ type Aggregator interface {
 	RegisterExpression(field, expression string, fields []string, evaluator func(data any) (any, error))
 }
 
+// Output columns injected by EnableQualityCounts.
+const (
+	InputCountField    = "__input_count"
+	FilteredCountField = "__filtered_count"
+)
+
+// NonFinitePolicy selects how numeric aggregates treat NaN and ±Inf inputs
+// (e.g. produced by a division by zero or log(0) upstream).
+type NonFinitePolicy string
+
+const (
+	// NonFinitePassThrough feeds NaN/Inf to the aggregate unchanged (default),
+	// which usually turns the whole result into NaN/Inf.
+	NonFinitePassThrough NonFinitePolicy = ""
+	// NonFiniteSkip ignores the value, as if the field were missing.
+	NonFiniteSkip NonFinitePolicy = "skip"
+	// NonFiniteError ignores the value and makes Add return an error naming
+	// the aggregate; the row's other aggregates are still updated.
+	NonFiniteError NonFinitePolicy = "error"
+	// NonFiniteNullify makes the aggregate's result NULL for that group until
+	// the next Reset.
+	NonFiniteNullify NonFinitePolicy = "nullify"
+)
+
+// AggregateBufferPolicy selects what buffering aggregates (median, percentile,
+// collect, ...) do beyond their per-group buffer limit (re-exports
+// functions.AggregateBufferPolicy).
+type AggregateBufferPolicy = functions.AggregateBufferPolicy
+
+const (
+	// AggregateBufferDrop ignores values beyond the limit (default).
+	AggregateBufferDrop = functions.AggregateBufferDrop
+	// AggregateBufferApproximate keeps a uniform random sample of the values.
+	AggregateBufferApproximate = functions.AggregateBufferApproximate
+)
+
+// BufferDropCounter is implemented by aggregators that report how many values
+// buffering aggregates did not keep because of the buffer limit (see
+// GroupAggregator.SetAggregateBufferLimit).
+type BufferDropCounter interface {
+	BufferDropped() int64
+}
+
+// RejectCounter is implemented by aggregators that count rows rejected before
+// aggregation (e.g. by WHERE) for data-quality reporting. ts is the row's
+// window timestamp (event time or arrival time) and attributes the row to the
+// window containing it; a zero ts counts towards the group's next result.
+type RejectCounter interface {
+	AddRejected(data any, ts time.Time) error
+}
+
+// TimedAdder is implemented by aggregators that accept each row's timestamp
+// (window event time or arrival time). Callers fall back to Add otherwise.
+type TimedAdder interface {
+	AddAt(data any, ts time.Time) error
+}
+
+// addRowAt adds a row to agg with its timestamp when agg is a TimedAdder.
+func addRowAt(agg Aggregator, data any, ts time.Time) error {
+	if timed, ok := agg.(TimedAdder); ok {
+		return timed.AddAt(data, ts)
+	}
+	return agg.Add(data)
+}
+
 // AggregationField defines configuration for a single aggregation field
 type AggregationField struct {
 	InputField    string        // Input field name (e.g., "temperature")
@@ -48,6 +115,24 @@ type GroupAggregator struct {
 	context           map[string]any
 	// Expression evaluators
 	expressions map[string]*ExpressionEvaluator
+	// Data-quality counters per group key (see EnableQualityCounts).
+	// accepted is also kept when minGroupCount > 0.
+	qualityCounts bool
+	accepted      map[string]int64
+	rejected      map[string][]time.Time // timestamps of rejected rows per group key
+	// keyedEmission: each result covers only its own groups (see SetKeyedEmission)
+	keyedEmission bool
+	// Groups with fewer added rows are left out of GetResults (see SetMinGroupCount)
+	minGroupCount int64
+	// NaN/Inf handling for numeric aggregates (see SetNonFinitePolicy);
+	// nullified holds, per group key, the aggregates forced to NULL.
+	nonFinitePolicy NonFinitePolicy
+	nullified       map[string]map[string]bool
+	// NULL group-key handling (see SetGroupNullPolicy)
+	nullKeys nullKeying
+	// Per-group value cap of buffering aggregates (see SetAggregateBufferLimit)
+	bufferLimit  int
+	bufferPolicy AggregateBufferPolicy
 }
 
 // ExpressionEvaluator wraps expression evaluation functionality
@@ -116,10 +201,13 @@ func (ga *GroupAggregator) isNumericAggregator(aggType AggregateType) bool {
 			// Check if it's a numeric aggregation function
 			switch string(aggType) {
 			case functions.SumStr, functions.AvgStr, functions.MinStr, functions.MaxStr, functions.CountStr,
-				functions.StdDevStr, functions.MedianStr, functions.PercentileStr,
-				functions.VarStr, functions.VarSStr, functions.StdDevSStr:
+				functions.StdDevStr, functions.MedianStr, functions.PercentileStr, functions.IQRStr,
+				functions.VarStr, functions.VarSStr, functions.StdDevSStr, functions.ConsecutiveDiffMedianStr,
+				functions.TrimmedMeanStr, functions.TopKStr, functions.HistogramStr, functions.WeightedAvgStr, functions.WindowDeltaStr, functions.WindowRateStr, functions.RateStr,
+				functions.MaxRowStr, functions.MinRowStr:
 				return true
-			case functions.CollectStr, functions.MergeAggStr, functions.DeduplicateStr, functions.LastValueStr:
+			case functions.CollectStr, functions.ArrayAggStr, functions.MergeAggStr, functions.DeduplicateStr, functions.FirstValueStr, functions.LastValueStr,
+				functions.CountDistinctStr, functions.ApproxCountDistinctStr, functions.StringAggStr:
 				// These functions can handle any type
 				return false
 			default:
@@ -154,96 +242,61 @@ func (ga *GroupAggregator) isNumericAggregator(aggType AggregateType) bool {
 
 // shouldAllowNullValues 判断聚合函数是否应该允许NULL值
 func (ga *GroupAggregator) shouldAllowNullValues(aggType AggregateType) bool {
-	// FIRST_VALUE和LAST_VALUE函数应该允许NULL值，因为它们需要记录第一个/最后一个值，即使是NULL
-	return aggType == FirstValue || aggType == LastValue
+	// FIRST_VALUE和LAST_VALUE函数应该允许NULL值，因为它们需要记录第一个/最后一个值，即使是NULL；
+	// COLLECT/ARRAY_AGG 收集 NULL 以保持数组下标与行序一一对应
+	return aggType == FirstValue || aggType == LastValue || aggType == Collect || aggType == ArrayAgg
 }
 
 func (ga *GroupAggregator) Add(data any) error {
+	return ga.AddAt(data, time.Time{})
+}
+
+// AddAt adds a row observed at ts. Aggregates implementing
+// functions.TimestampedAggregator (e.g. time_in_state) receive ts with each
+// value, and functions.RowAggregator (e.g. max_row) also the row itself; the
+// rest ignore them. A zero ts means the row carries no timestamp.
+func (ga *GroupAggregator) AddAt(data any, ts time.Time) error {
 	ga.mu.Lock()
 	defer ga.mu.Unlock()
 
-	// 检查数据是否为nil
-	if data == nil {
-		return fmt.Errorf("data cannot be nil")
+	v, err := rowValue(data)
+	if err != nil {
+		return err
 	}
 
-	var v reflect.Value
-
-	switch data.(type) {
-	case map[string]any:
-		dataMap := data.(map[string]any)
-		v = reflect.ValueOf(dataMap)
-	default:
-		v = reflect.ValueOf(data)
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
-		}
-		// 检查是否为支持的数据类型
-		if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
-			return fmt.Errorf("unsupported data type: %T, expected struct or map", data)
-		}
-	}
-
-	key := ""
-	keyVals := make([]any, 0, len(ga.groupFields))
-	for _, field := range ga.groupFields {
-		var fieldVal any
-		var found bool
-
-		// Check if it's a nested field
-		if fieldpath.IsNestedField(field) {
-			fieldVal, found = fieldpath.GetNestedField(data, field)
-		} else {
-			// Original field access logic
-			var f reflect.Value
-			if v.Kind() == reflect.Map {
-				keyVal := reflect.ValueOf(field)
-				f = v.MapIndex(keyVal)
-			} else {
-				f = v.FieldByName(field)
-			}
-
-			if f.IsValid() {
-				fieldVal = f.Interface()
-				found = true
-			}
-		}
-
-		// Missing or nil group field (e.g. a LEFT JOIN row with no match)
-		// collapses into a single NULL group keyed by the sentinel; GetResults
-		// maps it back to nil. Avoids dropping the whole row on a nullable key.
-		if !found || fieldVal == nil {
-			key += nullGroupKeyMarker + groupKeySep
-			keyVals = append(keyVals, nil)
-			continue
-		}
-
-		if str, ok := fieldVal.(string); ok {
-			key += str + groupKeySep
-		} else {
-			key += fmt.Sprintf("%v", fieldVal) + groupKeySep
-		}
-		keyVals = append(keyVals, fieldVal)
+	key, keyVals, ok := groupKeyOf(data, v, ga.groupFields, ga.nullKeys)
+	if !ok {
+		return nil
 	}
 
 	if _, exists := ga.groups[key]; !exists {
 		ga.groups[key] = make(map[string]AggregatorFunction)
 		ga.groupKeyVals[key] = keyVals
 	}
+	if ga.accepted != nil {
+		ga.accepted[key]++
+	}
 
 	// Create aggregator instances for each field
 	for outputAlias, agg := range ga.aggregators {
 		if _, exists := ga.groups[key][outputAlias]; !exists {
-			ga.groups[key][outputAlias] = agg.New()
+			groupAgg := agg.New()
+			if ga.bufferLimit > 0 {
+				functions.SetBufferLimit(groupAgg, ga.bufferLimit, ga.bufferPolicy)
+			}
+			ga.groups[key][outputAlias] = groupAgg
 		}
 	}
 
 	// Process each aggregation field
+	var nonFiniteErr error
 	for _, aggField := range ga.aggregationFields {
 		outputAlias := aggField.OutputAlias
 		if outputAlias == "" {
 			outputAlias = aggField.InputField
 		}
+		// SQL may spell the function in any case (AVG, count, ...)
+		aggType := AggregateType(strings.ToLower(string(aggField.AggregateType)))
 
 		// Check if there's an expression evaluator
 		if expr, hasExpr := ga.expressions[outputAlias]; hasExpr {
@@ -251,9 +304,17 @@ func (ga *GroupAggregator) Add(data any) error {
 			if err != nil {
 				continue
 			}
+			if aggType != Count && ga.isNumericAggregator(aggType) {
+				if ok, err := ga.admitNonFinite(key, outputAlias, result); !ok {
+					if nonFiniteErr == nil {
+						nonFiniteErr = err
+					}
+					continue
+				}
+			}
 
 			if groupAgg, exists := ga.groups[key][outputAlias]; exists {
-				groupAgg.Add(result)
+				functions.AddRow(groupAgg, result, ts, data)
 			}
 			continue
 		}
@@ -264,7 +325,7 @@ func (ga *GroupAggregator) Add(data any) error {
 		if inputField == "*" {
 			// For count(*), directly add 1 without getting specific field value
 			if groupAgg, exists := ga.groups[key][outputAlias]; exists {
-				groupAgg.Add(1)
+				functions.AddRow(groupAgg, 1, ts, data)
 			}
 			continue
 		}
@@ -292,13 +353,20 @@ func (ga *GroupAggregator) Add(data any) error {
 		}
 
 		if !found {
+			// collect/array_agg keep one element per row: a missing field is collected as nil
+			if aggType == Collect || aggType == ArrayAgg {
+				if groupAgg, exists := ga.groups[key][outputAlias]; exists {
+					functions.AddRow(groupAgg, nil, ts, data)
+				}
+				continue
+			}
 			// Try to get from context
 			if ga.context != nil {
 				if groupAgg, exists := ga.groups[key][outputAlias]; exists {
 					if contextAgg, ok := groupAgg.(ContextAggregator); ok {
 						contextKey := contextAgg.GetContextKey()
 						if val, exists := ga.context[contextKey]; exists {
-							groupAgg.Add(val)
+							functions.AddRow(groupAgg, val, ts, data)
 						}
 					}
 				}
@@ -306,8 +374,6 @@ func (ga *GroupAggregator) Add(data any) error {
 			continue
 		}
 
-		aggType := aggField.AggregateType
-
 		// Skip nil values for most aggregation functions, but allow FIRST_VALUE and LAST_VALUE to handle them
 		if fieldVal == nil && !ga.shouldAllowNullValues(aggType) {
 			continue
@@ -317,14 +383,20 @@ func (ga *GroupAggregator) Add(data any) error {
 		if aggType == Count {
 			// Count can handle any non-null value
 			if groupAgg, exists := ga.groups[key][outputAlias]; exists {
-				groupAgg.Add(fieldVal)
+				functions.AddRow(groupAgg, fieldVal, ts, data)
 			}
 		} else if ga.isNumericAggregator(aggType) {
 			// For numeric aggregation functions, try to convert to numeric type
 			if numVal, err := cast.ToFloat64E(fieldVal); err == nil {
+				if ok, err := ga.admitNonFinite(key, outputAlias, numVal); !ok {
+					if nonFiniteErr == nil {
+						nonFiniteErr = err
+					}
+					continue
+				}
 				if groupAgg, exists := ga.groups[key][outputAlias]; exists {
 
-					groupAgg.Add(numVal)
+					functions.AddRow(groupAgg, numVal, ts, data)
 				}
 			} else {
 				// 非数值跳过该字段，不中断整行 Add。
@@ -334,17 +406,279 @@ func (ga *GroupAggregator) Add(data any) error {
 			// For non-numeric aggregation functions, pass original value directly
 			if groupAgg, exists := ga.groups[key][outputAlias]; exists {
 
-				groupAgg.Add(fieldVal)
+				functions.AddRow(groupAgg, fieldVal, ts, data)
 			}
 		}
 	}
 
-	return nil
+	return nonFiniteErr
 }
 
-func (ga *GroupAggregator) GetResults() ([]map[string]any, error) {
+// rowValue validates an aggregation input row and returns its reflect value
+// (struct pointers dereferenced).
+func rowValue(data any) (reflect.Value, error) {
+	// 检查数据是否为nil
+	if data == nil {
+		return reflect.Value{}, fmt.Errorf("data cannot be nil")
+	}
+
+	var v reflect.Value
+
+	switch data.(type) {
+	case map[string]any:
+		dataMap := data.(map[string]any)
+		v = reflect.ValueOf(dataMap)
+	default:
+		v = reflect.ValueOf(data)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		// 检查是否为支持的数据类型
+		if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+			return reflect.Value{}, fmt.Errorf("unsupported data type: %T, expected struct or map", data)
+		}
+	}
+	return v, nil
+}
+
+// groupKeyOf builds the group key (and the raw group-field values) of a row.
+// v is reflect.ValueOf(data), dereferenced for struct pointers. It reports
+// false when nulls drops the row for a NULL group field.
+func groupKeyOf(data any, v reflect.Value, groupFields []string, nulls nullKeying) (string, []any, bool) {
+	key := ""
+	keyVals := make([]any, 0, len(groupFields))
+	for _, field := range groupFields {
+		var fieldVal any
+		var found bool
+
+		// Check if it's a nested field; a computed key (e.g. a CASE expression
+		// containing "." in a literal) is stored on the row under its own text
+		if fieldpath.IsNestedField(field) {
+			fieldVal, found = fieldpath.GetNestedField(data, field)
+			if !found && v.Kind() == reflect.Map {
+				if f := v.MapIndex(reflect.ValueOf(field)); f.IsValid() {
+					fieldVal, found = f.Interface(), true
+				}
+			}
+		} else {
+			// Original field access logic
+			var f reflect.Value
+			if v.Kind() == reflect.Map {
+				keyVal := reflect.ValueOf(field)
+				f = v.MapIndex(keyVal)
+			} else {
+				f = v.FieldByName(field)
+			}
+
+			if f.IsValid() {
+				fieldVal = f.Interface()
+				found = true
+			}
+		}
+
+		// Missing or nil group field (e.g. a LEFT JOIN row with no match)
+		// collapses into a single NULL group keyed by the sentinel; GetResults
+		// maps it back to nil. Avoids dropping the whole row on a nullable key.
+		// GroupNullPolicy may instead drop the row or substitute a literal.
+		if !found || fieldVal == nil {
+			switch {
+			case nulls.drop:
+				return "", nil, false
+			case nulls.coalesce:
+				key += nulls.literal + groupKeySep
+				keyVals = append(keyVals, nulls.literal)
+			default:
+				key += nullGroupKeyMarker + groupKeySep
+				keyVals = append(keyVals, nil)
+			}
+			continue
+		}
+
+		if str, ok := fieldVal.(string); ok {
+			key += str + groupKeySep
+		} else {
+			key += fmt.Sprintf("%v", fieldVal) + groupKeySep
+		}
+		keyVals = append(keyVals, fieldVal)
+	}
+	return key, keyVals, true
+}
+
+// EnableQualityCounts makes every result row carry InputCountField (rows
+// added plus rows reported via AddRejected) and FilteredCountField (rows added)
+// for its group. Added rows are counted since the last Reset. Rejected rows
+// count towards the result of the window containing their timestamp (all of a
+// group's pending rejected rows when the window has no bounds, e.g. counting
+// windows), so a group that has no added rows produces no result row, and
+// Reset discards the rejected rows of windows that have ended.
+func (ga *GroupAggregator) EnableQualityCounts() {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.qualityCounts = true
+	if ga.accepted == nil {
+		ga.accepted = make(map[string]int64)
+	}
+	ga.rejected = make(map[string][]time.Time)
+}
+
+// SetKeyedEmission declares that every result covers only the groups it
+// contains, as with per-key counting windows, where each group fires on its
+// own. Reset then keeps the rejected rows of groups not in the result for their
+// own next window.
+func (ga *GroupAggregator) SetKeyedEmission(keyed bool) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.keyedEmission = keyed
+}
+
+// SetMinGroupCount leaves groups with fewer than n rows added since the last
+// Reset out of GetResults, so low-sample groups are never emitted. Unlike
+// HAVING, it applies before any projection or post-aggregation step. n <= 0
+// disables the check.
+func (ga *GroupAggregator) SetMinGroupCount(n int) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.minGroupCount = int64(n)
+	if n > 0 && ga.accepted == nil {
+		ga.accepted = make(map[string]int64)
+	}
+}
+
+// SetNonFinitePolicy sets how numeric aggregates (sum, avg, min, max, stddev,
+// ...) treat NaN and ±Inf inputs. count is unaffected: a NaN is still a value.
+func (ga *GroupAggregator) SetNonFinitePolicy(policy NonFinitePolicy) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.nonFinitePolicy = policy
+	if policy == NonFiniteNullify && ga.nullified == nil {
+		ga.nullified = make(map[string]map[string]bool)
+	}
+}
+
+// SetAggregateBufferLimit caps the values each buffering aggregate (median,
+// percentile, collect, stddev, ...) keeps per group at limit; values beyond it
+// are dropped or sampled per policy and counted by BufferDropped. limit <= 0
+// disables the cap. It applies to groups created after the call.
+func (ga *GroupAggregator) SetAggregateBufferLimit(limit int, policy AggregateBufferPolicy) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.bufferLimit, ga.bufferPolicy = limit, policy
+}
+
+// BufferDropped returns the values buffering aggregates did not keep because of
+// the buffer limit, summed over all groups since the last Reset.
+func (ga *GroupAggregator) BufferDropped() int64 {
 	ga.mu.RLock()
 	defer ga.mu.RUnlock()
+	var dropped int64
+	for _, aggregators := range ga.groups {
+		for _, agg := range aggregators {
+			dropped += functions.BufferDropped(agg)
+		}
+	}
+	return dropped
+}
+
+// SetGroupNullPolicy sets how rows with a NULL or missing group field are
+// grouped: in a NULL group of their own (default), dropped, or grouped under a
+// literal (GroupNullCoalesceTo). An invalid policy is rejected and the current
+// one kept.
+func (ga *GroupAggregator) SetGroupNullPolicy(policy GroupNullPolicy) error {
+	nulls, err := policy.keying()
+	if err != nil {
+		return err
+	}
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	ga.nullKeys = nulls
+	return nil
+}
+
+// admitNonFinite applies the NaN/Inf policy to val, a numeric aggregate input
+// of group key and aggregate alias. It reports whether val should be added.
+func (ga *GroupAggregator) admitNonFinite(key, alias string, val any) (bool, error) {
+	var f float64
+	switch n := val.(type) {
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	default:
+		return true, nil
+	}
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return true, nil
+	}
+	switch ga.nonFinitePolicy {
+	case NonFiniteSkip:
+		return false, nil
+	case NonFiniteError:
+		return false, fmt.Errorf("non-finite value %v for aggregate %s", f, alias)
+	case NonFiniteNullify:
+		if ga.nullified[key] == nil {
+			ga.nullified[key] = make(map[string]bool)
+		}
+		ga.nullified[key][alias] = true
+		return false, nil
+	}
+	return true, nil
+}
+
+// AddRejected counts a row that was rejected before aggregation towards its
+// group's InputCountField. It is a no-op unless EnableQualityCounts was called.
+func (ga *GroupAggregator) AddRejected(data any, ts time.Time) error {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
+	if !ga.qualityCounts {
+		return nil
+	}
+	v, err := rowValue(data)
+	if err != nil {
+		return err
+	}
+	key, _, ok := groupKeyOf(data, v, ga.groupFields, ga.nullKeys)
+	if ok {
+		ga.rejected[key] = append(ga.rejected[key], ts)
+	}
+	return nil
+}
+
+// rejectedIn counts the group's rejected rows inside [start, end); every
+// pending row counts when hasSpan is false. Rows with a zero ts always count.
+func (ga *GroupAggregator) rejectedIn(key string, start, end time.Time, hasSpan bool) int64 {
+	if !hasSpan {
+		return int64(len(ga.rejected[key]))
+	}
+	var n int64
+	for _, ts := range ga.rejected[key] {
+		if ts.IsZero() || (!ts.Before(start) && ts.Before(end)) {
+			n++
+		}
+	}
+	return n
+}
+
+// pruneRejected drops the rejected rows of key that no later window can
+// contain: those before cutoff and those with a zero ts.
+func (ga *GroupAggregator) pruneRejected(key string, cutoff time.Time) {
+	kept := ga.rejected[key][:0]
+	for _, ts := range ga.rejected[key] {
+		if !ts.IsZero() && !ts.Before(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	if len(kept) == 0 {
+		delete(ga.rejected, key)
+		return
+	}
+	ga.rejected[key] = kept
+}
+
+// GetResults takes the write lock: span-aware aggregators (window_rate, rate)
+// get the window bounds set before their Result is read.
+func (ga *GroupAggregator) GetResults() ([]map[string]any, error) {
+	ga.mu.Lock()
+	defer ga.mu.Unlock()
 
 	// 如果既没有分组字段又没有聚合字段，但有数据被添加过，返回一个空的结果行
 	if len(ga.aggregationFields) == 0 && len(ga.groupFields) == 0 {
@@ -354,8 +688,12 @@ func (ga *GroupAggregator) GetResults() ([]map[string]any, error) {
 		return []map[string]any{}, nil
 	}
 
+	spanStart, spanEnd, hasSpan := ga.windowSpan()
 	result := make([]map[string]any, 0, len(ga.groups))
 	for key, aggregators := range ga.groups {
+		if ga.minGroupCount > 0 && ga.accepted[key] < ga.minGroupCount {
+			continue
+		}
 		group := make(map[string]any)
 		keyVals := ga.groupKeyVals[key]
 		for i, field := range ga.groupFields {
@@ -364,6 +702,13 @@ func (ga *GroupAggregator) GetResults() ([]map[string]any, error) {
 			}
 		}
 		for field, agg := range aggregators {
+			if ga.nullified[key][field] {
+				group[field] = nil
+				continue
+			}
+			if hasSpan {
+				functions.SetWindowSpan(agg, spanStart, spanEnd)
+			}
 			result := agg.Result()
 			group[field] = result
 			// Debug: log aggregator results (can be removed in production)
@@ -371,14 +716,64 @@ func (ga *GroupAggregator) GetResults() ([]map[string]any, error) {
 			//	fmt.Printf("Aggregator %s result: %v (%T)\n", field, result, result)
 			// }
 		}
+		if ga.qualityCounts {
+			group[InputCountField] = ga.accepted[key] + ga.rejectedIn(key, spanStart, spanEnd, hasSpan)
+			group[FilteredCountField] = ga.accepted[key]
+		}
 		result = append(result, group)
 	}
 	return result, nil
 }
 
+// resetRejected discards the rejected rows of the window just emitted. Per-key
+// windows (SetKeyedEmission) only touch the groups in the result: without
+// bounds their pending rows are all counted, with bounds rows from the group's
+// next window (ts >= end) stay. Windows firing for all groups at once discard
+// every group's rows before the window start, including groups whose rows were
+// all rejected; later rows may belong to an overlapping next window.
+func (ga *GroupAggregator) resetRejected() {
+	start, end, hasSpan := ga.windowSpan()
+	switch {
+	case ga.keyedEmission && !hasSpan:
+		for key := range ga.groups {
+			delete(ga.rejected, key)
+		}
+	case ga.keyedEmission:
+		for key := range ga.groups {
+			ga.pruneRejected(key, end)
+		}
+	case !hasSpan:
+		ga.rejected = make(map[string][]time.Time)
+	default:
+		for key := range ga.rejected {
+			ga.pruneRejected(key, start)
+		}
+	}
+}
+
+// windowSpan returns the current window bounds put by the stream
+// (window_start/window_end, unix nanoseconds); ok is false outside windows.
+func (ga *GroupAggregator) windowSpan() (start, end time.Time, ok bool) {
+	s, okStart := ga.context[functions.WindowStartStr].(int64)
+	e, okEnd := ga.context[functions.WindowEndStr].(int64)
+	if !okStart || !okEnd || e <= s {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(0, s), time.Unix(0, e), true
+}
+
 func (ga *GroupAggregator) Reset() {
 	ga.mu.Lock()
 	defer ga.mu.Unlock()
+	if ga.qualityCounts {
+		ga.resetRejected()
+	}
+	if ga.accepted != nil {
+		ga.accepted = make(map[string]int64)
+	}
+	if ga.nullified != nil {
+		ga.nullified = make(map[string]map[string]bool)
+	}
 	ga.groups = make(map[string]map[string]AggregatorFunction)
 	ga.groupKeyVals = make(map[string][]any)
 }
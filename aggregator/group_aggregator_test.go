@@ -3,10 +3,12 @@ package aggregator
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rulego/streamsql/functions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -2026,3 +2028,424 @@ func TestGroupAggregatorExpressionEvaluationError(t *testing.T) {
 	// sum_value 应该有值，expr_result 应该没有值或为默认值
 	assert.Equal(t, float64(10), results[0]["sum_value"])
 }
+
+// TestGroupAggregator_QualityCounts 测试数据质量计数：被拒绝行计入 __input_count
+func TestGroupAggregator_QualityCounts(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+	})
+
+	// 未启用时 AddRejected 无效果、结果不含计数列
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "a", "v": 1}, time.Time{}))
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 2}))
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotContains(t, results[0], InputCountField)
+	agg.Reset()
+
+	agg.EnableQualityCounts()
+	for _, row := range []map[string]any{
+		{"device": "a", "v": 10}, {"device": "a", "v": 20}, {"device": "b", "v": 5},
+	} {
+		require.NoError(t, agg.Add(row))
+	}
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "a", "v": -1}, time.Time{}))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "b", "v": -1}, time.Time{}))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "b", "v": -2}, time.Time{}))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "c", "v": -3}, time.Time{})) // 无通过行的分组不输出
+	assert.Error(t, agg.AddRejected(nil, time.Time{}))
+
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	byDevice := map[string]map[string]any{}
+	for _, r := range results {
+		byDevice[r["device"].(string)] = r
+	}
+	assert.Equal(t, int64(3), byDevice["a"][InputCountField])
+	assert.Equal(t, int64(2), byDevice["a"][FilteredCountField])
+	assert.Equal(t, int64(3), byDevice["b"][InputCountField])
+	assert.Equal(t, int64(1), byDevice["b"][FilteredCountField])
+
+	// Reset 随窗口清空全部计数：上一窗口里全被过滤的分组 c 不把旧计数带入下一窗口
+	agg.Reset()
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 1}))
+	require.NoError(t, agg.Add(map[string]any{"device": "c", "v": 1}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		assert.Equal(t, int64(1), r[InputCountField], r["device"])
+		assert.Equal(t, int64(1), r[FilteredCountField], r["device"])
+	}
+	agg.Reset()
+	assert.Empty(t, agg.rejected)
+}
+
+// TestGroupAggregator_ConcurrentGetResultsWindowSpan 并发读取结果时写入窗口边界不产生数据竞争（-race）
+func TestGroupAggregator_ConcurrentGetResultsWindowSpan(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: "window_rate", OutputAlias: "r"},
+	})
+	base := time.Unix(1000, 0)
+	require.NoError(t, agg.Put(functions.WindowStartStr, base.UnixNano()))
+	require.NoError(t, agg.Put(functions.WindowEndStr, base.Add(10*time.Second).UnixNano()))
+	require.NoError(t, agg.AddAt(map[string]any{"device": "a", "v": 10}, base))
+	require.NoError(t, agg.AddAt(map[string]any{"device": "a", "v": 40}, base.Add(5*time.Second)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := agg.GetResults()
+			assert.NoError(t, err)
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, 3.0, results[0]["r"])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestGroupAggregator_QualityCountsWindowSpan 有窗口边界时拒绝行按时间戳归入所在窗口：
+// 分组在一个窗口里全被过滤、下一窗口有通过行时，不带入上一窗口的拒绝计数
+func TestGroupAggregator_QualityCountsWindowSpan(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+	})
+	agg.EnableQualityCounts()
+	base := time.Unix(1000, 0)
+	span := func(start time.Time) {
+		require.NoError(t, agg.Put(functions.WindowStartStr, start.UnixNano()))
+		require.NoError(t, agg.Put(functions.WindowEndStr, start.Add(10*time.Second).UnixNano()))
+	}
+
+	// 窗口 1：a 通过一行；c 全被过滤。c 在窗口 2 的拒绝行先于窗口 1 触发到达
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "c", "v": -1}, base.Add(time.Second)))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "c", "v": -2}, base.Add(2*time.Second)))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "c", "v": -3}, base.Add(11*time.Second)))
+	span(base)
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 1}))
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(1), results[0][InputCountField])
+	agg.Reset()
+
+	// 窗口 2：c 只计入本窗口的拒绝行
+	span(base.Add(10 * time.Second))
+	require.NoError(t, agg.Add(map[string]any{"device": "c", "v": 5}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "c", results[0]["device"])
+	assert.Equal(t, int64(2), results[0][InputCountField])
+	assert.Equal(t, int64(1), results[0][FilteredCountField])
+	agg.Reset()
+	// 早于窗口 2 起点的拒绝行被丢弃，map 不会无界增长；窗口 2 内的行留到下次 Reset
+	// （滑动窗口时它们可能属于重叠的下一窗口）
+	require.Len(t, agg.rejected, 1)
+	for _, pending := range agg.rejected {
+		assert.Equal(t, []time.Time{base.Add(11 * time.Second)}, pending)
+	}
+	span(base.Add(20 * time.Second))
+	agg.Reset()
+	assert.Empty(t, agg.rejected)
+}
+
+// TestGroupAggregator_QualityCountsKeyedEmission 按键触发的窗口：未输出分组的拒绝行留到其下一次结果
+func TestGroupAggregator_QualityCountsKeyedEmission(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+	})
+	agg.EnableQualityCounts()
+	agg.SetKeyedEmission(true)
+
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "c", "v": -3}, time.Time{}))
+	require.NoError(t, agg.AddRejected(map[string]any{"device": "a", "v": -1}, time.Time{}))
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 1}))
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(2), results[0][InputCountField])
+	agg.Reset()
+
+	require.NoError(t, agg.Add(map[string]any{"device": "c", "v": 1}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "c", results[0]["device"])
+	assert.Equal(t, int64(2), results[0][InputCountField])
+	assert.Equal(t, int64(1), results[0][FilteredCountField])
+	agg.Reset()
+	assert.Empty(t, agg.rejected)
+}
+
+// collect 按行加入顺序收集，NULL 与缺失字段收集为 nil，数组长度与行数一致。
+func TestGroupAggregator_CollectKeepsOrderAndNulls(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Collect, OutputAlias: "vs"},
+		{InputField: "*", AggregateType: Count, OutputAlias: "n"},
+	})
+	for _, row := range []map[string]any{
+		{"device": "a", "v": 3.0}, {"device": "b", "v": "x"}, {"device": "a", "v": nil},
+		{"device": "a"}, {"device": "a", "v": 1.0}, {"device": "a", "v": 2.0},
+	} {
+		require.NoError(t, agg.Add(row))
+	}
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	byDevice := map[string]map[string]any{}
+	for _, r := range results {
+		byDevice[r["device"].(string)] = r
+	}
+	assert.Equal(t, []any{3.0, nil, nil, 1.0, 2.0}, byDevice["a"]["vs"])
+	assert.Equal(t, 5.0, byDevice["a"]["n"])
+	assert.Equal(t, []any{"x"}, byDevice["b"]["vs"])
+}
+
+// first_value 与 last_value 对称：按到达顺序取首/末行的值（含 NULL），全为 NULL 时结果为 nil
+func TestGroupAggregator_FirstValue(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: FirstValue, OutputAlias: "first"},
+		{InputField: "v", AggregateType: LastValue, OutputAlias: "last"},
+	})
+	for _, row := range []map[string]any{
+		{"device": "a", "v": "x"}, {"device": "a", "v": 2.0}, {"device": "a", "v": "z"},
+		{"device": "b", "v": nil}, {"device": "b", "v": nil},
+	} {
+		require.NoError(t, agg.Add(row))
+	}
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	byDevice := map[string]map[string]any{}
+	for _, r := range results {
+		byDevice[r["device"].(string)] = r
+	}
+	assert.Equal(t, "x", byDevice["a"]["first"])
+	assert.Equal(t, "z", byDevice["a"]["last"])
+	assert.Nil(t, byDevice["b"]["first"])
+	assert.Nil(t, byDevice["b"]["last"])
+
+	agg.Reset()
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 7.0}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 7.0, results[0]["first"])
+}
+
+func TestGroupAggregator_NonFinitePolicy(t *testing.T) {
+	rows := []map[string]any{
+		{"device": "a", "v": 1.0}, {"device": "a", "v": math.NaN()}, {"device": "a", "v": 3.0},
+		{"device": "b", "v": math.Inf(1)}, {"device": "b", "v": 2.0},
+	}
+	newAgg := func(policy NonFinitePolicy) *GroupAggregator {
+		agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+			{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+			{InputField: "v", AggregateType: Count, OutputAlias: "cnt"},
+		})
+		agg.SetNonFinitePolicy(policy)
+		return agg
+	}
+	collect := func(agg *GroupAggregator) map[string]map[string]any {
+		results, err := agg.GetResults()
+		require.NoError(t, err)
+		byDevice := map[string]map[string]any{}
+		for _, r := range results {
+			byDevice[r["device"].(string)] = r
+		}
+		return byDevice
+	}
+
+	t.Run("默认原样参与", func(t *testing.T) {
+		agg := newAgg(NonFinitePassThrough)
+		for _, row := range rows {
+			require.NoError(t, agg.Add(row))
+		}
+		got := collect(agg)
+		assert.True(t, math.IsNaN(got["a"]["total"].(float64)))
+		assert.True(t, math.IsInf(got["b"]["total"].(float64), 1))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		agg := newAgg(NonFiniteSkip)
+		for _, row := range rows {
+			require.NoError(t, agg.Add(row))
+		}
+		got := collect(agg)
+		assert.Equal(t, 4.0, got["a"]["total"])
+		assert.Equal(t, 2.0, got["b"]["total"])
+		assert.Equal(t, 3.0, got["a"]["cnt"], "count 不受策略影响")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		agg := newAgg(NonFiniteError)
+		var errs int
+		for _, row := range rows {
+			if err := agg.Add(row); err != nil {
+				errs++
+				assert.Contains(t, err.Error(), "total")
+			}
+		}
+		assert.Equal(t, 2, errs)
+		got := collect(agg)
+		assert.Equal(t, 4.0, got["a"]["total"], "非有限值不进入聚合")
+		assert.Equal(t, 2.0, got["b"]["cnt"], "同一行的其他聚合照常更新")
+	})
+
+	t.Run("nullify", func(t *testing.T) {
+		agg := newAgg(NonFiniteNullify)
+		for _, row := range rows {
+			require.NoError(t, agg.Add(row))
+		}
+		require.NoError(t, agg.Add(map[string]any{"device": "c", "v": 5.0}))
+		got := collect(agg)
+		assert.Nil(t, got["a"]["total"])
+		assert.Nil(t, got["b"]["total"])
+		assert.Equal(t, 5.0, got["c"]["total"])
+		assert.Equal(t, 3.0, got["a"]["cnt"])
+
+		// 下一个窗口（Reset 之后）恢复正常
+		agg.Reset()
+		require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 7.0}))
+		assert.Equal(t, 7.0, collect(agg)["a"]["total"])
+	})
+}
+
+func TestGroupAggregator_GroupNullPolicy(t *testing.T) {
+	rows := []map[string]any{
+		{"device": "a", "v": 1.0}, {"device": nil, "v": 2.0}, {"v": 3.0},
+		{"device": "unknown", "v": 4.0}, {"device": "a", "v": 5.0},
+	}
+	totals := func(agg Aggregator) map[any]any {
+		for _, row := range rows {
+			require.NoError(t, agg.Add(row))
+		}
+		results, err := agg.GetResults()
+		require.NoError(t, err)
+		got := map[any]any{}
+		for _, r := range results {
+			got[r["device"]] = r["total"]
+		}
+		return got
+	}
+	newAgg := func(policy GroupNullPolicy) *GroupAggregator {
+		agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+			{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+		})
+		require.NoError(t, agg.SetGroupNullPolicy(policy))
+		return agg
+	}
+
+	t.Run("默认单独成组", func(t *testing.T) {
+		want := map[any]any{"a": 6.0, nil: 5.0, "unknown": 4.0}
+		assert.Equal(t, want, totals(newAgg("")))
+		assert.Equal(t, want, totals(newAgg(GroupNullSeparate)))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		assert.Equal(t, map[any]any{"a": 6.0, "unknown": 4.0}, totals(newAgg(GroupNullDrop)))
+	})
+
+	t.Run("coalesce_to", func(t *testing.T) {
+		assert.Equal(t, map[any]any{"a": 6.0, "unknown": 9.0}, totals(newAgg(GroupNullCoalesceTo("unknown"))), "与取值相同的分组合并")
+		assert.Equal(t, map[any]any{"a": 6.0, "n/a": 5.0, "unknown": 4.0}, totals(newAgg(`coalesce_to:"n/a"`)))
+	})
+
+	t.Run("分片路由一致", func(t *testing.T) {
+		parts := make([]Aggregator, 4)
+		for i := range parts {
+			parts[i] = NewGroupAggregator([]string{"device"}, []AggregationField{
+				{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+			})
+		}
+		sa := NewShardedAggregator([]string{"device"}, parts)
+		require.NoError(t, sa.SetGroupNullPolicy(GroupNullCoalesceTo("unknown")))
+		assert.Equal(t, map[any]any{"a": 6.0, "unknown": 9.0}, totals(sa))
+	})
+
+	t.Run("非法策略", func(t *testing.T) {
+		agg := NewGroupAggregator([]string{"device"}, nil)
+		for _, policy := range []GroupNullPolicy{"skip", "coalesce_to:unknown", `coalesce_to:"x`} {
+			assert.Error(t, agg.SetGroupNullPolicy(policy), policy)
+			assert.Error(t, policy.Validate(), policy)
+		}
+	})
+}
+
+func TestGroupAggregator_MinGroupCount(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+	})
+	agg.SetMinGroupCount(2)
+	for _, row := range []map[string]any{
+		{"device": "a", "v": 10}, {"device": "a", "v": 20}, {"device": "b", "v": 5},
+		{"device": "c", "v": 1}, {"device": "c", "v": 2}, {"device": "c", "v": 3},
+	} {
+		require.NoError(t, agg.Add(row))
+	}
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	totals := map[string]any{}
+	for _, r := range results {
+		totals[r["device"].(string)] = r["total"]
+	}
+	assert.Equal(t, map[string]any{"a": 30.0, "c": 6.0}, totals, "单样本分组 b 不输出")
+
+	// 计数按窗口（Reset）重新开始
+	agg.Reset()
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 1}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// 与质量计数同时启用时计数互不干扰
+	agg.Reset()
+	agg.EnableQualityCounts()
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 1}))
+	require.NoError(t, agg.Add(map[string]any{"device": "a", "v": 2}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(2), results[0][FilteredCountField])
+
+	// n <= 0 关闭过滤
+	agg.SetMinGroupCount(0)
+	agg.Reset()
+	require.NoError(t, agg.Add(map[string]any{"device": "b", "v": 1}))
+	results, err = agg.GetResults()
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestGroupAggregator_AggregateBufferLimit(t *testing.T) {
+	agg := NewGroupAggregator([]string{"device"}, []AggregationField{
+		{InputField: "v", AggregateType: Median, OutputAlias: "med"},
+		{InputField: "v", AggregateType: Collect, OutputAlias: "vals"},
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+	})
+	agg.SetAggregateBufferLimit(3, AggregateBufferDrop)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, agg.Add(map[string]any{"device": "a", "v": float64(i)}))
+	}
+	require.NoError(t, agg.Add(map[string]any{"device": "b", "v": 9.0}))
+
+	results, err := agg.GetResults()
+	require.NoError(t, err)
+	byDevice := map[string]map[string]any{}
+	for _, r := range results {
+		byDevice[r["device"].(string)] = r
+	}
+	assert.Equal(t, 2.0, byDevice["a"]["med"], "median of the first 3 values")
+	assert.Equal(t, []any{1.0, 2.0, 3.0}, byDevice["a"]["vals"])
+	assert.Equal(t, 15.0, byDevice["a"]["total"], "sum does not buffer")
+	assert.Equal(t, []any{9.0}, byDevice["b"]["vals"], "the limit is per group")
+	// median 与 collect 各丢弃 2 个
+	assert.Equal(t, int64(4), agg.BufferDropped())
+
+	agg.Reset()
+	assert.Equal(t, int64(0), agg.BufferDropped())
+}
@@ -0,0 +1,60 @@
+package aggregator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GroupNullPolicy selects how rows whose GROUP BY field is NULL or missing are
+// grouped (e.g. a LEFT JOIN row with no match, a device without a site tag).
+type GroupNullPolicy string
+
+const (
+	// GroupNullSeparate collects those rows into one group of their own whose
+	// key column is NULL (default; the empty value behaves the same).
+	GroupNullSeparate GroupNullPolicy = "separate_group"
+	// GroupNullDrop leaves those rows out of the aggregation.
+	GroupNullDrop GroupNullPolicy = "drop"
+
+	groupNullCoalescePrefix = "coalesce_to:"
+)
+
+// GroupNullCoalesceTo returns the policy coalesce_to:"<literal>", which groups
+// those rows under literal in place of NULL: they join the group of rows whose
+// field equals literal, and the key column shows literal.
+func GroupNullCoalesceTo(literal string) GroupNullPolicy {
+	return GroupNullPolicy(groupNullCoalescePrefix + strconv.Quote(literal))
+}
+
+// Validate reports an error for an unknown policy or a coalesce_to literal that
+// is not a double-quoted string.
+func (p GroupNullPolicy) Validate() error {
+	_, err := p.keying()
+	return err
+}
+
+// nullKeying is the resolved form of a GroupNullPolicy used by groupKeyOf.
+type nullKeying struct {
+	drop     bool
+	coalesce bool
+	literal  string
+}
+
+func (p GroupNullPolicy) keying() (nullKeying, error) {
+	switch p {
+	case "", GroupNullSeparate:
+		return nullKeying{}, nil
+	case GroupNullDrop:
+		return nullKeying{drop: true}, nil
+	}
+	if strings.HasPrefix(string(p), groupNullCoalescePrefix) {
+		raw := strings.TrimSpace(strings.TrimPrefix(string(p), groupNullCoalescePrefix))
+		literal, err := strconv.Unquote(raw)
+		if err != nil || !strings.HasPrefix(raw, `"`) {
+			return nullKeying{}, fmt.Errorf("invalid group null policy %q: coalesce_to needs a double-quoted literal, e.g. coalesce_to:\"unknown\"", p)
+		}
+		return nullKeying{coalesce: true, literal: literal}, nil
+	}
+	return nullKeying{}, fmt.Errorf("invalid group null policy %q: must be %q, %q or coalesce_to:\"<literal>\"", p, GroupNullSeparate, GroupNullDrop)
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rulego/streamsql/functions"
 )
@@ -122,6 +123,13 @@ func (p *PostAggregationProcessor) ProcessResults(results []map[string]any) ([]m
 				continue
 			}
 
+			// Bare placeholder (e.g. a parameterized top_k(v, 3)): take the aggregate as is,
+			// so a one-element slice result is not unwrapped
+			if len(expr.RequiredAggFields) == 1 && expr.Expression == expr.RequiredAggFields[0] {
+				result[expr.OutputField] = result[expr.Expression]
+				continue
+			}
+
 			// Evaluate expression
 			exprResult, err := p.evaluateExpressionFast(expr.Expression, result)
 			if err != nil {
@@ -437,8 +445,10 @@ func (ega *EnhancedGroupAggregator) AddPostAggregationExpression(outputField, or
 			}
 		}
 
-		// Check if input field is an expression (contains function calls)
-		isInputExpression := strings.Contains(field.InputField, "(") && strings.Contains(field.InputField, ")")
+		// Check if input field is an expression (function calls or arithmetic such as
+		// "t * 1.8 + 32" in wavg(t * 1.8 + 32, c)), not a plain or nested field name
+		isInputExpression := strings.Contains(field.InputField, "(") && strings.Contains(field.InputField, ")") ||
+			field.InputField != "*" && strings.ContainsAny(field.InputField, "+-*/% ")
 
 		// If input expression itself contains aggregation calls, skip creating an aggregator for this field
 		// Use dynamic function registry instead of hardcoded list
@@ -774,8 +784,8 @@ func (ega *EnhancedGroupAggregator) parseFunctionCall(funcCall string) ([]any, e
 		return []any{}, nil
 	}
 
-	// Split parameters by comma
-	paramStrs := strings.Split(paramsStr, ",")
+	// Split parameters by top-level comma (commas inside quotes or parentheses are kept)
+	paramStrs := splitTopLevelArgs(paramsStr)
 	args := make([]any, len(paramStrs))
 
 	for i, paramStr := range paramStrs {
@@ -800,6 +810,40 @@ func (ega *EnhancedGroupAggregator) parseFunctionCall(funcCall string) ([]any, e
 	return args, nil
 }
 
+// splitTopLevelArgs splits a parameter list on commas outside string literals and
+// parentheses, so string_agg(name, ',') yields two arguments.
+func splitTopLevelArgs(params string) []string {
+	var parts []string
+	level := 0
+	last := 0
+	stringChar := byte(0)
+	for i := 0; i < len(params); i++ {
+		ch := params[i]
+		if stringChar != 0 {
+			if ch == stringChar {
+				stringChar = 0
+			}
+			continue
+		}
+		switch ch {
+		case '\'', '"':
+			stringChar = ch
+		case '(':
+			level++
+		case ')':
+			if level > 0 {
+				level--
+			}
+		case ',':
+			if level == 0 {
+				parts = append(parts, params[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, params[last:])
+}
+
 // WindowFunctionWrapper wraps window functions to make them compatible with LegacyAggregatorFunction
 type WindowFunctionWrapper struct {
 	aggFunc functions.AggregatorFunction
@@ -813,6 +857,26 @@ func (w *WindowFunctionWrapper) Add(value any) {
 	w.aggFunc.Add(value)
 }
 
+func (w *WindowFunctionWrapper) AddAt(value any, ts time.Time) {
+	functions.AddAt(w.aggFunc, value, ts)
+}
+
+func (w *WindowFunctionWrapper) AddRow(value any, ts time.Time, row any) {
+	functions.AddRow(w.aggFunc, value, ts, row)
+}
+
+func (w *WindowFunctionWrapper) SetWindowSpan(start, end time.Time) {
+	functions.SetWindowSpan(w.aggFunc, start, end)
+}
+
+func (w *WindowFunctionWrapper) SetBufferLimit(limit int, policy functions.AggregateBufferPolicy) {
+	functions.SetBufferLimit(w.aggFunc, limit, policy)
+}
+
+func (w *WindowFunctionWrapper) BufferDropped() int64 {
+	return functions.BufferDropped(w.aggFunc)
+}
+
 func (w *WindowFunctionWrapper) Result() any {
 	return w.aggFunc.Result()
 }
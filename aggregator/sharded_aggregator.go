@@ -0,0 +1,208 @@
+package aggregator
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// BatchAdder is implemented by aggregators that can ingest a whole window batch
+// at once (e.g. in parallel). ts holds each row's timestamp and may be nil.
+// Callers fall back to per-row Add otherwise.
+type BatchAdder interface {
+	AddBatch(rows []any, ts []time.Time) error
+}
+
+// ShardedAggregator spreads groups across independent aggregators by group-key
+// hash. Every group lives in exactly one shard, so shards never share state:
+// AddBatch and GetResults run one goroutine per shard and the per-shard
+// results are concatenated at emit. Put/RegisterExpression/Reset fan out to
+// all shards. Expression evaluators are therefore invoked concurrently and
+// must be safe for concurrent use.
+type ShardedAggregator struct {
+	groupFields []string
+	shards      []Aggregator
+	nullKeys    nullKeying
+}
+
+// NewShardedAggregator wraps shards (identically configured aggregators, one
+// per worker) grouping by groupFields.
+func NewShardedAggregator(groupFields []string, shards []Aggregator) *ShardedAggregator {
+	return &ShardedAggregator{groupFields: groupFields, shards: shards}
+}
+
+// SetGroupNullPolicy applies policy to every shard and to shard routing, so
+// rows coalesced to a literal reach the shard owning that literal's group.
+func (sa *ShardedAggregator) SetGroupNullPolicy(policy GroupNullPolicy) error {
+	nulls, err := policy.keying()
+	if err != nil {
+		return err
+	}
+	for _, shard := range sa.shards {
+		if setter, ok := shard.(interface{ SetGroupNullPolicy(GroupNullPolicy) error }); ok {
+			if err := setter.SetGroupNullPolicy(policy); err != nil {
+				return err
+			}
+		}
+	}
+	sa.nullKeys = nulls
+	return nil
+}
+
+// SetKeyedEmission applies keyed to every shard that supports it.
+func (sa *ShardedAggregator) SetKeyedEmission(keyed bool) {
+	for _, shard := range sa.shards {
+		if setter, ok := shard.(interface{ SetKeyedEmission(bool) }); ok {
+			setter.SetKeyedEmission(keyed)
+		}
+	}
+}
+
+// BufferDropped sums the buffer-limit drops of all shards.
+func (sa *ShardedAggregator) BufferDropped() int64 {
+	var dropped int64
+	for _, shard := range sa.shards {
+		if counter, ok := shard.(BufferDropCounter); ok {
+			dropped += counter.BufferDropped()
+		}
+	}
+	return dropped
+}
+
+// shardOf returns the shard index owning the row's group.
+func (sa *ShardedAggregator) shardOf(data any) (int, error) {
+	v, err := rowValue(data)
+	if err != nil {
+		return 0, err
+	}
+	key, _, _ := groupKeyOf(data, v, sa.groupFields, sa.nullKeys)
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(sa.shards))), nil
+}
+
+func (sa *ShardedAggregator) Add(data any) error {
+	idx, err := sa.shardOf(data)
+	if err != nil {
+		return err
+	}
+	return sa.shards[idx].Add(data)
+}
+
+func (sa *ShardedAggregator) AddAt(data any, ts time.Time) error {
+	idx, err := sa.shardOf(data)
+	if err != nil {
+		return err
+	}
+	return addRowAt(sa.shards[idx], data, ts)
+}
+
+// AddRejected routes a rejected row to its group's shard when the shard counts
+// rejected rows.
+func (sa *ShardedAggregator) AddRejected(data any, ts time.Time) error {
+	idx, err := sa.shardOf(data)
+	if err != nil {
+		return err
+	}
+	if rc, ok := sa.shards[idx].(RejectCounter); ok {
+		return rc.AddRejected(data, ts)
+	}
+	return nil
+}
+
+// AddBatch partitions rows by shard and aggregates the partitions in parallel.
+// Row order within a group is preserved. Returns the first error encountered;
+// rows that fail are skipped like with Add.
+func (sa *ShardedAggregator) AddBatch(rows []any, ts []time.Time) error {
+	parts := make([][]int, len(sa.shards))
+	var firstErr error
+	for i, row := range rows {
+		idx, err := sa.shardOf(row)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		parts[idx] = append(parts[idx], i)
+	}
+
+	errs := make([]error, len(sa.shards))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, part []int) {
+			defer wg.Done()
+			for _, r := range part {
+				var at time.Time
+				if ts != nil {
+					at = ts[r]
+				}
+				if err := addRowAt(sa.shards[i], rows[r], at); err != nil && errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}(i, part)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if firstErr == nil && err != nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (sa *ShardedAggregator) Put(key string, val any) error {
+	for _, shard := range sa.shards {
+		if err := shard.Put(key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetResults collects every shard's results in parallel and merges them in
+// shard order.
+func (sa *ShardedAggregator) GetResults() ([]map[string]any, error) {
+	results := make([][]map[string]any, len(sa.shards))
+	errs := make([]error, len(sa.shards))
+	var wg sync.WaitGroup
+	for i := range sa.shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = sa.shards[i].GetResults()
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		total += len(results[i])
+	}
+	merged := make([]map[string]any, 0, total)
+	for _, r := range results {
+		merged = append(merged, r...)
+	}
+	return merged, nil
+}
+
+func (sa *ShardedAggregator) Reset() {
+	for _, shard := range sa.shards {
+		shard.Reset()
+	}
+}
+
+func (sa *ShardedAggregator) RegisterExpression(field, expression string, fields []string, evaluator func(data any) (any, error)) {
+	for _, shard := range sa.shards {
+		shard.RegisterExpression(field, expression, fields, evaluator)
+	}
+}
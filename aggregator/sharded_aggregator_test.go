@@ -0,0 +1,119 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shardTestFields() []AggregationField {
+	return []AggregationField{
+		{InputField: "v", AggregateType: Sum, OutputAlias: "total"},
+		{InputField: "v", AggregateType: Avg, OutputAlias: "mean"},
+		{InputField: "*", AggregateType: Count, OutputAlias: "cnt"},
+		{InputField: "v", AggregateType: Max, OutputAlias: "peak"},
+	}
+}
+
+func shardTestRows(groups, perGroup int) []any {
+	rows := make([]any, 0, groups*perGroup)
+	for i := 0; i < perGroup; i++ {
+		for g := 0; g < groups; g++ {
+			rows = append(rows, map[string]any{"device": fmt.Sprintf("d%04d", g), "v": float64(g*perGroup + i)})
+		}
+	}
+	return rows
+}
+
+func newShardedForTest(shards int) *ShardedAggregator {
+	parts := make([]Aggregator, shards)
+	for i := range parts {
+		parts[i] = NewGroupAggregator([]string{"device"}, shardTestFields())
+	}
+	return NewShardedAggregator([]string{"device"}, parts)
+}
+
+func sortByDevice(rows []map[string]any) {
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i]["device"].(string) < rows[j]["device"].(string)
+	})
+}
+
+// TestShardedAggregator_MatchesSingleThreaded 分片聚合与单线程聚合结果一致
+func TestShardedAggregator_MatchesSingleThreaded(t *testing.T) {
+	rows := shardTestRows(257, 7)
+
+	single := NewGroupAggregator([]string{"device"}, shardTestFields())
+	for _, row := range rows {
+		require.NoError(t, single.Add(row))
+	}
+	want, err := single.GetResults()
+	require.NoError(t, err)
+	sortByDevice(want)
+
+	for _, shards := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			sharded := newShardedForTest(shards)
+			require.NoError(t, sharded.AddBatch(rows, nil))
+			got, err := sharded.GetResults()
+			require.NoError(t, err)
+			sortByDevice(got)
+			assert.Equal(t, want, got)
+
+			// Reset 清空所有分片；逐行 Add 与 AddBatch 路由一致
+			sharded.Reset()
+			got, err = sharded.GetResults()
+			require.NoError(t, err)
+			assert.Empty(t, got)
+			for _, row := range rows {
+				require.NoError(t, sharded.Add(row))
+			}
+			got, err = sharded.GetResults()
+			require.NoError(t, err)
+			sortByDevice(got)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+// TestShardedAggregator_PutAndErrors 上下文广播到所有分片；非法行报错但不影响其余行
+func TestShardedAggregator_PutAndErrors(t *testing.T) {
+	sharded := newShardedForTest(3)
+	require.NoError(t, sharded.Put("window_start", int64(1)))
+	for _, shard := range sharded.shards {
+		assert.Equal(t, int64(1), shard.(*GroupAggregator).context["window_start"])
+	}
+
+	err := sharded.AddBatch([]any{nil, map[string]any{"device": "a", "v": 1.0}, 42}, nil)
+	assert.Error(t, err)
+	got, err := sharded.GetResults()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0]["total"])
+}
+
+// BenchmarkShardedAggregation 对比高基数分组下单线程与分片聚合的吞吐
+func BenchmarkShardedAggregation(b *testing.B) {
+	rows := shardTestRows(10000, 10)
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			agg := NewGroupAggregator([]string{"device"}, shardTestFields())
+			for _, row := range rows {
+				_ = agg.Add(row)
+			}
+			_, _ = agg.GetResults()
+		}
+	})
+	for _, shards := range []int{4, 8} {
+		b.Run(fmt.Sprintf("sharded-%d", shards), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				agg := newShardedForTest(shards)
+				_ = agg.AddBatch(rows, nil)
+				_, _ = agg.GetResults()
+			}
+		})
+	}
+}
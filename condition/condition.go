@@ -16,6 +16,12 @@ type Condition interface {
 	Evaluate(env any) bool
 }
 
+// ErrorCondition is implemented by conditions that can report why an
+// evaluation failed instead of treating the failure as false.
+type ErrorCondition interface {
+	EvaluateWithError(env any) (bool, error)
+}
+
 type ExprCondition struct {
 	program *vm.Program
 	// fast is a compiled fast-path for trivial `field OP literal` comparisons.
@@ -59,8 +65,17 @@ func NewExprCondition(expression string) (Condition, error) {
 	}
 	// 注入 StreamSQL 内置函数，使 WHERE/HAVING/OVER-WHEN 等条件可调用 to_seconds/now/abs 等
 	options = append(options, functions.GetExprBridge().RegisterStreamSQLFunctionsToExpr()...)
+	// 用户注册的条件函数最后注入，同名时覆盖内置函数
+	options = append(options, registeredFunctionOptions()...)
 
-	program, err := expr.Compile(expression, options...)
+	// coalesce 改写为 ?? 链，遇到首个非 NULL 参数即短路
+	compiled := expression
+	if bridge := functions.GetExprBridge(); bridge.ContainsCoalesceCall(expression) {
+		if processed, err := bridge.PreprocessCoalesceExpression(expression); err == nil {
+			compiled = processed
+		}
+	}
+	program, err := expr.Compile(compiled, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -74,20 +89,31 @@ func NewExprCondition(expression string) (Condition, error) {
 }
 
 func (ec *ExprCondition) Evaluate(env any) bool {
+	ok, _ := ec.EvaluateWithError(env)
+	return ok
+}
+
+// EvaluateWithError evaluates the condition like Evaluate, additionally
+// returning the runtime error (e.g. a type mismatch) that made it false.
+func (ec *ExprCondition) EvaluateWithError(env any) (bool, error) {
 	if ec.compound != nil {
 		if r, ok := ec.compound.eval(env); ok {
-			return r
+			return r, nil
 		}
 	} else if ec.fast != nil {
 		if r, ok := ec.fast.eval(env); ok {
-			return r
+			return r, nil
 		}
 	}
 	result, err := expr.Run(ec.program, env)
 	if err != nil {
-		return false
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition result is %T, not bool", result)
 	}
-	return result.(bool)
+	return b, nil
 }
 
 // fastCompare is a compiled fast-path for a single `field OP literal`
@@ -1,6 +1,8 @@
 package condition
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -496,3 +498,73 @@ func TestExprCondition_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestExprCondition_EvaluateWithError 测试EvaluateWithError返回运行时错误和非布尔结果错误
+func TestExprCondition_EvaluateWithError(t *testing.T) {
+	cond, err := NewExprCondition("age > 18")
+	require.NoError(t, err)
+	ec, ok := cond.(ErrorCondition)
+	require.True(t, ok)
+
+	pass, err := ec.EvaluateWithError(map[string]any{"age": 30})
+	assert.NoError(t, err)
+	assert.True(t, pass)
+
+	pass, err = ec.EvaluateWithError(map[string]any{"age": "unknown"})
+	assert.Error(t, err)
+	assert.False(t, pass)
+
+	cond, err = NewExprCondition("age + 1")
+	require.NoError(t, err)
+	pass, err = cond.(ErrorCondition).EvaluateWithError(map[string]any{"age": 1})
+	assert.Error(t, err)
+	assert.False(t, pass)
+}
+
+// TestRegisterConditionFunction 测试注册的条件函数可在之后编译的条件中调用，且可覆盖内置函数
+func TestRegisterConditionFunction(t *testing.T) {
+	RegisterConditionFunction("in_test_fence", func(args ...any) any {
+		lat, _ := args[0].(float64)
+		lon, _ := args[1].(float64)
+		return lat >= 30 && lat <= 31 && lon >= 120 && lon <= 121
+	})
+	cond, err := NewExprCondition("in_test_fence(lat, lon) && speed > 10")
+	require.NoError(t, err)
+	assert.True(t, cond.Evaluate(map[string]any{"lat": 30.5, "lon": 120.5, "speed": 20}))
+	assert.False(t, cond.Evaluate(map[string]any{"lat": 40.0, "lon": 120.5, "speed": 20}))
+
+	RegisterConditionFunction("is_null", func(args ...any) any { return true })
+	defer func() {
+		conditionFuncsMu.Lock()
+		delete(conditionFuncs, "is_null")
+		conditionFuncsMu.Unlock()
+	}()
+	cond, err = NewExprCondition("is_null(name)")
+	require.NoError(t, err)
+	assert.True(t, cond.Evaluate(map[string]any{"name": "x"}), "同名注册覆盖内置函数")
+
+	RegisterConditionFunction("test_panics", func(args ...any) any { panic("boom") })
+	cond, err = NewExprCondition("test_panics()")
+	require.NoError(t, err)
+	ok, err := cond.(ErrorCondition).EvaluateWithError(map[string]any{})
+	assert.False(t, ok)
+	assert.Error(t, err)
+}
+
+// TestRegisterConditionFunction_Concurrent 测试注册与编译并发执行（配合 -race）
+func TestRegisterConditionFunction_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			RegisterConditionFunction(fmt.Sprintf("test_concurrent_%d", i), func(args ...any) any { return true })
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := NewExprCondition("age > 1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
@@ -50,6 +50,17 @@ Built-in SQL-compatible functions:
 	is_null(value) - Check if value is NULL
 	is_not_null(value) - Check if value is not NULL
 
+Applications can add their own boolean helpers, available to every condition
+compiled afterward (and accepted by the SQL parser in WHERE and HAVING):
+
+	condition.RegisterConditionFunction("in_geofence", func(args ...any) any {
+		return fence.Contains(cast.ToFloat64(args[0]), cast.ToFloat64(args[1]))
+	})
+	// SELECT * FROM stream WHERE in_geofence(lat, lon)
+
+The registry is safe for concurrent registration and compilation; registered
+functions are called concurrently by every stream using them.
+
 # Usage Examples
 
 Basic condition evaluation:
@@ -0,0 +1,73 @@
+package condition
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/expr-lang/expr"
+)
+
+// conditionFuncs holds the user-registered condition functions. It is read
+// on every NewExprCondition and written by RegisterConditionFunction, which
+// may run concurrently (e.g. several queries compiling their WHERE while a
+// plugin registers a helper), so all access goes through conditionFuncsMu.
+var (
+	conditionFuncsMu sync.RWMutex
+	conditionFuncs   = make(map[string]func(args ...any) any)
+)
+
+// RegisterConditionFunction makes fn callable by name from every condition
+// compiled afterward (WHERE, HAVING, OVER ... WHEN), e.g.
+//
+//	condition.RegisterConditionFunction("in_geofence", func(args ...any) any {
+//		lat, _ := args[0].(float64)
+//		lon, _ := args[1].(float64)
+//		return fence.Contains(lat, lon)
+//	})
+//
+// and then `WHERE in_geofence(lat, lon)`. Registering an existing name
+// replaces it; a name taken by a built-in (like_match, is_null, a StreamSQL
+// function) is overridden for conditions. Conditions already compiled keep
+// the function they were compiled with. A nil fn or empty name is ignored.
+//
+// The registry is safe for concurrent use: registration may run while other
+// goroutines compile conditions. fn itself is called from every stream
+// evaluating such a condition, possibly concurrently, so it must be safe for
+// concurrent use; a panic in fn fails that evaluation as false.
+func RegisterConditionFunction(name string, fn func(args ...any) any) {
+	if name == "" || fn == nil {
+		return
+	}
+	conditionFuncsMu.Lock()
+	conditionFuncs[name] = fn
+	conditionFuncsMu.Unlock()
+}
+
+// IsConditionFunction reports whether name was registered with
+// RegisterConditionFunction.
+func IsConditionFunction(name string) bool {
+	conditionFuncsMu.RLock()
+	defer conditionFuncsMu.RUnlock()
+	_, ok := conditionFuncs[name]
+	return ok
+}
+
+// registeredFunctionOptions returns the expr options exposing the registered
+// condition functions, in name order for deterministic compilation.
+func registeredFunctionOptions() []expr.Option {
+	conditionFuncsMu.RLock()
+	defer conditionFuncsMu.RUnlock()
+	names := make([]string, 0, len(conditionFuncs))
+	for name := range conditionFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	options := make([]expr.Option, 0, len(names))
+	for _, name := range names {
+		fn := conditionFuncs[name]
+		options = append(options, expr.Function(name, func(params ...any) (any, error) {
+			return fn(params...), nil
+		}))
+	}
+	return options
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package streamsql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// Encoder serializes one result batch for AddWriterSink. The returned bytes are
+// written to the writer as-is, so they must carry their own framing (trailing
+// newlines, length prefixes, ...). Encoders may keep state across batches
+// (e.g. CSVEncoder writes its header once), so give each writer its own
+// instance.
+type Encoder interface {
+	Encode(rows []map[string]interface{}) ([]byte, error)
+}
+
+// CSVEncoder writes result rows as CSV records, one line per row. The header
+// line is written before the first record unless NoHeader is set.
+//
+// Columns fixes the column order; when empty it is taken, sorted, from the keys
+// of the first row encoded and then kept for the encoder's lifetime, so later
+// rows with other keys are written with empty cells for the missing columns
+// and their extra keys dropped. NULL is written as an empty cell, time.Time as
+// RFC3339, nested maps and slices as JSON text.
+type CSVEncoder struct {
+	Columns  []string
+	NoHeader bool
+
+	headerDone bool
+}
+
+// NewCSVEncoder creates a CSV encoder with the given column order (see
+// CSVEncoder.Columns).
+func NewCSVEncoder(columns ...string) *CSVEncoder {
+	return &CSVEncoder{Columns: columns}
+}
+
+// Encode implements Encoder.
+func (e *CSVEncoder) Encode(rows []map[string]interface{}) ([]byte, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	if len(e.Columns) == 0 {
+		e.Columns = make([]string, 0, len(rows[0]))
+		for k := range rows[0] {
+			e.Columns = append(e.Columns, k)
+		}
+		sort.Strings(e.Columns)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if !e.NoHeader && !e.headerDone {
+		if err := w.Write(e.Columns); err != nil {
+			return nil, err
+		}
+	}
+	record := make([]string, len(e.Columns))
+	for _, row := range rows {
+		for i, col := range e.Columns {
+			cell, err := csvCell(row[col])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col, err)
+			}
+			record[i] = cell
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	e.headerDone = true
+	return buf.Bytes(), nil
+}
+
+func csvCell(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}, []map[string]interface{}:
+		enc := jsonEncoders.Get().(*jsonBatchEncoder)
+		defer jsonEncoders.Put(enc)
+		b, err := enc.encode(x)
+		return string(b), err
+	}
+	return cast.ToString(v), nil
+}
+
+// NDJSONEncoder writes each result row as one JSON object followed by a
+// newline (newline-delimited JSON), encoded like AddJSONSink.
+type NDJSONEncoder struct{}
+
+// NewNDJSONEncoder creates a newline-delimited JSON encoder.
+func NewNDJSONEncoder() *NDJSONEncoder {
+	return &NDJSONEncoder{}
+}
+
+// Encode implements Encoder.
+func (NDJSONEncoder) Encode(rows []map[string]interface{}) ([]byte, error) {
+	enc := jsonEncoders.Get().(*jsonBatchEncoder)
+	defer jsonEncoders.Put(enc)
+	var out []byte
+	for _, row := range rows {
+		b, err := enc.encode(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// BinaryEncoder frames each result batch as a 4-byte big-endian payload length
+// followed by the payload, so a reader can split a byte stream back into
+// batches. Payload encodes the batch; nil uses a JSON array as AddJSONSink does.
+type BinaryEncoder struct {
+	Payload Encoder
+}
+
+// NewBinaryEncoder creates a length-prefixed encoder around payload (nil for a
+// JSON array).
+func NewBinaryEncoder(payload Encoder) *BinaryEncoder {
+	return &BinaryEncoder{Payload: payload}
+}
+
+// Encode implements Encoder.
+func (e *BinaryEncoder) Encode(rows []map[string]interface{}) ([]byte, error) {
+	var payload []byte
+	if e.Payload != nil {
+		b, err := e.Payload.Encode(rows)
+		if err != nil {
+			return nil, err
+		}
+		payload = b
+	} else {
+		enc := jsonEncoders.Get().(*jsonBatchEncoder)
+		defer jsonEncoders.Put(enc)
+		b, err := enc.encode(rows)
+		if err != nil {
+			return nil, err
+		}
+		payload = b
+	}
+	if uint64(len(payload)) > uint64(^uint32(0)) {
+		return nil, fmt.Errorf("batch of %d bytes exceeds the 4-byte length prefix", len(payload))
+	}
+	out := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(out, uint32(len(payload)))
+	return append(out, payload...), nil
+}
+
+// ReadBinaryFrame reads one batch payload written by BinaryEncoder from r. It
+// returns io.EOF when r is exhausted at a frame boundary.
+func ReadBinaryFrame(r io.Reader) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(prefix[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// AddWriterSink writes every result batch to w, serialized by encoder — e.g.
+// NewCSVEncoder, NewNDJSONEncoder or NewBinaryEncoder, or any custom Encoder —
+// giving one extension point for output formats. Batches are encoded and
+// written one at a time in emission order (the sink runs as a sync sink, see
+// AddSyncSink), so a slow writer slows result delivery; wrap w in a
+// bufio.Writer and flush it as needed. Empty batches are not written. Encoding
+// and write errors are logged and the batch is skipped.
+//
+// Example:
+//
+//	f, _ := os.Create("out.csv")
+//	ssql.AddWriterSink(f, streamsql.NewCSVEncoder("deviceId", "avg_temp"))
+func (s *Streamsql) AddWriterSink(w io.Writer, encoder Encoder) {
+	if w == nil || encoder == nil {
+		return
+	}
+	var mu sync.Mutex
+	s.AddSyncSink(func(results []map[string]interface{}) {
+		if len(results) == 0 {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		b, err := encoder.Encode(results)
+		if err != nil {
+			s.log.Error("AddWriterSink: failed to encode result batch: %v", err)
+			return
+		}
+		if len(b) == 0 {
+			return
+		}
+		if _, err := w.Write(b); err != nil {
+			s.log.Error("AddWriterSink: failed to write result batch: %v", err)
+		}
+	})
+}
+
+// AddCSVSink writes result rows to w as CSV through AddWriterSink and a
+// CSVEncoder: the header once, then one line per row, with missing fields as
+// empty cells and values containing commas, quotes or newlines quoted. fields
+// fixes the column order; when nil the SELECT order of the executed query is
+// used (for SELECT * the sorted keys of the first row). When w has a
+// Flush() error method (e.g. bufio.Writer) it is flushed after each batch.
+//
+// Must be called after Execute when fields is nil.
+//
+// Example:
+//
+//	f, _ := os.Create("out.csv")
+//	ssql.AddCSVSink(bufio.NewWriter(f), nil)
+func (s *Streamsql) AddCSVSink(w io.Writer, fields []string) {
+	if w == nil {
+		return
+	}
+	if fields == nil {
+		s.schemaMu.Lock()
+		fieldOrder := s.fieldOrder
+		s.schemaMu.Unlock()
+		for _, f := range fieldOrder {
+			if f == "*" || strings.HasSuffix(f, ".*") {
+				fieldOrder = nil
+				break
+			}
+		}
+		fields = fieldOrder
+	}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		w = flushWriter{w: w, flush: f.Flush}
+	}
+	s.AddWriterSink(w, NewCSVEncoder(append([]string(nil), fields...)...))
+}
+
+// flushWriter flushes the wrapped writer after every write.
+type flushWriter struct {
+	w     io.Writer
+	flush func() error
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.flush()
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package streamsql
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	enc := NewCSVEncoder()
+	ts := time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC)
+	b, err := enc.Encode([]map[string]any{
+		{"device": "a,1", "v": 1.5, "ts": ts},
+		{"device": "b", "v": nil, "ts": ts, "extra": 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "device,ts,v\n\"a,1\",2025-03-01T08:30:00Z,1.5\nb,2025-03-01T08:30:00Z,\n", string(b))
+
+	// 表头只写一次，列顺序沿用首批
+	b, err = enc.Encode([]map[string]any{{"v": 2, "device": "c", "tags": []any{"x"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "c,,2\n", string(b))
+
+	b, err = NewCSVEncoder("tags", "device").Encode([]map[string]any{{"device": "c", "tags": []any{"x", 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, "tags,device\n\"[\"\"x\"\",1]\",c\n", string(b))
+
+	b, err = (&CSVEncoder{Columns: []string{"v"}, NoHeader: true}).Encode([]map[string]any{{"v": 3}})
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", string(b))
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	b, err := NewNDJSONEncoder().Encode([]map[string]any{{"a": 1}, {"a": "x<y"}})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"a\":\"x<y\"}\n", string(b))
+}
+
+func TestBinaryEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewBinaryEncoder(nil)
+	for _, batch := range [][]map[string]any{{{"a": 1}}, {{"a": 2}, {"a": 3}}} {
+		b, err := enc.Encode(batch)
+		require.NoError(t, err)
+		buf.Write(b)
+	}
+	csvFramed, err := NewBinaryEncoder(NewCSVEncoder("a")).Encode([]map[string]any{{"a": 4}})
+	require.NoError(t, err)
+	buf.Write(csvFramed)
+
+	var frames []string
+	for {
+		p, err := ReadBinaryFrame(&buf)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		frames = append(frames, string(p))
+	}
+	assert.Equal(t, []string{`[{"a":1}]`, `[{"a":2},{"a":3}]`, "a\n4\n"}, frames)
+
+	_, err = ReadBinaryFrame(bytes.NewReader([]byte{0, 0, 0, 5, '[', ']'}))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// lockedBuffer 供 sink 写、测试读的并发安全缓冲区
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamSQLAddWriterSink(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT device, v FROM stream"))
+	var csvOut, ndjsonOut lockedBuffer
+	ssql.AddWriterSink(&csvOut, NewCSVEncoder("device", "v"))
+	ssql.AddWriterSink(&ndjsonOut, NewNDJSONEncoder())
+
+	for i, device := range []string{"a", "b", "c"} {
+		_, err := ssql.EmitSync(map[string]any{"device": device, "v": i})
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		return strings.Count(ndjsonOut.String(), "\n") == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "device,v\na,0\nb,1\nc,2\n", csvOut.String())
+	assert.Equal(t, "{\"device\":\"a\",\"v\":0}\n{\"device\":\"b\",\"v\":1}\n{\"device\":\"c\",\"v\":2}\n", ndjsonOut.String())
+}
+
+func TestStreamSQLAddCSVSink(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT device, note AS n, v FROM stream"))
+	var out lockedBuffer
+	// fields 为 nil 时按 SELECT 顺序；bufio.Writer 每批后自动 Flush
+	ssql.AddCSVSink(bufio.NewWriter(&out), nil)
+
+	_, err := ssql.EmitSync(map[string]any{"device": "a", "note": "x,y", "v": 1})
+	require.NoError(t, err)
+	_, err = ssql.EmitSync(map[string]any{"device": "b", "note": "line1\nline2"})
+	require.NoError(t, err)
+	want := "device,n,v\na,\"x,y\",1\nb,\"line1\nline2\",\n"
+	require.Eventually(t, func() bool { return out.String() == want }, 2*time.Second, 10*time.Millisecond, out.String())
+}
@@ -299,17 +299,14 @@ func registerAggregateFunctions() {
 	functions.Register(NewGeometricMeanFunction())
 	functions.RegisterAggregatorAdapter("geometric_mean")
 
-	// 注册众数聚合函数到functions模块
-	functions.Register(NewModeFunction())
-	functions.RegisterAggregatorAdapter("mode_agg")
+	// 注册众数聚合函数：只需实现分组状态，引擎为每个分组、每个窗口调用工厂创建新状态
+	functions.Register(functions.NewStatefulAggregator("mode_agg", "统计聚合", "计算众数", 1, -1,
+		func() functions.AggregatorState { return &modeState{counts: make(map[string]int)} }))
 
 	// 保留原有的aggregator注册用于兼容性
 	aggregator.Register("geometric_mean", func() aggregator.AggregatorFunction {
 		return &GeometricMeanAggregator{}
 	})
-	aggregator.Register("mode_agg", func() aggregator.AggregatorFunction {
-		return &ModeAggregator{}
-	})
 
 	fmt.Println("  ✓ 聚合函数: geometric_mean, mode_agg")
 }
@@ -446,73 +443,24 @@ func (g *GeometricMeanAggregator) Result() any {
 	return math.Pow(product, 1.0/float64(len(g.values)))
 }
 
-// 众数聚合函数
-type ModeFunction struct {
-	*functions.BaseFunction
+// 众数聚合函数的分组状态
+type modeState struct {
 	counts map[string]int
 }
 
-func NewModeFunction() *ModeFunction {
-	return &ModeFunction{
-		BaseFunction: functions.NewBaseFunction(
-			"mode_agg",
-			functions.TypeAggregation,
-			"统计聚合",
-			"计算众数",
-			1, -1,
-		),
-		counts: make(map[string]int),
-	}
-}
-
-func (f *ModeFunction) Validate(args []any) error {
-	return f.ValidateArgCount(args)
-}
-
-func (f *ModeFunction) Execute(ctx *functions.FunctionContext, args []any) (any, error) {
-	// 批量执行模式
-	counts := make(map[string]int)
-	for _, arg := range args {
-		key := fmt.Sprintf("%v", arg)
-		counts[key]++
-	}
-
-	if len(counts) == 0 {
-		return nil, nil
-	}
-
-	maxCount := 0
-	var mode any
-	for key, count := range counts {
-		if count > maxCount {
-			maxCount = count
-			mode = key
-		}
-	}
-	return mode, nil
-}
-
-// 实现AggregatorFunction接口以支持增量计算
-func (f *ModeFunction) New() functions.AggregatorFunction {
-	return &ModeFunction{
-		BaseFunction: f.BaseFunction,
-		counts:       make(map[string]int),
-	}
-}
-
-func (f *ModeFunction) Add(value any) {
+func (m *modeState) Add(value any) {
 	key := fmt.Sprintf("%v", value)
-	f.counts[key]++
+	m.counts[key]++
 }
 
-func (f *ModeFunction) Result() any {
-	if len(f.counts) == 0 {
+func (m *modeState) Result() any {
+	if len(m.counts) == 0 {
 		return nil
 	}
 
 	maxCount := 0
 	var mode any
-	for key, count := range f.counts {
+	for key, count := range m.counts {
 		if count > maxCount {
 			maxCount = count
 			mode = key
@@ -521,53 +469,14 @@ func (f *ModeFunction) Result() any {
 	return mode
 }
 
-func (f *ModeFunction) Reset() {
-	f.counts = make(map[string]int)
-}
-
-func (f *ModeFunction) Clone() functions.AggregatorFunction {
-	clone := &ModeFunction{
-		BaseFunction: f.BaseFunction,
-		counts:       make(map[string]int),
-	}
-	for k, v := range f.counts {
+func (m *modeState) Clone() functions.AggregatorState {
+	clone := &modeState{counts: make(map[string]int, len(m.counts))}
+	for k, v := range m.counts {
 		clone.counts[k] = v
 	}
 	return clone
 }
 
-// 众数聚合器（保留用于兼容性）
-type ModeAggregator struct {
-	counts map[string]int
-}
-
-func (m *ModeAggregator) New() aggregator.AggregatorFunction {
-	return &ModeAggregator{
-		counts: make(map[string]int),
-	}
-}
-
-func (m *ModeAggregator) Add(value any) {
-	key := fmt.Sprintf("%v", value)
-	m.counts[key]++
-}
-
-func (m *ModeAggregator) Result() any {
-	if len(m.counts) == 0 {
-		return nil
-	}
-
-	maxCount := 0
-	var mode any
-	for key, count := range m.counts {
-		if count > maxCount {
-			maxCount = count
-			mode = key
-		}
-	}
-	return mode
-}
-
 // 演示自定义函数在SQL中的使用
 func demonstrateCustomFunctions() {
 	fmt.Println("\n🎯 演示自定义函数在SQL中的使用")
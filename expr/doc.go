@@ -24,7 +24,7 @@ It serves as the foundation for WHERE clauses, HAVING clauses, and computed fiel
 # Core Features
 
 • Mathematical Operations - Supports arithmetic operators (+, -, *, /, %, ^) with proper precedence
-• Logical Operations - Boolean logic with AND, OR operators and comparison operators (=, !=, <, >, <=, >=, LIKE)
+• Logical Operations - Boolean logic with AND, OR operators and comparison operators (=, !=, <, >, <=, >=, LIKE, [NOT] BETWEEN ... AND ..., [NOT] IN (...))
 • Function Integration - Seamless integration with the functions package for built-in and custom functions
 • Field References - Dynamic field access with dot notation support for nested data structures
 • CASE Expressions - Full support for both simple and searched CASE expressions
@@ -84,9 +84,11 @@ The expression parser follows standard mathematical precedence rules:
  3. Multiplication, Division, Modulo (*, /, %)
  4. Addition, Subtraction (+, -)
  5. Comparison (>, <, >=, <=, LIKE, IS)
- 6. Equality (=, ==, !=, <>)
- 7. Logical AND
- 8. Logical OR (lowest)
+ 6. Range and membership ([NOT] BETWEEN low AND high, bounds inclusive; [NOT] IN (a, b, ...);
+    NULL operands yield false)
+ 7. Equality (=, ==, !=, <>)
+ 8. Logical AND
+ 9. Logical OR (lowest)
 
 # Error Handling
 
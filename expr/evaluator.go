@@ -98,6 +98,14 @@ func evaluateFieldNode(node *ExprNode, data map[string]any) (float64, error) {
 
 // evaluateOperatorNode evaluates the value of an operator node
 func evaluateOperatorNode(node *ExprNode, data map[string]any) (float64, error) {
+	if isBetweenOperator(node.Value) || isInOperator(node.Value) {
+		result, err := evaluateRangeOperator(node, data)
+		if err != nil || !result {
+			return 0, err
+		}
+		return 1.0, nil
+	}
+
 	// Check if it's a comparison operator
 	if isComparisonOperator(node.Value) {
 		// For comparison operators, use evaluateNodeValue to get original type
@@ -183,29 +191,7 @@ func evaluateFunctionNode(node *ExprNode, data map[string]any) (float64, error)
 		return 0, fmt.Errorf("unknown function: %s", node.Value)
 	}
 
-	// Calculate all arguments but keep original types
-	args := make([]any, len(node.Args))
-	for i, arg := range node.Args {
-		// Use evaluateNodeValue to get original type values
-		val, err := evaluateNodeValue(arg, data)
-		if err != nil {
-			return 0, err
-		}
-		args[i] = val
-	}
-
-	// Validate arguments
-	if err := fn.Validate(args); err != nil {
-		return 0, err
-	}
-
-	// Create function execution context
-	ctx := &functions.FunctionContext{
-		Data: data,
-	}
-
-	// Execute function
-	result, err := fn.Execute(ctx, args)
+	result, err := callFunction(fn, node, data)
 	if err != nil {
 		return 0, err
 	}
@@ -307,6 +293,9 @@ func evaluateOperatorValue(node *ExprNode, data map[string]any) (any, error) {
 	if operator == "IS" || operator == "IS NOT" {
 		return evaluateIsOperator(node, data)
 	}
+	if isBetweenOperator(operator) || isInOperator(operator) {
+		return evaluateRangeOperator(node, data)
+	}
 
 	// Check if it's a logical operator
 	if isLogicalOperator(node.Value) {
@@ -403,6 +392,37 @@ func evaluateFunctionValue(node *ExprNode, data map[string]any) (any, error) {
 		return nil, fmt.Errorf("unknown function: %s", node.Value)
 	}
 
+	return callFunction(fn, node, data)
+}
+
+// callFunction evaluates the arguments of a function node and executes fn.
+// A LazyFunction (COALESCE, NULLIF) receives its arguments unevaluated and
+// evaluates only those it needs, with missing fields as NULL.
+func callFunction(fn functions.Function, node *ExprNode, data map[string]any) (any, error) {
+	// Create function execution context
+	ctx := &functions.FunctionContext{
+		Data: data,
+	}
+
+	if lazy, ok := fn.(functions.LazyFunction); ok {
+		// Argument count validation only; the values are not known yet
+		if err := fn.Validate(make([]any, len(node.Args))); err != nil {
+			return nil, err
+		}
+		args := make([]functions.LazyArg, len(node.Args))
+		for i, arg := range node.Args {
+			arg := arg
+			args[i] = func() (any, error) {
+				val, isNull, err := evaluateNodeValueWithNull(arg, data)
+				if err != nil || isNull {
+					return nil, err
+				}
+				return val, nil
+			}
+		}
+		return lazy.ExecuteLazy(ctx, args)
+	}
+
 	// Calculate all arguments but keep original types
 	args := make([]any, len(node.Args))
 	for i, arg := range node.Args {
@@ -418,11 +438,6 @@ func evaluateFunctionValue(node *ExprNode, data map[string]any) (any, error) {
 		return nil, err
 	}
 
-	// Create function execution context
-	ctx := &functions.FunctionContext{
-		Data: data,
-	}
-
 	// Execute function
 	return fn.Execute(ctx, args)
 }
@@ -607,6 +622,14 @@ func evaluateNodeWithNull(node *ExprNode, data map[string]any) (float64, bool, e
 		return 0, false, fmt.Errorf("field '%s' is not a number", fieldName)
 
 	case TypeOperator:
+		if isBetweenOperator(node.Value) || isInOperator(node.Value) {
+			result, err := evaluateRangeOperator(node, data)
+			if err != nil || !result {
+				return 0, false, err
+			}
+			return 1, false, nil
+		}
+
 		// For comparison operators, return boolean converted to numeric
 		if isComparisonOperator(node.Value) {
 			leftValue, leftIsNull, err := evaluateNodeValueWithNull(node.Left, data)
@@ -882,6 +905,12 @@ func evaluateBoolOperator(node *ExprNode, data map[string]any) (bool, error) {
 		}
 		return convertToBool(result), nil
 
+	case "BETWEEN", "NOT BETWEEN":
+		return evaluateBetween(node, data)
+
+	case "IN", "NOT IN":
+		return evaluateIn(node, data)
+
 	case "==", "=", "!=", "<>", ">", "<", ">=", "<=", "LIKE":
 		// Comparison operators
 		leftValue, err := evaluateNodeValue(node.Left, data)
@@ -954,3 +983,73 @@ func evaluateIsOperator(node *ExprNode, data map[string]any) (any, error) {
 
 	return nil, fmt.Errorf("unsupported IS operator: %s", operator)
 }
+
+// evaluateRangeOperator evaluates the BETWEEN and IN operator families
+func evaluateRangeOperator(node *ExprNode, data map[string]any) (bool, error) {
+	if isInOperator(node.Value) {
+		return evaluateIn(node, data)
+	}
+	return evaluateBetween(node, data)
+}
+
+// evaluateIn evaluates x IN (a, b, ...) and its negation with the equality
+// rules of =. A NULL or missing operand makes both forms false; NULL list
+// elements match nothing. An empty list is false for IN and true for NOT IN.
+func evaluateIn(node *ExprNode, data map[string]any) (bool, error) {
+	negate := strings.EqualFold(node.Value, "NOT IN")
+	if len(node.Args) == 0 {
+		return negate, nil
+	}
+	val, isNull, err := evaluateNodeValueWithNull(node.Left, data)
+	if err != nil {
+		return false, err
+	}
+	if isNull {
+		return false, nil
+	}
+	for _, arg := range node.Args {
+		elem, elemIsNull, err := evaluateNodeValueWithNull(arg, data)
+		if err != nil {
+			return false, err
+		}
+		if !elemIsNull && compareValuesForEquality(val, elem) {
+			return !negate, nil
+		}
+	}
+	return negate, nil
+}
+
+// evaluateBetween evaluates x BETWEEN low AND high (bounds inclusive) and its
+// negation, comparing numerically or as strings like the other comparisons.
+// A NULL or missing operand or bound makes both forms false.
+func evaluateBetween(node *ExprNode, data map[string]any) (bool, error) {
+	if node.Right == nil || node.Right.Left == nil || node.Right.Right == nil {
+		return false, fmt.Errorf("%s operator requires low and high bounds", node.Value)
+	}
+	operands := [3]*ExprNode{node.Left, node.Right.Left, node.Right.Right}
+	var values [3]any
+	for i, operand := range operands {
+		val, isNull, err := evaluateNodeValueWithNull(operand, data)
+		if err != nil {
+			return false, err
+		}
+		if isNull {
+			return false, nil
+		}
+		values[i] = val
+	}
+
+	geLow, err := compareValues(values[0], values[1], ">=")
+	if err != nil {
+		return false, err
+	}
+	leHigh, err := compareValues(values[0], values[2], "<=")
+	if err != nil {
+		return false, err
+	}
+	in := geLow && leHigh
+	if strings.EqualFold(node.Value, "NOT BETWEEN") {
+		return !in, nil
+	}
+	return in, nil
+}
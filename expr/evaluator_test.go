@@ -869,6 +869,13 @@ func TestEvaluateFunctionNode(t *testing.T) {
 		{"参数数量错误", "abs", []*ExprNode{}, 0, true},
 		{"SQRT负数", "sqrt", []*ExprNode{{Type: TypeNumber, Value: "-1"}}, 0, true},
 		{"LOG零或负数", "log", []*ExprNode{{Type: TypeNumber, Value: "0"}}, 0, true},
+		// COALESCE/NULLIF 短路：不需要的参数不求值，缺失字段视为 NULL
+		{"COALESCE短路", "coalesce", []*ExprNode{{Type: TypeNumber, Value: "7"}, {Type: TypeFunction, Value: "sqrt", Args: []*ExprNode{{Type: TypeNumber, Value: "-1"}}}}, 7.0, false},
+		{"COALESCE缺失字段", "coalesce", []*ExprNode{{Type: TypeField, Value: "missing"}, {Type: TypeNumber, Value: "3"}}, 3.0, false},
+		{"COALESCE求值到出错参数", "coalesce", []*ExprNode{{Type: TypeField, Value: "missing"}, {Type: TypeFunction, Value: "sqrt", Args: []*ExprNode{{Type: TypeNumber, Value: "-1"}}}}, 0, true},
+		{"COALESCE无参数", "coalesce", []*ExprNode{}, 0, true},
+		{"NULLIF不相等", "nullif", []*ExprNode{{Type: TypeNumber, Value: "5"}, {Type: TypeNumber, Value: "0"}}, 5.0, false},
+		{"NULLIF首参为NULL不求值第二参", "coalesce", []*ExprNode{{Type: TypeFunction, Value: "nullif", Args: []*ExprNode{{Type: TypeField, Value: "missing"}, {Type: TypeFunction, Value: "sqrt", Args: []*ExprNode{{Type: TypeNumber, Value: "-1"}}}}}, {Type: TypeNumber, Value: "1"}}, 1.0, false},
 	}
 
 	for _, tt := range tests {
@@ -1141,6 +1141,117 @@ func TestEvaluateBool(t *testing.T) {
 	}
 }
 
+// TestBetweenOperator BETWEEN / NOT BETWEEN：闭区间、数值与字符串比较、嵌套字段、NULL 为 false
+func TestBetweenOperator(t *testing.T) {
+	data := map[string]any{
+		"temperature": 25,
+		"name":        "bob",
+		"sensor":      map[string]any{"temperature": 31.5},
+		"missing":     nil,
+	}
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"temperature BETWEEN 20 AND 30", true},
+		{"temperature BETWEEN 25 AND 25", true},
+		{"temperature between 26 and 30", false},
+		{"temperature NOT BETWEEN 20 AND 30", false},
+		{"temperature BETWEEN 10 + 10 AND 15 * 2", true},
+		{"temperature BETWEEN 20 AND 30 AND name = 'bob'", true},
+		{"temperature BETWEEN 26 AND 30 OR name BETWEEN 'a' AND 'c'", true},
+		{"name BETWEEN 'c' AND 'z'", false},
+		{"sensor.temperature BETWEEN 20 AND 30", false},
+		{"sensor.temperature NOT BETWEEN 20 AND 30", true},
+		{"missing BETWEEN 20 AND 30", false},
+		{"missing NOT BETWEEN 20 AND 30", false},
+		{"temperature NOT BETWEEN missing AND 30", false},
+		{"nothing BETWEEN 20 AND 30", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := NewExpression(tt.expr)
+			require.NoError(t, err)
+			require.False(t, e.useExprLang, "应由自定义解析器处理")
+			got, err := e.EvaluateBool(data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+
+			v, isNull, err := e.EvaluateValueWithNull(data)
+			require.NoError(t, err)
+			assert.False(t, isNull)
+			if e.Root.Type == TypeOperator && isBetweenOperator(e.Root.Value) {
+				assert.Equal(t, tt.expected, v)
+			}
+		})
+	}
+
+	require.NoError(t, ValidateExpression("temperature NOT BETWEEN 20 AND 30"))
+	e, err := NewExpression("sensor.temperature BETWEEN low AND high")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"high", "low", "sensor.temperature"}, e.GetFields())
+
+	_, err = ParseExpression([]string{"temperature", "BETWEEN", "20", "OR", "30"})
+	assert.Error(t, err)
+}
+
+// TestInOperator IN / NOT IN：数值与字符串按等值规则比较、混合列表、空列表、NULL 为 false
+func TestInOperator(t *testing.T) {
+	data := map[string]any{
+		"deviceId": "b",
+		"status":   2,
+		"code":     "2",
+		"sensor":   map[string]any{"level": 3.0},
+		"missing":  nil,
+	}
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"deviceId IN ('a', 'b', 'c')", true},
+		{"deviceId in ('x')", false},
+		{"deviceId NOT IN ('a', 'c')", true},
+		{"status IN (1, 2)", true},
+		{"status NOT IN (1, 2)", false},
+		{"status IN (2.0)", true},
+		{"code IN (1, 2)", true},
+		{"status IN ('a', 2)", true},
+		{"status IN (1 + 1, 5)", true},
+		{"sensor.level IN (3, 4)", true},
+		{"deviceId IN ()", false},
+		{"deviceId NOT IN ()", true},
+		{"missing IN (1, 2)", false},
+		{"missing NOT IN (1, 2)", false},
+		{"status NOT IN (missing, 1)", true},
+		{"status IN (1, 2) AND deviceId NOT IN ('a')", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := NewExpression(tt.expr)
+			require.NoError(t, err)
+			require.False(t, e.useExprLang, "应由自定义解析器处理")
+			got, err := e.EvaluateBool(data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+
+			v, isNull, err := e.EvaluateValueWithNull(data)
+			require.NoError(t, err)
+			assert.False(t, isNull)
+			if e.Root.Type == TypeOperator && isInOperator(e.Root.Value) {
+				assert.Equal(t, tt.expected, v)
+			}
+		})
+	}
+
+	require.NoError(t, ValidateExpression("status NOT IN (1, 2)"))
+	e, err := NewExpression("status IN (low, 2)")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"low", "status"}, e.GetFields())
+
+	_, err = ParseExpression([]string{"status", "IN", "(", "1", "2", ")"})
+	assert.Error(t, err)
+}
+
 func TestEvaluateValueWithNull(t *testing.T) {
 	tests := []struct {
 		name        string
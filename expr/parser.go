@@ -113,6 +113,23 @@ func parseComparisonExpression(tokens []string) (*ExprNode, []string, error) {
 		}, newRemaining, nil
 	}
 
+	// Check BETWEEN / NOT BETWEEN: x BETWEEN low AND high. The bounds are
+	// arithmetic expressions, so the AND here binds tighter than logical AND.
+	if len(remaining) >= 1 && strings.EqualFold(remaining[0], "BETWEEN") {
+		return parseBetweenExpression(left, "BETWEEN", remaining[1:])
+	}
+	if len(remaining) >= 2 && strings.EqualFold(remaining[0], "NOT") && strings.EqualFold(remaining[1], "BETWEEN") {
+		return parseBetweenExpression(left, "NOT BETWEEN", remaining[2:])
+	}
+
+	// Check IN / NOT IN: x IN (a, b, ...)
+	if len(remaining) >= 2 && strings.EqualFold(remaining[0], "IN") && remaining[1] == "(" {
+		return parseInExpression(left, "IN", remaining[2:])
+	}
+	if len(remaining) >= 3 && strings.EqualFold(remaining[0], "NOT") && strings.EqualFold(remaining[1], "IN") && remaining[2] == "(" {
+		return parseInExpression(left, "NOT IN", remaining[3:])
+	}
+
 	// Check single token comparison operators
 	if len(remaining) > 0 && isComparisonOperator(remaining[0]) {
 		op := remaining[0]
@@ -132,6 +149,68 @@ func parseComparisonExpression(tokens []string) (*ExprNode, []string, error) {
 	return left, remaining, nil
 }
 
+// parseBetweenExpression parses the bounds of a BETWEEN operator. The node
+// keeps the operand in Left and the bounds in an AND node in Right.
+func parseBetweenExpression(operand *ExprNode, op string, tokens []string) (*ExprNode, []string, error) {
+	low, remaining, err := parseArithmeticExpression(tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(remaining) == 0 || (!strings.EqualFold(remaining[0], "AND") && remaining[0] != "&&") {
+		return nil, nil, fmt.Errorf("%s requires AND between the bounds", op)
+	}
+	high, remaining, err := parseArithmeticExpression(remaining[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ExprNode{
+		Type:  TypeOperator,
+		Value: op,
+		Left:  operand,
+		Right: &ExprNode{
+			Type:  TypeOperator,
+			Value: "AND",
+			Left:  low,
+			Right: high,
+		},
+	}, remaining, nil
+}
+
+// parseInExpression parses the list of an IN operator after its opening
+// parenthesis. The node keeps the operand in Left and the list elements in
+// Args; the list may be empty.
+func parseInExpression(operand *ExprNode, op string, tokens []string) (*ExprNode, []string, error) {
+	node := &ExprNode{
+		Type:  TypeOperator,
+		Value: op,
+		Left:  operand,
+		Args:  []*ExprNode{},
+	}
+	remaining := tokens
+	if len(remaining) > 0 && remaining[0] == ")" {
+		return node, remaining[1:], nil
+	}
+	for {
+		elem, rest, err := parseArithmeticExpression(remaining)
+		if err != nil {
+			return nil, nil, err
+		}
+		node.Args = append(node.Args, elem)
+		if len(rest) == 0 {
+			return nil, nil, fmt.Errorf("%s list is missing its closing parenthesis", op)
+		}
+		switch rest[0] {
+		case ")":
+			return node, rest[1:], nil
+		case ",":
+			remaining = rest[1:]
+		default:
+			return nil, nil, fmt.Errorf("unexpected token %s in %s list", rest[0], op)
+		}
+	}
+}
+
 // parseArithmeticExpression parses arithmetic expression
 func parseArithmeticExpression(tokens []string) (*ExprNode, []string, error) {
 	left, remaining, err := parseTermExpression(tokens)
@@ -0,0 +1,183 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrEvaluationTimeout is returned (wrapped) by EvaluateWithTimeout when an
+// evaluation does not finish before its deadline, or when too many timed-out
+// evaluations are still running to start another one.
+var ErrEvaluationTimeout = errors.New("expression evaluation timed out")
+
+// MaxAbandonedEvaluations caps the timed-out evaluations that may still be
+// running in the background. Once reached, EvaluateWithTimeout fails fast
+// instead of handing out another evaluation, so a function that never returns
+// cannot tie up workers without bound.
+const MaxAbandonedEvaluations = 1024
+
+// maxActiveEvaluations caps the workers running evaluations somebody still
+// waits for. Callers beyond it wait for a free worker within their deadline.
+const maxActiveEvaluations = 256
+
+// evalWorkerIdle is how long an idle evaluation worker waits for the next job
+// before it exits.
+const evalWorkerIdle = 30 * time.Second
+
+// abandoned counts timed-out evaluations that have not returned yet.
+var abandoned int64
+
+// AbandonedEvaluations returns the number of timed-out evaluations still
+// running in the background.
+func AbandonedEvaluations() int64 {
+	return atomic.LoadInt64(&abandoned)
+}
+
+// Evaluation states shared by EvaluateContext and the worker running eval.
+const (
+	evalRunning int32 = iota
+	evalFinished
+	evalAbandoned
+)
+
+type evalOutcome struct {
+	value any
+	err   error
+}
+
+// evalJob is one evaluation handed to a worker.
+type evalJob struct {
+	eval  func() (any, error)
+	state int32
+	done  chan evalOutcome // buffered: a late eval must not block its worker
+}
+
+// run evaluates the job and delivers the outcome unless the caller gave up.
+func (j *evalJob) run() {
+	var out evalOutcome
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				out = evalOutcome{err: fmt.Errorf("expression evaluation panicked: %v", r)}
+			}
+		}()
+		out.value, out.err = j.eval()
+	}()
+	if atomic.CompareAndSwapInt32(&j.state, evalRunning, evalFinished) {
+		j.done <- out
+		return
+	}
+	atomic.AddInt64(&abandoned, -1) // the caller gave up on this eval
+}
+
+var (
+	// evalJobs hands jobs to idle workers; unbuffered, so a send succeeds only
+	// when a worker is waiting.
+	evalJobs = make(chan *evalJob)
+	// evalWorkers counts the running workers, idle or busy.
+	evalWorkers int64
+)
+
+// submitEval gives job to an idle worker, starting a new one while the pool is
+// below its cap, or waits for a worker to free up until ctx ends.
+func submitEval(ctx context.Context, job *evalJob) error {
+	for {
+		select {
+		case evalJobs <- job:
+			return nil
+		default:
+		}
+		n := atomic.LoadInt64(&evalWorkers)
+		if n >= maxActiveEvaluations+atomic.LoadInt64(&abandoned) {
+			break
+		}
+		if atomic.CompareAndSwapInt64(&evalWorkers, n, n+1) {
+			go evalWorker(job)
+			return nil
+		}
+	}
+	select {
+	case evalJobs <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// evalWorker runs job and then further jobs from evalJobs until it has been
+// idle for evalWorkerIdle.
+func evalWorker(job *evalJob) {
+	defer atomic.AddInt64(&evalWorkers, -1)
+	idle := time.NewTimer(evalWorkerIdle)
+	defer idle.Stop()
+	for {
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		job.run()
+		idle.Reset(evalWorkerIdle)
+		select {
+		case job = <-evalJobs:
+		case <-idle.C:
+			return
+		}
+	}
+}
+
+// EvaluateWithTimeout is EvaluateContext with a background context.
+func EvaluateWithTimeout(timeout time.Duration, eval func() (any, error)) (any, error) {
+	return EvaluateContext(context.Background(), timeout, eval)
+}
+
+// EvaluateContext runs eval under a deadline of timeout derived from ctx and
+// returns its result, or an error wrapping ErrEvaluationTimeout when the
+// deadline passes first. When ctx is canceled first the error wraps ctx.Err()
+// instead. timeout <= 0 runs eval inline with no limit.
+//
+// eval runs on a pooled worker goroutine, reused across calls, while the caller
+// waits. Go cannot preempt a running function, so a timed-out eval keeps its
+// worker until it returns and its result is discarded. The caller is
+// unblocked, which keeps a runaway custom function from stalling the pipeline.
+// eval must therefore not touch data the caller may reuse or modify after the
+// timeout (pass it a copy of the row), and at most MaxAbandonedEvaluations such
+// evals run at once.
+func EvaluateContext(ctx context.Context, timeout time.Duration, eval func() (any, error)) (any, error) {
+	if timeout <= 0 {
+		return eval()
+	}
+	if n := atomic.LoadInt64(&abandoned); n >= MaxAbandonedEvaluations {
+		return nil, fmt.Errorf("%w: %d earlier evaluations are still running", ErrEvaluationTimeout, n)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	job := &evalJob{eval: eval, done: make(chan evalOutcome, 1)}
+	if err := submitEval(ctx, job); err != nil {
+		return nil, evalContextError(err, timeout)
+	}
+	select {
+	case out := <-job.done:
+		return out.value, out.err
+	case <-ctx.Done():
+		if !atomic.CompareAndSwapInt32(&job.state, evalRunning, evalAbandoned) {
+			out := <-job.done // finished just as the deadline passed
+			return out.value, out.err
+		}
+		atomic.AddInt64(&abandoned, 1)
+		return nil, evalContextError(ctx.Err(), timeout)
+	}
+}
+
+// evalContextError reports why an evaluation's context ended.
+func evalContextError(err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", ErrEvaluationTimeout, timeout)
+	}
+	return fmt.Errorf("expression evaluation canceled: %w", err)
+}
@@ -0,0 +1,122 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEvaluateWithTimeout 测试带时限的求值
+func TestEvaluateWithTimeout(t *testing.T) {
+	t.Run("时限内完成", func(t *testing.T) {
+		v, err := EvaluateWithTimeout(time.Second, func() (any, error) { return 42, nil })
+		require.NoError(t, err)
+		assert.Equal(t, 42, v)
+	})
+
+	t.Run("求值错误原样返回", func(t *testing.T) {
+		want := errors.New("boom")
+		_, err := EvaluateWithTimeout(time.Second, func() (any, error) { return nil, want })
+		assert.Equal(t, want, err)
+	})
+
+	t.Run("超时", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		start := time.Now()
+		v, err := EvaluateWithTimeout(20*time.Millisecond, func() (any, error) {
+			<-release
+			return 1, nil
+		})
+		assert.True(t, errors.Is(err, ErrEvaluationTimeout))
+		assert.Nil(t, v)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("panic 转为错误", func(t *testing.T) {
+		_, err := EvaluateWithTimeout(time.Second, func() (any, error) { panic("bad udf") })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bad udf")
+	})
+
+	t.Run("时限为0不限时", func(t *testing.T) {
+		v, err := EvaluateWithTimeout(0, func() (any, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "ok", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", v)
+	})
+
+	t.Run("超时求值计数与上限", func(t *testing.T) {
+		// 等待前面子测试遗留的超时求值返回
+		require.Eventually(t, func() bool { return AbandonedEvaluations() == 0 }, 2*time.Second, 5*time.Millisecond)
+		release := make(chan struct{})
+		// 同时等待结果的求值受 maxActiveEvaluations 限制，分批制造超时求值
+		for round := 0; round < MaxAbandonedEvaluations/maxActiveEvaluations; round++ {
+			var wg sync.WaitGroup
+			for i := 0; i < maxActiveEvaluations; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := EvaluateWithTimeout(20*time.Millisecond, func() (any, error) {
+						<-release
+						return nil, nil
+					})
+					assert.True(t, errors.Is(err, ErrEvaluationTimeout))
+				}()
+			}
+			wg.Wait()
+		}
+		assert.Equal(t, int64(MaxAbandonedEvaluations), AbandonedEvaluations())
+
+		// 达到上限后快速失败，不再启动新的 goroutine
+		called := false
+		_, err := EvaluateWithTimeout(time.Second, func() (any, error) {
+			called = true
+			return 1, nil
+		})
+		assert.True(t, errors.Is(err, ErrEvaluationTimeout))
+		assert.False(t, called)
+
+		close(release)
+		assert.Eventually(t, func() bool { return AbandonedEvaluations() == 0 }, 2*time.Second, 5*time.Millisecond)
+		v, err := EvaluateWithTimeout(time.Second, func() (any, error) { return 1, nil })
+		require.NoError(t, err)
+		assert.Equal(t, 1, v)
+	})
+}
+
+// TestEvaluateContext 测试调用方 context 与工作协程复用
+func TestEvaluateContext(t *testing.T) {
+	t.Run("取消时返回取消错误而非超时", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		defer close(release)
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err := EvaluateContext(ctx, time.Minute, func() (any, error) {
+			<-release
+			return nil, nil
+		})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.False(t, errors.Is(err, ErrEvaluationTimeout))
+	})
+
+	t.Run("顺序求值复用工作协程", func(t *testing.T) {
+		require.Eventually(t, func() bool { return AbandonedEvaluations() == 0 }, 2*time.Second, 5*time.Millisecond)
+		before := atomic.LoadInt64(&evalWorkers)
+		for i := 0; i < 1000; i++ {
+			v, err := EvaluateContext(context.Background(), time.Second, func() (any, error) { return i, nil })
+			require.NoError(t, err)
+			require.Equal(t, i, v)
+		}
+		// 工作协程刚交付结果、尚未回到等待时才会另起一个，远少于求值次数
+		assert.LessOrEqual(t, atomic.LoadInt64(&evalWorkers), before+8)
+	})
+}
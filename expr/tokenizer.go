@@ -287,7 +287,7 @@ func isOperator(s string) bool {
 	operators := []string{
 		"+", "-", "*", "/", "%", "^",
 		"=", "==", "!=", "<>", ">", "<", ">=", "<=",
-		"AND", "OR", "NOT", "LIKE", "IS",
+		"AND", "OR", "NOT", "LIKE", "IS", "BETWEEN", "NOT BETWEEN", "IN", "NOT IN",
 	}
 
 	for _, op := range operators {
@@ -310,6 +310,16 @@ func isComparisonOperator(op string) bool {
 	return false
 }
 
+// isBetweenOperator checks if it's the ternary BETWEEN / NOT BETWEEN operator
+func isBetweenOperator(op string) bool {
+	return strings.EqualFold(op, "BETWEEN") || strings.EqualFold(op, "NOT BETWEEN")
+}
+
+// isInOperator checks if it's the list membership IN / NOT IN operator
+func isInOperator(op string) bool {
+	return strings.EqualFold(op, "IN") || strings.EqualFold(op, "NOT IN")
+}
+
 // isStringLiteral checks if it's a string literal
 func isStringLiteral(s string) bool {
 	return len(s) >= 2 && ((s[0] == '\'' && s[len(s)-1] == '\'') || (s[0] == '"' && s[len(s)-1] == '"'))
@@ -80,7 +80,7 @@ func isUnaryOperator(op string) bool {
 // isKeyword checks if it's a keyword
 func isKeyword(word string) bool {
 	switch strings.ToUpper(word) {
-	case "CASE", "WHEN", "THEN", "ELSE", "END", "AND", "OR", "NOT", "LIKE", "IS", "NULL", "TRUE", "FALSE":
+	case "CASE", "WHEN", "THEN", "ELSE", "END", "AND", "OR", "NOT", "LIKE", "IS", "BETWEEN", "IN", "NULL", "TRUE", "FALSE":
 		return true
 	default:
 		return false
@@ -117,7 +117,7 @@ func getOperatorPrecedence(op string) int {
 		return 2
 	case "NOT":
 		return 3
-	case "=", "==", "!=", "<>", ">", "<", ">=", "<=", "LIKE", "NOT LIKE", "IS", "IS NOT":
+	case "=", "==", "!=", "<>", ">", "<", ">=", "<=", "LIKE", "NOT LIKE", "IS", "IS NOT", "BETWEEN", "NOT BETWEEN", "IN", "NOT IN":
 		return 4
 	case "+", "-":
 		return 5
@@ -107,6 +107,19 @@ func validateOperatorNode(node *ExprNode) error {
 		return fmt.Errorf("operator %s missing left operand", node.Value)
 	}
 
+	// IN keeps its list in Args, which may be empty
+	if isInOperator(node.Value) {
+		if err := validateExpression(node.Left); err != nil {
+			return fmt.Errorf("invalid left operand for operator %s: %v", node.Value, err)
+		}
+		for _, arg := range node.Args {
+			if err := validateExpression(arg); err != nil {
+				return fmt.Errorf("invalid list element for operator %s: %v", node.Value, err)
+			}
+		}
+		return nil
+	}
+
 	if node.Right == nil {
 		return fmt.Errorf("operator %s missing right operand", node.Value)
 	}
@@ -319,8 +332,8 @@ func validateTokenOrder(tokens []string) error {
 
 		// Check consecutive operators
 		if isOperator(current) && isOperator(next) {
-			// Allowed combination: operator followed by unary operator
-			if !isUnaryOperator(next) {
+			// Allowed combinations: operator followed by unary operator, NOT BETWEEN, NOT IN
+			if !isUnaryOperator(next) && !(strings.EqualFold(current, "NOT") && (strings.EqualFold(next, "BETWEEN") || strings.EqualFold(next, "IN"))) {
 				return fmt.Errorf("consecutive operators not allowed: %s %s at position %d", current, next, i)
 			}
 		}
@@ -0,0 +1,149 @@
+package functions
+
+import "math/rand"
+
+// AggregateBufferPolicy selects what a buffering aggregate (median, percentile,
+// collect, ...) does with values beyond its buffer limit (see
+// BufferLimitedAggregator).
+type AggregateBufferPolicy string
+
+const (
+	// AggregateBufferDrop ignores values beyond the limit (default): the result
+	// is computed over the first values of the group.
+	AggregateBufferDrop AggregateBufferPolicy = ""
+	// AggregateBufferApproximate keeps a uniform random sample of all values
+	// (reservoir sampling), so the result approximates the full-window one.
+	// Order-sensitive aggregates (collect, string_agg) then lose input order.
+	AggregateBufferApproximate AggregateBufferPolicy = "approximate"
+)
+
+// BufferLimitedAggregator is implemented by aggregators that buffer every input
+// value and so grow with the window. SetBufferLimit caps the buffered values
+// at limit (<= 0 means unbounded); values beyond it are handled per policy and
+// counted by BufferDropped until the next Reset.
+type BufferLimitedAggregator interface {
+	SetBufferLimit(limit int, policy AggregateBufferPolicy)
+	BufferDropped() int64
+}
+
+// SetBufferLimit applies the buffer limit to agg when agg is a BufferLimitedAggregator.
+func SetBufferLimit(agg any, limit int, policy AggregateBufferPolicy) {
+	if limited, ok := agg.(BufferLimitedAggregator); ok {
+		limited.SetBufferLimit(limit, policy)
+	}
+}
+
+// BufferDropped returns the values agg did not keep because of its buffer
+// limit, or 0 when agg is not a BufferLimitedAggregator.
+func BufferDropped(agg any) int64 {
+	if limited, ok := agg.(BufferLimitedAggregator); ok {
+		return limited.BufferDropped()
+	}
+	return 0
+}
+
+// bufferGuard 嵌入缓冲型聚合函数，实现 BufferLimitedAggregator：缓冲达到上限后
+// 按策略丢弃新值，或以蓄水池抽样（Algorithm R）替换已缓冲的值。
+type bufferGuard struct {
+	limit   int
+	policy  AggregateBufferPolicy
+	seen    int64 // 自上次 Reset 以来送入缓冲的值个数
+	dropped int64 // 其中未保留（丢弃或被抽样淘汰）的个数
+}
+
+func (g *bufferGuard) SetBufferLimit(limit int, policy AggregateBufferPolicy) {
+	g.limit, g.policy = limit, policy
+}
+
+func (g *bufferGuard) BufferDropped() int64 {
+	return g.dropped
+}
+
+// resetGuard 清零计数，保留上限与策略。
+func (g *bufferGuard) resetGuard() {
+	g.seen, g.dropped = 0, 0
+}
+
+// slot 返回下一个值在长度为 n 的缓冲中的位置：n 表示追加，小于 n 表示覆盖该下标，
+// -1 表示丢弃。
+func (g *bufferGuard) slot(n int) int {
+	g.seen++
+	if g.limit <= 0 || n < g.limit {
+		return n
+	}
+	g.dropped++
+	if g.policy == AggregateBufferApproximate {
+		if j := rand.Int63n(g.seen); j < int64(n) {
+			return int(j)
+		}
+	}
+	return -1
+}
+
+func (g *bufferGuard) appendFloat(values []float64, v float64) []float64 {
+	switch i := g.slot(len(values)); {
+	case i == len(values):
+		return append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+func (g *bufferGuard) appendAny(values []any, v any) []any {
+	switch i := g.slot(len(values)); {
+	case i == len(values):
+		return append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+func (g *bufferGuard) appendString(values []string, v string) []string {
+	switch i := g.slot(len(values)); {
+	case i == len(values):
+		return append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+func (g *bufferGuard) appendSample(values []timedSample, v timedSample) []timedSample {
+	switch i := g.slot(len(values)); {
+	case i == len(values):
+		return append(values, v)
+	case i >= 0:
+		values[i] = v
+	}
+	return values
+}
+
+// admitKey 判断新键能否加入当前有 n 个键的集合：集合达到上限后新键一律丢弃并计入
+// dropped（对集合做蓄水池替换不会改变计数，故 approximate 策略同样丢弃），此时结果为下界。
+func (g *bufferGuard) admitKey(n int) bool {
+	g.seen++
+	if g.limit > 0 && n >= g.limit {
+		g.dropped++
+		return false
+	}
+	return true
+}
+
+// addKey 把 k 加入去重集合：已在集合中的键不占额度，新键经 admitKey 准入。
+func (g *bufferGuard) addKey(set map[string]struct{}, k string) {
+	if _, ok := set[k]; ok {
+		return
+	}
+	if g.admitKey(len(set)) {
+		set[k] = struct{}{}
+	}
+}
+
+// countKey 为 k 计数一次：已有的键照常累加，新键经 admitKey 准入，被拒的键不计数。
+func (g *bufferGuard) countKey(counts map[string]int, k string) {
+	if _, ok := counts[k]; ok || g.admitKey(len(counts)) {
+		counts[k]++
+	}
+}
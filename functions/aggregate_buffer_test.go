@@ -0,0 +1,134 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAggregateBufferLimit 测试缓冲型聚合的缓冲上限：drop 保留前 N 个值，
+// approximate 以抽样保留 N 个值，两者都计数未保留的值
+func TestAggregateBufferLimit(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		agg := NewMedianAggregatorFunction().New()
+		SetBufferLimit(agg, 10, AggregateBufferDrop)
+		for i := 1; i <= 100; i++ {
+			agg.Add(i)
+		}
+		assert.Equal(t, 5.5, agg.Result(), "median of the first 10 values")
+		assert.Equal(t, int64(90), BufferDropped(agg))
+
+		clone := agg.Clone()
+		clone.Add(1000)
+		assert.Equal(t, int64(91), BufferDropped(clone), "clone keeps the limit")
+
+		agg.Reset()
+		assert.Equal(t, int64(0), BufferDropped(agg))
+		for i := 1; i <= 20; i++ {
+			agg.Add(i)
+		}
+		assert.Equal(t, int64(10), BufferDropped(agg), "limit survives Reset")
+	})
+
+	t.Run("approximate", func(t *testing.T) {
+		agg := NewCollectFunction().New()
+		SetBufferLimit(agg, 10, AggregateBufferApproximate)
+		for i := 0; i < 1000; i++ {
+			agg.Add(i)
+		}
+		values := agg.Result().([]any)
+		require.Len(t, values, 10)
+		assert.Equal(t, int64(990), BufferDropped(agg))
+		late := 0
+		for _, v := range values {
+			if v.(int) >= 10 {
+				late++
+			}
+		}
+		assert.Greater(t, late, 0, "the sample covers values past the limit")
+
+		median := NewMedianAggregatorFunction().New()
+		SetBufferLimit(median, 1000, AggregateBufferApproximate)
+		for i := 0; i < 100000; i++ {
+			median.Add(i)
+		}
+		assert.InDelta(t, 50000, median.Result(), 10000)
+	})
+
+	t.Run("count_distinct", func(t *testing.T) {
+		for _, policy := range []AggregateBufferPolicy{AggregateBufferDrop, AggregateBufferApproximate} {
+			agg := NewCountDistinctAggregatorFunction().New()
+			SetBufferLimit(agg, 10, policy)
+			for i := 0; i < 100; i++ {
+				agg.Add(i % 50)
+			}
+			assert.Equal(t, int64(10), agg.Result(), "distinct set capped at the limit")
+			assert.Equal(t, int64(80), BufferDropped(agg), "inputs of values outside the set count as dropped")
+
+			clone := agg.Clone()
+			clone.Add(1000)
+			assert.Equal(t, int64(81), BufferDropped(clone), "clone keeps the limit")
+
+			agg.Reset()
+			assert.Equal(t, int64(0), BufferDropped(agg))
+			assert.Equal(t, int64(0), agg.Result())
+		}
+	})
+
+	t.Run("value_counts", func(t *testing.T) {
+		agg := NewValueCountsAggregatorFunction().New()
+		SetBufferLimit(agg, 3, AggregateBufferDrop)
+		for _, v := range []string{"a", "b", "a", "c", "d", "a", "e", "b"} {
+			agg.Add(v)
+		}
+		assert.Equal(t, map[string]int{"a": 3, "b": 2, "c": 1}, agg.Result(), "tracked values keep counting, new ones are dropped")
+		assert.Equal(t, int64(2), BufferDropped(agg))
+
+		clone := agg.Clone()
+		clone.Add("f")
+		assert.Equal(t, int64(3), BufferDropped(clone), "clone keeps the limit")
+
+		fresh := agg.New()
+		for _, v := range []string{"x", "y", "z", "w"} {
+			fresh.Add(v)
+		}
+		assert.Equal(t, int64(1), BufferDropped(fresh), "New keeps the limit")
+
+		agg.Reset()
+		assert.Equal(t, int64(0), BufferDropped(agg))
+		assert.Equal(t, map[string]int{}, agg.Result())
+	})
+
+	t.Run("time_in_state", func(t *testing.T) {
+		agg := NewTimeInStateAggregatorFunction()
+		require.NoError(t, agg.Init([]any{"status", "error"}))
+		inst := agg.New().(*TimeInStateAggregatorFunction)
+		SetBufferLimit(inst, 3, AggregateBufferDrop)
+		base := time.Unix(1000, 0)
+		for i, s := range []string{"error", "ok", "error", "error", "ok"} {
+			inst.AddAt(s, base.Add(time.Duration(i)*time.Second))
+		}
+		assert.Equal(t, 1.0, inst.Result(), "only the first 3 samples are kept")
+		assert.Equal(t, int64(2), BufferDropped(inst))
+
+		clone := inst.Clone()
+		clone.Add("ok")
+		assert.Equal(t, int64(3), BufferDropped(clone), "clone keeps the limit")
+
+		inst.Reset()
+		assert.Equal(t, int64(0), BufferDropped(inst))
+		assert.Equal(t, 0.0, inst.Result())
+	})
+
+	t.Run("unlimited_by_default", func(t *testing.T) {
+		agg := NewStringAggAggregatorFunction().New()
+		for i := 0; i < 5; i++ {
+			agg.Add("x")
+		}
+		assert.Equal(t, "x,x,x,x,x", agg.Result())
+		assert.Equal(t, int64(0), BufferDropped(agg))
+		assert.Equal(t, int64(0), BufferDropped(NewSumFunction().New()), "non-buffering aggregate")
+	})
+}
@@ -2,6 +2,7 @@ package functions
 
 import (
 	"sync"
+	"time"
 )
 
 // AggregatorAdapter provides adapter for aggregator functions, compatible with legacy aggregator interface
@@ -33,6 +34,31 @@ func (a *AggregatorAdapter) Add(value any) {
 	a.aggFunc.Add(value)
 }
 
+// AddAt forwards the row timestamp to timestamp-aware aggregators
+func (a *AggregatorAdapter) AddAt(value any, ts time.Time) {
+	AddAt(a.aggFunc, value, ts)
+}
+
+// AddRow forwards the source row to row-aware aggregators
+func (a *AggregatorAdapter) AddRow(value any, ts time.Time, row any) {
+	AddRow(a.aggFunc, value, ts, row)
+}
+
+// SetWindowSpan forwards the window bounds to span-aware aggregators
+func (a *AggregatorAdapter) SetWindowSpan(start, end time.Time) {
+	SetWindowSpan(a.aggFunc, start, end)
+}
+
+// SetBufferLimit forwards the buffer limit to buffering aggregators
+func (a *AggregatorAdapter) SetBufferLimit(limit int, policy AggregateBufferPolicy) {
+	SetBufferLimit(a.aggFunc, limit, policy)
+}
+
+// BufferDropped returns the values the buffering aggregator did not keep
+func (a *AggregatorAdapter) BufferDropped() int64 {
+	return BufferDropped(a.aggFunc)
+}
+
 // Result returns the result
 func (a *AggregatorAdapter) Result() any {
 	return a.aggFunc.Result()
@@ -1,11 +1,24 @@
 package functions
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
-// AggregatorFunction defines the interface for aggregator functions that support incremental computation
+// AggregatorFunction defines the interface for aggregator functions that support incremental computation.
+//
+// State contract: the registered instance is only a prototype. The engine calls
+// New once per group per window and feeds that group's values to the returned
+// instance only, so New must return empty state that shares no mutable memory
+// (maps, slices, pointers) with the receiver; configuration such as Init
+// parameters may be copied. An instance is used by one goroutine at a time,
+// while Execute on the registered instance may run concurrently and must not
+// touch its state. Clone deep-copies the current state for snapshots and is
+// never used to create group state. NewStatefulAggregator satisfies this
+// contract by construction.
 type AggregatorFunction interface {
 	Function
-	// New creates a new aggregator instance
+	// New creates a new aggregator instance with fresh, unshared state
 	New() AggregatorFunction
 	// Add adds a value for incremental computation
 	Add(value any)
@@ -13,10 +26,59 @@ type AggregatorFunction interface {
 	Result() any
 	// Reset resets the aggregator state
 	Reset()
-	// Clone clones the aggregator (used for window functions and similar scenarios)
+	// Clone deep-copies the aggregator including its state (used for window functions and similar scenarios)
 	Clone() AggregatorFunction
 }
 
+// TimestampedAggregator is implemented by aggregators that need each row's timestamp
+// (e.g. time_in_state). Window aggregation calls AddAt instead of Add, passing the
+// row's event time (or arrival time for processing-time windows); a zero ts means
+// the row has no timestamp.
+type TimestampedAggregator interface {
+	AddAt(value any, ts time.Time)
+}
+
+// AddAt adds value to agg with its row timestamp when agg is a TimestampedAggregator,
+// otherwise falls back to Add.
+func AddAt(agg interface{ Add(value any) }, value any, ts time.Time) {
+	if timed, ok := agg.(TimestampedAggregator); ok {
+		timed.AddAt(value, ts)
+		return
+	}
+	agg.Add(value)
+}
+
+// RowAggregator is implemented by aggregators whose result carries the input row
+// (e.g. max_row). Window aggregation calls AddRow instead of AddAt, passing the
+// whole source row along with the aggregated value.
+type RowAggregator interface {
+	AddRow(value any, ts time.Time, row any)
+}
+
+// AddRow adds value to agg with its source row when agg is a RowAggregator,
+// otherwise falls back to AddAt.
+func AddRow(agg interface{ Add(value any) }, value any, ts time.Time, row any) {
+	if rowAgg, ok := agg.(RowAggregator); ok {
+		rowAgg.AddRow(value, ts, row)
+		return
+	}
+	AddAt(agg, value, ts)
+}
+
+// WindowSpanAggregator is implemented by aggregators whose result depends on the
+// window's time bounds (e.g. window_rate). Window aggregation calls SetWindowSpan
+// with the window start/end before reading Result.
+type WindowSpanAggregator interface {
+	SetWindowSpan(start, end time.Time)
+}
+
+// SetWindowSpan passes the window bounds to agg when agg is a WindowSpanAggregator.
+func SetWindowSpan(agg any, start, end time.Time) {
+	if spanned, ok := agg.(WindowSpanAggregator); ok {
+		spanned.SetWindowSpan(start, end)
+	}
+}
+
 // ParameterizedFunction defines the interface for functions that need parameter initialization
 type ParameterizedFunction interface {
 	AggregatorFunction
@@ -2,6 +2,7 @@ package functions
 
 import (
 	"sync"
+	"time"
 )
 
 // AggregateType defines aggregate types, migrated from aggregator.AggregateType
@@ -15,9 +16,11 @@ const (
 	Min         AggregateType = "min"
 	Median      AggregateType = "median"
 	Percentile  AggregateType = "percentile"
+	IQR         AggregateType = "iqr"
 	WindowStart AggregateType = "window_start"
 	WindowEnd   AggregateType = "window_end"
 	Collect     AggregateType = "collect"
+	ArrayAgg    AggregateType = "array_agg"
 	FirstValue  AggregateType = "first_value"
 	LastValue   AggregateType = "last_value"
 	MergeAgg    AggregateType = "merge_agg"
@@ -26,6 +29,32 @@ const (
 	Deduplicate AggregateType = "deduplicate"
 	Var         AggregateType = "var"
 	VarS        AggregateType = "vars"
+	ValueCounts AggregateType = "value_counts"
+	// Approximate distinct count (HyperLogLog)
+	ApproxCountDistinct AggregateType = "approx_count_distinct"
+	// Exact distinct count, COUNT(DISTINCT col)
+	CountDistinct AggregateType = "count_distinct"
+	// String concatenation with separator
+	StringAgg AggregateType = "string_agg"
+	// Watermark that fired the window
+	WindowWatermark AggregateType = "window_watermark"
+	// How full the window was when it fired
+	WindowFillRatio AggregateType = "window_fill_ratio"
+	// Window bounds as RFC3339 strings
+	WindowStartISO AggregateType = "window_start_iso"
+	WindowEndISO   AggregateType = "window_end_iso"
+	// Signal statistics
+	ConsecutiveDiffMedian AggregateType = "consecutive_diff_median"
+	TimeInState           AggregateType = "time_in_state"
+	TrimmedMean           AggregateType = "trimmed_mean"
+	TopK                  AggregateType = "top_k"
+	Histogram             AggregateType = "histogram"
+	WeightedAvg           AggregateType = "wavg"
+	WindowDelta           AggregateType = "window_delta"
+	WindowRate            AggregateType = "window_rate"
+	Rate                  AggregateType = "rate"
+	MaxRow                AggregateType = "max_row"
+	MinRow                AggregateType = "min_row"
 	// Analytical functions
 	Lag        AggregateType = "lag"
 	Latest     AggregateType = "latest"
@@ -46,9 +75,11 @@ const (
 	MinStr         = string(Min)
 	MedianStr      = string(Median)
 	PercentileStr  = string(Percentile)
+	IQRStr         = string(IQR)
 	WindowStartStr = string(WindowStart)
 	WindowEndStr   = string(WindowEnd)
 	CollectStr     = string(Collect)
+	ArrayAggStr    = string(ArrayAgg)
 	FirstValueStr  = string(FirstValue)
 	LastValueStr   = string(LastValue)
 	MergeAggStr    = string(MergeAgg)
@@ -58,6 +89,32 @@ const (
 	DeduplicateStr = string(Deduplicate)
 	VarStr         = string(Var)
 	VarSStr        = string(VarS)
+	ValueCountsStr = string(ValueCounts)
+	// Approximate distinct count
+	ApproxCountDistinctStr = string(ApproxCountDistinct)
+	// Exact distinct count
+	CountDistinctStr = string(CountDistinct)
+	// String concatenation with separator
+	StringAggStr = string(StringAgg)
+	// Window watermark
+	WindowWatermarkStr = string(WindowWatermark)
+	// Window fill ratio
+	WindowFillRatioStr = string(WindowFillRatio)
+	// Window bounds as RFC3339 strings
+	WindowStartISOStr = string(WindowStartISO)
+	WindowEndISOStr   = string(WindowEndISO)
+	// Signal statistics
+	ConsecutiveDiffMedianStr = string(ConsecutiveDiffMedian)
+	TimeInStateStr           = string(TimeInState)
+	TrimmedMeanStr           = string(TrimmedMean)
+	TopKStr                  = string(TopK)
+	HistogramStr             = string(Histogram)
+	WeightedAvgStr           = string(WeightedAvg)
+	WindowDeltaStr           = string(WindowDelta)
+	WindowRateStr            = string(WindowRate)
+	RateStr                  = string(Rate)
+	MaxRowStr                = string(MaxRow)
+	MinRowStr                = string(MinRow)
 	// Analytical functions
 	LagStr        = string(Lag)
 	LatestStr     = string(Latest)
@@ -132,6 +189,26 @@ func (w *FunctionAggregatorWrapper) Add(value any) {
 	w.adapter.Add(value)
 }
 
+func (w *FunctionAggregatorWrapper) AddAt(value any, ts time.Time) {
+	w.adapter.AddAt(value, ts)
+}
+
+func (w *FunctionAggregatorWrapper) AddRow(value any, ts time.Time, row any) {
+	w.adapter.AddRow(value, ts, row)
+}
+
+func (w *FunctionAggregatorWrapper) SetWindowSpan(start, end time.Time) {
+	w.adapter.SetWindowSpan(start, end)
+}
+
+func (w *FunctionAggregatorWrapper) SetBufferLimit(limit int, policy AggregateBufferPolicy) {
+	w.adapter.SetBufferLimit(limit, policy)
+}
+
+func (w *FunctionAggregatorWrapper) BufferDropped() int64 {
+	return w.adapter.BufferDropped()
+}
+
 func (w *FunctionAggregatorWrapper) Result() any {
 	return w.adapter.Result()
 }
@@ -145,6 +222,14 @@ func (w *FunctionAggregatorWrapper) GetContextKey() string {
 			return "window_start"
 		case "window_end":
 			return "window_end"
+		case "window_watermark":
+			return "window_watermark"
+		case "window_fill_ratio":
+			return "window_fill_ratio"
+		case "window_start_iso":
+			return "window_start_iso"
+		case "window_end_iso":
+			return "window_end_iso"
 		}
 	}
 	return ""
@@ -21,6 +21,7 @@ func TestAggregateTypeConstants(t *testing.T) {
 		{"WindowStart", WindowStart, "window_start"},
 		{"WindowEnd", WindowEnd, "window_end"},
 		{"Collect", Collect, "collect"},
+		{"ArrayAgg", ArrayAgg, "array_agg"},
 		{"LastValue", LastValue, "last_value"},
 		{"MergeAgg", MergeAgg, "merge_agg"},
 		{"StdDev", StdDev, "stddev"},
@@ -61,6 +62,7 @@ func TestStringConstants(t *testing.T) {
 		{"WindowStartStr", WindowStartStr, "window_start"},
 		{"WindowEndStr", WindowEndStr, "window_end"},
 		{"CollectStr", CollectStr, "collect"},
+		{"ArrayAggStr", ArrayAggStr, "array_agg"},
 		{"LastValueStr", LastValueStr, "last_value"},
 		{"MergeAggStr", MergeAggStr, "merge_agg"},
 		{"StdDevStr", StdDevStr, "stddev"},
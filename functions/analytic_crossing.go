@@ -0,0 +1,62 @@
+package functions
+
+import "fmt"
+
+// crossingState 是 crossed_above/crossed_below 的边沿检测状态：保存上一个数值，
+// 仅在越过阈值的那一条记录返回 true（持续处于阈值之上/之下不重复触发）。
+// 首条记录没有前值，返回 false；nil 或非数字值返回 false 且不覆盖前值。
+type crossingState struct {
+	above   bool // true: crossed_above（≤t → >t）；false: crossed_below（≥t → <t）
+	prev    float64
+	hasPrev bool
+}
+
+func (s *crossingState) Apply(args []any) any {
+	if len(args) < 2 {
+		return false
+	}
+	v, ok := toFloat64Generic(args[0])
+	if !ok {
+		return false
+	}
+	threshold, ok := toFloat64Generic(args[1])
+	if !ok {
+		return false
+	}
+	crossed := false
+	if s.hasPrev {
+		if s.above {
+			crossed = s.prev <= threshold && v > threshold
+		} else {
+			crossed = s.prev >= threshold && v < threshold
+		}
+	}
+	s.prev = v
+	s.hasPrev = true
+	return crossed
+}
+
+func (s *crossingState) Reset() { s.prev = 0; s.hasPrev = false }
+
+// crossingFunction crossed_above/crossed_below(value, threshold)（TypeAnalytical）。
+// 配合 OVER (PARTITION BY ...) 按分组各持一份前值，适用于只告警一次的边沿触发。
+type crossingFunction struct {
+	*BaseFunction
+	above bool
+}
+
+func (f *crossingFunction) Validate(args []any) error { return f.ValidateArgCount(args) }
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *crossingFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+func (f *crossingFunction) NewState() AnalyticState { return &crossingState{above: f.above} }
+
+func NewCrossedAboveFunction() *crossingFunction {
+	return &crossingFunction{BaseFunction: NewBaseFunction("crossed_above", TypeAnalytical, "分析函数", "值从不高于阈值变为高于阈值", 2, 2), above: true}
+}
+func NewCrossedBelowFunction() *crossingFunction {
+	return &crossingFunction{BaseFunction: NewBaseFunction("crossed_below", TypeAnalytical, "分析函数", "值从不低于阈值变为低于阈值", 2, 2), above: false}
+}
@@ -0,0 +1,125 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maxDuplicateKeys 是每个分区已见集合保留的键数上限，超出后最早记录的键被淘汰
+// （此后再出现视为首次），键空间无界且未指定窗口时内存仍有上界。
+const maxDuplicateKeys = 100000
+
+// duplicateState 是 is_duplicate 的去重状态：记录当前窗口内已出现过的键，
+// 键再次出现时返回 true（首次出现返回 false），用于保留重复记录但打标记。
+// 窗口查询（GROUP BY 窗口）里已见集合随每次窗口产出清空；非窗口查询里带窗口长度时
+// 窗口从清空后的首条记录起算（处理时间），到期后下一条记录先清空已见集合，不带窗口长度时
+// 已见集合随分区常驻。任何情况下最多保留 maxKeys 个键。nil 键返回 false 且不记录。
+type duplicateState struct {
+	seen    map[any]struct{}
+	order   []any // 按记录顺序的键，超出 maxKeys 时从头部淘汰
+	head    int
+	maxKeys int
+	end     time.Time // 当前窗口结束时间；零值表示窗口未开始
+	now     func() time.Time
+}
+
+func (s *duplicateState) Apply(args []any) any {
+	if len(args) == 0 || args[0] == nil {
+		return false
+	}
+	if len(args) >= 2 {
+		if size, ok := duplicateWindow(args[1]); ok {
+			now := s.now()
+			if s.end.IsZero() || !now.Before(s.end) {
+				s.clear()
+				s.end = now.Add(size)
+			}
+		}
+	}
+	key := duplicateKey(args[0])
+	if _, ok := s.seen[key]; ok {
+		return true
+	}
+	if s.seen == nil {
+		s.seen = make(map[any]struct{})
+	}
+	s.seen[key] = struct{}{}
+	s.order = append(s.order, key)
+	if len(s.order)-s.head > s.maxKeys {
+		delete(s.seen, s.order[s.head])
+		s.order[s.head] = nil
+		s.head++
+		if s.head > s.maxKeys {
+			s.order = append(s.order[:0], s.order[s.head:]...)
+			s.head = 0
+		}
+	}
+	return false
+}
+
+func (s *duplicateState) clear() { s.seen = nil; s.order = nil; s.head = 0 }
+
+func (s *duplicateState) Reset() { s.clear(); s.end = time.Time{} }
+
+// duplicateKey 归一化键：数字统一为 float64（1 与 1.0 视为同一键，与 analyticEqual 一致），
+// 不可比较的值（map/slice）按格式化字符串作键。
+func duplicateKey(v any) any {
+	if f, ok := toFloat64Generic(v); ok {
+		return f
+	}
+	if !reflect.TypeOf(v).Comparable() {
+		return fmt.Sprintf("%v", v)
+	}
+	return v
+}
+
+// duplicateWindow 解析窗口长度参数（如 '1m'）；非法或非正值视为不分窗口。
+func duplicateWindow(v any) (time.Duration, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(strings.Trim(strings.TrimSpace(s), `'"`))
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// duplicateFunction is_duplicate(key_expr[, window])（TypeAnalytical）。
+// 配合 OVER (PARTITION BY ...) 按分组各持一份已见集合。
+type duplicateFunction struct {
+	*BaseFunction
+}
+
+func (f *duplicateFunction) Validate(args []any) error {
+	if err := f.ValidateArgCount(args); err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		if s, ok := args[1].(string); ok {
+			if _, ok := duplicateWindow(s); !ok {
+				return fmt.Errorf("is_duplicate window must be a positive duration such as '1m', got %q", s)
+			}
+		}
+	}
+	return nil
+}
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *duplicateFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+// WindowScoped 窗口查询里已见集合随每次窗口产出清空。
+func (f *duplicateFunction) WindowScoped() bool { return true }
+
+func (f *duplicateFunction) NewState() AnalyticState {
+	return &duplicateState{maxKeys: maxDuplicateKeys, now: time.Now}
+}
+
+func NewIsDuplicateFunction() *duplicateFunction {
+	return &duplicateFunction{BaseFunction: NewBaseFunction("is_duplicate", TypeAnalytical, "分析函数", "键在当前窗口内已出现过则返回true", 1, 2)}
+}
@@ -0,0 +1,26 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 已见集合超出上限后淘汰最早记录的键，状态不再增长。
+func TestDuplicateStateBounded(t *testing.T) {
+	s := &duplicateState{maxKeys: 3, now: time.Now}
+	for _, k := range []int{1, 2, 3} {
+		assert.Equal(t, false, s.Apply([]any{k}))
+	}
+	assert.Equal(t, true, s.Apply([]any{1}))
+	assert.Equal(t, false, s.Apply([]any{4}), "新键挤出最早的键 1")
+	assert.Equal(t, true, s.Apply([]any{2}))
+	assert.Equal(t, false, s.Apply([]any{1}), "键 1 已淘汰，再次出现视为首次")
+
+	for i := 0; i < 100; i++ {
+		s.Apply([]any{i + 10})
+	}
+	assert.Len(t, s.seen, 3)
+	assert.LessOrEqual(t, len(s.order), 2*3+1)
+}
@@ -0,0 +1,44 @@
+package functions
+
+import "fmt"
+
+// NtileFunction ntile(n)（TypeAnalytical）：把分区行按 OVER (ORDER BY ...) 排序后
+// 尽量均分到 n 个桶，返回当前行的桶号 1..n。行数不能整除时与标准 SQL 一致，
+// 靠前的桶各多分一行。分区需整体可见，只用于窗口查询：分区取每次窗口产出的结果行。
+type NtileFunction struct {
+	*BaseFunction
+}
+
+func NewNtileFunction() *NtileFunction {
+	return &NtileFunction{BaseFunction: NewBaseFunction("ntile", TypeAnalytical, "分析函数", "把分区有序行均分到 n 个桶，返回当前行的桶号（1..n）", 1, 1)}
+}
+
+func (f *NtileFunction) Validate(args []any) error { return f.ValidateArgCount(args) }
+
+// Execute 标量路径禁用：ntile 需要整个分区，只能作为窗口查询的独立字段求值。
+func (f *NtileFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field with OVER in a window query, not in a scalar expression", f.GetName())
+}
+
+// ApplyPartition 实现 PartitionAnalytic：args[0] 为桶数 n（须为正整数，否则返回 nil）。
+func (f *NtileFunction) ApplyPartition(args []any, index, size int) any {
+	if len(args) == 0 {
+		return nil
+	}
+	n, ok := analyticToInt(args[0])
+	if !ok || n <= 0 || index < 0 || index >= size {
+		return nil
+	}
+	return ntileBucket(n, index, size)
+}
+
+// ntileBucket 返回分区第 index 行（从 0 起，共 size 行）的桶号：每桶 size/n 行，
+// 前 size%n 个桶各多一行；n > size 时每行独占一桶。
+func ntileBucket(n, index, size int) int64 {
+	q, r := size/n, size%n
+	big := r * (q + 1) // 前 r 个大桶覆盖的行数
+	if index < big {
+		return int64(index/(q+1) + 1)
+	}
+	return int64(r + (index-big)/q + 1)
+}
@@ -0,0 +1,28 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNtileFunction 测试 ntile 分桶：不能整除时靠前的桶多一行，桶数多于行数时每行一桶
+func TestNtileFunction(t *testing.T) {
+	f := NewNtileFunction()
+	buckets := func(n, size int) []any {
+		out := make([]any, size)
+		for i := range out {
+			out[i] = f.ApplyPartition([]any{n}, i, size)
+		}
+		return out
+	}
+	assert.Equal(t, []any{int64(1), int64(1), int64(2), int64(2)}, buckets(2, 4))
+	assert.Equal(t, []any{int64(1), int64(1), int64(1), int64(2), int64(2), int64(3), int64(3)}, buckets(3, 7))
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, buckets(5, 3))
+	assert.Equal(t, []any{int64(1)}, buckets(4, 1))
+
+	assert.Nil(t, f.ApplyPartition([]any{0}, 0, 3), "n 须为正整数")
+	assert.Nil(t, f.ApplyPartition([]any{"x"}, 0, 3))
+	_, err := f.Execute(nil, []any{4})
+	assert.Error(t, err, "标量路径禁用")
+}
@@ -0,0 +1,68 @@
+package functions
+
+import (
+	"fmt"
+	"time"
+)
+
+// positionState 是 window_position 的分区内位置计数：返回记录在当前窗口内的到达序号（从 0 起）。
+// 窗口查询（GROUP BY 窗口）里按每次窗口产出的结果行计数，每个窗口从 0 重新开始。
+// 非窗口查询里窗口语义同 is_duplicate：带窗口长度时窗口从清空后的首条记录起算（处理时间），
+// 到期后下一条记录从 0 重新计数；不带窗口长度时在整个流生命周期内递增。
+type positionState struct {
+	next int64
+	end  time.Time // 当前窗口结束时间；零值表示窗口未开始
+	now  func() time.Time
+}
+
+func (s *positionState) Apply(args []any) any {
+	if len(args) >= 1 {
+		if size, ok := duplicateWindow(args[0]); ok {
+			now := s.now()
+			if s.end.IsZero() || !now.Before(s.end) {
+				s.next = 0
+				s.end = now.Add(size)
+			}
+		}
+	}
+	pos := s.next
+	s.next++
+	return pos
+}
+
+func (s *positionState) Reset() { s.next = 0; s.end = time.Time{} }
+
+// positionFunction window_position([window])（TypeAnalytical）。
+// 配合 OVER (PARTITION BY ...) 按分组各自计数。与 row_number 不同，只按到达顺序计数、
+// 不接受 ORDER BY，且随窗口重置。
+type positionFunction struct {
+	*BaseFunction
+}
+
+func (f *positionFunction) Validate(args []any) error {
+	if err := f.ValidateArgCount(args); err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			if _, ok := duplicateWindow(s); !ok {
+				return fmt.Errorf("window_position window must be a positive duration such as '1m', got %q", s)
+			}
+		}
+	}
+	return nil
+}
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *positionFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+// WindowScoped 窗口查询里状态随每次窗口产出重置。
+func (f *positionFunction) WindowScoped() bool { return true }
+
+func (f *positionFunction) NewState() AnalyticState { return &positionState{now: time.Now} }
+
+func NewWindowPositionFunction() *positionFunction {
+	return &positionFunction{BaseFunction: NewBaseFunction("window_position", TypeAnalytical, "分析函数", "记录在分组当前窗口内的到达序号，从0开始", 0, 1)}
+}
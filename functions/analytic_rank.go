@@ -0,0 +1,279 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxRankRows 是每个分区排名时保留的最近行数。超出后最早到达的行退出排名集合，
+// 名次按最近 maxRankRows 行求得，单分区状态与每行插入代价（O(log n)）都有上界。
+const maxRankRows = 10000
+
+// rankNode 是排名 treap 的节点：一个不同的排序键及其出现次数。rows/keys 为子树内
+// 行数与不同键数，用于 O(log n) 求某键之前的行数与键数。
+type rankNode struct {
+	key         []any
+	count       int64
+	rows        int64
+	keys        int64
+	prio        uint64
+	left, right *rankNode
+}
+
+func (n *rankNode) update() {
+	n.rows, n.keys = n.count, 1
+	for _, c := range []*rankNode{n.left, n.right} {
+		if c != nil {
+			n.rows += c.rows
+			n.keys += c.keys
+		}
+	}
+}
+
+// rankState 是 row_number/rank/dense_rank 的分区内排名状态：按排序键维护有序的
+// 不同键集合（treap），每条新行插入后对照分区内最近 maxRankRows 行求名次，因此乱序
+// 到达也能得到正确的排名（名次是截至当前行为止的结果，后到的更小键不会回改已产出的行）。
+type rankState struct {
+	kind     string // "row_number" / "rank" / "dense_rank"
+	root     *rankNode
+	desc     []bool
+	arrivals [][]any // 按到达顺序的排序键，超出 maxRankRows 时从头部淘汰
+	head     int
+	seed     uint64 // treap 优先级序列（splitmix64），保证排名结果可复现
+}
+
+// Apply 无 ORDER BY 键时的退化路径：所有行视为并列（与标准 SQL 一致），
+// row_number 仍逐行递增。
+func (s *rankState) Apply(args []any) any { return s.ApplyOrdered(nil, nil) }
+
+func (s *rankState) ApplyOrdered(orderKey []any, desc []bool) any {
+	s.desc = desc
+	key := append([]any(nil), orderKey...)
+	s.root = s.insert(s.root, key)
+	s.arrivals = append(s.arrivals, key)
+	if len(s.arrivals)-s.head > maxRankRows {
+		s.root = s.remove(s.root, s.arrivals[s.head])
+		s.arrivals[s.head] = nil
+		s.head++
+		if s.head > maxRankRows {
+			s.arrivals = append(s.arrivals[:0], s.arrivals[s.head:]...)
+			s.head = 0
+		}
+	}
+	before, beforeKeys, count := s.position(key)
+	switch s.kind {
+	case "rank":
+		return before + 1
+	case "dense_rank":
+		return beforeKeys + 1
+	}
+	// row_number：并列行按到达顺序排在已有同键行之后
+	return before + count
+}
+
+// position 返回键之前的行数、不同键数，以及该键的出现次数。
+func (s *rankState) position(key []any) (before, beforeKeys, count int64) {
+	for n := s.root; n != nil; {
+		c := compareRankKey(key, n.key, s.desc)
+		switch {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			if n.left != nil {
+				before += n.left.rows
+				beforeKeys += n.left.keys
+			}
+			before += n.count
+			beforeKeys++
+			n = n.right
+		default:
+			if n.left != nil {
+				before += n.left.rows
+				beforeKeys += n.left.keys
+			}
+			return before, beforeKeys, n.count
+		}
+	}
+	return before, beforeKeys, 0
+}
+
+func (s *rankState) nextPrio() uint64 {
+	s.seed += 0x9e3779b97f4a7c15
+	z := s.seed
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *rankState) insert(n *rankNode, key []any) *rankNode {
+	if n == nil {
+		n = &rankNode{key: key, count: 1, prio: s.nextPrio()}
+		n.update()
+		return n
+	}
+	switch c := compareRankKey(key, n.key, s.desc); {
+	case c < 0:
+		n.left = s.insert(n.left, key)
+		if n.left.prio > n.prio {
+			n = rotateRight(n)
+		}
+	case c > 0:
+		n.right = s.insert(n.right, key)
+		if n.right.prio > n.prio {
+			n = rotateLeft(n)
+		}
+	default:
+		n.count++
+	}
+	n.update()
+	return n
+}
+
+// remove 去掉键的一次出现，次数归零时删除节点。
+func (s *rankState) remove(n *rankNode, key []any) *rankNode {
+	if n == nil {
+		return nil
+	}
+	switch c := compareRankKey(key, n.key, s.desc); {
+	case c < 0:
+		n.left = s.remove(n.left, key)
+	case c > 0:
+		n.right = s.remove(n.right, key)
+	default:
+		if n.count > 1 {
+			n.count--
+			break
+		}
+		return mergeRank(n.left, n.right)
+	}
+	n.update()
+	return n
+}
+
+func rotateRight(n *rankNode) *rankNode {
+	l := n.left
+	n.left, l.right = l.right, n
+	n.update()
+	l.update()
+	return l
+}
+
+func rotateLeft(n *rankNode) *rankNode {
+	r := n.right
+	n.right, r.left = r.left, n
+	n.update()
+	r.update()
+	return r
+}
+
+// mergeRank 合并两棵 treap（a 中所有键小于 b）。
+func mergeRank(a, b *rankNode) *rankNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.prio > b.prio {
+		a.right = mergeRank(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = mergeRank(a, b.left)
+	b.update()
+	return b
+}
+
+func (s *rankState) Reset() {
+	s.root = nil
+	s.arrivals = s.arrivals[:0]
+	s.head = 0
+}
+
+// compareRankKey 逐键三路比较排序键，desc[i] 为 true 时该键反向。
+func compareRankKey(a, b []any, desc []bool) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		c := compareRankValue(a[i], b[i])
+		if c == 0 {
+			continue
+		}
+		if i < len(desc) && desc[i] {
+			return -c
+		}
+		return c
+	}
+	return len(a) - len(b)
+}
+
+// compareRankValue 三路比较单个排序值：nil 最小，数字跨类型按数值比较，
+// time.Time 按时刻，bool 为 false<true，其余按字符串比较。
+func compareRankValue(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if af, ok := toFloat64Generic(a); ok {
+		if bf, ok2 := toFloat64Generic(b); ok2 {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok2 := b.(time.Time); ok2 {
+			switch {
+			case at.Before(bt):
+				return -1
+			case at.After(bt):
+				return 1
+			}
+			return 0
+		}
+	}
+	if ab, ok := a.(bool); ok {
+		if bb, ok2 := b.(bool); ok2 {
+			switch {
+			case ab == bb:
+				return 0
+			case !ab:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// rankFunction row_number()/rank()/dense_rank()（TypeAnalytical）。
+// 配合 OVER (PARTITION BY ... ORDER BY ...) 为每个分区独立编号。
+type rankFunction struct {
+	*BaseFunction
+}
+
+func (f *rankFunction) Validate(args []any) error { return f.ValidateArgCount(args) }
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *rankFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+func (f *rankFunction) NewState() AnalyticState { return &rankState{kind: f.GetName()} }
+
+func NewRowNumberFunction() *rankFunction {
+	return &rankFunction{BaseFunction: NewBaseFunction("row_number", TypeAnalytical, "分析函数", "分区内已到达行按排序键的行号，从1开始", 0, 0)}
+}
+func NewRankFunction() *rankFunction {
+	return &rankFunction{BaseFunction: NewBaseFunction("rank", TypeAnalytical, "分析函数", "分区内排名，并列同名次且后续名次跳号", 0, 0)}
+}
+func NewDenseRankFunction() *rankFunction {
+	return &rankFunction{BaseFunction: NewBaseFunction("dense_rank", TypeAnalytical, "分析函数", "分区内排名，并列同名次且后续名次连续", 0, 0)}
+}
@@ -0,0 +1,68 @@
+package functions
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// naiveRank 按定义对照窗口内全部行重算名次
+func naiveRank(kind string, window []float64, v float64, desc bool) int64 {
+	var before, same int64
+	distinct := map[float64]bool{}
+	for _, w := range window {
+		switch {
+		case w == v:
+			same++
+		case (w < v) != desc:
+			before++
+			distinct[w] = true
+		}
+	}
+	switch kind {
+	case "rank":
+		return before + 1
+	case "dense_rank":
+		return int64(len(distinct)) + 1
+	}
+	return before + same
+}
+
+func TestRankState(t *testing.T) {
+	for _, kind := range []string{"row_number", "rank", "dense_rank"} {
+		for _, desc := range []bool{false, true} {
+			f, ok := Get(kind)
+			require.True(t, ok)
+			state := f.(StatefulAnalytic).NewState().(OrderedAnalyticState)
+			rnd := rand.New(rand.NewSource(1))
+			var seen []float64
+			for i := 0; i < 3*maxRankRows; i++ {
+				v := float64(rnd.Intn(500))
+				seen = append(seen, v)
+				got := state.ApplyOrdered([]any{v}, []bool{desc})
+				if i%97 != 0 {
+					continue // 抽样对照，naive 重算为 O(n)
+				}
+				window := seen
+				if len(window) > maxRankRows {
+					window = window[len(window)-maxRankRows:]
+				}
+				require.Equal(t, naiveRank(kind, window, v, desc), got, "%s desc=%v 第 %d 条", kind, desc, i)
+			}
+		}
+	}
+}
+
+// 超出 maxRankRows 后最早的行退出排名集合，状态不再增长。
+func TestRankStateBounded(t *testing.T) {
+	state := NewRankFunction().NewState().(*rankState)
+	for i := 0; i < 3*maxRankRows; i++ {
+		state.ApplyOrdered([]any{i}, nil)
+	}
+	assert.Equal(t, int64(maxRankRows), state.root.rows)
+	assert.LessOrEqual(t, len(state.arrivals), 2*maxRankRows+1)
+	// 最早的 2*maxRankRows 行已淘汰：键 0 排在保留的全部行之前
+	assert.Equal(t, int64(1), state.ApplyOrdered([]any{0}, nil))
+}
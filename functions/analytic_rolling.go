@@ -0,0 +1,118 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+)
+
+// rollingStddevState 是 rolling_stddev 的滑动窗口状态：环形缓冲保存最近 n 个数值，
+// 按 Welford 方法增量维护均值与离差平方和（m2），每条记录 O(1) 计算样本标准差；
+// 相比直接累加平方和，在大均值、小方差的数据上不会因相减抵消丢失精度。缓冲每写满
+// 一圈按实际值重算一次 mean/m2，避免长时间运行的浮点累积误差。
+type rollingStddevState struct {
+	buf   []float64
+	pos   int
+	count int
+	mean  float64
+	m2    float64
+}
+
+func (s *rollingStddevState) Apply(args []any) any {
+	if len(args) < 2 {
+		return nil
+	}
+	n, ok := analyticToInt(args[1])
+	if !ok || n < 2 {
+		return nil
+	}
+	if len(s.buf) != n {
+		// 窗口大小变化（或首条记录）时重新开始预热
+		s.Reset()
+		s.buf = make([]float64, n)
+	}
+	// nil 或非数字值不进入窗口，返回当前窗口的结果
+	if v, ok := toFloat64Generic(args[0]); ok {
+		s.push(v)
+	}
+	return s.stddev()
+}
+
+func (s *rollingStddevState) push(v float64) {
+	if s.count < len(s.buf) {
+		s.count++
+		delta := v - s.mean
+		s.mean += delta / float64(s.count)
+		s.m2 += delta * (v - s.mean)
+	} else {
+		// 窗口已满：用 v 替换最旧的值
+		old := s.buf[s.pos]
+		oldMean := s.mean
+		s.mean += (v - old) / float64(s.count)
+		s.m2 += (v - old) * (v - s.mean + old - oldMean)
+	}
+	s.buf[s.pos] = v
+	s.pos = (s.pos + 1) % len(s.buf)
+	if s.pos == 0 && s.count == len(s.buf) {
+		s.recompute()
+	}
+}
+
+// recompute 按缓冲中的实际值两遍法重算 mean/m2。
+func (s *rollingStddevState) recompute() {
+	sum := 0.0
+	for _, x := range s.buf {
+		sum += x
+	}
+	s.mean = sum / float64(len(s.buf))
+	s.m2 = 0
+	for _, x := range s.buf {
+		s.m2 += (x - s.mean) * (x - s.mean)
+	}
+}
+
+// stddev 返回窗口内的样本标准差，窗口未满（预热期）返回 nil。
+func (s *rollingStddevState) stddev() any {
+	if s.count < len(s.buf) {
+		return nil
+	}
+	variance := s.m2 / float64(s.count-1)
+	if variance < 0 {
+		variance = 0 // 浮点误差可能产生极小的负数
+	}
+	return math.Sqrt(variance)
+}
+
+func (s *rollingStddevState) Reset() {
+	s.buf = nil
+	s.pos, s.count, s.mean, s.m2 = 0, 0, 0, 0
+}
+
+// rollingStddevFunction rolling_stddev(value, n)（TypeAnalytical）：最近 n 条记录的样本标准差。
+// 配合 OVER (PARTITION BY ...) 按分组各持一个窗口。
+type rollingStddevFunction struct {
+	*BaseFunction
+}
+
+func (f *rollingStddevFunction) Validate(args []any) error {
+	if err := f.ValidateArgCount(args); err != nil {
+		return err
+	}
+	if args[1] == nil {
+		return nil
+	}
+	if n, ok := analyticToInt(args[1]); ok && n < 2 {
+		return fmt.Errorf("rolling_stddev window size must be an integer >= 2, got %v", args[1])
+	}
+	return nil
+}
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *rollingStddevFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+func (f *rollingStddevFunction) NewState() AnalyticState { return &rollingStddevState{} }
+
+func NewRollingStddevFunction() *rollingStddevFunction {
+	return &rollingStddevFunction{BaseFunction: NewBaseFunction("rolling_stddev", TypeAnalytical, "分析函数", "最近N条记录的样本标准差", 2, 2)}
+}
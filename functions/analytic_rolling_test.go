@@ -0,0 +1,61 @@
+package functions
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// naiveSampleStddev 按定义重算样本标准差，作为增量结果的对照
+func naiveSampleStddev(vals []float64) float64 {
+	mean := 0.0
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+	ss := 0.0
+	for _, v := range vals {
+		ss += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(ss / float64(len(vals)-1))
+}
+
+func TestRollingStddevState(t *testing.T) {
+	const n = 5
+	f := NewRollingStddevFunction()
+	state := f.NewState()
+
+	rnd := rand.New(rand.NewSource(1))
+	var seen []float64
+	for i := 0; i < 1000; i++ {
+		v := 1e6 + rnd.Float64()*100 // 大均值小方差，检验增量和的精度
+		seen = append(seen, v)
+		got := state.Apply([]any{v, n})
+		if len(seen) < n {
+			assert.Nil(t, got, "预热期第 %d 条应返回 nil", i)
+			continue
+		}
+		require.IsType(t, float64(0), got)
+		assert.InDelta(t, naiveSampleStddev(seen[len(seen)-n:]), got.(float64), 1e-6, "第 %d 条", i)
+	}
+
+	// nil 与非数字值不进入窗口
+	before := state.Apply([]any{nil, n})
+	assert.InDelta(t, naiveSampleStddev(seen[len(seen)-n:]), before.(float64), 1e-6)
+	assert.Equal(t, before, state.Apply([]any{"x", n}))
+
+	state.Reset()
+	assert.Nil(t, state.Apply([]any{1.0, n}))
+}
+
+func TestRollingStddevValidate(t *testing.T) {
+	f := NewRollingStddevFunction()
+	assert.NoError(t, f.Validate([]any{"v", 3}))
+	assert.Error(t, f.Validate([]any{"v", 1}))
+	assert.Error(t, f.Validate([]any{"v"}))
+	_, err := f.Execute(nil, []any{1.0, 3})
+	assert.Error(t, err)
+}
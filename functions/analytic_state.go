@@ -3,6 +3,7 @@ package functions
 import (
 	"reflect"
 	"strings"
+	"time"
 )
 
 // AnalyticState 分析函数的流级状态机。每条事件调 Apply：
@@ -35,6 +36,34 @@ type NamedRowState interface {
 	ApplyNamed(ignoreNull bool, cols map[string]any) any
 }
 
+// OrderedAnalyticState 由排名类分析函数（row_number/rank/dense_rank）实现。引擎把当前行
+// OVER (ORDER BY ...) 各排序键的值及方向（desc[i] 为 true 表示 DESC）按顺序传入
+// ApplyOrdered，取代 Apply(args)。名次按排序键对照分区内已到达的全部行求得。
+type OrderedAnalyticState interface {
+	ApplyOrdered(orderKey []any, desc []bool) any
+}
+
+// PartitionAnalytic 由需要整个分区才能求值的分析函数（ntile）在函数上实现，不走
+// 逐条 Apply 的状态机。引擎收齐分区全部行、按 OVER (ORDER BY ...) 排序后，对第 index 行
+// （从 0 起，分区共 size 行）调用 ApplyPartition 取值。流式逐条求值无法得知分区大小，
+// 故此类函数只用于窗口查询：分区取每次窗口产出的结果行。
+type PartitionAnalytic interface {
+	ApplyPartition(args []any, index, size int) any
+}
+
+// TimedAnalyticState 由按持续时间判定的分析函数（sustained_above/below）实现。引擎传入
+// 当前行的事件时间（WITH (TIMESTAMP=...) 配置时取该列，否则为处理时间），取代 Apply(args)。
+type TimedAnalyticState interface {
+	ApplyAt(ts time.Time, args []any) any
+}
+
+// WindowScopedAnalytic 由状态应随查询窗口重置的分析函数（window_position）在函数上实现。
+// 窗口查询每次窗口产出结果行前，引擎丢弃这类字段的全部分区状态，使其只统计本窗口内的行；
+// 非窗口查询不受影响，状态仍随流保留。
+type WindowScopedAnalytic interface {
+	WindowScoped() bool
+}
+
 // analyticToInt 容错整数转换：lag offset 等参数经 parseFunctionArgs 后可能为
 // int/int64/float64，统一转 int。
 func analyticToInt(v any) (int, bool) {
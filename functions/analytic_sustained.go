@@ -0,0 +1,81 @@
+package functions
+
+import (
+	"fmt"
+	"time"
+)
+
+// sustainedState 是 sustained_above/sustained_below 的持续越限状态：记录本轮越限的
+// 起始事件时间，值持续越限且已满 duration 时返回 true（此后仍越限的记录都返回 true），
+// 值回到阈值内、为 nil 或非数字时清除起点并返回 false。短暂尖峰因此不会触发告警。
+type sustainedState struct {
+	above    bool // true: sustained_above（> t）；false: sustained_below（< t）
+	start    time.Time
+	breached bool
+}
+
+func (s *sustainedState) ApplyAt(ts time.Time, args []any) any {
+	if len(args) < 3 {
+		return false
+	}
+	duration, ok := duplicateWindow(args[2])
+	if !ok {
+		return false
+	}
+	v, ok := toFloat64Generic(args[0])
+	if !ok {
+		s.Reset()
+		return false
+	}
+	threshold, ok := toFloat64Generic(args[1])
+	if !ok {
+		return false
+	}
+	if (s.above && v <= threshold) || (!s.above && v >= threshold) {
+		s.Reset()
+		return false
+	}
+	if !s.breached {
+		s.start = ts
+		s.breached = true
+	}
+	return ts.Sub(s.start) >= duration
+}
+
+// Apply 无事件时间时按处理时间判定。
+func (s *sustainedState) Apply(args []any) any { return s.ApplyAt(time.Now(), args) }
+
+func (s *sustainedState) Reset() { s.start = time.Time{}; s.breached = false }
+
+// sustainedFunction sustained_above/sustained_below(value, threshold, duration)（TypeAnalytical）。
+// 配合 OVER (PARTITION BY ...) 按分组各自计时，用于去抖告警。
+type sustainedFunction struct {
+	*BaseFunction
+	above bool
+}
+
+func (f *sustainedFunction) Validate(args []any) error {
+	if err := f.ValidateArgCount(args); err != nil {
+		return err
+	}
+	if s, ok := args[2].(string); ok {
+		if _, ok := duplicateWindow(s); !ok {
+			return fmt.Errorf("%s duration must be a positive duration such as '10s', got %q", f.GetName(), s)
+		}
+	}
+	return nil
+}
+
+// Execute 标量路径禁用：分析函数需跨行状态，只能作为独立字段/OVER 由状态机求值。
+func (f *sustainedFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return nil, fmt.Errorf("analytic function %q must be used as a field or with OVER, not in a scalar expression", f.GetName())
+}
+
+func (f *sustainedFunction) NewState() AnalyticState { return &sustainedState{above: f.above} }
+
+func NewSustainedAboveFunction() *sustainedFunction {
+	return &sustainedFunction{BaseFunction: NewBaseFunction("sustained_above", TypeAnalytical, "分析函数", "值持续高于阈值达到指定时长后返回true", 3, 3), above: true}
+}
+func NewSustainedBelowFunction() *sustainedFunction {
+	return &sustainedFunction{BaseFunction: NewBaseFunction("sustained_below", TypeAnalytical, "分析函数", "值持续低于阈值达到指定时长后返回true", 3, 3), above: false}
+}
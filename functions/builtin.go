@@ -33,6 +33,7 @@ func registerBuiltinFunctions() {
 	_ = Register(NewTanFunction())
 	_ = Register(NewTanhFunction())
 	_ = Register(NewPowerFunction())
+	_ = Register(NewGeohashFunction())
 
 	// String functions
 	_ = Register(NewConcatFunction())
@@ -65,6 +66,7 @@ func registerBuiltinFunctions() {
 	_ = Register(NewToSecondsFunction())
 	_ = Register(NewChrFunction())
 	_ = Register(NewTruncFunction())
+	_ = Register(NewConvertUnitFunction())
 	_ = Register(NewUrlEncodeFunction())
 	_ = Register(NewUrlDecodeFunction())
 
@@ -73,6 +75,7 @@ func registerBuiltinFunctions() {
 	_ = Register(NewCurrentTimeFunction())
 	_ = Register(NewCurrentDateFunction())
 	_ = Register(NewDateAddFunction())
+	_ = Register(NewDateTruncFunction())
 	_ = Register(NewDateSubFunction())
 	_ = Register(NewDateDiffFunction())
 	_ = Register(NewDateFormatFunction())
@@ -89,6 +92,7 @@ func registerBuiltinFunctions() {
 	_ = Register(NewDayOfWeekFunction())
 	_ = Register(NewDayOfYearFunction())
 	_ = Register(NewWeekOfYearFunction())
+	_ = Register(NewBusinessDurationFunction())
 
 	// Aggregation functions
 	_ = Register(NewSumFunction())
@@ -100,6 +104,7 @@ func registerBuiltinFunctions() {
 	_ = Register(NewMedianAggregatorFunction())
 	_ = Register(NewPercentileAggregatorFunction())
 	_ = Register(NewCollectFunction())
+	_ = Register(NewArrayAggFunction())
 	_ = Register(NewFirstValueFunction())
 	_ = Register(NewLastValueFunction())
 	_ = Register(NewMergeAggFunction())
@@ -107,10 +112,30 @@ func registerBuiltinFunctions() {
 	_ = Register(NewDeduplicateAggregatorFunction())
 	_ = Register(NewVarAggregatorFunction())
 	_ = Register(NewVarSAggregatorFunction())
+	_ = Register(NewIQRAggregatorFunction())
+	_ = Register(NewValueCountsAggregatorFunction())
+	_ = Register(NewCountDistinctAggregatorFunction())
+	_ = Register(NewApproxCountDistinctAggregatorFunction())
+	_ = Register(NewStringAggAggregatorFunction())
+	_ = Register(NewConsecutiveDiffMedianAggregatorFunction())
+	_ = Register(NewTimeInStateAggregatorFunction())
+	_ = Register(NewTrimmedMeanAggregatorFunction())
+	_ = Register(NewTopKAggregatorFunction())
+	_ = Register(NewHistogramAggregatorFunction())
+	_ = Register(NewWeightedAvgAggregatorFunction())
+	_ = Register(NewWindowDeltaAggregatorFunction())
+	_ = Register(NewWindowRateAggregatorFunction())
+	_ = Register(NewRateAggregatorFunction())
+	_ = Register(NewMaxRowAggregatorFunction())
+	_ = Register(NewMinRowAggregatorFunction())
 
 	// Window functions
 	_ = Register(NewWindowStartFunction())
 	_ = Register(NewWindowEndFunction())
+	_ = Register(NewWindowWatermarkFunction())
+	_ = Register(NewWindowFillRatioFunction())
+	_ = Register(NewWindowStartISOFunction())
+	_ = Register(NewWindowEndISOFunction())
 	_ = Register(NewNthValueFunction())
 
 	// Analytical functions
@@ -124,6 +149,17 @@ func registerBuiltinFunctions() {
 	_ = Register(NewAccMinFunction())
 	_ = Register(NewAccCountFunction())
 	_ = Register(NewAccAvgFunction())
+	_ = Register(NewCrossedAboveFunction())
+	_ = Register(NewCrossedBelowFunction())
+	_ = Register(NewIsDuplicateFunction())
+	_ = Register(NewWindowPositionFunction())
+	_ = Register(NewSustainedAboveFunction())
+	_ = Register(NewSustainedBelowFunction())
+	_ = Register(NewRowNumberFunction())
+	_ = Register(NewRankFunction())
+	_ = Register(NewDenseRankFunction())
+	_ = Register(NewNtileFunction())
+	_ = Register(NewRollingStddevFunction())
 
 	// Expression functions
 	_ = Register(NewExpressionFunction())
@@ -143,6 +179,8 @@ func registerBuiltinFunctions() {
 	_ = Register(NewSha1Function())
 	_ = Register(NewSha256Function())
 	_ = Register(NewSha512Function())
+	_ = Register(NewHashFunction())
+	_ = Register(NewCrc32Function())
 
 	// Array functions
 	_ = Register(NewArrayLengthFunction())
@@ -166,10 +204,12 @@ func registerBuiltinFunctions() {
 	// Conditional functions
 	_ = Register(NewIfNullFunction())
 	_ = Register(NewCoalesceFunction())
+	_ = Register(NewCoalesceExprFunction())
 	_ = Register(NewNullIfFunction())
 	_ = Register(NewGreatestFunction())
 	_ = Register(NewLeastFunction())
 	_ = Register(NewCaseWhenFunction())
+	_ = Register(NewInSetFunction())
 
 	// Multi-row functions
 	_ = Register(NewUnnestFunction())
@@ -0,0 +1,268 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// BETWEEN 在 expr-lang 中的实现函数名。PreprocessBetweenExpression 把
+// x [NOT] BETWEEN low AND high 改写为对它们的调用。
+const (
+	betweenFuncName    = "sql_between"
+	notBetweenFuncName = "sql_not_between"
+)
+
+// betweenExprOptions 返回 BETWEEN 改写所依赖的 expr-lang 函数
+func betweenExprOptions() []expr.Option {
+	return []expr.Option{
+		expr.Function(betweenFuncName, func(params ...any) (any, error) {
+			return evalBetween(params, false)
+		}),
+		expr.Function(notBetweenFuncName, func(params ...any) (any, error) {
+			return evalBetween(params, true)
+		}),
+	}
+}
+
+// addBetweenFunctions 把 BETWEEN 函数加入 expr.Eval 使用的环境
+func addBetweenFunctions(env map[string]any) {
+	env[betweenFuncName] = func(v, low, high any) (bool, error) {
+		return evalBetween([]any{v, low, high}, false)
+	}
+	env[notBetweenFuncName] = func(v, low, high any) (bool, error) {
+		return evalBetween([]any{v, low, high}, true)
+	}
+}
+
+// evalBetween 计算 v BETWEEN low AND high（闭区间）或其否定。三者均可转为数值时
+// 按数值比较，均不可转时按字符串比较；任一为 NULL 时两种形式都返回 false。
+func evalBetween(params []any, negate bool) (bool, error) {
+	if len(params) != 3 {
+		return false, fmt.Errorf("BETWEEN requires 3 operands, got %d", len(params))
+	}
+	for _, p := range params {
+		if p == nil {
+			return false, nil
+		}
+	}
+	geLow, err := compareForBetween(params[0], params[1])
+	if err != nil {
+		return false, err
+	}
+	leHigh, err := compareForBetween(params[0], params[2])
+	if err != nil {
+		return false, err
+	}
+	in := geLow >= 0 && leHigh <= 0
+	return in != negate, nil
+}
+
+// compareForBetween 比较 a 与 b，返回 -1/0/1
+func compareForBetween(a, b any) (int, error) {
+	af, aErr := cast.ToFloat64E(a)
+	bf, bErr := cast.ToFloat64E(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case af < bf:
+			return -1, nil
+		case af > bf:
+			return 1, nil
+		}
+		return 0, nil
+	case aErr == nil || bErr == nil:
+		return 0, fmt.Errorf("BETWEEN cannot compare incompatible types: %T and %T", a, b)
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b)), nil
+}
+
+// ContainsBetweenOperator 检查表达式是否包含BETWEEN操作符
+func (bridge *ExprBridge) ContainsBetweenOperator(expression string) bool {
+	return strings.Contains(strings.ToUpper(expression), " BETWEEN ")
+}
+
+// PreprocessBetweenExpression 把 x [NOT] BETWEEN low AND high 转换为 expr-lang 可理解的
+// 函数调用。边界之间的 AND 可以是 AND 或 &&（WHERE/HAVING 解析后的形式）。被比较的
+// 操作数向左取到最近的逻辑运算符、比较运算符、逗号或未闭合的括号为止，上界向右取到
+// 下一个同类分隔符为止，因此 a + 1 BETWEEN 2 AND 3 AND b 中比较的是 a + 1。
+func (bridge *ExprBridge) PreprocessBetweenExpression(expression string) (string, error) {
+	result := expression
+	// 每轮改写最左边的一个 BETWEEN；上限防止异常输入死循环
+	for round := 0; round < 64; round++ {
+		toks := scanBetweenTokens(result)
+		at := -1
+		for i, t := range toks {
+			if strings.EqualFold(t.text, "BETWEEN") {
+				at = i
+				break
+			}
+		}
+		if at < 0 {
+			return result, nil
+		}
+		rewritten, err := rewriteBetween(result, toks, at)
+		if err != nil {
+			return expression, err
+		}
+		result = rewritten
+	}
+	return result, nil
+}
+
+// betweenToken 是 BETWEEN 改写用的词法单元，start/end 为其在原串中的区间
+type betweenToken struct {
+	text       string
+	start, end int
+}
+
+// scanBetweenTokens 切分表达式：引号字符串与反引号标识符为整体，标识符/数字连同
+// 其中的点号为整体，双字符运算符为整体，其余为单字符。
+func scanBetweenTokens(s string) []betweenToken {
+	var toks []betweenToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			i++
+			for i < len(s) && s[i] != c {
+				if s[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			if i < len(s) {
+				i++
+			}
+		case isBetweenWordByte(c):
+			for i < len(s) && (isBetweenWordByte(s[i]) || s[i] == '.') {
+				i++
+			}
+		case i+1 < len(s) && isTwoCharOperator(s[i:i+2]):
+			i += 2
+		default:
+			i++
+		}
+		if i > len(s) {
+			i = len(s)
+		}
+		toks = append(toks, betweenToken{text: s[start:i], start: start, end: i})
+	}
+	return toks
+}
+
+func isBetweenWordByte(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+func isTwoCharOperator(op string) bool {
+	switch op {
+	case "&&", "||", "==", "!=", "<>", ">=", "<=":
+		return true
+	}
+	return false
+}
+
+// isBetweenBoundary 报告 t 是否结束 BETWEEN/IN 的操作数或 BETWEEN 的上界
+func isBetweenBoundary(t string) bool {
+	switch t {
+	case ",", "&&", "||", "!", "==", "=", "!=", "<>", ">", "<", ">=", "<=", "?", ":":
+		return true
+	}
+	switch strings.ToUpper(t) {
+	case "AND", "OR", "NOT", "WHERE", "HAVING", "CASE", "WHEN", "THEN", "ELSE", "END", "IS", "LIKE", "BETWEEN", "IN":
+		return true
+	}
+	return false
+}
+
+// leftOperandStart 返回结束于 toks[end-1] 的操作数的起始下标：向左扫描到同层的
+// 分隔符（见 isBetweenBoundary）或未闭合的左括号为止
+func leftOperandStart(toks []betweenToken, end int) int {
+	k, depth := end-1, 0
+	for ; k >= 0; k-- {
+		t := toks[k].text
+		if t == ")" {
+			depth++
+		} else if t == "(" {
+			if depth == 0 {
+				break
+			}
+			depth--
+		} else if depth == 0 && isBetweenBoundary(t) {
+			break
+		}
+	}
+	return k + 1
+}
+
+// rewriteBetween 改写 toks[at]（BETWEEN）所在的一个 BETWEEN 表达式
+func rewriteBetween(s string, toks []betweenToken, at int) (string, error) {
+	fn := betweenFuncName
+	opEnd := at
+	if at > 0 && strings.EqualFold(toks[at-1].text, "NOT") {
+		fn = notBetweenFuncName
+		opEnd = at - 1
+	}
+
+	operandStart := leftOperandStart(toks, opEnd)
+	if operandStart >= opEnd {
+		return "", fmt.Errorf("BETWEEN is missing its left operand")
+	}
+
+	// 下界：到同层的 AND/&& 为止
+	and := -1
+	depth := 0
+	for j := at + 1; j < len(toks); j++ {
+		t := toks[j].text
+		if t == "(" {
+			depth++
+		} else if t == ")" {
+			if depth == 0 {
+				break
+			}
+			depth--
+		} else if depth == 0 && (strings.EqualFold(t, "AND") || t == "&&") {
+			and = j
+			break
+		}
+	}
+	if and < 0 {
+		return "", fmt.Errorf("BETWEEN requires AND between the bounds")
+	}
+	if and == at+1 {
+		return "", fmt.Errorf("BETWEEN is missing its lower bound")
+	}
+
+	// 上界：到同层的下一个分隔符为止
+	m := and + 1
+	depth = 0
+	for ; m < len(toks); m++ {
+		t := toks[m].text
+		if t == "(" {
+			depth++
+		} else if t == ")" {
+			if depth == 0 {
+				break
+			}
+			depth--
+		} else if depth == 0 && isBetweenBoundary(t) {
+			break
+		}
+	}
+	if m == and+1 {
+		return "", fmt.Errorf("BETWEEN is missing its upper bound")
+	}
+
+	operand := s[toks[operandStart].start:toks[opEnd-1].end]
+	low := s[toks[at+1].start:toks[and-1].end]
+	high := s[toks[and+1].start:toks[m-1].end]
+	call := fmt.Sprintf("%s(%s, %s, %s)", fn, operand, low, high)
+	return s[:toks[operandStart].start] + call + s[toks[m-1].end:], nil
+}
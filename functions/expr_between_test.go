@@ -0,0 +1,65 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreprocessBetweenExpression(t *testing.T) {
+	bridge := NewExprBridge()
+	tests := []struct {
+		in, want string
+	}{
+		{"temperature BETWEEN 20 && 30", "sql_between(temperature, 20, 30)"},
+		{"temperature between 20 and 30", "sql_between(temperature, 20, 30)"},
+		{"sensor.temperature NOT BETWEEN 20 && 30 && a == 1", "sql_not_between(sensor.temperature, 20, 30) && a == 1"},
+		{"a + 1 BETWEEN lo - 1 && abs(hi) || b > 2", "sql_between(a + 1, lo - 1, abs(hi)) || b > 2"},
+		{"(abs ( a - 1 ) between -1 && 5)", "(sql_between(abs ( a - 1 ), -1, 5))"},
+		{"name BETWEEN 'a and b' && 'z' && x BETWEEN 1 && 2", "sql_between(name, 'a and b', 'z') && sql_between(x, 1, 2)"},
+	}
+	for _, tt := range tests {
+		got, err := bridge.PreprocessBetweenExpression(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"BETWEEN 1 && 2", "a BETWEEN 1", "a BETWEEN && 2", "a BETWEEN 1 &&"} {
+		_, err := bridge.PreprocessBetweenExpression(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestEvalBetween(t *testing.T) {
+	tests := []struct {
+		params []any
+		negate bool
+		want   bool
+	}{
+		{[]any{25, 20, 30}, false, true},
+		{[]any{20, 20, 30.0}, false, true},
+		{[]any{30.5, 20, 30}, false, false},
+		{[]any{30.5, 20, 30}, true, true},
+		{[]any{"25", 20, 30}, false, true},
+		{[]any{"bob", "a", "c"}, false, true},
+		{[]any{"dan", "a", "c"}, true, true},
+		{[]any{nil, 20, 30}, false, false},
+		{[]any{nil, 20, 30}, true, false},
+		{[]any{25, nil, 30}, true, false},
+	}
+	for _, tt := range tests {
+		got, err := evalBetween(tt.params, tt.negate)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%v negate=%v", tt.params, tt.negate)
+	}
+
+	_, err := evalBetween([]any{"x", 1, 2}, false)
+	assert.Error(t, err)
+
+	// 经桥接器求值：预处理后调用注册的 expr-lang 函数
+	bridge := NewExprBridge()
+	got, err := bridge.EvaluateExpression("t BETWEEN 20 AND 30", map[string]any{"t": 21})
+	require.NoError(t, err)
+	assert.Equal(t, true, got)
+}
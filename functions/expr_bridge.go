@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/builtin"
 	"github.com/expr-lang/expr/vm"
 	"github.com/rulego/streamsql/utils/cast"
 )
@@ -88,6 +89,11 @@ func (bridge *ExprBridge) RegisterStreamSQLFunctionsToExpr() []expr.Option {
 		))
 	}
 
+	// BETWEEN 改写后的调用目标（见 PreprocessBetweenExpression）
+	options = append(options, betweenExprOptions()...)
+	// IN 改写后的调用目标（见 PreprocessInExpression）
+	options = append(options, inExprOptions()...)
+
 	return options
 }
 
@@ -137,11 +143,13 @@ func (bridge *ExprBridge) CreateEnhancedExprEnvironment(data map[string]any) map
 	env["like_match"] = func(text, pattern string) bool {
 		return bridge.matchesLikePattern(text, pattern)
 	}
+	addBetweenFunctions(env)
+	addInFunctions(env)
 
 	return env
 }
 
-// preprocessCached applies the deterministic backtick / LIKE / IS NULL
+// preprocessCached applies the deterministic backtick / LIKE / IS NULL / BETWEEN / IN / COALESCE
 // preprocessing, memoized per input expression. These transforms depend only
 // on the expression text, so caching avoids repeated ToUpper/Contains/regex
 // scans on every row. The data-dependent string-concat check is NOT cached and
@@ -166,6 +174,21 @@ func (bridge *ExprBridge) preprocessCached(expression string) string {
 			result = processed
 		}
 	}
+	if bridge.ContainsBetweenOperator(result) {
+		if processed, err := bridge.PreprocessBetweenExpression(result); err == nil {
+			result = processed
+		}
+	}
+	if bridge.ContainsInOperator(result) {
+		if processed, err := bridge.PreprocessInExpression(result); err == nil {
+			result = processed
+		}
+	}
+	if bridge.ContainsCoalesceCall(result) {
+		if processed, err := bridge.PreprocessCoalesceExpression(result); err == nil {
+			result = processed
+		}
+	}
 	bridge.preprocessCache.Store(expression, result)
 	return result
 }
@@ -230,7 +253,7 @@ type progCacheEntry struct {
 
 // EvaluateExpression 评估表达式，自动选择最合适的引擎
 func (bridge *ExprBridge) EvaluateExpression(expression string, data map[string]any) (any, error) {
-	// 预处理（反引号 / LIKE / IS NULL）：仅依赖表达式文本，按输入表达式缓存。
+	// 预处理（反引号 / LIKE / IS NULL / BETWEEN）：仅依赖表达式文本，按输入表达式缓存。
 	expression = bridge.preprocessCached(expression)
 
 	// 检查是否包含字符串拼接模式
@@ -572,19 +595,49 @@ func (bridge *ExprBridge) ContainsBacktickIdentifiers(expression string) bool {
 	return strings.Contains(expression, "`")
 }
 
-// PreprocessBacktickIdentifiers 预处理反引号标识符，去除反引号
+// backtickIdentPattern 匹配反引号标识符：`identifier`、`nested.field`、`device id`
+var backtickIdentPattern = regexp.MustCompile("`([^`]+)`")
+
+// plainFieldPathPattern 匹配可直接写入 expr-lang 表达式的字段路径（a 或 a.b.c）
+var plainFieldPathPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// exprLangOperatorWords 是 expr-lang 中作为运算符或字面量的保留词，不能直接作字段名。
+var exprLangOperatorWords = map[string]bool{
+	"in": true, "or": true, "and": true, "not": true, "matches": true, "contains": true,
+	"startsWith": true, "endsWith": true, "let": true, "if": true, "else": true,
+	"nil": true, "true": true, "false": true,
+}
+
+// PreprocessBacktickIdentifiers 预处理反引号标识符：普通字段路径去除反引号；含空格等字符、
+// 或首段与 expr-lang 保留词、内置函数、StreamSQL 函数同名（`count`）的名称改写为
+// $env["..."]，按字段名取值而不是被解析为运算符或函数。
 func (bridge *ExprBridge) PreprocessBacktickIdentifiers(expression string) (string, error) {
-	// 使用正则表达式匹配反引号标识符
-	// 匹配: `identifier` 或 `nested.field`
-	backtickPattern := "`([^`]+)`"
-	re, err := regexp.Compile(backtickPattern)
-	if err != nil {
-		return expression, err
-	}
+	return backtickIdentPattern.ReplaceAllStringFunc(expression, func(m string) string {
+		name := m[1 : len(m)-1]
+		if !plainFieldPathPattern.MatchString(name) {
+			return "$env[" + strconv.Quote(name) + "]"
+		}
+		head, rest, _ := strings.Cut(name, ".")
+		if !isReservedExprName(head) {
+			return name
+		}
+		if rest == "" {
+			return "$env[" + strconv.Quote(head) + "]"
+		}
+		return "$env[" + strconv.Quote(head) + "]." + rest
+	}), nil
+}
 
-	// 替换所有反引号标识符，去除反引号
-	result := re.ReplaceAllString(expression, "$1")
-	return result, nil
+// isReservedExprName 报告 name 作为 expr-lang 标识符时是否会被解析为字段以外的东西。
+func isReservedExprName(name string) bool {
+	if exprLangOperatorWords[name] {
+		return true
+	}
+	if _, ok := builtin.Index[name]; ok {
+		return true
+	}
+	_, ok := Get(name)
+	return ok
 }
 
 // convertLikeToFunction 将LIKE模式转换为expr-lang操作符
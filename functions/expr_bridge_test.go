@@ -255,6 +255,11 @@ func TestExprBridgeAdvancedFunctions(t *testing.T) {
 		processed, err := bridge.PreprocessBacktickIdentifiers("`field_name` = 1")
 		assert.NoError(t, err)
 		assert.Contains(t, processed, "field_name")
+
+		// 不能直接作 expr-lang 标识符的名称改写为 $env 取值
+		processed, err = bridge.PreprocessBacktickIdentifiers("`device id` + `count` + `a.b` + `count.x`")
+		assert.NoError(t, err)
+		assert.Equal(t, `$env["device id"] + $env["count"] + a.b + $env["count"].x`, processed)
 	})
 
 	t.Run("Like Pattern Matching", func(t *testing.T) {
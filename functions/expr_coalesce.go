@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContainsCoalesceCall 检查表达式是否可能包含 coalesce(...) 调用
+func (bridge *ExprBridge) ContainsCoalesceCall(expression string) bool {
+	return strings.Contains(strings.ToLower(expression), "coalesce")
+}
+
+// PreprocessCoalesceExpression 把 coalesce(a, b, c) 改写为 expr-lang 的空值合并
+// ((a) ?? (b) ?? (c))。expr-lang 调用函数前会求值全部参数，而 ?? 只在左侧为 nil 时
+// 才求值右侧，改写后遇到首个非 NULL 参数即短路，之后会出错的参数不再求值。
+// 引号内的文本和 coalesce_expr 等同前缀标识符不改写；嵌套调用一并改写。
+func (bridge *ExprBridge) PreprocessCoalesceExpression(expression string) (string, error) {
+	const name = "coalesce"
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			sb.WriteByte(c)
+			continue
+		}
+		if c == '\'' || c == '"' || c == '`' {
+			quote = c
+			sb.WriteByte(c)
+			continue
+		}
+		if !isCoalesceAt(expression, i) {
+			sb.WriteByte(c)
+			continue
+		}
+		open := skipExprSpaces(expression, i+len(name))
+		args, end, err := splitCallArgs(expression, open)
+		if err != nil {
+			return expression, err
+		}
+		parts := make([]string, len(args))
+		for j, a := range args {
+			rewritten, err := bridge.PreprocessCoalesceExpression(a)
+			if err != nil {
+				return expression, err
+			}
+			parts[j] = "(" + rewritten + ")"
+		}
+		sb.WriteString("(" + strings.Join(parts, " ?? ") + ")")
+		i = end
+	}
+	return sb.String(), nil
+}
+
+// isCoalesceAt 判断 expression[i:] 是否以独立标识符 coalesce 开头且后接 '('
+func isCoalesceAt(expression string, i int) bool {
+	const name = "coalesce"
+	if i+len(name) > len(expression) || !strings.EqualFold(expression[i:i+len(name)], name) {
+		return false
+	}
+	if i > 0 && isExprIdentByte(expression[i-1]) {
+		return false
+	}
+	open := skipExprSpaces(expression, i+len(name))
+	return open < len(expression) && expression[open] == '('
+}
+
+// splitCallArgs 从 expression[open]=='(' 起按顶层逗号切分调用参数，返回参数文本
+// 与匹配的 ')' 位置。空参数列表视为错误。
+func splitCallArgs(expression string, open int) ([]string, int, error) {
+	var args []string
+	depth, start := 0, open+1
+	var quote byte
+	for i := open; i < len(expression); i++ {
+		c := expression[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+			if depth == 0 {
+				last := strings.TrimSpace(expression[start:i])
+				if last == "" && len(args) == 0 {
+					return nil, 0, fmt.Errorf("coalesce requires at least 1 argument")
+				}
+				return append(args, last), i, nil
+			}
+		case c == ',' && depth == 1:
+			args = append(args, strings.TrimSpace(expression[start:i]))
+			start = i + 1
+		}
+	}
+	return nil, 0, fmt.Errorf("unclosed coalesce call in %q", expression)
+}
+
+func skipExprSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func isExprIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,66 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreprocessCoalesceExpression(t *testing.T) {
+	bridge := NewExprBridge()
+	tests := []struct {
+		in, want string
+	}{
+		{"coalesce(a, b, 'default')", "((a) ?? (b) ?? ('default'))"},
+		{"COALESCE (a,0) + 1", "((a) ?? (0)) + 1"},
+		{"coalesce(a, coalesce(b, c)) > 2", "((a) ?? (((b) ?? (c)))) > 2"},
+		{"coalesce(f(x, y), 'a,b')", "((f(x, y)) ?? ('a,b'))"},
+		{"coalesce_expr(a, 0)", "coalesce_expr(a, 0)"},
+		{"my_coalesce(a, 0)", "my_coalesce(a, 0)"},
+		{"concat('coalesce(', a)", "concat('coalesce(', a)"},
+	}
+	for _, tt := range tests {
+		got, err := bridge.PreprocessCoalesceExpression(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"coalesce()", "coalesce(a, b"} {
+		_, err := bridge.PreprocessCoalesceExpression(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestCoalesceShortCircuit 测试 coalesce 找到非 NULL 参数后不再求值后续参数
+func TestCoalesceShortCircuit(t *testing.T) {
+	bridge := NewExprBridge()
+	v, err := bridge.EvaluateExpression("coalesce(a, sqrt(-1))", map[string]any{"a": 2.0})
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, v)
+
+	_, err = bridge.EvaluateExpression("coalesce(a, sqrt(-1))", map[string]any{"a": nil})
+	assert.Error(t, err, "首个参数为 NULL 时求值后续参数")
+
+	v, err = bridge.EvaluateExpression("coalesce(a, b, 'default')", map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, "default", v)
+
+	calls := 0
+	args := []LazyArg{
+		func() (any, error) { calls++; return nil, nil },
+		func() (any, error) { calls++; return "x", nil },
+		func() (any, error) { calls++; return nil, assert.AnError },
+	}
+	v, err = NewCoalesceFunction().ExecuteLazy(nil, args)
+	require.NoError(t, err)
+	assert.Equal(t, "x", v)
+	assert.Equal(t, 2, calls)
+
+	v, err = NewNullIfFunction().ExecuteLazy(nil, []LazyArg{
+		func() (any, error) { return nil, nil },
+		func() (any, error) { return nil, assert.AnError },
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
@@ -0,0 +1,183 @@
+package functions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// inKeyFuncName 是 IN 改写后归一化被比较值的 expr-lang 函数名。
+// PreprocessInExpression 把 x [NOT] IN (a, b) 改写为 sql_in_key(x) in [...]。
+const inKeyFuncName = "sql_in_key"
+
+var inOperatorPattern = regexp.MustCompile(`(?i)\bIN\s*\(`)
+
+// inExprOptions 返回 IN 改写所依赖的 expr-lang 函数
+func inExprOptions() []expr.Option {
+	return []expr.Option{
+		expr.Function(inKeyFuncName, func(params ...any) (any, error) {
+			if len(params) != 1 {
+				return nil, fmt.Errorf("%s requires 1 parameter, got %d", inKeyFuncName, len(params))
+			}
+			return inKey(params[0]), nil
+		}),
+	}
+}
+
+// addInFunctions 把 IN 函数加入 expr.Eval 使用的环境
+func addInFunctions(env map[string]any) {
+	env[inKeyFuncName] = func(v any) any { return inKey(v) }
+}
+
+// inKey 把 IN 的被比较值与列表元素归一化为同一键空间，等值比较规则与其他比较一致：
+// 可转为数值的按数值比较（1、1.0、"1" 相同），否则按字符串比较。NULL 返回 nil，
+// 不匹配任何元素。
+func inKey(v any) any {
+	if v == nil {
+		return nil
+	}
+	if f, err := cast.ToFloat64E(v); err == nil {
+		return "n:" + strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return "s:" + fmt.Sprintf("%v", v)
+}
+
+// ContainsInOperator 检查表达式是否包含 IN (list) 操作符
+func (bridge *ExprBridge) ContainsInOperator(expression string) bool {
+	return inOperatorPattern.MatchString(expression)
+}
+
+// PreprocessInExpression 把 x [NOT] IN (a, b, ...) 转换为 expr-lang 的集合成员判断。
+// 字面量元素在改写时即归一化为字符串常量，expr-lang 编译时把全常量列表优化为哈希集合，
+// 因此长列表也只做一次查找，而不是一串 OR。被比较的操作数向左的取值范围同 BETWEEN。
+// NULL 操作数使 IN 与 NOT IN 都为 false；空列表 IN () 为 false，NOT IN () 为 true。
+func (bridge *ExprBridge) PreprocessInExpression(expression string) (string, error) {
+	result := expression
+	// 每轮改写最左边的一个 IN；上限防止异常输入死循环
+	for round := 0; round < 64; round++ {
+		toks := scanBetweenTokens(result)
+		at := -1
+		for i := 0; i+1 < len(toks); i++ {
+			if strings.EqualFold(toks[i].text, "IN") && toks[i+1].text == "(" {
+				at = i
+				break
+			}
+		}
+		if at < 0 {
+			return result, nil
+		}
+		rewritten, err := rewriteIn(result, toks, at)
+		if err != nil {
+			return expression, err
+		}
+		result = rewritten
+	}
+	return result, nil
+}
+
+// rewriteIn 改写 toks[at]（IN）所在的一个 IN 表达式
+func rewriteIn(s string, toks []betweenToken, at int) (string, error) {
+	negate := false
+	opEnd := at
+	if at > 0 && strings.EqualFold(toks[at-1].text, "NOT") {
+		negate = true
+		opEnd = at - 1
+	}
+	operandStart := leftOperandStart(toks, opEnd)
+	if operandStart >= opEnd {
+		return "", fmt.Errorf("IN is missing its left operand")
+	}
+
+	// 列表：匹配的右括号之前，按同层逗号切分
+	var elems [][2]int
+	elemStart, depth, closing := at+2, 0, -1
+	for j := at + 2; j < len(toks) && closing < 0; j++ {
+		switch toks[j].text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			if depth > 0 {
+				depth--
+				continue
+			}
+			closing = j
+			fallthrough
+		case ",":
+			if depth > 0 {
+				continue
+			}
+			if j == elemStart {
+				if closing == j && len(elems) == 0 {
+					continue // 空列表
+				}
+				return "", fmt.Errorf("IN list has an empty element")
+			}
+			elems = append(elems, [2]int{elemStart, j})
+			elemStart = j + 1
+		}
+	}
+	if closing < 0 {
+		return "", fmt.Errorf("IN list is missing its closing parenthesis")
+	}
+
+	var call string
+	if len(elems) == 0 {
+		call = strconv.FormatBool(negate)
+	} else {
+		keys := make([]string, len(elems))
+		for i, e := range elems {
+			if key, ok := inLiteralKey(toks[e[0]:e[1]]); ok {
+				keys[i] = key
+			} else {
+				keys[i] = fmt.Sprintf("%s(%s)", inKeyFuncName, s[toks[e[0]].start:toks[e[1]-1].end])
+			}
+		}
+		operand := fmt.Sprintf("%s(%s)", inKeyFuncName, s[toks[operandStart].start:toks[opEnd-1].end])
+		list := "[" + strings.Join(keys, ", ") + "]"
+		if negate {
+			call = fmt.Sprintf("(%s != nil && %s not in %s)", operand, operand, list)
+		} else {
+			call = fmt.Sprintf("(%s in %s)", operand, list)
+		}
+	}
+	return s[:toks[operandStart].start] + call + s[toks[closing].end:], nil
+}
+
+// inLiteralKey 返回字面量元素（数字、可带负号；不含转义的引号字符串；true/false）
+// 归一化后的键，以 expr-lang 字符串字面量表示；NULL 与非字面量元素返回 false。
+func inLiteralKey(toks []betweenToken) (string, bool) {
+	sign := ""
+	if len(toks) == 2 && (toks[0].text == "-" || toks[0].text == "+") {
+		sign, toks = toks[0].text, toks[1:]
+	}
+	if len(toks) != 1 {
+		return "", false
+	}
+	t := toks[0].text
+	var v any
+	switch {
+	case len(t) >= 2 && (t[0] == '\'' || t[0] == '"') && t[len(t)-1] == t[0]:
+		if sign != "" || strings.ContainsRune(t, '\\') {
+			return "", false
+		}
+		v = t[1 : len(t)-1]
+	case strings.EqualFold(t, "true") || strings.EqualFold(t, "false"):
+		if sign != "" {
+			return "", false
+		}
+		v = strings.EqualFold(t, "true")
+	case t[0] >= '0' && t[0] <= '9' || t[0] == '.':
+		f, err := strconv.ParseFloat(sign+t, 64)
+		if err != nil {
+			return "", false
+		}
+		v = f
+	default:
+		return "", false
+	}
+	return strconv.Quote(inKey(v).(string)), true
+}
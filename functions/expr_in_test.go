@@ -0,0 +1,79 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/expr-lang/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreprocessInExpression(t *testing.T) {
+	bridge := NewExprBridge()
+	tests := []struct {
+		in, want string
+	}{
+		{"deviceId IN ('a', 'b')", `(sql_in_key(deviceId) in ["s:a", "s:b"])`},
+		{"status not in (1, 2.0, -3) && x > 1", `(sql_in_key(status) != nil && sql_in_key(status) not in ["n:1", "n:2", "n:-3"]) && x > 1`},
+		{"a.b IN ('1', c, abs(d))", `(sql_in_key(a.b) in ["n:1", sql_in_key(c), sql_in_key(abs(d))])`},
+		{"x IN () || y NOT IN ()", "false || true"},
+		{"name IN ('in (x)', 'a\\'b')", `(sql_in_key(name) in ["s:in (x)", sql_in_key('a\'b')])`},
+		{"x + 1 IN (2) && y BETWEEN 1 AND 2", `(sql_in_key(x + 1) in ["n:2"]) && y BETWEEN 1 AND 2`},
+	}
+	for _, tt := range tests {
+		got, err := bridge.PreprocessInExpression(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, bad := range []string{"IN (1)", "x IN (1, )", "x IN (1"} {
+		_, err := bridge.PreprocessInExpression(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestInKey(t *testing.T) {
+	assert.Equal(t, inKey(1), inKey(1.0))
+	assert.Equal(t, inKey(1), inKey("1"))
+	assert.NotEqual(t, inKey(1), inKey("a"))
+	assert.Equal(t, inKey("a"), inKey("a"))
+	assert.Nil(t, inKey(nil))
+}
+
+func TestInExpressionEvaluation(t *testing.T) {
+	bridge := NewExprBridge()
+	rewritten, err := bridge.PreprocessInExpression("deviceId IN ('a', 'b', 'c')")
+	require.NoError(t, err)
+
+	// 全字面量列表由 expr-lang 编译为哈希集合，而不是逐个比较
+	program, err := expr.Compile(rewritten, append(inExprOptions(), expr.AllowUndefinedVariables())...)
+	require.NoError(t, err)
+	hasSet := false
+	for _, c := range program.Constants {
+		if _, ok := c.(map[string]struct{}); ok {
+			hasSet = true
+		}
+	}
+	assert.True(t, hasSet, "expected a set constant in %s", program.Disassemble())
+
+	for _, tt := range []struct {
+		data map[string]any
+		want bool
+	}{
+		{map[string]any{"deviceId": "b"}, true},
+		{map[string]any{"deviceId": "z"}, false},
+		{map[string]any{"deviceId": nil}, false},
+		{map[string]any{}, false},
+	} {
+		got, err := expr.Run(program, tt.data)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%v", tt.data)
+	}
+
+	got, err := bridge.EvaluateExpression("status NOT IN (1, 2)", map[string]any{"status": 3})
+	require.NoError(t, err)
+	assert.Equal(t, true, got)
+	got, err = bridge.EvaluateExpression("status NOT IN (1, 2)", map[string]any{"status": nil})
+	require.NoError(t, err)
+	assert.Equal(t, false, got)
+}
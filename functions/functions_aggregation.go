@@ -1,11 +1,14 @@
 package functions
 
 import (
+	"container/heap"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/rulego/streamsql/metrics"
 	"github.com/rulego/streamsql/utils/cast"
 )
 
@@ -553,9 +556,11 @@ func (f *PercentileFunction) Execute(ctx *FunctionContext, args []any) (any, err
 	return values[index], nil
 }
 
-// CollectFunction 收集函数 - 获取当前窗口所有消息的列值组成的数组
+// CollectFunction 收集函数 - 获取当前窗口所有消息的列值组成的数组。
+// 数组按行加入分组的顺序排列，NULL（含缺失字段）收集为 nil，下标与行一一对应。
 type CollectFunction struct {
 	*BaseFunction
+	bufferGuard
 	values []any
 }
 
@@ -586,7 +591,7 @@ func (f *CollectFunction) New() AggregatorFunction {
 }
 
 func (f *CollectFunction) Add(value any) {
-	f.values = append(f.values, value)
+	f.values = f.appendAny(f.values, value)
 }
 
 func (f *CollectFunction) Result() any {
@@ -597,17 +602,79 @@ func (f *CollectFunction) Result() any {
 
 func (f *CollectFunction) Reset() {
 	f.values = make([]any, 0)
+	f.resetGuard()
 }
 
 func (f *CollectFunction) Clone() AggregatorFunction {
 	newFunc := &CollectFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]any, len(f.values)),
 	}
 	copy(newFunc.values, f.values)
 	return newFunc
 }
 
+// ArrayAggFunction 数组聚合函数 - array_agg(expr) 把窗口内每行的表达式值按到达顺序
+// 收集为 []any，保留重复值与 NULL（与 deduplicate 不同，不做去重）。没有值时返回空切片
+// 而不是 nil，JSON 序列化为 []。
+type ArrayAggFunction struct {
+	*BaseFunction
+	bufferGuard
+	values []any
+}
+
+func NewArrayAggFunction() *ArrayAggFunction {
+	return &ArrayAggFunction{
+		BaseFunction: NewBaseFunction("array_agg", TypeAggregation, "聚合函数", "按到达顺序收集所有值（保留重复值）", 1, 1),
+		values:       make([]any, 0),
+	}
+}
+
+func (f *ArrayAggFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *ArrayAggFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return []any{args[0]}, nil
+}
+
+// 实现AggregatorFunction接口
+func (f *ArrayAggFunction) New() AggregatorFunction {
+	return &ArrayAggFunction{
+		BaseFunction: f.BaseFunction,
+		values:       make([]any, 0),
+	}
+}
+
+func (f *ArrayAggFunction) Add(value any) {
+	f.values = f.appendAny(f.values, value)
+}
+
+func (f *ArrayAggFunction) Result() any {
+	result := make([]any, len(f.values))
+	copy(result, f.values)
+	return result
+}
+
+func (f *ArrayAggFunction) Reset() {
+	f.values = make([]any, 0)
+	f.resetGuard()
+}
+
+func (f *ArrayAggFunction) Clone() AggregatorFunction {
+	clone := &ArrayAggFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		values:       make([]any, len(f.values)),
+	}
+	copy(clone.values, f.values)
+	return clone
+}
+
 // FirstValueFunction 首个值函数 - 返回组中第一行的值
 type FirstValueFunction struct {
 	*BaseFunction
@@ -920,187 +987,10 @@ func (f *DeduplicateFunction) Execute(ctx *FunctionContext, args []any) (any, er
 	return result, nil
 }
 
-// VarFunction 总体方差函数（韦尔福德算法实现）
-type VarFunction struct {
-	*BaseFunction
-	count int
-	mean  float64
-	m2    float64
-}
-
-func NewVarFunction() *VarFunction {
-	return &VarFunction{
-		BaseFunction: NewBaseFunction("var", TypeAggregation, "聚合函数", "计算总体方差", 1, -1),
-	}
-}
-
-func (f *VarFunction) Validate(args []any) error {
-	return f.ValidateArgCount(args)
-}
-
-func (f *VarFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	// 批量执行模式
-	sum := 0.0
-	count := 0
-	for _, arg := range args {
-		val, err := cast.ToFloat64E(arg)
-		if err != nil {
-			continue
-		}
-		sum += val
-		count++
-	}
-	if count == 0 {
-		return 0.0, nil
-	}
-	mean := sum / float64(count)
-	variance := 0.0
-	for _, arg := range args {
-		val, err := cast.ToFloat64E(arg)
-		if err != nil {
-			continue
-		}
-		variance += math.Pow(val-mean, 2)
-	}
-	return variance / float64(count), nil
-}
-
-// 实现AggregatorFunction接口 - 韦尔福德算法
-func (f *VarFunction) New() AggregatorFunction {
-	return &VarFunction{
-		BaseFunction: f.BaseFunction,
-		count:        0,
-		mean:         0,
-		m2:           0,
-	}
-}
-
-func (f *VarFunction) Add(value any) {
-	val, err := cast.ToFloat64E(value)
-	if err != nil {
-		return
-	}
-	f.count++
-	delta := val - f.mean
-	f.mean += delta / float64(f.count)
-	delta2 := val - f.mean
-	f.m2 += delta * delta2
-}
-
-func (f *VarFunction) Result() any {
-	if f.count < 1 {
-		return 0.0
-	}
-	return f.m2 / float64(f.count)
-}
-
-func (f *VarFunction) Reset() {
-	f.count = 0
-	f.mean = 0
-	f.m2 = 0
-}
-
-func (f *VarFunction) Clone() AggregatorFunction {
-	return &VarFunction{
-		BaseFunction: f.BaseFunction,
-		count:        f.count,
-		mean:         f.mean,
-		m2:           f.m2,
-	}
-}
-
-// VarSFunction 样本方差函数（韦尔福德算法实现）
-type VarSFunction struct {
-	*BaseFunction
-	count int
-	mean  float64
-	m2    float64
-}
-
-func NewVarSFunction() *VarSFunction {
-	return &VarSFunction{
-		BaseFunction: NewBaseFunction("vars", TypeAggregation, "聚合函数", "计算样本方差", 1, -1),
-	}
-}
-
-func (f *VarSFunction) Validate(args []any) error {
-	return f.ValidateArgCount(args)
-}
-
-func (f *VarSFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	// 批量执行模式
-	sum := 0.0
-	count := 0
-	for _, arg := range args {
-		val, err := cast.ToFloat64E(arg)
-		if err != nil {
-			continue
-		}
-		sum += val
-		count++
-	}
-	if count <= 1 {
-		return 0.0, nil
-	}
-	mean := sum / float64(count)
-	variance := 0.0
-	for _, arg := range args {
-		val, err := cast.ToFloat64E(arg)
-		if err != nil {
-			continue
-		}
-		variance += math.Pow(val-mean, 2)
-	}
-	return variance / float64(count-1), nil
-}
-
-// 实现AggregatorFunction接口 - 韦尔福德算法
-func (f *VarSFunction) New() AggregatorFunction {
-	return &VarSFunction{
-		BaseFunction: f.BaseFunction,
-		count:        0,
-		mean:         0,
-		m2:           0,
-	}
-}
-
-func (f *VarSFunction) Add(value any) {
-	val, err := cast.ToFloat64E(value)
-	if err != nil {
-		return
-	}
-	f.count++
-	delta := val - f.mean
-	f.mean += delta / float64(f.count)
-	delta2 := val - f.mean
-	f.m2 += delta * delta2
-}
-
-func (f *VarSFunction) Result() any {
-	if f.count < 2 {
-		return 0.0
-	}
-	return f.m2 / float64(f.count-1)
-}
-
-func (f *VarSFunction) Reset() {
-	f.count = 0
-	f.mean = 0
-	f.m2 = 0
-}
-
-func (f *VarSFunction) Clone() AggregatorFunction {
-	return &VarSFunction{
-		BaseFunction: f.BaseFunction,
-		count:        f.count,
-		mean:         f.mean,
-		m2:           f.m2,
-	}
-}
-
 // 为StdDevFunction添加AggregatorFunction接口实现
 type StdDevAggregatorFunction struct {
 	*BaseFunction
+	bufferGuard
 	values []float64
 }
 
@@ -1128,7 +1018,7 @@ func (f *StdDevAggregatorFunction) New() AggregatorFunction {
 
 func (f *StdDevAggregatorFunction) Add(value any) {
 	if val, err := cast.ToFloat64E(value); err == nil {
-		f.values = append(f.values, val)
+		f.values = f.appendFloat(f.values, val)
 	}
 }
 
@@ -1155,11 +1045,13 @@ func (f *StdDevAggregatorFunction) Result() any {
 
 func (f *StdDevAggregatorFunction) Reset() {
 	f.values = make([]float64, 0)
+	f.resetGuard()
 }
 
 func (f *StdDevAggregatorFunction) Clone() AggregatorFunction {
 	clone := &StdDevAggregatorFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]float64, len(f.values)),
 	}
 	copy(clone.values, f.values)
@@ -1169,6 +1061,7 @@ func (f *StdDevAggregatorFunction) Clone() AggregatorFunction {
 // 为MedianFunction添加AggregatorFunction接口实现
 type MedianAggregatorFunction struct {
 	*BaseFunction
+	bufferGuard
 	values []float64
 }
 
@@ -1196,7 +1089,7 @@ func (f *MedianAggregatorFunction) New() AggregatorFunction {
 
 func (f *MedianAggregatorFunction) Add(value any) {
 	if val, err := cast.ToFloat64E(value); err == nil {
-		f.values = append(f.values, val)
+		f.values = f.appendFloat(f.values, val)
 	}
 }
 
@@ -1208,7 +1101,12 @@ func (f *MedianAggregatorFunction) Result() any {
 	sorted := make([]float64, len(f.values))
 	copy(sorted, f.values)
 	sort.Float64s(sorted)
+	return medianOfSorted(sorted)
+}
 
+// medianOfSorted 取已升序 sorted 的中位数（偶数个取中间两值均值），
+// 供 median 与 consecutive_diff_median 共用。sorted 不可为空。
+func medianOfSorted(sorted []float64) float64 {
 	mid := len(sorted) / 2
 	if len(sorted)%2 == 0 {
 		return (sorted[mid-1] + sorted[mid]) / 2
@@ -1218,11 +1116,13 @@ func (f *MedianAggregatorFunction) Result() any {
 
 func (f *MedianAggregatorFunction) Reset() {
 	f.values = make([]float64, 0)
+	f.resetGuard()
 }
 
 func (f *MedianAggregatorFunction) Clone() AggregatorFunction {
 	clone := &MedianAggregatorFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]float64, len(f.values)),
 	}
 	copy(clone.values, f.values)
@@ -1232,6 +1132,7 @@ func (f *MedianAggregatorFunction) Clone() AggregatorFunction {
 // 为PercentileFunction添加AggregatorFunction接口实现
 type PercentileAggregatorFunction struct {
 	*BaseFunction
+	bufferGuard
 	values []float64
 	p      float64
 }
@@ -1262,7 +1163,7 @@ func (f *PercentileAggregatorFunction) New() AggregatorFunction {
 
 func (f *PercentileAggregatorFunction) Add(value any) {
 	if val, err := cast.ToFloat64E(value); err == nil {
-		f.values = append(f.values, val)
+		f.values = f.appendFloat(f.values, val)
 	}
 }
 
@@ -1274,9 +1175,16 @@ func (f *PercentileAggregatorFunction) Result() any {
 	sorted := make([]float64, len(f.values))
 	copy(sorted, f.values)
 	sort.Float64s(sorted)
+	return percentileOfSorted(sorted, f.p)
+}
 
-	index := int(math.Floor(f.p * float64(len(sorted)-1)))
-	if index >= len(sorted) {
+// percentileOfSorted 取已升序 sorted 的 p 分位值（index=floor(p*(n-1))），
+// 供 percentile 与 iqr 共用同一口径。sorted 不可为空。
+func percentileOfSorted(sorted []float64, p float64) float64 {
+	index := int(math.Floor(p * float64(len(sorted)-1)))
+	if index < 0 {
+		index = 0
+	} else if index >= len(sorted) {
 		index = len(sorted) - 1
 	}
 	return sorted[index]
@@ -1284,11 +1192,13 @@ func (f *PercentileAggregatorFunction) Result() any {
 
 func (f *PercentileAggregatorFunction) Reset() {
 	f.values = make([]float64, 0)
+	f.resetGuard()
 }
 
 func (f *PercentileAggregatorFunction) Clone() AggregatorFunction {
 	clone := &PercentileAggregatorFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]float64, len(f.values)),
 		p:            f.p,
 	}
@@ -1476,6 +1386,7 @@ func (f *MergeAggAggregatorFunction) Clone() AggregatorFunction {
 // 为StdDevSFunction添加AggregatorFunction接口实现
 type StdDevSAggregatorFunction struct {
 	*BaseFunction
+	bufferGuard
 	values []float64
 }
 
@@ -1504,7 +1415,7 @@ func (f *StdDevSAggregatorFunction) New() AggregatorFunction {
 func (f *StdDevSAggregatorFunction) Add(value any) {
 	if value != nil {
 		if val, err := cast.ToFloat64E(value); err == nil {
-			f.values = append(f.values, val)
+			f.values = f.appendFloat(f.values, val)
 		}
 	}
 }
@@ -1533,11 +1444,13 @@ func (f *StdDevSAggregatorFunction) Result() any {
 
 func (f *StdDevSAggregatorFunction) Reset() {
 	f.values = make([]float64, 0)
+	f.resetGuard()
 }
 
 func (f *StdDevSAggregatorFunction) Clone() AggregatorFunction {
 	clone := &StdDevSAggregatorFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]float64, len(f.values)),
 	}
 	copy(clone.values, f.values)
@@ -1605,16 +1518,38 @@ func (f *DeduplicateAggregatorFunction) Clone() AggregatorFunction {
 	return clone
 }
 
-// 为VarFunction添加AggregatorFunction接口实现
+// welford 以韦尔福德算法增量维护计数、均值与离差平方和：无需缓冲窗口内的值，
+// 大窗口或大数值下也不会像先求和再求方差那样损失精度。
+type welford struct {
+	count int
+	mean  float64
+	m2    float64
+}
+
+// add 累加一个值，nil 和非数值忽略。
+func (w *welford) add(value any) {
+	if value == nil {
+		return
+	}
+	val, err := cast.ToFloat64E(value)
+	if err != nil {
+		return
+	}
+	w.count++
+	delta := val - w.mean
+	w.mean += delta / float64(w.count)
+	w.m2 += delta * (val - w.mean)
+}
+
+// VarAggregatorFunction 总体方差聚合函数 var(x)：增量计算，空窗口或单值窗口返回 0。
 type VarAggregatorFunction struct {
 	*BaseFunction
-	values []float64
+	welford
 }
 
 func NewVarAggregatorFunction() *VarAggregatorFunction {
 	return &VarAggregatorFunction{
 		BaseFunction: NewBaseFunction("var", TypeAggregation, "聚合函数", "计算总体方差", 1, -1),
-		values:       make([]float64, 0),
 	}
 }
 
@@ -1623,69 +1558,45 @@ func (f *VarAggregatorFunction) Validate(args []any) error {
 }
 
 func (f *VarAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	return NewVarFunction().Execute(ctx, args)
+	agg := f.New()
+	for _, arg := range args {
+		agg.Add(arg)
+	}
+	return agg.Result(), nil
 }
 
 func (f *VarAggregatorFunction) New() AggregatorFunction {
-	return &VarAggregatorFunction{
-		BaseFunction: f.BaseFunction,
-		values:       make([]float64, 0),
-	}
+	return &VarAggregatorFunction{BaseFunction: f.BaseFunction}
 }
 
 func (f *VarAggregatorFunction) Add(value any) {
-	if value != nil {
-		if val, err := cast.ToFloat64E(value); err == nil {
-			f.values = append(f.values, val)
-		}
-	}
+	f.add(value)
 }
 
 func (f *VarAggregatorFunction) Result() any {
-	if len(f.values) < 1 {
+	if f.count < 1 {
 		return 0.0
 	}
-
-	// 计算平均值
-	sum := 0.0
-	for _, v := range f.values {
-		sum += v
-	}
-	mean := sum / float64(len(f.values))
-
-	// 计算总体方差
-	variance := 0.0
-	for _, v := range f.values {
-		variance += math.Pow(v-mean, 2)
-	}
-	variance = variance / float64(len(f.values)) // 总体方差使用n
-
-	return variance
+	return f.m2 / float64(f.count) // 总体方差使用n
 }
 
 func (f *VarAggregatorFunction) Reset() {
-	f.values = make([]float64, 0)
+	f.welford = welford{}
 }
 
 func (f *VarAggregatorFunction) Clone() AggregatorFunction {
-	clone := &VarAggregatorFunction{
-		BaseFunction: f.BaseFunction,
-		values:       make([]float64, len(f.values)),
-	}
-	copy(clone.values, f.values)
-	return clone
+	return &VarAggregatorFunction{BaseFunction: f.BaseFunction, welford: f.welford}
 }
 
-// 为VarSFunction添加AggregatorFunction接口实现
+// VarSAggregatorFunction 样本方差聚合函数 vars(x)：增量计算，少于 2 个值时样本方差无定义，返回 nil。
 type VarSAggregatorFunction struct {
 	*BaseFunction
-	values []float64
+	welford
 }
 
 func NewVarSAggregatorFunction() *VarSAggregatorFunction {
 	return &VarSAggregatorFunction{
 		BaseFunction: NewBaseFunction("vars", TypeAggregation, "聚合函数", "计算样本方差", 1, -1),
-		values:       make([]float64, 0),
 	}
 }
 
@@ -1694,55 +1605,1521 @@ func (f *VarSAggregatorFunction) Validate(args []any) error {
 }
 
 func (f *VarSAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	return NewVarSFunction().Execute(ctx, args)
+	agg := f.New()
+	for _, arg := range args {
+		agg.Add(arg)
+	}
+	return agg.Result(), nil
 }
 
 func (f *VarSAggregatorFunction) New() AggregatorFunction {
-	return &VarSAggregatorFunction{
-		BaseFunction: f.BaseFunction,
-		values:       make([]float64, 0),
-	}
+	return &VarSAggregatorFunction{BaseFunction: f.BaseFunction}
 }
 
 func (f *VarSAggregatorFunction) Add(value any) {
-	if value != nil {
-		if val, err := cast.ToFloat64E(value); err == nil {
-			f.values = append(f.values, val)
-		}
-	}
+	f.add(value)
 }
 
 func (f *VarSAggregatorFunction) Result() any {
-	if len(f.values) < 2 {
+	if f.count < 2 {
+		return nil
+	}
+	return f.m2 / float64(f.count-1) // 样本方差使用n-1
+}
+
+func (f *VarSAggregatorFunction) Reset() {
+	f.welford = welford{}
+}
+
+func (f *VarSAggregatorFunction) Clone() AggregatorFunction {
+	return &VarSAggregatorFunction{BaseFunction: f.BaseFunction, welford: f.welford}
+}
+
+// VarFunction 总体方差函数，保留给既有调用方。
+//
+// Deprecated: 使用 VarAggregatorFunction。
+type VarFunction struct {
+	*VarAggregatorFunction
+}
+
+// Deprecated: 使用 NewVarAggregatorFunction。
+func NewVarFunction() *VarFunction {
+	return &VarFunction{VarAggregatorFunction: NewVarAggregatorFunction()}
+}
+
+func (f *VarFunction) New() AggregatorFunction {
+	return &VarFunction{VarAggregatorFunction: f.VarAggregatorFunction.New().(*VarAggregatorFunction)}
+}
+
+func (f *VarFunction) Clone() AggregatorFunction {
+	return &VarFunction{VarAggregatorFunction: f.VarAggregatorFunction.Clone().(*VarAggregatorFunction)}
+}
+
+// VarSFunction 样本方差函数，保留给既有调用方；与旧实现一致，少于 2 个值时返回 0。
+//
+// Deprecated: 使用 VarSAggregatorFunction。
+type VarSFunction struct {
+	*VarSAggregatorFunction
+}
+
+// Deprecated: 使用 NewVarSAggregatorFunction。
+func NewVarSFunction() *VarSFunction {
+	return &VarSFunction{VarSAggregatorFunction: NewVarSAggregatorFunction()}
+}
+
+func (f *VarSFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	agg := f.New()
+	for _, arg := range args {
+		agg.Add(arg)
+	}
+	return agg.Result(), nil
+}
+
+func (f *VarSFunction) New() AggregatorFunction {
+	return &VarSFunction{VarSAggregatorFunction: f.VarSAggregatorFunction.New().(*VarSAggregatorFunction)}
+}
+
+func (f *VarSFunction) Result() any {
+	if f.count < 2 {
 		return 0.0
 	}
+	return f.VarSAggregatorFunction.Result()
+}
 
-	// 计算平均值
-	sum := 0.0
-	for _, v := range f.values {
-		sum += v
+func (f *VarSFunction) Clone() AggregatorFunction {
+	return &VarSFunction{VarSAggregatorFunction: f.VarSAggregatorFunction.Clone().(*VarSAggregatorFunction)}
+}
+
+// iqrMinPoints 是 iqr 给出结果所需的最少样本数；少于此数时四分位无意义，返回 nil。
+const iqrMinPoints = 4
+
+// IQRAggregatorFunction 四分位距函数：Q3-Q1（p75 与 p25 之差），与 percentile 同口径。
+// 对离群点稳健，常与 median 搭配做异常检测（如 v > median + 1.5*iqr）。
+type IQRAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	values []float64
+}
+
+func NewIQRAggregatorFunction() *IQRAggregatorFunction {
+	return &IQRAggregatorFunction{
+		BaseFunction: NewBaseFunction("iqr", TypeAggregation, "聚合函数", "计算数值四分位距(Q3-Q1)", 1, -1),
+		values:       make([]float64, 0),
 	}
-	mean := sum / float64(len(f.values))
+}
 
-	// 计算样本方差
-	variance := 0.0
-	for _, v := range f.values {
-		variance += math.Pow(v-mean, 2)
+func (f *IQRAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *IQRAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	agg := f.New().(*IQRAggregatorFunction)
+	for _, arg := range args {
+		val, err := cast.ToFloat64E(arg)
+		if err != nil {
+			return nil, err
+		}
+		agg.values = append(agg.values, val)
 	}
-	variance = variance / float64(len(f.values)-1) // 样本方差使用n-1
+	return agg.Result(), nil
+}
 
-	return variance
+func (f *IQRAggregatorFunction) New() AggregatorFunction {
+	return &IQRAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		values:       make([]float64, 0),
+	}
 }
 
-func (f *VarSAggregatorFunction) Reset() {
+func (f *IQRAggregatorFunction) Add(value any) {
+	if val, err := cast.ToFloat64E(value); err == nil {
+		f.values = f.appendFloat(f.values, val)
+	}
+}
+
+// Result 一次排序同时取 p25 与 p75；样本不足 iqrMinPoints 时返回 nil。
+func (f *IQRAggregatorFunction) Result() any {
+	if len(f.values) < iqrMinPoints {
+		return nil
+	}
+	sorted := make([]float64, len(f.values))
+	copy(sorted, f.values)
+	sort.Float64s(sorted)
+	return percentileOfSorted(sorted, 0.75) - percentileOfSorted(sorted, 0.25)
+}
+
+func (f *IQRAggregatorFunction) Reset() {
 	f.values = make([]float64, 0)
+	f.resetGuard()
 }
 
-func (f *VarSAggregatorFunction) Clone() AggregatorFunction {
-	clone := &VarSAggregatorFunction{
+func (f *IQRAggregatorFunction) Clone() AggregatorFunction {
+	clone := &IQRAggregatorFunction{
 		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
 		values:       make([]float64, len(f.values)),
 	}
 	copy(clone.values, f.values)
 	return clone
 }
+
+// ConsecutiveDiffMedianAggregatorFunction 相邻差中位数函数：按到达顺序取相邻两值之差的
+// 绝对值，窗口关闭时返回这些差值的中位数，用于刻画信号抖动/稳定性。
+// 少于 2 个有效值时没有相邻差，返回 nil；非数值被跳过，不打断相邻关系。
+type ConsecutiveDiffMedianAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	diffs   []float64
+	prev    float64
+	hasPrev bool
+}
+
+func NewConsecutiveDiffMedianAggregatorFunction() *ConsecutiveDiffMedianAggregatorFunction {
+	return &ConsecutiveDiffMedianAggregatorFunction{
+		BaseFunction: NewBaseFunction("consecutive_diff_median", TypeAggregation, "聚合函数", "计算相邻值绝对差的中位数", 1, -1),
+		diffs:        make([]float64, 0),
+	}
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	agg := f.New().(*ConsecutiveDiffMedianAggregatorFunction)
+	for _, arg := range args {
+		if _, err := cast.ToFloat64E(arg); err != nil {
+			return nil, err
+		}
+		agg.Add(arg)
+	}
+	return agg.Result(), nil
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) New() AggregatorFunction {
+	return &ConsecutiveDiffMedianAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		diffs:        make([]float64, 0),
+	}
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Add(value any) {
+	val, err := cast.ToFloat64E(value)
+	if err != nil {
+		return
+	}
+	if f.hasPrev {
+		f.diffs = f.appendFloat(f.diffs, math.Abs(val-f.prev))
+	}
+	f.prev, f.hasPrev = val, true
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Result() any {
+	if len(f.diffs) == 0 {
+		return nil
+	}
+	sorted := make([]float64, len(f.diffs))
+	copy(sorted, f.diffs)
+	sort.Float64s(sorted)
+	return medianOfSorted(sorted)
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Reset() {
+	f.diffs = make([]float64, 0)
+	f.prev, f.hasPrev = 0, false
+	f.resetGuard()
+}
+
+func (f *ConsecutiveDiffMedianAggregatorFunction) Clone() AggregatorFunction {
+	clone := &ConsecutiveDiffMedianAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		diffs:        make([]float64, len(f.diffs)),
+		prev:         f.prev,
+		hasPrev:      f.hasPrev,
+	}
+	copy(clone.diffs, f.diffs)
+	return clone
+}
+
+// valueCountsTrackFactor 设置 top-N 上限时，最多跟踪 N*factor 个不同值；
+// 不同值数不超过该容量时计数精确，超出后按 Space-Saving 淘汰最小计数项。
+const valueCountsTrackFactor = 4
+
+// ValueCountsAggregatorFunction 频次分布函数：value_counts(field[, topN]) 返回
+// 窗口内每个不同值（以字符串为键）到出现次数的 map，可直接用于构建分布表，
+// 众数即计数最大的键。NULL 不计入。
+//
+// topN>0 时仅保留计数最高的 N 项（计数相同按键升序取前 N，结果确定），
+// 并把跟踪的不同值数限制在 N*valueCountsTrackFactor 以内以约束内存。
+// 不同值数超过该容量后结果是近似的：新值替换计数最小项并继承其计数，
+// 因此计数只会偏高不会偏低（误差不超过被替换项的计数），被淘汰的值不再计数，
+// 计数接近的值也可能误入 top-N。需要精确计数时不要设置 topN。
+// map 的 JSON 序列化按键排序，输出稳定。
+//
+// 聚合缓冲上限（BufferLimitedAggregator）限制不同值的个数：达到上限后新值不再计数，
+// 已跟踪的值照常累加。
+type ValueCountsAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	counts map[string]int
+	topN   int
+}
+
+func NewValueCountsAggregatorFunction() *ValueCountsAggregatorFunction {
+	return &ValueCountsAggregatorFunction{
+		BaseFunction: NewBaseFunction("value_counts", TypeAggregation, "聚合函数", "统计每个不同值的出现次数", 1, 2),
+		counts:       make(map[string]int),
+	}
+}
+
+func (f *ValueCountsAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *ValueCountsAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*ValueCountsAggregatorFunction)
+	if err := agg.Init(args); err != nil {
+		return nil, err
+	}
+	// 非聚合上下文中第一个参数可以是数组
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *ValueCountsAggregatorFunction) New() AggregatorFunction {
+	return &ValueCountsAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  bufferGuard{limit: f.limit, policy: f.policy},
+		counts:       make(map[string]int),
+		topN:         f.topN,
+	}
+}
+
+func (f *ValueCountsAggregatorFunction) Add(value any) {
+	if value == nil {
+		return
+	}
+	key := cast.ToString(value)
+	if _, ok := f.counts[key]; ok || f.topN <= 0 || len(f.counts) < f.topN*valueCountsTrackFactor {
+		f.countKey(f.counts, key)
+		return
+	}
+	// 容量已满：Space-Saving，用新值替换计数最小项并继承其计数
+	minKey, minCount := "", 0
+	for k, c := range f.counts {
+		if minKey == "" || c < minCount || (c == minCount && k > minKey) {
+			minKey, minCount = k, c
+		}
+	}
+	delete(f.counts, minKey)
+	f.counts[key] = minCount + 1
+}
+
+// Result 返回值到计数的新 map；topN>0 时只含计数最高的 N 项。
+func (f *ValueCountsAggregatorFunction) Result() any {
+	result := make(map[string]int, len(f.counts))
+	if f.topN <= 0 || len(f.counts) <= f.topN {
+		for k, c := range f.counts {
+			result[k] = c
+		}
+		return result
+	}
+	keys := make([]string, 0, len(f.counts))
+	for k := range f.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := f.counts[keys[i]], f.counts[keys[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys[:f.topN] {
+		result[k] = f.counts[k]
+	}
+	return result
+}
+
+func (f *ValueCountsAggregatorFunction) Reset() {
+	f.counts = make(map[string]int)
+	f.resetGuard()
+}
+
+func (f *ValueCountsAggregatorFunction) Clone() AggregatorFunction {
+	clone := &ValueCountsAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		counts:       make(map[string]int, len(f.counts)),
+		topN:         f.topN,
+	}
+	for k, c := range f.counts {
+		clone.counts[k] = c
+	}
+	return clone
+}
+
+// Init 实现 ParameterizedFunction：可选第二参数为 top-N 上限（正整数）。
+func (f *ValueCountsAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 {
+		return nil
+	}
+	n, err := cast.ToIntE(args[1])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("value_counts topN must be a positive integer, got %v", args[1])
+	}
+	f.topN = n
+	return nil
+}
+
+// timedSample 是 time_in_state 的一个样本：时间戳及该时刻是否处于目标状态。
+type timedSample struct {
+	ts      time.Time
+	inState bool
+}
+
+// TimeInStateAggregatorFunction 状态持续时长函数：time_in_state(status, 'error')
+// 返回窗口内 status 处于目标状态的总时长（秒，float64）。
+//
+// 时间取每行的时间戳（事件时间窗口为事件时间，处理时间窗口为到达时间），
+// 样本按时间排序后，每个样本的状态持续到下一个样本为止，即累加所有处于
+// 目标状态的样本 i 的 ts[i+1]-ts[i]。边界策略：仅在窗口内的样本之间计时——
+// 最后一个样本的状态不延伸到窗口结束，也不从上一窗口继承起始状态；
+// 少于两个样本时结果为 0。NULL 状态视为不在目标状态。
+// 无时间戳的调用（直接 Add）按调用时刻计时。样本数受聚合缓冲上限约束。
+type TimeInStateAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	target  string
+	samples []timedSample
+}
+
+func NewTimeInStateAggregatorFunction() *TimeInStateAggregatorFunction {
+	return &TimeInStateAggregatorFunction{
+		BaseFunction: NewBaseFunction("time_in_state", TypeAggregation, "聚合函数", "计算窗口内处于指定状态的总时长（秒）", 2, 2),
+	}
+}
+
+func (f *TimeInStateAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中只有一个样本，时长恒为 0。
+func (f *TimeInStateAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return 0.0, nil
+}
+
+func (f *TimeInStateAggregatorFunction) New() AggregatorFunction {
+	return &TimeInStateAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  bufferGuard{limit: f.limit, policy: f.policy},
+		target:       f.target,
+	}
+}
+
+func (f *TimeInStateAggregatorFunction) Add(value any) {
+	f.AddAt(value, time.Time{})
+}
+
+// AddAt 实现 TimestampedAggregator；ts 为零值时使用当前时间。
+func (f *TimeInStateAggregatorFunction) AddAt(value any, ts time.Time) {
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	f.samples = f.appendSample(f.samples, timedSample{ts: ts, inState: value != nil && cast.ToString(value) == f.target})
+}
+
+func (f *TimeInStateAggregatorFunction) Result() any {
+	if len(f.samples) < 2 {
+		return 0.0
+	}
+	sorted := make([]timedSample, len(f.samples))
+	copy(sorted, f.samples)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ts.Before(sorted[j].ts) })
+	var total time.Duration
+	for i := 0; i+1 < len(sorted); i++ {
+		if sorted[i].inState {
+			total += sorted[i+1].ts.Sub(sorted[i].ts)
+		}
+	}
+	return total.Seconds()
+}
+
+func (f *TimeInStateAggregatorFunction) Reset() {
+	f.samples = nil
+	f.resetGuard()
+}
+
+func (f *TimeInStateAggregatorFunction) Clone() AggregatorFunction {
+	clone := &TimeInStateAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		target:       f.target,
+		samples:      make([]timedSample, len(f.samples)),
+	}
+	copy(clone.samples, f.samples)
+	return clone
+}
+
+// Init 实现 ParameterizedFunction：第二参数为目标状态值（按字符串比较）。
+func (f *TimeInStateAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 || args[1] == nil {
+		return fmt.Errorf("time_in_state requires a target state")
+	}
+	f.target = cast.ToString(args[1])
+	return nil
+}
+
+// TrimmedMeanAggregatorFunction 截尾均值函数：trimmed_mean(value, 0.1) 缓存窗口内的
+// 数值，排序后去掉最低与最高各 floor(n*fraction) 个值，再对剩余值求平均，降低尖峰
+// 离群值的影响。fraction 取值 [0,0.5)；未通过 Init 配置（如 fraction 非法）时结果为
+// NULL，窗口内没有数值时同样为 NULL。非数值被跳过。
+type TrimmedMeanAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	fraction float64 // <0 表示未配置
+	values   []float64
+}
+
+func NewTrimmedMeanAggregatorFunction() *TrimmedMeanAggregatorFunction {
+	return &TrimmedMeanAggregatorFunction{
+		BaseFunction: NewBaseFunction("trimmed_mean", TypeAggregation, "聚合函数", "去掉首尾一定比例的值后求平均", 2, 2),
+		fraction:     -1,
+	}
+}
+
+func (f *TrimmedMeanAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中第一个参数可以是数组。
+func (f *TrimmedMeanAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*TrimmedMeanAggregatorFunction)
+	if err := agg.Init(args); err != nil {
+		return nil, err
+	}
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *TrimmedMeanAggregatorFunction) New() AggregatorFunction {
+	return &TrimmedMeanAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		fraction:     f.fraction,
+	}
+}
+
+func (f *TrimmedMeanAggregatorFunction) Add(value any) {
+	if val, err := cast.ToFloat64E(value); err == nil {
+		f.values = f.appendFloat(f.values, val)
+	}
+}
+
+func (f *TrimmedMeanAggregatorFunction) Result() any {
+	if f.fraction < 0 || len(f.values) == 0 {
+		return nil
+	}
+	sorted := make([]float64, len(f.values))
+	copy(sorted, f.values)
+	sort.Float64s(sorted)
+	k := int(math.Floor(float64(len(sorted)) * f.fraction))
+	kept := sorted[k : len(sorted)-k]
+	sum := 0.0
+	for _, v := range kept {
+		sum += v
+	}
+	return sum / float64(len(kept))
+}
+
+func (f *TrimmedMeanAggregatorFunction) Reset() {
+	f.values = nil
+	f.resetGuard()
+}
+
+func (f *TrimmedMeanAggregatorFunction) Clone() AggregatorFunction {
+	clone := &TrimmedMeanAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		fraction:     f.fraction,
+		values:       make([]float64, len(f.values)),
+	}
+	copy(clone.values, f.values)
+	return clone
+}
+
+// Init 实现 ParameterizedFunction：第二参数为截尾比例，须在 [0,0.5) 内。
+func (f *TrimmedMeanAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 {
+		return fmt.Errorf("trimmed_mean requires a trim fraction")
+	}
+	frac, err := cast.ToFloat64E(args[1])
+	if err != nil || frac < 0 || frac >= 0.5 {
+		return fmt.Errorf("trimmed_mean fraction must be in [0,0.5), got %v", args[1])
+	}
+	f.fraction = frac
+	return nil
+}
+
+// StringAggAggregatorFunction 字符串拼接聚合：string_agg(value, separator) 按到达顺序
+// 把各值转为字符串后以 separator 连接；单参数形式使用逗号。NULL 被跳过，不会在分隔符
+// 之间留下空项；窗口内没有非 NULL 值时结果为 NULL。separator 须为字符串常量，由解析期
+// 校验；Init 收到非字符串参数时报错。
+type StringAggAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	separator string
+	values    []string
+}
+
+func NewStringAggAggregatorFunction() *StringAggAggregatorFunction {
+	return &StringAggAggregatorFunction{
+		BaseFunction: NewBaseFunction("string_agg", TypeAggregation, "聚合函数", "按顺序以分隔符拼接字符串", 1, 2),
+		separator:    ",",
+	}
+}
+
+func (f *StringAggAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中第一个参数可以是数组。
+func (f *StringAggAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*StringAggAggregatorFunction)
+	if err := agg.Init(args); err != nil {
+		return nil, err
+	}
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *StringAggAggregatorFunction) New() AggregatorFunction {
+	return &StringAggAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		separator:    f.separator,
+	}
+}
+
+func (f *StringAggAggregatorFunction) Add(value any) {
+	if value == nil {
+		return
+	}
+	f.values = f.appendString(f.values, cast.ToString(value))
+}
+
+func (f *StringAggAggregatorFunction) Result() any {
+	if len(f.values) == 0 {
+		return nil
+	}
+	return strings.Join(f.values, f.separator)
+}
+
+func (f *StringAggAggregatorFunction) Reset() {
+	f.values = nil
+	f.resetGuard()
+}
+
+func (f *StringAggAggregatorFunction) Clone() AggregatorFunction {
+	clone := &StringAggAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		bufferGuard:  f.bufferGuard,
+		separator:    f.separator,
+		values:       make([]string, len(f.values)),
+	}
+	copy(clone.values, f.values)
+	return clone
+}
+
+// Init 实现 ParameterizedFunction：可选第二参数为分隔符（字符串常量）。
+func (f *StringAggAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 {
+		return nil
+	}
+	sep, ok := args[1].(string)
+	if !ok {
+		return fmt.Errorf("string_agg separator must be a constant string, got %v", args[1])
+	}
+	f.separator = sep
+	return nil
+}
+
+// TopKAggregatorFunction 最大 K 值函数：top_k(value, 3) 返回窗口内最大的 K 个数值，
+// 按降序排列为 []any；不足 K 个时返回全部。以容量为 K 的最小堆维护候选，内存为 O(K)，
+// 与窗口大小无关。值相等时先到达的排在前面（并列时保留先到达者）。K 须为正整数常量，
+// 由解析期校验；未通过 Init 配置时结果为 NULL。非数值被跳过。
+type TopKAggregatorFunction struct {
+	*BaseFunction
+	k    int // <=0 表示未配置
+	seq  int64
+	heap topKHeap
+}
+
+// topKEntry 是堆中的一个候选值及其到达序号。
+type topKEntry struct {
+	value float64
+	seq   int64
+}
+
+// topKHeap 堆顶为最差候选：值最小，值相等时到达最晚。
+type topKHeap []topKEntry
+
+func (h topKHeap) Len() int { return len(h) }
+func (h topKHeap) Less(i, j int) bool {
+	if h[i].value != h[j].value {
+		return h[i].value < h[j].value
+	}
+	return h[i].seq > h[j].seq
+}
+func (h topKHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *topKHeap) Push(x any)   { *h = append(*h, x.(topKEntry)) }
+func (h *topKHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+func NewTopKAggregatorFunction() *TopKAggregatorFunction {
+	return &TopKAggregatorFunction{
+		BaseFunction: NewBaseFunction("top_k", TypeAggregation, "聚合函数", "返回窗口内最大的K个值（降序）", 2, 2),
+	}
+}
+
+func (f *TopKAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中第一个参数可以是数组。
+func (f *TopKAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*TopKAggregatorFunction)
+	if err := agg.Init(args); err != nil {
+		return nil, err
+	}
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *TopKAggregatorFunction) New() AggregatorFunction {
+	return &TopKAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		k:            f.k,
+	}
+}
+
+func (f *TopKAggregatorFunction) Add(value any) {
+	if value == nil || f.k <= 0 {
+		return
+	}
+	val, err := cast.ToFloat64E(value)
+	if err != nil {
+		return
+	}
+	f.seq++
+	e := topKEntry{value: val, seq: f.seq}
+	if len(f.heap) < f.k {
+		heap.Push(&f.heap, e)
+		return
+	}
+	// 相等的新值到达更晚，不替换已有候选
+	if val > f.heap[0].value {
+		f.heap[0] = e
+		heap.Fix(&f.heap, 0)
+	}
+}
+
+func (f *TopKAggregatorFunction) Result() any {
+	if f.k <= 0 {
+		return nil
+	}
+	sorted := make(topKHeap, len(f.heap))
+	copy(sorted, f.heap)
+	sort.Slice(sorted, func(i, j int) bool { return sorted.Less(j, i) })
+	result := make([]any, len(sorted))
+	for i, e := range sorted {
+		result[i] = e.value
+	}
+	return result
+}
+
+func (f *TopKAggregatorFunction) Reset() {
+	f.heap = nil
+	f.seq = 0
+}
+
+func (f *TopKAggregatorFunction) Clone() AggregatorFunction {
+	clone := &TopKAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		k:            f.k,
+		seq:          f.seq,
+		heap:         make(topKHeap, len(f.heap)),
+	}
+	copy(clone.heap, f.heap)
+	return clone
+}
+
+// Init 实现 ParameterizedFunction：第二参数为 K（正整数）。
+func (f *TopKAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 {
+		return fmt.Errorf("top_k requires k")
+	}
+	k, err := cast.ToIntE(args[1])
+	if err != nil || k <= 0 {
+		return fmt.Errorf("top_k k must be a positive integer, got %v", args[1])
+	}
+	f.k = k
+	return nil
+}
+
+// HistogramAggregatorFunction 直方图函数：histogram(response_ms, 0, 100, 10) 把 [min,max]
+// 等分为 bucketCount 个桶统计窗口内各桶的值个数。小于 min 的值计入下溢桶、大于 max 的值
+// 计入上溢桶；等于 max 的值计入最后一个桶。Result 返回按区间升序排列的 []any，每项为
+// {"from","to","count"} 的 map，下溢桶 from 与上溢桶 to 为 NULL，便于输出稳定的 JSON。
+// 下溢/上溢桶总是出现在结果中。参数由解析期校验；未通过 Init 配置时结果为 NULL。
+// 非数值被跳过。
+type HistogramAggregatorFunction struct {
+	*BaseFunction
+	min, max  float64
+	buckets   []int64 // 长度 bucketCount，nil 表示未配置
+	underflow int64
+	overflow  int64
+}
+
+func NewHistogramAggregatorFunction() *HistogramAggregatorFunction {
+	return &HistogramAggregatorFunction{
+		BaseFunction: NewBaseFunction("histogram", TypeAggregation, "聚合函数", "按等宽桶统计值分布", 4, 4),
+	}
+}
+
+func (f *HistogramAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中第一个参数可以是数组。
+func (f *HistogramAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*HistogramAggregatorFunction)
+	if err := agg.Init(args); err != nil {
+		return nil, err
+	}
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *HistogramAggregatorFunction) New() AggregatorFunction {
+	agg := &HistogramAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		min:          f.min,
+		max:          f.max,
+	}
+	if f.buckets != nil {
+		agg.buckets = make([]int64, len(f.buckets))
+	}
+	return agg
+}
+
+func (f *HistogramAggregatorFunction) Add(value any) {
+	if value == nil || f.buckets == nil {
+		return
+	}
+	val, err := cast.ToFloat64E(value)
+	if err != nil || math.IsNaN(val) {
+		return
+	}
+	switch {
+	case val < f.min:
+		f.underflow++
+	case val > f.max:
+		f.overflow++
+	default:
+		i := int((val - f.min) / (f.max - f.min) * float64(len(f.buckets)))
+		if i >= len(f.buckets) {
+			i = len(f.buckets) - 1
+		}
+		f.buckets[i]++
+	}
+}
+
+func (f *HistogramAggregatorFunction) Result() any {
+	if f.buckets == nil {
+		return nil
+	}
+	result := make([]any, 0, len(f.buckets)+2)
+	result = append(result, map[string]any{"from": nil, "to": f.min, "count": f.underflow})
+	width := (f.max - f.min) / float64(len(f.buckets))
+	for i, c := range f.buckets {
+		to := f.min + float64(i+1)*width
+		if i == len(f.buckets)-1 {
+			to = f.max
+		}
+		result = append(result, map[string]any{"from": f.min + float64(i)*width, "to": to, "count": c})
+	}
+	return append(result, map[string]any{"from": f.max, "to": nil, "count": f.overflow})
+}
+
+func (f *HistogramAggregatorFunction) Reset() {
+	for i := range f.buckets {
+		f.buckets[i] = 0
+	}
+	f.underflow, f.overflow = 0, 0
+}
+
+func (f *HistogramAggregatorFunction) Clone() AggregatorFunction {
+	clone := *f
+	if f.buckets != nil {
+		clone.buckets = make([]int64, len(f.buckets))
+		copy(clone.buckets, f.buckets)
+	}
+	return &clone
+}
+
+// Init 实现 ParameterizedFunction：后三个参数依次为 min、max（min<max）与桶数（正整数）。
+func (f *HistogramAggregatorFunction) Init(args []any) error {
+	if len(args) < 4 {
+		return fmt.Errorf("histogram requires min, max and bucket count")
+	}
+	lo, err1 := cast.ToFloat64E(args[1])
+	hi, err2 := cast.ToFloat64E(args[2])
+	if err1 != nil || err2 != nil || !(lo < hi) || math.IsInf(hi-lo, 0) {
+		return fmt.Errorf("histogram range must satisfy min < max, got %v, %v", args[1], args[2])
+	}
+	n, err := cast.ToIntE(args[3])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("histogram bucket count must be a positive integer, got %v", args[3])
+	}
+	f.min, f.max = lo, hi
+	f.buckets = make([]int64, n)
+	f.underflow, f.overflow = 0, 0
+	return nil
+}
+
+// WeightedAvgAggregatorFunction 加权平均函数：wavg(temperature, confidence) 返回
+// sum(value*weight)/sum(weight)。第一个参数由聚合路径按行求值（可为表达式），第二个
+// 参数为权重的字段名、表达式或数值常量，由 Init 记录后在 AddRow 中对源行求值。值或
+// 权重为 NULL/非数值的行被跳过；总权重为 0 时结果为 NULL。
+type WeightedAvgAggregatorFunction struct {
+	*BaseFunction
+	weightExpr  string  // 权重表达式，空表示使用常量 weightConst
+	weightConst float64 // 常量权重，weightSet 为 true 时有效
+	weightSet   bool
+	weightedSum float64
+	weightSum   float64
+}
+
+func NewWeightedAvgAggregatorFunction() *WeightedAvgAggregatorFunction {
+	return &WeightedAvgAggregatorFunction{
+		BaseFunction: NewBaseFunction("wavg", TypeAggregation, "聚合函数", "计算加权平均值 sum(value*weight)/sum(weight)", 2, 2),
+	}
+}
+
+func (f *WeightedAvgAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中两个参数可以是等长数组，按位置配对。
+func (f *WeightedAvgAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*WeightedAvgAggregatorFunction)
+	values, okValues := args[0].([]any)
+	weights, okWeights := args[1].([]any)
+	if okValues != okWeights || len(values) != len(weights) {
+		return nil, fmt.Errorf("wavg requires value and weight arrays of the same length")
+	}
+	if !okValues {
+		values, weights = []any{args[0]}, []any{args[1]}
+	}
+	for i := range values {
+		agg.addWeighted(values[i], weights[i])
+	}
+	return agg.Result(), nil
+}
+
+func (f *WeightedAvgAggregatorFunction) New() AggregatorFunction {
+	return &WeightedAvgAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		weightExpr:   f.weightExpr,
+		weightConst:  f.weightConst,
+		weightSet:    f.weightSet,
+	}
+}
+
+// Add 没有源行可供求值权重，仅在权重为常量时计入。
+func (f *WeightedAvgAggregatorFunction) Add(value any) {
+	if f.weightSet && f.weightExpr == "" {
+		f.addWeighted(value, f.weightConst)
+	}
+}
+
+// AddRow 实现 RowAggregator：对源行求值权重后计入。
+func (f *WeightedAvgAggregatorFunction) AddRow(value any, _ time.Time, row any) {
+	if !f.weightSet {
+		return
+	}
+	if f.weightExpr == "" {
+		f.addWeighted(value, f.weightConst)
+		return
+	}
+	data, ok := row.(map[string]any)
+	if !ok {
+		return
+	}
+	weight, found := data[f.weightExpr]
+	if !found {
+		var err error
+		if weight, err = GetExprBridge().EvaluateExpression(f.weightExpr, data); err != nil {
+			return
+		}
+	}
+	f.addWeighted(value, weight)
+}
+
+func (f *WeightedAvgAggregatorFunction) addWeighted(value, weight any) {
+	if value == nil || weight == nil {
+		return
+	}
+	v, err1 := cast.ToFloat64E(value)
+	w, err2 := cast.ToFloat64E(weight)
+	if err1 != nil || err2 != nil || math.IsNaN(v) || math.IsNaN(w) {
+		return
+	}
+	f.weightedSum += v * w
+	f.weightSum += w
+}
+
+func (f *WeightedAvgAggregatorFunction) Result() any {
+	if f.weightSum == 0 {
+		return nil
+	}
+	return f.weightedSum / f.weightSum
+}
+
+func (f *WeightedAvgAggregatorFunction) Reset() {
+	f.weightedSum, f.weightSum = 0, 0
+}
+
+func (f *WeightedAvgAggregatorFunction) Clone() AggregatorFunction {
+	clone := *f
+	return &clone
+}
+
+// Init 实现 ParameterizedFunction：第二参数为权重的字段名/表达式或数值常量。
+func (f *WeightedAvgAggregatorFunction) Init(args []any) error {
+	if len(args) < 2 || args[1] == nil {
+		return fmt.Errorf("wavg requires a weight argument")
+	}
+	f.weightExpr, f.weightConst = "", 0
+	if expr, ok := args[1].(string); ok {
+		if strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("wavg weight cannot be empty")
+		}
+		f.weightExpr = strings.TrimSpace(expr)
+	} else {
+		w, err := cast.ToFloat64E(args[1])
+		if err != nil {
+			return fmt.Errorf("wavg weight must be a field, expression or number, got %v", args[1])
+		}
+		f.weightConst = w
+	}
+	f.weightSet = true
+	f.Reset()
+	return nil
+}
+
+// firstLastTracker 记录窗口内按时间戳最早与最晚的数值样本，供 window_delta/window_rate
+// 使用。时间戳相同时，最早取先到达的样本、最晚取后到达的样本；非数值被跳过。
+type firstLastTracker struct {
+	count              int
+	firstTs, lastTs    time.Time
+	firstVal, lastVal  float64
+	spanStart, spanEnd time.Time // 窗口边界，由 SetWindowSpan 设置
+}
+
+func (t *firstLastTracker) addAt(value any, ts time.Time) {
+	val, err := cast.ToFloat64E(value)
+	if value == nil || err != nil {
+		return
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if t.count == 0 || ts.Before(t.firstTs) {
+		t.firstTs, t.firstVal = ts, val
+	}
+	if t.count == 0 || !ts.Before(t.lastTs) {
+		t.lastTs, t.lastVal = ts, val
+	}
+	t.count++
+}
+
+// delta 返回 last−first；少于两个样本时 ok 为 false。
+func (t *firstLastTracker) delta() (float64, bool) {
+	if t.count < 2 {
+		return 0, false
+	}
+	return t.lastVal - t.firstVal, true
+}
+
+// span 返回窗口时长：优先取窗口边界，未设置时退化为首尾样本的时间差。
+func (t *firstLastTracker) span() time.Duration {
+	if t.spanStart.IsZero() || t.spanEnd.IsZero() {
+		return t.lastTs.Sub(t.firstTs)
+	}
+	return t.spanEnd.Sub(t.spanStart)
+}
+
+func (t *firstLastTracker) reset() {
+	*t = firstLastTracker{}
+}
+
+// WindowDeltaAggregatorFunction 窗口差值函数：window_delta(value) 返回窗口内按时间戳
+// 最后一个值减去第一个值（float64）。时间取每行的时间戳（事件时间窗口为事件时间，
+// 处理时间窗口为到达时间）；窗口内少于两个数值时结果为 NULL。
+type WindowDeltaAggregatorFunction struct {
+	*BaseFunction
+	tracker firstLastTracker
+}
+
+func NewWindowDeltaAggregatorFunction() *WindowDeltaAggregatorFunction {
+	return &WindowDeltaAggregatorFunction{
+		BaseFunction: NewBaseFunction("window_delta", TypeAggregation, "聚合函数", "计算窗口内最后一个值与第一个值之差", 1, 1),
+	}
+}
+
+func (f *WindowDeltaAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中只有一个样本，结果恒为 NULL。
+func (f *WindowDeltaAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *WindowDeltaAggregatorFunction) New() AggregatorFunction {
+	return &WindowDeltaAggregatorFunction{BaseFunction: f.BaseFunction}
+}
+
+func (f *WindowDeltaAggregatorFunction) Add(value any) {
+	f.AddAt(value, time.Time{})
+}
+
+// AddAt 实现 TimestampedAggregator；ts 为零值时使用当前时间。
+func (f *WindowDeltaAggregatorFunction) AddAt(value any, ts time.Time) {
+	f.tracker.addAt(value, ts)
+}
+
+func (f *WindowDeltaAggregatorFunction) Result() any {
+	if d, ok := f.tracker.delta(); ok {
+		return d
+	}
+	return nil
+}
+
+func (f *WindowDeltaAggregatorFunction) Reset() {
+	f.tracker.reset()
+}
+
+func (f *WindowDeltaAggregatorFunction) Clone() AggregatorFunction {
+	return &WindowDeltaAggregatorFunction{BaseFunction: f.BaseFunction, tracker: f.tracker}
+}
+
+// WindowRateAggregatorFunction 窗口变化率函数：window_rate(value) 返回
+// window_delta(value) 除以窗口时长（秒），即每秒变化量。窗口时长取窗口边界
+// window_end−window_start（计数窗口为其首尾行的时间跨度）；不在窗口中时退化为
+// 首尾样本的时间差。窗口内少于两个数值或时长为 0 时结果为 NULL。
+type WindowRateAggregatorFunction struct {
+	*BaseFunction
+	tracker firstLastTracker
+}
+
+func NewWindowRateAggregatorFunction() *WindowRateAggregatorFunction {
+	return &WindowRateAggregatorFunction{
+		BaseFunction: NewBaseFunction("window_rate", TypeAggregation, "聚合函数", "计算窗口内首尾值之差除以窗口时长（每秒）", 1, 1),
+	}
+}
+
+func (f *WindowRateAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中只有一个样本，结果恒为 NULL。
+func (f *WindowRateAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *WindowRateAggregatorFunction) New() AggregatorFunction {
+	return &WindowRateAggregatorFunction{BaseFunction: f.BaseFunction}
+}
+
+func (f *WindowRateAggregatorFunction) Add(value any) {
+	f.AddAt(value, time.Time{})
+}
+
+// AddAt 实现 TimestampedAggregator；ts 为零值时使用当前时间。
+func (f *WindowRateAggregatorFunction) AddAt(value any, ts time.Time) {
+	f.tracker.addAt(value, ts)
+}
+
+// SetWindowSpan 实现 WindowSpanAggregator。
+func (f *WindowRateAggregatorFunction) SetWindowSpan(start, end time.Time) {
+	f.tracker.spanStart, f.tracker.spanEnd = start, end
+}
+
+func (f *WindowRateAggregatorFunction) Result() any {
+	d, ok := f.tracker.delta()
+	if !ok {
+		return nil
+	}
+	span := f.tracker.span()
+	if span <= 0 {
+		return nil
+	}
+	return d / span.Seconds()
+}
+
+func (f *WindowRateAggregatorFunction) Reset() {
+	f.tracker.reset()
+}
+
+func (f *WindowRateAggregatorFunction) Clone() AggregatorFunction {
+	return &WindowRateAggregatorFunction{BaseFunction: f.BaseFunction, tracker: f.tracker}
+}
+
+// RateAggregatorFunction 计数器速率函数：rate(total_bytes) 返回窗口内按时间戳
+// (last−first)/窗口时长（秒），即计数器的每秒增量（float64）。窗口时长的取法与
+// window_rate 相同。与 window_rate 不同，计数器回绕或重置（last < first）时结果为 0
+// 而非负速率。窗口内少于两个数值或时长为 0 时结果为 NULL。
+type RateAggregatorFunction struct {
+	*BaseFunction
+	tracker firstLastTracker
+}
+
+func NewRateAggregatorFunction() *RateAggregatorFunction {
+	return &RateAggregatorFunction{
+		BaseFunction: NewBaseFunction("rate", TypeAggregation, "聚合函数", "计算计数器在窗口内的每秒增量，重置时为0", 1, 1),
+	}
+}
+
+func (f *RateAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中只有一个样本，结果恒为 NULL。
+func (f *RateAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *RateAggregatorFunction) New() AggregatorFunction {
+	return &RateAggregatorFunction{BaseFunction: f.BaseFunction}
+}
+
+func (f *RateAggregatorFunction) Add(value any) {
+	f.AddAt(value, time.Time{})
+}
+
+// AddAt 实现 TimestampedAggregator；ts 为零值时使用当前时间。
+func (f *RateAggregatorFunction) AddAt(value any, ts time.Time) {
+	f.tracker.addAt(value, ts)
+}
+
+// SetWindowSpan 实现 WindowSpanAggregator。
+func (f *RateAggregatorFunction) SetWindowSpan(start, end time.Time) {
+	f.tracker.spanStart, f.tracker.spanEnd = start, end
+}
+
+func (f *RateAggregatorFunction) Result() any {
+	d, ok := f.tracker.delta()
+	if !ok {
+		return nil
+	}
+	span := f.tracker.span()
+	if span <= 0 {
+		return nil
+	}
+	if d < 0 {
+		return 0.0
+	}
+	return d / span.Seconds()
+}
+
+func (f *RateAggregatorFunction) Reset() {
+	f.tracker.reset()
+}
+
+func (f *RateAggregatorFunction) Clone() AggregatorFunction {
+	return &RateAggregatorFunction{BaseFunction: f.BaseFunction, tracker: f.tracker}
+}
+
+// ExtremumRowAggregatorFunction 极值行函数：max_row(value) / min_row(value) 返回窗口内
+// value 取最大/最小值的那一行的完整记录（map），免去为取上下文字段再扫描一遍窗口。
+// 每个分组只保留当前极值行的引用；并列时保留最先到达的行。非数值与 NaN 被跳过，
+// 窗口内没有数值时结果为 NULL。结果为该行的浅拷贝，下游修改不影响输入记录。
+type ExtremumRowAggregatorFunction struct {
+	*BaseFunction
+	min  bool
+	has  bool
+	best float64
+	row  any
+}
+
+func NewMaxRowAggregatorFunction() *ExtremumRowAggregatorFunction {
+	return &ExtremumRowAggregatorFunction{
+		BaseFunction: NewBaseFunction("max_row", TypeAggregation, "聚合函数", "返回窗口内取最大值的整行记录", 1, 1),
+	}
+}
+
+func NewMinRowAggregatorFunction() *ExtremumRowAggregatorFunction {
+	return &ExtremumRowAggregatorFunction{
+		BaseFunction: NewBaseFunction("min_row", TypeAggregation, "聚合函数", "返回窗口内取最小值的整行记录", 1, 1),
+		min:          true,
+	}
+}
+
+func (f *ExtremumRowAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute 非聚合上下文中没有源行，结果恒为 NULL。
+func (f *ExtremumRowAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *ExtremumRowAggregatorFunction) New() AggregatorFunction {
+	return &ExtremumRowAggregatorFunction{BaseFunction: f.BaseFunction, min: f.min}
+}
+
+// Add 没有源行时只跟踪极值，结果为 NULL。
+func (f *ExtremumRowAggregatorFunction) Add(value any) {
+	f.AddRow(value, time.Time{}, nil)
+}
+
+// AddRow 实现 RowAggregator。
+func (f *ExtremumRowAggregatorFunction) AddRow(value any, _ time.Time, row any) {
+	val, err := cast.ToFloat64E(value)
+	if err != nil || math.IsNaN(val) {
+		return
+	}
+	if f.has && (f.min && val >= f.best || !f.min && val <= f.best) {
+		return
+	}
+	f.has, f.best, f.row = true, val, row
+}
+
+func (f *ExtremumRowAggregatorFunction) Result() any {
+	if m, ok := f.row.(map[string]any); ok {
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			out[k] = v
+		}
+		return out
+	}
+	return f.row
+}
+
+func (f *ExtremumRowAggregatorFunction) Reset() {
+	f.has, f.best, f.row = false, 0, nil
+}
+
+func (f *ExtremumRowAggregatorFunction) Clone() AggregatorFunction {
+	clone := *f
+	return &clone
+}
+
+// CountDistinctAggregatorFunction 精确去重计数：count_distinct(field)，SQL 中也写作
+// COUNT(DISTINCT field)。用集合记录窗口内出现过的值，返回不同值的个数（int64）；
+// 内存随不同值数增长，高基数字段可改用 approx_count_distinct；设置缓冲上限
+// （MaxAggregateBufferPerGroup）后集合最多保留上限个值，超出的新值丢弃，结果为下界。
+// 值按字符串比较（1 与 "1" 计为同一值，与 approx_count_distinct 一致），NULL 不计入。
+type CountDistinctAggregatorFunction struct {
+	*BaseFunction
+	bufferGuard
+	seen map[string]struct{}
+}
+
+func NewCountDistinctAggregatorFunction() *CountDistinctAggregatorFunction {
+	return &CountDistinctAggregatorFunction{
+		BaseFunction: NewBaseFunction("count_distinct", TypeAggregation, "聚合函数", "精确计算不同值的个数", 1, 1),
+		seen:         make(map[string]struct{}),
+	}
+}
+
+func (f *CountDistinctAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *CountDistinctAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*CountDistinctAggregatorFunction)
+	// 非聚合上下文中参数可以是数组
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *CountDistinctAggregatorFunction) New() AggregatorFunction {
+	return &CountDistinctAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		seen:         make(map[string]struct{}),
+	}
+}
+
+func (f *CountDistinctAggregatorFunction) Add(value any) {
+	if value == nil {
+		return
+	}
+	f.addKey(f.seen, cast.ToString(value))
+}
+
+// Result 返回不同值个数（int64）；没有非 NULL 值时为 0。
+func (f *CountDistinctAggregatorFunction) Result() any {
+	return int64(len(f.seen))
+}
+
+func (f *CountDistinctAggregatorFunction) Reset() {
+	f.seen = make(map[string]struct{})
+	f.resetGuard()
+}
+
+// Clone 复制集合，克隆与原实例互不影响。
+func (f *CountDistinctAggregatorFunction) Clone() AggregatorFunction {
+	seen := make(map[string]struct{}, len(f.seen))
+	for k := range f.seen {
+		seen[k] = struct{}{}
+	}
+	return &CountDistinctAggregatorFunction{BaseFunction: f.BaseFunction, bufferGuard: f.bufferGuard, seen: seen}
+}
+
+// ApproxCountDistinctAggregatorFunction 近似去重计数：approx_count_distinct(field)
+// 用 HyperLogLog（metrics.Cardinality，精度 p=12，标准误差约 1.6%）估算窗口内不同值的
+// 个数（int64），内存固定，不随不同值数增长，适合高基数字段。哈希固定，同样的输入
+// 总得到同样的估计。值按字符串比较（1 与 "1" 计为同一值），NULL 不计入。
+type ApproxCountDistinctAggregatorFunction struct {
+	*BaseFunction
+	sketch *metrics.Cardinality
+}
+
+func NewApproxCountDistinctAggregatorFunction() *ApproxCountDistinctAggregatorFunction {
+	return &ApproxCountDistinctAggregatorFunction{
+		BaseFunction: NewBaseFunction("approx_count_distinct", TypeAggregation, "聚合函数", "用HyperLogLog近似计算不同值的个数", 1, 1),
+		sketch:       metrics.NewCardinality(""),
+	}
+}
+
+func (f *ApproxCountDistinctAggregatorFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *ApproxCountDistinctAggregatorFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	agg := f.New().(*ApproxCountDistinctAggregatorFunction)
+	// 非聚合上下文中参数可以是数组
+	if arr, ok := args[0].([]any); ok {
+		for _, v := range arr {
+			agg.Add(v)
+		}
+	} else {
+		agg.Add(args[0])
+	}
+	return agg.Result(), nil
+}
+
+func (f *ApproxCountDistinctAggregatorFunction) New() AggregatorFunction {
+	return &ApproxCountDistinctAggregatorFunction{
+		BaseFunction: f.BaseFunction,
+		sketch:       metrics.NewCardinality(""),
+	}
+}
+
+func (f *ApproxCountDistinctAggregatorFunction) Add(value any) {
+	if value == nil {
+		return
+	}
+	f.sketch.AddString(cast.ToString(value))
+}
+
+// Merge 把 other 观察到的值并入 f（逐寄存器取最大），结果等同于两者输入之并。
+func (f *ApproxCountDistinctAggregatorFunction) Merge(other *ApproxCountDistinctAggregatorFunction) {
+	f.sketch.Merge(other.sketch)
+}
+
+// Result 返回不同值个数的估计（int64）；没有非 NULL 值时为 0。
+func (f *ApproxCountDistinctAggregatorFunction) Result() any {
+	return f.sketch.Estimate()
+}
+
+func (f *ApproxCountDistinctAggregatorFunction) Reset() {
+	f.sketch = metrics.NewCardinality("")
+}
+
+// Clone 深拷贝寄存器，克隆与原实例互不影响。
+func (f *ApproxCountDistinctAggregatorFunction) Clone() AggregatorFunction {
+	return &ApproxCountDistinctAggregatorFunction{BaseFunction: f.BaseFunction, sketch: f.sketch.Clone()}
+}
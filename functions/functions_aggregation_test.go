@@ -1,9 +1,11 @@
 package functions
 
 import (
+	"fmt"
 	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestStdDevFunction(t *testing.T) {
@@ -95,6 +97,101 @@ func TestVarSFunction(t *testing.T) {
 	}
 }
 
+func TestVarAggregatorFunction(t *testing.T) {
+	fn := NewVarAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{1.0, 2.0, 3.0, 4.0})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if math.Abs(result.(float64)-1.25) > 0.01 {
+		t.Errorf("Execute var result = %v, want ~1.25", result)
+	}
+	agg := fn.New().(*VarAggregatorFunction)
+	agg.Add(1.0)
+	agg.Add(2.0)
+	agg.Add(3.0)
+	agg.Add(4.0)
+	res := agg.Result().(float64)
+	if math.Abs(res-1.25) > 0.01 {
+		t.Errorf("Agg var result = %v, want ~1.25", res)
+	}
+	clone := agg.Clone().(*VarAggregatorFunction)
+	if clone.welford != agg.welford {
+		t.Errorf("Clone failed")
+	}
+	agg.Reset()
+	if agg.Result().(float64) != 0.0 {
+		t.Errorf("Reset failed")
+	}
+	if clone.Result() != res {
+		t.Errorf("Clone shares state: %v after Reset, want %v", clone.Result(), res)
+	}
+}
+
+func TestVarSAggregatorFunction(t *testing.T) {
+	fn := NewVarSAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{1.0, 2.0, 3.0, 4.0})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if math.Abs(result.(float64)-1.6666) > 0.01 {
+		t.Errorf("Execute varS result = %v, want ~1.666", result)
+	}
+	agg := fn.New().(*VarSAggregatorFunction)
+	agg.Add(1.0)
+	agg.Add(2.0)
+	agg.Add(3.0)
+	agg.Add(4.0)
+	res := agg.Result().(float64)
+	if math.Abs(res-1.6666) > 0.01 {
+		t.Errorf("Agg varS result = %v, want ~1.666", res)
+	}
+	clone := agg.Clone().(*VarSAggregatorFunction)
+	if clone.welford != agg.welford {
+		t.Errorf("Clone failed")
+	}
+	agg.Reset()
+	if agg.Result() != nil {
+		t.Errorf("Reset failed: %v", agg.Result())
+	}
+}
+
+// TestVarAggregatorWelford 测试 var/vars 增量计算的精度与空窗口、单值窗口的结果
+func TestVarAggregatorWelford(t *testing.T) {
+	pop := NewVarAggregatorFunction().New()
+	samp := NewVarSAggregatorFunction().New()
+	if pop.Result() != 0.0 || samp.Result() != nil {
+		t.Errorf("empty: var = %v, vars = %v, want 0 and nil", pop.Result(), samp.Result())
+	}
+	pop.Add(5)
+	samp.Add(5)
+	if pop.Result() != 0.0 || samp.Result() != nil {
+		t.Errorf("single value: var = %v, vars = %v, want 0 and nil", pop.Result(), samp.Result())
+	}
+
+	// 大偏移量下朴素的平方和公式会严重失真
+	pop.Reset()
+	samp.Reset()
+	for _, v := range []float64{4, 7, 13, 16} {
+		pop.Add(1e9 + v)
+		samp.Add(1e9 + v)
+	}
+	pop.Add(nil)
+	samp.Add("n/a")
+	if got := pop.Result().(float64); math.Abs(got-22.5) > 1e-6 {
+		t.Errorf("var = %v, want 22.5", got)
+	}
+	if got := samp.Result().(float64); math.Abs(got-30) > 1e-6 {
+		t.Errorf("vars = %v, want 30", got)
+	}
+
+	if got, _ := NewVarSAggregatorFunction().Execute(&FunctionContext{}, []any{1.0}); got != nil {
+		t.Errorf("vars of one value = %v, want nil", got)
+	}
+}
+
 func TestStdDevSFunction(t *testing.T) {
 	fn := NewStdDevSFunction()
 	ctx := &FunctionContext{}
@@ -188,6 +285,618 @@ func TestPercentileFunction(t *testing.T) {
 	}
 }
 
+func TestIQRFunction(t *testing.T) {
+	fn := NewIQRAggregatorFunction()
+	ctx := &FunctionContext{}
+	// 1..8：p25 -> floor(0.25*7)=1 -> 2；p75 -> floor(0.75*7)=5 -> 6；IQR=4
+	result, err := fn.Execute(ctx, []any{8, 3, 5, 1, 7, 2, 6, 4})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if result != 4.0 {
+		t.Errorf("Execute iqr result = %v, want 4", result)
+	}
+	agg := fn.New().(*IQRAggregatorFunction)
+	for _, v := range []any{10.0, 20.0, 30.0} {
+		agg.Add(v)
+	}
+	if agg.Result() != nil {
+		t.Errorf("iqr with too few points = %v, want nil", agg.Result())
+	}
+	agg.Add(40.0)
+	agg.Add("not a number")
+	// 10,20,30,40：p25 -> index 0 -> 10；p75 -> index 2 -> 30
+	if agg.Result() != 20.0 {
+		t.Errorf("Agg iqr result = %v, want 20", agg.Result())
+	}
+	clone := agg.Clone().(*IQRAggregatorFunction)
+	if !reflect.DeepEqual(clone.values, agg.values) {
+		t.Errorf("Clone failed")
+	}
+	agg.Reset()
+	if agg.Result() != nil {
+		t.Errorf("Reset failed")
+	}
+}
+
+func TestTimeInStateFunction(t *testing.T) {
+	fn := NewTimeInStateAggregatorFunction()
+	agg := fn.New().(*TimeInStateAggregatorFunction)
+	if err := agg.Init([]any{"status", "error"}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	agg.AddAt("error", base)
+	if agg.Result() != 0.0 {
+		t.Errorf("time_in_state with one sample = %v, want 0", agg.Result())
+	}
+	// 乱序加入：按时间排序后 0s error, 2s ok, 3s error, 4.5s nil, 10s error
+	agg.AddAt("error", base.Add(3*time.Second))
+	agg.AddAt("ok", base.Add(2*time.Second))
+	agg.AddAt(nil, base.Add(4500*time.Millisecond))
+	agg.AddAt("error", base.Add(10*time.Second))
+	// error 区间 (0→2)+(3→4.5) = 3.5s；最后一个 error 样本不延伸
+	if agg.Result() != 3.5 {
+		t.Errorf("time_in_state result = %v, want 3.5", agg.Result())
+	}
+	clone := agg.Clone().(*TimeInStateAggregatorFunction)
+	clone.AddAt("ok", base.Add(12*time.Second))
+	if clone.Result() != 5.5 || agg.Result() != 3.5 {
+		t.Errorf("Clone failed: clone=%v agg=%v", clone.Result(), agg.Result())
+	}
+	if fresh := agg.New().(*TimeInStateAggregatorFunction); fresh.target != "error" {
+		t.Errorf("New should keep target, got %q", fresh.target)
+	}
+	agg.Reset()
+	if agg.Result() != 0.0 {
+		t.Errorf("Reset failed")
+	}
+	if err := fn.New().(*TimeInStateAggregatorFunction).Init([]any{"status"}); err == nil {
+		t.Errorf("Init without target state should fail")
+	}
+}
+
+func TestWindowDeltaRateFunction(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	delta := NewWindowDeltaAggregatorFunction().New().(*WindowDeltaAggregatorFunction)
+	rate := NewWindowRateAggregatorFunction().New().(*WindowRateAggregatorFunction)
+	delta.AddAt(10, base)
+	rate.AddAt(10, base)
+	if delta.Result() != nil || rate.Result() != nil {
+		t.Errorf("single sample: delta=%v rate=%v, want nil", delta.Result(), rate.Result())
+	}
+	// 乱序加入：按时间戳 first=10@0s、last=40@8s；非数值被跳过
+	for _, s := range []struct {
+		v  any
+		at time.Duration
+	}{{25, 5 * time.Second}, {40, 8 * time.Second}, {"x", 9 * time.Second}, {5, 3 * time.Second}} {
+		delta.AddAt(s.v, base.Add(s.at))
+		rate.AddAt(s.v, base.Add(s.at))
+	}
+	if delta.Result() != 30.0 {
+		t.Errorf("window_delta = %v, want 30", delta.Result())
+	}
+	// 未设置窗口边界时按首尾样本时间差 8s
+	if rate.Result() != 3.75 {
+		t.Errorf("window_rate without span = %v, want 3.75", rate.Result())
+	}
+	// 窗口 [0s,10s)：30/10
+	SetWindowSpan(rate, base, base.Add(10*time.Second))
+	if rate.Result() != 3.0 {
+		t.Errorf("window_rate = %v, want 3", rate.Result())
+	}
+	clone := delta.Clone().(*WindowDeltaAggregatorFunction)
+	clone.AddAt(0, base.Add(-time.Second))
+	if clone.Result() != 40.0 || delta.Result() != 30.0 {
+		t.Errorf("Clone failed: clone=%v delta=%v", clone.Result(), delta.Result())
+	}
+	delta.Reset()
+	rate.Reset()
+	if delta.Result() != nil || rate.Result() != nil {
+		t.Errorf("Reset failed")
+	}
+}
+
+func TestTrimmedMeanFunction(t *testing.T) {
+	fn := NewTrimmedMeanAggregatorFunction()
+	ctx := &FunctionContext{}
+	// 10 个值含两个离群值：10% 截尾去掉 -100 与 1000，剩余 2..9 均值 5.5
+	data := []any{1000, 2, 3, -100, 4, 5, 6, 7, 8, 9}
+	result, err := fn.Execute(ctx, []any{data, 0.1})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if result != 5.5 {
+		t.Errorf("Execute trimmed_mean result = %v, want 5.5", result)
+	}
+	for _, bad := range []any{0.5, -0.1, "x"} {
+		if _, err := fn.Execute(ctx, []any{data, bad}); err == nil {
+			t.Errorf("fraction %v should be rejected", bad)
+		}
+	}
+
+	agg := fn.New().(*TrimmedMeanAggregatorFunction)
+	agg.Add(1.0)
+	if agg.Result() != nil {
+		t.Errorf("unconfigured trimmed_mean = %v, want nil", agg.Result())
+	}
+	if err := agg.Init([]any{"v", 0.25}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	agg.Reset()
+	if agg.Result() != nil {
+		t.Errorf("empty trimmed_mean = %v, want nil", agg.Result())
+	}
+	for _, v := range []any{50.0, 1.0, "skip", 2.0, 3.0, -40.0, 4.0, 5.0, 6.0} {
+		agg.Add(v)
+	}
+	// 8 个数值，25% 截尾各去 2 个：-40,1 与 6,50 -> 2,3,4,5 均值 3.5
+	if agg.Result() != 3.5 {
+		t.Errorf("Agg trimmed_mean result = %v, want 3.5", agg.Result())
+	}
+	clone := agg.Clone().(*TrimmedMeanAggregatorFunction)
+	clone.Add(100.0)
+	// 9 个值截尾各去 2 个：-40,1 与 50,100 -> 2..6 均值 4；原对象不受影响
+	if clone.Result() != 4.0 || agg.Result() != 3.5 {
+		t.Errorf("Clone failed: clone=%v agg=%v", clone.Result(), agg.Result())
+	}
+	if zero := fn.New().(*TrimmedMeanAggregatorFunction); zero.Init([]any{"v", 0}) != nil {
+		t.Errorf("fraction 0 should be accepted")
+	}
+}
+
+func TestTopKFunction(t *testing.T) {
+	fn := NewTopKAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{[]any{5, 9, "x", 1, 9, 7}, 3})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if !reflect.DeepEqual(result, []any{9.0, 9.0, 7.0}) {
+		t.Errorf("Execute top_k result = %v, want [9 9 7]", result)
+	}
+	for _, bad := range []any{0, -1, "x"} {
+		if _, err := fn.Execute(ctx, []any{[]any{1}, bad}); err == nil {
+			t.Errorf("k %v should be rejected", bad)
+		}
+	}
+
+	agg := fn.New().(*TopKAggregatorFunction)
+	agg.Add(1.0)
+	if agg.Result() != nil {
+		t.Errorf("unconfigured top_k = %v, want nil", agg.Result())
+	}
+	if err := agg.Init([]any{"v", 5}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	agg.Reset()
+	for _, v := range []any{3.0, 1.0, 2.0} {
+		agg.Add(v)
+	}
+	// 不足 k 个：返回全部，降序
+	if !reflect.DeepEqual(agg.Result(), []any{3.0, 2.0, 1.0}) {
+		t.Errorf("Agg top_k result = %v, want [3 2 1]", agg.Result())
+	}
+	clone := agg.Clone().(*TopKAggregatorFunction)
+	for i := 0; i < 1000; i++ {
+		clone.Add(float64(i))
+	}
+	if len(clone.heap) != 5 || !reflect.DeepEqual(clone.Result(), []any{999.0, 998.0, 997.0, 996.0, 995.0}) {
+		t.Errorf("Clone top_k result = %v, heap size %d", clone.Result(), len(clone.heap))
+	}
+	if len(agg.Result().([]any)) != 3 {
+		t.Errorf("Clone should not affect the original: %v", agg.Result())
+	}
+
+	// 并列时保留先到达者
+	ties := fn.New().(*TopKAggregatorFunction)
+	_ = ties.Init([]any{"v", 2})
+	for _, v := range []float64{5, 5, 5} {
+		ties.Add(v)
+	}
+	if seqs := []int64{ties.heap[0].seq, ties.heap[1].seq}; seqs[0]+seqs[1] != 3 {
+		t.Errorf("ties should keep the first two arrivals, got seqs %v", seqs)
+	}
+}
+
+func TestHistogramFunction(t *testing.T) {
+	fn := NewHistogramAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{[]any{-5, 0, 25, "x", 99.9, 100, 250}, 0, 100, 4})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	want := []any{
+		map[string]any{"from": nil, "to": 0.0, "count": int64(1)},
+		map[string]any{"from": 0.0, "to": 25.0, "count": int64(1)},
+		map[string]any{"from": 25.0, "to": 50.0, "count": int64(1)},
+		map[string]any{"from": 50.0, "to": 75.0, "count": int64(0)},
+		map[string]any{"from": 75.0, "to": 100.0, "count": int64(2)},
+		map[string]any{"from": 100.0, "to": nil, "count": int64(1)},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Execute histogram result = %v, want %v", result, want)
+	}
+	for _, bad := range [][]any{{1, 10, 0, 4}, {1, 0, 0, 4}, {1, 0, 10, 0}, {1, "a", 10, 2}} {
+		if _, err := fn.Execute(ctx, bad); err == nil {
+			t.Errorf("args %v should be rejected", bad)
+		}
+	}
+
+	agg := fn.New().(*HistogramAggregatorFunction)
+	agg.Add(1.0)
+	if agg.Result() != nil {
+		t.Errorf("unconfigured histogram = %v, want nil", agg.Result())
+	}
+	if err := agg.Init([]any{"v", -1, 1, 2}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	agg.Add(-0.5)
+	clone := agg.Clone().(*HistogramAggregatorFunction)
+	clone.Add(0.5)
+	if agg.buckets[1] != 0 || clone.buckets[0] != 1 || clone.buckets[1] != 1 {
+		t.Errorf("Clone failed: agg=%v clone=%v", agg.buckets, clone.buckets)
+	}
+	fresh := agg.New().(*HistogramAggregatorFunction)
+	if len(fresh.buckets) != 2 || fresh.buckets[0] != 0 {
+		t.Errorf("New should keep the configuration with empty counts: %v", fresh.buckets)
+	}
+	agg.Reset()
+	if agg.buckets[0] != 0 || agg.underflow != 0 || len(agg.Result().([]any)) != 4 {
+		t.Errorf("Reset failed: %v", agg.Result())
+	}
+}
+
+func TestRateFunction(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rate := NewRateAggregatorFunction().New().(*RateAggregatorFunction)
+	rate.AddAt(100, base)
+	if rate.Result() != nil {
+		t.Errorf("single sample rate = %v, want nil", rate.Result())
+	}
+	rate.AddAt(140, base.Add(8*time.Second))
+	if rate.Result() != 5.0 {
+		t.Errorf("rate without span = %v, want 5", rate.Result())
+	}
+	SetWindowSpan(rate, base, base.Add(10*time.Second))
+	if rate.Result() != 4.0 {
+		t.Errorf("rate = %v, want 4", rate.Result())
+	}
+	// 计数器重置：last < first 时为 0 而非负速率
+	clone := rate.Clone().(*RateAggregatorFunction)
+	clone.AddAt(20, base.Add(9*time.Second))
+	if clone.Result() != 0.0 || rate.Result() != 4.0 {
+		t.Errorf("counter reset: clone=%v rate=%v, want 0 and 4", clone.Result(), rate.Result())
+	}
+	rate.Reset()
+	if rate.Result() != nil {
+		t.Errorf("Reset failed: %v", rate.Result())
+	}
+}
+
+func TestWeightedAvgFunction(t *testing.T) {
+	fn := NewWeightedAvgAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{[]any{10, 20, "x"}, []any{1, 3, 5}})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if result != 17.5 {
+		t.Errorf("Execute wavg result = %v, want 17.5", result)
+	}
+	if _, err := fn.Execute(ctx, []any{[]any{1, 2}, []any{1}}); err == nil {
+		t.Error("mismatched value/weight arrays should be rejected")
+	}
+
+	agg := fn.New().(*WeightedAvgAggregatorFunction)
+	agg.AddRow(1.0, time.Time{}, map[string]any{"w": 1})
+	if agg.Result() != nil {
+		t.Errorf("unconfigured wavg = %v, want nil", agg.Result())
+	}
+	if err := agg.Init([]any{"v", "w * 2"}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	agg.AddRow(10.0, time.Time{}, map[string]any{"w": 1})
+	agg.AddRow(40.0, time.Time{}, map[string]any{"w": 2})
+	agg.AddRow(99.0, time.Time{}, map[string]any{"w": nil})
+	if got := agg.Result(); got != 30.0 {
+		t.Errorf("wavg with weight expression = %v, want 30", got)
+	}
+	clone := agg.Clone().(*WeightedAvgAggregatorFunction)
+	agg.Reset()
+	if agg.Result() != nil || clone.Result() != 30.0 {
+		t.Errorf("Reset/Clone failed: agg=%v clone=%v", agg.Result(), clone.Result())
+	}
+	// 总权重为 0：NULL 而非除零
+	agg.AddRow(5.0, time.Time{}, map[string]any{"w": 0})
+	if agg.Result() != nil {
+		t.Errorf("zero total weight = %v, want nil", agg.Result())
+	}
+}
+
+func TestExtremumRowFunction(t *testing.T) {
+	rows := []map[string]any{
+		{"id": 1, "v": 20.0},
+		{"id": 2, "v": 35.0},
+		{"id": 3, "v": "n/a"},
+		{"id": 4, "v": 35.0}, // 与最大值并列：保留先到的行
+		{"id": 5, "v": -3.0},
+	}
+	maxRow := NewMaxRowAggregatorFunction().New()
+	minRow := NewMinRowAggregatorFunction().New()
+	for _, row := range rows {
+		AddRow(maxRow, row["v"], time.Time{}, row)
+		AddRow(minRow, row["v"], time.Time{}, row)
+	}
+	got := maxRow.Result().(map[string]any)
+	if got["id"] != 2 {
+		t.Errorf("max_row = %v, want row id 2", got)
+	}
+	if got := minRow.Result().(map[string]any); got["id"] != 5 {
+		t.Errorf("min_row = %v, want row id 5", got)
+	}
+	// 结果是拷贝，修改不影响源行
+	got["id"] = 99
+	if rows[1]["id"] != 2 {
+		t.Errorf("max_row result aliases the source row")
+	}
+
+	clone := maxRow.Clone()
+	AddRow(clone, 40.0, time.Time{}, map[string]any{"id": 6})
+	if clone.Result().(map[string]any)["id"] != 6 || maxRow.Result().(map[string]any)["id"] != 2 {
+		t.Errorf("Clone failed: clone=%v orig=%v", clone.Result(), maxRow.Result())
+	}
+	maxRow.Reset()
+	if maxRow.Result() != nil {
+		t.Errorf("Reset max_row = %v, want nil", maxRow.Result())
+	}
+	if fresh := NewMaxRowAggregatorFunction().New(); fresh.Result() != nil {
+		t.Errorf("empty max_row = %v, want nil", fresh.Result())
+	}
+}
+
+func TestConsecutiveDiffMedianFunction(t *testing.T) {
+	fn := NewConsecutiveDiffMedianAggregatorFunction()
+	ctx := &FunctionContext{}
+	// 10,12,11,15,14：相邻差 2,1,4,1 -> 排序 1,1,2,4 -> 中位数 1.5
+	result, err := fn.Execute(ctx, []any{10, 12, 11, 15, 14})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if result != 1.5 {
+		t.Errorf("Execute consecutive_diff_median result = %v, want 1.5", result)
+	}
+	agg := fn.New().(*ConsecutiveDiffMedianAggregatorFunction)
+	agg.Add(5.0)
+	if agg.Result() != nil {
+		t.Errorf("consecutive_diff_median with one value = %v, want nil", agg.Result())
+	}
+	agg.Add("not a number")
+	agg.Add(8.0)
+	agg.Add(2.0)
+	// 5,8,2：相邻差 3,6 -> 中位数 4.5（非数值被跳过）
+	if agg.Result() != 4.5 {
+		t.Errorf("Agg consecutive_diff_median result = %v, want 4.5", agg.Result())
+	}
+	clone := agg.Clone().(*ConsecutiveDiffMedianAggregatorFunction)
+	clone.Add(2.0)
+	// 克隆独立：3,6,0 -> 中位数 3；原对象不受影响
+	if clone.Result() != 3.0 || agg.Result() != 4.5 {
+		t.Errorf("Clone failed: clone=%v agg=%v", clone.Result(), agg.Result())
+	}
+	agg.Reset()
+	agg.Add(100.0)
+	if agg.Result() != nil {
+		t.Errorf("Reset failed")
+	}
+}
+
+func TestValueCountsFunction(t *testing.T) {
+	fn := NewValueCountsAggregatorFunction()
+	ctx := &FunctionContext{}
+	result, err := fn.Execute(ctx, []any{[]any{"a", "b", "a", nil, 1}})
+	if err != nil {
+		t.Errorf("Execute error: %v", err)
+	}
+	if want := map[string]int{"a": 2, "b": 1, "1": 1}; !reflect.DeepEqual(result, want) {
+		t.Errorf("Execute value_counts result = %v, want %v", result, want)
+	}
+
+	agg := fn.New().(*ValueCountsAggregatorFunction)
+	if err := agg.Init([]any{"category", 2}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	for _, v := range []any{"x", "y", "z", "y", "x", "y", "w"} {
+		agg.Add(v)
+	}
+	// top-2：y=3, x=2；z/w 被截断
+	if want := map[string]int{"y": 3, "x": 2}; !reflect.DeepEqual(agg.Result(), want) {
+		t.Errorf("top-2 value_counts = %v, want %v", agg.Result(), want)
+	}
+	clone := agg.Clone().(*ValueCountsAggregatorFunction)
+	if !reflect.DeepEqual(clone.counts, agg.counts) || clone.topN != 2 {
+		t.Errorf("Clone failed")
+	}
+	agg.Reset()
+	if !reflect.DeepEqual(agg.Result(), map[string]int{}) {
+		t.Errorf("Reset failed")
+	}
+	if err := agg.Init([]any{"category", 0}); err == nil {
+		t.Errorf("Init with topN=0 should fail")
+	}
+}
+
+// TestValueCountsTrackingCap 不同值超过 topN*factor 时跟踪项数受限，重度值计数仍保留
+func TestValueCountsTrackingCap(t *testing.T) {
+	agg := NewValueCountsAggregatorFunction().New().(*ValueCountsAggregatorFunction)
+	if err := agg.Init([]any{"v", 1}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		agg.Add("hot")
+		agg.Add(i)
+	}
+	if len(agg.counts) > valueCountsTrackFactor {
+		t.Errorf("tracked %d distinct values, want <= %d", len(agg.counts), valueCountsTrackFactor)
+	}
+	if want := map[string]int{"hot": 100}; !reflect.DeepEqual(agg.Result(), want) {
+		t.Errorf("top-1 value_counts = %v, want %v", agg.Result(), want)
+	}
+}
+
+// TestValueCountsApproximateAfterEviction 超出跟踪容量后计数为近似值：新值继承被淘汰项的计数
+func TestValueCountsApproximateAfterEviction(t *testing.T) {
+	agg := NewValueCountsAggregatorFunction().New().(*ValueCountsAggregatorFunction)
+	if err := agg.Init([]any{"v", 1}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	// 容量为 1*valueCountsTrackFactor，填满后再来一个新值即触发淘汰
+	for i := 0; i < valueCountsTrackFactor; i++ {
+		agg.Add(fmt.Sprintf("v%d", i))
+	}
+	agg.Add("late")
+	// late 实际只出现 1 次，但继承了被淘汰项的计数 1，报告为 2 并挤进 top-1
+	if want := map[string]int{"late": 2}; !reflect.DeepEqual(agg.Result(), want) {
+		t.Errorf("top-1 value_counts after eviction = %v, want %v", agg.Result(), want)
+	}
+	if len(agg.counts) != valueCountsTrackFactor {
+		t.Errorf("tracked %d distinct values, want %d", len(agg.counts), valueCountsTrackFactor)
+	}
+}
+
+// TestApproxCountDistinctFunction 估计误差应在 HyperLogLog 标准误差（~1.6%）的数倍以内
+func TestApproxCountDistinctFunction(t *testing.T) {
+	fn := NewApproxCountDistinctAggregatorFunction()
+	result, err := fn.Execute(&FunctionContext{}, []any{[]any{"a", "b", "a", nil, 1, "1"}})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if result != int64(3) {
+		t.Errorf("Execute approx_count_distinct = %v, want 3", result)
+	}
+
+	agg := fn.New().(*ApproxCountDistinctAggregatorFunction)
+	if agg.Result() != int64(0) {
+		t.Errorf("empty result = %v, want 0", agg.Result())
+	}
+	const n = 50000
+	for i := 0; i < n; i++ {
+		agg.Add(fmt.Sprintf("device-%d", i))
+		agg.Add(fmt.Sprintf("device-%d", i%100)) // 重复值不影响估计
+	}
+	got := agg.Result().(int64)
+	if relErr := math.Abs(float64(got)-n) / n; relErr > 0.06 {
+		t.Errorf("estimate %d for %d distinct values, relative error %.3f", got, n, relErr)
+	}
+
+	clone := agg.Clone().(*ApproxCountDistinctAggregatorFunction)
+	for i := n; i < 2*n; i++ {
+		clone.Add(fmt.Sprintf("device-%d", i))
+	}
+	if agg.Result() != got {
+		t.Errorf("Clone shares registers: original changed to %v", agg.Result())
+	}
+
+	other := fn.New().(*ApproxCountDistinctAggregatorFunction)
+	for i := n; i < 2*n; i++ {
+		other.Add(fmt.Sprintf("device-%d", i))
+	}
+	agg.Merge(other)
+	if agg.Result() != clone.Result() {
+		t.Errorf("Merge = %v, want %v (same union of values)", agg.Result(), clone.Result())
+	}
+
+	agg.Reset()
+	if agg.Result() != int64(0) {
+		t.Errorf("Reset failed: %v", agg.Result())
+	}
+}
+
+// TestCountDistinctFunction 精确去重：NULL 不计入，Clone 复制集合，Reset 清空
+func TestCountDistinctFunction(t *testing.T) {
+	fn := NewCountDistinctAggregatorFunction()
+	result, err := fn.Execute(&FunctionContext{}, []any{[]any{"a", "b", "a", nil, 1, "1"}})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if result != int64(3) {
+		t.Errorf("Execute count_distinct = %v, want 3", result)
+	}
+
+	agg := fn.New().(*CountDistinctAggregatorFunction)
+	if agg.Result() != int64(0) {
+		t.Errorf("empty result = %v, want 0", agg.Result())
+	}
+	for i := 0; i < 1000; i++ {
+		agg.Add(i % 250)
+	}
+	if agg.Result() != int64(250) {
+		t.Errorf("Result = %v, want 250", agg.Result())
+	}
+
+	clone := agg.Clone().(*CountDistinctAggregatorFunction)
+	clone.Add("new")
+	if agg.Result() != int64(250) || clone.Result() != int64(251) {
+		t.Errorf("Clone shares set: original %v, clone %v", agg.Result(), clone.Result())
+	}
+
+	agg.Reset()
+	if agg.Result() != int64(0) {
+		t.Errorf("Reset failed: %v", agg.Result())
+	}
+	if clone.Result() != int64(251) {
+		t.Errorf("Reset affected clone: %v", clone.Result())
+	}
+}
+
+func TestStringAggFunction(t *testing.T) {
+	fn := NewStringAggAggregatorFunction()
+	result, err := fn.Execute(&FunctionContext{}, []any{[]any{"a", nil, 1, "b"}, " | "})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if result != "a | 1 | b" {
+		t.Errorf("Execute string_agg = %v, want %q", result, "a | 1 | b")
+	}
+	if _, err := fn.Execute(&FunctionContext{}, []any{"a", 1}); err == nil {
+		t.Error("expected error for non-string separator")
+	}
+
+	agg := fn.New().(*StringAggAggregatorFunction)
+	if agg.Result() != nil {
+		t.Errorf("empty result = %v, want nil", agg.Result())
+	}
+	agg.Add(nil)
+	if agg.Result() != nil {
+		t.Errorf("NULL-only result = %v, want nil", agg.Result())
+	}
+	agg.Add("x")
+	agg.Add(nil)
+	agg.Add("y")
+	if agg.Result() != "x,y" {
+		t.Errorf("default separator result = %v, want %q", agg.Result(), "x,y")
+	}
+
+	param := fn.New().(*StringAggAggregatorFunction)
+	if err := param.Init([]any{"name", ";"}); err != nil {
+		t.Fatalf("Init error: %v", err)
+	}
+	param.Add("p")
+	clone := param.Clone().(*StringAggAggregatorFunction)
+	clone.Add("q")
+	if param.Result() != "p" || clone.Result() != "p;q" {
+		t.Errorf("Clone = %v / %v, want p / p;q", param.Result(), clone.Result())
+	}
+	if err := param.Init([]any{"name", 1}); err == nil {
+		t.Error("expected Init error for non-string separator")
+	}
+
+	param.Reset()
+	if param.Result() != nil {
+		t.Errorf("Reset failed: %v", param.Result())
+	}
+}
+
 func TestCollectFunction(t *testing.T) {
 	fn := NewCollectFunction()
 	ctx := &FunctionContext{}
@@ -227,6 +936,37 @@ func TestCollectFunction(t *testing.T) {
 	}
 }
 
+func TestArrayAggFunction(t *testing.T) {
+	fn := NewArrayAggFunction()
+
+	if err := fn.Validate([]any{"a", "b"}); err == nil {
+		t.Error("array_agg should accept exactly one argument")
+	}
+
+	agg := fn.New().(*ArrayAggFunction)
+	if res := agg.Result().([]any); res == nil || len(res) != 0 {
+		t.Errorf("empty array_agg result = %#v, want empty non-nil slice", res)
+	}
+
+	// 保留重复值、NULL 与到达顺序
+	for _, v := range []any{3, 1, 3, nil, 1} {
+		agg.Add(v)
+	}
+	expected := []any{3, 1, 3, nil, 1}
+	if res := agg.Result(); !reflect.DeepEqual(res, expected) {
+		t.Errorf("array_agg result = %v, want %v", res, expected)
+	}
+
+	clone := agg.Clone().(*ArrayAggFunction)
+	agg.Reset()
+	if res := agg.Result().([]any); res == nil || len(res) != 0 {
+		t.Errorf("Reset failed, result = %#v", res)
+	}
+	if !reflect.DeepEqual(clone.Result(), expected) {
+		t.Errorf("Clone result = %v, want %v", clone.Result(), expected)
+	}
+}
+
 func TestLastValueFunction(t *testing.T) {
 	fn := NewLastValueFunction()
 	ctx := &FunctionContext{}
@@ -479,7 +1219,7 @@ func TestAggregatorFunctions(t *testing.T) {
 		t.Errorf("VarAggregator Reset failed")
 	}
 	clone9 := agg9.Clone().(*VarAggregatorFunction)
-	if !reflect.DeepEqual(clone9.values, agg9.values) {
+	if clone9.welford != agg9.welford {
 		t.Errorf("VarAggregator Clone failed")
 	}
 
@@ -495,11 +1235,11 @@ func TestAggregatorFunctions(t *testing.T) {
 		t.Errorf("VarSAggregator result = %v, want ~1.667", res10)
 	}
 	agg10.Reset()
-	if agg10.Result().(float64) != 0.0 {
+	if agg10.Result() != nil {
 		t.Errorf("VarSAggregator Reset failed")
 	}
 	clone10 := agg10.Clone().(*VarSAggregatorFunction)
-	if !reflect.DeepEqual(clone10.values, agg10.values) {
+	if clone10.welford != agg10.welford {
 		t.Errorf("VarSAggregator Clone failed")
 	}
 }
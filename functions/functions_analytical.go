@@ -2,6 +2,8 @@ package functions
 
 import (
 	"fmt"
+	"math"
+	"strings"
 )
 
 // LagFunction LAG函数 - 返回当前行之前的第N行的值
@@ -169,6 +171,10 @@ func (s *latestState) Reset() { s.latest = nil; s.hasVal = false }
 func (f *LatestFunction) NewState() AnalyticState { return &latestState{} }
 
 // hadChangedState 维护各列上次值，Apply 返回是否有变化（首次视为变化。
+// 两种调用形式：had_changed(ignoreNull, col...) 逐列精确比较；
+// had_changed(col, tolerance) 数值与基准之差不超过 tolerance 视为未变化，用于滤除传感器浮点噪声。
+// col 与 tolerance 都是逐行求出的值而非列名：未加引号的 temperature 是字段引用，
+// temperature * 10 这样的表达式按结果比较，加引号的 'temperature' 是常量，永远不变。
 type hadChangedState struct {
 	prev       []any
 	first      bool
@@ -177,6 +183,11 @@ type hadChangedState struct {
 }
 
 func (s *hadChangedState) Apply(args []any) any {
+	if len(args) == 2 && !isBoolArg(args[0]) {
+		if tolerance, ok := toFloat64Generic(args[1]); ok {
+			return s.applyTolerance(args[0], math.Abs(tolerance))
+		}
+	}
 	ignoreNull := false
 	var values []any
 	if len(args) > 0 {
@@ -207,6 +218,37 @@ func (s *hadChangedState) Apply(args []any) any {
 	return changed
 }
 
+// applyTolerance 实现 had_changed(col, tolerance)。基准只在报告变化时更新，
+// 故缓慢漂移累计超过 tolerance 时仍会报告一次变化。非数值按精确比较。
+func (s *hadChangedState) applyTolerance(v any, tolerance float64) any {
+	if !s.first {
+		s.first = true
+		s.prev = []any{v}
+		return true
+	}
+	prev := s.prev[0]
+	if pf, ok := toFloat64Generic(prev); ok {
+		if vf, ok := toFloat64Generic(v); ok && math.Abs(vf-pf) <= tolerance {
+			return false
+		}
+	} else if analyticEqual(prev, v) {
+		return false
+	}
+	s.prev[0] = v
+	return true
+}
+
+// isBoolArg 判断参数是否为 ignoreNull 布尔值（未加引号的 true/false 可能以字符串到达）。
+func isBoolArg(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return true
+	case string:
+		return strings.EqualFold(b, "true") || strings.EqualFold(b, "false")
+	}
+	return false
+}
+
 // ApplyNamed 按列名比较整行（had_changed '*' 用）。列增删/乱序均按名字判定，
 // 不受位置影响。ignoreNull+nil 不触发变化且保留旧基准。
 func (s *hadChangedState) ApplyNamed(ignoreNull bool, cols map[string]any) any {
@@ -2,7 +2,6 @@ package functions
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/rulego/streamsql/utils/cast"
 )
@@ -40,14 +39,16 @@ func (f *IfNullFunction) Execute(ctx *FunctionContext, args []any) (any, error)
 	return args[0], nil
 }
 
-// CoalesceFunction returns first non-NULL value
+// CoalesceFunction returns the first non-NULL argument. Arguments are
+// evaluated left to right only until one is non-NULL, so later arguments that
+// would fail are not evaluated.
 type CoalesceFunction struct {
 	*BaseFunction
 }
 
 func NewCoalesceFunction() *CoalesceFunction {
 	return &CoalesceFunction{
-		BaseFunction: NewBaseFunction("coalesce", TypeString, "conditional", "Return first non-NULL value", 1, -1),
+		BaseFunction: NewBaseFunction("coalesce", TypeConversion, "conditional", "Return first non-NULL value", 1, -1),
 	}
 }
 
@@ -64,14 +65,55 @@ func (f *CoalesceFunction) Execute(ctx *FunctionContext, args []any) (any, error
 	return nil, nil
 }
 
-// NullIfFunction returns NULL if two values are equal
+// ExecuteLazy implements LazyFunction.
+func (f *CoalesceFunction) ExecuteLazy(ctx *FunctionContext, args []LazyArg) (any, error) {
+	for _, arg := range args {
+		v, err := arg()
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// CoalesceExprFunction returns fallback when expr evaluates to NULL. Used as
+// the outermost call of a SELECT field, coalesce_expr(expr, fallback) also
+// substitutes fallback when expr fails to evaluate for a row; the projection
+// handles that case because a failing argument never reaches Execute.
+type CoalesceExprFunction struct {
+	*BaseFunction
+}
+
+func NewCoalesceExprFunction() *CoalesceExprFunction {
+	return &CoalesceExprFunction{
+		BaseFunction: NewBaseFunction("coalesce_expr", TypeConversion, "conditional", "Return fallback when expression is NULL or fails to evaluate", 2, 2),
+	}
+}
+
+func (f *CoalesceExprFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *CoalesceExprFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if args[0] != nil {
+		return args[0], nil
+	}
+	return args[1], nil
+}
+
+// NullIfFunction returns NULL when its two arguments are equal (numbers compare
+// across int/float kinds), otherwise the first argument. The second argument
+// is not evaluated when the first is NULL.
 type NullIfFunction struct {
 	*BaseFunction
 }
 
 func NewNullIfFunction() *NullIfFunction {
 	return &NullIfFunction{
-		BaseFunction: NewBaseFunction("null_if", TypeString, "conditional", "Return NULL if two values are equal", 2, 2),
+		BaseFunction: NewBaseFunctionWithAliases("nullif", TypeConversion, "conditional", "Return NULL if two values are equal", 2, 2, []string{"null_if"}),
 	}
 }
 
@@ -80,12 +122,25 @@ func (f *NullIfFunction) Validate(args []any) error {
 }
 
 func (f *NullIfFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	if reflect.DeepEqual(args[0], args[1]) {
+	if args[0] == nil || analyticEqual(args[0], args[1]) {
 		return nil, nil
 	}
 	return args[0], nil
 }
 
+// ExecuteLazy implements LazyFunction.
+func (f *NullIfFunction) ExecuteLazy(ctx *FunctionContext, args []LazyArg) (any, error) {
+	v, err := args[0]()
+	if err != nil || v == nil {
+		return nil, err
+	}
+	other, err := args[1]()
+	if err != nil {
+		return nil, err
+	}
+	return f.Execute(ctx, []any{v, other})
+}
+
 // GreatestFunction returns maximum value
 type GreatestFunction struct {
 	*BaseFunction
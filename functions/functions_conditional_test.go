@@ -37,6 +37,18 @@ func TestConditionalFunctions(t *testing.T) {
 			args:     []any{"test", "other"},
 			expected: "test",
 		},
+		{
+			name:     "nullif numeric across types",
+			funcName: "nullif",
+			args:     []any{0.0, 0},
+			expected: nil,
+		},
+		{
+			name:     "nullif null first",
+			funcName: "nullif",
+			args:     []any{nil, 0},
+			expected: nil,
+		},
 		{
 			name:     "greatest basic",
 			funcName: "greatest",
@@ -142,6 +154,18 @@ func TestConditionalFunctionValidation(t *testing.T) {
 			args:     []any{nil, "default"},
 			wantErr:  false,
 		},
+		{
+			name:     "coalesce_expr one arg",
+			function: NewCoalesceExprFunction(),
+			args:     []any{1},
+			wantErr:  true,
+		},
+		{
+			name:     "coalesce_expr valid args",
+			function: NewCoalesceExprFunction(),
+			args:     []any{nil, 0},
+			wantErr:  false,
+		},
 		{
 			name:     "null_if no args",
 			function: NewNullIfFunction(),
@@ -244,6 +268,20 @@ func TestConditionalFunctionEdgeCases(t *testing.T) {
 			expected: "second",
 			wantErr:  false,
 		},
+		{
+			name:     "coalesce_expr null value",
+			function: NewCoalesceExprFunction(),
+			args:     []any{nil, 0},
+			expected: 0,
+			wantErr:  false,
+		},
+		{
+			name:     "coalesce_expr keeps value",
+			function: NewCoalesceExprFunction(),
+			args:     []any{2.5, 0},
+			expected: 2.5,
+			wantErr:  false,
+		},
 		{
 			name:     "greatest with mixed types",
 			function: NewGreatestFunction(),
@@ -302,3 +340,18 @@ func TestConditionalFunctionEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestCoalesceFunctionTypes coalesce 与 coalesce_expr 都原样返回参数值，按类型列举函数时归为同一类
+func TestCoalesceFunctionTypes(t *testing.T) {
+	coalesce, ok := Get("coalesce")
+	if !ok {
+		t.Fatal("coalesce not found")
+	}
+	coalesceExpr, ok := Get("coalesce_expr")
+	if !ok {
+		t.Fatal("coalesce_expr not found")
+	}
+	if coalesceExpr.GetType() != coalesce.GetType() {
+		t.Errorf("coalesce_expr type = %v, want %v", coalesceExpr.GetType(), coalesce.GetType())
+	}
+}
@@ -113,7 +113,9 @@ func (f *Dec2HexFunction) Execute(ctx *FunctionContext, args []any) (any, error)
 	return fmt.Sprintf("%x", val), nil
 }
 
-// EncodeFunction 将输入值编码为指定格式的字符串
+// EncodeFunction 将输入值编码为指定格式的字符串。
+// 支持的格式：base64（标准编码，带填充）、hex（小写十六进制）、url（查询参数转义，空格编码为 +）。
+// 输入可以是 string 或 []byte，未知格式返回错误。
 type EncodeFunction struct {
 	*BaseFunction
 }
@@ -170,7 +172,9 @@ func (f *EncodeFunction) Execute(ctx *FunctionContext, args []any) (any, error)
 	}
 }
 
-// DecodeFunction 将编码的字符串解码为原始数据
+// DecodeFunction 将编码的字符串解码为原始数据，格式与 EncodeFunction 相同。
+// 结果统一为 string：base64 和 hex 解码出的二进制数据按原始字节放入字符串（不做 UTF-8 校验），
+// 需要 []byte 时可直接转换。非法输入和未知格式返回错误。
 type DecodeFunction struct {
 	*BaseFunction
 }
@@ -268,3 +268,43 @@ func TestCastIntRanges(t *testing.T) {
 		t.Errorf(`cast(100, "int") returned %T, want int`, r)
 	}
 }
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	encode, _ := Get("encode")
+	decode, _ := Get("decode")
+	inputs := []string{"", "hello", "a b/c?d=e&f", "温度=25℃", "\x00\x01\xfe\xff"}
+	for _, format := range []string{"base64", "hex", "url"} {
+		for _, in := range inputs {
+			enc, err := encode.Execute(&FunctionContext{}, []any{in, format})
+			if err != nil {
+				t.Fatalf("encode(%q, %s): %v", in, format, err)
+			}
+			dec, err := decode.Execute(&FunctionContext{}, []any{enc, format})
+			if err != nil {
+				t.Fatalf("decode(%q, %s): %v", enc, format, err)
+			}
+			if dec != in {
+				t.Errorf("%s round trip of %q = %q", format, in, dec)
+			}
+		}
+	}
+
+	// []byte 输入与等价字符串编码结果一致，解码结果为字节字符串
+	enc, err := encode.Execute(&FunctionContext{}, []any{[]byte{0, 1, 255}, "hex"})
+	if err != nil || enc != "0001ff" {
+		t.Fatalf(`encode([]byte{0,1,255}, "hex") = %v, %v`, enc, err)
+	}
+	dec, err := decode.Execute(&FunctionContext{}, []any{enc, "hex"})
+	if err != nil || dec != "\x00\x01\xff" {
+		t.Errorf(`decode(%q, "hex") = %q, %v`, enc, dec, err)
+	}
+
+	for _, f := range []Function{encode, decode} {
+		if _, err := f.Execute(&FunctionContext{}, []any{"x", "base32"}); err == nil {
+			t.Errorf("%s with unknown format: expected error", f.GetName())
+		}
+	}
+	if _, err := decode.Execute(&FunctionContext{}, []any{"%zz", "url"}); err == nil {
+		t.Error(`decode("%zz", "url"): expected error`)
+	}
+}
@@ -107,129 +107,207 @@ func (f *DateAddFunction) Validate(args []any) error {
 }
 
 func (f *DateAddFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	dateStr, err := dateArgString(args[0])
+	return shiftDate(args, false)
+}
+
+// shiftDate 实现 date_add/date_sub：按 parseDateArg 解析日期，加上（negate 时减去）
+// interval 个 unit，按输入的表示形式返回结果。
+func shiftDate(args []any, negate bool) (any, error) {
+	t, kind, err := parseDateArg(args[0])
 	if err != nil {
-		return nil, fmt.Errorf("invalid date: %v", err)
+		return nil, err
 	}
 
 	interval, err := cast.ToInt64E(args[1])
 	if err != nil {
 		return nil, fmt.Errorf("invalid interval: %v", err)
 	}
+	if negate {
+		if interval == math.MinInt64 {
+			return nil, fmt.Errorf("interval %d out of range", interval)
+		}
+		interval = -interval
+	}
 
 	unit, err := cast.ToStringE(args[2])
 	if err != nil {
 		return nil, fmt.Errorf("invalid unit: %v", err)
 	}
 
-	t, err := time.Parse("2006-01-02 15:04:05", dateStr)
-	if err != nil {
-		// 尝试其他格式
-		if t, err = time.Parse("2006-01-02", dateStr); err != nil {
-			return nil, fmt.Errorf("invalid date format: %v", err)
-		}
+	if t, err = addDateInterval(t, interval, unit); err != nil {
+		return nil, err
 	}
-
-	switch strings.ToLower(unit) {
-	case "year", "years":
-		t = t.AddDate(int(interval), 0, 0)
-	case "month", "months":
-		t = t.AddDate(0, int(interval), 0)
-	case "day", "days":
-		t = t.AddDate(0, 0, int(interval))
-	case "hour", "hours":
-		d, err := durationFromInterval(interval, time.Hour)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(d)
-	case "minute", "minutes":
-		d, err := durationFromInterval(interval, time.Minute)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(d)
-	case "second", "seconds":
-		d, err := durationFromInterval(interval, time.Second)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(d)
-	default:
-		return nil, fmt.Errorf("unsupported unit: %s", unit)
-	}
-
-	return t.Format("2006-01-02 15:04:05"), nil
+	return kind.format(t), nil
 }
 
-// DateSubFunction 日期减法函数
-type DateSubFunction struct {
+// DateTruncFunction 把时间截断到指定单位的起点：date_trunc('hour', ts) 将分、秒及更小的
+// 部分清零，week 截断到周一 00:00:00。返回值与输入同类型（见 parseDateArg）。
+type DateTruncFunction struct {
 	*BaseFunction
 }
 
-func NewDateSubFunction() *DateSubFunction {
-	return &DateSubFunction{
-		BaseFunction: NewBaseFunction("date_sub", TypeDateTime, "时间日期函数", "日期减法", 3, 3),
+func NewDateTruncFunction() *DateTruncFunction {
+	return &DateTruncFunction{
+		BaseFunction: NewBaseFunction("date_trunc", TypeDateTime, "时间日期函数", "按单位截断时间", 2, 2),
 	}
 }
 
-func (f *DateSubFunction) Validate(args []any) error {
+func (f *DateTruncFunction) Validate(args []any) error {
 	return f.ValidateArgCount(args)
 }
 
-func (f *DateSubFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
-	dateStr, err := dateArgString(args[0])
+func (f *DateTruncFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	unit, err := cast.ToStringE(args[0])
 	if err != nil {
-		return nil, fmt.Errorf("invalid date: %v", err)
+		return nil, fmt.Errorf("invalid unit: %v", err)
 	}
 
-	interval, err := cast.ToInt64E(args[1])
+	t, kind, err := parseDateArg(args[1])
 	if err != nil {
-		return nil, fmt.Errorf("invalid interval: %v", err)
+		return nil, err
 	}
 
-	unit, err := cast.ToStringE(args[2])
-	if err != nil {
-		return nil, fmt.Errorf("invalid unit: %v", err)
+	if t, err = truncateDate(t, unit); err != nil {
+		return nil, err
 	}
+	return kind.format(t), nil
+}
+
+// dateKind 记录日期参数的表示形式，date_add/date_sub/date_trunc 按原形式返回结果。
+type dateKind int
+
+const (
+	dateKindString  dateKind = iota // "2006-01-02 15:04:05" 或 "2006-01-02"
+	dateKindRFC3339                 // RFC3339 字符串，保留其时区偏移
+	dateKindTime                    // time.Time，保留其时区
+	dateKindUnix                    // Unix 毫秒（整数或浮点），按 UTC 计算
+)
+
+func (k dateKind) format(t time.Time) any {
+	switch k {
+	case dateKindTime:
+		return t
+	case dateKindUnix:
+		return t.UnixMilli()
+	case dateKindRFC3339:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
 
+// parseDateArg 解析日期参数：time.Time 原样使用，数值视为 Unix 毫秒（与流事件时间
+// TIMEUNIT='ms' 及 business_duration 一致），字符串按 RFC3339、"2006-01-02 15:04:05"、
+// "2006-01-02" 依次尝试。
+func parseDateArg(v any) (time.Time, dateKind, error) {
+	switch d := v.(type) {
+	case time.Time:
+		return d, dateKindTime, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		ms, err := cast.ToInt64E(d)
+		if err != nil {
+			return time.Time{}, dateKindUnix, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		return time.UnixMilli(ms).UTC(), dateKindUnix, nil
+	}
+	dateStr, err := dateArgString(v)
+	if err != nil {
+		return time.Time{}, dateKindString, fmt.Errorf("invalid date: %v", err)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, dateStr); err == nil {
+		return t, dateKindRFC3339, nil
+	}
 	t, err := time.Parse("2006-01-02 15:04:05", dateStr)
 	if err != nil {
 		if t, err = time.Parse("2006-01-02", dateStr); err != nil {
-			return nil, fmt.Errorf("invalid date format: %v", err)
+			return time.Time{}, dateKindString, fmt.Errorf("invalid date format: %v", err)
 		}
 	}
+	return t, dateKindString, nil
+}
 
+// addDateInterval 给 t 加上有符号的 interval 个 unit；year/month/week/day 按日历计算，
+// year/month 落到目标月不存在的日期时取该月最后一天（1 月 31 日加 1 个月为 2 月 28/29 日）。
+func addDateInterval(t time.Time, interval int64, unit string) (time.Time, error) {
+	var step time.Duration
 	switch strings.ToLower(unit) {
 	case "year", "years":
-		t = t.AddDate(-int(interval), 0, 0)
+		return addMonthsClamped(t, 12*int(interval)), nil
 	case "month", "months":
-		t = t.AddDate(0, -int(interval), 0)
+		return addMonthsClamped(t, int(interval)), nil
+	case "week", "weeks":
+		return t.AddDate(0, 0, 7*int(interval)), nil
 	case "day", "days":
-		t = t.AddDate(0, 0, -int(interval))
+		return t.AddDate(0, 0, int(interval)), nil
 	case "hour", "hours":
-		d, err := durationFromInterval(interval, time.Hour)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(-d)
+		step = time.Hour
 	case "minute", "minutes":
-		d, err := durationFromInterval(interval, time.Minute)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(-d)
+		step = time.Minute
 	case "second", "seconds":
-		d, err := durationFromInterval(interval, time.Second)
-		if err != nil {
-			return nil, err
-		}
-		t = t.Add(-d)
+		step = time.Second
 	default:
-		return nil, fmt.Errorf("unsupported unit: %s", unit)
+		return t, fmt.Errorf("unsupported unit: %s", unit)
+	}
+	d, err := durationFromInterval(interval, step)
+	if err != nil {
+		return t, err
+	}
+	return t.Add(d), nil
+}
+
+// addMonthsClamped 加 months 个月，日期超出目标月天数时截到月末，而非像 AddDate 那样溢出到下月。
+func addMonthsClamped(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
 	}
+	return first.AddDate(0, 0, d-1)
+}
 
-	return t.Format("2006-01-02 15:04:05"), nil
+// truncateDate 把 t 在其时区内截断到 unit 的起点。
+func truncateDate(t time.Time, unit string) (time.Time, error) {
+	y, mon, d := t.Date()
+	loc := t.Location()
+	switch strings.ToLower(unit) {
+	case "year", "years":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, loc), nil
+	case "month", "months":
+		return time.Date(y, mon, 1, 0, 0, 0, 0, loc), nil
+	case "week", "weeks":
+		// ISO 周从周一开始
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, mon, d-offset, 0, 0, 0, 0, loc), nil
+	case "day", "days":
+		return time.Date(y, mon, d, 0, 0, 0, 0, loc), nil
+	case "hour", "hours":
+		return time.Date(y, mon, d, t.Hour(), 0, 0, 0, loc), nil
+	case "minute", "minutes":
+		return time.Date(y, mon, d, t.Hour(), t.Minute(), 0, 0, loc), nil
+	case "second", "seconds":
+		return time.Date(y, mon, d, t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+	default:
+		return t, fmt.Errorf("unsupported unit: %s", unit)
+	}
+}
+
+// DateSubFunction 日期减法函数：date_sub(d, n, unit) 等同于 date_add(d, -n, unit)。
+type DateSubFunction struct {
+	*BaseFunction
+}
+
+func NewDateSubFunction() *DateSubFunction {
+	return &DateSubFunction{
+		BaseFunction: NewBaseFunction("date_sub", TypeDateTime, "时间日期函数", "日期减法", 3, 3),
+	}
+}
+
+func (f *DateSubFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *DateSubFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return shiftDate(args, true)
 }
 
 // DateDiffFunction 日期差函数
@@ -804,3 +882,184 @@ func (f *WeekOfYearFunction) Execute(ctx *FunctionContext, args []any) (any, err
 	_, week := t.ISOWeek()
 	return week, nil
 }
+
+// BusinessDurationFunction 营业时长函数：business_duration(start, end, schedule)
+// 返回 [start, end) 落在营业时段内的秒数（int64），用于 SLA 类指标。
+// start/end 可为 time.Time、RFC3339 或 "2006-01-02[ 15:04:05]" 字符串、Unix 毫秒时间戳，统一按 UTC 计算；
+// end 早于 start 时返回负值。schedule 形如 "Mon-Fri 09:00-17:00"，多段以 ';' 分隔
+// （如 "Mon-Fri 09:00-17:00; Sat 10:00-14:00"），星期可用 ',' 列举，结束时间允许 24:00。
+type BusinessDurationFunction struct {
+	*BaseFunction
+}
+
+func NewBusinessDurationFunction() *BusinessDurationFunction {
+	return &BusinessDurationFunction{
+		BaseFunction: NewBaseFunction("business_duration", TypeDateTime, "时间日期函数", "计算营业时段内的时长（秒）", 3, 3),
+	}
+}
+
+func (f *BusinessDurationFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *BusinessDurationFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	start, err := businessTimeArg(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %v", err)
+	}
+	end, err := businessTimeArg(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %v", err)
+	}
+	desc, err := cast.ToStringE(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	sched, err := parseBusinessSchedule(desc)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return -int64(sched.overlap(end, start) / time.Second), nil
+	}
+	return int64(sched.overlap(start, end) / time.Second), nil
+}
+
+// businessTimeArg 把时间参数统一为 UTC time.Time，解析规则同 parseDateArg（数值按 Unix 毫秒）。
+func businessTimeArg(v any) (time.Time, error) {
+	t, _, err := parseDateArg(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// businessSchedule 按星期索引（time.Weekday）保存当天的营业时段，时段以距当日零点的偏移表示。
+type businessSchedule [7][]businessSpan
+
+type businessSpan struct {
+	from, to time.Duration
+}
+
+var businessWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseBusinessSchedule 解析 "Mon-Fri 09:00-17:00; Sat 10:00-14:00" 形式的营业时段描述。
+// 星期区间可跨周末（如 "Fri-Mon"），时段不可跨午夜；同一天的时段不应重叠，否则重叠部分重复计入。
+func parseBusinessSchedule(desc string) (*businessSchedule, error) {
+	var sched businessSchedule
+	segments := 0
+	for _, seg := range strings.Split(desc, ";") {
+		seg = strings.TrimSpace(seg)
+		if seg == "" {
+			continue
+		}
+		parts := strings.Fields(seg)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid schedule segment %q: expected \"<days> <HH:MM-HH:MM>\"", seg)
+		}
+		days, err := parseBusinessDays(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		span, err := parseBusinessSpan(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range days {
+			sched[d] = append(sched[d], span)
+		}
+		segments++
+	}
+	if segments == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	return &sched, nil
+}
+
+func parseBusinessDays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, item := range strings.Split(s, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		first, ok := businessWeekdays[strings.ToLower(bounds[0])]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q in schedule", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = businessWeekdays[strings.ToLower(bounds[1])]; !ok {
+				return nil, fmt.Errorf("invalid weekday %q in schedule", bounds[1])
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseBusinessSpan(s string) (businessSpan, error) {
+	bounds := strings.SplitN(s, "-", 2)
+	if len(bounds) != 2 {
+		return businessSpan{}, fmt.Errorf("invalid schedule hours %q: expected HH:MM-HH:MM", s)
+	}
+	from, err := parseClock(bounds[0])
+	if err != nil {
+		return businessSpan{}, err
+	}
+	to, err := parseClock(bounds[1])
+	if err != nil {
+		return businessSpan{}, err
+	}
+	if to <= from {
+		return businessSpan{}, fmt.Errorf("invalid schedule hours %q: end must be after start", s)
+	}
+	return businessSpan{from: from, to: to}, nil
+}
+
+// parseClock 解析 HH:MM，允许 24:00 表示当日结束。
+func parseClock(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid clock time %q in schedule", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// overlap 计算 [start, end) 与营业时段的交集总时长（start <= end）。
+// 整周直接按每周营业时长累加，只对首尾不足一周的部分逐日求交。
+func (s *businessSchedule) overlap(start, end time.Time) time.Duration {
+	const week = 7 * 24 * time.Hour
+	var total time.Duration
+	if weeks := end.Sub(start) / week; weeks > 0 {
+		var perWeek time.Duration
+		for _, spans := range s {
+			for _, sp := range spans {
+				perWeek += sp.to - sp.from
+			}
+		}
+		total += time.Duration(weeks) * perWeek
+		start = start.Add(time.Duration(weeks) * week)
+	}
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		for _, sp := range s[day.Weekday()] {
+			from, to := day.Add(sp.from), day.Add(sp.to)
+			if from.Before(start) {
+				from = start
+			}
+			if to.After(end) {
+				to = end
+			}
+			if to.After(from) {
+				total += to.Sub(from)
+			}
+		}
+	}
+	return total
+}
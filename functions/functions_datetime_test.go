@@ -27,8 +27,8 @@ func TestDateTimeFunctions_TimeTimeInput(t *testing.T) {
 		{"dayofweek", NewDayOfWeekFunction(), []any{tm}, int(tm.Weekday())},
 		{"dayofyear", NewDayOfYearFunction(), []any{tm}, tm.YearDay()},
 		{"weekofyear", NewWeekOfYearFunction(), []any{tm}, isoWeek},
-		{"date_add days", NewDateAddFunction(), []any{tm, 1, "day"}, "2025-08-26 15:30:45"},
-		{"date_sub days", NewDateSubFunction(), []any{tm, 1, "day"}, "2025-08-24 15:30:45"},
+		{"date_add days", NewDateAddFunction(), []any{tm, 1, "day"}, tm.AddDate(0, 0, 1)},
+		{"date_sub days", NewDateSubFunction(), []any{tm, 1, "day"}, tm.AddDate(0, 0, -1)},
 		{"date_format", NewDateFormatFunction(), []any{tm, "YYYY-MM-DD"}, "2025-08-25"},
 		{"extract year", NewExtractFunction(), []any{"year", tm}, tm.Year()},
 		{"date_diff days", NewDateDiffFunction(), []any{tm, tm.AddDate(0, 0, -1), "day"}, int64(1)},
@@ -641,6 +641,7 @@ func TestDateTimeRegistration(t *testing.T) {
 	dateTimeFunctions := []string{
 		"date_format",
 		"date_add",
+		"date_trunc",
 		"date_sub",
 		"date_diff",
 		"date_parse",
@@ -675,6 +676,64 @@ func TestDateTimeRegistration(t *testing.T) {
 	}
 }
 
+// date_trunc/date_add/date_sub 按输入的表示形式（time.Time / Unix 毫秒 / 字符串）返回结果
+func TestDateTruncAndAdd(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tm := time.Date(2025, 8, 27, 15, 30, 45, 123, loc) // 周三
+	unix := time.Date(2025, 8, 27, 15, 30, 45, 0, time.UTC).UnixMilli()
+
+	cases := []struct {
+		name string
+		fn   Function
+		args []any
+		want any
+	}{
+		{"trunc second", NewDateTruncFunction(), []any{"second", tm}, time.Date(2025, 8, 27, 15, 30, 45, 0, loc)},
+		{"trunc minute", NewDateTruncFunction(), []any{"minute", tm}, time.Date(2025, 8, 27, 15, 30, 0, 0, loc)},
+		{"trunc hour", NewDateTruncFunction(), []any{"HOUR", tm}, time.Date(2025, 8, 27, 15, 0, 0, 0, loc)},
+		{"trunc day", NewDateTruncFunction(), []any{"day", tm}, time.Date(2025, 8, 27, 0, 0, 0, 0, loc)},
+		{"trunc week", NewDateTruncFunction(), []any{"week", tm}, time.Date(2025, 8, 25, 0, 0, 0, 0, loc)},
+		{"trunc month", NewDateTruncFunction(), []any{"month", tm}, time.Date(2025, 8, 1, 0, 0, 0, 0, loc)},
+		{"trunc unix hour", NewDateTruncFunction(), []any{"hour", unix}, time.Date(2025, 8, 27, 15, 0, 0, 0, time.UTC).UnixMilli()},
+		{"trunc unix float day", NewDateTruncFunction(), []any{"day", float64(unix)}, time.Date(2025, 8, 27, 0, 0, 0, 0, time.UTC).UnixMilli()},
+		{"trunc string", NewDateTruncFunction(), []any{"hour", "2025-08-27 15:30:45"}, "2025-08-27 15:00:00"},
+		{"add day", NewDateAddFunction(), []any{tm, 1, "day"}, tm.AddDate(0, 0, 1)},
+		{"add negative week", NewDateAddFunction(), []any{tm, -2, "week"}, tm.AddDate(0, 0, -14)},
+		{"add month", NewDateAddFunction(), []any{tm, 1, "month"}, tm.AddDate(0, 1, 0)},
+		{"add unix hour", NewDateAddFunction(), []any{unix, 2, "hour"}, unix + 7200*1000},
+		{"add unix negative minute", NewDateAddFunction(), []any{int(unix), -1, "minute"}, unix - 60*1000},
+		{"add string", NewDateAddFunction(), []any{"2025-08-27 15:30:45", 1, "week"}, "2025-09-03 15:30:45"},
+		{"add month clamps to month end", NewDateAddFunction(), []any{"2025-01-31 10:00:00", 1, "month"}, "2025-02-28 10:00:00"},
+		{"add month clamps leap year", NewDateAddFunction(), []any{"2024-01-31", 1, "month"}, "2024-02-29 00:00:00"},
+		{"add negative month clamps", NewDateAddFunction(), []any{"2025-03-31 00:00:00", -1, "months"}, "2025-02-28 00:00:00"},
+		{"add year from leap day", NewDateAddFunction(), []any{"2024-02-29 00:00:00", 1, "year"}, "2025-02-28 00:00:00"},
+		{"add month keeps day", NewDateAddFunction(), []any{"2025-01-15 00:00:00", 13, "month"}, "2026-02-15 00:00:00"},
+		{"sub week", NewDateSubFunction(), []any{tm, 1, "week"}, tm.AddDate(0, 0, -7)},
+		{"sub unix hour", NewDateSubFunction(), []any{unix, 2, "hour"}, unix - 7200*1000},
+		{"sub month clamps to month end", NewDateSubFunction(), []any{"2025-03-31 00:00:00", 1, "month"}, "2025-02-28 00:00:00"},
+		{"sub negative interval adds", NewDateSubFunction(), []any{"2025-08-27 15:30:45", -1, "day"}, "2025-08-28 15:30:45"},
+		{"add rfc3339", NewDateAddFunction(), []any{"2025-08-27T15:30:45+08:00", 1, "hour"}, "2025-08-27T16:30:45+08:00"},
+		{"trunc rfc3339", NewDateTruncFunction(), []any{"day", "2025-08-27T15:30:45.5Z"}, "2025-08-27T00:00:00Z"},
+	}
+	for _, c := range cases {
+		got, err := c.fn.Execute(nil, c.args)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v (%T), want %v (%T)", c.name, got, got, c.want, c.want)
+		}
+	}
+
+	if _, err := NewDateTruncFunction().Execute(nil, []any{"fortnight", tm}); err == nil {
+		t.Error("date_trunc with unsupported unit should fail")
+	}
+	if _, err := NewDateAddFunction().Execute(nil, []any{"not a date", 1, "day"}); err == nil {
+		t.Error("date_add with invalid date should fail")
+	}
+}
+
 func TestDateFormatConversion(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -703,3 +762,47 @@ func TestDateFormatConversion(t *testing.T) {
 		})
 	}
 }
+
+// business_duration 只累计营业时段内的时长：跨周末、非营业时间与多周跨度。
+// 2025-08-22 为周五，2025-08-25 为周一。
+func TestBusinessDurationFunction(t *testing.T) {
+	fn := NewBusinessDurationFunction()
+	const weekdays = "Mon-Fri 09:00-17:00"
+	hour := int64(3600)
+	tests := []struct {
+		name     string
+		start    any
+		end      any
+		schedule string
+		want     int64
+	}{
+		{"同日营业时段内", "2025-08-25 10:00:00", "2025-08-25 12:30:00", weekdays, 2*hour + hour/2},
+		{"跨越下班与次日上班", "2025-08-25 16:00:00", "2025-08-26 10:00:00", weekdays, 2 * hour},
+		{"全部在非营业时间", "2025-08-25 18:00:00", "2025-08-26 08:00:00", weekdays, 0},
+		{"跨周末", "2025-08-22 16:00:00", "2025-08-25 10:00:00", weekdays, 2 * hour},
+		{"完全落在周末", "2025-08-23 09:00:00", "2025-08-24 17:00:00", weekdays, 0},
+		{"两整周加半天", "2025-08-25 09:00:00", "2025-09-08 13:00:00", weekdays, 2*5*8*hour + 4*hour},
+		{"多段含周六", "2025-08-22 16:00:00", "2025-08-25 10:00:00", "Mon-Fri 09:00-17:00; Sat 10:00-14:00", 6 * hour},
+		{"跨周末的星期区间", "2025-08-22 00:00:00", "2025-08-26 00:00:00", "Sat-Sun 00:00-24:00", 48 * hour},
+		{"time.Time 与毫秒时间戳", time.Date(2025, 8, 25, 8, 0, 0, 0, time.UTC),
+			time.Date(2025, 8, 25, 9, 30, 0, 0, time.UTC).UnixMilli(), weekdays, hour / 2},
+		{"结束早于开始返回负值", "2025-08-26 10:00:00", "2025-08-25 16:00:00", weekdays, -2 * hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fn.Execute(nil, []any{tt.start, tt.end, tt.schedule})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "Mon-Fri", "Mon-Xyz 09:00-17:00", "Mon 17:00-09:00", "Mon 09:00-25:00"} {
+		if _, err := fn.Execute(nil, []any{"2025-08-25", "2025-08-26", bad}); err == nil {
+			t.Errorf("schedule %q: expected error", bad)
+		}
+	}
+}
@@ -5,7 +5,14 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"strings"
 )
 
 // Md5Function calculates MD5 hash value
@@ -107,3 +114,127 @@ func (f *Sha512Function) Execute(ctx *FunctionContext, args []any) (any, error)
 	hash := sha512.Sum512([]byte(str))
 	return fmt.Sprintf("%x", hash), nil
 }
+
+// hashAlgorithms maps hash() algorithm names to their constructors
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// canonicalHashBytes serializes a value for hashing so that equal values hash
+// equally regardless of their Go type: strings and []byte as-is, integers in
+// decimal, integral floats as integers (42.0 -> "42", matching JSON-decoded
+// numbers), other floats in shortest form, booleans as true/false, and
+// anything else as JSON (map keys sorted).
+func canonicalHashBytes(v any) ([]byte, error) {
+	switch x := v.(type) {
+	case string:
+		return []byte(x), nil
+	case []byte:
+		return x, nil
+	case bool:
+		return []byte(strconv.FormatBool(x)), nil
+	case int:
+		return []byte(strconv.FormatInt(int64(x), 10)), nil
+	case int8:
+		return []byte(strconv.FormatInt(int64(x), 10)), nil
+	case int16:
+		return []byte(strconv.FormatInt(int64(x), 10)), nil
+	case int32:
+		return []byte(strconv.FormatInt(int64(x), 10)), nil
+	case int64:
+		return []byte(strconv.FormatInt(x, 10)), nil
+	case uint:
+		return []byte(strconv.FormatUint(uint64(x), 10)), nil
+	case uint8:
+		return []byte(strconv.FormatUint(uint64(x), 10)), nil
+	case uint16:
+		return []byte(strconv.FormatUint(uint64(x), 10)), nil
+	case uint32:
+		return []byte(strconv.FormatUint(uint64(x), 10)), nil
+	case uint64:
+		return []byte(strconv.FormatUint(x, 10)), nil
+	case float32:
+		return canonicalFloat(float64(x)), nil
+	case float64:
+		return canonicalFloat(x), nil
+	default:
+		return json.Marshal(x)
+	}
+}
+
+func canonicalFloat(f float64) []byte {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return []byte(strconv.FormatInt(int64(f), 10))
+	}
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// HashFunction hashes any value with a selectable algorithm and returns the hex digest
+type HashFunction struct {
+	*BaseFunction
+}
+
+func NewHashFunction() *HashFunction {
+	return &HashFunction{
+		BaseFunction: NewBaseFunction("hash", TypeString, "hash", "Calculate hex digest of a value (md5/sha1/sha256/sha512, default sha256)", 1, 2),
+	}
+}
+
+func (f *HashFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *HashFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	algorithm := "sha256"
+	if len(args) > 1 {
+		name, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("hash algorithm must be a string")
+		}
+		algorithm = strings.ToLower(name)
+	}
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+	data, err := canonicalHashBytes(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("hash: %w", err)
+	}
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Crc32Function calculates the CRC-32 (IEEE) checksum of a value as uint32,
+// e.g. for sampling by hash: crc32(device_id) % 10 = 0
+type Crc32Function struct {
+	*BaseFunction
+}
+
+func NewCrc32Function() *Crc32Function {
+	return &Crc32Function{
+		BaseFunction: NewBaseFunction("crc32", TypeConversion, "hash", "Calculate CRC-32 checksum of a value", 1, 1),
+	}
+}
+
+func (f *Crc32Function) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *Crc32Function) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	data, err := canonicalHashBytes(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("crc32: %w", err)
+	}
+	return crc32.ChecksumIEEE(data), nil
+}
@@ -169,3 +169,52 @@ func TestHashFunctionErrors(t *testing.T) {
 		})
 	}
 }
+
+// TestHashAndCrc32Functions 测试 hash/crc32 对字符串、数值与其他类型的规范化摘要
+func TestHashAndCrc32Functions(t *testing.T) {
+	hashFn := NewHashFunction()
+	crcFn := NewCrc32Function()
+	tests := []struct {
+		name   string
+		args   []any
+		digest string
+		crc    uint32
+	}{
+		{"字符串默认sha256", []any{"hello"}, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", 907060870},
+		{"md5", []any{"hello", "MD5"}, "5d41402abc4b2a76b9719d911017c592", 907060870},
+		{"整数", []any{42, "sha256"}, "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049", 841265288},
+		{"整值浮点与整数一致", []any{42.0}, "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049", 841265288},
+		{"int64", []any{int64(42), "md5"}, "a1d0c6e83f027327d8461063f4ac58a6", 841265288},
+		{"小数", []any{3.5}, "8a199b120cf400d69c300e1dd6eacdc8b0002353e3044bbab21e0125b32c9fd6", 2228768724},
+		{"布尔", []any{true, "md5"}, "b326b5062b2f0e69046810717534cb09", 4261170317},
+		{"map按JSON键序", []any{map[string]any{"b": 2, "a": 1}}, "43258cff783fe7036d8a43033f830adfc60ec037382473548ac742b888292777", 3477196419},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hashFn.Execute(&FunctionContext{}, tt.args)
+			if err != nil || got != tt.digest {
+				t.Errorf("hash(%v) = %v, %v; want %s", tt.args, got, err, tt.digest)
+			}
+			got, err = crcFn.Execute(&FunctionContext{}, tt.args[:1])
+			if err != nil || got != tt.crc {
+				t.Errorf("crc32(%v) = %v, %v; want %d", tt.args[0], got, err, tt.crc)
+			}
+		})
+	}
+
+	if got, err := hashFn.Execute(&FunctionContext{}, []any{nil}); err != nil || got != nil {
+		t.Errorf("hash(NULL) = %v, %v; want nil", got, err)
+	}
+	if got, err := crcFn.Execute(&FunctionContext{}, []any{nil}); err != nil || got != nil {
+		t.Errorf("crc32(NULL) = %v, %v; want nil", got, err)
+	}
+	if _, err := hashFn.Execute(&FunctionContext{}, []any{"x", "sha3"}); err == nil {
+		t.Errorf("unsupported algorithm should fail")
+	}
+	if fn, ok := Get("crc32"); !ok || fn.GetType() != TypeConversion {
+		t.Errorf("crc32 should be registered as a conversion function")
+	}
+	if fn, ok := Get("hash"); !ok || fn.GetType() != TypeString {
+		t.Errorf("hash should be registered as a string function")
+	}
+}
@@ -733,3 +733,82 @@ func (f *PowerFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
 	}
 	return result, nil
 }
+
+// geohashBase32 是 geohash 使用的 base32 字母表（不含 a、i、l、o）
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashFunction 把经纬度编码为 geohash 字符串，可用于 GROUP BY 做空间分桶
+type GeohashFunction struct {
+	*BaseFunction
+}
+
+func NewGeohashFunction() *GeohashFunction {
+	return &GeohashFunction{
+		BaseFunction: NewBaseFunction("geohash", TypeMath, "数学函数", "把纬度、经度编码为指定精度（1-12，默认12）的geohash", 2, 3),
+	}
+}
+
+func (f *GeohashFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *GeohashFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+	lat, err := cast.ToFloat64E(args[0])
+	if err != nil {
+		return nil, err
+	}
+	lon, err := cast.ToFloat64E(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("geohash latitude must be between -90 and 90, got %v", lat)
+	}
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("geohash longitude must be between -180 and 180, got %v", lon)
+	}
+	precision := 12
+	if len(args) == 3 {
+		if precision, err = cast.ToIntE(args[2]); err != nil {
+			return nil, err
+		}
+		if precision < 1 || precision > 12 {
+			return nil, fmt.Errorf("geohash precision must be between 1 and 12, got %d", precision)
+		}
+	}
+	return encodeGeohash(lat, lon, precision), nil
+}
+
+// encodeGeohash 交替二分经度与纬度区间（经度在先），每 5 位输出一个 base32 字符
+func encodeGeohash(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	even := true
+	bit, ch := 0, 0
+	for len(hash) < precision {
+		rng, v := &latRange, lat
+		if even {
+			rng, v = &lonRange, lon
+		}
+		mid := (rng[0] + rng[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			rng[0] = mid
+		} else {
+			rng[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
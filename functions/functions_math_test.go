@@ -756,3 +756,46 @@ func TestMathFunctionEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestGeohashFunction 测试geohash编码的已知值与参数校验
+func TestGeohashFunction(t *testing.T) {
+	fn := NewGeohashFunction()
+	tests := []struct {
+		name     string
+		args     []any
+		expected any
+		wantErr  bool
+	}{
+		{"jutland precision 11", []any{57.64911, 10.40744, 11}, "u4pruydqqvj", false},
+		{"spain precision 5", []any{42.6, -5.6, 5}, "ezs42", false},
+		{"beijing precision 8", []any{39.92324, 116.3906, 8}, "wx4g0ec1", false},
+		{"curitiba default precision", []any{-25.382708, -49.265506}, "6gkzwgjzn820", false},
+		{"numeric strings", []any{"42.6", "-5.6", "5"}, "ezs42", false},
+		{"north-east corner", []any{90, 180, 6}, "zzzzzz", false},
+		{"south-west corner", []any{-90, -180, 6}, "000000", false},
+		{"nil coordinate", []any{nil, 10.0, 5}, nil, false},
+		{"latitude out of range", []any{90.5, 0, 5}, nil, true},
+		{"longitude out of range", []any{0, -180.1, 5}, nil, true},
+		{"precision too small", []any{0, 0, 0}, nil, true},
+		{"precision too large", []any{0, 0, 13}, nil, true},
+		{"non-numeric latitude", []any{"north", 0, 5}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := fn.Validate(tt.args); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			result, err := fn.Execute(&FunctionContext{}, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result != tt.expected {
+				t.Errorf("Execute() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	if err := fn.Validate([]any{1.0}); err == nil {
+		t.Error("Validate() should reject a single argument")
+	}
+}
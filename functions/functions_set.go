@@ -0,0 +1,73 @@
+package functions
+
+import (
+	"sync"
+
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// setRegistry 保存具名字符串集合，供 in_set 在运行时做 O(1) 成员判断。
+// 更新以整体替换的方式进行，读取方拿到的集合不会被并发修改。
+type setRegistry struct {
+	mu   sync.RWMutex
+	sets map[string]map[string]struct{}
+}
+
+var globalSets = &setRegistry{sets: make(map[string]map[string]struct{})}
+
+// RegisterSet 注册或整体替换名为 name 的集合。可在查询运行期间调用，
+// 之后处理的数据立即按新集合判断，无需重新解析 SQL。集合为进程级全局共享。
+func RegisterSet(name string, values []string) {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	globalSets.mu.Lock()
+	globalSets.sets[name] = set
+	globalSets.mu.Unlock()
+}
+
+// UnregisterSet 删除名为 name 的集合，返回它是否存在。
+func UnregisterSet(name string) bool {
+	globalSets.mu.Lock()
+	defer globalSets.mu.Unlock()
+	_, ok := globalSets.sets[name]
+	delete(globalSets.sets, name)
+	return ok
+}
+
+// SetContains 判断 value 是否在名为 name 的集合中；未注册的集合视为空集。
+func SetContains(name, value string) bool {
+	globalSets.mu.RLock()
+	set := globalSets.sets[name]
+	globalSets.mu.RUnlock()
+	_, ok := set[value]
+	return ok
+}
+
+// InSetFunction 判断值是否属于已注册的集合：in_set('blocklist', deviceId)
+type InSetFunction struct {
+	*BaseFunction
+}
+
+func NewInSetFunction() *InSetFunction {
+	return &InSetFunction{
+		BaseFunction: NewBaseFunction("in_set", TypeString, "conditional", "Check if value is a member of a registered set", 2, 2),
+	}
+}
+
+func (f *InSetFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *InSetFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	name, err := cast.ToStringE(args[0])
+	if err != nil {
+		return nil, err
+	}
+	// NULL 不属于任何集合
+	if args[1] == nil {
+		return false, nil
+	}
+	return SetContains(name, cast.ToString(args[1])), nil
+}
@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInSetFunction 测试 in_set 成员判断及集合的运行时替换
+func TestInSetFunction(t *testing.T) {
+	fn, ok := Get("in_set")
+	require.True(t, ok)
+	defer UnregisterSet("test_in_set")
+
+	call := func(args ...any) any {
+		require.NoError(t, fn.Validate(args))
+		result, err := fn.Execute(&FunctionContext{}, args)
+		require.NoError(t, err)
+		return result
+	}
+
+	// 未注册的集合视为空集
+	assert.Equal(t, false, call("test_in_set", "dev1"))
+
+	RegisterSet("test_in_set", []string{"dev1", "42"})
+	assert.Equal(t, true, call("test_in_set", "dev1"))
+	assert.Equal(t, true, call("test_in_set", 42), "非字符串值按字符串比较")
+	assert.Equal(t, false, call("test_in_set", "dev2"))
+	assert.Equal(t, false, call("test_in_set", nil))
+
+	// 整体替换
+	RegisterSet("test_in_set", []string{"dev2"})
+	assert.Equal(t, false, call("test_in_set", "dev1"))
+	assert.Equal(t, true, call("test_in_set", "dev2"))
+
+	assert.True(t, UnregisterSet("test_in_set"))
+	assert.False(t, UnregisterSet("test_in_set"))
+	assert.Equal(t, false, call("test_in_set", "dev2"))
+
+	assert.Error(t, fn.Validate([]any{"test_in_set"}))
+}
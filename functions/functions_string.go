@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rulego/streamsql/utils/cast"
 )
@@ -559,6 +561,41 @@ func (f *RtrimFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
 	}), nil
 }
 
+// maxRegexpCacheSize 限制正则缓存的条目数：模式可能来自数据列，超出后新模式按次编译、不再缓存。
+const maxRegexpCacheSize = 1024
+
+var (
+	// regexpCache 缓存 regexp_* 函数编译过的模式（含编译错误），流上反复求值同一模式时只编译一次
+	regexpCache     sync.Map // pattern -> *regexpCacheEntry
+	regexpCacheSize int64
+)
+
+type regexpCacheEntry struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compileRegexpCached 编译并缓存 pattern。非法模式返回带函数名与模式的错误，而不是 panic。
+func compileRegexpCached(funcName, pattern string) (*regexp.Regexp, error) {
+	if v, ok := regexpCache.Load(pattern); ok {
+		e := v.(*regexpCacheEntry)
+		if e.err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %v", funcName, pattern, e.err)
+		}
+		return e.re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if atomic.LoadInt64(&regexpCacheSize) < maxRegexpCacheSize {
+		if _, loaded := regexpCache.LoadOrStore(pattern, &regexpCacheEntry{re: re, err: err}); !loaded {
+			atomic.AddInt64(&regexpCacheSize, 1)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid pattern %q: %v", funcName, pattern, err)
+	}
+	return re, nil
+}
+
 // RegexpMatchesFunction 正则表达式匹配
 type RegexpMatchesFunction struct {
 	*BaseFunction
@@ -584,11 +621,11 @@ func (f *RegexpMatchesFunction) Execute(ctx *FunctionContext, args []any) (any,
 		return nil, err
 	}
 
-	matched, err := regexp.MatchString(pattern, str)
+	re, err := compileRegexpCached(f.GetName(), pattern)
 	if err != nil {
 		return nil, err
 	}
-	return matched, nil
+	return re.MatchString(str), nil
 }
 
 // RegexpReplaceFunction 正则表达式替换
@@ -620,7 +657,7 @@ func (f *RegexpReplaceFunction) Execute(ctx *FunctionContext, args []any) (any,
 		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexpCached(f.GetName(), pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -652,7 +689,7 @@ func (f *RegexpSubstringFunction) Execute(ctx *FunctionContext, args []any) (any
 		return nil, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegexpCached(f.GetName(), pattern)
 	if err != nil {
 		return nil, err
 	}
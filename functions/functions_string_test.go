@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -414,3 +415,26 @@ func TestStringFunctionEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestRegexpPatternCache 同一模式只编译一次；非法模式（含缓存命中时）返回带模式的错误
+func TestRegexpPatternCache(t *testing.T) {
+	re1, err := compileRegexpCached("regexp_matches", `^dev-\d+$`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	re2, err := compileRegexpCached("regexp_replace", `^dev-\d+$`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if re1 != re2 {
+		t.Error("expected the cached *regexp.Regexp to be reused")
+	}
+
+	fn := NewRegexpMatchesFunction()
+	for i := 0; i < 2; i++ {
+		_, err := fn.Execute(&FunctionContext{}, []any{"abc", "(unclosed"})
+		if err == nil || !strings.Contains(err.Error(), `regexp_matches: invalid pattern "(unclosed"`) {
+			t.Errorf("call %d: unexpected error %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package functions
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestConvertUnitFunction 测试单位换算函数
+func TestConvertUnitFunction(t *testing.T) {
+	fn, exists := Get("convert")
+	if !exists {
+		t.Fatal("Function convert not found")
+	}
+	if fn.GetType() != TypeConversion {
+		t.Errorf("convert type = %v, want %v", fn.GetType(), TypeConversion)
+	}
+
+	tests := []struct {
+		name     string
+		args     []any
+		expected float64
+	}{
+		{"F to C", []any{212, "F", "C"}, 100},
+		{"C to F", []any{-40.0, "C", "F"}, -40},
+		{"C to K", []any{25, "c", "k"}, 298.15},
+		{"K to F", []any{0, "K", "F"}, -459.67},
+		{"km to mi", []any{42.195, "km", "mi"}, 26.218757},
+		{"ft to m", []any{10, "ft", "m"}, 3.048},
+		{"in to cm", []any{1, "in", "cm"}, 2.54},
+		{"bar to psi", []any{1, "bar", "psi"}, 14.503774},
+		{"atm to kPa", []any{1, "atm", "kPa"}, 101.325},
+		{"hPa to mmHg", []any{1013.25, "hPa", "mmHg"}, 760},
+		{"same unit", []any{"3.5", "m", "m"}, 3.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Execute(&FunctionContext{}, tt.args)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			got, ok := result.(float64)
+			if !ok {
+				t.Fatalf("Execute() = %T, want float64", result)
+			}
+			if math.Abs(got-tt.expected) > 1e-6 {
+				t.Errorf("Execute() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("NULL input", func(t *testing.T) {
+		result, err := fn.Execute(&FunctionContext{}, []any{nil, "F", "C"})
+		if err != nil || result != nil {
+			t.Errorf("Execute() = %v, %v, want nil, nil", result, err)
+		}
+	})
+
+	errTests := []struct {
+		name string
+		args []any
+	}{
+		{"cross dimension", []any{1, "m", "C"}},
+		{"unknown unit", []any{1, "furlong", "m"}},
+		{"non-numeric value", []any{"hot", "F", "C"}},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fn.Execute(&FunctionContext{}, tt.args)
+			if err == nil {
+				t.Fatal("Execute() expected error")
+			}
+			if !strings.Contains(err.Error(), "convert") {
+				t.Errorf("error %q should mention convert", err)
+			}
+		})
+	}
+
+	if err := fn.Validate([]any{1, "F"}); err == nil {
+		t.Error("Validate() expected error for 2 args")
+	}
+}
@@ -2,6 +2,7 @@ package functions
 
 import (
 	"fmt"
+	"time"
 )
 
 // WindowStartFunction returns window start time
@@ -104,6 +105,185 @@ func (f *WindowEndFunction) Clone() AggregatorFunction {
 	}
 }
 
+// WindowWatermarkFunction returns the watermark that fired the window: the
+// event-time watermark for EventTime windows, the trigger wall-clock time for
+// ProcessingTime windows (unix nanoseconds, like window_start/window_end).
+type WindowWatermarkFunction struct {
+	*BaseFunction
+	watermark any
+}
+
+func NewWindowWatermarkFunction() *WindowWatermarkFunction {
+	return &WindowWatermarkFunction{
+		BaseFunction: NewBaseFunction("window_watermark", TypeWindow, "窗口函数", "返回触发窗口的水位线", 0, 0),
+	}
+}
+
+func (f *WindowWatermarkFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *WindowWatermarkFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if ctx.WindowInfo != nil {
+		return ctx.WindowInfo.Watermark, nil
+	}
+	return f.watermark, nil
+}
+
+// 实现AggregatorFunction接口
+func (f *WindowWatermarkFunction) New() AggregatorFunction {
+	return &WindowWatermarkFunction{
+		BaseFunction: f.BaseFunction,
+	}
+}
+
+func (f *WindowWatermarkFunction) Add(value any) {
+	// 同一窗口的所有行携带相同的水位线，保留最新值即可
+	f.watermark = value
+}
+
+func (f *WindowWatermarkFunction) Result() any {
+	return f.watermark
+}
+
+func (f *WindowWatermarkFunction) Reset() {
+	f.watermark = nil
+}
+
+func (f *WindowWatermarkFunction) Clone() AggregatorFunction {
+	return &WindowWatermarkFunction{
+		BaseFunction: f.BaseFunction,
+		watermark:    f.watermark,
+	}
+}
+
+// WindowFillRatioFunction returns how full the window was when it fired, in
+// [0,1]: rows/count for counting windows, elapsed time/size for tumbling and
+// sliding windows. NULL for session and global windows, which have no
+// expected size.
+type WindowFillRatioFunction struct {
+	*BaseFunction
+	ratio any
+}
+
+func NewWindowFillRatioFunction() *WindowFillRatioFunction {
+	return &WindowFillRatioFunction{
+		BaseFunction: NewBaseFunction("window_fill_ratio", TypeWindow, "窗口函数", "返回窗口触发时的填充比例", 0, 0),
+	}
+}
+
+func (f *WindowFillRatioFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+func (f *WindowFillRatioFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	return f.ratio, nil
+}
+
+// 实现AggregatorFunction接口
+func (f *WindowFillRatioFunction) New() AggregatorFunction {
+	return &WindowFillRatioFunction{
+		BaseFunction: f.BaseFunction,
+	}
+}
+
+func (f *WindowFillRatioFunction) Add(value any) {
+	// 同一窗口的所有行携带相同的填充比例，保留最新值即可
+	f.ratio = value
+}
+
+func (f *WindowFillRatioFunction) Result() any {
+	return f.ratio
+}
+
+func (f *WindowFillRatioFunction) Reset() {
+	f.ratio = nil
+}
+
+func (f *WindowFillRatioFunction) Clone() AggregatorFunction {
+	return &WindowFillRatioFunction{
+		BaseFunction: f.BaseFunction,
+		ratio:        f.ratio,
+	}
+}
+
+// FormatWindowISO formats a window bound (unix nanoseconds) as an RFC3339
+// string in loc (UTC when nil), with fractional seconds only when non-zero.
+func FormatWindowISO(ns int64, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Unix(0, ns).In(loc).Format(time.RFC3339Nano)
+}
+
+// WindowISOFunction returns the window start (window_start_iso) or end
+// (window_end_iso) as an RFC3339 string. The stream formats the bounds in the
+// configured time zone (Config.TimeZone) and passes them through the window
+// context, like window_start/window_end.
+type WindowISOFunction struct {
+	*BaseFunction
+	end   bool
+	bound any
+}
+
+func NewWindowStartISOFunction() *WindowISOFunction {
+	return &WindowISOFunction{
+		BaseFunction: NewBaseFunction("window_start_iso", TypeWindow, "窗口函数", "以RFC3339字符串返回窗口开始时间", 0, 0),
+	}
+}
+
+func NewWindowEndISOFunction() *WindowISOFunction {
+	return &WindowISOFunction{
+		BaseFunction: NewBaseFunction("window_end_iso", TypeWindow, "窗口函数", "以RFC3339字符串返回窗口结束时间", 0, 0),
+		end:          true,
+	}
+}
+
+func (f *WindowISOFunction) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute formats the bounds of ctx.WindowInfo in UTC; WindowInfo carries no
+// time zone.
+func (f *WindowISOFunction) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if ctx != nil && ctx.WindowInfo != nil {
+		if f.end {
+			return FormatWindowISO(ctx.WindowInfo.WindowEnd, nil), nil
+		}
+		return FormatWindowISO(ctx.WindowInfo.WindowStart, nil), nil
+	}
+	return f.bound, nil
+}
+
+// 实现AggregatorFunction接口
+func (f *WindowISOFunction) New() AggregatorFunction {
+	return &WindowISOFunction{
+		BaseFunction: f.BaseFunction,
+		end:          f.end,
+	}
+}
+
+func (f *WindowISOFunction) Add(value any) {
+	// 同一窗口的所有行携带相同的边界，保留最新值即可
+	f.bound = value
+}
+
+func (f *WindowISOFunction) Result() any {
+	return f.bound
+}
+
+func (f *WindowISOFunction) Reset() {
+	f.bound = nil
+}
+
+func (f *WindowISOFunction) Clone() AggregatorFunction {
+	return &WindowISOFunction{
+		BaseFunction: f.BaseFunction,
+		end:          f.end,
+		bound:        f.bound,
+	}
+}
+
 // ExpressionFunction 表达式函数，用于处理自定义表达式
 type ExpressionFunction struct {
 	*BaseFunction
@@ -3,6 +3,7 @@ package functions
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 // isWindowFunction 判断是否为窗口函数
@@ -263,6 +264,39 @@ func TestWindowFunctionResetAndClone(t *testing.T) {
 	}
 }
 
+func TestWindowWatermarkFunction(t *testing.T) {
+	fn := NewWindowWatermarkFunction()
+	if fn.GetType() != TypeWindow {
+		t.Fatalf("type = %v, want %v", fn.GetType(), TypeWindow)
+	}
+	if err := fn.Validate([]any{1}); err == nil {
+		t.Error("window_watermark() takes no arguments")
+	}
+
+	got, err := fn.Execute(&FunctionContext{WindowInfo: &WindowInfo{WindowStart: 1, WindowEnd: 2, Watermark: 3}}, nil)
+	if err != nil || got != int64(3) {
+		t.Errorf("Execute() = %v, %v; want 3", got, err)
+	}
+
+	// 作为聚合器时从窗口上下文接收水位线
+	agg := fn.New().(*WindowWatermarkFunction)
+	agg.Add(int64(10))
+	agg.Add(int64(12))
+	if agg.Result() != int64(12) {
+		t.Errorf("Result() = %v, want 12", agg.Result())
+	}
+	clone := agg.Clone().(*WindowWatermarkFunction)
+	agg.Reset()
+	if agg.Result() != nil || clone.Result() != int64(12) {
+		t.Errorf("Reset/Clone: result %v, clone %v", agg.Result(), clone.Result())
+	}
+
+	wrapper := CreateLegacyAggregator(WindowWatermark)
+	if ca, ok := wrapper.(ContextAggregator); !ok || ca.GetContextKey() != WindowWatermarkStr {
+		t.Errorf("window_watermark should read the %q context key", WindowWatermarkStr)
+	}
+}
+
 func TestExpressionFunction(t *testing.T) {
 	fn := NewExpressionFunction()
 	ctx := &FunctionContext{}
@@ -428,3 +462,39 @@ func TestWindowFunctionEdgeCases(t *testing.T) {
 	we.Reset()
 	_ = we.Clone()
 }
+
+func TestWindowISOFunction(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(1500 * time.Millisecond)
+	info := &WindowInfo{WindowStart: start.UnixNano(), WindowEnd: end.UnixNano()}
+
+	got, err := NewWindowStartISOFunction().Execute(&FunctionContext{WindowInfo: info}, nil)
+	if err != nil || got != "2024-01-02T03:04:05Z" {
+		t.Errorf("window_start_iso Execute() = %v, %v", got, err)
+	}
+	got, err = NewWindowEndISOFunction().Execute(&FunctionContext{WindowInfo: info}, nil)
+	if err != nil || got != "2024-01-02T03:04:06.5Z" {
+		t.Errorf("window_end_iso Execute() = %v, %v", got, err)
+	}
+
+	shanghai := time.FixedZone("CST", 8*3600)
+	if s := FormatWindowISO(start.UnixNano(), shanghai); s != "2024-01-02T11:04:05+08:00" {
+		t.Errorf("FormatWindowISO = %q", s)
+	}
+
+	agg := NewWindowEndISOFunction().New().(*WindowISOFunction)
+	if !agg.end {
+		t.Error("New() should keep the end flag")
+	}
+	agg.Add("2024-01-02T03:04:06Z")
+	clone := agg.Clone()
+	agg.Reset()
+	if agg.Result() != nil || clone.Result() != "2024-01-02T03:04:06Z" {
+		t.Errorf("Reset/Clone: result %v, clone %v", agg.Result(), clone.Result())
+	}
+
+	wrapper := CreateLegacyAggregator(WindowStartISO)
+	if ca, ok := wrapper.(ContextAggregator); !ok || ca.GetContextKey() != WindowStartISOStr {
+		t.Errorf("window_start_iso should read the %q context key", WindowStartISOStr)
+	}
+}
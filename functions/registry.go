@@ -43,6 +43,8 @@ type WindowInfo struct {
 	WindowStart int64
 	WindowEnd   int64
 	RowCount    int
+	// Watermark that fired the window (unix nanoseconds)
+	Watermark int64
 }
 
 // Function defines the interface for all functions
@@ -68,6 +70,18 @@ type Function interface {
 	GetMaxArgs() int
 }
 
+// LazyFunction is implemented by functions that evaluate their arguments on
+// demand (COALESCE, NULLIF). Evaluators hand each argument over as a LazyArg,
+// so an argument the function does not need is never evaluated and cannot
+// fail the call.
+type LazyFunction interface {
+	ExecuteLazy(ctx *FunctionContext, args []LazyArg) (any, error)
+}
+
+// LazyArg evaluates one argument of a LazyFunction for the current row; a
+// missing field evaluates to nil.
+type LazyArg func() (any, error)
+
 // FunctionRegistry manages function registration and retrieval
 type FunctionRegistry struct {
 	mu         sync.RWMutex
@@ -0,0 +1,88 @@
+package functions
+
+// AggregatorState is the per-group, per-window state of an aggregate built with
+// NewStatefulAggregator. The engine only ever touches a state from one goroutine
+// at a time, so implementations need no locking.
+type AggregatorState interface {
+	// Add folds one input value into the state
+	Add(value any)
+	// Result returns the aggregate of the values added so far
+	Result() any
+	// Clone returns an independent deep copy (no shared maps or slices)
+	Clone() AggregatorState
+}
+
+// StatefulAggregator is an AggregatorFunction whose state lives entirely in an
+// AggregatorState created by a factory. Because New and Reset always call the
+// factory, every group and window starts from a fresh state and nothing can
+// leak between groups, which is the easiest way to satisfy the AggregatorFunction
+// contract for custom aggregates:
+//
+//	functions.Register(functions.NewStatefulAggregator("mode_agg", "统计聚合", "计算众数", 1, 1,
+//		func() functions.AggregatorState { return &modeState{counts: map[string]int{}} }))
+type StatefulAggregator struct {
+	*BaseFunction
+	newState func() AggregatorState
+	state    AggregatorState
+}
+
+// NewStatefulAggregator creates a TypeAggregation function named name whose
+// per-group state is produced by newState.
+func NewStatefulAggregator(name, category, description string, minArgs, maxArgs int, newState func() AggregatorState) *StatefulAggregator {
+	return &StatefulAggregator{
+		BaseFunction: NewBaseFunction(name, TypeAggregation, category, description, minArgs, maxArgs),
+		newState:     newState,
+		state:        newState(),
+	}
+}
+
+func (f *StatefulAggregator) Validate(args []any) error {
+	return f.ValidateArgCount(args)
+}
+
+// Execute aggregates args (or the elements of an array first argument) with a
+// fresh state, so concurrent calls on the registered instance are safe.
+func (f *StatefulAggregator) Execute(ctx *FunctionContext, args []any) (any, error) {
+	if err := f.Validate(args); err != nil {
+		return nil, err
+	}
+	state := f.newState()
+	if arr, ok := args[0].([]any); ok && len(args) == 1 {
+		for _, v := range arr {
+			state.Add(v)
+		}
+	} else {
+		for _, v := range args {
+			state.Add(v)
+		}
+	}
+	return state.Result(), nil
+}
+
+func (f *StatefulAggregator) New() AggregatorFunction {
+	return &StatefulAggregator{
+		BaseFunction: f.BaseFunction,
+		newState:     f.newState,
+		state:        f.newState(),
+	}
+}
+
+func (f *StatefulAggregator) Add(value any) {
+	f.state.Add(value)
+}
+
+func (f *StatefulAggregator) Result() any {
+	return f.state.Result()
+}
+
+func (f *StatefulAggregator) Reset() {
+	f.state = f.newState()
+}
+
+func (f *StatefulAggregator) Clone() AggregatorFunction {
+	return &StatefulAggregator{
+		BaseFunction: f.BaseFunction,
+		newState:     f.newState,
+		state:        f.state.Clone(),
+	}
+}
@@ -0,0 +1,65 @@
+package functions
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seenState 记录收到的全部值，便于检查状态是否在实例间泄漏
+type seenState struct {
+	seen []string
+}
+
+func (s *seenState) Add(value any) { s.seen = append(s.seen, value.(string)) }
+
+func (s *seenState) Result() any {
+	sorted := append([]string(nil), s.seen...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func (s *seenState) Clone() AggregatorState {
+	return &seenState{seen: append([]string(nil), s.seen...)}
+}
+
+func newSeenAggregator() *StatefulAggregator {
+	return NewStatefulAggregator("seen_test", "测试", "记录收到的值", 1, -1,
+		func() AggregatorState { return &seenState{} })
+}
+
+// TestStatefulAggregator_Isolation New/Reset/Clone 得到的实例互不共享状态
+func TestStatefulAggregator_Isolation(t *testing.T) {
+	proto := newSeenAggregator()
+	assert.Equal(t, TypeAggregation, proto.GetType())
+
+	a := proto.New()
+	b := proto.New()
+	a.Add("a1")
+	a.Add("a2")
+	b.Add("b1")
+	assert.Equal(t, "a1,a2", a.Result())
+	assert.Equal(t, "b1", b.Result())
+	assert.Equal(t, "", proto.Result(), "原型不应收到分组的值")
+
+	clone := a.Clone()
+	clone.Add("c1")
+	assert.Equal(t, "a1,a2,c1", clone.Result())
+	assert.Equal(t, "a1,a2", a.Result())
+
+	a.Reset()
+	assert.Equal(t, "", a.Result())
+	assert.Equal(t, "a1,a2,c1", clone.Result())
+
+	// Execute 每次使用独立状态，可在原型上并发调用
+	got, err := proto.Execute(&FunctionContext{}, []any{"x", "y"})
+	require.NoError(t, err)
+	assert.Equal(t, "x,y", got)
+	got, err = proto.Execute(&FunctionContext{}, []any{[]any{"z"}})
+	require.NoError(t, err)
+	assert.Equal(t, "z", got)
+	assert.Equal(t, "", proto.Result())
+}
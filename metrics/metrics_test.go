@@ -17,6 +17,7 @@
 package metrics
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -154,3 +155,63 @@ func TestHistogramConcurrent(t *testing.T) {
 	assert.GreaterOrEqual(t, snap.Min, time.Duration(0))
 	assert.LessOrEqual(t, snap.Max, 49*time.Microsecond)
 }
+
+func TestCardinality(t *testing.T) {
+	c := NewCardinality("keys")
+	assert.Equal(t, "keys", c.Name())
+	assert.Equal(t, int64(0), c.Estimate())
+
+	// 重复值不增加估计；小基数走线性计数，几乎精确
+	for r := 0; r < 3; r++ {
+		for i := 0; i < 100; i++ {
+			c.Add(i)
+		}
+	}
+	assert.InDelta(t, 100, c.Estimate(), 3)
+
+	for i := 0; i < 50000; i++ {
+		c.AddString(fmt.Sprintf("device-%d", i))
+	}
+	assert.InEpsilon(t, 50100, float64(c.Estimate()), 0.05)
+	assert.Equal(t, c.Estimate(), c.SnapshotValue())
+
+	c.Reset()
+	assert.Equal(t, int64(0), c.Estimate())
+}
+
+// 哈希固定：同样的输入在不同实例（不同进程）得到同样的估计，草图可合并、可克隆。
+func TestCardinalityDeterministicMerge(t *testing.T) {
+	a, b := NewCardinality("a"), NewCardinality("b")
+	for i := 0; i < 30; i++ {
+		a.Add(i)
+		b.Add(i)
+	}
+	assert.Equal(t, int64(30), a.Estimate())
+	assert.Equal(t, a.regs, b.regs)
+
+	c := NewCardinality("c")
+	for i := 30; i < 60; i++ {
+		c.Add(i)
+	}
+	clone := a.Clone()
+	a.Merge(c)
+	assert.Equal(t, int64(60), a.Estimate())
+	assert.Equal(t, int64(30), clone.Estimate(), "克隆与原实例互不影响")
+	assert.Equal(t, "a", clone.Name())
+}
+
+func TestCardinalityConcurrent(t *testing.T) {
+	c := NewCardinality("keys")
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				c.Add(g*2000 + i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.InEpsilon(t, 16000, float64(c.Estimate()), 0.05)
+}
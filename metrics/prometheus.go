@@ -0,0 +1,287 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PrometheusContentType is the Content-Type of the text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Prometheus metric types.
+const (
+	PrometheusCounter   = "counter"
+	PrometheusGauge     = "gauge"
+	PrometheusHistogram = "histogram"
+)
+
+// Label is a Prometheus label pair attached to every sample of a source.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Exposition builds a Prometheus text-format scrape. Samples of the same metric
+// family from several sources (e.g. one registry per query, told apart by a
+// label) are grouped under one TYPE line, as the format requires. It carries no
+// dependency on the Prometheus client library; serve the output from any
+// /metrics handler.
+type Exposition struct {
+	namespace string
+	families  map[string]*family
+}
+
+type family struct {
+	typ     string
+	help    string
+	samples []sample
+}
+
+type sample struct {
+	suffix string
+	labels []Label
+	value  float64
+}
+
+// NewExposition creates an empty scrape; namespace (e.g. "streamsql") prefixes
+// every metric name.
+func NewExposition(namespace string) *Exposition {
+	return &Exposition{namespace: namespace, families: make(map[string]*family)}
+}
+
+// Add records one counter or gauge sample. Counter names get the conventional
+// "_total" suffix (a trailing "_count" is replaced by it).
+func (e *Exposition) Add(name, typ, help string, value float64, labels ...Label) {
+	f := e.family(name, typ, help)
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// AddRegistry records every metric of r: counters as counters, gauges and
+// cardinality estimates as gauges, histograms as histograms in seconds.
+// Counters are monotonic until the owner resets them (e.g. Stream.ResetStats),
+// which Prometheus treats as a counter restart.
+func (e *Exposition) AddRegistry(r *Registry, labels ...Label) {
+	if r == nil {
+		return
+	}
+	for _, name := range r.Names() {
+		m, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		switch v := m.(type) {
+		case *Counter:
+			e.Add(name, PrometheusCounter, "", float64(v.Value()), labels...)
+		case *Gauge:
+			e.Add(name, PrometheusGauge, "", float64(v.Value()), labels...)
+		case *Cardinality:
+			e.Add(name, PrometheusGauge, "Estimated number of distinct values.", float64(v.Estimate()), labels...)
+		case *Histogram:
+			e.addHistogram(v, labels)
+		default:
+			if f, ok := toFloat(m.SnapshotValue()); ok {
+				e.Add(name, PrometheusGauge, "", f, labels...)
+			}
+		}
+	}
+}
+
+func (e *Exposition) addHistogram(h *Histogram, labels []Label) {
+	f := e.family(h.name, PrometheusHistogram, "")
+	for i, ub := range h.buckets {
+		le := strconv.FormatFloat(time.Duration(ub).Seconds(), 'g', -1, 64)
+		f.samples = append(f.samples, sample{
+			suffix: "_bucket",
+			labels: append(append([]Label{}, labels...), Label{Name: "le", Value: le}),
+			value:  float64(atomic.LoadInt64(&h.counts[i])),
+		})
+	}
+	count := float64(atomic.LoadInt64(&h.count))
+	f.samples = append(f.samples,
+		sample{suffix: "_bucket", labels: append(append([]Label{}, labels...), Label{Name: "le", Value: "+Inf"}), value: count},
+		sample{suffix: "_sum", labels: labels, value: time.Duration(atomic.LoadInt64(&h.sum)).Seconds()},
+		sample{suffix: "_count", labels: labels, value: count},
+	)
+}
+
+// family returns the family for name, creating it on first use. The first
+// registration decides its type and help text.
+func (e *Exposition) family(name, typ, help string) *family {
+	full := prometheusName(e.namespace, name, typ)
+	f, ok := e.families[full]
+	if !ok {
+		f = &family{typ: typ, help: help}
+		e.families[full] = f
+	}
+	return f
+}
+
+// WriteTo writes the scrape in the text exposition format, families sorted by name.
+func (e *Exposition) WriteTo(w io.Writer) (int64, error) {
+	names := make([]string, 0, len(e.families))
+	for n := range e.families {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, n := range names {
+		f := e.families[n]
+		if f.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", n, f.help)
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", n, f.typ)
+		for _, s := range f.samples {
+			bw.WriteString(n + s.suffix)
+			writeLabels(bw, s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.value))
+			bw.WriteByte('\n')
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func writeLabels(bw *bufio.Writer, labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+	bw.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(sanitizeName(l.Name))
+		bw.WriteString(`="`)
+		bw.WriteString(labelEscaper.Replace(l.Value))
+		bw.WriteByte('"')
+	}
+	bw.WriteByte('}')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// prometheusName builds namespace_name with invalid characters replaced by '_'.
+func prometheusName(namespace, name, typ string) string {
+	name = sanitizeName(toSnake(name))
+	if typ == PrometheusCounter {
+		name = strings.TrimSuffix(name, "_count")
+		if !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+	}
+	if namespace != "" {
+		name = sanitizeName(namespace) + "_" + name
+	}
+	return name
+}
+
+// toSnake converts camelCase statistic keys (e.g. window "sentCount") to snake_case.
+func toSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func sanitizeName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		valid := c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// AddRuntime records process memory gauges (heap in use, memory obtained from
+// the OS) and the goroutine count.
+func (e *Exposition) AddRuntime() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	e.Add("memory_heap_alloc_bytes", PrometheusGauge, "Bytes of allocated heap objects.", float64(ms.HeapAlloc))
+	e.Add("memory_sys_bytes", PrometheusGauge, "Bytes of memory obtained from the OS.", float64(ms.Sys))
+	e.Add("goroutines", PrometheusGauge, "Number of goroutines.", float64(runtime.NumGoroutine()))
+}
+
+// PrometheusHandler serves a scrape built by collect on every request, plus the
+// AddRuntime gauges. Mount it on an existing /metrics endpoint.
+func PrometheusHandler(namespace string, collect func(e *Exposition)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		e := NewExposition(namespace)
+		collect(e)
+		e.AddRuntime()
+		w.Header().Set("Content-Type", PrometheusContentType)
+		_, _ = e.WriteTo(w)
+	})
+}
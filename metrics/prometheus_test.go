@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("input_count").IncBy(5)
+	r.Gauge("queue depth").Set(3)
+	h := NewHistogram("latency", []time.Duration{time.Millisecond, time.Second})
+	r.Register(h)
+	h.Observe(500 * time.Microsecond)
+	h.Observe(2 * time.Second)
+
+	e := NewExposition("streamsql")
+	e.AddRegistry(r, Label{Name: "query", Value: "0"})
+	e.AddRegistry(r, Label{Name: "query", Value: `a"b`})
+	var sb strings.Builder
+	_, err := e.WriteTo(&sb)
+	require.NoError(t, err)
+	out := sb.String()
+
+	assert.Equal(t, 1, strings.Count(out, "# TYPE streamsql_input_total counter"), "同名指标族只声明一次")
+	assert.Contains(t, out, `streamsql_input_total{query="0"} 5`)
+	assert.Contains(t, out, `streamsql_input_total{query="a\"b"} 5`)
+	assert.Contains(t, out, "# TYPE streamsql_queue_depth gauge\n")
+	assert.Contains(t, out, `streamsql_queue_depth{query="0"} 3`)
+	assert.Contains(t, out, "# TYPE streamsql_latency histogram\n")
+	assert.Contains(t, out, `streamsql_latency_bucket{query="0",le="0.001"} 1`)
+	assert.Contains(t, out, `streamsql_latency_bucket{query="0",le="1"} 1`)
+	assert.Contains(t, out, `streamsql_latency_bucket{query="0",le="+Inf"} 2`)
+	assert.Contains(t, out, `streamsql_latency_sum{query="0"} 2.0005`)
+	assert.Contains(t, out, `streamsql_latency_count{query="0"} 2`)
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	c := NewCounter("rows")
+	rec := httptest.NewRecorder()
+	PrometheusHandler("app", func(e *Exposition) {
+		c.Inc()
+		e.Add(c.Name(), PrometheusCounter, "Rows seen.", float64(c.Value()))
+	}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, PrometheusContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# HELP app_rows_total Rows seen.\n# TYPE app_rows_total counter\napp_rows_total 1\n")
+	assert.Contains(t, body, "# TYPE app_memory_heap_alloc_bytes gauge")
+	assert.Contains(t, body, "# TYPE app_goroutines gauge")
+}
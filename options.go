@@ -152,3 +152,322 @@ func WithAnalyticMaxPartitions(n int) Option {
 		ss.analyticMaxPartitions = n
 	}
 }
+
+// WithOutputShape selects the layout of aggregate results: types.OutputShapeWide
+// (default, one column per aggregate) or types.OutputShapeLong, which emits one
+// row per group and aggregate holding the group's non-aggregate columns plus
+// "metric" (the aggregate's output name) and "value". LIMIT and ORDER BY apply
+// to groups before reshaping. Non-aggregate queries are unaffected.
+func WithOutputShape(shape types.OutputShape) Option {
+	return func(ss *Streamsql) {
+		ss.outputShape = shape
+	}
+}
+
+// WithEmitGranularity selects how window results are batched: types.EmitPerWindow
+// (default) delivers one batch per window holding every group, suited to
+// reporting; types.EmitPerGroup delivers one batch per GROUP BY key, suited to
+// routing each group to its own destination. Sinks and ToChannel both observe
+// the chosen batching. Non-window queries are unaffected.
+func WithEmitGranularity(g types.EmitGranularity) Option {
+	return func(ss *Streamsql) {
+		ss.emitGranularity = g
+	}
+}
+
+// WithMaxOpenWindows caps how many sliding windows may be open at once (started
+// but not yet closed, including windows kept open by allowed lateness). A small
+// slide with a large size otherwise keeps size/slide windows, and their rows,
+// in memory. When a row would exceed n, policy decides:
+// types.OpenWindowCloseOldest (default) emits the oldest windows early, and
+// types.OpenWindowReject drops the row (written to the dead-letter store when
+// WithDeadLetterDir is set). The open-window count and the number of
+// force-closed windows and rejected rows appear in GetStats as "openWindows",
+// "forceClosedWindows" and "rejectedRows". n <= 0 (default) means unlimited;
+// other window types ignore the setting.
+func WithMaxOpenWindows(n int, policy types.OpenWindowPolicy) Option {
+	return func(ss *Streamsql) {
+		ss.maxOpenWindows = n
+		ss.openWindowPolicy = policy
+	}
+}
+
+// WithPerKeyWindowClock gives each GROUP BY key of a processing-time tumbling
+// window its own clock: a key's window opens at its first row and fires size
+// later, independently of other keys, instead of all keys sharing one
+// epoch-aligned timer. Suited to bursty multi-device streams where sparse keys
+// would otherwise land in empty or arbitrarily cut windows. A key keeps no
+// state or timer once its window fires, so idle keys cost nothing. Event-time
+// windows and queries without GROUP BY keys are unaffected.
+func WithPerKeyWindowClock() Option {
+	return func(ss *Streamsql) {
+		ss.perKeyWindowClock = true
+	}
+}
+
+// WithPerKeyWatermark gives each GROUP BY key of an event-time tumbling or
+// sliding window its own watermark, so devices with different clock skews fire
+// their windows independently instead of all waiting for the most lagging one.
+// Idle-source handling (IDLETIMEOUT) also becomes per key: a silent key's
+// windows close on their own, after which the key is forgotten. Processing-time
+// windows and queries without GROUP BY keys are unaffected.
+func WithPerKeyWatermark() Option {
+	return func(ss *Streamsql) {
+		ss.perKeyWatermark = true
+	}
+}
+
+// WithDeadLetterDir persists rejected input records instead of only dropping
+// them: rows failing schema validation (WithSchema) and event-time rows whose
+// timestamp cannot be parsed are appended, with the failure reason, to
+// dir/dead_letter.jsonl (see stream.ReadDeadLetters). The directory is created
+// if needed. Empty (default) disables persistence.
+//
+// Every query gets a file of its own: when Execute runs several statements or
+// ExecutePipeline several stages, the n-th one writes under dir/statement-n or
+// dir/stage-n instead. Give each instance its own dir.
+func WithDeadLetterDir(dir string) Option {
+	return func(ss *Streamsql) {
+		ss.deadLetterDir = dir
+	}
+}
+
+// WithExpressionTimeout bounds every evaluation of a SELECT expression, an
+// aggregate's input expression (e.g. a custom function call) or the WHERE
+// predicate to d. An evaluation that exceeds d yields NULL for that field (a
+// timed-out WHERE rejects the row), is logged, and the row is written to the
+// dead-letter store when WithDeadLetterDir is set. Evaluations run on a
+// bounded pool of reused worker goroutines, and Stop ends the wait for any
+// still in progress. Default (d<=0) places no limit.
+func WithExpressionTimeout(d time.Duration) Option {
+	return func(ss *Streamsql) {
+		ss.expressionTimeout = d
+	}
+}
+
+// WithQualityCounts makes each windowed aggregate result carry, per group,
+// __input_count (rows before WHERE) and __filtered_count (rows that passed
+// WHERE and were aggregated), showing how much data WHERE rejected. A rejected
+// row counts towards the result of its group's window containing the row's
+// timestamp (the group's next result for counting windows); a group whose rows
+// in a window are all rejected produces no result row for it. Non-window queries and the global window
+// (which aggregates internally) are unaffected.
+func WithQualityCounts() Option {
+	return func(ss *Streamsql) {
+		ss.qualityCounts = true
+	}
+}
+
+// WithMinGroupCount suppresses low-sample groups: a group with fewer than n rows
+// aggregated in a window (rows that passed WHERE) is not emitted for that
+// window. Unlike HAVING it needs no aggregate in the SELECT and is applied
+// before projection and HAVING. n <= 0 (default) emits every group.
+// Non-window queries and the global window are unaffected.
+func WithMinGroupCount(n int) Option {
+	return func(ss *Streamsql) {
+		ss.minGroupCount = n
+	}
+}
+
+// WithNonFinitePolicy sets how numeric aggregates (sum, avg, min, max, stddev,
+// ...) treat NaN and ±Inf inputs, e.g. from a division by zero upstream:
+// types.NonFiniteSkip ignores the value, types.NonFiniteError ignores it and
+// logs an aggregate error, and types.NonFiniteNullify makes that aggregate NULL
+// for the group in the current window. By default the value is aggregated
+// as-is, which usually turns the result into NaN/Inf. count is unaffected.
+// Non-window queries and the global window are unaffected.
+func WithNonFinitePolicy(policy types.NonFinitePolicy) Option {
+	return func(ss *Streamsql) {
+		ss.nonFinitePolicy = policy
+	}
+}
+
+// WithMaxAggregateBufferPerGroup caps the values that aggregates which buffer
+// every input (median, percentile, iqr, stddev, var, trimmed_mean,
+// consecutive_diff_median, collect, string_agg, time_in_state) keep per group
+// and window, so a huge window cannot exhaust memory. Beyond max,
+// types.AggregateBufferDrop (default) ignores further values and
+// types.AggregateBufferApproximate keeps a uniform random sample of max values,
+// making the result approximate. Either way the values not kept are counted in
+// the aggregate_buffer_dropped metric.
+// count_distinct and value_counts keep at most max distinct values under
+// either policy and drop new ones beyond it, so their results become lower
+// bounds.
+// max <= 0 (default) means unbounded.
+func WithMaxAggregateBufferPerGroup(max int, policy types.AggregateBufferPolicy) Option {
+	return func(ss *Streamsql) {
+		ss.maxAggregateBuffer = max
+		ss.aggregateBufferPolicy = policy
+	}
+}
+
+// WithCompactUnchangedWindows compacts consecutive window results of a group
+// whose aggregate values are identical into one record spanning all those
+// windows: window_id becomes "<first start>_<last end>" and end-type window
+// columns (window_end(), window_end_iso(), window_watermark()) take the last
+// window's value. A group's record is emitted once its values change, the
+// group misses a later window, or on CloseInput/Stop, so output lags until
+// then. Intended for downsampled storage of stable signals. The global
+// window is unaffected.
+func WithCompactUnchangedWindows() Option {
+	return func(ss *Streamsql) {
+		ss.compactUnchangedWindows = true
+	}
+}
+
+// WithGroupNullPolicy sets how window aggregation groups rows whose GROUP BY
+// field is NULL or missing: types.GroupNullSeparate (default) keeps them in a
+// group of their own with a NULL key column, types.GroupNullDrop leaves them
+// out, and types.GroupNullCoalesceTo("unknown") groups them under "unknown",
+// together with rows whose field is "unknown". Execute fails on an invalid
+// policy. The global window is unaffected.
+func WithGroupNullPolicy(policy types.GroupNullPolicy) Option {
+	return func(ss *Streamsql) {
+		ss.groupNullPolicy = policy
+	}
+}
+
+// WithTimeZone sets the IANA time zone (e.g. "Asia/Shanghai", or "Local")
+// in which window_start_iso()/window_end_iso() format window bounds. The
+// default is UTC. Execute fails on an unknown zone.
+func WithTimeZone(name string) Option {
+	return func(ss *Streamsql) {
+		ss.timeZone = name
+	}
+}
+
+// WithProjectionErrorPolicy sets how a non-aggregation query handles a SELECT
+// field whose evaluation fails for a row, e.g. a function rejecting its
+// argument: types.ProjectionErrorNullify (default) emits the row with that
+// field NULL, types.ProjectionErrorSkipRow drops the row, and
+// types.ProjectionErrorFail drops it and reports the error (returned by
+// EmitSync, logged on the asynchronous path). Other rows are unaffected.
+func WithProjectionErrorPolicy(policy types.ProjectionErrorPolicy) Option {
+	return func(ss *Streamsql) {
+		ss.projectionErrorPolicy = policy
+	}
+}
+
+// WithExpressionFallback substitutes value for a SELECT expression that fails
+// to evaluate for a row in a non-aggregation query, so the row is emitted with
+// the fallback instead of NULL and WithProjectionErrorPolicy does not apply. A
+// field written as coalesce_expr(expr, fallback) uses its own fallback instead.
+// A nil value disables the substitution (the default).
+func WithExpressionFallback(value any) Option {
+	return func(ss *Streamsql) {
+		ss.expressionFallback = value
+	}
+}
+
+// WithTransformFlushInterval batches the result rows of a non-aggregation query
+// and dispatches them together, so sinks are called less often under load. A
+// row waits at most d before its batch is sent, which bounds the output
+// latency of sparse streams; a batch is also sent as soon as it reaches 1000
+// rows, and CloseInput/Stop send whatever is left. EmitSync is unaffected.
+// d <= 0 (default) dispatches every row immediately.
+func WithTransformFlushInterval(d time.Duration) Option {
+	return func(ss *Streamsql) {
+		ss.transformFlushInterval = d
+	}
+}
+
+// WithStateFlushInterval sets how a GROUP BY query without a window emits its
+// per-group state, e.g. SELECT deviceId, latest(temperature) AS t FROM stream
+// GROUP BY deviceId. By default (d <= 0) every record emits the updated row of
+// its group right away. With d > 0 each group keeps only its latest row and a
+// snapshot of all groups is emitted as one batch every d, skipped when no
+// group changed; CloseInput/Stop emit a final snapshot. EmitSync is not
+// available in that mode. Other queries ignore the setting.
+func WithStateFlushInterval(d time.Duration) Option {
+	return func(ss *Streamsql) {
+		ss.stateFlushInterval = d
+	}
+}
+
+// WithWindowHistory keeps the results of the last n emitted windows in a
+// bounded in-memory ring buffer so ReplayLastWindows can re-dispatch them to
+// sinks after a downstream outage. The history is not persisted. n <= 0
+// (default) keeps no history.
+func WithWindowHistory(n int) Option {
+	return func(ss *Streamsql) {
+		ss.windowHistory = n
+	}
+}
+
+// WithPrimaryKey declares the output columns that identify a result row. Each
+// emitted row then carries __pk, a stable key (the JSON array of those column
+// values), and __upsert, true when a row with the same key was emitted before
+// (e.g. a late re-emit under allowed lateness), so sinks can do idempotent
+// upserts. Window queries usually include window_id in the key. With
+// types.OutputShapeLong the key must include the "metric" column and must not
+// name an aggregate column or "value"; Execute rejects such keys, as it does
+// duplicate columns.
+func WithPrimaryKey(columns ...string) Option {
+	return func(ss *Streamsql) {
+		ss.primaryKey = columns
+	}
+}
+
+// WithCloseInputGrace bounds how long CloseInput waits for queued input to be
+// processed, open windows to be flushed and sinks to finish. Default (d<=0)
+// is the same 5s grace period Stop uses.
+func WithCloseInputGrace(d time.Duration) Option {
+	return func(ss *Streamsql) {
+		ss.closeInputGrace = d
+	}
+}
+
+// WithAggregationShards shards GROUP BY groups across n parallel aggregators
+// keyed by group hash, parallelizing window aggregation for high-cardinality
+// groupings. Results are identical to single-threaded aggregation (row order
+// across groups may differ, as with any GROUP BY without ORDER BY). n<=1
+// keeps single-threaded aggregation. Builds on the current custom config if
+// one was set, otherwise on the default config.
+func WithAggregationShards(n int) Option {
+	return func(s *Streamsql) {
+		config := types.DefaultPerformanceConfig()
+		if s.customConfig != nil {
+			config = *s.customConfig
+		}
+		s.performanceMode = "custom"
+		config.WorkerConfig.AggregationShards = n
+		s.customConfig = &config
+	}
+}
+
+// WithMaxInFlightBatches bounds memory under slow sinks: at most n result
+// batches may be dispatched to the async sinks (AddSink) without every sink
+// having finished them. Beyond the limit the "block" overflow strategy (see
+// WithOverflowStrategy) waits for a batch to finish, up to its block timeout,
+// and the other strategies drop the batch for the async sinks; drops are
+// reported as sink_batches_dropped in GetStats. Sync sinks and the result
+// channel are unaffected. n <= 0 (default) is unbounded. Builds on the current
+// custom config if one was set, otherwise on the default config.
+func WithMaxInFlightBatches(n int) Option {
+	return func(s *Streamsql) {
+		config := types.DefaultPerformanceConfig()
+		if s.customConfig != nil {
+			config = *s.customConfig
+		}
+		s.performanceMode = "custom"
+		config.MaxInFlightBatches = n
+		s.customConfig = &config
+	}
+}
+
+// WithRowPooling recycles the row batches emitted by windows, and the per-row
+// JOIN working maps of tumbling, counting and session windows, once they have
+// been aggregated, reducing GC pressure at high window throughput. Emitted rows
+// and the result rows passed to sinks are never pooled. Off by default. Builds
+// on the current custom config if one was set, otherwise on the default config.
+func WithRowPooling() Option {
+	return func(s *Streamsql) {
+		config := types.DefaultPerformanceConfig()
+		if s.customConfig != nil {
+			config = *s.customConfig
+		}
+		s.performanceMode = "custom"
+		config.RowPooling = true
+		s.customConfig = &config
+	}
+}
@@ -18,13 +18,18 @@ package streamsql
 
 import (
 	"fmt"
+	"math"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/logger"
 	"github.com/rulego/streamsql/schema"
+	"github.com/rulego/streamsql/stream"
 	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/utils/cast"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -205,6 +210,15 @@ func TestWithWorkerConfig(t *testing.T) {
 	})
 }
 
+// TestWithMaxInFlightBatches 在途批次上限叠加在已有的自定义配置之上
+func TestWithMaxInFlightBatches(t *testing.T) {
+	s := New(WithOverflowStrategy("block", time.Second), WithMaxInFlightBatches(3))
+	assert.Equal(t, "custom", s.performanceMode)
+	require.NotNil(t, s.customConfig)
+	assert.Equal(t, 3, s.customConfig.MaxInFlightBatches)
+	assert.Equal(t, "block", s.customConfig.OverflowConfig.Strategy)
+}
+
 // TestWithMonitoring 测试监控配置选项
 func TestWithMonitoring(t *testing.T) {
 	t.Run("启用详细监控", func(t *testing.T) {
@@ -362,3 +376,830 @@ func TestWithLogger(t *testing.T) {
 		assert.Empty(t, capB.msgs, "B's logger must not get A's warn (no cross-talk)")
 	})
 }
+
+// TestWithDeadLetterDir 验证校验失败与时间戳无法解析的记录连同原因写入死信文件。
+func TestWithDeadLetterDir(t *testing.T) {
+	sch := schema.Schema{Fields: []schema.FieldDef{{Name: "v", Type: schema.TypeInt, Required: true}}}
+
+	t.Run("schema 校验失败", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithSchema(sch), WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT v FROM stream"))
+
+		s.Emit(map[string]any{"v": "bad"})
+		s.EmitMany([]map[string]any{{"v": 1}, {"other": 2}})
+		_, err := s.EmitSync(map[string]any{"v": "x"})
+		require.Error(t, err)
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, map[string]any{"v": "bad"}, records[0].Record)
+		assert.Equal(t, map[string]any{"other": 2.0}, records[1].Record)
+		assert.Equal(t, map[string]any{"v": "x"}, records[2].Record)
+		for _, r := range records {
+			assert.Contains(t, r.Reason, "schema validation failed")
+			assert.False(t, r.Time.IsZero())
+		}
+	})
+
+	t.Run("事件时间戳无法解析", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "dlq")
+		s := New(WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT COUNT(*) AS cnt FROM stream GROUP BY TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')"))
+
+		s.Emit(map[string]any{"id": 1, "ts": time.Now().UnixMilli()})
+		s.Emit(map[string]any{"id": 2, "ts": "yesterday"})
+		s.Emit(map[string]any{"id": 3})
+		require.NoError(t, s.CloseInput())
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, 2.0, records[0].Record.(map[string]any)["id"])
+		assert.Equal(t, 3.0, records[1].Record.(map[string]any)["id"])
+		assert.Contains(t, records[0].Reason, `no usable event timestamp in field "ts"`)
+	})
+
+	t.Run("多语句各写各的文件", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute(`
+			SELECT COUNT(*) AS cnt FROM stream GROUP BY TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms');
+			SELECT MAX(id) AS top FROM stream GROUP BY TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+
+		s.Emit(map[string]any{"id": 1, "ts": "yesterday"})
+		require.NoError(t, s.CloseInput())
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		assert.Empty(t, records, "多语句不写共享文件")
+		for _, sub := range []string{"statement-1", "statement-2"} {
+			records, err := stream.ReadDeadLetters(filepath.Join(dir, sub))
+			require.NoError(t, err)
+			require.Len(t, records, 1, sub)
+			assert.Equal(t, 1.0, records[0].Record.(map[string]any)["id"])
+		}
+	})
+
+	t.Run("未配置时不写入", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithSchema(sch))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT v FROM stream"))
+		s.Emit(map[string]any{"v": "bad"})
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+}
+
+// TestWithExpressionTimeout 慢自定义函数超时：字段为 NULL、记录进入死信，快速行不受影响
+func TestWithExpressionTimeout(t *testing.T) {
+	require.NoError(t, functions.RegisterCustomFunction("slow_echo", functions.TypeCustom, "测试", "v>=100 时阻塞", 1, 1,
+		func(ctx *functions.FunctionContext, args []any) (any, error) {
+			v := cast.ToFloat64(args[0])
+			if v >= 100 {
+				time.Sleep(500 * time.Millisecond)
+			}
+			return v, nil
+		}))
+	defer functions.Unregister("slow_echo")
+
+	t.Run("SELECT 表达式", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithExpressionTimeout(50*time.Millisecond), WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT id, slow_echo(v) AS out FROM stream"))
+
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": 2})
+		require.NoError(t, err)
+		assert.Equal(t, 2.0, row["out"])
+
+		start := time.Now()
+		row, err = s.EmitSync(map[string]any{"id": 2, "v": 100})
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 400*time.Millisecond, "超时后应立即返回，不等待慢函数")
+		assert.Nil(t, row["out"])
+		assert.Equal(t, 2, row["id"])
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, 2.0, records[0].Record.(map[string]any)["id"])
+		assert.Contains(t, records[0].Reason, "timed out")
+	})
+
+	t.Run("聚合输入表达式", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithExpressionTimeout(50*time.Millisecond), WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT SUM(slow_echo(v)) AS total FROM stream GROUP BY CountingWindow(3)"))
+		ch := make(chan []map[string]any, 1)
+		s.AddSink(func(r []map[string]any) { ch <- r })
+
+		s.Emit(map[string]any{"id": 1, "v": 1})
+		s.Emit(map[string]any{"id": 2, "v": 100})
+		s.Emit(map[string]any{"id": 3, "v": 2})
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 1)
+			assert.Equal(t, 3.0, rows[0]["total"], "超时的行不计入 SUM")
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+		}
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, 2.0, records[0].Record.(map[string]any)["id"])
+	})
+
+	t.Run("WHERE 谓词", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithExpressionTimeout(50*time.Millisecond), WithDeadLetterDir(dir))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT id FROM stream WHERE slow_echo(v) > 0"))
+
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": 2})
+		require.NoError(t, err)
+		assert.Equal(t, 1, row["id"])
+
+		start := time.Now()
+		in := map[string]any{"id": 2, "v": 100}
+		row, _ = s.EmitSync(in)
+		assert.Less(t, time.Since(start), 400*time.Millisecond, "超时后应立即返回，不等待慢函数")
+		assert.Nil(t, row, "WHERE 超时的行被丢弃")
+		in["v"] = 3 // 仍在运行的求值持有副本，调用方可复用输入 map
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, 2.0, records[0].Record.(map[string]any)["id"])
+		assert.Contains(t, records[0].Reason, "where")
+	})
+
+	t.Run("Stop 取消等待中的 WHERE 求值", func(t *testing.T) {
+		dir := t.TempDir()
+		s := New(WithExpressionTimeout(time.Minute), WithDeadLetterDir(dir))
+		require.NoError(t, s.Execute("SELECT id FROM stream WHERE slow_echo(v) > 0"))
+
+		time.AfterFunc(50*time.Millisecond, s.Stop)
+		start := time.Now()
+		row, _ := s.EmitSync(map[string]any{"id": 1, "v": 100})
+		assert.Less(t, time.Since(start), 400*time.Millisecond, "Stop 后应立即返回，不等待慢函数")
+		assert.Nil(t, row)
+
+		records, err := stream.ReadDeadLetters(dir)
+		require.NoError(t, err)
+		assert.Empty(t, records, "取消不是超时，不写死信")
+	})
+}
+
+// TestWithQualityCounts 窗口结果按分组携带 WHERE 前后的行数
+func TestWithQualityCounts(t *testing.T) {
+	run := func(t *testing.T, opts ...Option) map[string]map[string]any {
+		s := New(opts...)
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT device, SUM(v) AS total FROM stream WHERE v > 10 GROUP BY device, CountingWindow(2)"))
+		ch := make(chan []map[string]any, 4)
+		s.AddSink(func(r []map[string]any) { ch <- r })
+
+		// 计数窗口按分组计数通过 WHERE 的行：
+		// a: 5(拒) 20 30 -> 输入 3、通过 2；b: 1(拒) 2(拒) 50 60 -> 输入 4、通过 2
+		for _, row := range []map[string]any{
+			{"device": "a", "v": 5}, {"device": "b", "v": 1}, {"device": "a", "v": 20},
+			{"device": "b", "v": 2}, {"device": "a", "v": 30}, {"device": "b", "v": 50},
+			{"device": "b", "v": 60},
+		} {
+			s.Emit(row)
+		}
+		byDevice := map[string]map[string]any{}
+		for len(byDevice) < 2 {
+			select {
+			case rows := <-ch:
+				for _, r := range rows {
+					byDevice[r["device"].(string)] = r
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window results")
+			}
+		}
+		return byDevice
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"启用", []Option{WithQualityCounts()}},
+		{"分片聚合", []Option{WithQualityCounts(), WithAggregationShards(4)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			byDevice := run(t, tc.opts...)
+			assert.Equal(t, 50.0, byDevice["a"]["total"])
+			assert.Equal(t, int64(3), byDevice["a"]["__input_count"])
+			assert.Equal(t, int64(2), byDevice["a"]["__filtered_count"])
+			assert.Equal(t, 110.0, byDevice["b"]["total"])
+			assert.Equal(t, int64(4), byDevice["b"]["__input_count"])
+			assert.Equal(t, int64(2), byDevice["b"]["__filtered_count"])
+		})
+	}
+
+	// 时间窗口：c 在第一个窗口全被过滤，其拒绝计数不得带入第二个窗口
+	t.Run("全过滤分组不跨窗口", func(t *testing.T) {
+		s := New(WithQualityCounts())
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT device, SUM(v) AS total FROM stream WHERE v > 10 GROUP BY device, TumblingWindow('10s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')"))
+		ch := make(chan []map[string]any, 4)
+		s.AddSink(func(r []map[string]any) { ch <- r })
+
+		base := time.Now().UnixMilli()/10000*10000 - 60000
+		for _, row := range []map[string]any{
+			{"device": "a", "v": 20, "ts": base}, {"device": "c", "v": 1, "ts": base + 1}, {"device": "c", "v": 2, "ts": base + 2},
+			{"device": "c", "v": 30, "ts": base + 10000}, {"device": "c", "v": 3, "ts": base + 10001},
+			{"device": "a", "v": 40, "ts": base + 30000},
+		} {
+			s.Emit(row)
+		}
+		// sink 异步派发，窗口到达顺序不定：按分组收集
+		byDevice := map[string]map[string]any{}
+		for len(byDevice) < 2 {
+			select {
+			case rows := <-ch:
+				for _, r := range rows {
+					byDevice[r["device"].(string)] = r
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window results")
+			}
+		}
+		assert.Equal(t, int64(1), byDevice["a"]["__input_count"])
+		assert.Equal(t, int64(2), byDevice["c"]["__input_count"])
+		assert.Equal(t, int64(1), byDevice["c"]["__filtered_count"])
+	})
+
+	t.Run("默认不输出", func(t *testing.T) {
+		for _, r := range run(t) {
+			assert.NotContains(t, r, "__input_count")
+			assert.NotContains(t, r, "__filtered_count")
+		}
+	})
+}
+
+func TestWithEmitGranularity(t *testing.T) {
+	// run 推送 3 个设备的数据并触发一次窗口，收齐 wantRows 行后返回每次 sink 调用收到的批次。
+	run := func(t *testing.T, sql string, wantRows int, opts ...Option) [][]map[string]any {
+		s := New(opts...)
+		defer s.Stop()
+		require.NoError(t, s.Execute(sql))
+		var mu sync.Mutex
+		var batches [][]map[string]any
+		s.AddSyncSink(func(r []map[string]any) {
+			mu.Lock()
+			batches = append(batches, r)
+			mu.Unlock()
+		})
+		for _, row := range []map[string]any{
+			{"device": "a", "v": 1}, {"device": "b", "v": 2}, {"device": "c", "v": 3},
+			{"device": "a", "v": 4}, {"device": "b", "v": 5},
+		} {
+			s.Emit(row)
+		}
+		time.Sleep(100 * time.Millisecond)
+		s.TriggerWindow()
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			n := 0
+			for _, b := range batches {
+				n += len(b)
+			}
+			return n >= wantRows
+		}, 5*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+	const sql = "SELECT device, SUM(v) AS total FROM stream GROUP BY device, TumblingWindow('1h')"
+
+	t.Run("默认按窗口一批", func(t *testing.T) {
+		batches := run(t, sql, 3)
+		require.Len(t, batches, 1)
+		assert.Len(t, batches[0], 3)
+	})
+
+	t.Run("显式按窗口", func(t *testing.T) {
+		batches := run(t, sql, 3, WithEmitGranularity(types.EmitPerWindow))
+		require.Len(t, batches, 1)
+		assert.Len(t, batches[0], 3)
+	})
+
+	t.Run("按分组每组一批", func(t *testing.T) {
+		batches := run(t, sql, 3, WithEmitGranularity(types.EmitPerGroup))
+		require.Len(t, batches, 3)
+		totals := map[string]any{}
+		for _, b := range batches {
+			require.Len(t, b, 1)
+			totals[b[0]["device"].(string)] = b[0]["total"]
+		}
+		assert.Equal(t, map[string]any{"a": 5.0, "b": 7.0, "c": 3.0}, totals)
+	})
+
+	t.Run("按分组与long形态", func(t *testing.T) {
+		batches := run(t, "SELECT device, SUM(v) AS total, COUNT(*) AS cnt FROM stream GROUP BY device, TumblingWindow('1h')", 6,
+			WithEmitGranularity(types.EmitPerGroup), WithOutputShape(types.OutputShapeLong))
+		require.Len(t, batches, 3)
+		for _, b := range batches {
+			require.Len(t, b, 2)
+			assert.Equal(t, b[0]["device"], b[1]["device"])
+		}
+	})
+
+	t.Run("非法取值", func(t *testing.T) {
+		s := New(WithEmitGranularity("row"))
+		defer s.Stop()
+		err := s.Execute(sql)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid emit granularity")
+	})
+}
+
+func TestWithMaxOpenWindows(t *testing.T) {
+	const sql = "SELECT COUNT(*) AS c FROM stream GROUP BY SlidingWindow('10s', '1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')"
+	// 事件时间戳间隔 1s 且远早于水位线推进所需：窗口只可能因上限关闭
+	base := time.Now().UnixMilli()/10000*10000 - 60000
+	emitRows := func(s *Streamsql) {
+		for i := 0; i < 6; i++ {
+			s.Emit(map[string]any{"ts": base + int64(i)*1000, "v": i})
+		}
+	}
+
+	t.Run("强制关闭最旧窗口", func(t *testing.T) {
+		s := New(WithMaxOpenWindows(2, types.OpenWindowCloseOldest))
+		defer s.Stop()
+		require.NoError(t, s.Execute(sql))
+		ch := make(chan []map[string]any, 8)
+		s.AddSink(func(r []map[string]any) { ch <- r })
+		emitRows(s)
+
+		// 6 行最多 2 个打开窗口：前 4 个窗口被提前关闭
+		var counts []any
+		for len(counts) < 4 {
+			select {
+			case rows := <-ch:
+				counts = append(counts, rows[0]["c"])
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout, got %v", counts)
+			}
+		}
+		assert.Equal(t, []any{3.0, 3.0, 3.0, 3.0}, counts)
+		stats := s.GetStats()
+		assert.Equal(t, int64(2), stats["openWindows"])
+		assert.Equal(t, int64(4), stats["forceClosedWindows"])
+	})
+
+	t.Run("拒绝新行", func(t *testing.T) {
+		s := New(WithMaxOpenWindows(2, types.OpenWindowReject))
+		defer s.Stop()
+		require.NoError(t, s.Execute(sql))
+		emitRows(s)
+		require.Eventually(t, func() bool {
+			return s.GetStats()["rejectedRows"] == 4
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, int64(2), s.GetStats()["openWindows"])
+	})
+
+	t.Run("非法策略", func(t *testing.T) {
+		s := New(WithMaxOpenWindows(2, "drop"))
+		defer s.Stop()
+		assert.Error(t, s.Execute(sql))
+	})
+}
+
+func TestWithPerKeyWindowClock(t *testing.T) {
+	s := New(WithPerKeyWindowClock())
+	defer s.Stop()
+	require.NoError(t, s.Execute("SELECT device, COUNT(*) AS c FROM stream GROUP BY device, TumblingWindow('400ms')"))
+	ch := make(chan []map[string]any, 4)
+	s.AddSink(func(r []map[string]any) { ch <- r })
+
+	// a 先到、b 晚 200ms 到：两个键的窗口各自从首条数据起算，分别触发
+	s.Emit(map[string]any{"device": "a"})
+	s.Emit(map[string]any{"device": "a"})
+	time.Sleep(200 * time.Millisecond)
+	s.Emit(map[string]any{"device": "b"})
+
+	var got []map[string]any
+	for len(got) < 2 {
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 1)
+			got = append(got, rows[0])
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout, got %v", got)
+		}
+	}
+	assert.Equal(t, "a", got[0]["device"])
+	assert.Equal(t, 2.0, got[0]["c"])
+	assert.Equal(t, "b", got[1]["device"])
+	assert.Equal(t, 1.0, got[1]["c"])
+}
+
+func TestWithMinGroupCount(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"单聚合器", []Option{WithMinGroupCount(3)}},
+		{"分片聚合", []Option{WithMinGroupCount(3), WithAggregationShards(4)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(tc.opts...)
+			defer s.Stop()
+			// WHERE 拒绝的行不计入：b 有 3 行但仅 2 行通过
+			require.NoError(t, s.Execute("SELECT device, AVG(v) AS a FROM stream WHERE v > 0 GROUP BY device, TumblingWindow('1h')"))
+			ch := make(chan []map[string]any, 1)
+			s.AddSink(func(r []map[string]any) { ch <- r })
+			for _, row := range []map[string]any{
+				{"device": "a", "v": 1}, {"device": "a", "v": 2}, {"device": "a", "v": 3},
+				{"device": "b", "v": 1}, {"device": "b", "v": 2}, {"device": "b", "v": -1},
+				{"device": "c", "v": 9},
+			} {
+				s.Emit(row)
+			}
+			time.Sleep(100 * time.Millisecond)
+			s.TriggerWindow()
+			select {
+			case rows := <-ch:
+				require.Len(t, rows, 1)
+				assert.Equal(t, "a", rows[0]["device"])
+				assert.Equal(t, 2.0, rows[0]["a"])
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window result")
+			}
+		})
+	}
+}
+
+func TestWithNonFinitePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy types.NonFinitePolicy
+		want   any
+	}{
+		{types.NonFiniteSkip, 2.0},
+		{types.NonFiniteError, 2.0},
+		{types.NonFiniteNullify, nil},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			s := New(WithNonFinitePolicy(tc.policy))
+			defer s.Stop()
+			require.NoError(t, s.Execute("SELECT device, AVG(v) AS a, COUNT(*) AS c FROM stream GROUP BY device, TumblingWindow('1h')"))
+			ch := make(chan []map[string]any, 1)
+			s.AddSink(func(r []map[string]any) { ch <- r })
+			for _, v := range []float64{1, math.NaN(), 3, math.Inf(-1)} {
+				s.Emit(map[string]any{"device": "a", "v": v})
+			}
+			time.Sleep(100 * time.Millisecond)
+			s.TriggerWindow()
+			select {
+			case rows := <-ch:
+				require.Len(t, rows, 1)
+				assert.Equal(t, tc.want, rows[0]["a"])
+				assert.Equal(t, 4.0, rows[0]["c"])
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window result")
+			}
+		})
+	}
+}
+
+// TestWithGroupNullPolicy 分组字段为 NULL 或缺失的行：单独成组、丢弃或并入指定取值的分组
+func TestWithGroupNullPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy types.GroupNullPolicy
+		want   map[any]any
+	}{
+		{types.GroupNullSeparate, map[any]any{"a": 1.0, nil: 2.0, "unknown": 1.0}},
+		{types.GroupNullDrop, map[any]any{"a": 1.0, "unknown": 1.0}},
+		{types.GroupNullCoalesceTo("unknown"), map[any]any{"a": 1.0, "unknown": 3.0}},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			s := New(WithGroupNullPolicy(tc.policy))
+			defer s.Stop()
+			require.NoError(t, s.Execute("SELECT device, COUNT(*) AS c FROM stream GROUP BY device, TumblingWindow('1h')"))
+			ch := make(chan []map[string]any, 1)
+			s.AddSink(func(r []map[string]any) { ch <- r })
+			for _, row := range []map[string]any{
+				{"device": "a"}, {"device": nil}, {"v": 1}, {"device": "unknown"},
+			} {
+				s.Emit(row)
+			}
+			time.Sleep(100 * time.Millisecond)
+			s.TriggerWindow()
+			select {
+			case rows := <-ch:
+				got := map[any]any{}
+				for _, r := range rows {
+					got[r["device"]] = r["c"]
+				}
+				assert.Equal(t, tc.want, got)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window result")
+			}
+		})
+	}
+
+	s := New(WithGroupNullPolicy("coalesce_to:unknown"))
+	defer s.Stop()
+	assert.Error(t, s.Execute("SELECT device, COUNT(*) AS c FROM stream GROUP BY device, TumblingWindow('1h')"))
+}
+
+// TestWithProjectionErrorPolicy 单行投影字段求值出错：nullify 该字段为 NULL，
+// skip_row 丢弃该行，error 由 EmitSync 返回错误；其他行不受影响
+func TestWithProjectionErrorPolicy(t *testing.T) {
+	require.NoError(t, functions.RegisterCustomFunction("must_positive", functions.TypeCustom, "测试", "v<0 时报错", 1, 1,
+		func(ctx *functions.FunctionContext, args []any) (any, error) {
+			v := cast.ToFloat64(args[0])
+			if v < 0 {
+				return nil, fmt.Errorf("negative value %v", v)
+			}
+			return v, nil
+		}))
+	defer functions.Unregister("must_positive")
+
+	newStream := func(t *testing.T, opts ...Option) *Streamsql {
+		s := New(opts...)
+		t.Cleanup(s.Stop)
+		require.NoError(t, s.Execute("SELECT id, must_positive(v) AS p FROM stream"))
+		return s
+	}
+
+	for _, policy := range []types.ProjectionErrorPolicy{"", types.ProjectionErrorNullify} {
+		t.Run("nullify/"+string(policy), func(t *testing.T) {
+			s := newStream(t, WithProjectionErrorPolicy(policy))
+			row, err := s.EmitSync(map[string]any{"id": 1, "v": -1})
+			require.NoError(t, err)
+			require.NotNil(t, row)
+			assert.Equal(t, 1, row["id"])
+			assert.Contains(t, row, "p")
+			assert.Nil(t, row["p"])
+		})
+	}
+
+	t.Run("skip_row", func(t *testing.T) {
+		s := newStream(t, WithProjectionErrorPolicy(types.ProjectionErrorSkipRow))
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": -1})
+		require.NoError(t, err)
+		assert.Nil(t, row)
+
+		row, err = s.EmitSync(map[string]any{"id": 2, "v": 4})
+		require.NoError(t, err)
+		assert.Equal(t, 4.0, row["p"])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s := newStream(t, WithProjectionErrorPolicy(types.ProjectionErrorFail))
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": -1})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "negative value")
+		assert.Nil(t, row)
+
+		row, err = s.EmitSync(map[string]any{"id": 2, "v": 4})
+		require.NoError(t, err)
+		assert.Equal(t, 4.0, row["p"])
+	})
+
+	t.Run("异步路径丢弃出错行", func(t *testing.T) {
+		for _, policy := range []types.ProjectionErrorPolicy{types.ProjectionErrorSkipRow, types.ProjectionErrorFail} {
+			s := newStream(t, WithProjectionErrorPolicy(policy))
+			ch := make(chan []map[string]any, 4)
+			s.AddSink(func(r []map[string]any) { ch <- r })
+			s.Emit(map[string]any{"id": 1, "v": -1})
+			s.Emit(map[string]any{"id": 2, "v": 9})
+			select {
+			case rows := <-ch:
+				require.Len(t, rows, 1)
+				assert.Equal(t, 2, rows[0]["id"], "policy %s", policy)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for result")
+			}
+			select {
+			case rows := <-ch:
+				t.Fatalf("policy %s: unexpected rows %v", policy, rows)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// TestWithExpressionFallback 投影表达式求值出错时以替代值输出：全局 ExpressionFallback
+// 与字段级 coalesce_expr(expr, fallback)，后者优先；未出错的行不受影响
+func TestWithExpressionFallback(t *testing.T) {
+	require.NoError(t, functions.RegisterCustomFunction("must_positive_fb", functions.TypeCustom, "测试", "v<0 时报错", 1, 1,
+		func(ctx *functions.FunctionContext, args []any) (any, error) {
+			v := cast.ToFloat64(args[0])
+			if v < 0 {
+				return nil, fmt.Errorf("negative value %v", v)
+			}
+			return v, nil
+		}))
+	defer functions.Unregister("must_positive_fb")
+
+	t.Run("config", func(t *testing.T) {
+		s := New(WithExpressionFallback(0.0), WithProjectionErrorPolicy(types.ProjectionErrorFail))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT id, must_positive_fb(v) * factor AS p FROM stream"))
+
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": -1, "factor": 2})
+		require.NoError(t, err, "fallback replaces the error policy")
+		assert.Equal(t, 0.0, row["p"])
+
+		row, err = s.EmitSync(map[string]any{"id": 2, "v": 3, "factor": 2})
+		require.NoError(t, err)
+		assert.Equal(t, 6.0, row["p"])
+	})
+
+	t.Run("coalesce_expr", func(t *testing.T) {
+		s := New(WithExpressionFallback(-1))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT id, coalesce_expr(must_positive_fb(v), 100) AS p, must_positive_fb(v) AS q, "+
+			"coalesce_expr(must_positive_fb(v), id * 10) AS r FROM stream"))
+
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": -5})
+		require.NoError(t, err)
+		assert.EqualValues(t, 100, row["p"], "field fallback wins over config")
+		assert.Equal(t, -1, row["q"], "fields without coalesce_expr use the config fallback")
+		assert.EqualValues(t, 10, row["r"], "fallback is evaluated against the row")
+
+		row, err = s.EmitSync(map[string]any{"id": 3, "v": 7})
+		require.NoError(t, err)
+		assert.Equal(t, 7.0, row["p"])
+	})
+
+	t.Run("without fallback", func(t *testing.T) {
+		s := New()
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT id, must_positive_fb(v) AS p FROM stream"))
+		row, err := s.EmitSync(map[string]any{"id": 1, "v": -1})
+		require.NoError(t, err)
+		assert.Nil(t, row["p"])
+	})
+}
+
+func TestWithWindowHistory(t *testing.T) {
+	s := New(WithWindowHistory(2))
+	defer s.Stop()
+	require.NoError(t, s.Execute("SELECT device, SUM(v) AS total FROM stream GROUP BY device, CountingWindow(1)"))
+
+	// 下游故障期间 sink 丢弃结果；emitted 用于确认每个窗口都已发出
+	var mu sync.Mutex
+	down := true
+	var delivered []any
+	emitted := make(chan struct{}, 8)
+	s.AddSyncSink(func(rows []map[string]any) {
+		mu.Lock()
+		if !down {
+			for _, r := range rows {
+				delivered = append(delivered, r["total"])
+			}
+		}
+		mu.Unlock()
+		emitted <- struct{}{}
+	})
+
+	for _, v := range []int{1, 2, 3} {
+		s.Emit(map[string]any{"device": "a", "v": v})
+		select {
+		case <-emitted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+		}
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	// 仅保留最近 2 个窗口，按发出顺序重放
+	assert.Equal(t, 2, s.ReplayLastWindows(5))
+	mu.Lock()
+	assert.Equal(t, []any{2.0, 3.0}, delivered)
+	delivered = nil
+	mu.Unlock()
+
+	assert.Equal(t, 1, s.ReplayLastWindows(1))
+	mu.Lock()
+	assert.Equal(t, []any{3.0}, delivered)
+	mu.Unlock()
+
+	// 未开启历史时不重放
+	plain := New()
+	defer plain.Stop()
+	require.NoError(t, plain.Execute("SELECT device, SUM(v) AS total FROM stream GROUP BY device, CountingWindow(1)"))
+	assert.Equal(t, 0, plain.ReplayLastWindows(1))
+}
+
+func TestWithPrimaryKey(t *testing.T) {
+	t.Run("迟到重发标记为upsert", func(t *testing.T) {
+		s := New(WithPrimaryKey("device", "window_id"))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT device, COUNT(*) AS cnt FROM stream GROUP BY device, TumblingWindow('2s') WITH (TIMESTAMP='ts', TIMEUNIT='ms', ALLOWEDLATENESS='5s')"))
+		ch := make(chan map[string]any, 8)
+		s.AddSink(func(rows []map[string]any) {
+			for _, r := range rows {
+				if r["device"] == "a" {
+					ch <- r
+				}
+			}
+		})
+		next := func() map[string]any {
+			select {
+			case r := <-ch:
+				return r
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window result")
+				return nil
+			}
+		}
+
+		base := time.Now().UnixMilli()/2000*2000 - 60000
+		s.Emit(map[string]any{"device": "a", "ts": base + 100})
+		s.Emit(map[string]any{"device": "a", "ts": base + 900})
+		s.Emit(map[string]any{"device": "b", "ts": base + 2500}) // 推进水位，触发 [base, base+2s)
+		first := next()
+		assert.Equal(t, 2.0, first["cnt"])
+		assert.Equal(t, false, first[stream.UpsertField])
+
+		s.Emit(map[string]any{"device": "a", "ts": base + 500}) // 迟到数据，在 AllowedLateness 内
+		late := next()
+		assert.Equal(t, 3.0, late["cnt"])
+		assert.Equal(t, true, late[stream.UpsertField])
+		assert.Equal(t, first[stream.PrimaryKeyField], late[stream.PrimaryKeyField])
+		assert.Equal(t, fmt.Sprintf(`["a","%v"]`, first["window_id"]), late[stream.PrimaryKeyField])
+	})
+
+	t.Run("直连查询重复主键", func(t *testing.T) {
+		s := New(WithPrimaryKey("device"))
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT device, v FROM stream"))
+		var rows []map[string]any
+		s.AddSyncSink(func(r []map[string]any) { rows = append(rows, r...) })
+		for _, row := range []map[string]any{{"device": "a", "v": 1}, {"device": "b", "v": 2}, {"device": "a", "v": 3}} {
+			_, err := s.EmitSync(row)
+			require.NoError(t, err)
+		}
+		require.Len(t, rows, 3)
+		assert.Equal(t, []any{false, false, true}, []any{rows[0][stream.UpsertField], rows[1][stream.UpsertField], rows[2][stream.UpsertField]})
+		assert.Equal(t, `["a"]`, rows[2][stream.PrimaryKeyField])
+	})
+
+	t.Run("空列名被拒绝", func(t *testing.T) {
+		s := New(WithPrimaryKey(""))
+		defer s.Stop()
+		assert.Error(t, s.Execute("SELECT device FROM stream"))
+	})
+
+	t.Run("long形态主键组合校验", func(t *testing.T) {
+		const sql = "SELECT device, COUNT(*) AS cnt, SUM(v) AS total FROM stream GROUP BY device, CountingWindow(2)"
+		for _, key := range [][]string{
+			{"device", "window_id"},        // 缺少 metric：同一分组的多个 metric 行主键相同
+			{"device", "metric", "value"},  // value 是度量值而非标识
+			{"device", "metric", "cnt"},    // cnt 已展开为行，不再是输出列
+			{"device", "metric", "device"}, // 重复列
+		} {
+			s := New(WithOutputShape(types.OutputShapeLong), WithPrimaryKey(key...))
+			assert.Error(t, s.Execute(sql), "%v", key)
+			s.Stop()
+		}
+
+		s := New(WithOutputShape(types.OutputShapeLong), WithPrimaryKey("device", "metric"))
+		defer s.Stop()
+		require.NoError(t, s.Execute(sql))
+		ch := make(chan map[string]any, 8)
+		s.AddSyncSink(func(r []map[string]any) {
+			for _, row := range r {
+				ch <- row
+			}
+		})
+		for _, v := range []int{1, 2, 3, 4} {
+			s.Emit(map[string]any{"device": "a", "v": v})
+		}
+		rows := make([]map[string]any, 0, 4)
+		for len(rows) < 4 {
+			select {
+			case r := <-ch:
+				rows = append(rows, r)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window results")
+			}
+		}
+		assert.Equal(t, []any{false, false, true, true}, []any{rows[0][stream.UpsertField], rows[1][stream.UpsertField], rows[2][stream.UpsertField], rows[3][stream.UpsertField]})
+		assert.Equal(t, `["a","cnt"]`, rows[0][stream.PrimaryKeyField])
+		assert.Equal(t, `["a","total"]`, rows[1][stream.PrimaryKeyField])
+	})
+}
@@ -128,12 +128,14 @@ func parseOverFromString(s string, pos int) (*types.OverSpec, int, error) {
 }
 
 var (
-	overPartitionRe = regexp.MustCompile(`(?i)\bpartition\s+by\b\s+(.*?)(?:\bwhen\b|$)`)
-	overWhenRe      = regexp.MustCompile(`(?i)\bwhen\b\s+(.*)$`)
+	overPartitionRe = regexp.MustCompile(`(?i)\bpartition\s+by\b\s+(.*?)(?:\bwhen\b|\border\s+by\b|$)`)
+	overOrderRe     = regexp.MustCompile(`(?i)\border\s+by\b\s+(.*?)(?:\bwhen\b|\bpartition\s+by\b|$)`)
+	overWhenRe      = regexp.MustCompile(`(?i)\bwhen\b\s+(.*?)(?:\border\s+by\b|\bpartition\s+by\b|$)`)
+	overDirRe       = regexp.MustCompile(`(?i)\s+(asc|desc)$`)
 )
 
-// parseOverBodyString 解析 OVER 体（如 "partition by deviceId, region when status > 0"）。
-// 仅支持 PARTITION BY 和 WHEN。
+// parseOverBodyString 解析 OVER 体（如 "partition by deviceId, region order by ts when status > 0"）。
+// 支持 PARTITION BY、ORDER BY 和 WHEN。
 func parseOverBodyString(body string) (*types.OverSpec, error) {
 	spec := &types.OverSpec{}
 	if m := overPartitionRe.FindStringSubmatch(body); m != nil {
@@ -144,6 +146,18 @@ func parseOverBodyString(body string) (*types.OverSpec, error) {
 			}
 		}
 	}
+	if m := overOrderRe.FindStringSubmatch(body); m != nil {
+		for _, f := range strings.Split(m[1], ",") {
+			f = strings.TrimSpace(f)
+			dir := overDirRe.FindStringSubmatch(f)
+			f = overDirRe.ReplaceAllString(f, "")
+			f = strings.TrimSpace(strings.Trim(f, "`"))
+			if f != "" {
+				spec.OrderBy = append(spec.OrderBy, f)
+				spec.OrderDesc = append(spec.OrderDesc, dir != nil && strings.EqualFold(dir[1], "desc"))
+			}
+		}
+	}
 	if m := overWhenRe.FindStringSubmatch(body); m != nil {
 		spec.When = strings.TrimSpace(m[1])
 	}
@@ -151,7 +165,7 @@ func parseOverBodyString(body string) (*types.OverSpec, error) {
 }
 
 func skipSpaces(s string, i int) int {
-	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
 		i++
 	}
 	return i
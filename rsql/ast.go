@@ -3,6 +3,7 @@ package rsql
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 )
 
 type SelectStatement struct {
+	// InsertInto 为 INSERT INTO <sink> 指定的目标具名 sink，空表示无 INSERT INTO。
+	InsertInto  string
 	Fields      []Field
 	Distinct    bool
 	SelectAll   bool // Flag to indicate if this is a SELECT * query
@@ -26,9 +29,13 @@ type SelectStatement struct {
 	Window      WindowDefinition
 	GroupBy     []string
 	Limit       int
+	Offset      int // 跳过的前 N 条结果（OFFSET），在 LIMIT 之前生效
 	Having      string
 	OrderBy     []types.OrderByField
 	JoinConfigs []types.JoinConfig
+	// SelfJoin is a JOIN of the FROM source itself (interval self-join); its
+	// OnPairs keep the alias qualifiers. nil means no self-join.
+	SelfJoin *types.JoinConfig
 	// MatchRecognize 携带 MATCH_RECOGNIZE 子句（FROM 后、WHERE 前）。非空时走 CEP 路径。
 	MatchRecognize *types.MatchRecognizeSpec
 }
@@ -46,11 +53,13 @@ type WindowDefinition struct {
 	TsProp            string
 	TimeUnit          time.Duration
 	MaxOutOfOrderness time.Duration // Maximum allowed out-of-orderness for event time
+	WatermarkInterval time.Duration // Watermark update interval for event time (0 = window default)
 	AllowedLateness   time.Duration // Maximum allowed lateness for event time windows
 	IdleTimeout       time.Duration // Idle source timeout: when no data arrives within this duration, watermark advances based on processing time
 	CountStateTTL     time.Duration // Counting-window keyed state TTL; inactive keys reaped after this (0 = disabled)
 	TriggerCondition  string        // Global-window TRIGGER WHEN predicate (raw string)
 	Over              *types.OverSpec // GROUP BY window OVER(...) 子句（仅 WHEN 输入门控）
+	MissingParam      string          // 未解析的窗口参数名（parseGroupBy 的错误会被吞掉，由 ToStreamConfig 报告）
 }
 
 // ToStreamConfig converts AST to Stream configuration
@@ -66,6 +75,8 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 		windowType = window.TypeTumbling
 	case "SLIDINGWINDOW":
 		windowType = window.TypeSliding
+	case "HOPWINDOW":
+		windowType = window.TypeHop
 	case "COUNTINGWINDOW":
 		windowType = window.TypeCounting
 	case "SESSIONWINDOW":
@@ -79,6 +90,10 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 		}
 	}
 
+	if s.Window.MissingParam != "" {
+		return nil, "", fmt.Errorf("missing parameter %s for %s", s.Window.MissingParam, s.Window.Type)
+	}
+
 	// Parse window parameters - now returns array directly
 	params := s.Window.Params
 
@@ -100,13 +115,35 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 	analyticFields := make([]types.AnalyticField, 0, len(s.Fields))
 	otherFields := make([]Field, 0, len(s.Fields))
 	for _, f := range s.Fields {
+		if err := validateStringAggSeparator(f.Expression); err != nil {
+			return nil, "", err
+		}
+		if err := validateTopK(f.Expression); err != nil {
+			return nil, "", err
+		}
+		if err := validateHistogram(f.Expression); err != nil {
+			return nil, "", err
+		}
+		if err := validateTrimmedMean(f.Expression); err != nil {
+			return nil, "", err
+		}
+		if err := validateValueCounts(f.Expression); err != nil {
+			return nil, "", err
+		}
+		if err := validateNtile(f.Expression); err != nil {
+			return nil, "", err
+		}
 		if isAnalyticField(f) {
 			// 校验分析函数自身的嵌套：分析套分析、聚合套分析均不允许
 			// （分析套聚合在窗口查询里允许，由 extractInlineAggregates 处理）。
 			if err := detectNestedAggregation(f.Expression); err != nil {
 				return nil, "", err
 			}
-			analyticFields = append(analyticFields, buildAnalyticField(f))
+			af := buildAnalyticField(f)
+			if err := validateOverOrderBy(af); err != nil {
+				return nil, "", err
+			}
+			analyticFields = append(analyticFields, af)
 			continue
 		}
 		// 表达式包分析函数：算术（ts-lag(ts)）、标量套（coalesce(lag(temp))、UPPER(lag)）、
@@ -117,7 +154,11 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 			if err := detectNestedAggregation(f.Expression); err != nil {
 				return nil, "", err
 			}
-			analyticFields = append(analyticFields, buildAnalyticField(f))
+			af := buildAnalyticField(f)
+			if err := validateOverOrderBy(af); err != nil {
+				return nil, "", err
+			}
+			analyticFields = append(analyticFields, af)
 			continue
 		}
 		otherFields = append(otherFields, f)
@@ -145,28 +186,31 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 
 	// If no aggregation functions, collect simple fields
 	if !hasAggregation {
-		// If SELECT * query, set special marker
+		// If SELECT * query, set special marker; columns listed next to it
+		// (SELECT *, device.info.name AS n) are still projected
 		if s.SelectAll {
 			simpleFields = append(simpleFields, "*")
-		} else {
-			for _, field := range otherFields {
-				fieldName := field.Expression
-				if field.Alias != "" {
-					// If has alias, use alias as field name
-					simpleFields = append(simpleFields, fieldName+":"+field.Alias)
+		}
+		for _, field := range otherFields {
+			fieldName := field.Expression
+			if s.SelectAll && fieldName == "*" {
+				continue
+			}
+			if field.Alias != "" {
+				// If has alias, use alias as field name
+				simpleFields = append(simpleFields, fieldName+":"+field.Alias)
+			} else {
+				// For fields without alias, check if it's a string literal
+				_, n, _, _, err := ParseAggregateTypeWithExpression(fieldName)
+				if err != nil {
+					return nil, "", err
+				}
+				if n != "" {
+					// If string literal, use parsed field name (remove quotes)
+					simpleFields = append(simpleFields, n)
 				} else {
-					// For fields without alias, check if it's a string literal
-					_, n, _, _, err := ParseAggregateTypeWithExpression(fieldName)
-					if err != nil {
-						return nil, "", err
-					}
-					if n != "" {
-						// If string literal, use parsed field name (remove quotes)
-						simpleFields = append(simpleFields, n)
-					} else {
-						// Otherwise use original expression
-						simpleFields = append(simpleFields, fieldName)
-					}
+					// Otherwise use original expression
+					simpleFields = append(simpleFields, fieldName)
 				}
 			}
 		}
@@ -181,27 +225,30 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 
 	// 窗口查询里的分析函数：把参数中的内联聚合（如 changed_cols 内的 avg(...)））
 	// 提取为隐藏计算字段，重写参数为隐藏键引用，供窗口聚合计算后供分析函数消费。
+	if err := validatePartitionAnalytics(analyticFields, needWindow); err != nil {
+		return nil, "", err
+	}
 	if needWindow && len(analyticFields) > 0 {
+		// 排名函数按到达行编号，窗口输出行没有可编号的原始行序，仅支持非聚合查询。
+		for _, af := range analyticFields {
+			if isRankingFunction(af.FuncName) {
+				return nil, "", fmt.Errorf("ranking function %s() is not supported in GROUP BY window queries; use it with OVER in a non-aggregation query", af.FuncName)
+			}
+		}
 		extractInlineAggregates(analyticFields, aggs, fields)
 		// 分析函数默认按 GROUP BY 键分区：跨窗口为每个分组各自保留状态，
 		// 避免不同分组的窗口输出共享状态而串扰。
 		gk := extractGroupFields(s)
-		if len(gk) > 0 {
-			for i := range analyticFields {
-				af := &analyticFields[i]
-				if af.Over == nil {
-					af.Over = &types.OverSpec{}
-				}
-				if len(af.Over.PartitionBy) == 0 {
-					af.Over.PartitionBy = append([]string(nil), gk...)
-				}
-			}
-		}
+		partitionByGroupKeys(analyticFields, gk, true)
 		// 校验：窗口查询里分析函数的参数必须引用窗口输出字段（聚合或 GROUP BY 键），
 		// 不能引用裸原始列——否则求值时取不到值，会静默得到列名字符串而非结果。
 		if err := validateWindowAnalyticArgs(analyticFields, gk); err != nil {
 			return nil, "", err
 		}
+	} else if !needWindow && s.MatchRecognize == nil {
+		// 非窗口 GROUP BY：分组键同样是分析函数的默认分区，latest(x) 等按键维护
+		// 最新状态，每条输入输出其所在分组的更新行（见 Config.StateFlushInterval）。
+		partitionByGroupKeys(analyticFields, extractGroupFields(s), false)
 	}
 
 	// Extract field order information
@@ -255,7 +302,7 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 		if needWindow {
 			return nil, "", fmt.Errorf("MATCH_RECOGNIZE cannot be combined with GROUP BY/aggregation yet")
 		}
-		if len(s.JoinConfigs) > 0 {
+		if len(s.JoinConfigs) > 0 || s.SelfJoin != nil {
 			return nil, "", fmt.Errorf("MATCH_RECOGNIZE with JOIN is not supported yet")
 		}
 		if s.MatchRecognize.Pattern == nil {
@@ -282,6 +329,7 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 			TimeUnit:           s.Window.TimeUnit,
 			TimeCharacteristic: timeCharacteristic,
 			MaxOutOfOrderness:  s.Window.MaxOutOfOrderness,
+			WatermarkInterval:  s.Window.WatermarkInterval,
 			AllowedLateness:    s.Window.AllowedLateness,
 			IdleTimeout:        s.Window.IdleTimeout,
 			CountStateTTL:      s.Window.CountStateTTL,
@@ -297,6 +345,7 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 		SelectAlias:        selectAlias,
 		Distinct:           s.Distinct,
 		Limit:              s.Limit,
+		Offset:             s.Offset,
 		NeedWindow:         needWindow,
 		Mode:               mode,
 		MatchRecognize:     s.MatchRecognize,
@@ -306,9 +355,17 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 		FieldExpressions:   expressions,
 		PostAggExpressions: postAggExpressions,
 		FieldOrder:         fieldOrder,
-		OrderBy:            s.OrderBy,
+		OrderBy:            resolveOrderByFields(s.OrderBy, s.Fields),
 		JoinConfigs:        s.JoinConfigs,
 		SourceAlias:        s.SourceAlias,
+		InsertInto:         s.InsertInto,
+	}
+	if s.SelfJoin != nil {
+		ij, err := buildIntervalJoin(s)
+		if err != nil {
+			return nil, "", err
+		}
+		config.IntervalJoin = ij
 	}
 
 	// 提取 WHERE 中的分析函数调用（含 OVER），替换为占位符，供直连路径状态机求值。
@@ -316,11 +373,96 @@ func (s *SelectStatement) ToStreamConfig() (*types.Config, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	for _, c := range whereCalls {
+		if isPartitionFunction(c.FuncName) {
+			return nil, "", fmt.Errorf("%s() cannot be used in WHERE: it needs the whole partition, which is only known for window results", c.FuncName)
+		}
+		if err := validateOverOrderBy(types.AnalyticField{FuncName: c.FuncName, Expression: c.Expression, Over: c.Over}); err != nil {
+			return nil, "", err
+		}
+	}
 	config.WhereAnalyticCalls = whereCalls
 
 	return &config, rewrittenCondition, nil
 }
 
+// validateOverOrderBy 校验 OVER (... ORDER BY ...) 只用于排名函数（row_number/rank/dense_rank）
+// 和整分区函数（ntile）。
+// 流式求值按到达顺序推进、不重排数据，lag/latest 等带 ORDER BY 会被误读为按序求值，解析期拒绝。
+func validateOverOrderBy(af types.AnalyticField) error {
+	if af.Over == nil || len(af.Over.OrderBy) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(af.Calls)+1)
+	for _, c := range af.Calls {
+		names = append(names, c.FuncName)
+	}
+	if len(names) == 0 {
+		names = append(names, af.FuncName)
+	}
+	for _, name := range names {
+		if _, ok := functions.Get(strings.ToLower(name)); !ok || isRankingFunction(name) || isPartitionFunction(name) {
+			continue
+		}
+		return fmt.Errorf("OVER (ORDER BY ...) is only supported for ranking functions (row_number, rank, dense_rank, ntile), got %s", name)
+	}
+	return nil
+}
+
+// isPartitionFunction 判断是否为整分区分析函数（函数实现 PartitionAnalytic，如 ntile）。
+func isPartitionFunction(name string) bool {
+	fn, ok := functions.Get(strings.ToLower(name))
+	if !ok {
+		return false
+	}
+	_, ok = fn.(functions.PartitionAnalytic)
+	return ok
+}
+
+// isWindowScopedFunction 函数状态是否随查询窗口重置（见 functions.WindowScopedAnalytic）。
+func isWindowScopedFunction(name string) bool {
+	fn, ok := functions.Get(strings.ToLower(name))
+	if !ok {
+		return false
+	}
+	ws, ok := fn.(functions.WindowScopedAnalytic)
+	return ok && ws.WindowScoped()
+}
+
+// validatePartitionAnalytics 校验整分区分析函数（ntile）：只能用于窗口查询（分区取每次
+// 窗口产出的结果行），且须为独立字段、不带 WHEN，求值时不经逐条状态机。
+func validatePartitionAnalytics(analyticFields []types.AnalyticField, needWindow bool) error {
+	for _, af := range analyticFields {
+		if !isPartitionFunction(af.FuncName) {
+			continue
+		}
+		if !needWindow {
+			return fmt.Errorf("%s() requires a window query: buckets are assigned over each window's result rows", af.FuncName)
+		}
+		if af.WrapperExpr != "" || len(af.Calls) > 1 {
+			return fmt.Errorf("%s() must be a standalone SELECT field, not part of an expression", af.FuncName)
+		}
+		if af.Over != nil && strings.TrimSpace(af.Over.When) != "" {
+			return fmt.Errorf("%s() does not support OVER (WHEN ...)", af.FuncName)
+		}
+	}
+	return nil
+}
+
+// isRankingFunction 判断是否为排名类分析函数（状态实现 OrderedAnalyticState）。
+func isRankingFunction(name string) bool {
+	fn, ok := functions.Get(strings.ToLower(name))
+	if !ok {
+		return false
+	}
+	sf, ok := fn.(functions.StatefulAnalytic)
+	if !ok {
+		return false
+	}
+	_, ordered := sf.NewState().(functions.OrderedAnalyticState)
+	return ordered
+}
+
 // isAnalyticField 判断 Field 是否为分析函数（TypeAnalytical）。
 func isAnalyticField(f Field) bool {
 	funcName := extractFunctionName(f.Expression)
@@ -404,6 +546,28 @@ func buildAnalyticField(f Field) types.AnalyticField {
 	return af
 }
 
+// partitionByGroupKeys 让未指定 PARTITION BY 的分析函数按 GROUP BY 键分区，
+// 每个分组各自保留状态。整分区函数（ntile）在单次结果行集上求值，按分组键分区
+// 会让每个分区只剩一行，故跳过；窗口查询里的窗口级函数（window_position/is_duplicate）
+// 同理只在本窗口结果行内求值，也跳过。
+func partitionByGroupKeys(analyticFields []types.AnalyticField, groupKeys []string, windowed bool) {
+	if len(groupKeys) == 0 {
+		return
+	}
+	for i := range analyticFields {
+		af := &analyticFields[i]
+		if isPartitionFunction(af.FuncName) || (windowed && isWindowScopedFunction(af.FuncName)) {
+			continue
+		}
+		if af.Over == nil {
+			af.Over = &types.OverSpec{}
+		}
+		if len(af.Over.PartitionBy) == 0 {
+			af.Over.PartitionBy = append([]string(nil), groupKeys...)
+		}
+	}
+}
+
 // splitAnalyticExprMulti 抽出表达式里的全部 Analytic 调用（按出现顺序），各调用子串替换为
 // types.AnalyticSelfTokenN(i) 占位构成 wrapper。纯单调用且覆盖整式时 wrapper=""（纯分析字段语义）；
 // 否则 wrapper 含占位。不含分析调用时返回 (nil, "")。
@@ -527,7 +691,7 @@ func extractInlineAggregates(analyticFields []types.AnalyticField, aggs map[stri
 	}
 }
 
-// collapseSpacesOutsideQuotes 去掉引号外的空白，引号内（字符串字面量）保留原样。
+// collapseSpacesOutsideQuotes 去掉引号外的空白，引号内（字符串字面量、反引号标识符）保留原样。
 // 用于归一化 HAVING 里带空格的聚合调用文本（parser 存为 "max ( v )"），便于复用解析函数。
 func collapseSpacesOutsideQuotes(s string) string {
 	var b strings.Builder
@@ -535,7 +699,7 @@ func collapseSpacesOutsideQuotes(s string) string {
 	inQuote := byte(0)
 	for i := 0; i < len(s); i++ {
 		c := s[i]
-		if c == '\'' || c == '"' {
+		if c == '\'' || c == '"' || c == '`' {
 			if inQuote == c {
 				inQuote = 0
 			} else if inQuote == 0 {
@@ -552,6 +716,26 @@ func collapseSpacesOutsideQuotes(s string) string {
 	return b.String()
 }
 
+// isCaseExpr 判断表达式是否为完整的 CASE ... END 表达式（不区分大小写）。
+func isCaseExpr(expr string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(expr))
+	return strings.HasPrefix(upper, "CASE ") && strings.HasSuffix(upper, " END")
+}
+
+// canonicalTokenText 以单个空格连接表达式的词法 token，使同一表达式在 SELECT 项与
+// GROUP BY 项中的不同空白写法（如 "t>30" 与 "t > 30"）得到相同文本。
+func canonicalTokenText(expr string) string {
+	lexer := NewLexer(expr)
+	var b strings.Builder
+	for tok := lexer.NextToken(); tok.Type != TokenEOF; tok = lexer.NextToken() {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok.Value)
+	}
+	return b.String()
+}
+
 // extractHavingAggregates 处理 HAVING 引用的聚合（标准 SQL：HAVING 可引用任意聚合，不必在 SELECT）。
 // 对 HAVING 文本里每个聚合调用 ac：
 //   - selectAlias[ac] 命中（SELECT 里 ac AS alias）→ 改写 HAVING 里 ac 为 alias（聚合已在算）。
@@ -682,6 +866,143 @@ func splitCallArgs(expr string) []string {
 	return splitTopLevelCommas(body)
 }
 
+// validateStringAggSeparator 校验 string_agg(value, separator) 的分隔符为字符串常量。
+// 聚合器只在创建时读取一次分隔符，列名或表达式会被当成字面文本静默拼接，故在解析期拒绝。
+func validateStringAggSeparator(expr string) error {
+	return forEachCall(expr, "string_agg", func(args []string) error {
+		if len(args) == 2 && !isQuotedString(args[1]) {
+			return fmt.Errorf("string_agg separator must be a constant string, got %s", args[1])
+		}
+		return nil
+	})
+}
+
+// validateTopK 校验 top_k(value, k) 的 k 为正整数常量：聚合器只在创建时读取一次 k。
+func validateTopK(expr string) error {
+	return forEachCall(expr, "top_k", func(args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("top_k requires 2 arguments (value, k), got %d", len(args))
+		}
+		if k, err := strconv.Atoi(args[1]); err != nil || k <= 0 {
+			return fmt.Errorf("top_k k must be a constant positive integer, got %s", args[1])
+		}
+		return nil
+	})
+}
+
+// validateHistogram 校验 histogram(value, min, max, buckets) 的参数为数值常量，
+// min < max 且桶数为正整数：聚合器只在创建时读取一次这些参数。
+func validateHistogram(expr string) error {
+	return forEachCall(expr, "histogram", func(args []string) error {
+		if len(args) != 4 {
+			return fmt.Errorf("histogram requires 4 arguments (value, min, max, buckets), got %d", len(args))
+		}
+		lo, err1 := strconv.ParseFloat(args[1], 64)
+		hi, err2 := strconv.ParseFloat(args[2], 64)
+		if err1 != nil || err2 != nil || !(lo < hi) {
+			return fmt.Errorf("histogram min and max must be numeric constants with min < max, got %s, %s", args[1], args[2])
+		}
+		if n, err := strconv.Atoi(args[3]); err != nil || n <= 0 {
+			return fmt.Errorf("histogram bucket count must be a constant positive integer, got %s", args[3])
+		}
+		return nil
+	})
+}
+
+// validateTrimmedMean 校验 trimmed_mean(value, fraction) 的 fraction 为 [0, 0.5) 内的
+// 数值常量：聚合器只在创建时读取一次 fraction，运行期出错只会让每个窗口都得到 NULL。
+func validateTrimmedMean(expr string) error {
+	return forEachCall(expr, "trimmed_mean", func(args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("trimmed_mean requires 2 arguments (value, fraction), got %d", len(args))
+		}
+		if frac, err := strconv.ParseFloat(args[1], 64); err != nil || frac < 0 || frac >= 0.5 {
+			return fmt.Errorf("trimmed_mean fraction must be a numeric constant in [0, 0.5), got %s", args[1])
+		}
+		return nil
+	})
+}
+
+// validateValueCounts 校验 value_counts(field[, topN]) 的 topN 为正整数常量：聚合器只在
+// 创建时读取一次 topN，运行期出错会静默忽略上限、返回完整分布。
+func validateValueCounts(expr string) error {
+	return forEachCall(expr, "value_counts", func(args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("value_counts requires 1 or 2 arguments (field[, topN]), got %d", len(args))
+		}
+		if len(args) == 2 {
+			if n, err := strconv.Atoi(args[1]); err != nil || n <= 0 {
+				return fmt.Errorf("value_counts topN must be a constant positive integer, got %s", args[1])
+			}
+		}
+		return nil
+	})
+}
+
+// validateNtile 校验 ntile(n) 的桶数为正整数常量：运行期无效的 n 会让每一行都得到 NULL。
+func validateNtile(expr string) error {
+	return forEachCall(expr, "ntile", func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("ntile requires 1 argument (bucket count), got %d", len(args))
+		}
+		if n, err := strconv.Atoi(args[0]); err != nil || n <= 0 {
+			return fmt.Errorf("ntile bucket count must be a constant positive integer, got %s", args[0])
+		}
+		return nil
+	})
+}
+
+// forEachCall 对 expr 中每个 name(...) 调用（不区分大小写，忽略字符串字面量内的文本）
+// 以其顶层参数片段调用 check，返回第一个错误。
+func forEachCall(expr, name string, check func(args []string) error) error {
+	// 把字符串字面量（含引号）替换为空格：偏移不变，字面量里的括号与函数名不参与匹配
+	masked := []byte(strings.ToLower(expr))
+	var quote byte
+	for i, c := range masked {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			masked[i] = ' '
+		case c == '\'' || c == '"':
+			quote = c
+			masked[i] = ' '
+		}
+	}
+	lower := string(masked)
+	for from := 0; ; {
+		idx := strings.Index(lower[from:], name)
+		if idx < 0 {
+			return nil
+		}
+		start := from + idx
+		from = start + len(name)
+		if start > 0 && (isLetter(lower[start-1]) || isDigit(lower[start-1])) {
+			continue
+		}
+		open := from
+		for open < len(lower) && lower[open] == ' ' {
+			open++
+		}
+		if open >= len(lower) || lower[open] != '(' {
+			continue
+		}
+		closeIdx := findMatchingParenInternal(lower, open)
+		if closeIdx < 0 {
+			continue
+		}
+		if err := check(splitTopLevelCommas(expr[open+1 : closeIdx])); err != nil {
+			return err
+		}
+	}
+}
+
+// isQuotedString 判断是否为单引号或双引号包围的字符串字面量。
+func isQuotedString(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
 // splitTopLevelCommas 按顶层逗号拆分，忽略嵌套括号与字符串字面量内的逗号。
 func splitTopLevelCommas(s string) []string {
 	var args []string
@@ -805,29 +1126,89 @@ func extractFieldOrder(fields []Field) ([]string, error) {
 func extractGroupFields(s *SelectStatement) []string {
 	var fields []string
 	for _, f := range s.GroupBy {
-		// 保留裸列与标量函数表达式（如 upper(device)）；只排除聚合函数当分组键（无意义）。
-		if isAggregationFunction(f) {
+		// 保留裸列、标量函数表达式（如 upper(device)）与 CASE 表达式；只排除聚合函数
+		// 当分组键（无意义）。
+		if !isCaseExpr(f) && isAggregationFunction(f) {
 			continue
 		}
+		// 带引号的单个标识符（`device id`，或词法分析改写后的 "device id"）按字段名分组
+		if len(f) > 2 && f[0] == '`' && f[len(f)-1] == '`' && !strings.Contains(f[1:len(f)-1], "`") {
+			f = f[1 : len(f)-1]
+		}
 		fields = append(fields, f)
 	}
 	return fields
 }
 
-// buildSelectAliasMap maps each SELECT item's raw expression to its AS alias.
-// Items without an alias are omitted. Used by the aggregation path to name
-// output columns for grouped non-aggregate columns (e.g. "m.location AS loc"),
-// matching the direct path.
+// buildSelectAliasMap maps each SELECT item's raw expression, and its form as
+// GROUP BY keys are spelled (spaces removed, or single-spaced tokens for CASE
+// expressions), to its AS alias. Items without an alias are omitted. Used by
+// the aggregation path to name output columns for grouped non-aggregate
+// columns (e.g. "m.location AS loc"), matching the direct path.
 func buildSelectAliasMap(fields []Field) map[string]string {
 	m := make(map[string]string, len(fields))
 	for _, f := range fields {
 		if f.Alias != "" {
 			m[f.Expression] = f.Alias
+			// GROUP BY keys are stored without spaces (e.g. "round(lat,0)")
+			c := collapseSpacesOutsideQuotes(f.Expression)
+			if isCaseExpr(f.Expression) {
+				c = canonicalTokenText(f.Expression)
+			}
+			if c != f.Expression {
+				if _, taken := m[c]; !taken {
+					m[c] = f.Alias
+				}
+			}
 		}
 	}
 	return m
 }
 
+// resolveOrderByFields 把 ORDER BY 里与 SELECT 列相同的键（如 avg(temperature)，或别名
+// 的另一种大小写）改写为该列的输出名（alias，无 alias 时为表达式本身），使排序能在结果行里
+// 取到值。与别名、表达式的比较不区分大小写，大小写完全一致的列优先；比较忽略引号外空格：
+// ORDER BY 键由 token 拼接而成，不含空格。
+func resolveOrderByFields(orderBy []types.OrderByField, fields []Field) []types.OrderByField {
+	if len(orderBy) == 0 {
+		return orderBy
+	}
+	out := make([]types.OrderByField, len(orderBy))
+	for i, ob := range orderBy {
+		out[i] = ob
+		key := collapseSpacesOutsideQuotes(ob.Expression)
+		if name, ok := matchOrderByField(key, fields, false); ok {
+			out[i].Expression = name
+		} else if name, ok := matchOrderByField(key, fields, true); ok {
+			out[i].Expression = name
+		}
+	}
+	return out
+}
+
+// matchOrderByField 返回别名或表达式与 key 相同的 SELECT 列的输出名；fold 时不区分大小写。
+func matchOrderByField(key string, fields []Field, fold bool) (string, bool) {
+	same := func(a string) bool {
+		if fold {
+			return strings.EqualFold(a, key)
+		}
+		return a == key
+	}
+	for _, f := range fields {
+		if f.Expression == "" {
+			continue
+		}
+		name := f.Expression
+		if f.Alias != "" {
+			name = f.Alias
+		}
+		if (f.Alias != "" && same(f.Alias)) || same(collapseSpacesOutsideQuotes(f.Expression)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 func buildSelectFields(fields []Field) (aggMap map[string]aggregator.AggregateType, fieldMap map[string]string, err error) {
 	selectFields := make(map[string]aggregator.AggregateType)
 	fieldMap = make(map[string]string)
@@ -1307,6 +1688,28 @@ func validateWindowParams(params []any, windowType string) ([]any, error) {
 
 	validated := make([]any, 0, len(params))
 
+	// Helper function to convert a value to time.Duration
+	// For numeric types, treats them as seconds
+	// For strings, uses time.ParseDuration
+	convertToDuration := func(val any) (time.Duration, error) {
+		switch v := val.(type) {
+		case time.Duration:
+			return v, nil
+		case string:
+			// Use ToDurationE which handles string parsing
+			return cast.ToDurationE(v)
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			// Treat numeric integers as seconds
+			return time.Duration(cast.ToInt(v)) * time.Second, nil
+		case float32, float64:
+			// Treat numeric floats as seconds
+			return time.Duration(int(cast.ToFloat64(v))) * time.Second, nil
+		default:
+			// Try ToDurationE as fallback
+			return cast.ToDurationE(v)
+		}
+	}
+
 	if windowType == window.TypeCounting {
 		// CountingWindow expects integer count as first parameter
 		if len(params) == 0 {
@@ -1325,42 +1728,42 @@ func validateWindowParams(params []any, windowType string) ([]any, error) {
 
 		validated = append(validated, count)
 
-		// Add any additional parameters
+		// CountingWindow(count, timeout): optional time limit for a partial window
 		if len(params) > 1 {
-			validated = append(validated, params[1:]...)
+			timeout, err := convertToDuration(params[1])
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("counting window timeout must be a positive duration, got: %v", params[1])
+			}
+			validated = append(validated, timeout)
+			validated = append(validated, params[2:]...)
 		}
 
 		return validated, nil
 	}
 
-	// Helper function to convert a value to time.Duration
-	// For numeric types, treats them as seconds
-	// For strings, uses time.ParseDuration
-	convertToDuration := func(val any) (time.Duration, error) {
-		switch v := val.(type) {
-		case time.Duration:
-			return v, nil
-		case string:
-			// Use ToDurationE which handles string parsing
-			return cast.ToDurationE(v)
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-			// Treat numeric integers as seconds
-			return time.Duration(cast.ToInt(v)) * time.Second, nil
-		case float32, float64:
-			// Treat numeric floats as seconds
-			return time.Duration(int(cast.ToFloat64(v))) * time.Second, nil
-		default:
-			// Try ToDurationE as fallback
-			return cast.ToDurationE(v)
-		}
-	}
-
 	if windowType == window.TypeSession {
 		// SessionWindow expects timeout duration as first parameter
 		if len(params) == 0 {
 			return nil, fmt.Errorf("session window requires at least one parameter")
 		}
 
+		if gapExpr, isGap := params[0].(window.SessionGap); isGap {
+			// SessionWindow(gap_expr[, default]): gap resolved per row from an
+			// unquoted field or expression; the window compiles and checks it.
+			if _, err := expr.NewExpression(string(gapExpr)); err != nil {
+				return nil, fmt.Errorf("invalid session gap expression %q: %w", gapExpr, err)
+			}
+			validated = append(validated, gapExpr)
+			if len(params) > 1 {
+				def, err := convertToDuration(params[1])
+				if err != nil || def <= 0 {
+					return nil, fmt.Errorf("session window default gap must be a positive duration, got: %v", params[1])
+				}
+				validated = append(validated, def)
+				validated = append(validated, params[2:]...)
+			}
+			return validated, nil
+		}
 		timeout, err := convertToDuration(params[0])
 		if err != nil {
 			return nil, fmt.Errorf("invalid timeout duration: %w", err)
@@ -1472,9 +1875,10 @@ func buildSelectFieldsWithExpressions(fields []Field) (
 			return nil, nil, nil, nil, parseErr
 		}
 		if t != "" {
-			// Check if this is a multi-parameter function that needs special handling
+			// Check if this is a multi-parameter aggregate that needs special handling;
+			// scalar functions (e.g. round(x, 2) as a GROUP BY key) stay expressions
 			isMultiParamFunction := false
-			if expression != "" && strings.Contains(expression, ",") {
+			if t != "expression" && expression != "" && strings.Contains(expression, ",") {
 				// Check if the function needs multi-parameter handling
 				funcName := extractFunctionName(f.Expression)
 				if fn, exists := functions.Get(funcName); exists {
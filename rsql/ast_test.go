@@ -1,6 +1,7 @@
 package rsql
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -62,6 +63,30 @@ func TestSelectStatement_ToStreamConfig(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "SELECT * 与带别名的嵌套字段",
+			stmt: &SelectStatement{
+				SelectAll: true,
+				Fields: []Field{
+					{Expression: "*"},
+					{Expression: "device.info.name", Alias: "n"},
+					{Expression: "device.location"},
+				},
+				Source: "sensor_data",
+			},
+			wantErr: false,
+			checkFunc: func(t *testing.T, stmt *SelectStatement) {
+				config, _, err := stmt.ToStreamConfig()
+				if err != nil {
+					t.Errorf("ToStreamConfig() error = %v", err)
+					return
+				}
+				want := []string{"*", "device.info.name:n", "device.location"}
+				if !reflect.DeepEqual(config.SimpleFields, want) {
+					t.Errorf("Expected SimpleFields %v, got %v", want, config.SimpleFields)
+				}
+			},
+		},
 		{
 			name: "带聚合函数的语句",
 			stmt: &SelectStatement{
@@ -692,3 +717,126 @@ func TestExtractAggFieldWithExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateStringAggSeparator(t *testing.T) {
+	valid := []string{
+		"string_agg(name)",
+		"string_agg(name, ',')",
+		`STRING_AGG(name, " | ")`,
+		"string_agg(name, ')')",
+		"upper(string_agg(concat(a, b), ';'))",
+		"my_string_agg(name, sep)",
+	}
+	for _, expr := range valid {
+		if err := validateStringAggSeparator(expr); err != nil {
+			t.Errorf("validateStringAggSeparator(%q) = %v, want nil", expr, err)
+		}
+	}
+	invalid := []string{
+		"string_agg(name, sep)",
+		"string_agg(name, 1)",
+		"upper(string_agg(name, concat('a', 'b')))",
+	}
+	for _, expr := range invalid {
+		if err := validateStringAggSeparator(expr); err == nil {
+			t.Errorf("validateStringAggSeparator(%q) = nil, want error", expr)
+		}
+	}
+}
+
+func TestValidateTopK(t *testing.T) {
+	for _, expr := range []string{"top_k(v, 3)", "TOP_K(v,1)", "my_top_k(v, n)", "upper('top_k(v, 0)')"} {
+		if err := validateTopK(expr); err != nil {
+			t.Errorf("validateTopK(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"top_k(v)", "top_k(v, 0)", "top_k(v, -2)", "top_k(v, 2.5)", "top_k(v, n)", "top_k(v, '3')"} {
+		if err := validateTopK(expr); err == nil {
+			t.Errorf("validateTopK(%q) = nil, want error", expr)
+		}
+	}
+}
+
+func TestValidateHistogram(t *testing.T) {
+	for _, expr := range []string{"histogram(ms, 0, 100, 10)", "HISTOGRAM(ms, -1.5, 2.5, 4)", "my_histogram(ms, x)"} {
+		if err := validateHistogram(expr); err != nil {
+			t.Errorf("validateHistogram(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"histogram(ms)", "histogram(ms, 100, 0, 10)", "histogram(ms, 0, 0, 10)",
+		"histogram(ms, 0, 100, 0)", "histogram(ms, 0, 100, 2.5)", "histogram(ms, lo, 100, 10)"} {
+		if err := validateHistogram(expr); err == nil {
+			t.Errorf("validateHistogram(%q) = nil, want error", expr)
+		}
+	}
+}
+
+func TestValidateTrimmedMean(t *testing.T) {
+	for _, expr := range []string{"trimmed_mean(v, 0.1)", "TRIMMED_MEAN(v,0)", "trimmed_mean(v, 0.49)", "my_trimmed_mean(v, x)"} {
+		if err := validateTrimmedMean(expr); err != nil {
+			t.Errorf("validateTrimmedMean(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"trimmed_mean(v)", "trimmed_mean(v, 0.5)", "trimmed_mean(v, 0.7)", "trimmed_mean(v, -0.1)",
+		"trimmed_mean(v, f)", "trimmed_mean(v, '0.1')"} {
+		if err := validateTrimmedMean(expr); err == nil {
+			t.Errorf("validateTrimmedMean(%q) = nil, want error", expr)
+		}
+	}
+}
+
+func TestValidateValueCounts(t *testing.T) {
+	for _, expr := range []string{"value_counts(c)", "value_counts(c, 3)", "VALUE_COUNTS(c,1)", "my_value_counts(c, n)"} {
+		if err := validateValueCounts(expr); err != nil {
+			t.Errorf("validateValueCounts(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"value_counts(c, 0)", "value_counts(c, -1)", "value_counts(c, 1.5)", "value_counts(c, n)", "value_counts(c, '2')"} {
+		if err := validateValueCounts(expr); err == nil {
+			t.Errorf("validateValueCounts(%q) = nil, want error", expr)
+		}
+	}
+}
+
+func TestValidateNtile(t *testing.T) {
+	for _, expr := range []string{"ntile(4)", "NTILE(1)", "my_ntile(n)"} {
+		if err := validateNtile(expr); err != nil {
+			t.Errorf("validateNtile(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"ntile()", "ntile(0)", "ntile(-3)", "ntile(2.5)", "ntile(n)", "ntile('4')", "ntile(4, 1)"} {
+		if err := validateNtile(expr); err == nil {
+			t.Errorf("validateNtile(%q) = nil, want error", expr)
+		}
+	}
+}
+
+// TestHopWindowConfig 验证 HopWindow 解析为 hop 类型，参数与 SlidingWindow 一致
+func TestHopWindowConfig(t *testing.T) {
+	hop, err := NewParser("SELECT deviceId, COUNT(*) AS cnt FROM stream GROUP BY deviceId, HopWindow('1m', '10s')").Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	hopConfig, _, err := hop.ToStreamConfig()
+	if err != nil {
+		t.Fatalf("ToStreamConfig() error = %v", err)
+	}
+	if hopConfig.WindowConfig.Type != window.TypeHop {
+		t.Errorf("Expected hop window, got %v", hopConfig.WindowConfig.Type)
+	}
+
+	sliding, err := NewParser("SELECT deviceId, COUNT(*) AS cnt FROM stream GROUP BY deviceId, SlidingWindow('1m', '10s')").Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	slidingConfig, _, err := sliding.ToStreamConfig()
+	if err != nil {
+		t.Fatalf("ToStreamConfig() error = %v", err)
+	}
+	if !reflect.DeepEqual(hopConfig.WindowConfig.Params, slidingConfig.WindowConfig.Params) {
+		t.Errorf("Expected params %v, got %v", slidingConfig.WindowConfig.Params, hopConfig.WindowConfig.Params)
+	}
+	if !reflect.DeepEqual(hopConfig.WindowConfig.GroupByKeys, []string{"deviceId"}) {
+		t.Errorf("Expected GroupByKeys [deviceId], got %v", hopConfig.WindowConfig.GroupByKeys)
+	}
+}
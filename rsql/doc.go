@@ -47,7 +47,9 @@ Standard SQL clauses with streaming extensions:
 	// Window functions
 	TumblingWindow('5s')           - Non-overlapping time windows
 	SlidingWindow('30s', '10s')    - Overlapping time windows
+	HopWindow('30s', '10s')        - Alias of SlidingWindow with window type "hop"
 	CountingWindow(100)            - Count-based windows
+	CountingWindow(100, '30s')     - Count-based windows with a time limit
 	SessionWindow('5m')            - Session-based windows
 
 # Lexical Analysis
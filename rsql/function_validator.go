@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/rulego/streamsql/condition"
 	"github.com/rulego/streamsql/functions"
 )
 
@@ -21,10 +22,23 @@ func NewFunctionValidator(errorRecovery *ErrorRecovery) *FunctionValidator {
 
 // ValidateExpression validates functions within expressions
 func (fv *FunctionValidator) ValidateExpression(expression string, position int) {
+	fv.validate(expression, position, false)
+}
+
+// ValidateCondition validates functions within a WHERE/HAVING condition, which
+// may additionally call functions registered via condition.RegisterConditionFunction.
+func (fv *FunctionValidator) ValidateCondition(expression string, position int) {
+	fv.validate(expression, position, true)
+}
+
+func (fv *FunctionValidator) validate(expression string, position int, isCondition bool) {
 	functionCalls := fv.extractFunctionCalls(expression)
 
 	for _, funcCall := range functionCalls {
 		funcName := funcCall.Name
+		if isCondition && condition.IsConditionFunction(funcName) {
+			continue
+		}
 
 		// Check if function exists in registry
 		if _, exists := functions.Get(funcName); !exists {
@@ -88,8 +102,8 @@ func (fv *FunctionValidator) isBuiltinFunction(funcName string) bool {
 func (fv *FunctionValidator) isKeyword(word string) bool {
 	keywords := []string{
 		"SELECT", "FROM", "WHERE", "GROUP", "BY", "HAVING", "ORDER",
-		"AS", "DISTINCT", "LIMIT", "WITH", "TIMESTAMP", "TIMEUNIT", "MAXOUTOFORDERNESS", "ALLOWEDLATENESS", "IDLETIMEOUT", "STATETTL",
-		"TUMBLINGWINDOW", "SLIDINGWINDOW", "COUNTINGWINDOW", "SESSIONWINDOW",
+		"AS", "DISTINCT", "LIMIT", "OFFSET", "WITH", "TIMESTAMP", "TIMEUNIT", "MAXOUTOFORDERNESS", "ALLOWEDLATENESS", "IDLETIMEOUT", "STATETTL",
+		"TUMBLINGWINDOW", "SLIDINGWINDOW", "HOPWINDOW", "COUNTINGWINDOW", "SESSIONWINDOW",
 		"AND", "OR", "NOT", "IN", "LIKE", "IS", "NULL", "TRUE", "FALSE",
 		"BETWEEN", "IS", "NULL", "TRUE", "FALSE", "CASE", "WHEN",
 		"THEN", "ELSE", "END", "IF", "CAST", "CONVERT",
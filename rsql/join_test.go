@@ -1,6 +1,7 @@
 package rsql
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/rulego/streamsql/types"
@@ -91,3 +92,58 @@ func TestParseDefaultAliasAndBareJoin(t *testing.T) {
 		t.Errorf("bare join type = %q, want INNER", jc.JoinType)
 	}
 }
+
+func TestParseSelfJoin(t *testing.T) {
+	cfg, cond, err := Parse("SELECT a.deviceId FROM stream a JOIN stream b ON b.deviceId = a.deviceId WHERE a.type = 'start' AND a.ts - b.ts BETWEEN -5000 AND -1")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cfg.JoinConfigs) != 0 {
+		t.Errorf("self-join parsed as table join: %+v", cfg.JoinConfigs)
+	}
+	want := types.IntervalJoinConfig{
+		LeftAlias:  "a",
+		RightAlias: "b",
+		Keys:       []types.IntervalJoinKey{{Left: "deviceId", Right: "deviceId"}},
+		TimeField:  "ts",
+		Lower:      1,
+		Upper:      5000,
+	}
+	if cfg.IntervalJoin == nil || !reflect.DeepEqual(*cfg.IntervalJoin, want) {
+		t.Errorf("interval join = %+v, want %+v", cfg.IntervalJoin, want)
+	}
+	if cond == "" {
+		t.Error("WHERE condition lost after self-join")
+	}
+
+	for _, where := range []string{
+		"(b.ts - a.ts BETWEEN 0 AND 10)",
+		"((b.ts - a.ts BETWEEN 0 AND 10))",
+		"a.type = 'start' AND (b.ts - a.ts BETWEEN 0 AND 10 AND b.v > 1)",
+		"(a.type = 'start' AND (b.ts - a.ts BETWEEN 0 AND 10))",
+	} {
+		cfg, _, err := Parse("SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE " + where)
+		if err != nil {
+			t.Errorf("Parse(WHERE %s): %v", where, err)
+			continue
+		}
+		if ij := cfg.IntervalJoin; ij == nil || ij.Lower != 0 || ij.Upper != 10 || ij.TimeField != "ts" {
+			t.Errorf("Parse(WHERE %s): interval join = %+v", where, ij)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE (b.ts - a.ts BETWEEN 0 AND 10 OR a.v > 1)",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE NOT (b.ts - a.ts BETWEEN 0 AND 10)",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE b.ts - a.t2 BETWEEN 0 AND 10",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE b.ts - a.ts NOT BETWEEN 0 AND 10",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k WHERE b.ts - a.ts BETWEEN 10 AND 0",
+		"SELECT a.x FROM stream a LEFT JOIN stream b ON a.k = b.k WHERE b.ts - a.ts BETWEEN 0 AND 10",
+		"SELECT a.x FROM stream a JOIN stream b ON a.k = b.k JOIN meta m ON a.k = m.k WHERE b.ts - a.ts BETWEEN 0 AND 10",
+	} {
+		if _, _, err := Parse(sql); err == nil {
+			t.Errorf("Parse(%q): expected error", sql)
+		}
+	}
+}
@@ -51,6 +51,7 @@ const (
 	TokenOrder
 	TokenDISTINCT
 	TokenLIMIT
+	TokenOFFSET
 	TokenHAVING
 	TokenLIKE
 	TokenIS
@@ -243,7 +244,7 @@ func (l *Lexer) NextToken() Token {
 	case '\'':
 		return l.readStringToken(tokenPos, tokenLine, tokenColumn)
 	case '"':
-		return l.readStringToken(tokenPos, tokenLine, tokenColumn)
+		return l.readDoubleQuotedIdentToken(tokenPos, tokenLine, tokenColumn)
 	case '`':
 		return l.readQuotedIdentToken(tokenPos, tokenLine, tokenColumn)
 	}
@@ -363,9 +364,31 @@ func (l *Lexer) readString() string {
 	return l.input[startPos:l.pos]
 }
 
+// skipWhitespace 跳过空白以及 SQL 注释（-- 行注释、/* */ 块注释）。
+// 字符串与反引号标识符由各自的读取函数整体消费，因此其中的注释标记不会被当作注释。
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '-' && l.peekChar() == '-':
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '*':
+			l.readChar()
+			l.readChar()
+			// 未闭合的块注释吞掉剩余输入
+			for l.ch != 0 && !(l.ch == '*' && l.peekChar() == '/') {
+				l.readChar()
+			}
+			if l.ch != 0 {
+				l.readChar()
+				l.readChar()
+			}
+		default:
+			return
+		}
 	}
 }
 
@@ -390,7 +413,7 @@ func (l *Lexer) lookupIdent(ident string) Token {
 		return Token{Type: TokenAND, Value: ident}
 	case "TUMBLINGWINDOW":
 		return Token{Type: TokenTumbling, Value: ident}
-	case "SLIDINGWINDOW":
+	case "SLIDINGWINDOW", "HOPWINDOW":
 		return Token{Type: TokenSliding, Value: ident}
 	case "COUNTINGWINDOW":
 		return Token{Type: TokenCounting, Value: ident}
@@ -422,6 +445,8 @@ func (l *Lexer) lookupIdent(ident string) Token {
 		return Token{Type: TokenDISTINCT, Value: ident}
 	case "LIMIT":
 		return Token{Type: TokenLIMIT, Value: ident}
+	case "OFFSET":
+		return Token{Type: TokenOFFSET, Value: ident}
 	case "HAVING":
 		return Token{Type: TokenHAVING, Value: ident}
 	case "LIKE":
@@ -490,7 +515,7 @@ func (l *Lexer) checkForTypos(original, upper string) {
 	}
 }
 
-// readStringToken 读取字符串token并处理错误
+// readStringToken 读取单引号字符串常量token并处理错误
 func (l *Lexer) readStringToken(pos, line, column int) Token {
 	quoteChar := l.ch
 	startPos := l.pos
@@ -527,6 +552,41 @@ func (l *Lexer) readStringToken(pos, line, column int) Token {
 	return Token{Type: TokenString, Value: value, Pos: pos, Line: line, Column: column}
 }
 
+// readDoubleQuotedIdentToken 读取双引号 token。内容不能写成普通标识符时——含空格等
+// 标识符以外的字符（"device id"）或与关键字同名（"count"）——它是带引号的标识符，
+// 在 SELECT、WHERE、HAVING、GROUP BY 与函数参数中都按字段名解析：值改写为反引号形式，
+// 走反引号标识符路径。其余双引号 token（"abc"）与 map 键（m["user-name"]）仍是字符串
+// 常量，兼容既有 SQL；单引号始终是字符串常量。
+func (l *Lexer) readDoubleQuotedIdentToken(pos, line, column int) Token {
+	tok := l.readStringToken(pos, line, column)
+	v := tok.Value
+	if len(v) < 3 || v[len(v)-1] != '"' {
+		return tok // 空串或未闭合，错误已由 readStringToken 记录
+	}
+	name := v[1 : len(v)-1]
+	if strings.ContainsRune(name, '`') || !needsQuoting(name) {
+		return tok
+	}
+	// map 键（settings["user-name"]）不是标识符位置
+	if prev := strings.TrimRight(l.input[:pos], " \t\r\n"); strings.HasSuffix(prev, "[") {
+		return tok
+	}
+	return Token{Type: TokenQuotedIdent, Value: "`" + name + "`", Pos: pos, Line: line, Column: column}
+}
+
+// needsQuoting 报告 name 能否只以带引号的形式作为标识符：含标识符以外的字符或是关键字。
+func needsQuoting(name string) bool {
+	if isKeyword(strings.ToUpper(name)) {
+		return true
+	}
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 // readQuotedIdentToken 读取反引号标识符token并处理错误
 func (l *Lexer) readQuotedIdentToken(pos, line, column int) Token {
 	startPos := l.pos
@@ -110,10 +110,24 @@ func TestStringLiterals(t *testing.T) {
 	})
 
 	t.Run("双引号字符串", func(t *testing.T) {
-		lexer := NewLexer(`"hello world"`)
+		lexer := NewLexer(`"hello"`)
 		token := lexer.NextToken()
 		assert.Equal(t, TokenString, token.Type)
-		assert.Equal(t, `"hello world"`, token.Value)
+		assert.Equal(t, `"hello"`, token.Value)
+	})
+
+	t.Run("需要引号的双引号名称为标识符", func(t *testing.T) {
+		for input, want := range map[string]string{`"hello world"`: "`hello world`", `"count"`: "`count`"} {
+			token := NewLexer(input).NextToken()
+			assert.Equal(t, TokenQuotedIdent, token.Type, input)
+			assert.Equal(t, want, token.Value, input)
+		}
+
+		lexer := NewLexer(`m["user-name"]`)
+		lexer.NextToken()
+		lexer.NextToken()
+		token := lexer.NextToken()
+		assert.Equal(t, TokenString, token.Type, "map key stays a string")
 	})
 
 	t.Run("未闭合的字符串", func(t *testing.T) {
@@ -433,3 +447,33 @@ func TestLexerComplexTokens(t *testing.T) {
 		})
 	}
 }
+
+// TestLexerComments 测试注释被跳过，而字符串/反引号内的注释标记保持原样
+func TestLexerComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []TokenType
+		values   []string
+	}{
+		{"SELECT -- 行注释\na", []TokenType{TokenSELECT, TokenIdent, TokenEOF}, []string{"SELECT", "a", ""}},
+		{"SELECT /* 块\n注释 */ a", []TokenType{TokenSELECT, TokenIdent, TokenEOF}, []string{"SELECT", "a", ""}},
+		{"a /* 未闭合", []TokenType{TokenIdent, TokenEOF}, []string{"a", ""}},
+		{"'-- x' '/* y */'", []TokenType{TokenString, TokenString, TokenEOF}, []string{"'-- x'", "'/* y */'", ""}},
+		{"`a--b`", []TokenType{TokenQuotedIdent, TokenEOF}, []string{"`a--b`", ""}},
+		{"a - -1", []TokenType{TokenIdent, TokenMinus, TokenNumber, TokenEOF}, []string{"a", "-", "-1", ""}},
+		{"a / b", []TokenType{TokenIdent, TokenSlash, TokenIdent, TokenEOF}, []string{"a", "/", "b", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			lexer := NewLexer(test.input)
+			for i, expectedType := range test.expected {
+				token := lexer.NextToken()
+				assert.Equal(t, expectedType, token.Type, "token %d", i)
+				if token.Type != TokenEOF {
+					assert.Equal(t, test.values[i], token.Value, "token %d", i)
+				}
+			}
+		})
+	}
+}
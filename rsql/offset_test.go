@@ -0,0 +1,45 @@
+package rsql
+
+import "testing"
+
+func TestParseOffset_WithLimit(t *testing.T) {
+	stmt := parseOrderBySQL(t, "SELECT * FROM stream LIMIT 10 OFFSET 5")
+	if stmt.Limit != 10 || stmt.Offset != 5 {
+		t.Fatalf("got limit=%d offset=%d, want 10/5", stmt.Limit, stmt.Offset)
+	}
+	cfg, _, err := stmt.ToStreamConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Limit != 10 || cfg.Offset != 5 {
+		t.Fatalf("config limit=%d offset=%d, want 10/5", cfg.Limit, cfg.Offset)
+	}
+}
+
+func TestParseOffset_WithoutLimit(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT * FROM stream OFFSET 3",
+		"SELECT a FROM stream WHERE a > 1 OFFSET 3",
+		"SELECT a FROM stream ORDER BY a DESC OFFSET 3",
+	} {
+		stmt := parseOrderBySQL(t, sql)
+		if stmt.Offset != 3 || stmt.Limit != 0 {
+			t.Errorf("%s: got limit=%d offset=%d, want 0/3", sql, stmt.Limit, stmt.Offset)
+		}
+		if stmt.Condition != "" && stmt.Condition != "a > 1" {
+			t.Errorf("%s: OFFSET leaked into WHERE: %q", sql, stmt.Condition)
+		}
+	}
+}
+
+func TestParseOffset_Invalid(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT * FROM stream LIMIT 10 OFFSET",
+		"SELECT * FROM stream LIMIT 10 OFFSET -1",
+		"SELECT * FROM stream OFFSET abc",
+	} {
+		if _, err := NewParser(sql).Parse(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
@@ -83,9 +83,9 @@ func TestParseOrderBy_NoClause(t *testing.T) {
 // This is the same robustness property parseLimit gained (H7 fix).
 func TestParseOrderBy_NoSubstringFalseMatch(t *testing.T) {
 	cases := []string{
-		"SELECT * FROM orders",                 // table named "orders"
-		"SELECT ordered FROM t",                // column "ordered"
-		"SELECT * FROM t WHERE tag = 'ORDER'",  // string literal
+		"SELECT * FROM orders",                // table named "orders"
+		"SELECT ordered FROM t",               // column "ordered"
+		"SELECT * FROM t WHERE tag = 'ORDER'", // string literal
 		"SELECT * FROM t WHERE note = 'x ORDER y'",
 	}
 	for _, sql := range cases {
@@ -96,3 +96,29 @@ func TestParseOrderBy_NoSubstringFalseMatch(t *testing.T) {
 		}
 	}
 }
+
+// TestOrderBy_ResolvesSelectExpression: an ORDER BY key written as a SELECT
+// expression sorts by that column's output name (its alias when present).
+func TestOrderBy_ResolvesSelectExpression(t *testing.T) {
+	cases := map[string]string{
+		"SELECT deviceId, avg(temperature) AS m FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY avg(temperature) DESC": "m",
+		"SELECT deviceId, max(temperature) FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY max(temperature)":           "max(temperature)",
+		"SELECT deviceId AS id, avg(temperature) AS m FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY m":               "m",
+		// 与别名、表达式的匹配不区分大小写
+		"SELECT deviceId, avg(temperature) AS Mean FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY MEAN DESC":     "Mean",
+		"SELECT deviceId, avg(temperature) AS m FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY AVG(temperature)": "m",
+		"SELECT deviceId, max(temperature) FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY MAX(temperature)":      "max(temperature)",
+		"SELECT deviceId, count(*) AS c FROM t GROUP BY deviceId, TumblingWindow('1s') ORDER BY DeviceID":                 "deviceId",
+		// 大小写完全一致的列优先
+		"SELECT a AS x, A AS y FROM t GROUP BY a, A, TumblingWindow('1s') ORDER BY A": "y",
+	}
+	for sql, want := range cases {
+		config, _, err := parseOrderBySQL(t, sql).ToStreamConfig()
+		if err != nil {
+			t.Fatalf("ToStreamConfig(%q): %v", sql, err)
+		}
+		if len(config.OrderBy) != 1 || config.OrderBy[0].Expression != want {
+			t.Errorf("%q: got %+v, want key %q", sql, config.OrderBy, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package rsql
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/rulego/streamsql/logger"
 	"github.com/rulego/streamsql/types"
 	"github.com/rulego/streamsql/utils/cast"
+	"github.com/rulego/streamsql/window"
 )
 
 // 解析器配置常量
@@ -43,6 +45,7 @@ var tokenTypeNames = map[TokenType]string{
 	TokenAS:          "AS",
 	TokenDISTINCT:    "DISTINCT",
 	TokenLIMIT:       "LIMIT",
+	TokenOFFSET:      "OFFSET",
 	TokenHAVING:      "HAVING",
 	TokenWITH:        "WITH",
 	TokenEOF:         "EOF",
@@ -57,6 +60,8 @@ type Parser struct {
 	errorRecovery *ErrorRecovery
 	currentToken  Token
 	input         string
+	// params 为执行期参数：窗口函数中未加引号的单个标识符参数若是其中的键，则替换为对应值
+	params map[string]any
 }
 
 func NewParser(input string) *Parser {
@@ -149,6 +154,11 @@ func (p *Parser) shouldAttemptRecovery(err *ParseError, depth int) bool {
 func (p *Parser) Parse() (*SelectStatement, error) {
 	stmt := &SelectStatement{}
 
+	// 解析可选的 INSERT INTO <sink> 前缀
+	if err := p.parseInsertInto(stmt); err != nil {
+		return nil, p.createDetailedError(err)
+	}
+
 	// 解析SELECT子句 - 对于特定的关键错误直接返回
 	if err := p.parseSelect(stmt); err != nil {
 		// 检查是否是关键的语法错误，这些错误应该停止进一步解析
@@ -239,6 +249,13 @@ func (p *Parser) Parse() (*SelectStatement, error) {
 		}
 	}
 
+	// 解析OFFSET子句
+	if err := p.parseOffset(stmt); err != nil {
+		if !p.errorRecovery.RecoverFromError(ErrorTypeSyntax) {
+			return nil, p.createDetailedError(err)
+		}
+	}
+
 	// 如果有错误但可以恢复，返回部分解析结果和错误信息
 	if p.errorRecovery.HasErrors() {
 		return stmt, p.createCombinedError()
@@ -254,7 +271,7 @@ func (p *Parser) Parse() (*SelectStatement, error) {
 func isKeyword(word string) bool {
 	keywords := map[string]bool{
 		"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true,
-		"ORDER": true, "HAVING": true, "LIMIT": true, "WITH": true, "AS": true,
+		"ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "WITH": true, "AS": true,
 		"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
 		"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true,
 		"DISTINCT": true, "COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
@@ -294,6 +311,36 @@ func (p *Parser) createCombinedError() error {
 	return fmt.Errorf("%s", builder.String())
 }
 
+// parseInsertInto 解析语句开头可选的 INSERT INTO <sink>，目标为标识符或反引号标识符。
+// INSERT/INTO 不是保留字：不以 INSERT 开头时不消费任何 token（避免首个 token 的
+// 拼写检查重复报错），交由 parseSelect 处理。
+func (p *Parser) parseInsertInto(stmt *SelectStatement) error {
+	words := strings.Fields(p.input)
+	if len(words) == 0 || !strings.EqualFold(words[0], "INSERT") {
+		return nil
+	}
+	p.lexer.NextToken() // INSERT
+	if err := p.expectKeyword("INTO"); err != nil {
+		return err
+	}
+	target := p.lexer.NextToken()
+	switch target.Type {
+	case TokenIdent:
+		stmt.InsertInto = target.Value
+	case TokenQuotedIdent:
+		stmt.InsertInto = strings.Trim(target.Value, "`")
+	}
+	if stmt.InsertInto == "" {
+		return CreateSyntaxError(
+			fmt.Sprintf("Expected sink name after INSERT INTO, got %s", target.Value),
+			target.Pos,
+			target.Value,
+			[]string{"INSERT INTO <sink> SELECT ..."},
+		)
+	}
+	return nil
+}
+
 // parseSelect 解析 SELECT 子句，包括字段列表、DISTINCT 关键字和别名
 // 支持 SELECT * 语法，并提供字段数量限制防止无限循环
 // 参数: stmt - 要填充的 SelectStatement 结构体
@@ -446,12 +493,21 @@ func (p *Parser) parseSelect(stmt *SelectStatement) error {
 
 		// 处理别名
 		if currentToken.Type == TokenAS {
-			field.Alias = p.lexer.NextToken().Value
+			aliasToken := p.lexer.NextToken()
+			field.Alias = aliasToken.Value
+			// 别名总是标识符：去掉引号（AS "device id" / AS `device id`）
+			switch {
+			case aliasToken.Type == TokenQuotedIdent:
+				field.Alias = strings.Trim(aliasToken.Value, "`")
+			case aliasToken.Type == TokenString && strings.HasPrefix(aliasToken.Value, "\""):
+				field.Alias = strings.Trim(aliasToken.Value, "\"")
+			}
 			currentToken = p.lexer.NextToken()
 		}
 
 		// 如果表达式为空，跳过这个字段
 		if field.Expression != "" {
+			field.Expression = p.rewriteDistinctAggregates(field.Expression)
 			// 验证表达式中的函数
 			validator := NewFunctionValidator(p.errorRecovery)
 			pos, _, _ := p.lexer.GetPosition()
@@ -491,6 +547,7 @@ func (p *Parser) parseWhere(stmt *SelectStatement) error {
 	// Set max iterations limit to prevent infinite loops
 	maxIterations := 100
 	iterations := 0
+	depth := 0 // 括号内的 ORDER 属于分析函数 OVER (... ORDER BY ...)
 
 	for {
 		iterations++
@@ -503,11 +560,17 @@ func (p *Parser) parseWhere(stmt *SelectStatement) error {
 		if tok.Type == TokenGROUP || tok.Type == TokenEOF || tok.Type == TokenSliding ||
 			tok.Type == TokenTumbling || tok.Type == TokenCounting || tok.Type == TokenSession ||
 			tok.Type == TokenGlobal ||
-			tok.Type == TokenHAVING || tok.Type == TokenLIMIT || tok.Type == TokenWITH ||
-			tok.Type == TokenOrder {
+			tok.Type == TokenHAVING || tok.Type == TokenLIMIT || tok.Type == TokenOFFSET || tok.Type == TokenWITH ||
+			(tok.Type == TokenOrder && depth == 0) {
 			break
 		}
 		switch tok.Type {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+		}
+		switch tok.Type {
 		case TokenIdent, TokenNumber, TokenQuotedIdent:
 			conditions = append(conditions, tok.Value)
 		case TokenString:
@@ -547,7 +610,7 @@ func (p *Parser) parseWhere(stmt *SelectStatement) error {
 		validated, _, _ := extractWhereAnalyticCalls(whereCondition)
 		validator := NewFunctionValidator(p.errorRecovery)
 		pos, _, _ := p.lexer.GetPosition()
-		validator.ValidateExpression(validated, pos-len(whereCondition))
+		validator.ValidateCondition(validated, pos-len(whereCondition))
 	}
 
 	stmt.Condition = whereCondition
@@ -564,7 +627,50 @@ func (p *Parser) parseWindowFunction(stmt *SelectStatement, winType string) erro
 	maxIterations := 100
 	iterations := 0
 
+	// A parameter spanning several tokens (e.g. SessionWindow(gap_ms / 1000))
+	// is kept as an expression string; a single token is converted as a value,
+	// or replaced by the execution parameter of that name (TumblingWindow(size)).
+	// For SessionWindow an unquoted identifier or expression is a per-row gap
+	// source (window.SessionGap); quoted strings stay duration literals. Other
+	// windows have no per-row arguments, so an unquoted identifier that is not
+	// an execution parameter is reported as a missing parameter.
+	session := strings.EqualFold(winType, "SessionWindow")
+	var parts []string
+	addParam := func() {
+		switch len(parts) {
+		case 0:
+		case 1:
+			v := parts[0]
+			if pv, ok := p.params[v]; ok {
+				params = append(params, pv)
+				break
+			}
+			// Handle quoted values
+			quoted := strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'")
+			if quoted {
+				v = strings.Trim(v, "'")
+			}
+			val := convertValue(v)
+			if src, isStr := val.(string); isStr && !quoted {
+				if session {
+					val = window.SessionGap(src)
+				} else if windowParamNameRe.MatchString(src) && stmt.Window.MissingParam == "" {
+					stmt.Window.MissingParam = src
+				}
+			}
+			params = append(params, val)
+		default:
+			if session {
+				params = append(params, window.SessionGap(strings.Join(parts, " ")))
+				break
+			}
+			params = append(params, strings.Join(parts, " "))
+		}
+		parts = nil
+	}
+
 	// Parse parameters until we find the closing parenthesis
+	depth := 0
 	for {
 		iterations++
 		if iterations > maxIterations {
@@ -575,29 +681,34 @@ func (p *Parser) parseWindowFunction(stmt *SelectStatement, winType string) erro
 		valTok := p.lexer.NextToken()
 
 		// If we hit the closing parenthesis or EOF, break
-		if valTok.Type == TokenRParen || valTok.Type == TokenEOF {
+		if valTok.Type == TokenEOF || (valTok.Type == TokenRParen && depth == 0) {
 			break
 		}
 
-		// Skip commas
-		if valTok.Type == TokenComma {
-			continue
-		}
-
-		// Handle quoted values
-		if strings.HasPrefix(valTok.Value, "'") && strings.HasSuffix(valTok.Value, "'") {
-			valTok.Value = strings.Trim(valTok.Value, "'")
+		switch valTok.Type {
+		case TokenComma:
+			if depth == 0 {
+				addParam()
+				continue
+			}
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
 		}
-
-		// Add the parameter value
-		params = append(params, convertValue(valTok.Value))
+		parts = append(parts, valTok.Value)
 	}
+	addParam()
 
 	stmt.Window.Params = params
 	stmt.Window.Type = winType
 	return nil
 }
 
+// windowParamNameRe matches an unquoted window argument that names an
+// execution parameter rather than a literal.
+var windowParamNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // parseGlobalWindow parses "GLOBAL WINDOW [TRIGGER WHEN <predicate>]".
 // Unlike other windows, the global window takes no parentheses/params; its
 // output is driven by the TRIGGER WHEN predicate. The predicate is collected
@@ -645,7 +756,7 @@ func (p *Parser) parseGlobalWindow(stmt *SelectStatement) error {
 		snap := p.lexer.save()
 		t := p.lexer.NextToken()
 		if t.Type == TokenWITH || t.Type == TokenOrder || t.Type == TokenEOF ||
-			t.Type == TokenHAVING || t.Type == TokenLIMIT {
+			t.Type == TokenHAVING || t.Type == TokenLIMIT || t.Type == TokenOFFSET {
 			p.lexer.restore(snap)
 			break
 		}
@@ -664,8 +775,8 @@ func (p *Parser) parseGlobalWindow(stmt *SelectStatement) error {
 	return nil
 }
 
-// parseOverClause 解析分析函数的 OVER 子句：OVER ([PARTITION BY ...] [WHEN ...])。
-// 仅支持 PARTITION BY 和 WHEN，ORDER BY / ROWS / BETWEEN 一律报错。
+// parseOverClause 解析分析函数的 OVER 子句：OVER ([PARTITION BY ...] [ORDER BY ...] [WHEN ...])。
+// ROWS / BETWEEN 等窗口帧一律报错。
 // 约定：调用时 currentToken == TokenOVER（已读出），lexer 待读为 '('；返回时
 // OVER(...) 已全部消费，'(' 内的 ')' 是最后读出的 token，调用者需 NextToken 取后续。
 func (p *Parser) parseOverClause() (*types.OverSpec, error) {
@@ -683,6 +794,10 @@ func (p *Parser) parseOverClause() (*types.OverSpec, error) {
 			if err := p.parseOverPartitionBy(spec); err != nil {
 				return nil, err
 			}
+		case TokenOrder:
+			if err := p.parseOverOrderBy(spec); err != nil {
+				return nil, err
+			}
 		case TokenWHEN:
 			pred, err := p.parseOverWhen()
 			if err != nil {
@@ -690,7 +805,7 @@ func (p *Parser) parseOverClause() (*types.OverSpec, error) {
 			}
 			spec.When = pred
 		default:
-			return nil, fmt.Errorf("OVER clause only supports PARTITION BY and WHEN (ORDER BY/ROWS not supported), got %q", t.Value)
+			return nil, fmt.Errorf("OVER clause only supports PARTITION BY, ORDER BY and WHEN (ROWS frames not supported), got %q", t.Value)
 		}
 	}
 }
@@ -722,7 +837,41 @@ func (p *Parser) parseOverPartitionBy(spec *types.OverSpec) error {
 	}
 }
 
-// parseOverWhen 解析 WHEN <predicate>，收集到 ) 或 PARTITION 为止。WHEN 已读出。
+// parseOverOrderBy 解析 ORDER BY <field> [ASC|DESC][, ...]。ORDER 已读出。
+// 方向记入 OrderDesc，供排名函数维护有序键集合、ntile 排序窗口结果行。
+func (p *Parser) parseOverOrderBy(spec *types.OverSpec) error {
+	by := p.lexer.NextToken()
+	if by.Type != TokenBY {
+		return fmt.Errorf("expected BY after ORDER, got %q", by.Value)
+	}
+	for {
+		id := p.lexer.NextToken()
+		if id.Type != TokenIdent && id.Type != TokenQuotedIdent {
+			return fmt.Errorf("expected order field after ORDER BY, got %q", id.Value)
+		}
+		name := id.Value
+		if len(name) >= 2 && name[0] == '`' && name[len(name)-1] == '`' {
+			name = name[1 : len(name)-1]
+		}
+		spec.OrderBy = append(spec.OrderBy, name)
+		desc := false
+		snap := p.lexer.save()
+		sep := p.lexer.NextToken()
+		if sep.Type == TokenIdent && (strings.EqualFold(sep.Value, "ASC") || strings.EqualFold(sep.Value, "DESC")) {
+			desc = strings.EqualFold(sep.Value, "DESC")
+			snap = p.lexer.save()
+			sep = p.lexer.NextToken()
+		}
+		spec.OrderDesc = append(spec.OrderDesc, desc)
+		if sep.Type == TokenComma {
+			continue
+		}
+		p.lexer.restore(snap) // 回退（WHEN/PARTITION 或 ')'），交给上层循环
+		return nil
+	}
+}
+
+// parseOverWhen 解析 WHEN <predicate>，收集到 )、PARTITION 或 ORDER 为止。WHEN 已读出。
 // 跟踪括号深度：WHEN 谓词里的函数调用（如 had_changed(true, status)）的括号要计入，
 // 仅在深度归零时 ')' 才是 OVER 子句结束。
 func (p *Parser) parseOverWhen() (string, error) {
@@ -731,7 +880,7 @@ func (p *Parser) parseOverWhen() (string, error) {
 	for i := 0; i < 100; i++ {
 		snap := p.lexer.save()
 		t := p.lexer.NextToken()
-		if depth == 0 && (t.Type == TokenRParen || t.Type == TokenPARTITION) {
+		if depth == 0 && (t.Type == TokenRParen || t.Type == TokenPARTITION || t.Type == TokenOrder) {
 			p.lexer.restore(snap)
 			return strings.Join(parts, " "), nil
 		}
@@ -820,7 +969,7 @@ func (p *Parser) parseFrom(stmt *SelectStatement) error {
 func isClauseBoundaryIdent(value string) bool {
 	switch strings.ToUpper(value) {
 	case "JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "ON",
-		"WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "WITH",
+		"WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "WITH",
 		"MATCH_RECOGNIZE": // 子句起点（词法器把 MATCH_RECOGNIZE 读成单标识符），不得当源别名消费
 		return true
 	}
@@ -885,6 +1034,9 @@ func (p *Parser) parseJoin(stmt *SelectStatement) error {
 		if jc.Alias == "" {
 			jc.Alias = jc.Table
 		}
+		// JOIN of the FROM source itself: an interval self-join. Its ON fields
+		// keep their alias qualifier, since both sides share the same fields.
+		selfJoin := strings.EqualFold(jc.Table, stmt.Source)
 
 		// ON <field> = <field> [AND <field> = <field>]...
 		onTok := p.lexer.NextToken()
@@ -904,10 +1056,14 @@ func (p *Parser) parseJoin(stmt *SelectStatement) error {
 			if err != nil {
 				return err
 			}
-			jc.OnPairs = append(jc.OnPairs, types.JoinOnPair{
-				StreamField: stripAliasPrefix(left, stmt.SourceAlias, jc.Alias),
-				TableField:  stripAliasPrefix(right, stmt.SourceAlias, jc.Alias),
-			})
+			if selfJoin {
+				jc.OnPairs = append(jc.OnPairs, types.JoinOnPair{StreamField: left, TableField: right})
+			} else {
+				jc.OnPairs = append(jc.OnPairs, types.JoinOnPair{
+					StreamField: stripAliasPrefix(left, stmt.SourceAlias, jc.Alias),
+					TableField:  stripAliasPrefix(right, stmt.SourceAlias, jc.Alias),
+				})
+			}
 
 			// Continue on AND, otherwise stop and put the boundary token back.
 			andSnap := p.lexer.save()
@@ -918,6 +1074,13 @@ func (p *Parser) parseJoin(stmt *SelectStatement) error {
 			}
 		}
 
+		if selfJoin {
+			if stmt.SelfJoin != nil {
+				p.errorRecovery.AddError(CreateSyntaxError("only one stream self-join is supported", tableTok.Pos, tableTok.Value, nil))
+			}
+			stmt.SelfJoin = &jc
+			continue
+		}
 		stmt.JoinConfigs = append(stmt.JoinConfigs, jc)
 	}
 }
@@ -1000,12 +1163,17 @@ func (p *Parser) parseGroupBy(stmt *SelectStatement) error {
 	var limitToken *Token // 保存LIMIT token以便后续处理
 
 	// 累积分组项：跟踪括号深度，把函数表达式（如 upper(device)）作为整体一项，
-	// 顶层逗号分隔。collapseSpacesOutsideQuotes 归一化（parser 读出的多 token 带空格）。
+	// 顶层逗号分隔。collapseSpacesOutsideQuotes 归一化（parser 读出的多 token 带空格）；
+	// CASE 表达式的关键字之间须保留空格，保持以单个空格连接的 token 文本。
 	var currentItem strings.Builder
 	parenLevel := 0
 	flushItem := func() {
 		if hasGroupBy && currentItem.Len() > 0 {
-			stmt.GroupBy = append(stmt.GroupBy, collapseSpacesOutsideQuotes(currentItem.String()))
+			item := currentItem.String()
+			if !isCaseExpr(item) {
+				item = collapseSpacesOutsideQuotes(item)
+			}
+			stmt.GroupBy = append(stmt.GroupBy, item)
 		}
 		currentItem.Reset()
 	}
@@ -1019,7 +1187,7 @@ func (p *Parser) parseGroupBy(stmt *SelectStatement) error {
 
 		tok := p.lexer.NextToken()
 		if tok.Type == TokenWITH || tok.Type == TokenOrder || tok.Type == TokenEOF ||
-			tok.Type == TokenHAVING || tok.Type == TokenLIMIT {
+			tok.Type == TokenHAVING || tok.Type == TokenLIMIT || tok.Type == TokenOFFSET {
 			// 如果是LIMIT token，保存它以便parseLimit处理
 			if tok.Type == TokenLIMIT {
 				limitToken = &tok
@@ -1027,7 +1195,7 @@ func (p *Parser) parseGroupBy(stmt *SelectStatement) error {
 			flushItem()
 			break
 		}
-		if tok.Type == TokenComma {
+		if tok.Type == TokenComma && parenLevel == 0 {
 			flushItem()
 			continue
 		}
@@ -1097,6 +1265,8 @@ func (p *Parser) parseWith(stmt *SelectStatement) error {
 	// 设置最大次数限制，防止无限循环
 	maxIterations := 100
 	iterations := 0
+	// TIMESTAMP 与 WATERMARK 字段的一致性在读完全部选项后检查，与书写顺序无关
+	var tsTok, watermarkTok Token
 
 	for p.lexer.peekChar() != ')' {
 		iterations++
@@ -1115,36 +1285,49 @@ func (p *Parser) parseWith(stmt *SelectStatement) error {
 		// Unknown WITH parameters (plain identifiers rather than a recognized
 		// option keyword) are tolerated but surfaced, so typos don't silently
 		// drop configuration. The following = and value tokens are consumed by
-		// later loop iterations (none of the known-option branches match).
-		if valTok.Type == TokenIdent {
-			logger.Warn("WITH: ignoring unknown option %q (known: TIMESTAMP, TIMEUNIT, MAXOUTOFORDERNESS, ALLOWEDLATENESS, IDLETIMEOUT, STATETTL)", valTok.Value)
+		// later loop iterations (none of the option branches match them).
+		// WATERMARK/MAXDELAY/LATENESS are matched by name rather than lexed as
+		// keywords, so columns with those names keep working.
+		option := ""
+		switch valTok.Type {
+		case TokenTimestamp, TokenTimeUnit, TokenMaxOutOfOrderness, TokenAllowedLateness, TokenIdleTimeout, TokenStateTTL:
+			option = strings.ToUpper(valTok.Value)
+		case TokenIdent:
+			switch name := strings.ToUpper(valTok.Value); name {
+			case "WATERMARK", "MAXDELAY", "LATENESS":
+				option = name
+			default:
+				logger.Warn("WITH: ignoring unknown option %q (known: TIMESTAMP, TIMEUNIT, WATERMARK, MAXOUTOFORDERNESS/MAXDELAY, ALLOWEDLATENESS/LATENESS, IDLETIMEOUT, STATETTL)", valTok.Value)
+			}
 		}
 
-		if valTok.Type == TokenTimestamp {
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				if strings.HasPrefix(next.Value, "'") && strings.HasSuffix(next.Value, "'") {
-					next.Value = strings.Trim(next.Value, "'")
-				}
-				// Check if Window is initialized; if not, create new WindowDefinition
-				if stmt.Window.Type == "" {
-					stmt.Window = WindowDefinition{
-						TsProp: next.Value,
+		// Options set fields only: replacing Window would drop options parsed
+		// earlier in the same WITH on a query without a window.
+		switch option {
+		case "TIMESTAMP":
+			if next, ok := p.readWithValue(); ok {
+				stmt.Window.TsProp = next.Value
+				tsTok = next
+			}
+		case "WATERMARK":
+			// WATERMARK='<duration>' 设置水位线更新间隔；WATERMARK='<field>' 指定事件时间字段
+			// （同 TIMESTAMP），已由 TIMESTAMP 指定时取值须相同或为 'eventtime'。
+			if next, ok := p.readWithValue(); ok {
+				if d, err := cast.ToDurationE(next.Value); err == nil {
+					if d <= 0 {
+						p.errorRecovery.AddError(CreateSyntaxError(
+							fmt.Sprintf("invalid WATERMARK interval %q: must be positive", next.Value),
+							next.Pos, next.Value, []string{"duration"}))
+						continue
 					}
+					stmt.Window.WatermarkInterval = d
 				} else {
-					stmt.Window.TsProp = next.Value
+					watermarkTok = next
 				}
 			}
-		}
-		if valTok.Type == TokenTimeUnit {
+		case "TIMEUNIT":
 			timeUnit := time.Millisecond // Default to milliseconds
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				if strings.HasPrefix(next.Value, "'") && strings.HasSuffix(next.Value, "'") {
-					next.Value = strings.Trim(next.Value, "'")
-				}
+			if next, ok := p.readWithValue(); ok {
 				switch next.Value {
 				case "dd":
 					timeUnit = 24 * time.Hour
@@ -1161,106 +1344,74 @@ func (p *Parser) parseWith(stmt *SelectStatement) error {
 				default:
 					// If unknown unit, keep default (milliseconds)
 				}
-				// Check if Window is initialized; if not, create new WindowDefinition
-				if stmt.Window.Type == "" {
-					stmt.Window = WindowDefinition{
-						TimeUnit: timeUnit,
-					}
-				} else {
-					stmt.Window.TimeUnit = timeUnit
-				}
+				stmt.Window.TimeUnit = timeUnit
 			}
-		}
-		if valTok.Type == TokenMaxOutOfOrderness {
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				durationStr := next.Value
-				if strings.HasPrefix(durationStr, "'") && strings.HasSuffix(durationStr, "'") {
-					durationStr = strings.Trim(durationStr, "'")
-				}
-				// Parse duration string like '5s', '2m', '1h', etc.
-				if duration, err := cast.ToDurationE(durationStr); err == nil {
-					// Check if Window is initialized; if not, create new WindowDefinition
-					if stmt.Window.Type == "" {
-						stmt.Window = WindowDefinition{
-							MaxOutOfOrderness: duration,
-						}
-					} else {
-						stmt.Window.MaxOutOfOrderness = duration
-					}
-				}
-				// If parsing fails, silently ignore (keep default 0)
+		case "MAXOUTOFORDERNESS", "MAXDELAY":
+			if d, ok := p.readWithDuration(option); ok {
+				stmt.Window.MaxOutOfOrderness = d
 			}
-		}
-		if valTok.Type == TokenAllowedLateness {
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				durationStr := next.Value
-				if strings.HasPrefix(durationStr, "'") && strings.HasSuffix(durationStr, "'") {
-					durationStr = strings.Trim(durationStr, "'")
-				}
-				// Parse duration string like '5s', '2m', '1h', etc.
-				if duration, err := cast.ToDurationE(durationStr); err == nil {
-					// Check if Window is initialized; if not, create new WindowDefinition
-					if stmt.Window.Type == "" {
-						stmt.Window = WindowDefinition{
-							AllowedLateness: duration,
-						}
-					} else {
-						stmt.Window.AllowedLateness = duration
-					}
-				}
-				// If parsing fails, silently ignore (keep default 0)
+		case "ALLOWEDLATENESS", "LATENESS":
+			if d, ok := p.readWithDuration(option); ok {
+				stmt.Window.AllowedLateness = d
 			}
-		}
-		if valTok.Type == TokenIdleTimeout {
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				durationStr := next.Value
-				if strings.HasPrefix(durationStr, "'") && strings.HasSuffix(durationStr, "'") {
-					durationStr = strings.Trim(durationStr, "'")
-				}
-				// Parse duration string like '5s', '2m', '1h', etc.
-				if duration, err := cast.ToDurationE(durationStr); err == nil {
-					// Check if Window is initialized; if not, create new WindowDefinition
-					if stmt.Window.Type == "" {
-						stmt.Window = WindowDefinition{
-							IdleTimeout: duration,
-						}
-					} else {
-						stmt.Window.IdleTimeout = duration
-					}
-				}
-				// If parsing fails, silently ignore (keep default 0)
+		case "IDLETIMEOUT":
+			if d, ok := p.readWithDuration(option); ok {
+				stmt.Window.IdleTimeout = d
 			}
-		}
-		if valTok.Type == TokenStateTTL {
-			next := p.lexer.NextToken()
-			if next.Type == TokenEQ {
-				next = p.lexer.NextToken()
-				durationStr := next.Value
-				if strings.HasPrefix(durationStr, "'") && strings.HasSuffix(durationStr, "'") {
-					durationStr = strings.Trim(durationStr, "'")
-				}
-				if duration, err := cast.ToDurationE(durationStr); err == nil {
-					if stmt.Window.Type == "" {
-						stmt.Window = WindowDefinition{
-							CountStateTTL: duration,
-						}
-					} else {
-						stmt.Window.CountStateTTL = duration
-					}
-				}
+		case "STATETTL":
+			if d, ok := p.readWithDuration(option); ok {
+				stmt.Window.CountStateTTL = d
 			}
 		}
 	}
 
+	if watermarkTok.Value != "" {
+		switch {
+		case stmt.Window.TsProp == "":
+			stmt.Window.TsProp = watermarkTok.Value
+		case stmt.Window.TsProp != watermarkTok.Value && !strings.EqualFold(watermarkTok.Value, "eventtime"):
+			p.errorRecovery.AddError(CreateSyntaxError(
+				fmt.Sprintf("WATERMARK field %q conflicts with TIMESTAMP field %q", watermarkTok.Value, tsTok.Value),
+				watermarkTok.Pos, watermarkTok.Value, []string{tsTok.Value}))
+		}
+	}
+
 	return nil
 }
 
+// readWithValue 读取 WITH 选项的 "= value" 部分并去掉值两侧的单引号；缺少 = 时返回 false。
+func (p *Parser) readWithValue() (Token, bool) {
+	next := p.lexer.NextToken()
+	if next.Type != TokenEQ {
+		return next, false
+	}
+	next = p.lexer.NextToken()
+	if strings.HasPrefix(next.Value, "'") && strings.HasSuffix(next.Value, "'") {
+		next.Value = strings.Trim(next.Value, "'")
+	}
+	return next, true
+}
+
+// readWithDuration 读取时长类 WITH 选项的值（如 '2s'、'500ms'）。值不是合法的非负时长时
+// 记录语法错误并返回 false，避免配置被静默忽略。
+func (p *Parser) readWithDuration(option string) (time.Duration, bool) {
+	next, ok := p.readWithValue()
+	if !ok {
+		return 0, false
+	}
+	d, err := cast.ToDurationE(next.Value)
+	if err == nil && d < 0 {
+		err = errors.New("must not be negative")
+	}
+	if err != nil {
+		p.errorRecovery.AddError(CreateSyntaxError(
+			fmt.Sprintf("invalid %s duration %q: %v (expected e.g. '2s', '500ms')", option, next.Value, err),
+			next.Pos, next.Value, []string{"duration"}))
+		return 0, false
+	}
+	return d, true
+}
+
 // handleLimitToken 处理在parseGroupBy中遇到的LIMIT token
 func (p *Parser) handleLimitToken(stmt *SelectStatement, limitToken Token) error {
 	// 获取下一个token，应该是一个数字
@@ -1427,6 +1578,42 @@ func (p *Parser) parseLimit(stmt *SelectStatement) error {
 	return nil
 }
 
+// parseOffset 解析OFFSET子句（LIMIT n OFFSET m，或单独的 OFFSET m）。
+// 与 parseLimit 一样用独立 lexer 定位真正的 OFFSET 关键字，括号内的不算。
+func (p *Parser) parseOffset(stmt *SelectStatement) error {
+	offsetLexer := NewLexer(p.input)
+	offsetLexer.SetErrorRecovery(NewErrorRecovery(nil))
+	depth := 0
+	for {
+		tok := offsetLexer.NextToken()
+		switch tok.Type {
+		case TokenEOF:
+			return nil
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+		}
+		if tok.Type != TokenOFFSET || depth != 0 {
+			continue
+		}
+		val := offsetLexer.NextToken()
+		offset, err := strconv.Atoi(val.Value)
+		if val.Type != TokenNumber || err != nil || offset < 0 {
+			parseErr := CreateMissingTokenError("number", val.Pos)
+			parseErr.Message = "OFFSET must be followed by a non-negative integer"
+			parseErr.Context = "OFFSET clause"
+			parseErr.Suggestions = []string{
+				"Add a number after OFFSET, e.g., LIMIT 10 OFFSET 5",
+			}
+			p.errorRecovery.AddError(parseErr)
+			return parseErr
+		}
+		stmt.Offset = offset
+		return nil
+	}
+}
+
 // parseOrderBy 解析 ORDER BY 子句。用独立 lexer 扫描真正的 TokenOrder，
 // 避免误匹配标识符/字符串字面量中的 "ORDER" 子串（与 parseLimit 同样的稳健做法）。
 // v0.5：每个排序键为结果列名（标识符，可含点路径），后接可选 ASC/DESC，逗号分隔。
@@ -1435,12 +1622,19 @@ func (p *Parser) parseOrderBy(stmt *SelectStatement) error {
 	orderLexer := NewLexer(p.input)
 	orderLexer.SetErrorRecovery(NewErrorRecovery(nil))
 	orderPos := -1
+	depth := 0 // 括号内的 ORDER 属于 OVER (... ORDER BY ...)，不是语句级 ORDER BY
 	for {
 		tok := orderLexer.NextToken()
 		if tok.Type == TokenEOF {
 			break
 		}
-		if tok.Type == TokenOrder {
+		switch tok.Type {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			depth--
+		}
+		if tok.Type == TokenOrder && depth == 0 {
 			orderPos = tok.Pos
 			break
 		}
@@ -1468,7 +1662,7 @@ func (p *Parser) parseOrderBy(stmt *SelectStatement) error {
 		// Collect the field expression tokens.
 		for {
 			tok := fieldLexer.NextToken()
-			if tok.Type == TokenEOF || tok.Type == TokenLIMIT {
+			if tok.Type == TokenEOF || tok.Type == TokenLIMIT || tok.Type == TokenOFFSET {
 				done = true
 				break
 			}
@@ -1530,7 +1724,7 @@ func (p *Parser) parseHaving(stmt *SelectStatement) error {
 		}
 
 		tok := p.lexer.NextToken()
-		if tok.Type == TokenLIMIT || tok.Type == TokenEOF || tok.Type == TokenWITH {
+		if tok.Type == TokenLIMIT || tok.Type == TokenOFFSET || tok.Type == TokenEOF || tok.Type == TokenWITH {
 			break
 		}
 
@@ -1568,11 +1762,11 @@ func (p *Parser) parseHaving(stmt *SelectStatement) error {
 	}
 
 	// Validate functions in HAVING condition
-	havingCondition := strings.Join(conditions, " ")
+	havingCondition := p.rewriteDistinctAggregates(strings.Join(conditions, " "))
 	if havingCondition != "" {
 		validator := NewFunctionValidator(p.errorRecovery)
 		pos, _, _ := p.lexer.GetPosition()
-		validator.ValidateExpression(havingCondition, pos-len(havingCondition))
+		validator.ValidateCondition(havingCondition, pos-len(havingCondition))
 	}
 
 	stmt.Having = havingCondition
@@ -1581,7 +1775,23 @@ func (p *Parser) parseHaving(stmt *SelectStatement) error {
 
 // Parse 是包级别的Parse函数，用于解析SQL字符串并返回配置和条件
 func Parse(sql string) (*types.Config, string, error) {
+	return ParseWithParams(sql, nil)
+}
+
+// ParseWithParams 与 Parse 相同，但窗口函数参数可引用执行期参数：未加引号的单个标识符
+// 若是 params 中的键（如 TumblingWindow(window_size)），按对应值解析，值的取法与字面量相同
+// （'5s' 这样的时长字符串、time.Duration、表示秒数或条数的数字）。参数优先于同名字段，
+// 因此 SessionWindow(gap) 中的 gap 若在 params 中则作为固定间隔而非按行取值的字段。
+func ParseWithParams(sql string, params map[string]any) (*types.Config, string, error) {
+	sql, err := prepareSingleStatement(sql)
+	if err != nil {
+		return nil, "", err
+	}
+	if sql, err = expandNamedWindows(sql); err != nil {
+		return nil, "", err
+	}
 	parser := NewParser(sql)
+	parser.params = params
 	stmt, err := parser.Parse()
 	if err != nil {
 		return nil, "", err
@@ -1595,9 +1805,12 @@ func Parse(sql string) (*types.Config, string, error) {
 	// Reject malformed GROUP BY. 用原始 stmt.GroupBy（extractGroupFields 过滤前），
 	// 否则 isAggregationFunction 的"含括号保守判聚合"兜底会把拼错的窗口函数
 	// （如 InvalidWindow('5s')）当聚合丢掉，使 config.GroupFields 为空、校验落空。
-	// 合法分组项：裸列名，或顶层为已注册标量函数的表达式（如 upper(device)）。
+	// 合法分组项：裸列名、顶层为已注册标量函数的表达式（如 upper(device)）或 CASE 表达式。
 	// 引号 artifact 或未注册函数 → 视为拼错的窗口函数泄漏，拒绝。
 	for _, g := range stmt.GroupBy {
+		if isCaseExpr(g) {
+			continue
+		}
 		if strings.ContainsAny(g, "'\"") {
 			return nil, "", fmt.Errorf("invalid GROUP BY field %q: unknown window function or unsupported expression", g)
 		}
@@ -1608,3 +1821,25 @@ func Parse(sql string) (*types.Config, string, error) {
 
 	return config, condition, nil
 }
+
+// distinctAggRe 匹配聚合函数参数前的 DISTINCT：COUNT(DISTINCT x)
+var distinctAggRe = regexp.MustCompile(`(?i)\b([a-z_][a-z0-9_]*)\s*\(\s*DISTINCT\s+`)
+
+// rewriteDistinctAggregates 把 COUNT(DISTINCT x) 改写为精确去重计数 count_distinct(x)，
+// 后续按普通聚合函数处理。DISTINCT 只支持用于 COUNT，其他函数记录语法错误。
+func (p *Parser) rewriteDistinctAggregates(expr string) string {
+	return distinctAggRe.ReplaceAllStringFunc(expr, func(m string) string {
+		name := distinctAggRe.FindStringSubmatch(m)[1]
+		if !strings.EqualFold(name, "count") {
+			pos, _, _ := p.lexer.GetPosition()
+			p.errorRecovery.AddError(CreateSyntaxError(
+				fmt.Sprintf("DISTINCT is only supported in COUNT(DISTINCT ...), got %s(DISTINCT ...)", name),
+				pos-len(expr),
+				name,
+				[]string{"COUNT(DISTINCT field)"},
+			))
+			return m
+		}
+		return "count_distinct("
+	})
+}
@@ -410,7 +410,7 @@ func (p *Parser) readMRUntilAS() (string, error) {
 		case TokenRParen, TokenRBrace:
 			depth--
 		}
-		parts = append(parts, t.Value)
+		parts = append(parts, p.mrTokenText(t))
 	}
 	return "", errors.New("MEASURES expression too long (missing AS)")
 }
@@ -438,7 +438,16 @@ func (p *Parser) readMRExpr() (string, error) {
 		case TokenRParen, TokenRBrace:
 			depth--
 		}
-		parts = append(parts, t.Value)
+		parts = append(parts, p.mrTokenText(t))
 	}
 	return "", errors.New("expression too long in MATCH_RECOGNIZE")
 }
+
+// mrTokenText 返回 token 在 MATCH_RECOGNIZE 表达式中的文本。DEFINE/MEASURES 中双引号
+// 始终是字符串常量（"end"），词法分析改写成的标识符在此还原为原文。
+func (p *Parser) mrTokenText(t Token) string {
+	if t.Type == TokenQuotedIdent && t.Pos < len(p.lexer.input) && p.lexer.input[t.Pos] == '"' {
+		return `"` + stripBackticks(t.Value) + `"`
+	}
+	return t.Value
+}
@@ -4,6 +4,10 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/window"
 )
 
 // TestNewParser 测试解析器的创建
@@ -168,6 +172,34 @@ func TestParserFieldParsing(t *testing.T) {
 			t.Errorf("Expected second field alias to be 'upper_name', got %s", stmt.Fields[1].Alias)
 		}
 	})
+
+	// 测试双引号标识符：需要引号的名称与反引号等价，其余双引号与单引号仍为字符串常量，AS 之后是列名
+	t.Run("double-quoted identifiers", func(t *testing.T) {
+		sql := `SELECT "device id", "count" AS c, "plain", 'device id', v AS "the value" FROM stream WHERE "device id" = 'a'`
+		stmt, err := NewParser(sql).Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if stmt.Condition != "`device id` == 'a'" {
+			t.Errorf("condition = %q, want %q", stmt.Condition, "`device id` == 'a'")
+		}
+		expected := []Field{
+			{Expression: "`device id`"},
+			{Expression: "`count`", Alias: "c"},
+			{Expression: `"plain"`},
+			{Expression: "'device id'"},
+			{Expression: "v", Alias: "the value"},
+		}
+		if len(stmt.Fields) != len(expected) {
+			t.Fatalf("Expected %d fields, got %d", len(expected), len(stmt.Fields))
+		}
+		for i, want := range expected {
+			got := stmt.Fields[i]
+			if got.Expression != want.Expression || got.Alias != want.Alias {
+				t.Errorf("field %d: expected %q AS %q, got %q AS %q", i, want.Expression, want.Alias, got.Expression, got.Alias)
+			}
+		}
+	})
 }
 
 // TestParserWindowFunctionParsing 测试窗口函数解析
@@ -217,6 +249,38 @@ func TestParserWindowFunctionParsing(t *testing.T) {
 	})
 }
 
+// TestParserSessionWindowGap 带引号的会话间隔是必须能解析的时长，未加引号的标识符/表达式按行取间隔
+func TestParserSessionWindowGap(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT COUNT(*) FROM stream GROUP BY SessionWindow('5mins')",
+		"SELECT COUNT(*) FROM stream GROUP BY SessionWindow('invalid')",
+		"SELECT COUNT(*) FROM stream GROUP BY SessionWindow('gap_ms / 1000')",
+	} {
+		if _, _, err := Parse(sql); err == nil || !strings.Contains(err.Error(), "invalid timeout duration") {
+			t.Errorf("Parse(%q) error = %v, want invalid timeout duration", sql, err)
+		}
+	}
+
+	cases := []struct {
+		sql  string
+		want []any
+	}{
+		{"SELECT COUNT(*) FROM stream GROUP BY SessionWindow('5m')", []any{5 * time.Minute}},
+		{"SELECT COUNT(*) FROM stream GROUP BY SessionWindow(gap)", []any{window.SessionGap("gap")}},
+		{"SELECT COUNT(*) FROM stream GROUP BY SessionWindow(gap_ms / 1000, '1m')", []any{window.SessionGap("gap_ms / 1000"), time.Minute}},
+	}
+	for _, c := range cases {
+		cfg, _, err := Parse(c.sql)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", c.sql, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.WindowConfig.Params, c.want) {
+			t.Errorf("Parse(%q) params = %#v, want %#v", c.sql, cfg.WindowConfig.Params, c.want)
+		}
+	}
+}
+
 // TestParserGroupByParsing 测试GROUP BY解析
 func TestParserGroupByParsing(t *testing.T) {
 	// 测试单个GROUP BY字段
@@ -254,6 +318,46 @@ func TestParserGroupByParsing(t *testing.T) {
 			t.Errorf("Expected group by fields %v, got %v", expectedGroupBy, stmt.GroupBy)
 		}
 	})
+
+	// 测试多参数函数作为GROUP BY字段：函数参数中的逗号不分隔分组项
+	t.Run("multi-argument function group by field", func(t *testing.T) {
+		sql := "SELECT geohash(lat, lon, 5) AS cell, COUNT(*) FROM stream GROUP BY geohash(lat, lon, 5), region, TumblingWindow('10s')"
+		stmt, err := NewParser(sql).Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		expectedGroupBy := []string{"geohash(lat,lon,5)", "region"}
+		if !reflect.DeepEqual(stmt.GroupBy, expectedGroupBy) {
+			t.Errorf("Expected group by fields %v, got %v", expectedGroupBy, stmt.GroupBy)
+		}
+
+		config, _, err := stmt.ToStreamConfig()
+		if err != nil {
+			t.Fatalf("ToStreamConfig() error = %v", err)
+		}
+		if got := config.SelectFields["cell"]; got != "expression" {
+			t.Errorf("Expected scalar function field to stay an expression, got %q", got)
+		}
+		if got := config.SelectAlias["geohash(lat,lon,5)"]; got != "cell" {
+			t.Errorf("Expected GROUP BY key to resolve to alias cell, got %q", got)
+		}
+	})
+
+	// 测试CASE表达式作为GROUP BY字段：保留单空格连接的token文本，并与SELECT别名关联
+	t.Run("case expression group by field", func(t *testing.T) {
+		sql := "SELECT CASE WHEN temperature>30 THEN 'hot' ELSE 'cold' END AS bucket, COUNT(*) AS c FROM stream GROUP BY CASE WHEN temperature > 30 THEN 'hot' ELSE 'cold' END, TumblingWindow('5s')"
+		config, _, err := Parse(sql)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		key := "CASE WHEN temperature > 30 THEN 'hot' ELSE 'cold' END"
+		if !reflect.DeepEqual(config.GroupFields, []string{key}) {
+			t.Errorf("Expected group fields [%s], got %v", key, config.GroupFields)
+		}
+		if got := config.SelectAlias[key]; got != "bucket" {
+			t.Errorf("Expected GROUP BY key to resolve to alias bucket, got %q", got)
+		}
+	})
 }
 
 // TestParserLimitParsing 测试LIMIT解析
@@ -301,6 +405,139 @@ func TestParserLimitParsing(t *testing.T) {
 	})
 }
 
+// TestParserInsertInto 测试可选的 INSERT INTO <sink> 前缀
+func TestParserInsertInto(t *testing.T) {
+	tests := []struct {
+		sql    string
+		target string
+	}{
+		{"INSERT INTO alertTopic SELECT deviceId FROM stream WHERE temperature > 40", "alertTopic"},
+		{"insert into `alert-topic` SELECT * FROM stream", "alert-topic"},
+		{"SELECT insert, into FROM stream", ""},
+	}
+	for _, tt := range tests {
+		stmt, err := NewParser(tt.sql).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.sql, err)
+		}
+		if stmt.InsertInto != tt.target {
+			t.Errorf("Parse(%q) InsertInto = %q, want %q", tt.sql, stmt.InsertInto, tt.target)
+		}
+	}
+
+	config, _, err := Parse("INSERT INTO alertTopic SELECT deviceId, temperature FROM stream WHERE temperature > 40")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.InsertInto != "alertTopic" || !reflect.DeepEqual(config.SimpleFields, []string{"deviceId", "temperature"}) {
+		t.Errorf("unexpected config: InsertInto=%q SimpleFields=%v", config.InsertInto, config.SimpleFields)
+	}
+
+	for _, sql := range []string{
+		"INSERT alertTopic SELECT * FROM stream",
+		"INSERT INTO SELECT * FROM stream",
+		"INSERT INTO 'alertTopic' SELECT * FROM stream",
+	} {
+		if _, _, err := Parse(sql); err == nil {
+			t.Errorf("Parse(%q) expected error", sql)
+		}
+	}
+}
+
+// TestParserWithClauseWithoutWindow 无窗口查询的 WITH 选项互不覆盖
+func TestParserWithClauseWithoutWindow(t *testing.T) {
+	stmt, err := NewParser("SELECT * FROM events WITH (TIMESTAMP='ts', TIMEUNIT='ss')").Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if stmt.Window.TsProp != "ts" || stmt.Window.TimeUnit != time.Second {
+		t.Errorf("Expected TsProp ts and TimeUnit 1s, got %q and %v", stmt.Window.TsProp, stmt.Window.TimeUnit)
+	}
+}
+
+// TestParserWithEventTimeOptions WITH 的事件时间别名选项映射到窗口配置，非法时长报错
+func TestParserWithEventTimeOptions(t *testing.T) {
+	cfg, _, err := Parse("SELECT deviceId, COUNT(*) AS c FROM stream GROUP BY deviceId, TumblingWindow('5s') " +
+		"WITH (TIMESTAMP='ts', TIMEUNIT='ms', WATERMARK='eventtime', MAXDELAY='2s', LATENESS='1s', IDLETIMEOUT='30s')")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	wc := cfg.WindowConfig
+	if wc.TsProp != "ts" || wc.TimeCharacteristic != types.EventTime {
+		t.Errorf("event time = %q/%v, want ts/EventTime", wc.TsProp, wc.TimeCharacteristic)
+	}
+	if wc.MaxOutOfOrderness != 2*time.Second || wc.AllowedLateness != time.Second || wc.IdleTimeout != 30*time.Second {
+		t.Errorf("durations = %v/%v/%v, want 2s/1s/30s", wc.MaxOutOfOrderness, wc.AllowedLateness, wc.IdleTimeout)
+	}
+
+	// WATERMARK 指定字段即启用事件时间；时长值设置水位线更新间隔
+	cfg, _, err = Parse("SELECT COUNT(*) AS c FROM stream GROUP BY TumblingWindow('5s') WITH (watermark='eventTime', Watermark='50ms')")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cfg.WindowConfig.TsProp != "eventTime" || cfg.WindowConfig.TimeCharacteristic != types.EventTime {
+		t.Errorf("WATERMARK field = %q/%v, want eventTime/EventTime", cfg.WindowConfig.TsProp, cfg.WindowConfig.TimeCharacteristic)
+	}
+	if cfg.WindowConfig.WatermarkInterval != 50*time.Millisecond {
+		t.Errorf("WatermarkInterval = %v, want 50ms", cfg.WindowConfig.WatermarkInterval)
+	}
+
+	for _, sql := range []string{
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', MAXDELAY='2 seconds')",
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', LATENESS='-1s')",
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', MAXOUTOFORDERNESS='abc')",
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', IDLETIMEOUT='5')",
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', WATERMARK='0s')",
+		"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (WATERMARK='other', TIMESTAMP='ts')",
+	} {
+		if _, _, err := Parse(sql); err == nil {
+			t.Errorf("Parse(%q) expected error", sql)
+		}
+	}
+	if _, _, err := Parse("SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('5s') WITH (TIMESTAMP='ts', MAXDELAY='2 seconds')"); err == nil ||
+		!strings.Contains(err.Error(), "MAXDELAY") {
+		t.Errorf("error should name the option, got %v", err)
+	}
+}
+
+// TestParseWithParams 窗口函数参数引用执行期参数
+func TestParseWithParams(t *testing.T) {
+	cases := []struct {
+		sql    string
+		params map[string]any
+		want   []any
+	}{
+		{"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow(window_size)", map[string]any{"window_size": "5s"}, []any{5 * time.Second}},
+		{"SELECT COUNT(*) FROM stream GROUP BY SlidingWindow(size, slide)", map[string]any{"size": time.Minute, "slide": 10}, []any{time.Minute, 10 * time.Second}},
+		{"SELECT COUNT(*) FROM stream GROUP BY CountingWindow(n, '1s')", map[string]any{"n": 50}, []any{50, time.Second}},
+		// 参数优先于同名字段；不在参数中的标识符仍按字段处理
+		{"SELECT COUNT(*) FROM stream GROUP BY SessionWindow(gap)", map[string]any{"gap": "2m"}, []any{2 * time.Minute}},
+		{"SELECT COUNT(*) FROM stream GROUP BY SessionWindow(gap)", map[string]any{"other": "2m"}, []any{window.SessionGap("gap")}},
+		// 引号内的值不是参数
+		{"SELECT COUNT(*) FROM stream GROUP BY TumblingWindow('3s')", map[string]any{"3s": "1h"}, []any{3 * time.Second}},
+	}
+	for _, c := range cases {
+		cfg, _, err := ParseWithParams(c.sql, c.params)
+		if err != nil {
+			t.Errorf("ParseWithParams(%q) error = %v", c.sql, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.WindowConfig.Params, c.want) {
+			t.Errorf("ParseWithParams(%q) params = %#v, want %#v", c.sql, cfg.WindowConfig.Params, c.want)
+		}
+	}
+
+	for _, params := range []map[string]any{nil, {"other": "5s"}} {
+		_, _, err := ParseWithParams("SELECT COUNT(*) FROM stream GROUP BY TumblingWindow(window_size)", params)
+		if err == nil || !strings.Contains(err.Error(), "missing parameter window_size") {
+			t.Errorf("unresolved window parameter with %v: error = %v, want missing parameter window_size", params, err)
+		}
+	}
+	if _, _, err := ParseWithParams("SELECT COUNT(*) FROM stream GROUP BY TumblingWindow(window_size)", map[string]any{"window_size": "soon"}); err == nil {
+		t.Error("invalid window parameter value: expected error")
+	}
+}
+
 // TestParserWhereClauseParsing 测试WHERE子句解析
 func TestParserWhereClauseParsing(t *testing.T) {
 	// 测试简单的WHERE条件
@@ -408,6 +645,34 @@ func TestParserEnhancedCoverage(t *testing.T) {
 		}
 	})
 
+	// COUNT(DISTINCT x) 改写为精确去重计数 count_distinct(x)；其他聚合不支持 DISTINCT
+	t.Run("parse count distinct", func(t *testing.T) {
+		stmt, err := NewParser("SELECT deviceId, COUNT(DISTINCT sensor) AS n FROM stream GROUP BY deviceId, TumblingWindow('5s') HAVING count(distinct sensor) > 1").Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if stmt.Distinct {
+			t.Error("COUNT(DISTINCT ...) must not set SELECT DISTINCT")
+		}
+		if stmt.Fields[1].Expression != "count_distinct(sensor)" {
+			t.Errorf("Expected 'count_distinct(sensor)', got %s", stmt.Fields[1].Expression)
+		}
+		if !strings.Contains(stmt.Having, "count_distinct(") {
+			t.Errorf("Expected HAVING rewritten to count_distinct, got %s", stmt.Having)
+		}
+		config, _, err := stmt.ToStreamConfig()
+		if err != nil {
+			t.Fatalf("ToStreamConfig() error = %v", err)
+		}
+		if config.SelectFields["n"] != "count_distinct" || config.FieldAlias["n"] != "sensor" {
+			t.Errorf("Expected n -> count_distinct(sensor), got %v / %v", config.SelectFields["n"], config.FieldAlias["n"])
+		}
+
+		if _, err := NewParser("SELECT SUM(DISTINCT v) AS s FROM stream GROUP BY TumblingWindow('5s')").Parse(); err == nil {
+			t.Error("Expected error for SUM(DISTINCT ...)")
+		}
+	})
+
 	// 测试解析SELECT DISTINCT
 	t.Run("parse select distinct", func(t *testing.T) {
 		sql := "SELECT DISTINCT category FROM products"
@@ -0,0 +1,191 @@
+package rsql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// intervalPredicateRe matches the time-range predicate of a self-join:
+// "b.ts - a.ts BETWEEN 0 AND 5000" (optionally NOT BETWEEN, which is rejected).
+// The parser has already rewritten the BETWEEN's AND to "&&".
+var intervalPredicateRe = regexp.MustCompile(`(?i)\b([a-z_][a-z0-9_]*)\.([a-z_][a-z0-9_]*)\s*-\s*([a-z_][a-z0-9_]*)\.([a-z_][a-z0-9_]*)\s+(NOT\s+)?BETWEEN\s+(-?\s*[0-9]+(?:\.[0-9]+)?)\s+(?:AND|&&)\s+(-?\s*[0-9]+(?:\.[0-9]+)?)`)
+
+// buildIntervalJoin turns a parsed self-join ("FROM stream a JOIN stream b ON
+// a.k = b.k") and the time-range predicate of its WHERE clause into an
+// IntervalJoinConfig. The predicate stays in WHERE and is re-checked on each
+// joined row; here it only bounds how long rows are buffered, so it must be an
+// AND term of the whole condition. Parentheses around it, or around a group
+// of AND terms containing it, are fine: "WHERE (b.ts - a.ts BETWEEN 0 AND 10)".
+func buildIntervalJoin(s *SelectStatement) (*types.IntervalJoinConfig, error) {
+	jc := s.SelfJoin
+	left, right := s.SourceAlias, jc.Alias
+	if left == "" || left == right {
+		return nil, fmt.Errorf("stream self-join needs a distinct alias for each side, e.g. FROM %s a JOIN %s b", s.Source, s.Source)
+	}
+	if jc.JoinType != "INNER" {
+		return nil, fmt.Errorf("%s JOIN is not supported for stream self-join", jc.JoinType)
+	}
+	if len(s.JoinConfigs) > 0 {
+		return nil, fmt.Errorf("stream self-join cannot be combined with table JOINs yet")
+	}
+
+	ij := &types.IntervalJoinConfig{LeftAlias: left, RightAlias: right}
+	for _, p := range jc.OnPairs {
+		la, lf := splitAliasField(p.StreamField)
+		ra, rf := splitAliasField(p.TableField)
+		switch {
+		case la == left && ra == right:
+		case la == right && ra == left:
+			lf, rf = rf, lf
+		default:
+			return nil, fmt.Errorf("self-join ON must compare a field of %s with a field of %s, got %s = %s", left, right, p.StreamField, p.TableField)
+		}
+		ij.Keys = append(ij.Keys, types.IntervalJoinKey{Left: lf, Right: rf})
+	}
+
+	loc := intervalPredicateRe.FindStringSubmatchIndex(s.Condition)
+	if loc == nil || loc[10] >= 0 || !inAndGroups(s.Condition, loc[0]) || hasTopLevelOr(s.Condition) {
+		return nil, fmt.Errorf("stream self-join requires a WHERE term %s.<time> - %s.<time> BETWEEN <low> AND <high>, combined with other terms only by AND", right, left)
+	}
+	m := func(i int) string { return s.Condition[loc[2*i]:loc[2*i+1]] }
+	minuendAlias, minuendField, subtrahendAlias, subtrahendField := m(1), m(2), m(3), m(4)
+	if minuendField != subtrahendField {
+		return nil, fmt.Errorf("self-join time predicate must use the same field on both sides, got %s and %s", minuendField, subtrahendField)
+	}
+	low, err := strconv.ParseFloat(strings.ReplaceAll(m(6), " ", ""), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid self-join lower bound %q: %w", m(6), err)
+	}
+	high, err := strconv.ParseFloat(strings.ReplaceAll(m(7), " ", ""), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid self-join upper bound %q: %w", m(7), err)
+	}
+	if low > high {
+		return nil, fmt.Errorf("self-join time range is empty: BETWEEN %v AND %v", low, high)
+	}
+	switch {
+	case minuendAlias == right && subtrahendAlias == left:
+		ij.Lower, ij.Upper = low, high
+	case minuendAlias == left && subtrahendAlias == right:
+		// a.ts - b.ts BETWEEN low AND high  <=>  b.ts - a.ts BETWEEN -high AND -low
+		ij.Lower, ij.Upper = -high, -low
+	default:
+		return nil, fmt.Errorf("self-join time predicate must subtract %s and %s fields, got %s.%s - %s.%s",
+			left, right, minuendAlias, minuendField, subtrahendAlias, subtrahendField)
+	}
+	ij.TimeField = minuendField
+	return ij, nil
+}
+
+// splitAliasField splits "a.deviceId" into ("a", "deviceId"); an unqualified
+// name has an empty alias.
+func splitAliasField(field string) (alias, name string) {
+	if i := strings.IndexByte(field, '.'); i > 0 {
+		return field[:i], field[i+1:]
+	}
+	return "", field
+}
+
+// inAndGroups reports whether the term at byte offset pos is an AND term of
+// cond: every parenthesized group enclosing it must be a plain grouping (not
+// a function call or NOT (...)) whose own top level has no OR.
+func inAndGroups(cond string, pos int) bool {
+	var opens []int
+	var quote byte
+	for i := 0; i < pos && i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			opens = append(opens, i)
+		case c == ')' && len(opens) > 0:
+			opens = opens[:len(opens)-1]
+		}
+	}
+	for _, o := range opens {
+		prefix := strings.TrimRight(cond[:o], " \t\r\n")
+		if n := len(prefix); n > 0 && isIdentByte(prefix[n-1]) {
+			j := n
+			for j > 0 && isIdentByte(prefix[j-1]) {
+				j--
+			}
+			// A word before "(" is a function name or NOT; AND is the only
+			// keyword that keeps the group a conjunction term.
+			if !strings.EqualFold(prefix[j:], "AND") {
+				return false
+			}
+		}
+		c := closingParen(cond, o)
+		if c < 0 || hasTopLevelOr(cond[o+1:c]) {
+			return false
+		}
+	}
+	return true
+}
+
+// closingParen returns the index of the ")" matching the "(" at open, or -1.
+func closingParen(cond string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasTopLevelOr reports whether cond contains an OR ("||" or the keyword)
+// outside parentheses and string literals.
+func hasTopLevelOr(cond string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth != 0:
+		case c == '|' && i+1 < len(cond) && cond[i+1] == '|':
+			return true
+		case (c == 'o' || c == 'O') && i+1 < len(cond) && (cond[i+1] == 'r' || cond[i+1] == 'R') &&
+			(i == 0 || !isIdentByte(cond[i-1])) && (i+2 == len(cond) || !isIdentByte(cond[i+2])):
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,82 @@
+package rsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scanSQL 以引号感知的方式扫描 SQL：'...'、"..." 字符串与 `...` 标识符原样保留，
+// -- 行注释与 /* */ 块注释替换为等长空白（保留换行，使错误位置不偏移）。
+// onSemicolon 非 nil 时，顶层 ';' 的下标会回调给它（';' 本身也替换为空格）。
+func scanSQL(sql string, onSemicolon func(i int)) string {
+	buf := []byte(sql)
+	for i := 0; i < len(buf); i++ {
+		switch ch := buf[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			j := i + 1
+			for j < len(buf) && buf[j] != ch {
+				j++
+			}
+			i = j
+		case ch == '-' && i+1 < len(buf) && buf[i+1] == '-':
+			for i < len(buf) && buf[i] != '\n' {
+				buf[i] = ' '
+				i++
+			}
+		case ch == '/' && i+1 < len(buf) && buf[i+1] == '*':
+			buf[i], buf[i+1] = ' ', ' '
+			i += 2
+			for i < len(buf) && !(buf[i] == '*' && i+1 < len(buf) && buf[i+1] == '/') {
+				if buf[i] != '\n' {
+					buf[i] = ' '
+				}
+				i++
+			}
+			if i < len(buf) {
+				buf[i], buf[i+1] = ' ', ' '
+				i++
+			}
+		case ch == ';' && onSemicolon != nil:
+			onSemicolon(i)
+			buf[i] = ' '
+		}
+	}
+	return string(buf)
+}
+
+// StripComments 去掉 SQL 中的 -- 与 /* */ 注释，字符串字面量与反引号标识符内的内容不受影响。
+// 注释被替换为等长空白，解析错误报告的位置与原始输入一致。
+func StripComments(sql string) string {
+	return scanSQL(sql, nil)
+}
+
+// SplitStatements 按顶层 ';' 拆分多条 SQL 语句，并去掉注释。
+// 引号内的 ';' 不作为分隔符；空语句（如末尾多余的 ';' 或仅含注释的片段）被忽略。
+func SplitStatements(sql string) []string {
+	var cuts []int
+	cleaned := scanSQL(sql, func(i int) { cuts = append(cuts, i) })
+	cuts = append(cuts, len(cleaned))
+
+	var stmts []string
+	start := 0
+	for _, end := range cuts {
+		if stmt := strings.TrimSpace(cleaned[start:end]); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		start = end + 1
+	}
+	return stmts
+}
+
+// prepareSingleStatement 为 Parse 规范化输入：注释与 ';' 替换为空白（位置不变），
+// 包含多条语句时返回错误（多语句请使用 Streamsql.Execute）。
+func prepareSingleStatement(sql string) (string, error) {
+	semicolons := 0
+	cleaned := scanSQL(sql, func(int) { semicolons++ })
+	if semicolons > 0 {
+		if n := len(SplitStatements(sql)); n > 1 {
+			return "", fmt.Errorf("expected a single SQL statement, got %d; use Streamsql.Execute for multiple statements", n)
+		}
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,67 @@
+package rsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSplitStatements 测试按顶层 ';' 拆分语句，引号内的 ';' 与注释标记不受影响
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"单条语句", "SELECT a FROM stream", []string{"SELECT a FROM stream"}},
+		{"末尾分号", "SELECT a FROM stream;", []string{"SELECT a FROM stream"}},
+		{"多条语句", "SELECT a FROM stream; SELECT b FROM stream;;", []string{"SELECT a FROM stream", "SELECT b FROM stream"}},
+		{"字符串内分号", "SELECT a FROM stream WHERE s = 'x;y'", []string{"SELECT a FROM stream WHERE s = 'x;y'"}},
+		{"注释内分号", "SELECT a FROM stream -- a; b\n", []string{"SELECT a FROM stream"}},
+		{"仅注释", "-- nothing here;\n/* ; */", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, SplitStatements(test.input))
+		})
+	}
+}
+
+// TestStripComments 测试去除注释时保留字符串字面量与位置
+func TestStripComments(t *testing.T) {
+	input := "SELECT a, -- c1\n '--x' /* c2 */ FROM s"
+	out := StripComments(input)
+	assert.Equal(t, len(input), len(out), "注释替换为等长空白，位置不变")
+	assert.NotContains(t, out, "c1")
+	assert.NotContains(t, out, "c2")
+	assert.Contains(t, out, "'--x'")
+	assert.Contains(t, out, "\n", "行注释保留换行")
+}
+
+// TestParseWithComments 测试带注释与末尾分号的 SQL 能正常解析
+func TestParseWithComments(t *testing.T) {
+	sql := `-- 设备温度监控
+SELECT deviceId, /* 平均温度 */ AVG(temperature) AS avg_temp
+FROM stream -- 输入流
+WHERE deviceId != '--not-a-comment' AND note != '/* keep */'
+GROUP BY deviceId, TumblingWindow('5s') /* 窗口 */
+LIMIT 10 -- 最多10行
+;`
+	config, condition, err := Parse(sql)
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	assert.Equal(t, []string{"deviceId"}, config.GroupFields)
+	assert.Equal(t, 10, config.Limit)
+	assert.Contains(t, condition, "--not-a-comment")
+	assert.Contains(t, condition, "/* keep */")
+	assert.NotContains(t, condition, "输入流")
+}
+
+// TestParseRejectsMultipleStatements 测试 Parse 只接受单条语句
+func TestParseRejectsMultipleStatements(t *testing.T) {
+	_, _, err := Parse("SELECT a FROM stream; SELECT b FROM stream")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single SQL statement")
+}
@@ -0,0 +1,241 @@
+package rsql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// expandNamedWindows 展开命名窗口：WINDOW w AS (PARTITION BY ... [WHEN ...])[, w2 AS (...)]
+// 定义一次，可被多个分析函数以 OVER w 引用。在词法分析之前以文本宏的方式处理：
+// 去掉 WINDOW 子句，并把每个 OVER w 替换为 OVER (<定义体>)，因此 SELECT 与 WHERE
+// 中的引用与内联 OVER(...) 完全等价（定义体的语法与限制同内联 OVER）。
+// 输入须已去除注释（见 prepareSingleStatement）。窗口名大小写不敏感。
+// 定义体中的 ORDER BY 只由排名/整分区函数（rank、ntile 等）继承；其余函数（lag 等）
+// 共享同一命名窗口时忽略它，按到达顺序求值，与不写 ORDER BY 的内联 OVER 相同。
+func expandNamedWindows(sql string) (string, error) {
+	start, end, defs, err := findWindowClause(sql)
+	if err != nil {
+		return "", err
+	}
+	if defs != nil {
+		sql = sql[:start] + " " + sql[end:]
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, ref := range findOverRefs(sql) {
+		body, ok := defs[strings.ToLower(ref.name)]
+		if !ok {
+			return "", fmt.Errorf("window %q referenced by OVER is not defined in a WINDOW clause", ref.name)
+		}
+		if fn := callNameBefore(sql, ref.over); fn != "" && !isRankingFunction(fn) && !isPartitionFunction(fn) {
+			body = strings.TrimSpace(overOrderClauseRe.ReplaceAllString(body, "$1"))
+		}
+		out.WriteString(sql[last:ref.start])
+		out.WriteString("(" + body + ")")
+		last = ref.end
+	}
+	if last == 0 {
+		return sql, nil
+	}
+	out.WriteString(sql[last:])
+	return out.String(), nil
+}
+
+// findWindowClause 定位顶层（括号外、引号外）的 WINDOW 子句，返回其 [start,end)
+// 区间与 名称(小写)->定义体 映射；不存在时 defs 为 nil。仅 WINDOW <name> AS ( 形式
+// 被视为子句，名为 window 的普通列不受影响。
+func findWindowClause(sql string) (start, end int, defs map[string]string, err error) {
+	start = -1
+	scanTopLevelWords(sql, func(i int, word string) bool {
+		if strings.EqualFold(word, "WINDOW") && windowDefAt(sql, i+len(word)) >= 0 {
+			start = i
+			return false
+		}
+		return true
+	})
+	if start < 0 {
+		return 0, 0, nil, nil
+	}
+
+	defs = make(map[string]string)
+	i := start + len("WINDOW")
+	for {
+		i = skipSpaces(sql, i)
+		name, _ := readIdent(sql, i)
+		lp := windowDefAt(sql, i)
+		if lp < 0 {
+			return 0, 0, nil, fmt.Errorf("expected '<name> AS (...)' in WINDOW clause")
+		}
+		rp := matchParen(sql, lp)
+		if rp < 0 {
+			return 0, 0, nil, fmt.Errorf("unterminated definition of window %q", name)
+		}
+		key := strings.ToLower(name)
+		if _, dup := defs[key]; dup {
+			return 0, 0, nil, fmt.Errorf("window %q is defined more than once", name)
+		}
+		defs[key] = strings.TrimSpace(sql[lp+1 : rp])
+		i = skipSpaces(sql, rp+1)
+		if i < len(sql) && sql[i] == ',' {
+			i++
+			continue
+		}
+		return start, i, defs, nil
+	}
+}
+
+// windowDefAt 检查 sql[i:] 是否以 <name> AS ( 开头（允许前导空白），是则返回 '(' 的下标，否则 -1。
+func windowDefAt(sql string, i int) int {
+	name, next := readIdent(sql, skipSpaces(sql, i))
+	if name == "" {
+		return -1
+	}
+	kw, next := readIdent(sql, skipSpaces(sql, next))
+	if !strings.EqualFold(kw, "AS") {
+		return -1
+	}
+	next = skipSpaces(sql, next)
+	if next >= len(sql) || sql[next] != '(' {
+		return -1
+	}
+	return next
+}
+
+// overRef 是一个 OVER <name> 引用中 <name> 的位置，over 为 OVER 关键字的下标。
+type overRef struct {
+	name       string
+	over       int
+	start, end int
+}
+
+// overOrderClauseRe 匹配 OVER 定义体中的 ORDER BY 子句（到 WHEN/PARTITION BY 或结尾为止），
+// 替换为 $1 即去掉该子句并保留其后的关键字。
+var overOrderClauseRe = regexp.MustCompile(`(?i)\border\s+by\b.*?(\bwhen\b|\bpartition\s+by\b|$)`)
+
+// callNameBefore 返回 OVER 关键字（下标 over）之前函数调用的函数名，如 "lag(x) OVER w" 中的 lag；
+// 前面不是函数调用时返回空串。
+func callNameBefore(sql string, over int) string {
+	k := over - 1
+	for k >= 0 && (sql[k] == ' ' || sql[k] == '\t' || sql[k] == '\n' || sql[k] == '\r') {
+		k--
+	}
+	if k < 0 || sql[k] != ')' {
+		return ""
+	}
+	depth := 0
+	for ; k >= 0; k-- {
+		if sql[k] == ')' {
+			depth++
+		} else if sql[k] == '(' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if k < 0 {
+		return ""
+	}
+	end := k
+	for end > 0 && sql[end-1] == ' ' {
+		end--
+	}
+	begin := end
+	for begin > 0 && (isLetter(sql[begin-1]) || isDigit(sql[begin-1])) {
+		begin--
+	}
+	return sql[begin:end]
+}
+
+// findOverRefs 找出所有 OVER <name>（而非 OVER (...)）引用，按出现顺序返回。
+func findOverRefs(sql string) []overRef {
+	var refs []overRef
+	scanWords(sql, func(i int, word string, _ int) bool {
+		if !strings.EqualFold(word, "OVER") {
+			return true
+		}
+		j := skipSpaces(sql, i+len(word))
+		if name, next := readIdent(sql, j); name != "" {
+			refs = append(refs, overRef{name: name, over: i, start: j, end: next})
+		}
+		return true
+	})
+	return refs
+}
+
+// scanTopLevelWords 对括号深度为 0 的单词调用 fn，fn 返回 false 时停止。
+func scanTopLevelWords(sql string, fn func(i int, word string) bool) {
+	scanWords(sql, func(i int, word string, depth int) bool {
+		if depth != 0 {
+			return true
+		}
+		return fn(i, word)
+	})
+}
+
+// scanWords 以引号感知的方式扫描 SQL 中的标识符/关键字单词，连同所在括号深度回调 fn；
+// fn 返回 false 时停止。引号内的内容被跳过。
+func scanWords(sql string, fn func(i int, word string, depth int) bool) {
+	depth := 0
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			j := i + 1
+			for j < len(sql) && sql[j] != ch {
+				j++
+			}
+			i = j
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case isLetter(ch):
+			word, next := readIdent(sql, i)
+			if !fn(i, word, depth) {
+				return
+			}
+			i = next - 1
+		case isDigit(ch):
+			// 跳过数字（含 1e5 之类），避免把其中的字母当作单词起点
+			for i+1 < len(sql) && (isLetter(sql[i+1]) || isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+		}
+	}
+}
+
+// matchParen 返回与 sql[open]=='(' 配对的 ')' 下标（跳过引号内容），未闭合返回 -1。
+func matchParen(sql string, open int) int {
+	depth := 0
+	for i := open; i < len(sql); i++ {
+		switch ch := sql[i]; ch {
+		case '\'', '"', '`':
+			j := i + 1
+			for j < len(sql) && sql[j] != ch {
+				j++
+			}
+			i = j
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func readIdent(sql string, i int) (string, int) {
+	if i >= len(sql) || !isLetter(sql[i]) {
+		return "", i
+	}
+	j := i + 1
+	for j < len(sql) && (isLetter(sql[j]) || isDigit(sql[j])) {
+		j++
+	}
+	return sql[i:j], j
+}
@@ -0,0 +1,105 @@
+package rsql
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExpandNamedWindows 测试 WINDOW 子句的展开与引用解析
+func TestExpandNamedWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "无命名窗口原样返回",
+			input:    "SELECT lag(v) OVER (PARTITION BY d) AS p FROM stream",
+			expected: "SELECT lag(v) OVER (PARTITION BY d) AS p FROM stream",
+		},
+		{
+			name:     "两个函数共享窗口",
+			input:    "SELECT lag(v) OVER w AS p, acc_sum(v) OVER W AS s FROM stream WINDOW w AS (PARTITION BY d)",
+			expected: "SELECT lag(v) OVER (PARTITION BY d) AS p, acc_sum(v) OVER (PARTITION BY d) AS s FROM stream  ",
+		},
+		{
+			name:     "多个定义且位于 LIMIT 之前",
+			input:    "SELECT lag(v) OVER a AS p, latest(v) OVER b AS l FROM stream WINDOW a AS (PARTITION BY d), b AS (PARTITION BY d WHEN v > (1)) LIMIT 5",
+			expected: "SELECT lag(v) OVER (PARTITION BY d) AS p, latest(v) OVER (PARTITION BY d WHEN v > (1)) AS l FROM stream  LIMIT 5",
+		},
+		{
+			name:     "引号与窗口函数名不受影响",
+			input:    "SELECT window, 'OVER w' AS s FROM stream GROUP BY TumblingWindow('5s')",
+			expected: "SELECT window, 'OVER w' AS s FROM stream GROUP BY TumblingWindow('5s')",
+		},
+		{
+			name:     "ORDER BY 只由排名函数继承",
+			input:    "SELECT rank() OVER w AS r, lag(x) OVER w AS p FROM stream WINDOW w AS (PARTITION BY device ORDER BY ts WHEN x > 0)",
+			expected: "SELECT rank() OVER (PARTITION BY device ORDER BY ts WHEN x > 0) AS r, lag(x) OVER (PARTITION BY device WHEN x > 0) AS p FROM stream  ",
+		},
+		{
+			name:    "引用未定义的窗口",
+			input:   "SELECT lag(v) OVER w AS p FROM stream",
+			wantErr: `window "w" referenced by OVER is not defined`,
+		},
+		{
+			name:    "重复定义",
+			input:   "SELECT lag(v) OVER w AS p FROM stream WINDOW w AS (PARTITION BY a), w AS (PARTITION BY b)",
+			wantErr: "defined more than once",
+		},
+		{
+			name:    "定义未闭合",
+			input:   "SELECT lag(v) OVER w AS p FROM stream WINDOW w AS (PARTITION BY a",
+			wantErr: "unterminated definition",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandNamedWindows(test.input)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+// TestParseNamedWindow 测试多个分析函数经命名窗口得到相同的 OVER 规格
+func TestParseNamedWindow(t *testing.T) {
+	config, _, err := Parse(`SELECT deviceId, lag(temp) OVER w AS prev, acc_sum(temp) OVER w AS total
+FROM stream
+WINDOW w AS (PARTITION BY deviceId WHEN temp > 0)`)
+	require.NoError(t, err)
+	require.Len(t, config.AnalyticFields, 2)
+	want := &types.OverSpec{PartitionBy: []string{"deviceId"}, When: "temp > 0"}
+	for _, f := range config.AnalyticFields {
+		assert.Equal(t, want, f.Over, f.Alias)
+	}
+}
+
+// TestParseNamedWindowInheritedOrderBy 排名函数与 lag 共享带 ORDER BY 的命名窗口
+func TestParseNamedWindowInheritedOrderBy(t *testing.T) {
+	config, _, err := Parse(`SELECT device, rank() OVER w AS r, lag(x) OVER w AS prev
+FROM stream
+WINDOW w AS (PARTITION BY device ORDER BY ts)`)
+	require.NoError(t, err)
+	require.Len(t, config.AnalyticFields, 2)
+	overs := map[string]*types.OverSpec{}
+	for _, f := range config.AnalyticFields {
+		overs[f.Alias] = f.Over
+	}
+	assert.Equal(t, &types.OverSpec{PartitionBy: []string{"device"}, OrderBy: []string{"ts"}, OrderDesc: []bool{false}}, overs["r"])
+	assert.Equal(t, &types.OverSpec{PartitionBy: []string{"device"}}, overs["prev"])
+
+	// 内联 OVER 上显式写给 lag 的 ORDER BY 仍被拒绝
+	_, _, err = Parse("SELECT lag(x) OVER (PARTITION BY device ORDER BY ts) AS prev FROM stream")
+	assert.Error(t, err)
+}
@@ -7,12 +7,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rulego/streamsql/condition"
 	"github.com/rulego/streamsql/expr"
 	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/types"
 	"github.com/rulego/streamsql/utils/fieldpath"
+	"github.com/rulego/streamsql/window"
 )
 
 // defaultMaxPartitions bounds per-field PARTITION state so high-cardinality keys
@@ -43,6 +45,7 @@ type analyticFieldEngine struct {
 	lru           *list.List                // LRU 顺序：front=最近使用，back=待淘汰
 	lastResults   map[string]any            // per-partition 上次结果（WHEN 不满足时复用）
 	maxPartitions int                       // 分区数上限（超出按 LRU 淘汰）
+	windowScoped  bool                      // 含 WindowScopedAnalytic 调用：窗口查询每个窗口重置
 	wrapperParsed *expr.Expression          // wrapper 的 expr 包解析缓存（仅 bridge 失败时用，如 CASE），每字段解析一次
 }
 
@@ -69,6 +72,7 @@ func NewAnalyticEngine(owner *Stream, fields []types.AnalyticField) (*AnalyticEn
 			return nil, err
 		}
 		fe := &analyticFieldEngine{
+			windowScoped:  windowScopedField(af),
 			af:            af,
 			stateCtors:    ctors,
 			partitions:    make(map[string]*list.Element),
@@ -113,6 +117,41 @@ func buildStateCtors(af types.AnalyticField) ([]func() functions.AnalyticState,
 	return ctors, nil
 }
 
+// windowScopedField 字段是否含随查询窗口重置状态的分析调用。
+func windowScopedField(af types.AnalyticField) bool {
+	names := []string{af.FuncName}
+	for _, c := range af.Calls {
+		names = append(names, c.FuncName)
+	}
+	for _, name := range names {
+		if fn, ok := functions.Get(name); ok {
+			if ws, ok := fn.(functions.WindowScopedAnalytic); ok && ws.WindowScoped() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResetWindowScoped 丢弃窗口级字段（window_position 等）的全部分区状态，窗口查询在
+// 每次窗口产出结果行前调用。
+func (e *AnalyticEngine) ResetWindowScoped() {
+	if e == nil {
+		return
+	}
+	for _, fe := range e.fields {
+		if !fe.windowScoped {
+			continue
+		}
+		fe.mu.Lock()
+		fe.noPart = nil
+		fe.partitions = make(map[string]*list.Element)
+		fe.lru.Init()
+		fe.lastResults = make(map[string]any)
+		fe.mu.Unlock()
+	}
+}
+
 // Evaluate 对一行求值所有分析函数字段，返回 map[alias]value。
 func (e *AnalyticEngine) Evaluate(row map[string]any) map[string]any {
 	out := make(map[string]any, len(e.fields))
@@ -221,7 +260,20 @@ func (fe *analyticFieldEngine) evalWrapper(data map[string]any) (any, bool, erro
 }
 
 // applyCall 求单个分析调用：解析参数（含 '*' 整行展开），应用到状态机。
+// 排名函数（row_number/rank/dense_rank）不取参数，改传 OVER ORDER BY 的排序键值。
 func (fe *analyticFieldEngine) applyCall(s *Stream, row map[string]any, c types.AnalyticCall, state functions.AnalyticState) any {
+	if ordered, ok := state.(functions.OrderedAnalyticState); ok {
+		var orderKey []any
+		var desc []bool
+		if fe.af.Over != nil {
+			desc = fe.af.Over.OrderDesc
+			orderKey = make([]any, len(fe.af.Over.OrderBy))
+			for i, k := range fe.af.Over.OrderBy {
+				orderKey[i] = resolvePartitionField(row, k)
+			}
+		}
+		return ordered.ApplyOrdered(orderKey, desc)
+	}
 	args, err := s.parseFunctionArgs(c.BareCall, row)
 	if err != nil || args == nil {
 		args = []any{}
@@ -229,9 +281,18 @@ func (fe *analyticFieldEngine) applyCall(s *Stream, row map[string]any, c types.
 	if hasStarArg(c.Args) {
 		args = expandStarArgs(c.Args, row, args)
 	}
+	if timed, ok := state.(functions.TimedAnalyticState); ok {
+		return timed.ApplyAt(s.rowTime(row), args)
+	}
 	return state.Apply(args)
 }
 
+// rowTime 返回行的事件时间：配置了 WITH (TIMESTAMP=...) 时取该列，列缺失或无法解析
+// 时（及未配置时）退化为处理时间。
+func (s *Stream) rowTime(row map[string]any) time.Time {
+	return window.GetTimestamp(row, s.config.WindowConfig.TsProp, s.config.WindowConfig.TimeUnit)
+}
+
 // evaluateMultiColumn 处理 changed_cols 等多列函数：按 prefix+列名 扇出变化列。
 func (fe *analyticFieldEngine) evaluateMultiColumn(s *Stream, row map[string]any) any {
 	values, err := s.parseFunctionArgs(fe.af.Expression, row)
@@ -396,12 +457,17 @@ func (fe *analyticFieldEngine) getStateLocked(partKey string) []functions.Analyt
 }
 
 func (fe *analyticFieldEngine) partitionKey(row map[string]any) string {
-	if fe.af.Over == nil || len(fe.af.Over.PartitionBy) == 0 {
+	return overPartitionKey(fe.af.Over, row)
+}
+
+// overPartitionKey 按 OVER PARTITION BY 各键的值生成行的分区键，无 PARTITION BY 时为 ""。
+func overPartitionKey(over *types.OverSpec, row map[string]any) string {
+	if over == nil || len(over.PartitionBy) == 0 {
 		return ""
 	}
 	var sb strings.Builder
 	var lbuf [4]byte // 分区键片段长度（十进制，常见 < 1000）
-	for _, k := range fe.af.Over.PartitionBy {
+	for _, k := range over.PartitionBy {
 		tk := typeKey(resolvePartitionField(row, k))
 		// 长度前缀 + 尾分隔，避免值里含 '|' 或类型名导致跨列键碰撞。
 		// 直接写 Builder，省去 fmt.Fprintf 的格式串解析。
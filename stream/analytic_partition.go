@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"github.com/rulego/streamsql/functions"
+	"github.com/rulego/streamsql/types"
+)
+
+// partitionAnalytic 返回字段对应的整分区分析函数（ntile），非此类字段返回 nil。
+func partitionAnalytic(af types.AnalyticField) functions.PartitionAnalytic {
+	fn, ok := functions.Get(af.FuncName)
+	if !ok {
+		return nil
+	}
+	pa, _ := fn.(functions.PartitionAnalytic)
+	return pa
+}
+
+// applyPartitionAnalytic 在一次窗口产出的结果行上求值整分区分析函数（ntile）：按 OVER
+// PARTITION BY 分组，组内按 OVER ORDER BY 稳定排序后逐行写入结果（按 alias）。只重排
+// 组内行的引用，不改变 rows 的输出顺序；状态不跨窗口保留。
+func (s *Stream) applyPartitionAnalytic(rows []map[string]any) {
+	for _, af := range s.config.AnalyticFields {
+		pa := partitionAnalytic(af)
+		if pa == nil {
+			continue
+		}
+		args := make([]any, len(af.Args))
+		for i, a := range af.Args {
+			args[i] = literalValue(a)
+		}
+		var keys []string
+		parts := make(map[string][]map[string]any)
+		for _, r := range rows {
+			k := overPartitionKey(af.Over, r)
+			if _, ok := parts[k]; !ok {
+				keys = append(keys, k)
+			}
+			parts[k] = append(parts[k], r)
+		}
+		sorter := NewSorter(overOrderFields(af.Over))
+		for _, k := range keys {
+			part := parts[k]
+			sorter.Sort(part)
+			for i, r := range part {
+				r[af.Alias] = pa.ApplyPartition(args, i, len(part))
+			}
+		}
+	}
+}
+
+// overOrderFields 把 OVER ORDER BY 转为排序键（方向取 OrderDesc，缺省升序）。
+func overOrderFields(over *types.OverSpec) []types.OrderByField {
+	if over == nil {
+		return nil
+	}
+	keys := make([]types.OrderByField, len(over.OrderBy))
+	for i, k := range over.OrderBy {
+		keys[i] = types.OrderByField{Expression: k, Direction: types.SortAsc}
+		if i < len(over.OrderDesc) && over.OrderDesc[i] {
+			keys[i].Direction = types.SortDesc
+		}
+	}
+	return keys
+}
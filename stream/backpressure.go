@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backpressure event kinds
+const (
+	BackpressureHighWater = "high_water" // Input buffer utilization reached the high water mark
+	BackpressureRecovered = "recovered"  // Utilization fell back to the low water mark
+	BackpressureDrop      = "drop"       // The overflow strategy dropped input records
+)
+
+// defaultBackpressureHighWater is used when BufferConfig.UsageThreshold is unset.
+const defaultBackpressureHighWater = 0.8
+
+// backpressureDropInterval is the minimum gap between two drop events.
+const backpressureDropInterval = time.Second
+
+// BackpressureEvent describes an input overload signal delivered to
+// OnBackpressure callbacks.
+type BackpressureEvent struct {
+	Kind     string // BackpressureHighWater, BackpressureRecovered or BackpressureDrop
+	Strategy string // Overflow strategy of the stream ("drop", "block", "expand" or a custom name)
+	// Utilization is the fill ratio (0..1) of the fuller of the record and
+	// EmitMany batch input buffers when the event was raised.
+	Utilization float64
+	// Dropped is the number of input records dropped since the previous drop
+	// event; drops suppressed by rate limiting are carried into the next one,
+	// which is raised at the end of the interval even if no further drop occurs.
+	Dropped int64
+	// TotalDropped is the number of input records dropped by the overflow
+	// strategy since the first callback was registered.
+	TotalDropped int64
+	Time         time.Time
+}
+
+// backpressureMonitor tracks water mark state and drop counts for OnBackpressure.
+type backpressureMonitor struct {
+	mu        sync.Mutex
+	listeners []func(BackpressureEvent)
+	high, low float64
+	// dropInterval rate-limits drop events; water mark events are bounded by
+	// the hysteresis between high and low instead.
+	dropInterval time.Duration
+	above        int32 // 1 after a high-water event until the matching recovery (atomic)
+	lastDrop     time.Time
+	pending      int64 // drops not yet reported
+	total        int64
+	flushArmed   bool // a trailing drop event is scheduled for the end of the interval
+}
+
+// OnBackpressure registers a callback for input overload signals:
+//   - BackpressureHighWater when input buffer utilization reaches
+//     BufferConfig.UsageThreshold (0.8 when unset),
+//   - BackpressureRecovered when it falls back to half of that threshold,
+//   - BackpressureDrop when the overflow strategy drops input records, at most
+//     once per second; drops in between are accumulated and reported when the
+//     second ends, so the last burst is never left unreported.
+//
+// The water marks use hysteresis, so a buffer hovering around the threshold
+// does not raise a stream of events. Callbacks run on the goroutine that
+// detected the condition (an Emit caller or the data processor) and should
+// return quickly. Records rejected after CloseInput or Stop are not reported.
+// Registered callbacks move to the replacement stream on HandOver.
+func (s *Stream) OnBackpressure(fn func(event BackpressureEvent)) {
+	if fn == nil {
+		return
+	}
+	s.backpressureMu.Lock()
+	defer s.backpressureMu.Unlock()
+	m := s.backpressureMonitor()
+	if m == nil {
+		high := s.config.PerformanceConfig.BufferConfig.UsageThreshold
+		if high <= 0 || high > 1 {
+			high = defaultBackpressureHighWater
+		}
+		m = &backpressureMonitor{high: high, low: high / 2, dropInterval: backpressureDropInterval}
+	}
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	m.mu.Unlock()
+	s.backpressure.Store(m)
+}
+
+// backpressureMonitor returns the monitor, or nil when no callback is registered.
+func (s *Stream) backpressureMonitor() *backpressureMonitor {
+	m, _ := s.backpressure.Load().(*backpressureMonitor)
+	return m
+}
+
+// backpressureListeners returns a snapshot of the registered callbacks.
+func (s *Stream) backpressureListeners() []func(BackpressureEvent) {
+	m := s.backpressureMonitor()
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]func(BackpressureEvent){}, m.listeners...)
+}
+
+// inputUtilization returns the fill ratio of the fuller input buffer.
+func (s *Stream) inputUtilization() float64 {
+	s.dataChanMux.RLock()
+	defer s.dataChanMux.RUnlock()
+	var u float64
+	if c := cap(s.dataChan); c > 0 {
+		u = float64(len(s.dataChan)) / float64(c)
+	}
+	if c := cap(s.batchChan); c > 0 {
+		if b := float64(len(s.batchChan)) / float64(c); b > u {
+			u = b
+		}
+	}
+	return u
+}
+
+// checkBackpressure raises a water mark event when utilization crossed the
+// high or low mark since the last one. No-op without callbacks.
+func (s *Stream) checkBackpressure() {
+	m := s.backpressureMonitor()
+	if m == nil {
+		return
+	}
+	u := s.inputUtilization()
+	above := atomic.LoadInt32(&m.above) == 1
+	if (!above && u < m.high) || (above && u > m.low) {
+		return
+	}
+
+	m.mu.Lock()
+	var kind string
+	switch {
+	case !above && u >= m.high && atomic.CompareAndSwapInt32(&m.above, 0, 1):
+		kind = BackpressureHighWater
+	case above && u <= m.low && atomic.CompareAndSwapInt32(&m.above, 1, 0):
+		kind = BackpressureRecovered
+	default:
+		// Another goroutine reported this crossing first
+		m.mu.Unlock()
+		return
+	}
+	event := s.newBackpressureEvent(m, kind, u)
+	listeners := m.listeners
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// recordInputDrop counts n input records dropped by the overflow strategy and
+// raises a drop event unless one was raised within the drop interval, in which
+// case a trailing event is scheduled for the end of the interval.
+func (s *Stream) recordInputDrop(n int64) {
+	s.mInputDropped.IncBy(n)
+	m := s.backpressureMonitor()
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	m.pending += n
+	m.total += n
+	if elapsed := now.Sub(m.lastDrop); elapsed < m.dropInterval {
+		if !m.flushArmed {
+			m.flushArmed = true
+			time.AfterFunc(m.dropInterval-elapsed, func() { s.flushInputDrops(m) })
+		}
+		m.mu.Unlock()
+		return
+	}
+	s.raiseInputDrop(m, now)
+}
+
+// flushInputDrops reports the drops accumulated during the last interval.
+func (s *Stream) flushInputDrops(m *backpressureMonitor) {
+	m.mu.Lock()
+	m.flushArmed = false
+	if m.pending == 0 {
+		m.mu.Unlock()
+		return
+	}
+	s.raiseInputDrop(m, time.Now())
+}
+
+// raiseInputDrop reports the pending drops. The caller holds m.mu, which is
+// released before the callbacks run.
+func (s *Stream) raiseInputDrop(m *backpressureMonitor, now time.Time) {
+	m.lastDrop = now
+	event := s.newBackpressureEvent(m, BackpressureDrop, s.inputUtilization())
+	event.Dropped = m.pending
+	m.pending = 0
+	listeners := m.listeners
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// newBackpressureEvent builds an event from the monitor state. The caller holds m.mu.
+func (s *Stream) newBackpressureEvent(m *backpressureMonitor, kind string, utilization float64) BackpressureEvent {
+	return BackpressureEvent{
+		Kind:         kind,
+		Strategy:     s.dataStrategy.GetStrategyName(),
+		Utilization:  utilization,
+		TotalDropped: m.total,
+		Time:         time.Now(),
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"github.com/rulego/streamsql/metrics"
+	"github.com/rulego/streamsql/utils/fieldpath"
+)
+
+// cardinalityTracker estimates the distinct values of one input field.
+type cardinalityTracker struct {
+	field  string
+	sketch *metrics.Cardinality
+}
+
+// TrackCardinality keeps a running estimate of the number of distinct values of
+// field (a nested path such as "device.id" is allowed) across all input rows,
+// independent of the query. The estimate appears in GetDetailedStats under
+// "cardinality" and in the metrics registry as "cardinality_<field>", to watch
+// key cardinality growth before it threatens memory. It uses a fixed-size
+// HyperLogLog sketch (~16KB per field, ~1.6% standard error); rows where the
+// field is missing or NULL are not counted. Tracking the same field twice is a
+// no-op. Without any tracked field the ingest path does no extra work.
+func (s *Stream) TrackCardinality(field string) {
+	s.cardinalityMu.Lock()
+	defer s.cardinalityMu.Unlock()
+	cur := s.cardinalityTrackers()
+	for _, t := range cur {
+		if t.field == field {
+			return
+		}
+	}
+	sketch := metrics.NewCardinality(CardinalityMetricPrefix + field)
+	s.metricsRegistry.Register(sketch)
+	next := make([]cardinalityTracker, len(cur), len(cur)+1)
+	copy(next, cur)
+	s.cardinality.Store(append(next, cardinalityTracker{field: field, sketch: sketch}))
+}
+
+// cardinalityTrackers returns the tracked fields (copy-on-write, never mutated).
+func (s *Stream) cardinalityTrackers() []cardinalityTracker {
+	trackers, _ := s.cardinality.Load().([]cardinalityTracker)
+	return trackers
+}
+
+// observeCardinality feeds one input row to every tracked field's sketch.
+func (s *Stream) observeCardinality(data map[string]any) {
+	for _, t := range s.cardinalityTrackers() {
+		v, ok := data[t.field]
+		if !ok && fieldpath.IsNestedField(t.field) {
+			v, ok = fieldpath.GetNestedField(data, t.field)
+		}
+		if ok && v != nil {
+			t.sketch.Add(v)
+		}
+	}
+}
+
+// cardinalityStats returns field -> estimated distinct count, or nil when no
+// field is tracked.
+func (s *Stream) cardinalityStats() map[string]int64 {
+	trackers := s.cardinalityTrackers()
+	if len(trackers) == 0 {
+		return nil
+	}
+	stats := make(map[string]int64, len(trackers))
+	for _, t := range trackers {
+		stats[t.field] = t.sketch.Estimate()
+	}
+	return stats
+}
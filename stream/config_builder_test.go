@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/aggregator"
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStream_ConfigBuilder 用 types.ConfigBuilder 构建的配置直接创建流，WHERE 经 Config.Where 生效
+func TestStream_ConfigBuilder(t *testing.T) {
+	config, err := types.NewConfigBuilder().
+		TumblingWindow("1s").
+		GroupBy("deviceId").
+		Agg("temperature", aggregator.Avg, "avg_temp").
+		Agg("temperature", aggregator.Count, "cnt").
+		Where("temperature > 0").
+		Build()
+	require.NoError(t, err)
+
+	s, err := NewStream(config)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	resultChan := make(chan []map[string]any, 4)
+	s.AddSink(func(results []map[string]any) { resultChan <- results })
+	s.Start()
+
+	for _, temp := range []float64{10, 20, -5} {
+		s.Emit(map[string]any{"deviceId": "d1", "temperature": temp})
+	}
+	time.Sleep(100 * time.Millisecond)
+	s.Window.Trigger()
+
+	select {
+	case rows := <-resultChan:
+		require.Len(t, rows, 1)
+		assert.Equal(t, "d1", rows[0]["deviceId"])
+		assert.Equal(t, 15.0, rows[0]["avg_temp"])
+		assert.Equal(t, 2.0, rows[0]["cnt"])
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for window result")
+	}
+
+	direct, err := types.NewConfigBuilder().Select("deviceId", "temperature:temp").Where("temperature > 0").Build()
+	require.NoError(t, err)
+	ds, err := NewStream(direct)
+	require.NoError(t, err)
+	defer ds.Stop()
+
+	row, err := ds.ProcessSync(map[string]any{"deviceId": "d1", "temperature": -1.0})
+	require.NoError(t, err)
+	assert.Nil(t, row)
+	row, err = ds.ProcessSync(map[string]any{"deviceId": "d1", "temperature": 3.0})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"deviceId": "d1", "temp": 3.0}, row)
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeadLetterFile is the file name, under Config.DeadLetterDir, that dead-letter
+// records are appended to (one JSON object per line).
+const DeadLetterFile = "dead_letter.jsonl"
+
+// DeadLetterRecord is one rejected input record together with why it was rejected.
+type DeadLetterRecord struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	Record any       `json:"record"`
+}
+
+// deadLetterStore appends rejected records to DeadLetterFile. Writes are
+// serialized and each record is a single line, so a crash loses at most the
+// line being written.
+type deadLetterStore struct {
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+}
+
+func newDeadLetterStore(dir string) (*deadLetterStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create dead-letter dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, DeadLetterFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open dead-letter file: %w", err)
+	}
+	return &deadLetterStore{file: f}, nil
+}
+
+func (d *deadLetterStore) write(data any, reason string) error {
+	line, err := json.Marshal(DeadLetterRecord{Time: time.Now(), Reason: reason, Record: data})
+	if err != nil {
+		// 记录本身不可序列化：仍保留原因与其文本形式，避免丢失线索
+		line, err = json.Marshal(DeadLetterRecord{Time: time.Now(), Reason: reason, Record: fmt.Sprintf("%v", data)})
+		if err != nil {
+			return err
+		}
+	}
+	line = append(line, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return fmt.Errorf("dead-letter store closed")
+	}
+	_, err = d.file.Write(line)
+	return err
+}
+
+func (d *deadLetterStore) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.file.Close()
+}
+
+// DeadLetter persists a rejected record with its failure reason when
+// Config.DeadLetterDir is set; otherwise it is a no-op. It is used for rows
+// rejected before they reach the pipeline (e.g. schema validation) and, via the
+// window OnDrop hook, for event-time rows without a usable timestamp.
+func (s *Stream) DeadLetter(data any, reason string) {
+	if s.deadLetter == nil {
+		return
+	}
+	if err := s.deadLetter.write(data, reason); err != nil {
+		s.log.Error("Failed to write dead-letter record: %v", err)
+	}
+}
+
+// ReadDeadLetters loads the dead-letter records persisted under dir, in write
+// order. A missing file yields no records.
+func ReadDeadLetters(dir string) ([]DeadLetterRecord, error) {
+	f, err := os.Open(filepath.Join(dir, DeadLetterFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []DeadLetterRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec DeadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return records, fmt.Errorf("corrupt dead-letter line %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,35 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeadLetterStore 测试死信按写入顺序追加、不可序列化记录降级为文本、关闭后拒绝写入
+func TestDeadLetterStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newDeadLetterStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, store.write(map[string]any{"id": 1}, "first"))
+	require.NoError(t, store.write(map[string]any{"ch": make(chan int)}, "unserializable"))
+	require.NoError(t, store.close())
+	assert.Error(t, store.write(map[string]any{"id": 3}, "after close"))
+	assert.NoError(t, store.close(), "重复关闭无副作用")
+
+	// 重新打开后追加而非覆盖
+	store, err = newDeadLetterStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.write(map[string]any{"id": 4}, "reopened"))
+	require.NoError(t, store.close())
+
+	records, err := ReadDeadLetters(dir)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "first", records[0].Reason)
+	assert.Equal(t, map[string]any{"id": 1.0}, records[0].Record)
+	assert.IsType(t, "", records[1].Record)
+	assert.Equal(t, "reopened", records[2].Reason)
+}
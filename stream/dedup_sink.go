@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupFilter drops result rows whose key was already delivered within ttl.
+// Sinks run concurrently on the worker pool, so access is guarded by mu.
+type dedupFilter struct {
+	mu       sync.Mutex
+	keyField string
+	ttl      time.Duration
+	// seen maps an encoded key to the time a row with it was last delivered.
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newDedupFilter(keyField string, ttl time.Duration) *dedupFilter {
+	return &dedupFilter{keyField: keyField, ttl: ttl, seen: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+// filter returns the rows of results that pass, in order. A row passes when
+// its key is missing or NULL, or when no row with the same key passed within
+// ttl; a passing row starts a new ttl for its key. Duplicates inside one batch
+// are dropped like duplicates across batches.
+func (d *dedupFilter) filter(results []map[string]any) []map[string]any {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Expired keys are swept at most once per ttl, so the map holds about one
+	// ttl of keys without a per-row scan.
+	if now.Sub(d.lastSweep) >= d.ttl {
+		for k, t := range d.seen {
+			if now.Sub(t) >= d.ttl {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	kept := make([]map[string]any, 0, len(results))
+	for _, row := range results {
+		v, _ := streamFieldValue(row, d.keyField)
+		if v == nil {
+			kept = append(kept, row)
+			continue
+		}
+		key := encodeKey([]any{v})
+		if t, ok := d.seen[key]; ok && now.Sub(t) < d.ttl {
+			continue
+		}
+		d.seen[key] = now
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// AddDedupSink adds an asynchronous sink that drops result rows whose
+// keyField value was already delivered to it within ttl, e.g. to avoid
+// re-sending the same alert. Deduplication is per row, within and across
+// batches: a batch is passed on with its duplicate rows removed, and skipped
+// when none remain. Rows without keyField (or with a NULL value) always pass.
+// Keys compare like GROUP BY keys (1 and 1.0 are the same key); the ttl counts
+// from the last delivered row, so a key firing continuously is delivered once
+// per ttl. Expired keys are evicted periodically. ttl <= 0 disables
+// deduplication. Other sinks on the stream still see every row.
+func (s *Stream) AddDedupSink(keyField string, ttl time.Duration, sink func([]map[string]any)) {
+	if ttl <= 0 {
+		s.AddSink(sink)
+		return
+	}
+	d := newDedupFilter(keyField, ttl)
+	s.AddSink(func(results []map[string]any) {
+		if kept := d.filter(results); len(kept) > 0 {
+			sink(kept)
+		}
+	})
+}
@@ -240,6 +240,10 @@ Synchronous processing for non-aggregation queries:
 		fmt.Printf("Immediate result: %v\n", result)
 	}
 
+	// All rows of one record (unnest expansion, ORDER BY, OFFSET/LIMIT);
+	// nil when filtered out, WHERE and projection errors are returned
+	rows, err := stream.ProcessSyncRows(data)
+
 # Integration
 
 Central integration point for all StreamSQL components:
@@ -0,0 +1,36 @@
+package stream
+
+// splitByGroup 按 GROUP BY 输出列把一个窗口的结果拆成每个分组一批（EmitPerGroup）。
+// 分组按首次出现的顺序排列，批内保持原有行序，因此 ORDER BY/LIMIT 的结果顺序不变；
+// long 形态下同一分组的多行 metric 落在同一批。无 GROUP BY 时整个窗口即一个分组。
+func (s *Stream) splitByGroup(results []map[string]any) [][]map[string]any {
+	if len(s.groupOutputNames) == 0 || len(results) <= 1 {
+		return [][]map[string]any{results}
+	}
+	index := make(map[string]int)
+	var batches [][]map[string]any
+	for _, row := range results {
+		key := s.outputGroupKey(row)
+		i, ok := index[key]
+		if !ok {
+			i = len(batches)
+			index[key] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], row)
+	}
+	return batches
+}
+
+// outputGroupKey 由结果行的 GROUP BY 输出列构成分组键（与 JOIN 键相同的编码，
+// 各列带类型标签和长度前缀，列值含分隔符也不会碰撞）；无 GROUP BY 时为空串。
+func (s *Stream) outputGroupKey(row map[string]any) string {
+	if len(s.groupOutputNames) == 0 {
+		return ""
+	}
+	vals := make([]any, len(s.groupOutputNames))
+	for i, name := range s.groupOutputNames {
+		vals[i] = row[name]
+	}
+	return encodeKey(vals)
+}
@@ -0,0 +1,19 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSplitByGroup_CompositeKeyNoCollision 测试多列分组值含分隔符时不会被并入同一批
+func TestSplitByGroup_CompositeKeyNoCollision(t *testing.T) {
+	s := &Stream{groupOutputNames: []string{"a", "b"}}
+	rows := []map[string]any{
+		{"a": "x|string:y", "b": "z", "cnt": 1},
+		{"a": "x", "b": "y|string:z", "cnt": 2},
+		{"a": "x|string:y", "b": "z", "cnt": 3},
+	}
+	batches := s.splitByGroup(rows)
+	assert.Equal(t, [][]map[string]any{{rows[0], rows[2]}, {rows[1]}}, batches)
+}
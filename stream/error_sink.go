@@ -0,0 +1,165 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/rulego/streamsql/condition"
+	"github.com/rulego/streamsql/expr"
+)
+
+// errorSinkQueueSize bounds the record errors waiting for the error sinks;
+// further errors are dropped and counted as ErrorSinkDropped.
+const errorSinkQueueSize = 1024
+
+// recordError is one input record whose processing failed.
+type recordError struct {
+	data map[string]any
+	err  error
+}
+
+// AddErrorSink registers a callback for records whose processing failed:
+// WHERE evaluation, SELECT projection, JOIN enrichment or aggregate input
+// evaluation errors. It receives the record and the error, e.g. to route bad
+// records to a dead-letter queue. Callbacks run on a dedicated goroutine fed by
+// a bounded queue, so a slow callback never blocks the pipeline; when the queue
+// is full the error is dropped and counted as ErrorSinkDropped. The callback
+// must not modify data.
+func (s *Stream) AddErrorSink(sink func(data map[string]any, err error)) {
+	s.sinksMux.Lock()
+	s.errorSinks = append(s.errorSinks, sink)
+	queue := s.errorQueue
+	start := queue == nil
+	if start {
+		queue = make(chan recordError, errorSinkQueueSize)
+		s.errorQueue = queue
+	}
+	s.sinksMux.Unlock()
+	if start {
+		s.startErrorDispatcher(queue)
+	}
+}
+
+// startErrorDispatcher runs the goroutine delivering queued record errors to
+// the error sinks until the stream stops.
+func (s *Stream) startErrorDispatcher(queue chan recordError) {
+	s.startMu.Lock()
+	if atomic.LoadInt32(&s.stopped) != 0 {
+		s.startMu.Unlock()
+		return
+	}
+	s.lifecycle.Add(1)
+	s.startMu.Unlock()
+	go func() {
+		defer s.lifecycle.Done()
+		for {
+			select {
+			case re := <-queue:
+				s.sinksMux.RLock()
+				sinks := s.errorSinks
+				s.sinksMux.RUnlock()
+				for _, sink := range sinks {
+					s.invokeErrorSink(sink, re)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Stream) invokeErrorSink(sink func(map[string]any, error), re recordError) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error("error sink panic recovered: %v", r)
+		}
+	}()
+	sink(re.data, re.err)
+}
+
+// reportRecordError queues a failed record for the error sinks; it is a no-op
+// when none is registered.
+func (s *Stream) reportRecordError(data map[string]any, err error) {
+	s.sinksMux.RLock()
+	queue := s.errorQueue
+	s.sinksMux.RUnlock()
+	if queue == nil || err == nil {
+		return
+	}
+	select {
+	case queue <- recordError{data: data, err: err}:
+	default:
+		s.mErrorSinkDropped.Inc()
+	}
+}
+
+// passesFilter evaluates WHERE on dataMap, reporting evaluation errors (which
+// reject the row) to the error sinks.
+func (s *Stream) passesFilter(dataMap map[string]any) bool {
+	pass, _ := s.evaluateFilter(dataMap)
+	return pass
+}
+
+// evaluateFilter is passesFilter that also returns the (already reported)
+// evaluation error, for callers that surface it. WHERE is bounded by
+// Config.ExpressionTimeout when set: a timed-out predicate rejects the row and
+// sends it to the dead-letter store, and one canceled by Stop just rejects it.
+func (s *Stream) evaluateFilter(dataMap map[string]any) (bool, error) {
+	if s.filter == nil {
+		return true, nil
+	}
+	if s.config.ExpressionTimeout > 0 {
+		row := copyRow(dataMap) // 超时后仍在运行的求值不读调用方的 map
+		v, err := expr.EvaluateContext(s.evalContext(), s.config.ExpressionTimeout, func() (any, error) {
+			return s.evaluateFilterInline(row)
+		})
+		if errors.Is(err, expr.ErrEvaluationTimeout) {
+			err = fmt.Errorf("where: %w", err)
+			s.DeadLetter(dataMap, err.Error())
+			s.reportRecordError(dataMap, err)
+		}
+		pass, _ := v.(bool)
+		return pass, err
+	}
+	return s.evaluateFilterInline(dataMap)
+}
+
+// evalContext is the context of time-limited expression evaluations; it ends
+// when the stream stops.
+func (s *Stream) evalContext() context.Context {
+	if s.evalCtx == nil {
+		return context.Background()
+	}
+	return s.evalCtx
+}
+
+// evaluateFilterInline evaluates WHERE without a time limit.
+func (s *Stream) evaluateFilterInline(dataMap map[string]any) (bool, error) {
+	if ec, ok := s.filter.(condition.ErrorCondition); ok {
+		pass, err := ec.EvaluateWithError(dataMap)
+		if err != nil {
+			err = fmt.Errorf("where: %w", err)
+			s.reportRecordError(dataMap, err)
+		}
+		return pass, err
+	}
+	return s.filter.Evaluate(dataMap), nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStream_AddErrorSink 测试WHERE求值失败的记录被投递到错误回调，且不影响正常记录输出
+func TestStream_AddErrorSink(t *testing.T) {
+	s, err := NewStream(types.Config{SimpleFields: []string{"id", "age"}})
+	require.NoError(t, err)
+	defer s.Stop()
+	require.NoError(t, s.RegisterFilter("age > 18"))
+
+	type failed struct {
+		data map[string]any
+		err  error
+	}
+	errCh := make(chan failed, 4)
+	s.AddErrorSink(func(data map[string]any, err error) {
+		errCh <- failed{data, err}
+	})
+	resultCh := make(chan []map[string]any, 4)
+	s.AddSink(func(results []map[string]any) { resultCh <- results })
+	s.Start()
+
+	s.Emit(map[string]any{"id": 1, "age": "unknown"})
+	s.Emit(map[string]any{"id": 2, "age": 30})
+
+	select {
+	case f := <-errCh:
+		assert.Equal(t, 1, f.data["id"])
+		assert.ErrorContains(t, f.err, "where")
+	case <-time.After(2 * time.Second):
+		t.Fatal("error sink not invoked")
+	}
+	select {
+	case results := <-resultCh:
+		require.Len(t, results, 1)
+		assert.Equal(t, 2, results[0]["id"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("valid record not emitted")
+	}
+}
+
+// TestStream_ErrorSinkDropped 测试错误队列已满时丢弃并计数，而不阻塞调用方
+func TestStream_ErrorSinkDropped(t *testing.T) {
+	s, err := NewStream(types.Config{SimpleFields: []string{"id"}})
+	require.NoError(t, err)
+	defer s.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	s.AddErrorSink(func(map[string]any, error) { <-release })
+
+	// 派发协程至多取走一条，其余填满队列后溢出
+	for i := 0; i < errorSinkQueueSize+10; i++ {
+		s.reportRecordError(map[string]any{"id": i}, errors.New("boom"))
+	}
+	assert.GreaterOrEqual(t, s.GetStats()[ErrorSinkDropped], int64(9))
+}
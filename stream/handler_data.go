@@ -152,3 +152,63 @@ migration_done:
 
 	s.log.Debug("Channel expansion completed: migrated %d items", migratedCount)
 }
+
+// sendBatchToChan enqueues an EmitMany batch honoring the overflow strategy.
+// "block" waits for space (up to blockingTimeout when > 0); "drop"/"expand"
+// try non-blocking plus a few short retries. The batch channel is not
+// expanded: it counts batches, not records, so it rarely fills. Returns false
+// if the batch was not enqueued (full, or the stream stopped).
+func (s *Stream) sendBatchToChan(batch []map[string]any) bool {
+	if atomic.LoadInt32(&s.stopped) == 1 {
+		return false
+	}
+	s.dataChanMux.RLock()
+	batchChan := s.batchChan
+	s.dataChanMux.RUnlock()
+	if batchChan == nil {
+		return false
+	}
+
+	if s.overflowStrategy == StrategyBlock {
+		if s.blockingTimeout <= 0 {
+			select {
+			case batchChan <- batch:
+				return true
+			case <-s.done:
+				return false
+			}
+		}
+		timer := time.NewTimer(s.blockingTimeout)
+		defer timer.Stop()
+		select {
+		case batchChan <- batch:
+			return true
+		case <-timer.C:
+			s.log.Warn("Batch channel still full after %s, dropping %d input records", s.blockingTimeout, len(batch))
+			return false
+		case <-s.done:
+			return false
+		}
+	}
+
+	select {
+	case batchChan <- batch:
+		return true
+	default:
+	}
+	// Channel full: a few short retries give the consumer a chance to drain.
+	for i := 0; i < 3; i++ {
+		timer := time.NewTimer(100 * time.Microsecond)
+		select {
+		case batchChan <- batch:
+			timer.Stop()
+			return true
+		case <-timer.C:
+		case <-s.done:
+			timer.Stop()
+			return false
+		}
+	}
+	s.log.Warn("Batch channel is full, dropping %d input records", len(batch))
+	return false
+}
@@ -19,6 +19,8 @@ package stream
 import (
 	"sync/atomic"
 	"time"
+
+	"github.com/rulego/streamsql/types"
 )
 
 // startSinkWorkerPool starts sink worker pool with configurable worker count
@@ -116,22 +118,21 @@ func (s *Stream) logDroppedDataWithThrottling() {
 
 // callSinksAsync asynchronously calls all sink functions
 func (s *Stream) callSinksAsync(results []map[string]any) {
-	// Safely access sinks slice using read lock
+	// Snapshot the sinks under the read lock: AddSink only appends, and waiting
+	// for an in-flight slot must not hold the lock.
 	s.sinksMux.RLock()
-	defer s.sinksMux.RUnlock()
+	sinks, syncSinks := s.targetSinks(), s.syncSinks
+	s.sinksMux.RUnlock()
 
-	if len(s.sinks) == 0 && len(s.syncSinks) == 0 {
-		return
-	}
-
-	// Directly iterate sinks slice to avoid copy overhead
-	// Since submitSinkTask is async, won't hold lock for long time
-	for _, sink := range s.sinks {
-		s.submitSinkTask(sink, results)
+	if len(sinks) > 0 && s.acquireSinkSlot() {
+		release := s.sinkSlotRelease(len(sinks))
+		for _, sink := range sinks {
+			s.submitSinkTask(sink, results, release)
+		}
 	}
 
 	// Execute synchronous sinks (blocking, sequential)
-	for _, sink := range s.syncSinks {
+	for _, sink := range syncSinks {
 		// Recover panic for each sync sink to prevent crashing the stream
 		func() {
 			defer func() {
@@ -144,13 +145,72 @@ func (s *Stream) callSinksAsync(results []map[string]any) {
 	}
 }
 
-// submitSinkTask submits sink task
-func (s *Stream) submitSinkTask(sink func([]map[string]any), results []map[string]any) {
+// newSinkInFlight creates the in-flight batch semaphore, nil when unbounded.
+func newSinkInFlight(maxInFlight int) chan struct{} {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	return make(chan struct{}, maxInFlight)
+}
+
+// acquireSinkSlot reserves an in-flight slot for a batch about to be dispatched
+// to the async sinks. With every slot taken, the "block" overflow strategy
+// waits for one (bounded by BlockTimeout when > 0) and the other strategies
+// drop the batch. It returns false when the batch is dropped.
+func (s *Stream) acquireSinkSlot() bool {
+	if s.sinkInFlight == nil {
+		return true
+	}
+	select {
+	case s.sinkInFlight <- struct{}{}:
+		return true
+	default:
+	}
+	if s.overflowStrategy == types.OverflowStrategyBlock {
+		var timeout <-chan time.Time
+		if s.blockingTimeout > 0 {
+			timer := time.NewTimer(s.blockingTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case s.sinkInFlight <- struct{}{}:
+			return true
+		case <-timeout:
+		case <-s.done:
+		}
+	}
+	s.mSinkDropped.Inc()
+	return false
+}
+
+// sinkSlotRelease returns the callback each of the batch's sink tasks runs when
+// done; the last one frees the batch's in-flight slot. nil when unbounded.
+func (s *Stream) sinkSlotRelease(tasks int) func() {
+	if s.sinkInFlight == nil {
+		return nil
+	}
+	remaining := int32(tasks)
+	return func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			<-s.sinkInFlight
+		}
+	}
+}
+
+// submitSinkTask submits sink task; release (may be nil) runs once the task
+// has finished or been dropped.
+func (s *Stream) submitSinkTask(sink func([]map[string]any), results []map[string]any, release func()) {
 	// Capture sink variable to avoid closure issues
 	currentSink := sink
 
 	// Submit task to worker pool
+	atomic.AddInt64(&s.pendingSinkTasks, 1)
 	task := func() {
+		defer atomic.AddInt64(&s.pendingSinkTasks, -1)
+		if release != nil {
+			defer release()
+		}
 		defer func() {
 			// Recover panic to prevent single sink error from affecting entire system
 			if r := recover(); r != nil {
@@ -171,6 +231,10 @@ func (s *Stream) submitSinkTask(sink func([]map[string]any), results []map[strin
 		// delaying or preventing a clean drain.
 		select {
 		case <-s.done:
+			atomic.AddInt64(&s.pendingSinkTasks, -1)
+			if release != nil {
+				release()
+			}
 			return
 		default:
 			// Degraded handling under load: execute in the calling goroutine.
@@ -191,6 +255,23 @@ func (s *Stream) AddSink(sink func([]map[string]any)) {
 	s.sinks = append(s.sinks, sink)
 }
 
+// AddSampledSink adds an asynchronous sink that only receives every nth result
+// batch (the nth, 2nth, ...) and drops the rest, for observing a fraction of a
+// high-volume output. n <= 1 forwards every batch. The batch counter is kept
+// per sampled sink, so other sinks on the stream still see every batch.
+func (s *Stream) AddSampledSink(n int, sink func([]map[string]any)) {
+	if n <= 1 {
+		s.AddSink(sink)
+		return
+	}
+	var seen uint64
+	s.AddSink(func(results []map[string]any) {
+		if atomic.AddUint64(&seen, 1)%uint64(n) == 0 {
+			sink(results)
+		}
+	})
+}
+
 // AddSyncSink adds a synchronous sink function
 // Parameters:
 //   - sink: result processing function that receives []map[string]any type result data
@@ -203,6 +284,30 @@ func (s *Stream) AddSyncSink(sink func([]map[string]any)) {
 	s.syncSinks = append(s.syncSinks, sink)
 }
 
+// AddNamedSink adds a sink function under name. A named sink only receives the
+// results of a query whose statement targets it with INSERT INTO <name>
+// SELECT ...; like AddSink it runs asynchronously. Unnamed sinks keep receiving
+// every result, with or without INSERT INTO.
+func (s *Stream) AddNamedSink(name string, sink func([]map[string]any)) {
+	s.sinksMux.Lock()
+	defer s.sinksMux.Unlock()
+	if s.namedSinks == nil {
+		s.namedSinks = make(map[string][]func([]map[string]any))
+	}
+	s.namedSinks[name] = append(s.namedSinks[name], sink)
+}
+
+// targetSinks returns the async sinks a result batch goes to: the unnamed ones
+// plus the named sinks of the INSERT INTO target. The caller holds sinksMux.
+func (s *Stream) targetSinks() []func([]map[string]any) {
+	named := s.namedSinks[s.config.InsertInto]
+	if s.config.InsertInto == "" || len(named) == 0 {
+		return s.sinks
+	}
+	sinks := make([]func([]map[string]any), 0, len(s.sinks)+len(named))
+	return append(append(sinks, s.sinks...), named...)
+}
+
 // GetResultsChan gets the result channel
 func (s *Stream) GetResultsChan() <-chan []map[string]any {
 	return s.resultChan
@@ -262,3 +262,142 @@ func TestStream_SinkWorkerPool_WorkerCount(t *testing.T) {
 		})
 	}
 }
+
+// TestStream_MaxInFlightBatches 慢 sink 下在途批次不超过 MaxInFlightBatches：
+// drop 策略丢弃超出的批次，block 策略等待空位（超时后丢弃）
+func TestStream_MaxInFlightBatches(t *testing.T) {
+	type slowSink struct {
+		gate            chan struct{}
+		running, peak   int32
+		delivered       int32
+		releaseGateOnce sync.Once
+	}
+	newStream := func(t *testing.T, strategy string, blockTimeout time.Duration) (*Stream, *slowSink) {
+		perf := types.DefaultPerformanceConfig()
+		perf.MaxInFlightBatches = 2
+		perf.WorkerConfig.SinkPoolSize = 16
+		perf.WorkerConfig.SinkWorkerCount = 8
+		perf.OverflowConfig.Strategy = strategy
+		perf.OverflowConfig.BlockTimeout = blockTimeout
+		s, err := NewStreamWithCustomPerformance(types.Config{SimpleFields: []string{"v"}}, perf)
+		require.NoError(t, err)
+		sink := &slowSink{gate: make(chan struct{})}
+		s.AddSink(func([]map[string]any) {
+			n := atomic.AddInt32(&sink.running, 1)
+			for {
+				p := atomic.LoadInt32(&sink.peak)
+				if n <= p || atomic.CompareAndSwapInt32(&sink.peak, p, n) {
+					break
+				}
+			}
+			<-sink.gate
+			atomic.AddInt32(&sink.running, -1)
+			atomic.AddInt32(&sink.delivered, 1)
+		})
+		t.Cleanup(s.Stop)
+		// 先于 Stop 执行，放行阻塞中的 sink
+		t.Cleanup(func() { sink.releaseGateOnce.Do(func() { close(sink.gate) }) })
+		return s, sink
+	}
+	batch := []map[string]any{{"v": 1}}
+
+	t.Run("drop", func(t *testing.T) {
+		s, sink := newStream(t, "drop", 0)
+		for i := 0; i < 5; i++ {
+			s.callSinksAsync(batch)
+		}
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&sink.running) == 2 }, time.Second, 5*time.Millisecond)
+		stats := s.GetStats()
+		assert.Equal(t, int64(2), stats[SinkInFlight])
+		assert.Equal(t, int64(3), stats[SinkBatchesDropped])
+
+		sink.releaseGateOnce.Do(func() { close(sink.gate) })
+		assert.Eventually(t, func() bool { return s.GetStats()[SinkInFlight] == 0 }, time.Second, 5*time.Millisecond)
+		s.callSinksAsync(batch)
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&sink.delivered) == 3 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&sink.peak))
+	})
+
+	t.Run("block", func(t *testing.T) {
+		s, sink := newStream(t, "block", 0)
+		var dispatched int32
+		go func() {
+			for i := 0; i < 4; i++ {
+				s.callSinksAsync(batch)
+				atomic.AddInt32(&dispatched, 1)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&dispatched), "第三个批次应等待在途批次完成")
+
+		sink.releaseGateOnce.Do(func() { close(sink.gate) })
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&sink.delivered) == 4 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, int64(0), s.GetStats()[SinkBatchesDropped])
+		assert.LessOrEqual(t, atomic.LoadInt32(&sink.peak), int32(2))
+	})
+
+	t.Run("block 超时丢弃", func(t *testing.T) {
+		s, sink := newStream(t, "block", 50*time.Millisecond)
+		s.callSinksAsync(batch)
+		s.callSinksAsync(batch)
+		start := time.Now()
+		s.callSinksAsync(batch)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, int64(1), s.GetStats()[SinkBatchesDropped])
+		assert.Equal(t, int32(0), atomic.LoadInt32(&sink.delivered))
+	})
+}
+
+// TestStream_AddSampledSink 测试采样 sink 只接收每第 N 个批次，且不影响普通 sink
+func TestStream_AddSampledSink(t *testing.T) {
+	s, err := NewStream(types.Config{SimpleFields: []string{"v"}})
+	require.NoError(t, err)
+	defer s.Stop()
+
+	var full, sampled, every int32
+	s.AddSink(func([]map[string]any) { atomic.AddInt32(&full, 1) })
+	s.AddSampledSink(3, func([]map[string]any) { atomic.AddInt32(&sampled, 1) })
+	s.AddSampledSink(0, func([]map[string]any) { atomic.AddInt32(&every, 1) })
+
+	batch := []map[string]any{{"v": 1}}
+	for i := 0; i < 10; i++ {
+		s.invokeSinksInline(batch)
+	}
+	assert.Equal(t, int32(10), atomic.LoadInt32(&full))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&sampled))
+	assert.Equal(t, int32(10), atomic.LoadInt32(&every))
+}
+
+// TestStream_AddDedupSink 测试去重 sink 按行过滤 TTL 内已输出的键，缺少键的行直接透传
+func TestStream_AddDedupSink(t *testing.T) {
+	s, err := NewStream(types.Config{SimpleFields: []string{"id"}})
+	require.NoError(t, err)
+	defer s.Stop()
+
+	var got [][]map[string]any
+	var full int32
+	s.AddSink(func([]map[string]any) { atomic.AddInt32(&full, 1) })
+	s.AddDedupSink("id", 100*time.Millisecond, func(rows []map[string]any) { got = append(got, rows) })
+
+	s.invokeSinksInline([]map[string]any{{"id": "a"}, {"id": "b"}, {"id": "a"}, {"v": 1}})
+	s.invokeSinksInline([]map[string]any{{"id": "a"}, {"id": "b"}})
+	s.invokeSinksInline([]map[string]any{{"id": 1}, {"id": 1.0}, {"v": 2}})
+	time.Sleep(150 * time.Millisecond)
+	s.invokeSinksInline([]map[string]any{{"id": "a"}})
+
+	require.Len(t, got, 3, "a batch with only duplicates is skipped")
+	assert.Equal(t, []map[string]any{{"id": "a"}, {"id": "b"}, {"v": 1}}, got[0])
+	assert.Equal(t, []map[string]any{{"id": 1}, {"v": 2}}, got[1])
+	assert.Equal(t, []map[string]any{{"id": "a"}}, got[2], "key is delivered again after the ttl")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&full), "other sinks see every batch")
+}
+
+// TestDedupFilterEviction 测试过期键在周期清理时被移除
+func TestDedupFilterEviction(t *testing.T) {
+	d := newDedupFilter("id", 50*time.Millisecond)
+	d.filter([]map[string]any{{"id": 1}, {"id": 2}})
+	assert.Len(t, d.seen, 2)
+	time.Sleep(60 * time.Millisecond)
+	d.filter([]map[string]any{{"id": 3}})
+	assert.Len(t, d.seen, 1)
+}
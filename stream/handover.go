@@ -0,0 +1,152 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/window"
+)
+
+// HandOver retires s in favour of next, a stream built for a replacement query
+// and not started yet, so a query can change without consumers re-registering.
+//
+// next first takes over the sinks, sync sinks, error sinks, backpressure callbacks, result channel, registered table
+// sources and tracked cardinality fields of s; then switchInput is called, after
+// which the caller must route new input to next instead of s. Input already
+// queued on s is processed by the old query, s is stopped and next is started.
+//
+// With keepWindow, when both queries define the same window (SameWindow), the
+// running window with its buffered rows and watermark moves to next, so the
+// window that is open now is computed by the new SELECT over all of its rows.
+// Otherwise s fires its open windows under the old query before it stops.
+// Returns whether the window was kept. grace bounds the drain of s (≤0 uses the
+// Stop grace); a drain that times out is logged and the handover continues.
+func (s *Stream) HandOver(next *Stream, keepWindow bool, grace time.Duration, switchInput func()) (bool, error) {
+	if next == nil || next == s {
+		return false, fmt.Errorf("invalid handover target")
+	}
+	if atomic.LoadInt32(&s.stopped) != 0 {
+		return false, fmt.Errorf("stream already stopped")
+	}
+	if atomic.LoadInt32(&next.stopped) != 0 {
+		return false, fmt.Errorf("target stream already stopped")
+	}
+	keep := keepWindow && s.config.NeedWindow && next.config.NeedWindow &&
+		SameWindow(s.config.WindowConfig, next.config.WindowConfig)
+
+	// 1. Share outputs before next can see any input. next is not running, so
+	// its fields are written without contention.
+	s.sinksMux.RLock()
+	sinks := append([]func([]map[string]any){}, s.sinks...)
+	syncSinks := append([]func([]map[string]any){}, s.syncSinks...)
+	namedSinks := make(map[string][]func([]map[string]any), len(s.namedSinks))
+	for name, fns := range s.namedSinks {
+		namedSinks[name] = append([]func([]map[string]any){}, fns...)
+	}
+	errorSinks := append([]func(map[string]any, error){}, s.errorSinks...)
+	s.sinksMux.RUnlock()
+	next.sinksMux.Lock()
+	next.sinks = append(sinks, next.sinks...)
+	next.syncSinks = append(syncSinks, next.syncSinks...)
+	for name, fns := range next.namedSinks {
+		namedSinks[name] = append(namedSinks[name], fns...)
+	}
+	next.namedSinks = namedSinks
+	next.sinksMux.Unlock()
+	for _, sink := range errorSinks {
+		next.AddErrorSink(sink)
+	}
+	for _, fn := range s.backpressureListeners() {
+		next.OnBackpressure(fn)
+	}
+	next.resultChan = s.resultChan
+	next.tables = s.tables
+	for _, t := range s.cardinalityTrackers() {
+		next.metricsRegistry.Register(t.sketch)
+	}
+	next.cardinality.Store(s.cardinalityTrackers())
+	// Read only by Stop, which runs on this goroutine below.
+	s.handedOff = true
+	s.windowHandedOff = keep
+
+	if switchInput != nil {
+		switchInput()
+	}
+
+	// 2. Let the old query finish what it has already accepted.
+	if err := s.closeInput(grace, !keep); err != nil {
+		s.log.Warn("HandOver: %v", err)
+	}
+	s.Stop()
+
+	// 3. Move the window; next's own window was never started.
+	if keep {
+		if next.Window != nil {
+			next.Window.Stop()
+		}
+		if next.deadLetter != nil {
+			if err := next.deadLetter.close(); err != nil {
+				next.log.Error("Failed to close dead-letter store: %v", err)
+			}
+		}
+		next.Window = s.Window
+		next.deadLetter = s.deadLetter
+		next.windowAdopted = true
+	}
+	next.Start()
+	return keep, nil
+}
+
+// SameWindow reports whether two window definitions buffer and fire rows
+// identically, so a running window of one can serve the other. Global windows
+// keep per-SELECT aggregate state rather than rows and never match.
+func SameWindow(a, b types.WindowConfig) bool {
+	if a.Type != b.Type || a.Type == window.TypeGlobal {
+		return false
+	}
+	return reflect.DeepEqual(a.Params, b.Params) &&
+		a.TsProp == b.TsProp &&
+		a.TimeUnit == b.TimeUnit &&
+		a.TimeCharacteristic == b.TimeCharacteristic &&
+		a.MaxOutOfOrderness == b.MaxOutOfOrderness &&
+		a.WatermarkInterval == b.WatermarkInterval &&
+		a.AllowedLateness == b.AllowedLateness &&
+		a.IdleTimeout == b.IdleTimeout &&
+		a.CountStateTTL == b.CountStateTTL &&
+		a.MaxOpenWindows == b.MaxOpenWindows &&
+		a.OpenWindowPolicy == b.OpenWindowPolicy &&
+		a.PerKeyClock == b.PerKeyClock &&
+		a.PerKeyWatermark == b.PerKeyWatermark &&
+		equalStrings(a.GroupByKeys, b.GroupByKeys)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
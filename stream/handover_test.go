@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/window"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameWindow(t *testing.T) {
+	base := types.WindowConfig{
+		Type:        window.TypeTumbling,
+		Params:      []any{5 * time.Second},
+		GroupByKeys: []string{"deviceId"},
+		OnDrop:      func(any, string) {},
+	}
+	same := base
+	same.OnDrop = nil
+	assert.True(t, SameWindow(base, same), "回调不参与比较")
+
+	for name, change := range map[string]func(c *types.WindowConfig){
+		"type":     func(c *types.WindowConfig) { c.Type = window.TypeSliding },
+		"size":     func(c *types.WindowConfig) { c.Params = []any{10 * time.Second} },
+		"keys":     func(c *types.WindowConfig) { c.GroupByKeys = []string{"deviceId", "site"} },
+		"time":     func(c *types.WindowConfig) { c.TimeCharacteristic = types.EventTime },
+		"lateness": func(c *types.WindowConfig) { c.AllowedLateness = time.Second },
+	} {
+		other := base
+		change(&other)
+		assert.False(t, SameWindow(base, other), name)
+	}
+
+	global := types.WindowConfig{Type: window.TypeGlobal}
+	assert.False(t, SameWindow(global, global), "全局窗口状态依赖 SELECT")
+}
+
+func TestStream_HandOver(t *testing.T) {
+	newStream := func() *Stream {
+		s, err := NewStream(types.Config{SimpleFields: []string{"v"}})
+		require.NoError(t, err)
+		return s
+	}
+	old := newStream()
+	old.Start()
+	results := make(chan []map[string]any, 4)
+	old.AddSyncSink(func(r []map[string]any) { results <- r })
+
+	next := newStream()
+	switched := false
+	kept, err := old.HandOver(next, true, time.Second, func() { switched = true })
+	require.NoError(t, err)
+	defer next.Stop()
+	assert.True(t, switched)
+	assert.False(t, kept, "无窗口查询不保留窗口")
+	assert.Equal(t, old.GetResultsChan(), next.GetResultsChan())
+
+	next.Emit(map[string]any{"v": 1})
+	select {
+	case r := <-results:
+		assert.Equal(t, 1, r[0]["v"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("sink registered on the old stream got no result")
+	}
+
+	_, err = old.HandOver(next, false, time.Second, nil)
+	assert.Error(t, err, "已停止的流不能再移交")
+}
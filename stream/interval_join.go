@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// intervalJoin is the operator behind a stream self-join. It buffers rows by
+// join key and pairs each incoming row with the buffered rows of the same key
+// whose time difference lies within [Lower, Upper], in both roles: as the right
+// side of an earlier row and as the left side of it. A row also pairs with
+// itself when 0 is inside the interval, as in a batch SQL self-join; other
+// WHERE terms (e.g. a.type = 'start' AND b.type = 'end') filter such pairs.
+//
+// Rows are kept while they can still match a row at the newest time seen, so
+// the buffer holds about one interval of data. It is only touched by the data
+// processor goroutine.
+type intervalJoin struct {
+	cfg *types.IntervalJoinConfig
+	// horizon is how far behind the newest time a buffered row can still match.
+	horizon float64
+	// byLeft/byRight index buffered rows by their left-side and right-side key,
+	// i.e. as candidates for the left and the right side of a pair.
+	byLeft  map[string][]*joinRow
+	byRight map[string][]*joinRow
+	// maxTime is the newest row time seen; lastCutoff the cutoff of the last sweep.
+	maxTime    float64
+	lastCutoff float64
+}
+
+type joinRow struct {
+	data map[string]any
+	ts   float64
+}
+
+func newIntervalJoin(cfg *types.IntervalJoinConfig) *intervalJoin {
+	return &intervalJoin{
+		cfg:        cfg,
+		horizon:    math.Max(0, math.Max(cfg.Upper, -cfg.Lower)),
+		byLeft:     make(map[string][]*joinRow),
+		byRight:    make(map[string][]*joinRow),
+		maxTime:    math.Inf(-1),
+		lastCutoff: math.Inf(-1),
+	}
+}
+
+// add buffers a row and returns the joined rows it completes, each exposing
+// the left row under LeftAlias and the right row under RightAlias. A row whose
+// join key is NULL joins nothing (SQL equality); a row without a numeric time
+// field is rejected with an error.
+func (ij *intervalJoin) add(data map[string]any) ([]map[string]any, error) {
+	raw, _ := streamFieldValue(data, ij.cfg.TimeField)
+	if raw == nil {
+		return nil, fmt.Errorf("self-join time field %q is missing", ij.cfg.TimeField)
+	}
+	ts, err := cast.ToFloat64E(raw)
+	if err != nil {
+		return nil, fmt.Errorf("self-join time field %q: %w", ij.cfg.TimeField, err)
+	}
+	leftKey, leftOK := ij.key(data, true)
+	rightKey, rightOK := ij.key(data, false)
+
+	var joined []map[string]any
+	if rightOK {
+		for _, r := range ij.byLeft[rightKey] {
+			if ij.inRange(ts - r.ts) {
+				joined = append(joined, ij.pair(r.data, data))
+			}
+		}
+	}
+	if leftOK {
+		for _, r := range ij.byRight[leftKey] {
+			if ij.inRange(r.ts - ts) {
+				joined = append(joined, ij.pair(data, r.data))
+			}
+		}
+	}
+	if leftOK && rightOK && leftKey == rightKey && ij.inRange(0) {
+		joined = append(joined, ij.pair(data, data))
+	}
+
+	if ts > ij.maxTime {
+		ij.maxTime = ts
+	}
+	cutoff := ij.maxTime - ij.horizon
+	if ts >= cutoff {
+		row := &joinRow{data: data, ts: ts}
+		if leftOK {
+			ij.byLeft[leftKey] = append(ij.byLeft[leftKey], row)
+		}
+		if rightOK {
+			ij.byRight[rightKey] = append(ij.byRight[rightKey], row)
+		}
+	}
+	// Sweep once the cutoff has moved by a horizon, so each buffered row is
+	// scanned a bounded number of times.
+	if cutoff > ij.lastCutoff && cutoff-ij.lastCutoff >= ij.horizon {
+		evictBefore(ij.byLeft, cutoff)
+		evictBefore(ij.byRight, cutoff)
+		ij.lastCutoff = cutoff
+	}
+	return joined, nil
+}
+
+func (ij *intervalJoin) inRange(diff float64) bool {
+	return diff >= ij.cfg.Lower && diff <= ij.cfg.Upper
+}
+
+func (ij *intervalJoin) pair(left, right map[string]any) map[string]any {
+	return map[string]any{ij.cfg.LeftAlias: left, ij.cfg.RightAlias: right}
+}
+
+// key builds the join key of a row from its left-side (left=true) or
+// right-side key fields, encoded like table JOIN keys. ok is false when any
+// key field is NULL.
+func (ij *intervalJoin) key(data map[string]any, left bool) (string, bool) {
+	vals := make([]any, len(ij.cfg.Keys))
+	for i, k := range ij.cfg.Keys {
+		field := k.Right
+		if left {
+			field = k.Left
+		}
+		v, _ := streamFieldValue(data, field)
+		if v == nil {
+			return "", false
+		}
+		vals[i] = v
+	}
+	return encodeKey(vals), true
+}
+
+// evictBefore drops rows older than cutoff, and keys left without rows.
+func evictBefore(index map[string][]*joinRow, cutoff float64) {
+	for k, rows := range index {
+		kept := rows[:0]
+		for _, r := range rows {
+			if r.ts >= cutoff {
+				kept = append(kept, r)
+			}
+		}
+		for i := len(kept); i < len(rows); i++ {
+			rows[i] = nil
+		}
+		if len(kept) == 0 {
+			delete(index, k)
+		} else {
+			index[k] = kept
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalJoin(t *testing.T) {
+	ij := newIntervalJoin(&types.IntervalJoinConfig{
+		LeftAlias:  "a",
+		RightAlias: "b",
+		Keys:       []types.IntervalJoinKey{{Left: "id", Right: "id"}},
+		TimeField:  "ts",
+		Lower:      0,
+		Upper:      100,
+	})
+	row := func(id any, ts int) map[string]any { return map[string]any{"id": id, "ts": ts} }
+
+	r1 := row("k", 10)
+	joined, err := ij.add(r1)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"a": r1, "b": r1}}, joined, "a row pairs with itself when 0 is in range")
+
+	// Later row: pairs as the right side of r1. An earlier row: as the left side.
+	r2 := row("k", 60)
+	joined, err = ij.add(r2)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"a": r1, "b": r2}, {"a": r2, "b": r2}}, joined)
+	r0 := row("k", 5)
+	joined, err = ij.add(r0)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"a": r0, "b": r1}, {"a": r0, "b": r2}, {"a": r0, "b": r0}}, joined)
+
+	// Numeric keys match across types; NULL keys never match.
+	joined, err = ij.add(row(float64(1), 70))
+	require.NoError(t, err)
+	assert.Len(t, joined, 1)
+	joined, err = ij.add(row(1, 70))
+	require.NoError(t, err)
+	assert.Len(t, joined, 3)
+	joined, err = ij.add(row(nil, 70))
+	require.NoError(t, err)
+	assert.Empty(t, joined)
+
+	_, err = ij.add(map[string]any{"id": "k"})
+	assert.Error(t, err, "missing time field")
+
+	// Rows older than one interval behind the newest time are evicted.
+	joined, err = ij.add(row("other", 500))
+	require.NoError(t, err)
+	assert.Len(t, joined, 1)
+	assert.NotContains(t, ij.byLeft, encodeKey([]any{"k"}))
+	assert.Len(t, ij.byRight, 1)
+}
@@ -3,8 +3,11 @@ package stream
 import (
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/rulego/streamsql/types"
 	"github.com/rulego/streamsql/utils/fieldpath"
+	"github.com/rulego/streamsql/window"
 )
 
 // emptyMetadataRow is a shared, never-mutated empty map used as the placeholder
@@ -13,6 +16,60 @@ import (
 // -> nil), so WHERE "m.col IS NULL" and NULL-filled SELECT columns both work.
 var emptyMetadataRow = map[string]any{}
 
+// maxPooledJoinRow caps the size of working rows kept for reuse, so one very
+// wide row does not pin its buckets in the pool.
+const maxPooledJoinRow = 256
+
+// joinRowPool holds the working rows enrichJoin builds, handed back by
+// releaseJoinRows once their window has been aggregated (Stream.poolJoinRows).
+var joinRowPool sync.Pool
+
+// joinRowPooling reports whether the working rows of a JOIN can be recycled
+// after aggregation: PerformanceConfig.RowPooling is on and each row reaches
+// the aggregator exactly once and is referenced by nothing afterwards. Sliding
+// windows (a row is in several windows), AllowedLateness (fired rows are kept
+// for late re-emits) and global windows (rows are the results) do not qualify.
+func joinRowPooling(config types.Config) bool {
+	if !config.PerformanceConfig.RowPooling || len(config.JoinConfigs) == 0 {
+		return false
+	}
+	if !config.NeedWindow || config.Mode == types.ExecCEP || config.WindowConfig.AllowedLateness > 0 {
+		return false
+	}
+	switch config.WindowConfig.Type {
+	case window.TypeTumbling, window.TypeCounting, window.TypeSession:
+		return true
+	}
+	return false
+}
+
+// newJoinRow returns an empty working row, reusing a released one when
+// pooling is on.
+func (s *Stream) newJoinRow(size int) map[string]any {
+	if s.poolJoinRows {
+		if m, ok := joinRowPool.Get().(map[string]any); ok {
+			return m
+		}
+	}
+	return make(map[string]any, size)
+}
+
+// releaseJoinRows hands the working rows of an aggregated batch back for reuse.
+// Only called when poolJoinRows is set, so every row in batch came from
+// enrichJoin; the input rows and table rows they referenced are left intact.
+func releaseJoinRows(batch []types.Row) {
+	for _, item := range batch {
+		m, ok := item.Data.(map[string]any)
+		if !ok || len(m) > maxPooledJoinRow {
+			continue
+		}
+		for k := range m {
+			delete(m, k)
+		}
+		joinRowPool.Put(m)
+	}
+}
+
 // hasJoin reports whether this stream has any JOIN configured, so callers skip
 // enrichment entirely on the common no-JOIN path (zero overhead).
 func (s *Stream) hasJoin() bool {
@@ -36,7 +93,7 @@ func (s *Stream) enrichJoin(data map[string]any) (working map[string]any, keep b
 	if len(s.config.JoinConfigs) == 0 {
 		return data, true, nil
 	}
-	working = make(map[string]any, len(data)+len(s.config.JoinConfigs)+1)
+	working = s.newJoinRow(len(data) + len(s.config.JoinConfigs) + 1)
 	for k, v := range data {
 		working[k] = v
 	}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// latestState 保存非窗口 GROUP BY 查询每个分组的最新结果行（Config.StateFlushInterval），
+// 由计时器按固定间隔整体输出快照。update 只在数据处理 goroutine 调用；snapshot 还会在
+// Stop 末尾调用，故加锁。
+type latestState struct {
+	mu      sync.Mutex
+	ticker  *time.Ticker
+	rows    map[string][]map[string]any
+	order   []string // 分组键按首次出现顺序，快照行序稳定
+	changed bool     // 上次快照后是否有分组更新
+}
+
+// newLatestState 创建按 interval 输出快照的分组状态；interval ≤ 0 返回 nil（逐条输出）。
+func newLatestState(interval time.Duration) *latestState {
+	if interval <= 0 {
+		return nil
+	}
+	return &latestState{
+		ticker: time.NewTicker(interval),
+		rows:   make(map[string][]map[string]any),
+	}
+}
+
+// C 返回快照计时器的 channel；状态为 nil 时返回 nil（select 中永不就绪）。
+func (l *latestState) C() <-chan time.Time {
+	if l == nil {
+		return nil
+	}
+	return l.ticker.C
+}
+
+// update 用一条输入产生的结果行替换该分组的最新行。
+func (l *latestState) update(key string, rows []map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.rows[key]; !ok {
+		l.order = append(l.order, key)
+	}
+	l.rows[key] = rows
+	l.changed = true
+}
+
+// snapshot 返回全部分组最新行的浅拷贝（sink 可能修改收到的行，状态中的行不外泄）；
+// 上次快照后没有更新时返回 nil。
+func (l *latestState) snapshot() []map[string]any {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.changed {
+		return nil
+	}
+	l.changed = false
+	out := make([]map[string]any, 0, len(l.order))
+	for _, key := range l.order {
+		for _, row := range l.rows[key] {
+			cp := make(map[string]any, len(row))
+			for k, v := range row {
+				cp[k] = v
+			}
+			out = append(out, cp)
+		}
+	}
+	return out
+}
+
+// stop 停止快照计时器。
+func (l *latestState) stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
+
+// groupStateKey 按 GROUP BY 字段取输入行的分组键（与 JOIN 键相同的编码，NULL 自成一组）。
+func (s *Stream) groupStateKey(dataMap map[string]any) string {
+	vals := make([]any, len(s.config.GroupFields))
+	for i, f := range s.config.GroupFields {
+		vals[i], _ = streamFieldValue(dataMap, f)
+	}
+	return encodeKey(vals)
+}
+
+// prepareLatestSnapshot 取出一次快照并按快照应用 ORDER BY、OFFSET/LIMIT 与主键标记；
+// 没有新快照时返回 nil。
+func (s *Stream) prepareLatestSnapshot() []map[string]any {
+	rows := s.latest.snapshot()
+	if len(rows) == 0 {
+		return nil
+	}
+	s.applyOrderBy(rows)
+	if offset := s.config.Offset; offset > 0 {
+		if offset >= len(rows) {
+			return nil
+		}
+		rows = rows[offset:]
+	}
+	if s.config.Limit > 0 && len(rows) > s.config.Limit {
+		rows = rows[:s.config.Limit]
+	}
+	s.tagPrimaryKey(rows)
+	return rows
+}
+
+// flushLatest 把分组最新行的快照作为一批派发到 resultChan 与 sinks。
+func (s *Stream) flushLatest() {
+	if rows := s.prepareLatestSnapshot(); len(rows) > 0 {
+		s.sendResultNonBlocking(rows)
+		s.callSinksAsync(rows)
+	}
+}
+
+// flushLatestSync 在 Stop 末尾同步派发最后一次快照（worker pool 已退出）。
+func (s *Stream) flushLatestSync() {
+	if rows := s.prepareLatestSnapshot(); len(rows) > 0 {
+		s.sendResultForFlush(rows)
+		s.invokeSinksInline(rows)
+	}
+}
@@ -36,19 +36,23 @@ func (s *Stream) GetStats() map[string]int64 {
 	s.dataChanMux.RUnlock()
 
 	stats := map[string]int64{
-		InputCount:         s.mInput.Value(),
-		OutputCount:        s.mOutput.Value(),
-		InputDroppedCount:  s.mInputDropped.Value(),
-		OutputDroppedCount: s.mOutputDropped.Value(),
-		DroppedCount:       s.mInputDropped.Value() + s.mOutputDropped.Value(),
-		DataChanLen:        dataChanLen,
-		DataChanCap:        dataChanCap,
-		ResultChanLen:      int64(len(s.resultChan)),
-		ResultChanCap:      int64(cap(s.resultChan)),
-		SinkPoolLen:        int64(len(s.sinkWorkerPool)),
-		SinkPoolCap:        int64(cap(s.sinkWorkerPool)),
-		ActiveRetries:      int64(atomic.LoadInt32(&s.activeRetries)),
-		Expanding:          int64(atomic.LoadInt32(&s.expanding)),
+		InputCount:             s.mInput.Value(),
+		OutputCount:            s.mOutput.Value(),
+		InputDroppedCount:      s.mInputDropped.Value(),
+		OutputDroppedCount:     s.mOutputDropped.Value(),
+		DroppedCount:           s.mInputDropped.Value() + s.mOutputDropped.Value(),
+		DataChanLen:            dataChanLen,
+		DataChanCap:            dataChanCap,
+		ResultChanLen:          int64(len(s.resultChan)),
+		ResultChanCap:          int64(cap(s.resultChan)),
+		SinkPoolLen:            int64(len(s.sinkWorkerPool)),
+		SinkPoolCap:            int64(cap(s.sinkWorkerPool)),
+		SinkInFlight:           int64(len(s.sinkInFlight)),
+		SinkBatchesDropped:     s.mSinkDropped.Value(),
+		ErrorSinkDropped:       s.mErrorSinkDropped.Value(),
+		AggregateBufferDropped: s.mAggBufDropped.Value(),
+		ActiveRetries:          int64(atomic.LoadInt32(&s.activeRetries)),
+		Expanding:              int64(atomic.LoadInt32(&s.expanding)),
 	}
 
 	if s.Window != nil {
@@ -94,6 +98,12 @@ func (s *Stream) GetDetailedStats() map[string]any {
 		DropRate:         dropRate,
 		PerformanceLevel: AssessPerformanceLevel(dataUsage, dropRate),
 	}
+	if card := s.cardinalityStats(); card != nil {
+		result[Cardinality] = card
+	}
+	if s.config.NeedWindow {
+		result[WindowType] = s.config.WindowConfig.Type
+	}
 
 	return result
 }
@@ -104,4 +114,10 @@ func (s *Stream) ResetStats() {
 	s.mOutput.Reset()
 	s.mInputDropped.Reset()
 	s.mOutputDropped.Reset()
+	s.mSinkDropped.Reset()
+	s.mErrorSinkDropped.Reset()
+	s.mAggBufDropped.Reset()
+	for _, t := range s.cardinalityTrackers() {
+		t.sketch.Reset()
+	}
 }
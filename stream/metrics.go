@@ -29,8 +29,16 @@ const (
 	ResultChanCap      = "result_chan_cap"
 	SinkPoolLen        = "sink_pool_len"
 	SinkPoolCap        = "sink_pool_cap"
-	ActiveRetries      = "active_retries"
-	Expanding          = "expanding"
+	SinkInFlight       = "sink_in_flight"
+	SinkBatchesDropped = "sink_batches_dropped"
+	// ErrorSinkDropped counts failed records not delivered to AddErrorSink
+	// callbacks because their queue was full.
+	ErrorSinkDropped = "error_sink_dropped"
+	// AggregateBufferDropped counts values buffering aggregates did not keep
+	// because of MaxAggregateBufferPerGroup, added when each window fires.
+	AggregateBufferDropped = "aggregate_buffer_dropped"
+	ActiveRetries          = "active_retries"
+	Expanding              = "expanding"
 )
 
 // Detailed statistics field keys returned by GetDetailedStats.
@@ -42,8 +50,17 @@ const (
 	ProcessRate      = "process_rate"
 	DropRate         = "drop_rate"
 	PerformanceLevel = "performance_level"
+	// Cardinality maps each TrackCardinality field to its estimated distinct count.
+	Cardinality = "cardinality"
+	// WindowType is the configured window type ("tumbling", "sliding", "hop", ...)
+	// of a window query; absent for non-window queries.
+	WindowType = "window_type"
 )
 
+// CardinalityMetricPrefix prefixes the registry name of a tracked field's
+// distinct-count estimate ("cardinality_<field>").
+const CardinalityMetricPrefix = "cardinality_"
+
 // AssessPerformanceLevel maps data usage and drop rate to a performance level.
 func AssessPerformanceLevel(dataUsage, dropRate float64) string {
 	switch {
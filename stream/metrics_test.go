@@ -17,6 +17,8 @@
 package stream
 
 import (
+	"fmt"
+	"net/http/httptest"
 	"sync"
 	"testing"
 
@@ -105,3 +107,48 @@ func TestStream_CountersConcurrent(t *testing.T) {
 	wg.Wait()
 	assert.Equal(t, int64(10000), s.mInput.Value())
 }
+
+func TestStream_TrackCardinality(t *testing.T) {
+	s := newTestStream(t)
+	assert.NotContains(t, s.GetDetailedStats(), Cardinality, "未跟踪时不输出")
+
+	s.TrackCardinality("name")
+	s.TrackCardinality("name")
+	s.TrackCardinality("meta.region")
+	for i := 0; i < 3000; i++ {
+		s.observeCardinality(map[string]any{
+			"name": fmt.Sprintf("dev-%d", i%1000),
+			"meta": map[string]any{"region": fmt.Sprintf("r%d", i%7)},
+		})
+	}
+	s.observeCardinality(map[string]any{"name": nil})
+
+	card, ok := s.GetDetailedStats()[Cardinality].(map[string]int64)
+	require.True(t, ok)
+	assert.Len(t, card, 2)
+	assert.InEpsilon(t, 1000, float64(card["name"]), 0.05)
+	assert.Equal(t, int64(7), card["meta.region"])
+
+	snap := s.metricsRegistry.Snapshot()
+	assert.Equal(t, card["name"], snap[CardinalityMetricPrefix+"name"])
+
+	s.ResetStats()
+	assert.Equal(t, int64(0), s.GetDetailedStats()[Cardinality].(map[string]int64)["name"])
+}
+
+func TestStream_PrometheusHandler(t *testing.T) {
+	s := newTestStream(t)
+	s.TrackCardinality("name")
+	s.Emit(map[string]any{"name": "a"})
+	s.mOutput.IncBy(2)
+
+	rec := httptest.NewRecorder()
+	s.PrometheusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE streamsql_input_total counter\nstreamsql_input_total 1\n")
+	assert.Contains(t, body, "streamsql_output_total 2\n")
+	assert.Contains(t, body, "# TYPE streamsql_cardinality_name gauge\nstreamsql_cardinality_name 1\n")
+	assert.Contains(t, body, "# TYPE streamsql_data_chan_cap gauge\n")
+	assert.Contains(t, body, "# TYPE streamsql_data_chan_utilization_ratio gauge\n")
+	assert.Contains(t, body, "# TYPE streamsql_memory_heap_alloc_bytes gauge\n")
+}
@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"strings"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// Column names used by long-format output rows.
+const (
+	longMetricField = "metric"
+	longValueField  = "value"
+)
+
+// compileMetricColumns 按 SELECT 顺序收集聚合输出列（含聚合后表达式），作为 long
+// 形态的 metric。window_start()/window_end()/window_watermark()/window_fill_ratio() 及
+// window_start_iso()/window_end_iso() 描述窗口而非度量，与分组列一起保留。
+func (s *Stream) compileMetricColumns() {
+	if s.config.OutputShape != types.OutputShapeLong {
+		return
+	}
+	for _, name := range s.config.FieldOrder {
+		aggType, ok := s.config.SelectFields[name]
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(string(aggType)) {
+		case "window_start", "window_end", "window_watermark", "window_fill_ratio", "window_start_iso", "window_end_iso":
+			continue
+		}
+		s.metricColumns = append(s.metricColumns, name)
+	}
+}
+
+// reshapeLong 将宽表结果展开为 long 形态：每个结果行的每个聚合列生成一行，
+// 携带该行的非聚合列（分组键、窗口边界等）以及 metric/value。行内缺失的聚合列跳过。
+func (s *Stream) reshapeLong(results []map[string]any) []map[string]any {
+	if len(s.metricColumns) == 0 {
+		return results
+	}
+	metrics := make(map[string]struct{}, len(s.metricColumns))
+	for _, m := range s.metricColumns {
+		metrics[m] = struct{}{}
+	}
+
+	long := make([]map[string]any, 0, len(results)*len(s.metricColumns))
+	for _, row := range results {
+		for _, m := range s.metricColumns {
+			v, ok := row[m]
+			if !ok {
+				continue
+			}
+			out := make(map[string]any, len(row)-len(s.metricColumns)+2)
+			for k, val := range row {
+				if _, isMetric := metrics[k]; !isMetric {
+					out[k] = val
+				}
+			}
+			out[longMetricField] = m
+			out[longValueField] = v
+			long = append(long, out)
+		}
+	}
+	return long
+}
@@ -0,0 +1,40 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql/aggregator"
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStream_ReshapeLong 测试 long 形态按 SELECT 顺序展开聚合列，保留分组列与窗口边界
+func TestStream_ReshapeLong(t *testing.T) {
+	s := &Stream{config: types.Config{
+		OutputShape: types.OutputShapeLong,
+		FieldOrder:  []string{"deviceId", "cnt", "total", "ws"},
+		SelectFields: map[string]aggregator.AggregateType{
+			"cnt": "COUNT", "total": "SUM", "ws": "window_start", "__sum_1__": "sum",
+		},
+	}}
+	s.compileMetricColumns()
+	assert.Equal(t, []string{"cnt", "total"}, s.metricColumns)
+
+	got := s.reshapeLong([]map[string]any{
+		{"deviceId": "a", "cnt": 2.0, "total": 5.0, "ws": int64(100)},
+		{"deviceId": "b", "cnt": 1.0, "ws": int64(100)},
+	})
+	assert.Equal(t, []map[string]any{
+		{"deviceId": "a", "ws": int64(100), "metric": "cnt", "value": 2.0},
+		{"deviceId": "a", "ws": int64(100), "metric": "total", "value": 5.0},
+		{"deviceId": "b", "ws": int64(100), "metric": "cnt", "value": 1.0},
+	}, got)
+}
+
+// TestStream_InvalidOutputShape 测试非法的输出形态在构造期报错
+func TestStream_InvalidOutputShape(t *testing.T) {
+	_, err := NewStream(types.Config{OutputShape: "tall"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output shape")
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/rulego/streamsql/window"
+)
+
+// ErrPaused is returned by ProcessSync and ProcessSyncRows while the stream is paused.
+var ErrPaused = errors.New("stream is paused")
+
+// Pause temporarily stops processing without losing buffered input or window
+// state, e.g. for maintenance. The data processor stops consuming the input
+// channel: Emit keeps queueing into it and, once it is full, blocks or drops
+// according to the overflow strategy. Window timers are frozen (see
+// window.Pauser), so no window fires while paused. A record already being
+// processed when Pause is called completes. CloseInput and HandOver still
+// drain queued input while paused. No-op when already paused or stopped.
+func (s *Stream) Pause() {
+	if atomic.LoadInt32(&s.stopped) != 0 || !atomic.CompareAndSwapInt32(&s.paused, 0, 1) {
+		return
+	}
+	if p, ok := s.Window.(window.Pauser); ok {
+		p.Pause()
+	}
+	s.wakeProcessor()
+}
+
+// Resume continues processing after Pause: queued input is consumed in order
+// and window timers restart with the time they had left, shifted by the paused
+// duration, so time-based windows do not fire a burst of overdue windows.
+// No-op when not paused.
+func (s *Stream) Resume() {
+	if !atomic.CompareAndSwapInt32(&s.paused, 1, 0) {
+		return
+	}
+	if p, ok := s.Window.(window.Pauser); ok {
+		p.Resume()
+	}
+	s.wakeProcessor()
+}
+
+// IsPaused reports whether the stream is paused.
+func (s *Stream) IsPaused() bool {
+	return atomic.LoadInt32(&s.paused) != 0
+}
+
+// wakeProcessor makes the data processor re-read the paused flag now instead
+// of on its next idle tick.
+func (s *Stream) wakeProcessor() {
+	select {
+	case s.pauseWake <- struct{}{}:
+	default:
+	}
+}
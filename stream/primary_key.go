@@ -0,0 +1,100 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Primary key metadata columns added to every emitted row when
+// Config.PrimaryKey is set.
+const (
+	// PrimaryKeyField holds the row's key: the JSON array of its PrimaryKey
+	// column values, e.g. ["dev1","1700000000000000000_1700000010000000000"].
+	PrimaryKeyField = "__pk"
+	// UpsertField is true when a row with the same key was emitted before
+	// (late re-emits under AllowedLateness, repeated direct-query keys), so
+	// sinks should update rather than insert.
+	UpsertField = "__upsert"
+)
+
+// primaryKeySeenLimit bounds how many distinct keys are remembered for upsert
+// detection; the oldest keys are forgotten first.
+const primaryKeySeenLimit = 100000
+
+// primaryKeyTracker 记录已输出过的主键（有界 FIFO），用于判断再次输出是否为 upsert。
+type primaryKeyTracker struct {
+	mu    sync.Mutex
+	seen  map[string]struct{}
+	order []string // 按首次输出顺序，超过上限时从头淘汰
+}
+
+func newPrimaryKeyTracker() *primaryKeyTracker {
+	return &primaryKeyTracker{seen: make(map[string]struct{})}
+}
+
+// markSeen 记录 key 并返回此前是否已输出过。
+func (t *primaryKeyTracker) markSeen(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.seen[key]; ok {
+		return true
+	}
+	t.seen[key] = struct{}{}
+	t.order = append(t.order, key)
+	if len(t.order) > primaryKeySeenLimit {
+		delete(t.seen, t.order[0])
+		t.order = t.order[1:]
+	}
+	return false
+}
+
+// validatePrimaryKey 拒绝无法唯一标识输出行的主键声明：重复列；long 形态下每个分组
+// 输出多行（每个 metric 一行），主键必须包含 metric 列，且不能引用已展开为行的聚合列
+// 或 value 列（它们不再是输出列）。需在 compileMetricColumns 之后调用。
+func (s *Stream) validatePrimaryKey() error {
+	cols := make(map[string]struct{}, len(s.config.PrimaryKey))
+	for _, col := range s.config.PrimaryKey {
+		if _, dup := cols[col]; dup {
+			return fmt.Errorf("invalid primary key: duplicate column %q", col)
+		}
+		cols[col] = struct{}{}
+	}
+	if len(cols) == 0 || len(s.metricColumns) == 0 {
+		return nil
+	}
+	if _, ok := cols[longValueField]; ok {
+		return fmt.Errorf("invalid primary key: column %q holds the aggregate value in long output shape", longValueField)
+	}
+	for _, m := range s.metricColumns {
+		if _, ok := cols[m]; ok {
+			return fmt.Errorf("invalid primary key: aggregate column %q is not an output column in long output shape", m)
+		}
+	}
+	if _, ok := cols[longMetricField]; !ok {
+		return fmt.Errorf("invalid primary key: long output shape emits one row per aggregate, the key must include %q", longMetricField)
+	}
+	return nil
+}
+
+// tagPrimaryKey 为每行写入 PrimaryKeyField/UpsertField（未配置 PrimaryKey 时不做处理）。
+// 缺失的主键列按 NULL 参与编码。
+func (s *Stream) tagPrimaryKey(results []map[string]any) {
+	if s.primaryKeys == nil {
+		return
+	}
+	vals := make([]any, len(s.config.PrimaryKey))
+	for _, r := range results {
+		for i, col := range s.config.PrimaryKey {
+			vals[i] = r[col]
+		}
+		b, err := json.Marshal(vals)
+		if err != nil {
+			// 不可 JSON 编码的值退化为文本形式，保证键仍然稳定
+			b, _ = json.Marshal(fmt.Sprintf("%v", vals))
+		}
+		key := string(b)
+		r[PrimaryKeyField] = key
+		r[UpsertField] = s.primaryKeys.markSeen(key)
+	}
+}
@@ -18,8 +18,10 @@ package stream
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rulego/streamsql/aggregator"
@@ -33,6 +35,9 @@ import (
 // DataProcessor data processor responsible for processing data streams
 type DataProcessor struct {
 	stream *Stream
+	// directRows counts non-aggregation result rows produced so far, for
+	// OFFSET/LIMIT. Only touched by the Process goroutine.
+	directRows int
 }
 
 // NewDataProcessor creates a data processor
@@ -57,12 +62,17 @@ func (dp *DataProcessor) Process() {
 		// Safely access dataChan using read lock
 		dp.stream.dataChanMux.RLock()
 		currentDataChan := dp.stream.dataChan
+		currentBatchChan := dp.stream.batchChan
 		dp.stream.dataChanMux.RUnlock()
 
 		// Check if dataChan is nil (stream has been stopped)
 		if currentDataChan == nil {
 			return
 		}
+		if atomic.LoadInt32(&dp.stream.paused) != 0 {
+			// Paused: leave input queued; barriers and shutdown are still served
+			currentDataChan, currentBatchChan = nil, nil
+		}
 
 		select {
 		case data, ok := <-currentDataChan:
@@ -71,6 +81,31 @@ func (dp *DataProcessor) Process() {
 				return
 			}
 			dp.processItem(data)
+			dp.stream.checkBackpressure()
+		case batch := <-currentBatchChan:
+			// EmitMany batch: records are processed in order, each as a single Emit.
+			for _, data := range batch {
+				dp.processItem(data)
+			}
+			dp.stream.checkBackpressure()
+		case ack := <-dp.stream.flushChan:
+			// CloseInput barrier: everything enqueued before it is processed first.
+			dp.drainInput()
+			dp.flushOpen()
+			close(ack)
+		case ack := <-dp.stream.drainChan:
+			// HandOver barrier: process queued input, leave open windows as they are.
+			dp.drainInput()
+			dp.stream.flushTransformBuffer()
+			close(ack)
+		case <-dp.stream.transformBuf.C():
+			// TransformFlushInterval elapsed since the oldest buffered result row.
+			dp.stream.flushTransformBuffer()
+		case <-dp.stream.latest.C():
+			// StateFlushInterval: snapshot of every group's latest row.
+			dp.stream.flushLatest()
+		case <-dp.stream.pauseWake:
+			// Pause/Resume: re-read the paused flag
 		case <-dp.stream.done:
 			// Received close signal
 			return
@@ -80,6 +115,43 @@ func (dp *DataProcessor) Process() {
 	}
 }
 
+// drainInput 处理 dataChan/batchChan 中已排队的全部数据（CloseInput 屏障用）。
+// expand 策略可能替换 dataChan，故每轮在读锁下重新取当前 channel。
+func (dp *DataProcessor) drainInput() {
+	for {
+		dp.stream.dataChanMux.RLock()
+		dataChan, batchChan := dp.stream.dataChan, dp.stream.batchChan
+		dp.stream.dataChanMux.RUnlock()
+		select {
+		case data, ok := <-dataChan:
+			if !ok {
+				return
+			}
+			dp.processItem(data)
+		case batch := <-batchChan:
+			for _, data := range batch {
+				dp.processItem(data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// flushOpen 在输入结束时派发缓冲的非聚合结果与分组快照，并触发所有未触发窗口（Flusher）与 CEP 未闭合匹配的输出。
+func (dp *DataProcessor) flushOpen() {
+	dp.stream.flushTransformBuffer()
+	dp.stream.flushLatest()
+	if dp.stream.config.NeedWindow {
+		if f, ok := dp.stream.Window.(window.Flusher); ok {
+			f.Flush()
+		}
+	}
+	if dp.stream.cep != nil {
+		dp.stream.emitCepResults(dp.stream.projectCep(dp.stream.cep.engine.Flush()))
+	}
+}
+
 // processItem 处理单条事件，recover 防止单行 panic 中断处理循环。
 func (dp *DataProcessor) processItem(data map[string]any) {
 	defer func() {
@@ -87,6 +159,24 @@ func (dp *DataProcessor) processItem(data map[string]any) {
 			dp.stream.log.Error("process panic recovered: %v", r)
 		}
 	}()
+	if dp.stream.intervalJoin == nil {
+		dp.dispatchItem(data)
+		return
+	}
+	// Stream self-join: each joined pair continues through the pipeline as one row.
+	joined, err := dp.stream.intervalJoin.add(data)
+	if err != nil {
+		dp.stream.log.Error("self-join error: %v", err)
+		dp.stream.reportRecordError(data, err)
+		return
+	}
+	for _, row := range joined {
+		dp.dispatchItem(row)
+	}
+}
+
+// dispatchItem routes one row to the CEP, window or direct path.
+func (dp *DataProcessor) dispatchItem(data map[string]any) {
 	switch {
 	case dp.stream.config.Mode == types.ExecCEP:
 		dp.processCEP(data)
@@ -97,10 +187,13 @@ func (dp *DataProcessor) processItem(data map[string]any) {
 		dataMap, keep, jerr := dp.stream.enrichData(data)
 		if jerr != nil {
 			dp.stream.log.Error("join enrichment error: %v", jerr)
+			dp.stream.reportRecordError(data, jerr)
 		}
-		if keep && (dp.stream.filter == nil || dp.stream.filter.Evaluate(dataMap)) {
+		if keep && dp.stream.passesFilter(dataMap) {
 			dp.stream.injectGroupKeyExprs(dataMap)
 			dp.stream.Window.Add(dataMap)
+		} else if keep && dp.stream.config.QualityCounts {
+			dp.countRejected(dataMap)
 		}
 	default:
 		// Direct mode: processDirectData does enrich(if JOIN) ->
@@ -120,12 +213,13 @@ func (dp *DataProcessor) processCEP(data map[string]any) {
 	dataMap, keep, err := dp.stream.enrichData(data)
 	if err != nil {
 		dp.stream.log.Error("cep join enrichment error: %v", err)
+		dp.stream.reportRecordError(data, err)
 		return
 	}
 	if !keep {
 		return
 	}
-	if dp.stream.filter != nil && !dp.stream.filter.Evaluate(dataMap) {
+	if !dp.stream.passesFilter(dataMap) {
 		return
 	}
 	if dp.stream.cep == nil {
@@ -140,8 +234,56 @@ func (dp *DataProcessor) processCEP(data map[string]any) {
 	dp.stream.emitCepResults(dp.stream.projectCep(raw))
 }
 
+// countRejected reports a row rejected by WHERE to the aggregator so its group's
+// __input_count includes it (Config.QualityCounts).
+func (dp *DataProcessor) countRejected(dataMap map[string]any) {
+	rc, ok := dp.stream.aggregator.(aggregator.RejectCounter)
+	if !ok {
+		return
+	}
+	dp.stream.injectGroupKeyExprs(dataMap)
+	// 计数窗口不按时间划分：零时间戳使拒绝行计入其分组的下一次结果
+	var ts time.Time
+	if dp.stream.config.WindowConfig.Type != window.TypeCounting {
+		ts = dp.stream.rowTime(dataMap)
+	}
+	if err := rc.AddRejected(dataMap, ts); err != nil {
+		dp.stream.log.Error("quality count error: %v", err)
+	}
+}
+
+// keyedEmission reports whether windows fire per group key (counting windows
+// with GROUP BY) rather than for all groups at once.
+func (dp *DataProcessor) keyedEmission() bool {
+	return dp.stream.config.WindowConfig.Type == window.TypeCounting && len(dp.stream.config.WindowConfig.GroupByKeys) > 0
+}
+
 // initializeAggregator initializes the aggregator
 func (dp *DataProcessor) initializeAggregator() {
+	// AggregationShards > 1 shards groups across parallel aggregators by group-key
+	// hash; only meaningful with GROUP BY fields (otherwise there is one group).
+	shards := dp.stream.config.PerformanceConfig.WorkerConfig.AggregationShards
+	if shards > 1 && len(dp.stream.config.GroupFields) > 0 {
+		parts := make([]aggregator.Aggregator, shards)
+		for i := range parts {
+			parts[i] = dp.newAggregator()
+		}
+		sharded := aggregator.NewShardedAggregator(dp.stream.config.GroupFields, parts)
+		// Validated by the stream factory.
+		_ = sharded.SetGroupNullPolicy(dp.stream.config.GroupNullPolicy)
+		dp.stream.aggregator = sharded
+	} else {
+		dp.stream.aggregator = dp.newAggregator()
+	}
+
+	// Register expression calculators
+	for field, fieldExpr := range dp.stream.config.FieldExpressions {
+		dp.registerExpressionCalculator(field, fieldExpr)
+	}
+}
+
+// newAggregator creates one group aggregator for the query's aggregation fields.
+func (dp *DataProcessor) newAggregator() aggregator.Aggregator {
 	// Convert to new AggregationField format
 	aggregationFields := convertToAggregationFields(dp.stream.config.SelectFields, dp.stream.config.FieldAlias)
 
@@ -163,16 +305,27 @@ func (dp *DataProcessor) initializeAggregator() {
 			}
 		}
 
-		dp.stream.aggregator = enhancedAgg
-	} else {
-		// Use regular aggregator
-		dp.stream.aggregator = aggregator.NewGroupAggregator(dp.stream.config.GroupFields, aggregationFields)
+		if dp.stream.config.QualityCounts {
+			enhancedAgg.EnableQualityCounts()
+			enhancedAgg.SetKeyedEmission(dp.keyedEmission())
+		}
+		enhancedAgg.SetMinGroupCount(dp.stream.config.MinGroupCount)
+		enhancedAgg.SetNonFinitePolicy(dp.stream.config.NonFinitePolicy)
+		enhancedAgg.SetAggregateBufferLimit(dp.stream.config.MaxAggregateBufferPerGroup, dp.stream.config.AggregateBufferPolicy)
+		_ = enhancedAgg.SetGroupNullPolicy(dp.stream.config.GroupNullPolicy)
+		return enhancedAgg
 	}
-
-	// Register expression calculators
-	for field, fieldExpr := range dp.stream.config.FieldExpressions {
-		dp.registerExpressionCalculator(field, fieldExpr)
+	// Use regular aggregator
+	groupAgg := aggregator.NewGroupAggregator(dp.stream.config.GroupFields, aggregationFields)
+	if dp.stream.config.QualityCounts {
+		groupAgg.EnableQualityCounts()
+		groupAgg.SetKeyedEmission(dp.keyedEmission())
 	}
+	groupAgg.SetMinGroupCount(dp.stream.config.MinGroupCount)
+	groupAgg.SetNonFinitePolicy(dp.stream.config.NonFinitePolicy)
+	groupAgg.SetAggregateBufferLimit(dp.stream.config.MaxAggregateBufferPerGroup, dp.stream.config.AggregateBufferPolicy)
+	_ = groupAgg.SetGroupNullPolicy(dp.stream.config.GroupNullPolicy)
+	return groupAgg
 }
 
 // convertToAggregationFieldInfos converts types.AggregationFieldInfo to aggregator.AggregationFieldInfo
@@ -204,7 +357,22 @@ func (dp *DataProcessor) registerExpressionCalculator(field string, fieldExpr ty
 		func(data any) (any, error) {
 			// Ensure data is map[string]any type
 			if dataMap, ok := data.(map[string]any); ok {
-				return dp.evaluateExpressionForAggregation(currentFieldExpr, dataMap)
+				timeout := dp.stream.config.ExpressionTimeout
+				if timeout <= 0 {
+					return dp.evaluateExpressionForAggregation(currentFieldExpr, dataMap)
+				}
+				row := copyRow(dataMap) // 超时后仍在运行的求值不读调用方的 map
+				value, err := expr.EvaluateContext(dp.stream.evalContext(), timeout, func() (any, error) {
+					return dp.evaluateExpressionForAggregation(currentFieldExpr, row)
+				})
+				if errors.Is(err, expr.ErrEvaluationTimeout) {
+					dp.stream.log.Error("Expression evaluation failed for field %s: %v", currentField, err)
+					dp.stream.DeadLetter(dataMap, fmt.Sprintf("field %s: %v", currentField, err))
+				}
+				if err != nil {
+					dp.stream.reportRecordError(dataMap, fmt.Errorf("field %s: %w", currentField, err))
+				}
+				return value, err
 			}
 			return nil, fmt.Errorf("unsupported data type: %T, expected map[string]any", data)
 		},
@@ -350,8 +518,10 @@ func (dp *DataProcessor) fallbackExpressionEvaluation(expression string, dataMap
 
 // startWindowProcessing starts window processing
 func (dp *DataProcessor) startWindowProcessing() {
-	// Start window processing goroutine
-	dp.stream.Window.Start()
+	// Start window processing goroutine (an adopted window is already running)
+	if !dp.stream.windowAdopted {
+		dp.stream.Window.Start()
+	}
 
 	// Process window mode
 	go func() {
@@ -371,6 +541,18 @@ func (dp *DataProcessor) startWindowProcessing() {
 					return
 				}
 				dp.processWindowBatch(batch)
+			case ack := <-dp.stream.windowFlushChan:
+				// CloseInput barrier: dispatch every window batch already emitted.
+				for drained := false; !drained; {
+					select {
+					case batch := <-outputChan:
+						dp.processWindowBatch(batch)
+					default:
+						drained = true
+					}
+				}
+				dp.stream.flushCompacted()
+				close(ack)
 			case <-dp.stream.done:
 				// Stream stopped, exit
 				return
@@ -381,6 +563,15 @@ func (dp *DataProcessor) startWindowProcessing() {
 
 // processWindowBatch processes window batch data
 func (dp *DataProcessor) processWindowBatch(batch []types.Row) {
+	if dp.stream.config.PerformanceConfig.RowPooling {
+		// Results are new maps built from the rows; no Row outlives this call.
+		defer window.ReleaseBatch(batch)
+	}
+	if dp.stream.poolJoinRows {
+		// Deferred after ReleaseBatch so it runs first, while the rows still
+		// reference their JOIN working maps.
+		defer releaseJoinRows(batch)
+	}
 	// Global window maintains its own running aggregate and emits final result
 	// maps directly (FIRE_AND_PURGE per group); each Row.Data is already a
 	// complete result row, so skip the stream aggregator and go straight to
@@ -396,27 +587,106 @@ func (dp *DataProcessor) processWindowBatch(batch []types.Row) {
 		return
 	}
 
-	// Process window batch data
-	for _, item := range batch {
-		if err := dp.stream.aggregator.Put(WindowStartField, item.Slot.WindowStart()); err != nil {
+	// Sharded aggregation: the whole batch shares one window slot, so set the
+	// window bounds once and let the shards ingest their partitions in parallel.
+	if batchAdder, ok := dp.stream.aggregator.(aggregator.BatchAdder); ok && len(batch) > 0 {
+		if err := dp.stream.aggregator.Put(WindowStartField, batch[0].Slot.WindowStart()); err != nil {
 			dp.stream.log.Error("failed to put window start: %v", err)
 		}
-		if err := dp.stream.aggregator.Put(WindowEndField, item.Slot.WindowEnd()); err != nil {
+		if err := dp.stream.aggregator.Put(WindowEndField, batch[0].Slot.WindowEnd()); err != nil {
 			dp.stream.log.Error("failed to put window end: %v", err)
 		}
-		if err := dp.stream.aggregator.Add(item.Data); err != nil {
+		if err := dp.stream.aggregator.Put(WindowWatermarkField, batch[0].Watermark.UnixNano()); err != nil {
+			dp.stream.log.Error("failed to put window watermark: %v", err)
+		}
+		dp.putFillRatio(batch)
+		dp.putWindowISO(batch[0].Slot)
+		rows := make([]any, len(batch))
+		ts := make([]time.Time, len(batch))
+		for i, item := range batch {
+			rows[i] = item.Data
+			ts[i] = item.Timestamp
+		}
+		if err := batchAdder.AddBatch(rows, ts); err != nil {
 			dp.stream.log.Error("aggregate error: %v", err)
 		}
+	} else {
+		dp.putFillRatio(batch)
+		// Process window batch data
+		for _, item := range batch {
+			if err := dp.stream.aggregator.Put(WindowStartField, item.Slot.WindowStart()); err != nil {
+				dp.stream.log.Error("failed to put window start: %v", err)
+			}
+			if err := dp.stream.aggregator.Put(WindowEndField, item.Slot.WindowEnd()); err != nil {
+				dp.stream.log.Error("failed to put window end: %v", err)
+			}
+			if err := dp.stream.aggregator.Put(WindowWatermarkField, item.Watermark.UnixNano()); err != nil {
+				dp.stream.log.Error("failed to put window watermark: %v", err)
+			}
+			dp.putWindowISO(item.Slot)
+			if err := dp.addRow(item); err != nil {
+				dp.stream.log.Error("aggregate error: %v", err)
+				if m, ok := item.Data.(map[string]any); ok {
+					if dp.stream.poolJoinRows {
+						// The working map is recycled once this batch is done.
+						m = copyRow(m)
+					}
+					dp.stream.reportRecordError(m, err)
+				}
+			}
+		}
 	}
 
 	// Get and send aggregation results
 	if results, err := dp.stream.aggregator.GetResults(); err == nil {
 		stampWindowID(results, batch)
 		dp.processAggregationResults(results)
+		if counter, ok := dp.stream.aggregator.(aggregator.BufferDropCounter); ok {
+			dp.stream.mAggBufDropped.IncBy(counter.BufferDropped())
+		}
 		dp.stream.aggregator.Reset()
 	}
 }
 
+// putFillRatio puts the fill ratio of the window emitting batch (nil for window
+// types without an expected size), shared by all rows of the batch.
+func (dp *DataProcessor) putFillRatio(batch []types.Row) {
+	var ratio any
+	if r, ok := window.FillRatio(dp.stream.config.WindowConfig, batch); ok {
+		ratio = r
+	}
+	if err := dp.stream.aggregator.Put(WindowFillRatioField, ratio); err != nil {
+		dp.stream.log.Error("failed to put window fill ratio: %v", err)
+	}
+}
+
+// putWindowISO hands the window bounds to window_start_iso()/window_end_iso()
+// as RFC3339 strings in the configured time zone; NULL when a bound is unknown.
+func (dp *DataProcessor) putWindowISO(slot *types.TimeSlot) {
+	var start, end any
+	if s := slot.GetStartTime(); s != nil {
+		start = functions.FormatWindowISO(s.UnixNano(), dp.stream.location)
+	}
+	if e := slot.GetEndTime(); e != nil {
+		end = functions.FormatWindowISO(e.UnixNano(), dp.stream.location)
+	}
+	if err := dp.stream.aggregator.Put(WindowStartISOField, start); err != nil {
+		dp.stream.log.Error("failed to put window start iso: %v", err)
+	}
+	if err := dp.stream.aggregator.Put(WindowEndISOField, end); err != nil {
+		dp.stream.log.Error("failed to put window end iso: %v", err)
+	}
+}
+
+// addRow feeds one window row to the aggregator, with its timestamp when the
+// aggregator accepts one (needed by time-based aggregates such as time_in_state).
+func (dp *DataProcessor) addRow(item types.Row) error {
+	if timed, ok := dp.stream.aggregator.(aggregator.TimedAdder); ok {
+		return timed.AddAt(item.Data, item.Timestamp)
+	}
+	return dp.stream.aggregator.Add(item.Data)
+}
+
 // stampWindowID stamps a stable window_id (window time bounds) onto each
 // result. It is identical across the initial emit and accumulating late
 // re-emits (AllowedLateness>0), so sinks can dedup/replace by group + window_id.
@@ -430,7 +700,7 @@ func stampWindowID(results []map[string]any, batch []types.Row) {
 	}
 	id := fmt.Sprintf("%d_%d", slot.Start.UnixNano(), slot.End.UnixNano())
 	for _, r := range results {
-		r["window_id"] = id
+		r[WindowIDField] = id
 	}
 }
 
@@ -440,9 +710,12 @@ func (dp *DataProcessor) processAggregationResults(results []map[string]any) {
 	// the qualified key temporarily so HAVING/ORDER BY can reference either form.
 	dp.stream.projectGroupColumns(results)
 
-	// 窗口查询里分析函数对结果行求值（状态跨窗口保留），在 HAVING 之前，
-	// 这样 HAVING 可引用分析函数别名。
+	// 窗口查询里分析函数对结果行求值（状态跨窗口保留，窗口级函数如 window_position
+	// 除外），在 HAVING 之前，这样 HAVING 可引用分析函数别名。
 	if dp.stream.hasAnalyticFields() {
+		dp.stream.ensureAnalytic()
+		dp.stream.analytic.ResetWindowScoped()
+		dp.stream.applyPartitionAnalytic(results)
 		kept := results[:0]
 		for _, r := range results {
 			if dp.stream.applyWindowAnalytic(r) {
@@ -477,19 +750,55 @@ func (dp *DataProcessor) processAggregationResults(results []map[string]any) {
 	// Apply ORDER BY before LIMIT so LIMIT selects the top-N of the sorted order.
 	dp.stream.applyOrderBy(finalResults)
 
-	// Apply LIMIT restriction
+	// Apply OFFSET, then LIMIT, per emitted batch
+	if offset := dp.stream.config.Offset; offset > 0 {
+		if offset >= len(finalResults) {
+			finalResults = finalResults[:0]
+		} else {
+			finalResults = finalResults[offset:]
+		}
+	}
 	if dp.stream.config.Limit > 0 && len(finalResults) > dp.stream.config.Limit {
 		finalResults = finalResults[:dp.stream.config.Limit]
 	}
 
-	// Send results to result channel and Sink functions
-	if len(finalResults) > 0 {
+	// CompactUnchangedWindows: hold each group's result while later windows repeat it
+	if dp.stream.compactor != nil {
+		finalResults = dp.stream.compactor.compact(finalResults, dp.stream.outputGroupKey)
+	}
+
+	dp.stream.emitAggregationRows(finalResults)
+}
+
+// emitAggregationRows sends finished aggregation rows to the result channel and sinks.
+func (s *Stream) emitAggregationRows(rows []map[string]any) {
+	for _, batch := range s.aggregationBatches(rows) {
 		// Non-blocking send to result channel
-		dp.stream.sendResultNonBlocking(finalResults)
+		s.sendResultNonBlocking(batch)
 
 		// Asynchronously call all sinks
-		dp.stream.callSinksAsync(finalResults)
+		s.callSinksAsync(batch)
+	}
+}
+
+// aggregationBatches reshapes and tags finished aggregation rows and splits
+// them into the batches to dispatch (one per group with EmitPerGroup).
+func (s *Stream) aggregationBatches(rows []map[string]any) [][]map[string]any {
+	// Reshape to long format (one row per aggregate) after LIMIT, so LIMIT counts groups
+	if s.config.OutputShape == types.OutputShapeLong {
+		rows = s.reshapeLong(rows)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	s.tagPrimaryKey(rows)
+	if s.windowHistory != nil {
+		s.windowHistory.record(rows)
 	}
+	if s.config.EmitGranularity == types.EmitPerGroup {
+		return s.splitByGroup(rows)
+	}
+	return [][]map[string]any{rows}
 }
 
 // applyDistinct applies DISTINCT deduplication
@@ -601,6 +910,20 @@ func (dp *DataProcessor) applyHavingWithCondition(results []map[string]any) []ma
 		}
 	}
 
+	// Preprocess BETWEEN syntax in HAVING condition
+	if bridge.ContainsBetweenOperator(processedHaving) {
+		if processed, err := bridge.PreprocessBetweenExpression(processedHaving); err == nil {
+			processedHaving = processed
+		}
+	}
+
+	// Preprocess IN syntax in HAVING condition
+	if bridge.ContainsInOperator(processedHaving) {
+		if processed, err := bridge.PreprocessInExpression(processedHaving); err == nil {
+			processedHaving = processed
+		}
+	}
+
 	// Create HAVING condition
 	havingFilter, err := condition.NewExprCondition(processedHaving)
 	if err != nil {
@@ -626,29 +949,74 @@ func (dp *DataProcessor) processDirectData(data map[string]any) {
 	dataMap, keep, err := dp.stream.enrichData(data)
 	if err != nil {
 		dp.stream.log.Error("join enrichment error: %v", err)
+		dp.stream.reportRecordError(data, err)
 		return
 	}
 	if !keep {
 		return
 	}
-	analyticResults, pass := dp.stream.applyWhereAndAnalytic(dataMap)
+	analyticResults, pass, _ := dp.stream.applyWhereAndAnalytic(dataMap)
 	if !pass {
 		return
 	}
-	result, emit := dp.stream.projectDirectRow(dataMap, analyticResults)
+	result, emit, err := dp.stream.projectDirectRow(dataMap, analyticResults)
+	if err != nil {
+		dp.stream.log.Error("projection error: %v", err)
+		return
+	}
 	if !emit {
 		return
 	}
 	// Check if any field contains unnest function result and expand to multiple rows
 	results := dp.expandUnnestResults(result, dataMap)
+	// StateFlushInterval: keep the rows as their group's latest state; they are
+	// emitted with the next snapshot.
+	if dp.stream.latest != nil {
+		dp.stream.latest.update(dp.stream.groupStateKey(dataMap), results)
+		return
+	}
 	// Apply ORDER BY to the (possibly unnest-expanded) batch.
 	dp.stream.applyOrderBy(results)
+	// An unnest of an empty array still emits its empty batch; only rows
+	// cut by OFFSET/LIMIT are suppressed.
+	if len(results) > 0 {
+		if results = dp.applyDirectOffsetLimit(results); len(results) == 0 {
+			return
+		}
+	}
+	dp.stream.tagPrimaryKey(results)
+	// With TransformFlushInterval, rows are batched and sent by flushTransformBuffer
+	if dp.stream.transformBuf != nil && len(results) > 0 {
+		if dp.stream.transformBuf.add(results) {
+			dp.stream.flushTransformBuffer()
+		}
+		return
+	}
 	// Non-blocking send result to resultChan
 	dp.stream.sendResultNonBlocking(results)
 	// Asynchronously call all sinks, avoid blocking
 	dp.stream.callSinksAsync(results)
 }
 
+// applyDirectOffsetLimit keeps the rows of a non-aggregation result batch that
+// fall within OFFSET/LIMIT, counted across the whole stream: the first Offset
+// rows ever produced are skipped and at most Limit rows are emitted after them.
+func (dp *DataProcessor) applyDirectOffsetLimit(results []map[string]any) []map[string]any {
+	offset, limit := dp.stream.config.Offset, dp.stream.config.Limit
+	if offset <= 0 && limit <= 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, r := range results {
+		idx := dp.directRows
+		dp.directRows++
+		if idx >= offset && (limit <= 0 || idx < offset+limit) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
 // expandUnnestResults 检查结果是否包含 unnest 函数输出并展开为多行
 func (dp *DataProcessor) expandUnnestResults(result map[string]any, originalData map[string]any) []map[string]any {
 	// Early return if no unnest function is used in the query
@@ -1,6 +1,7 @@
 package stream
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -21,7 +22,10 @@ type fieldProcessInfo struct {
 	isSelectAll     bool   // Whether it's SELECT *
 	isStringLiteral bool   // Whether it's a string literal
 	stringValue     string // Pre-processed string literal value (quotes removed)
+	isLiteral       bool   // Whether it's a numeric or boolean literal
+	literalValue    any    // Pre-parsed numeric or boolean literal value
 	alias           string // Field alias for quick access
+	fallbackExpr    string // Fallback argument of an outermost coalesce_expr(expr, fallback), "" otherwise
 }
 
 // expressionProcessInfo expression processing information for caching pre-compiled expression processing logic
@@ -33,6 +37,7 @@ type expressionProcessInfo struct {
 	compiledExpr            *expr.Expression // Pre-compiled expression object
 	compiledExprFastPath    bool             // compiledExpr usable as fast path (no quotes/backticks): skip bridge per-row checks
 	needsBacktickPreprocess bool             // Whether backtick preprocessing is needed
+	fallbackExpr            string           // Fallback argument of an outermost coalesce_expr(expr, fallback), "" otherwise
 }
 
 // compileFieldProcessInfo pre-compiles field processing information to avoid runtime re-parsing
@@ -53,6 +58,25 @@ func (s *Stream) compileFieldProcessInfo() {
 
 	// Pre-compile expression field information
 	s.compileExpressionInfo()
+	s.compileGroupKeyCases()
+}
+
+// compileGroupKeyCases pre-compiles CASE expression GROUP BY keys (e.g.
+// "CASE WHEN temperature > 30 THEN 'hot' ELSE 'cold' END"), which the expr
+// bridge cannot evaluate. Keys that fail to compile are left to the bridge.
+func (s *Stream) compileGroupKeyCases() {
+	s.groupKeyCases = nil
+	for _, gf := range s.config.GroupFields {
+		if !strings.HasPrefix(strings.ToUpper(gf), SQLKeywordCase+" ") {
+			continue
+		}
+		if compiled, err := expr.NewExpression(gf); err == nil {
+			if s.groupKeyCases == nil {
+				s.groupKeyCases = make(map[string]*expr.Expression)
+			}
+			s.groupKeyCases[gf] = compiled
+		}
+	}
 }
 
 // stripJoinAlias removes a leading stream/table alias ("m." / "s.") from an
@@ -70,6 +94,9 @@ func (s *Stream) stripJoinAlias(name string) string {
 	if first == s.config.SourceAlias {
 		return parts[1]
 	}
+	if ij := s.config.IntervalJoin; ij != nil && first == ij.RightAlias {
+		return parts[1]
+	}
 	for _, jc := range s.config.JoinConfigs {
 		if first == jc.Alias {
 			return parts[1]
@@ -202,11 +229,21 @@ func (s *Stream) projectGroupColumns(results []map[string]any) {
 	}
 }
 
-// injectGroupKeyExprs 对函数表达式分组键（如 upper(device)）就地求值并写入行，使窗口与
-// aggregator 能按该合成键分组（它们只按 row[key] 取值，不求值）。裸列键无需处理。
-// 仅窗口路径在 Window.Add 前调用；dataMap 为 Emit 拷贝或 JOIN 增强副本，注入安全。
+// injectGroupKeyExprs 对函数表达式分组键（如 upper(device)）与 CASE 表达式分组键就地
+// 求值并写入行，使窗口与 aggregator 能按该合成键分组（它们只按 row[key] 取值，不求值）。
+// 裸列键无需处理。仅窗口路径在 Window.Add 前调用；dataMap 为 Emit 拷贝或 JOIN 增强副本，
+// 注入安全。
 func (s *Stream) injectGroupKeyExprs(data map[string]any) {
 	for _, gf := range s.config.GroupFields {
+		if compiled := s.groupKeyCases[gf]; compiled != nil {
+			// NULL（无匹配分支且无 ELSE）或求值失败按 NULL 分组键处理
+			v, isNull, err := compiled.EvaluateValueWithNull(data)
+			if err != nil || isNull {
+				v = nil
+			}
+			data[gf] = v
+			continue
+		}
 		if !strings.Contains(gf, "(") {
 			continue
 		}
@@ -303,7 +340,8 @@ func (s *Stream) compileSimpleFieldInfo(fieldSpec string) *fieldProcessInfo {
 	parts = splitFieldSpec(fieldSpec)
 	info.fieldName = parts[0]
 	// Remove backticks from field name
-	if len(info.fieldName) >= 2 && info.fieldName[0] == '`' && info.fieldName[len(info.fieldName)-1] == '`' {
+	quoted := len(info.fieldName) >= 2 && info.fieldName[0] == '`' && info.fieldName[len(info.fieldName)-1] == '`'
+	if quoted {
 		info.fieldName = info.fieldName[1 : len(info.fieldName)-1]
 	}
 	info.outputName = info.fieldName
@@ -318,6 +356,9 @@ func (s *Stream) compileSimpleFieldInfo(fieldSpec string) *fieldProcessInfo {
 	// Pre-determine field characteristics
 	info.isFunctionCall = strings.Contains(info.fieldName, "(") && strings.Contains(info.fieldName, ")")
 	info.hasNestedField = !info.isFunctionCall && fieldpath.IsNestedField(info.fieldName)
+	if info.isFunctionCall {
+		info.fallbackExpr = coalesceExprFallback(info.fieldName)
+	}
 
 	// Check if it's a string literal and preprocess value
 	info.isStringLiteral = (len(info.fieldName) >= 2 &&
@@ -329,12 +370,44 @@ func (s *Stream) compileSimpleFieldInfo(fieldSpec string) *fieldProcessInfo {
 		info.stringValue = info.fieldName[1 : len(info.fieldName)-1]
 	}
 
+	// Numeric and boolean constants (SELECT 1 AS one) are projected like string literals;
+	// a backtick-quoted name is always a field reference
+	if !quoted && !info.isStringLiteral && !info.isFunctionCall {
+		info.literalValue, info.isLiteral = parseConstantLiteral(info.fieldName)
+	}
+
 	// Set alias for quick access
 	info.alias = info.outputName
 
 	return info
 }
 
+// numericLiteralRe is the SQL numeric literal grammar: optional sign, decimal
+// digits with an optional fraction, optional exponent. Words strconv would also
+// accept (NaN, Inf, Infinity), hex and underscores stay field names.
+var numericLiteralRe = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+
+// parseConstantLiteral parses a numeric or boolean constant SELECT item.
+// Integers become int64, other numbers float64.
+func parseConstantLiteral(s string) (any, bool) {
+	if !numericLiteralRe.MatchString(s) {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
 // compileExpressionInfo pre-compiles expression processing information
 func (s *Stream) compileExpressionInfo() {
 	// Initialize unnest function detection flag
@@ -364,6 +437,7 @@ func (s *Stream) compileExpressionInfo() {
 		exprInfo.isFunctionCall = strings.Contains(fieldExpr.Expression, "(") && strings.Contains(fieldExpr.Expression, ")")
 		exprInfo.hasNestedFields = !exprInfo.isFunctionCall && strings.Contains(fieldExpr.Expression, ".")
 		exprInfo.needsBacktickPreprocess = bridge.ContainsBacktickIdentifiers(fieldExpr.Expression)
+		exprInfo.fallbackExpr = coalesceExprFallback(fieldExpr.Expression)
 
 		// Check if expression contains unnest function
 		if exprInfo.isFunctionCall && strings.Contains(strings.ToLower(fieldExpr.Expression), "unnest(") {
@@ -394,13 +468,88 @@ func (s *Stream) compileExpressionInfo() {
 	}
 }
 
-// processExpressionField processes expression field
-func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any, result map[string]any) {
+// coalesceExprFallback returns the fallback argument when expression is a
+// single outermost coalesce_expr(expr, fallback) call, otherwise "".
+func coalesceExprFallback(expression string) string {
+	const name = "coalesce_expr"
+	e := strings.TrimSpace(expression)
+	if len(e) <= len(name) || !strings.EqualFold(e[:len(name)], name) {
+		return ""
+	}
+	rest := strings.TrimSpace(e[len(name):])
+	if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return ""
+	}
+	body := rest[1 : len(rest)-1]
+	depth, comma := 0, -1
+	var quote byte
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			// The opening parenthesis closed early, e.g. coalesce_expr(a, 0) + b
+			if depth--; depth < 0 {
+				return ""
+			}
+		case c == ',' && depth == 0:
+			if comma >= 0 {
+				return ""
+			}
+			comma = i
+		}
+	}
+	if depth != 0 || comma < 0 {
+		return ""
+	}
+	return strings.TrimSpace(body[comma+1:])
+}
+
+// processExpressionField processes expression field, bounded by
+// Config.ExpressionTimeout when set. A timed-out field is NULL and the row is
+// sent to the dead-letter store with the reason. The returned error is the
+// field's evaluation error; the caller applies Config.ProjectionErrorPolicy.
+func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any, result map[string]any) error {
+	if s.config.ExpressionTimeout <= 0 {
+		return s.evaluateExpressionField(fieldName, dataMap, result)
+	}
+	type outcome struct {
+		value any
+		err   error
+	}
+	// 独立的输入副本与结果 map：超时后仍在运行的求值既不读调用方可能复用的
+	// dataMap，也不会写入 result
+	row := copyRow(dataMap)
+	value, err := expr.EvaluateContext(s.evalContext(), s.config.ExpressionTimeout, func() (any, error) {
+		own := make(map[string]any, 1)
+		ferr := s.evaluateExpressionField(fieldName, row, own)
+		return outcome{value: own[fieldName], err: ferr}, nil
+	})
+	if err != nil {
+		if errors.Is(err, expr.ErrEvaluationTimeout) {
+			s.DeadLetter(dataMap, fmt.Sprintf("field %s: %v", fieldName, err))
+		}
+		result[fieldName] = nil
+		return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, err)
+	}
+	out := value.(outcome)
+	result[fieldName] = out.value
+	return out.err
+}
+
+// evaluateExpressionField evaluates an expression field without a time limit
+func (s *Stream) evaluateExpressionField(fieldName string, dataMap map[string]any, result map[string]any) error {
 	exprInfo := s.compiledExprInfo[fieldName]
 	if exprInfo == nil {
 		// Fallback to original logic
-		s.processExpressionFieldFallback(fieldName, dataMap, result)
-		return
+		return s.processExpressionFieldFallback(fieldName, dataMap, result)
 	}
 
 	var evalResult any
@@ -410,9 +559,8 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 		// For function calls, use bridge processor
 		exprResult, err := bridge.EvaluateExpression(exprInfo.processedExpr, dataMap)
 		if err != nil {
-			s.log.Error("Function call evaluation failed for field %s: %v", fieldName, err)
 			result[fieldName] = nil
-			return
+			return fmt.Errorf("function call evaluation failed for field %s: %w", fieldName, err)
 		}
 		evalResult = exprResult
 	} else if exprInfo.hasNestedFields {
@@ -421,9 +569,8 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 			// Use EvaluateValueWithNull to get actual value (including strings)
 			exprResult, isNull, err := exprInfo.compiledExpr.EvaluateValueWithNull(dataMap)
 			if err != nil {
-				s.log.Error("Expression evaluation failed for field %s: %v", fieldName, err)
 				result[fieldName] = nil
-				return
+				return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, err)
 			}
 			if isNull {
 				evalResult = nil
@@ -432,8 +579,7 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 			}
 		} else {
 			// Fallback to dynamic compilation
-			s.processExpressionFieldFallback(fieldName, dataMap, result)
-			return
+			return s.processExpressionFieldFallback(fieldName, dataMap, result)
 		}
 	} else if exprInfo.compiledExprFastPath {
 		// Fast path: pure arithmetic / field-reference expressions (no quotes) go
@@ -449,9 +595,8 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 		} else {
 			exprResult, berr := bridge.EvaluateExpression(exprInfo.processedExpr, dataMap)
 			if berr != nil {
-				s.log.Error("Expression evaluation failed for field %s: %v", fieldName, berr)
 				result[fieldName] = nil
-				return
+				return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, berr)
 			}
 			evalResult = exprResult
 		}
@@ -464,9 +609,8 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 				// Use EvaluateValueWithNull to get actual value (including strings)
 				exprResult, isNull, evalErr := exprInfo.compiledExpr.EvaluateValueWithNull(dataMap)
 				if evalErr != nil {
-					s.log.Error("Expression evaluation failed for field %s: %v", fieldName, evalErr)
 					result[fieldName] = nil
-					return
+					return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, evalErr)
 				}
 				if isNull {
 					evalResult = nil
@@ -475,8 +619,7 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 				}
 			} else {
 				// Fallback to dynamic compilation
-				s.processExpressionFieldFallback(fieldName, dataMap, result)
-				return
+				return s.processExpressionFieldFallback(fieldName, dataMap, result)
 			}
 		} else {
 			evalResult = exprResult
@@ -484,14 +627,15 @@ func (s *Stream) processExpressionField(fieldName string, dataMap map[string]any
 	}
 
 	result[fieldName] = evalResult
+	return nil
 }
 
 // processExpressionFieldFallback fallback logic for expression field processing
-func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[string]any, result map[string]any) {
+func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[string]any, result map[string]any) error {
 	fieldExpr, exists := s.config.FieldExpressions[fieldName]
 	if !exists {
 		result[fieldName] = nil
-		return
+		return nil
 	}
 
 	// Use bridge to calculate expression, supports IS NULL and other syntax
@@ -525,9 +669,8 @@ func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[st
 		// For function calls, prioritize bridge processor
 		exprResult, err := bridge.EvaluateExpression(processedExpr, dataMap)
 		if err != nil {
-			s.log.Error("Function call evaluation failed for field %s: %v", fieldName, err)
 			result[fieldName] = nil
-			return
+			return fmt.Errorf("function call evaluation failed for field %s: %w", fieldName, err)
 		}
 		evalResult = exprResult
 	} else if hasNestedFields {
@@ -540,17 +683,15 @@ func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[st
 		}
 		expression, parseErr := expr.NewExpression(exprToUse)
 		if parseErr != nil {
-			s.log.Error("Expression parse failed for field %s: %v", fieldName, parseErr)
 			result[fieldName] = nil
-			return
+			return fmt.Errorf("expression parse failed for field %s: %w", fieldName, parseErr)
 		}
 
 		// Use EvaluateValueWithNull to get actual value (including strings)
 		exprResult, isNull, err := expression.EvaluateValueWithNull(dataMap)
 		if err != nil {
-			s.log.Error("Expression evaluation failed for field %s: %v", fieldName, err)
 			result[fieldName] = nil
-			return
+			return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, err)
 		}
 		if isNull {
 			evalResult = nil
@@ -570,17 +711,15 @@ func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[st
 			}
 			expression, parseErr := expr.NewExpression(exprToUse)
 			if parseErr != nil {
-				s.log.Error("Expression parse failed for field %s: %v", fieldName, parseErr)
 				result[fieldName] = nil
-				return
+				return fmt.Errorf("expression parse failed for field %s: %w", fieldName, parseErr)
 			}
 
 			// Use EvaluateValueWithNull to get actual value (including strings)
 			exprResult, isNull, evalErr := expression.EvaluateValueWithNull(dataMap)
 			if evalErr != nil {
-				s.log.Error("Expression evaluation failed for field %s: %v", fieldName, evalErr)
 				result[fieldName] = nil
-				return
+				return fmt.Errorf("expression evaluation failed for field %s: %w", fieldName, evalErr)
 			}
 			if isNull {
 				evalResult = nil
@@ -593,15 +732,15 @@ func (s *Stream) processExpressionFieldFallback(fieldName string, dataMap map[st
 	}
 
 	result[fieldName] = evalResult
+	return nil
 }
 
 // processSimpleField processes simple field
-func (s *Stream) processSimpleField(fieldSpec string, dataMap map[string]any, data any, result map[string]any) {
+func (s *Stream) processSimpleField(fieldSpec string, dataMap map[string]any, data any, result map[string]any) error {
 	info := s.compiledFieldInfo[fieldSpec]
 	if info == nil {
 		// If no pre-compiled info, fallback to original logic (safety guarantee)
-		s.processSingleFieldFallback(fieldSpec, dataMap, data, result)
-		return
+		return s.processSingleFieldFallback(fieldSpec, dataMap, data, result)
 	}
 
 	if info.isSelectAll {
@@ -611,24 +750,26 @@ func (s *Stream) processSimpleField(fieldSpec string, dataMap map[string]any, da
 				result[k] = v
 			}
 		}
-		return
+		return nil
 	}
 
 	// Skip fields already processed by expression fields
 	if _, isExpression := s.config.FieldExpressions[info.outputName]; isExpression {
-		return
+		return nil
 	}
 
 	if info.isStringLiteral {
 		// String literal processing: use pre-compiled string value
 		result[info.alias] = info.stringValue
+	} else if info.isLiteral {
+		result[info.alias] = info.literalValue
 	} else if info.isFunctionCall {
 		// Execute function call
 		if funcResult, err := s.executeFunction(info.fieldName, dataMap); err == nil {
 			result[info.outputName] = funcResult
 		} else {
-			s.log.Error("Function execution error %s: %v", info.fieldName, err)
 			result[info.outputName] = nil
+			return fmt.Errorf("function execution error %s: %w", info.fieldName, err)
 		}
 	} else {
 		// Ordinary field processing
@@ -647,10 +788,11 @@ func (s *Stream) processSimpleField(fieldSpec string, dataMap map[string]any, da
 			result[info.outputName] = nil
 		}
 	}
+	return nil
 }
 
 // processSingleFieldFallback fallback processing for single field (when pre-compiled info is missing)
-func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string]any, data any, result map[string]any) {
+func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string]any, data any, result map[string]any) error {
 	// Handle special case of SELECT *
 	if fieldSpec == "*" {
 		// SELECT *: return all fields, but skip fields already processed by expression fields
@@ -660,7 +802,7 @@ func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string
 				result[k] = v
 			}
 		}
-		return
+		return nil
 	}
 
 	// Handle alias
@@ -698,7 +840,7 @@ func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string
 
 	// Skip fields already processed by expression fields
 	if _, isExpression := s.config.FieldExpressions[outputName]; isExpression {
-		return
+		return nil
 	}
 
 	// Check if it's a function call
@@ -707,8 +849,8 @@ func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string
 		if funcResult, err := s.executeFunction(fieldName, dataMap); err == nil {
 			result[outputName] = funcResult
 		} else {
-			s.log.Error("Function execution error %s: %v", fieldName, err)
 			result[outputName] = nil
+			return fmt.Errorf("function execution error %s: %w", fieldName, err)
 		}
 	} else {
 		// Ordinary field - supports nested fields
@@ -727,6 +869,7 @@ func (s *Stream) processSingleFieldFallback(fieldSpec string, dataMap map[string
 			result[outputName] = nil
 		}
 	}
+	return nil
 }
 
 // executeFunction executes function call
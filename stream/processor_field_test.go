@@ -189,6 +189,19 @@ func TestStream_CompileSimpleFieldInfo(t *testing.T) {
 			assert.Equal(t, tt.expectedOutput, info.alias)
 		})
 	}
+
+	// 数值和布尔常量按字面值投影，反引号包裹时仍是字段名
+	for spec, want := range map[string]any{"1:one": int64(1), "2.5:f": 2.5, "TRUE:b": true, "-3:n": int64(-3), "1e3:e": 1000.0, ".5:h": 0.5} {
+		info := stream.compileSimpleFieldInfo(spec)
+		assert.True(t, info.isLiteral, spec)
+		assert.Equal(t, want, info.literalValue, spec)
+	}
+	assert.False(t, stream.compileSimpleFieldInfo("`1`:one").isLiteral)
+	assert.False(t, stream.compileSimpleFieldInfo("temperature").isLiteral)
+	// strconv 接受的非数值字面量（NaN/Inf/Infinity、十六进制、下划线）仍是字段名
+	for _, name := range []string{"NaN", "nan", "Inf", "-Inf", "Infinity", "0x1F", "0x1p-2", "1_000"} {
+		assert.False(t, stream.compileSimpleFieldInfo(name).isLiteral, name)
+	}
 }
 
 // TestStream_CompileExpressionInfo 测试表达式信息编译
@@ -237,6 +250,24 @@ func TestStream_CompileExpressionInfo(t *testing.T) {
 	}
 }
 
+// TestCoalesceExprFallback 只识别作为字段最外层单个调用的 coalesce_expr
+func TestCoalesceExprFallback(t *testing.T) {
+	tests := map[string]string{
+		"coalesce_expr(a * b, 0)":                   "0",
+		"COALESCE_EXPR(upper(name), 'n/a')":         "'n/a'",
+		"coalesce_expr(round(x, 2) , id * 10)":      "id * 10",
+		"coalesce_expr(concat(a, ','), ')')":        "')'",
+		"coalesce_expr(a, 0) + coalesce_expr(b, 0)": "",
+		"coalesce_expr(a)":                          "",
+		"coalesce_expr(a, 0, 1)":                    "",
+		"round(coalesce_expr(a, 0), 2)":             "",
+		"coalesce_exprx(a, 0)":                      "",
+	}
+	for expression, want := range tests {
+		assert.Equal(t, want, coalesceExprFallback(expression), expression)
+	}
+}
+
 // TestFieldProcessInfo_EdgeCases 测试字段处理信息边界情况
 func TestFieldProcessInfo_EdgeCases(t *testing.T) {
 	config := types.Config{
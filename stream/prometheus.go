@@ -0,0 +1,87 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stream
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rulego/streamsql/metrics"
+)
+
+// PrometheusNamespace prefixes every exported metric name.
+const PrometheusNamespace = "streamsql"
+
+// stateGauges are the GetStats keys exported as gauges of the current state.
+var stateGauges = []string{
+	DataChanLen, DataChanCap, ResultChanLen, ResultChanCap,
+	SinkPoolLen, SinkPoolCap, SinkInFlight, ActiveRetries, Expanding,
+}
+
+// CollectPrometheus adds the stream's metrics to e, each sample carrying
+// labels: the registry counters (input/output/dropped totals, cardinality
+// estimates) and gauges reflecting the current state — channel and sink-pool
+// occupancy, capacity and utilization ratio (0..1), in-flight sink batches and
+// window statistics. Counters restart from zero after ResetStats.
+func (s *Stream) CollectPrometheus(e *metrics.Exposition, labels ...metrics.Label) {
+	e.AddRegistry(s.metricsRegistry, labels...)
+
+	stats := s.GetStats()
+	for _, key := range stateGauges {
+		e.Add(key, metrics.PrometheusGauge, "", float64(stats[key]), labels...)
+	}
+	ratio := func(length, capacity int64) float64 {
+		if capacity <= 0 {
+			return 0
+		}
+		return float64(length) / float64(capacity)
+	}
+	e.Add("data_chan_utilization_ratio", metrics.PrometheusGauge, "", ratio(stats[DataChanLen], stats[DataChanCap]), labels...)
+	e.Add("result_chan_utilization_ratio", metrics.PrometheusGauge, "", ratio(stats[ResultChanLen], stats[ResultChanCap]), labels...)
+	e.Add("sink_pool_utilization_ratio", metrics.PrometheusGauge, "", ratio(stats[SinkPoolLen], stats[SinkPoolCap]), labels...)
+
+	if s.Window != nil {
+		winStats := s.Window.GetStats()
+		keys := make([]string, 0, len(winStats))
+		for k := range winStats {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// Window "...Count" statistics only grow (until ResetStats).
+			typ := metrics.PrometheusGauge
+			if strings.HasSuffix(k, "Count") {
+				typ = metrics.PrometheusCounter
+			}
+			e.Add("window_"+k, typ, "", float64(winStats[k]), labels...)
+		}
+	}
+}
+
+// PrometheusHandler serves the stream's metrics in the Prometheus text format,
+// together with process memory and goroutine gauges; mount it on an existing
+// /metrics endpoint.
+//
+// Example:
+//
+//	http.Handle("/metrics", s.PrometheusHandler())
+func (s *Stream) PrometheusHandler() http.Handler {
+	return metrics.PrometheusHandler(PrometheusNamespace, func(e *metrics.Exposition) {
+		s.CollectPrometheus(e)
+	})
+}
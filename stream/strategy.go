@@ -53,6 +53,13 @@ type DataProcessingStrategy interface {
 	Stop() error
 }
 
+// offeringStrategy is implemented by the built-in strategies. offer enqueues
+// data exactly like ProcessData but reports whether it was accepted instead of
+// dropping it, so EmitBatch can hand the rejected rows back to the caller.
+type offeringStrategy interface {
+	offer(data map[string]any) bool
+}
+
 // BlockingStrategy blocking strategy implementation
 type BlockingStrategy struct {
 	stream *Stream
@@ -68,31 +75,42 @@ func NewBlockingStrategy() *BlockingStrategy {
 // blockingTimeout > 0: block up to the timeout, then drop so a slow consumer
 // cannot hang the producer — bounded block is the explicit contract.
 func (bs *BlockingStrategy) ProcessData(data map[string]any) {
+	if !bs.offer(data) && atomic.LoadInt32(&bs.stream.stopped) == 0 {
+		bs.stream.log.Warn("Data channel still full after %s, dropping input data", bs.stream.blockingTimeout)
+		bs.stream.recordInputDrop(1)
+	}
+}
+
+// offer blocks until data is enqueued, the stream stops or, when
+// blockingTimeout > 0, the timeout elapses.
+func (bs *BlockingStrategy) offer(data map[string]any) bool {
 	if atomic.LoadInt32(&bs.stream.stopped) == 1 {
-		return
+		return false
 	}
 
 	dataChan := bs.stream.safeGetDataChan()
 	if dataChan == nil {
-		return
+		return false
 	}
 
 	if bs.stream.blockingTimeout <= 0 {
 		select {
 		case dataChan <- data:
+			return true
 		case <-bs.stream.done:
+			return false
 		}
-		return
 	}
 
 	timer := time.NewTimer(bs.stream.blockingTimeout)
 	defer timer.Stop()
 	select {
 	case dataChan <- data:
+		return true
 	case <-timer.C:
-		bs.stream.log.Warn("Data channel still full after %s, dropping input data", bs.stream.blockingTimeout)
-		bs.stream.mInputDropped.Inc()
+		return false
 	case <-bs.stream.done:
+		return false
 	}
 }
 
@@ -127,18 +145,26 @@ func NewExpansionStrategy() *ExpansionStrategy {
 // never blocks on a cached channel reference — under concurrent expansion such
 // a reference can be swapped out and strand the send (and hang the caller).
 func (es *ExpansionStrategy) ProcessData(data map[string]any) {
+	if !es.offer(data) && atomic.LoadInt32(&es.stream.stopped) == 0 {
+		es.stream.log.Warn("Data channel still full after expansion, dropping input data")
+		es.stream.recordInputDrop(1)
+	}
+}
+
+// offer sends data, expanding the channel once and retrying briefly when full.
+func (es *ExpansionStrategy) offer(data map[string]any) bool {
 	if atomic.LoadInt32(&es.stream.stopped) == 1 {
-		return
+		return false
 	}
 	if es.stream.safeSendToDataChan(data) {
-		return
+		return true
 	}
 
 	// Full: grow capacity once, then retry.
 	es.stream.expandDataChannel()
 	if es.stream.safeSendToDataChan(data) {
 		es.stream.log.Debug("Successfully added data after data channel expansion")
-		return
+		return true
 	}
 
 	// Still full: a few short retries give the consumer a chance to drain.
@@ -148,15 +174,13 @@ func (es *ExpansionStrategy) ProcessData(data map[string]any) {
 		case <-timer.C:
 		case <-es.stream.done:
 			timer.Stop()
-			return
+			return false
 		}
 		if es.stream.safeSendToDataChan(data) {
-			return
+			return true
 		}
 	}
-
-	es.stream.log.Warn("Data channel still full after expansion, dropping input data")
-	es.stream.mInputDropped.Inc()
+	return false
 }
 
 // GetStrategyName gets strategy name
@@ -188,13 +212,21 @@ func NewDropStrategy() *DropStrategy {
 // ProcessData implements drop mode: non-blocking send, a few short retries to
 // absorb micro-bursts, then drop.
 func (ds *DropStrategy) ProcessData(data map[string]any) {
+	if !ds.offer(data) && atomic.LoadInt32(&ds.stream.stopped) == 0 {
+		ds.stream.log.Warn("Data channel is full, dropping input data")
+		ds.stream.recordInputDrop(1)
+	}
+}
+
+// offer sends data without blocking beyond a few short retries.
+func (ds *DropStrategy) offer(data map[string]any) bool {
 	if ds.stream.safeSendToDataChan(data) {
-		return
+		return true
 	}
 
 	dataChan := ds.stream.safeGetDataChan()
 	if dataChan == nil {
-		return
+		return false
 	}
 
 	// Channel full: a few short retries give the consumer a chance to drain.
@@ -203,16 +235,14 @@ func (ds *DropStrategy) ProcessData(data map[string]any) {
 		select {
 		case dataChan <- data:
 			timer.Stop()
-			return
+			return true
 		case <-timer.C:
 		case <-ds.stream.done:
 			timer.Stop()
-			return
+			return false
 		}
 	}
-
-	ds.stream.log.Warn("Data channel is full, dropping input data")
-	ds.stream.mInputDropped.Inc()
+	return false
 }
 
 // GetStrategyName gets strategy name
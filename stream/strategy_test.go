@@ -333,6 +333,39 @@ func TestCustomStrategy(t *testing.T) {
 	}
 }
 
+// TestCustomStrategyEmitMany 测试 EmitMany 与 Emit 一样经由自定义策略处理每条数据
+func TestCustomStrategyEmitMany(t *testing.T) {
+	mockStrategy := NewMockStrategy()
+	reg := metrics.NewRegistry()
+	stream := &Stream{
+		dataChan:        make(chan map[string]any, 10),
+		batchChan:       make(chan []map[string]any, 10),
+		done:            make(chan struct{}),
+		metricsRegistry: reg,
+		mInput:          reg.Counter(InputCount),
+		mOutput:         reg.Counter(OutputCount),
+		mInputDropped:   reg.Counter(InputDroppedCount),
+		mOutputDropped:  reg.Counter(OutputDroppedCount),
+	}
+	if err := mockStrategy.Init(stream, types.PerformanceConfig{}); err != nil {
+		t.Fatalf("Failed to init mock strategy: %v", err)
+	}
+	stream.dataStrategy = mockStrategy
+
+	stream.EmitMany([]map[string]any{{"id": 1}, {"id": 2}, {"id": 3}})
+
+	if mockStrategy.processed != 3 {
+		t.Errorf("Expected processed count 3, got %d", mockStrategy.processed)
+	}
+	if n := len(stream.batchChan); n != 0 {
+		t.Errorf("Expected batch channel to be bypassed, got %d queued batches", n)
+	}
+	if n := stream.mInput.Value(); n != 3 {
+		t.Errorf("Expected input count 3, got %d", n)
+	}
+	stream.Stop()
+}
+
 // TestStrategyRegistration 测试策略注册机制
 func TestStrategyRegistration(t *testing.T) {
 	factory := NewStrategyFactory()
@@ -410,3 +443,128 @@ func (ks *KafkaStrategy) Stop() error {
 	// 这里可以关闭Kafka连接等
 	return nil
 }
+
+// TestStream_OnBackpressure 测试背压回调：越过高水位、丢弃（限频累计）与回落到低水位
+func TestStream_OnBackpressure(t *testing.T) {
+	config := types.Config{
+		SimpleFields: []string{"id"},
+		PerformanceConfig: types.PerformanceConfig{
+			BufferConfig: types.BufferConfig{
+				DataChannelSize:   10,
+				ResultChannelSize: 100,
+				UsageThreshold:    0.5,
+			},
+			OverflowConfig: types.OverflowConfig{Strategy: StrategyDrop, AllowDataLoss: true},
+			WorkerConfig: types.WorkerConfig{
+				SinkPoolSize:     5,
+				SinkWorkerCount:  2,
+				MaxRetryRoutines: 3,
+			},
+		},
+	}
+	s, err := NewStream(config)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	events := make(chan BackpressureEvent, 16)
+	s.OnBackpressure(func(e BackpressureEvent) { events <- e })
+	next := func() BackpressureEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("no backpressure event")
+			return BackpressureEvent{}
+		}
+	}
+
+	// 未启动，无人消费：第 6 条入队前利用率达到 0.5
+	for i := 0; i < 5; i++ {
+		s.Emit(map[string]any{"id": i})
+	}
+	require.Empty(t, events)
+	s.Emit(map[string]any{"id": 5})
+	e := next()
+	require.Equal(t, BackpressureHighWater, e.Kind)
+	require.Equal(t, StrategyDrop, e.Strategy)
+	require.InDelta(t, 0.5, e.Utilization, 1e-9)
+
+	// 缓冲区满后丢弃：首次丢弃立即上报，其后限频期内的丢弃累计到下一次
+	for i := 6; i < 13; i++ {
+		s.Emit(map[string]any{"id": i})
+	}
+	e = next()
+	require.Equal(t, BackpressureDrop, e.Kind)
+	require.EqualValues(t, 1, e.Dropped)
+	require.InDelta(t, 1.0, e.Utilization, 1e-9)
+	require.Empty(t, events, "drops within the interval are rate-limited")
+
+	s.backpressureMonitor().mu.Lock()
+	s.backpressureMonitor().lastDrop = time.Time{}
+	s.backpressureMonitor().mu.Unlock()
+	s.Emit(map[string]any{"id": 13})
+	e = next()
+	require.Equal(t, BackpressureDrop, e.Kind)
+	require.EqualValues(t, 3, e.Dropped, "carries the suppressed drops")
+	require.EqualValues(t, 4, e.TotalDropped)
+	require.EqualValues(t, 4, s.mInputDropped.Value())
+
+	// 开始消费后利用率回落到低水位（0.25）
+	s.Start()
+	e = next()
+	require.Equal(t, BackpressureRecovered, e.Kind)
+	require.LessOrEqual(t, e.Utilization, 0.25)
+	require.Empty(t, events)
+}
+
+// TestStream_OnBackpressureTrailingDrop 限频期内被抑制的丢弃在区间结束时补报，即使之后不再丢弃
+func TestStream_OnBackpressureTrailingDrop(t *testing.T) {
+	config := types.Config{
+		SimpleFields: []string{"id"},
+		PerformanceConfig: types.PerformanceConfig{
+			BufferConfig: types.BufferConfig{
+				DataChannelSize:   2,
+				ResultChannelSize: 100,
+			},
+			OverflowConfig: types.OverflowConfig{Strategy: StrategyDrop, AllowDataLoss: true},
+			WorkerConfig: types.WorkerConfig{
+				SinkPoolSize:     5,
+				SinkWorkerCount:  2,
+				MaxRetryRoutines: 3,
+			},
+		},
+	}
+	s, err := NewStream(config)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	events := make(chan BackpressureEvent, 16)
+	s.OnBackpressure(func(e BackpressureEvent) {
+		if e.Kind == BackpressureDrop {
+			events <- e
+		}
+	})
+	s.backpressureMonitor().mu.Lock()
+	s.backpressureMonitor().dropInterval = 100 * time.Millisecond
+	s.backpressureMonitor().mu.Unlock()
+
+	// 未启动，缓冲 2 条后开始丢弃：首次丢弃立即上报，其余 4 条被限频
+	for i := 0; i < 7; i++ {
+		s.Emit(map[string]any{"id": i})
+	}
+	e := <-events
+	require.EqualValues(t, 1, e.Dropped)
+
+	select {
+	case e = <-events:
+		require.EqualValues(t, 4, e.Dropped, "suppressed drops are flushed at the end of the interval")
+		require.EqualValues(t, 5, e.TotalDropped)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no trailing drop event")
+	}
+	select {
+	case e = <-events:
+		t.Fatalf("unexpected drop event %+v", e)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
@@ -17,6 +17,8 @@
 package stream
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -25,6 +27,7 @@ import (
 
 	"github.com/rulego/streamsql/aggregator"
 	"github.com/rulego/streamsql/condition"
+	"github.com/rulego/streamsql/expr"
 	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/logger"
 	"github.com/rulego/streamsql/metrics"
@@ -34,8 +37,14 @@ import (
 
 // Window related constants
 const (
-	WindowStartField = "window_start"
-	WindowEndField   = "window_end"
+	WindowStartField     = "window_start"
+	WindowEndField       = "window_end"
+	WindowWatermarkField = "window_watermark"
+	WindowFillRatioField = "window_fill_ratio"
+	WindowStartISOField  = "window_start_iso"
+	WindowEndISOField    = "window_end_iso"
+	// WindowIDField is the stable "<start>_<end>" (UnixNano) id stamped on window results
+	WindowIDField = "window_id"
 )
 
 // Performance level constants
@@ -57,17 +66,45 @@ const (
 
 type Stream struct {
 	dataChan       chan map[string]any
+	batchChan      chan []map[string]any // EmitMany batches; one channel op per batch, guarded by dataChanMux
 	filter         condition.Condition
 	Window         window.Window
 	aggregator     aggregator.Aggregator
 	tables         *tableStore
+	intervalJoin   *intervalJoin // stream self-join operator; nil without a self-join
+	poolJoinRows   bool          // recycle JOIN working rows once their window is aggregated (see joinRowPooling)
 	config         types.Config
 	sinks          []func([]map[string]any)
-	syncSinks      []func([]map[string]any) // Synchronous sinks, executed sequentially
-	resultChan     chan []map[string]any    // Result channel
+	syncSinks      []func([]map[string]any)            // Synchronous sinks, executed sequentially
+	namedSinks     map[string][]func([]map[string]any) // AddNamedSink; only config.InsertInto's entry is invoked
+	errorSinks     []func(map[string]any, error)       // AddErrorSink; fed by errorQueue
+	errorQueue     chan recordError                    // Created by the first AddErrorSink
+	resultChan     chan []map[string]any               // Result channel
 	seenResults    *sync.Map
-	done           chan struct{} // Used to close processing goroutines
-	sinkWorkerPool chan func()   // Sink worker pool to avoid blocking
+	done           chan struct{}   // Used to close processing goroutines
+	evalCtx        context.Context // bounds Config.ExpressionTimeout evaluations; canceled by Stop
+	cancelEval     context.CancelFunc
+	sinkWorkerPool chan func() // Sink worker pool to avoid blocking
+	// sinkInFlight holds one token per result batch dispatched to the async
+	// sinks and not yet finished by all of them (PerformanceConfig.MaxInFlightBatches);
+	// nil when unbounded.
+	sinkInFlight chan struct{}
+
+	// CloseInput barriers, served by the data processor and window-output goroutines
+	flushChan       chan chan struct{}
+	windowFlushChan chan chan struct{}
+	// HandOver barrier: drains queued input without flushing open windows
+	drainChan chan chan struct{}
+	// pauseWake wakes the data processor after Pause/Resume (see pause.go)
+	pauseWake chan struct{}
+
+	// Set by HandOver on the retiring stream: the table sources (and, with
+	// windowHandedOff, the window and dead-letter store) now belong to the
+	// replacement stream, so Stop must not close them. windowAdopted marks the
+	// replacement whose window is already running.
+	handedOff       bool
+	windowHandedOff bool
+	windowAdopted   bool
 
 	// Thread safety control
 	dataChanMux      sync.RWMutex  // Read-write lock protecting dataChan access
@@ -78,6 +115,9 @@ type Stream struct {
 	activeRetries    int32         // Active retry count using atomic operations
 	maxRetryRoutines int32         // Maximum retry goroutine limit
 	stopped          int32         // Stop status flag using atomic operations
+	inputClosed      int32         // Set by CloseInput; Emit/EmitMany drop afterwards
+	paused           int32         // Set by Pause; the data processor stops consuming input
+	pendingSinkTasks int64         // Async sink tasks submitted but not yet finished
 	startMu          sync.Mutex    // serializes Start's stopped-check+Add with Stop's flag set
 	log              logger.Logger // per-instance logger; set at construction, immutable after
 
@@ -87,11 +127,28 @@ type Stream struct {
 	lifecycle sync.WaitGroup
 
 	// Performance monitoring metrics (consolidated in metrics.Registry)
-	metricsRegistry *metrics.Registry
-	mInput          *metrics.Counter
-	mOutput         *metrics.Counter
-	mInputDropped   *metrics.Counter
-	mOutputDropped  *metrics.Counter
+	metricsRegistry   *metrics.Registry
+	mInput            *metrics.Counter
+	mOutput           *metrics.Counter
+	mInputDropped     *metrics.Counter
+	mOutputDropped    *metrics.Counter
+	mSinkDropped      *metrics.Counter
+	mErrorSinkDropped *metrics.Counter
+	mAggBufDropped    *metrics.Counter
+
+	// cardinality holds the []cardinalityTracker of TrackCardinality fields
+	// (copy-on-write, read lock-free on ingest); cardinalityMu serializes writers.
+	cardinality   atomic.Value
+	cardinalityMu sync.Mutex
+
+	// backpressure holds the *backpressureMonitor of OnBackpressure (nil until
+	// the first callback, read lock-free on ingest); backpressureMu serializes writers.
+	backpressure   atomic.Value
+	backpressureMu sync.Mutex
+
+	// syncDirectRows counts rows returned by ProcessSync and ProcessSyncRows,
+	// for OFFSET/LIMIT (atomic; EmitSync callers may run concurrently)
+	syncDirectRows int64
 
 	// Log throttling fields for "Result channel is full" messages
 	lastDropLogTime int64 // Last time drop log was printed (unix timestamp)
@@ -108,6 +165,7 @@ type Stream struct {
 	// Pre-compiled field processing information to avoid repeated parsing
 	compiledFieldInfo map[string]*fieldProcessInfo      // Field processing information cache
 	compiledExprInfo  map[string]*expressionProcessInfo // Expression processing information cache
+	groupKeyCases     map[string]*expr.Expression       // Compiled CASE expression GROUP BY keys
 
 	// groupOutputNames holds the OUTPUT column name for each GROUP BY field
 	// (parallel to config.GroupFields): the SELECT AS alias if present, else the
@@ -115,6 +173,30 @@ type Stream struct {
 	// group key (needed to resolve values); this maps it to the output name.
 	groupOutputNames []string
 
+	// metricColumns 为 OutputShape=long 时展开为 metric/value 行的聚合输出列（SELECT 顺序）。
+	metricColumns []string
+
+	// deadLetter 持久化被拒绝的记录（Config.DeadLetterDir 非空时创建），Stop 时关闭。
+	deadLetter *deadLetterStore
+
+	// windowHistory 保留最近 N 个窗口的输出供 ReplayLastWindows 重放（Config.WindowHistorySize>0 时创建）。
+	windowHistory *windowHistory
+
+	// transformBuf 合并非聚合结果行按时限批量派发（Config.TransformFlushInterval>0 时创建）。
+	transformBuf *transformBuffer
+	// latest 保存非窗口 GROUP BY 每个分组的最新结果行并按时限输出快照
+	// （Config.StateFlushInterval>0 时创建）。
+	latest *latestState
+
+	// compactor 合并聚合值不变的相邻窗口结果（Config.CompactUnchangedWindows 时创建）。
+	compactor *windowCompactor
+
+	// primaryKeys 记录已输出的主键以标记 upsert（Config.PrimaryKey 非空时创建）。
+	primaryKeys *primaryKeyTracker
+
+	// location 为 Config.TimeZone 对应的时区，window_start_iso()/window_end_iso() 按它格式化。
+	location *time.Location
+
 	// Unnest function optimization flags
 	// hasUnnestFunction 标识查询是否使用了 unnest 函数，在预处理阶段确定
 	// 用于优化 expandUnnestResults 函数的性能，避免不必要的字段遍历检查
@@ -193,6 +275,20 @@ func (s *Stream) preprocessFilterCondition(conditionStr string) string {
 		}
 	}
 
+	// Preprocess BETWEEN / NOT BETWEEN syntax
+	if bridge.ContainsBetweenOperator(processedCondition) {
+		if processed, err := bridge.PreprocessBetweenExpression(processedCondition); err == nil {
+			processedCondition = processed
+		}
+	}
+
+	// Preprocess IN / NOT IN (list) syntax
+	if bridge.ContainsInOperator(processedCondition) {
+		if processed, err := bridge.PreprocessInExpression(processedCondition); err == nil {
+			processedCondition = processed
+		}
+	}
+
 	return processedCondition
 }
 
@@ -251,11 +347,90 @@ func (s *Stream) Start() {
 // Parameters:
 //   - data: data to be processed, must be map[string]any type
 func (s *Stream) Emit(data map[string]any) {
+	if atomic.LoadInt32(&s.inputClosed) != 0 {
+		s.mInputDropped.Inc()
+		return
+	}
 	s.mInput.Inc()
+	s.observeCardinality(data)
+	s.checkBackpressure()
 	// Use strategy pattern to process data, providing better extensibility
 	s.dataStrategy.ProcessData(data)
 }
 
+// EmitMany adds a batch of data to the stream processing pipeline in one
+// channel operation, reducing per-record synchronization for bursty producers.
+// Records of a batch are processed in order, each exactly as if passed to Emit
+// (same JOIN/WHERE/window path, processing-time windows stamp arrival per
+// record). Ordering relative to concurrent Emit calls is not guaranteed.
+//
+// The overflow strategy applies to the batch as a whole: "block" waits for
+// space (bounded by BlockTimeout when > 0), "drop"/"expand" retry briefly and
+// then drop the entire batch, counting every record as an input drop. A custom
+// DataProcessingStrategy gets every record through ProcessData, as with Emit.
+func (s *Stream) EmitMany(data []map[string]any) {
+	if len(data) == 0 {
+		return
+	}
+	if atomic.LoadInt32(&s.inputClosed) != 0 {
+		s.mInputDropped.IncBy(int64(len(data)))
+		return
+	}
+	s.mInput.IncBy(int64(len(data)))
+	for _, row := range data {
+		s.observeCardinality(row)
+	}
+	s.checkBackpressure()
+	if _, builtin := s.dataStrategy.(offeringStrategy); !builtin {
+		// Custom strategy: it decides on each record, exactly as for Emit
+		for _, row := range data {
+			s.dataStrategy.ProcessData(row)
+		}
+		return
+	}
+	if !s.sendBatchToChan(data) {
+		if atomic.LoadInt32(&s.stopped) != 0 {
+			s.mInputDropped.IncBy(int64(len(data)))
+		} else {
+			s.recordInputDrop(int64(len(data)))
+		}
+	}
+}
+
+// EmitBatch enqueues records one by one, in order, through the same path as
+// Emit, and reports how many were accepted. Under the "block" overflow strategy
+// it waits for space for each record (up to BlockTimeout when > 0), so a batch
+// larger than the buffer is paced by the consumer instead of being dropped.
+// Under the other strategies, or when the block times out, it stops at the
+// first record that does not fit: rows[accepted:] were not enqueued (nor
+// counted as dropped) and an error is returned, so the caller can retry them.
+func (s *Stream) EmitBatch(rows []map[string]any) (accepted int, err error) {
+	offerer, _ := s.dataStrategy.(offeringStrategy)
+	for i, row := range rows {
+		if atomic.LoadInt32(&s.inputClosed) != 0 {
+			return i, fmt.Errorf("stream input closed")
+		}
+		if atomic.LoadInt32(&s.stopped) != 0 {
+			return i, fmt.Errorf("stream stopped")
+		}
+		s.checkBackpressure()
+		if offerer == nil {
+			// Custom strategy: it decides on its own, count the row as accepted
+			s.Emit(row)
+			continue
+		}
+		if !offerer.offer(row) {
+			if atomic.LoadInt32(&s.stopped) != 0 {
+				return i, fmt.Errorf("stream stopped")
+			}
+			return i, fmt.Errorf("input buffer full: %d of %d records accepted", i, len(rows))
+		}
+		s.mInput.Inc()
+		s.observeCardinality(row)
+	}
+	return len(rows), nil
+}
+
 // Stop stops stream processing
 func (s *Stream) Stop() {
 	// Set the stopped flag under startMu so a concurrent Start observes it before
@@ -268,15 +443,19 @@ func (s *Stream) Stop() {
 	s.startMu.Unlock()
 
 	close(s.done)
+	if s.cancelEval != nil {
+		s.cancelEval()
+	}
 
 	// Stop window operations first to prevent new window triggers
-	if s.Window != nil {
+	if s.Window != nil && !s.windowHandedOff {
 		s.Window.Stop()
 	}
 
 	// Do not close dataChan: a close races with in-flight producers. Nil makes them stop.
 	s.dataChanMux.Lock()
 	s.dataChan = nil
+	s.batchChan = nil
 	s.dataChanMux.Unlock()
 
 	// Stop and clean up data processing strategy resources
@@ -293,6 +472,13 @@ func (s *Stream) Stop() {
 	// (e.g. a rulego component Destroy).
 	s.waitLifecycle()
 
+	// 所有 goroutine 已 join，不会再有死信写入（窗口移交时死信存储随窗口转给新流）
+	if s.deadLetter != nil && !s.windowHandedOff {
+		if err := s.deadLetter.close(); err != nil {
+			s.log.Error("Failed to close dead-letter store: %v", err)
+		}
+	}
+
 	// 停止 CEP sweeper：数据处理 goroutine 已 join，不再有并发 Process；紧接的 Flush 看到静止引擎。
 	if s.cep != nil {
 		s.cep.Stop()
@@ -305,12 +491,116 @@ func (s *Stream) Stop() {
 		s.emitCepFlushSync(s.projectCep(s.cep.engine.Flush()))
 	}
 
+	// 同理同步派发尚在缓冲中的非聚合结果行（TransformFlushInterval）。
+	s.flushTransformBufferSync()
+
+	// 以及非窗口 GROUP BY 的最后一次快照（StateFlushInterval）。
+	s.latest.stop()
+	s.flushLatestSync()
+
+	// 以及 CompactUnchangedWindows 暂存的窗口结果。
+	s.flushCompactedSync()
+
 	// Release table sources (custom sources may own background refresh goroutines).
-	if s.tables != nil {
+	if s.tables != nil && !s.handedOff {
 		s.tables.closeAll()
 	}
 }
 
+// CloseInput signals end of input for bounded/batch streams: later Emit and
+// EmitMany calls are dropped, records already queued are processed, every open
+// window is flushed (time windows fire early, count windows emit partial
+// groups, sessions close, pending MATCH_RECOGNIZE matches are emitted) and
+// CloseInput returns once the results have reached the sinks. Unlike Stop, the
+// stream is not torn down: results stay readable and Stop must still be called.
+//
+// grace bounds the wait (≤0 uses the Stop grace period); an error is returned
+// if the pipeline did not drain in time, e.g. because a sink is blocked.
+func (s *Stream) CloseInput(grace time.Duration) error {
+	return s.closeInput(grace, true)
+}
+
+// closeInput stops accepting input and waits until queued records have been
+// processed and their results delivered. flush additionally fires every open
+// window; without it open windows keep their rows (HandOver).
+func (s *Stream) closeInput(grace time.Duration, flush bool) error {
+	if grace <= 0 {
+		grace = defaultStopGrace
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	err := s.closeInputContext(ctx, flush)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w (grace %s)", err, grace)
+	}
+	return err
+}
+
+// closeInputContext is closeInput bounded by ctx; when ctx ends first the
+// returned error wraps ctx.Err().
+func (s *Stream) closeInputContext(ctx context.Context, flush bool) error {
+	atomic.StoreInt32(&s.inputClosed, 1)
+	if atomic.LoadInt32(&s.stopped) != 0 {
+		return fmt.Errorf("stream already stopped")
+	}
+
+	barrier := func(ch chan chan struct{}) error {
+		ack := make(chan struct{})
+		select {
+		case ch <- ack:
+		case <-s.done:
+			return fmt.Errorf("stream stopped during CloseInput")
+		case <-ctx.Done():
+			return fmt.Errorf("CloseInput: pipeline did not drain: %w", ctx.Err())
+		}
+		select {
+		case <-ack:
+			return nil
+		case <-s.done:
+			return fmt.Errorf("stream stopped during CloseInput")
+		case <-ctx.Done():
+			return fmt.Errorf("CloseInput: pipeline did not drain: %w", ctx.Err())
+		}
+	}
+
+	// 1. Data processor: drain queued input, then flush windows / CEP.
+	if !flush {
+		if err := barrier(s.drainChan); err != nil {
+			return err
+		}
+	} else if err := barrier(s.flushChan); err != nil {
+		return err
+	}
+	// 2. Window-output goroutine: aggregate the flushed windows and dispatch them.
+	if flush && s.config.NeedWindow {
+		if err := barrier(s.windowFlushChan); err != nil {
+			return err
+		}
+	}
+	// 3. Async sinks: wait for tasks already handed to the worker pool.
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&s.pendingSinkTasks) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("CloseInput: sinks did not finish: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Drain shuts the stream down gracefully: like CloseInput it stops accepting
+// input, processes the queued records, fires every open window one last time
+// and waits for the sinks to finish, then it stops the stream. ctx bounds the
+// wait; when it ends first the stream is stopped anyway and the returned error
+// wraps ctx.Err(), so the caller can tell the final flush is incomplete.
+func (s *Stream) Drain(ctx context.Context) error {
+	err := s.closeInputContext(ctx, true)
+	s.Stop()
+	return err
+}
+
 // RegisterTableSource registers a custom table source for stream-table JOIN.
 // The source's Init runs here (it may load data from a file/DB/Redis).
 func (s *Stream) RegisterTableSource(src TableSource) error {
@@ -400,7 +690,12 @@ func (s *Stream) IsCEPQuery() bool {
 func (s *Stream) projectCep(raw []map[string]any) []map[string]any {
 	out := make([]map[string]any, 0, len(raw))
 	for _, mrRow := range raw {
-		if r, emit := s.projectDirectRow(mrRow, nil); emit {
+		r, emit, err := s.projectDirectRow(mrRow, nil)
+		if err != nil {
+			s.log.Error("projection error: %v", err)
+			continue
+		}
+		if emit {
 			out = append(out, r)
 		}
 	}
@@ -431,8 +726,9 @@ func (s *Stream) emitCepFlushSync(results []map[string]any) {
 // 供 Stop-Flush 等 worker pool 已退出场景复用。
 func (s *Stream) invokeSinksInline(results []map[string]any) {
 	s.sinksMux.RLock()
-	sinks := make([]func([]map[string]any), len(s.sinks))
-	copy(sinks, s.sinks)
+	target := s.targetSinks()
+	sinks := make([]func([]map[string]any), len(target))
+	copy(sinks, target)
 	syncSinks := make([]func([]map[string]any), len(s.syncSinks))
 	copy(syncSinks, s.syncSinks)
 	s.sinksMux.RUnlock()
@@ -477,7 +773,12 @@ func (s *Stream) ensureAnalytic() {
 			return
 		}
 		all := make([]types.AnalyticField, 0, len(s.config.AnalyticFields)+len(s.config.WhereAnalyticCalls))
-		all = append(all, s.config.AnalyticFields...)
+		for _, af := range s.config.AnalyticFields {
+			// 整分区函数（ntile）不走逐条状态机，由 applyPartitionAnalytic 按窗口结果行求值。
+			if partitionAnalytic(af) == nil {
+				all = append(all, af)
+			}
+		}
 		for _, wc := range s.config.WhereAnalyticCalls {
 			all = append(all, types.AnalyticField{
 				Alias:      wc.Placeholder,
@@ -620,9 +921,14 @@ func (s *Stream) applyWindowAnalytic(row map[string]any) bool {
 //   - data: data to be processed, must be map[string]any type
 //
 // Returns:
-//   - map[string]any: processed result data, returns nil if doesn't match filter condition
-//   - error: processing error, returns error for aggregation queries
+//   - map[string]any: processed result data, returns nil if doesn't match filter
+//     condition or falls outside OFFSET/LIMIT (counted with ProcessSyncRows)
+//   - error: processing error, returns error for aggregation queries and
+//     ErrPaused while the stream is paused
 func (s *Stream) ProcessSync(data map[string]any) (map[string]any, error) {
+	if s.IsPaused() {
+		return nil, ErrPaused
+	}
 	// 同步单事件返回仅适用于直连路径：窗口聚合与 CEP 模式匹配都跨多事件，无法单事件返回。
 	// 窗口判定沿用 NeedWindow（兼容直接构造 config 的用例），CEP 判定用 Mode。
 	if s.config.NeedWindow {
@@ -631,12 +937,91 @@ func (s *Stream) ProcessSync(data map[string]any) (map[string]any, error) {
 	if s.config.Mode == types.ExecCEP {
 		return nil, fmt.Errorf("Synchronous processing is not supported for MATCH_RECOGNIZE queries.")
 	}
+	if s.intervalJoin != nil {
+		return nil, fmt.Errorf("Synchronous processing is not supported for stream self-join queries.")
+	}
+	if s.latest != nil {
+		return nil, fmt.Errorf("Synchronous processing is not supported with a state flush interval.")
+	}
+
+	s.observeCardinality(data)
 
 	// Directly process data and return result. processDirectDataSync applies the
 	// filter after JOIN enrichment so WHERE can reference joined columns.
 	return s.processDirectDataSync(data)
 }
 
+// ProcessSyncRows synchronously processes one record like ProcessSync, but
+// returns every row the record produces: an unnest() field expands into one
+// row per element, the rows are sorted by ORDER BY, and OFFSET/LIMIT count the
+// rows returned by ProcessSync and ProcessSyncRows so far. It returns nil when WHERE, an INNER
+// JOIN miss or OFFSET/LIMIT removes the record. Errors of the JOIN lookup, the
+// WHERE condition and the projection are returned instead of only being logged
+// (per-field projection errors still follow ProjectionErrorPolicy). Returned
+// rows are also delivered to the sinks.
+func (s *Stream) ProcessSyncRows(data map[string]any) ([]map[string]any, error) {
+	if s.IsPaused() {
+		return nil, ErrPaused
+	}
+	if s.config.NeedWindow {
+		return nil, fmt.Errorf("Synchronous processing is not supported for aggregation queries.")
+	}
+	if s.config.Mode == types.ExecCEP {
+		return nil, fmt.Errorf("Synchronous processing is not supported for MATCH_RECOGNIZE queries.")
+	}
+	if s.intervalJoin != nil {
+		return nil, fmt.Errorf("Synchronous processing is not supported for stream self-join queries.")
+	}
+	if s.latest != nil {
+		return nil, fmt.Errorf("Synchronous processing is not supported with a state flush interval.")
+	}
+
+	s.observeCardinality(data)
+
+	dataMap, keep, err := s.enrichData(data)
+	if err != nil {
+		s.reportRecordError(data, err)
+		return nil, err
+	}
+	if !keep {
+		return nil, nil
+	}
+	analyticResults, pass, err := s.applyWhereAndAnalytic(dataMap)
+	if !pass {
+		return nil, err
+	}
+	result, emit, err := s.projectDirectRow(dataMap, analyticResults)
+	if err != nil || !emit {
+		return nil, err
+	}
+	results := (&DataProcessor{stream: s}).expandUnnestResults(result, dataMap)
+	s.applyOrderBy(results)
+	if results = s.syncOffsetLimit(results); len(results) == 0 {
+		return nil, nil
+	}
+	s.tagPrimaryKey(results)
+	s.mOutput.IncBy(int64(len(results)))
+	s.callSinksAsync(results)
+	return results, nil
+}
+
+// syncOffsetLimit is applyDirectOffsetLimit for ProcessSync and ProcessSyncRows,
+// whose row counter is shared by concurrent callers.
+func (s *Stream) syncOffsetLimit(results []map[string]any) []map[string]any {
+	offset, limit := int64(s.config.Offset), int64(s.config.Limit)
+	if offset <= 0 && limit <= 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, r := range results {
+		idx := atomic.AddInt64(&s.syncDirectRows, 1) - 1
+		if idx >= offset && (limit <= 0 || idx < offset+limit) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
 // enrichData 解析流-表 JOIN 富化。返回富化后的 dataMap、是否保留、JOIN 错误。
 // 无 JOIN 时零开销直返。同步直连/异步直连/窗口前置三路径共用。
 func (s *Stream) enrichData(data map[string]any) (dataMap map[string]any, keep bool, err error) {
@@ -655,35 +1040,55 @@ func (s *Stream) enrichData(data map[string]any) (dataMap map[string]any, keep b
 }
 
 // applyWhereAndAnalytic 按 WHERE 是否引用分析函数决定求值序，并应用 WHERE 过滤。
-// 返回分析结果（供投影）与是否通过过滤。同步/异步直连路径共用。
-func (s *Stream) applyWhereAndAnalytic(dataMap map[string]any) (analyticResults map[string]any, keep bool) {
+// 返回分析结果（供投影）、是否通过过滤，以及 WHERE 求值错误（已投递给错误 sink，
+// 该行视为未通过）。同步/异步直连路径共用。
+func (s *Stream) applyWhereAndAnalytic(dataMap map[string]any) (analyticResults map[string]any, keep bool, err error) {
 	whereUsesAnalytic := len(s.config.WhereAnalyticCalls) > 0
 	if whereUsesAnalytic {
 		analyticResults = s.evalAnalytic(dataMap)
 	}
-	if s.filter != nil && !s.filter.Evaluate(dataMap) {
-		return nil, false
+	if pass, ferr := s.evaluateFilter(dataMap); !pass {
+		return nil, false, ferr
 	}
 	if !whereUsesAnalytic {
 		analyticResults = s.evalAnalytic(dataMap)
 	}
-	return analyticResults, true
+	return analyticResults, true, nil
 }
 
 // projectDirectRow 投影 SELECT 字段（表达式/简单字段/分析函数），含 omitEmpty 抑制。
-// emit=false 表示该行被 omitEmpty 抑制、不应输出。同步/异步直连路径共用。
-func (s *Stream) projectDirectRow(dataMap, analyticResults map[string]any) (result map[string]any, emit bool) {
+// emit=false 表示该行不应输出（被 omitEmpty 抑制，或按 ProjectionErrorPolicy 丢弃）；
+// err 非空仅出现在 ProjectionErrorFail 策略下。同步/异步直连路径共用。
+func (s *Stream) projectDirectRow(dataMap, analyticResults map[string]any) (result map[string]any, emit bool, err error) {
 	estimatedSize := len(s.config.FieldExpressions) + len(s.config.SimpleFields)
 	if estimatedSize < 8 {
 		estimatedSize = 8
 	}
 	result = make(map[string]any, estimatedSize)
 	for fieldName := range s.config.FieldExpressions {
-		s.processExpressionField(fieldName, dataMap, result)
+		if ferr := s.processExpressionField(fieldName, dataMap, result); ferr != nil {
+			fallbackExpr := ""
+			if info := s.compiledExprInfo[fieldName]; info != nil {
+				fallbackExpr = info.fallbackExpr
+			}
+			if s.applyExpressionFallback(fieldName, fallbackExpr, dataMap, result, ferr) {
+				continue
+			}
+			if drop, perr := s.handleProjectionError(dataMap, ferr); drop {
+				return nil, false, perr
+			}
+		}
 	}
 	if len(s.config.SimpleFields) > 0 {
 		for _, fieldSpec := range s.config.SimpleFields {
-			s.processSimpleField(fieldSpec, dataMap, dataMap, result)
+			if ferr := s.processSimpleField(fieldSpec, dataMap, dataMap, result); ferr != nil {
+				if info := s.compiledFieldInfo[fieldSpec]; info != nil && s.applyExpressionFallback(info.outputName, info.fallbackExpr, dataMap, result, ferr) {
+					continue
+				}
+				if drop, perr := s.handleProjectionError(dataMap, ferr); drop {
+					return nil, false, perr
+				}
+			}
 		}
 	} else if len(s.config.FieldExpressions) == 0 && len(s.config.AnalyticFields) == 0 {
 		for k, v := range dataMap {
@@ -692,9 +1097,45 @@ func (s *Stream) projectDirectRow(dataMap, analyticResults map[string]any) (resu
 	}
 	s.projectAnalytic(result, analyticResults)
 	if len(result) == 0 && s.hasOmitEmptyAnalytic() {
-		return nil, false
+		return nil, false, nil
+	}
+	return result, true, nil
+}
+
+// applyExpressionFallback 字段求值出错时写入替代值：优先取字段外层
+// coalesce_expr(expr, fallback) 的 fallbackExpr（按当前行求值），其次为 Config.ExpressionFallback。
+// 返回 false 表示没有可用的替代值，由调用方按 ProjectionErrorPolicy 处理。
+func (s *Stream) applyExpressionFallback(fieldName, fallbackExpr string, dataMap, result map[string]any, fieldErr error) bool {
+	if fallbackExpr != "" {
+		if value, err := functions.GetExprBridge().EvaluateExpression(fallbackExpr, dataMap); err == nil {
+			s.log.Debug("%v, using coalesce_expr fallback", fieldErr)
+			result[fieldName] = value
+			return true
+		}
+	}
+	if s.config.ExpressionFallback != nil {
+		s.log.Debug("%v, using expression fallback", fieldErr)
+		result[fieldName] = s.config.ExpressionFallback
+		return true
+	}
+	return false
+}
+
+// handleProjectionError 按 Config.ProjectionErrorPolicy 处理单个字段的求值错误：
+// drop 表示丢弃该行，err 非空表示需向调用方报告（ProjectionErrorFail）。
+// nullify 与 skip_row 记录日志，字段已由求值方置为 NULL。错误同时投递给错误 sink。
+func (s *Stream) handleProjectionError(dataMap map[string]any, fieldErr error) (drop bool, err error) {
+	s.reportRecordError(dataMap, fieldErr)
+	switch s.config.ProjectionErrorPolicy {
+	case types.ProjectionErrorFail:
+		return true, fieldErr
+	case types.ProjectionErrorSkipRow:
+		s.log.Error("%v, row skipped", fieldErr)
+		return true, nil
+	default:
+		s.log.Error("%v", fieldErr)
+		return false, nil
 	}
-	return result, true
 }
 
 // processDirectDataSync synchronous version of direct data processing
@@ -707,19 +1148,27 @@ func (s *Stream) projectDirectRow(dataMap, analyticResults map[string]any) (resu
 func (s *Stream) processDirectDataSync(data map[string]any) (map[string]any, error) {
 	dataMap, keep, err := s.enrichData(data)
 	if err != nil {
+		s.reportRecordError(data, err)
 		return nil, err
 	}
 	if !keep {
 		return nil, nil // INNER JOIN no match: filtered
 	}
-	analyticResults, pass := s.applyWhereAndAnalytic(dataMap)
+	analyticResults, pass, _ := s.applyWhereAndAnalytic(dataMap)
 	if !pass {
 		return nil, nil
 	}
-	result, emit := s.projectDirectRow(dataMap, analyticResults)
+	result, emit, err := s.projectDirectRow(dataMap, analyticResults)
+	if err != nil {
+		return nil, err
+	}
 	if !emit {
 		return nil, nil
 	}
+	if len(s.syncOffsetLimit([]map[string]any{result})) == 0 {
+		return nil, nil
+	}
+	s.tagPrimaryKey([]map[string]any{result})
 	s.mOutput.Inc()
 	s.callSinksAsync([]map[string]any{result})
 	return result, nil
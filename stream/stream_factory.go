@@ -17,8 +17,10 @@
 package stream
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rulego/streamsql/cep"
 	"github.com/rulego/streamsql/logger"
@@ -27,6 +29,10 @@ import (
 	"github.com/rulego/streamsql/window"
 )
 
+// defaultBatchChannelSize is the EmitMany batch buffer used when
+// BufferConfig.BatchChannelSize is unset (e.g. hand-built PerformanceConfig).
+const defaultBatchChannelSize = 64
+
 // StreamFactory Stream factory responsible for creating different types of Streams
 type StreamFactory struct{}
 
@@ -64,17 +70,60 @@ func (sf *StreamFactory) CreateCustomPerformanceStream(config types.Config, perf
 }
 
 // createStreamWithUnifiedConfig internal implementation for creating Stream using unified configuration
-func (sf *StreamFactory) createStreamWithUnifiedConfig(config types.Config) (*Stream, error) {
+func (sf *StreamFactory) createStreamWithUnifiedConfig(config types.Config) (_ *Stream, err error) {
 	var win window.Window
-	var err error
 
 	// Validate performance configuration
 	if err := sf.validatePerformanceConfig(config.PerformanceConfig); err != nil {
 		return nil, fmt.Errorf("invalid performance configuration: %w", err)
 	}
+	switch config.OutputShape {
+	case "", types.OutputShapeWide, types.OutputShapeLong:
+	default:
+		return nil, fmt.Errorf("invalid output shape %q: must be %q or %q", config.OutputShape, types.OutputShapeWide, types.OutputShapeLong)
+	}
+	switch config.EmitGranularity {
+	case "", types.EmitPerWindow, types.EmitPerGroup:
+	default:
+		return nil, fmt.Errorf("invalid emit granularity %q: must be %q or %q", config.EmitGranularity, types.EmitPerWindow, types.EmitPerGroup)
+	}
+	if err := config.GroupNullPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(config.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", config.TimeZone, err)
+	}
+	for _, col := range config.PrimaryKey {
+		if col == "" {
+			return nil, fmt.Errorf("invalid primary key: column name must not be empty")
+		}
+	}
+
+	// Dead-letter store is opened before the window so the window's OnDrop hook
+	// can reach it.
+	var deadLetter *deadLetterStore
+	if config.DeadLetterDir != "" {
+		deadLetter, err = newDeadLetterStore(config.DeadLetterDir)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				_ = deadLetter.close()
+			}
+		}()
+	}
 
 	// Only create window when needed
 	if config.NeedWindow {
+		if deadLetter != nil {
+			config.WindowConfig.OnDrop = func(data any, reason string) {
+				if err := deadLetter.write(data, reason); err != nil && config.Logger != nil {
+					config.Logger.Error("Failed to write dead-letter record: %v", err)
+				}
+			}
+		}
 		win, err = sf.createWindow(config)
 		if err != nil {
 			return nil, err
@@ -83,6 +132,21 @@ func (sf *StreamFactory) createStreamWithUnifiedConfig(config types.Config) (*St
 
 	// Create Stream instance
 	stream := sf.createStreamInstance(config, win)
+	stream.deadLetter = deadLetter
+	stream.poolJoinRows = joinRowPooling(config)
+	stream.location = location
+	if len(config.PrimaryKey) > 0 {
+		stream.primaryKeys = newPrimaryKeyTracker()
+	}
+	if config.IntervalJoin != nil {
+		stream.intervalJoin = newIntervalJoin(config.IntervalJoin)
+	}
+	if config.StateFlushInterval > 0 {
+		if config.NeedWindow || config.Mode == types.ExecCEP || len(config.GroupFields) == 0 {
+			return nil, fmt.Errorf("state flush interval requires a GROUP BY query without a window")
+		}
+		stream.latest = newLatestState(config.StateFlushInterval)
+	}
 
 	// Setup data processing strategy
 	if err := sf.setupDataProcessingStrategy(stream, config.PerformanceConfig); err != nil {
@@ -97,6 +161,14 @@ func (sf *StreamFactory) createStreamWithUnifiedConfig(config types.Config) (*St
 	if err := stream.compileOutputNames(); err != nil {
 		return nil, err
 	}
+	stream.compileMetricColumns()
+	if err = stream.validatePrimaryKey(); err != nil {
+		return nil, err
+	}
+	// Config.Where (set by types.ConfigBuilder); SQL passes WHERE via RegisterFilter.
+	if err := stream.RegisterFilter(config.Where); err != nil {
+		return nil, err
+	}
 
 	// CEP 模式：构造期编译并实例化引擎。fail-fast（编译错误在 Execute 即暴露），
 	// 且引擎在 Start 派生 goroutine 前就绪，消除原懒初始化对 s.cep 的并发读。
@@ -139,25 +211,44 @@ func (sf *StreamFactory) createStreamInstance(config types.Config, win window.Wi
 	if log == nil {
 		log = logger.GetDefault()
 	}
+	evalCtx, cancelEval := context.WithCancel(context.Background())
+	batchSize := perfConfig.BufferConfig.BatchChannelSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchChannelSize
+	}
 	return &Stream{
-		dataChan:         make(chan map[string]any, perfConfig.BufferConfig.DataChannelSize),
-		config:           config,
-		log:              log,
-		Window:           win,
-		tables:           newTableStore(),
-		resultChan:       make(chan []map[string]any, perfConfig.BufferConfig.ResultChannelSize),
-		seenResults:      &sync.Map{},
-		done:             make(chan struct{}),
-		sinkWorkerPool:   make(chan func(), perfConfig.WorkerConfig.SinkPoolSize),
-		allowDataDrop:    perfConfig.OverflowConfig.AllowDataLoss,
-		blockingTimeout:  perfConfig.OverflowConfig.BlockTimeout,
-		overflowStrategy: perfConfig.OverflowConfig.Strategy,
-		maxRetryRoutines: int32(perfConfig.WorkerConfig.MaxRetryRoutines),
-		metricsRegistry:  reg,
-		mInput:           reg.Counter(InputCount),
-		mOutput:          reg.Counter(OutputCount),
-		mInputDropped:    reg.Counter(InputDroppedCount),
-		mOutputDropped:   reg.Counter(OutputDroppedCount),
+		dataChan:          make(chan map[string]any, perfConfig.BufferConfig.DataChannelSize),
+		batchChan:         make(chan []map[string]any, batchSize),
+		config:            config,
+		log:               log,
+		Window:            win,
+		tables:            newTableStore(),
+		windowHistory:     newWindowHistory(config.WindowHistorySize),
+		transformBuf:      newTransformBuffer(config.TransformFlushInterval),
+		compactor:         newWindowCompactor(config),
+		resultChan:        make(chan []map[string]any, perfConfig.BufferConfig.ResultChannelSize),
+		seenResults:       &sync.Map{},
+		done:              make(chan struct{}),
+		evalCtx:           evalCtx,
+		cancelEval:        cancelEval,
+		flushChan:         make(chan chan struct{}),
+		windowFlushChan:   make(chan chan struct{}),
+		drainChan:         make(chan chan struct{}),
+		pauseWake:         make(chan struct{}, 1),
+		sinkWorkerPool:    make(chan func(), perfConfig.WorkerConfig.SinkPoolSize),
+		allowDataDrop:     perfConfig.OverflowConfig.AllowDataLoss,
+		blockingTimeout:   perfConfig.OverflowConfig.BlockTimeout,
+		overflowStrategy:  perfConfig.OverflowConfig.Strategy,
+		maxRetryRoutines:  int32(perfConfig.WorkerConfig.MaxRetryRoutines),
+		metricsRegistry:   reg,
+		mInput:            reg.Counter(InputCount),
+		mOutput:           reg.Counter(OutputCount),
+		mInputDropped:     reg.Counter(InputDroppedCount),
+		mOutputDropped:    reg.Counter(OutputDroppedCount),
+		mSinkDropped:      reg.Counter(SinkBatchesDropped),
+		mErrorSinkDropped: reg.Counter(ErrorSinkDropped),
+		mAggBufDropped:    reg.Counter(AggregateBufferDropped),
+		sinkInFlight:      newSinkInFlight(perfConfig.MaxInFlightBatches),
 	}
 }
 
@@ -196,6 +287,12 @@ func (sf *StreamFactory) validatePerformanceConfig(config types.PerformanceConfi
 	if config.WorkerConfig.SinkPoolSize < 0 {
 		return fmt.Errorf("SinkPoolSize cannot be negative: %d", config.WorkerConfig.SinkPoolSize)
 	}
+	if config.WorkerConfig.AggregationShards < 0 {
+		return fmt.Errorf("AggregationShards cannot be negative: %d", config.WorkerConfig.AggregationShards)
+	}
+	if config.MaxInFlightBatches < 0 {
+		return fmt.Errorf("MaxInFlightBatches cannot be negative: %d", config.MaxInFlightBatches)
+	}
 
 	// Validate overflow configuration
 	validStrategies := map[string]bool{
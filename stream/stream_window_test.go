@@ -235,3 +235,57 @@ func TestAggregationTypes(t *testing.T) {
 		})
 	}
 }
+
+// TestRowPooling runs consecutive windows with PerformanceConfig.RowPooling:
+// recycled batches must not leak rows between windows or alter the input rows.
+func TestRowPooling(t *testing.T) {
+	perf := types.DefaultPerformanceConfig()
+	perf.RowPooling = true
+	config := types.Config{
+		WindowConfig: types.WindowConfig{
+			Type:   "counting",
+			Params: []any{2},
+		},
+		GroupFields: []string{"device"},
+		SelectFields: map[string]aggregator.AggregateType{
+			"total": aggregator.Sum,
+			"cnt":   aggregator.Count,
+		},
+		FieldAlias:        map[string]string{"total": "v", "cnt": "v"},
+		NeedWindow:        true,
+		PerformanceConfig: perf,
+	}
+	strm, err := NewStream(config)
+	require.NoError(t, err)
+	defer strm.Stop()
+
+	results := make(chan map[string]any, 10)
+	strm.AddSink(func(rows []map[string]any) {
+		for _, r := range rows {
+			results <- r
+		}
+	})
+	strm.Start()
+
+	var inputs []map[string]any
+	for i := 1; i <= 6; i++ {
+		row := map[string]any{"device": "d1", "v": i}
+		inputs = append(inputs, row)
+		strm.Emit(row)
+	}
+
+	var totals []float64
+	for len(totals) < 3 {
+		select {
+		case r := <-results:
+			assert.EqualValues(t, 2, r["cnt"])
+			totals = append(totals, r["total"].(float64))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout, got %v", totals)
+		}
+	}
+	assert.ElementsMatch(t, []float64{3, 7, 11}, totals)
+	for i, row := range inputs {
+		assert.Equal(t, map[string]any{"device": "d1", "v": i + 1}, row)
+	}
+}
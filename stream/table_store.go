@@ -94,16 +94,26 @@ func (m *MemoryTableSource) encodeRow(row map[string]any) []any {
 
 // encodeKey serializes a lookup key into a stable, type-tagged string so that
 // 1 (int) and "1" (string) never collide. Accepts a single value or a
-// []any tuple.
+// []any tuple. Tuple segments are length-prefixed, so values containing the
+// separator cannot shift into the next segment: ("a|b","c") and ("a","b|c")
+// stay distinct. A one-element tuple encodes like its single value.
 func encodeKey(key any) string {
-	if vals, ok := key.([]any); ok {
-		parts := make([]string, len(vals))
-		for i, v := range vals {
-			parts[i] = encodeOne(v)
-		}
-		return strings.Join(parts, "\x1f")
+	vals, ok := key.([]any)
+	if !ok {
+		return encodeOne(key)
+	}
+	if len(vals) == 1 {
+		return encodeOne(vals[0])
+	}
+	var sb strings.Builder
+	for _, v := range vals {
+		part := encodeOne(v)
+		sb.WriteString(strconv.Itoa(len(part)))
+		sb.WriteByte(':')
+		sb.WriteString(part)
+		sb.WriteByte('\x1f')
 	}
-	return encodeOne(key)
+	return sb.String()
 }
 
 func encodeOne(v any) string {
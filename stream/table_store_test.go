@@ -35,6 +35,17 @@ func TestEncodeKey_NumericNormalization(t *testing.T) {
 	if encodeKey([]any{int(1), "a"}) != encodeKey([]any{float64(1), "a"}) {
 		t.Error("composite key: int/float64 segment should normalize")
 	}
+	// 复合键：分量含分隔符时不得跨分量碰撞
+	if encodeKey([]any{"a|b", "c"}) == encodeKey([]any{"a", "b|c"}) {
+		t.Error("composite key: separator inside a segment must not collide")
+	}
+	if encodeKey([]any{"a\x1fs:b", "c"}) == encodeKey([]any{"a", "b\x1fs:c"}) {
+		t.Error("composite key: encoded separator inside a segment must not collide")
+	}
+	// 单元素元组与单值编码一致（维度表单列键查找依赖这一点）
+	if encodeKey([]any{"k"}) != encodeKey("k") {
+		t.Error("one-element tuple should encode like its value")
+	}
 }
 
 func mathNegZero() float64 {
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// maxTransformBatchRows 是非聚合结果缓冲的行数上限，达到即派发，避免高吞吐下缓冲无界增长。
+const maxTransformBatchRows = 1000
+
+// transformBuffer 把非聚合查询的结果行合并为批次（Config.TransformFlushInterval）：
+// 缓冲中最早的行等待不超过 interval 即整批派发，因此稀疏流的端到端延迟有界，
+// 高吞吐时则以较少的 sink 调用换取吞吐。计时器由数据处理 goroutine 驱动。
+type transformBuffer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	rows     []map[string]any
+	timer    *time.Timer
+}
+
+// newTransformBuffer 创建刷新间隔为 interval 的缓冲；interval ≤ 0 返回 nil（逐行派发）。
+func newTransformBuffer(interval time.Duration) *transformBuffer {
+	if interval <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(interval)
+	timer.Stop()
+	return &transformBuffer{interval: interval, timer: timer}
+}
+
+// C 返回刷新计时器的 channel；缓冲为 nil 时返回 nil（select 中永不就绪）。
+func (b *transformBuffer) C() <-chan time.Time {
+	if b == nil {
+		return nil
+	}
+	return b.timer.C
+}
+
+// add 追加一批结果行，缓冲由空变非空时启动计时；达到行数上限时返回 true，调用方应立即 take。
+func (b *transformBuffer) add(rows []map[string]any) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.rows) == 0 {
+		b.timer.Reset(b.interval)
+	}
+	b.rows = append(b.rows, rows...)
+	return len(b.rows) >= maxTransformBatchRows
+}
+
+// take 取出全部待派发的行并停止计时；缓冲为空时返回 nil。
+func (b *transformBuffer) take() []map[string]any {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.rows) == 0 {
+		return nil
+	}
+	b.timer.Stop()
+	rows := b.rows
+	b.rows = nil
+	return rows
+}
+
+// flushTransformBuffer 把缓冲中的结果行作为一批派发到 resultChan 与 sinks。
+func (s *Stream) flushTransformBuffer() {
+	if rows := s.transformBuf.take(); len(rows) > 0 {
+		s.sendResultNonBlocking(rows)
+		s.callSinksAsync(rows)
+	}
+}
+
+// flushTransformBufferSync 在 Stop 末尾同步派发缓冲中剩余的结果行（worker pool 已退出）。
+func (s *Stream) flushTransformBufferSync() {
+	if rows := s.transformBuf.take(); len(rows) > 0 {
+		s.sendResultForFlush(rows)
+		s.invokeSinksInline(rows)
+	}
+}
@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransformBuffer 测试非聚合结果缓冲的计时与行数上限
+func TestTransformBuffer(t *testing.T) {
+	assert.Nil(t, newTransformBuffer(0), "interval<=0 disables buffering")
+	var nilBuf *transformBuffer
+	assert.Nil(t, nilBuf.C())
+	assert.Nil(t, nilBuf.take())
+
+	b := newTransformBuffer(20 * time.Millisecond)
+	select {
+	case <-b.C():
+		t.Fatal("timer must not run while the buffer is empty")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.False(t, b.add([]map[string]any{{"id": 1}}))
+	assert.False(t, b.add([]map[string]any{{"id": 2}}))
+	select {
+	case <-b.C():
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire after the first buffered row")
+	}
+	rows := b.take()
+	require.Len(t, rows, 2)
+	assert.Nil(t, b.take())
+
+	full := make([]map[string]any, maxTransformBatchRows)
+	assert.True(t, b.add(full), "reaching the row limit asks for an immediate flush")
+	assert.Len(t, b.take(), maxTransformBatchRows)
+}
+
+// TestTransformBufferStopFlush 测试 Stop 同步派发缓冲中剩余的结果行
+func TestTransformBufferStopFlush(t *testing.T) {
+	config := types.NewConfig()
+	config.SimpleFields = []string{"id"}
+	config.TransformFlushInterval = time.Hour
+	s, err := NewStream(config)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var got []map[string]any
+	s.AddSink(func(rows []map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, rows...)
+	})
+	s.Start()
+	s.Emit(map[string]any{"id": 1})
+	s.Emit(map[string]any{"id": 2})
+	require.Eventually(t, func() bool {
+		s.transformBuf.mu.Lock()
+		defer s.transformBuf.mu.Unlock()
+		return len(s.transformBuf.rows) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	s.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, got, 2)
+	assert.Equal(t, 1, got[0]["id"])
+}
@@ -0,0 +1,180 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rulego/streamsql/aggregator"
+	"github.com/rulego/streamsql/types"
+)
+
+// windowCompactor 实现 Config.CompactUnchangedWindows：每个分组暂存最近一条窗口结果，
+// 后续相接（或重叠）窗口的聚合值与之相同时只延长其时间范围，不另行输出；值变化、
+// 分组在之后的窗口缺席、输入结束（CloseInput）或 Stop 时才派发暂存的结果。
+// 没有 window_id（无时间边界的窗口，如全局窗口）的行原样通过。
+type windowCompactor struct {
+	mu      sync.Mutex
+	pending map[string]*compactedRow
+	// 窗口元数据列（window_start() 等），不参与比较，合并时按时间范围更新
+	startCols, endCols map[string]bool
+}
+
+// compactedRow 是一个分组暂存的结果行及其覆盖的时间范围（UnixNano）。
+type compactedRow struct {
+	row        map[string]any
+	start, end int64
+}
+
+// newWindowCompactor 为窗口查询创建压缩器；未开启 CompactUnchangedWindows 时返回 nil。
+func newWindowCompactor(config types.Config) *windowCompactor {
+	if !config.CompactUnchangedWindows || !config.NeedWindow {
+		return nil
+	}
+	c := &windowCompactor{
+		pending:   make(map[string]*compactedRow),
+		startCols: make(map[string]bool),
+		endCols:   make(map[string]bool),
+	}
+	for col, aggType := range config.SelectFields {
+		switch aggType {
+		case aggregator.WindowStart, aggregator.WindowStartISO, aggregator.WindowFillRatio:
+			c.startCols[col] = true
+		case aggregator.WindowEnd, aggregator.WindowEndISO, aggregator.WindowWatermark:
+			c.endCols[col] = true
+		}
+	}
+	return c
+}
+
+// compact 处理一个窗口的结果行，返回此刻应派发的行（已结束的连续区间）。
+func (c *windowCompactor) compact(rows []map[string]any, keyOf func(map[string]any) string) []map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []map[string]any
+	seen := make(map[string]bool, len(rows))
+	windowStart := int64(-1)
+	for _, row := range rows {
+		start, end, ok := parseWindowID(row[WindowIDField])
+		if !ok {
+			out = append(out, row)
+			continue
+		}
+		if windowStart < 0 || start < windowStart {
+			windowStart = start
+		}
+		key := keyOf(row)
+		seen[key] = true
+		if p := c.pending[key]; p != nil {
+			if start <= p.end && c.sameValues(p.row, row) {
+				c.extend(p, row, end)
+				continue
+			}
+			out = append(out, p.row)
+		}
+		c.pending[key] = &compactedRow{row: row, start: start, end: end}
+	}
+	// 本窗口从其区间末尾或之后开始、而分组未出现：该分组的连续区间已结束
+	if windowStart >= 0 {
+		for _, key := range c.sortedKeys() {
+			if p := c.pending[key]; !seen[key] && p.end <= windowStart {
+				out = append(out, p.row)
+				delete(c.pending, key)
+			}
+		}
+	}
+	return out
+}
+
+// take 取出全部暂存的行（按分组键排序）；compactor 为 nil 时返回 nil。
+func (c *windowCompactor) take() []map[string]any {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []map[string]any
+	for _, key := range c.sortedKeys() {
+		out = append(out, c.pending[key].row)
+	}
+	c.pending = make(map[string]*compactedRow)
+	return out
+}
+
+func (c *windowCompactor) sortedKeys() []string {
+	keys := make([]string, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sameValues 比较两行除窗口元数据列之外的所有列。
+func (c *windowCompactor) sameValues(a, b map[string]any) bool {
+	count := 0
+	for col, av := range a {
+		if c.isWindowColumn(col) {
+			continue
+		}
+		bv, ok := b[col]
+		if !ok || !reflect.DeepEqual(av, bv) {
+			return false
+		}
+		count++
+	}
+	for col := range b {
+		if !c.isWindowColumn(col) {
+			count--
+		}
+	}
+	return count == 0
+}
+
+func (c *windowCompactor) isWindowColumn(col string) bool {
+	return col == WindowIDField || c.startCols[col] || c.endCols[col]
+}
+
+// extend 把暂存行的时间范围延长到 end：window_id 改为合并后的范围，结束类窗口列取新行的值。
+func (c *windowCompactor) extend(p *compactedRow, row map[string]any, end int64) {
+	if end > p.end {
+		p.end = end
+		for col := range c.endCols {
+			if v, ok := row[col]; ok {
+				p.row[col] = v
+			}
+		}
+	}
+	p.row[WindowIDField] = fmt.Sprintf("%d_%d", p.start, p.end)
+}
+
+// parseWindowID 解析 stampWindowID 写入的 "<start>_<end>"（UnixNano）。
+func parseWindowID(v any) (start, end int64, ok bool) {
+	id, isStr := v.(string)
+	if !isStr {
+		return 0, 0, false
+	}
+	s, e, found := strings.Cut(id, "_")
+	if !found {
+		return 0, 0, false
+	}
+	start, err1 := strconv.ParseInt(s, 10, 64)
+	end, err2 := strconv.ParseInt(e, 10, 64)
+	return start, end, err1 == nil && err2 == nil
+}
+
+// flushCompacted 派发全部暂存的压缩结果（CloseInput 冲刷窗口之后）。
+func (s *Stream) flushCompacted() {
+	s.emitAggregationRows(s.compactor.take())
+}
+
+// flushCompactedSync 在 Stop 末尾同步派发暂存的压缩结果（worker pool 已退出）。
+func (s *Stream) flushCompactedSync() {
+	for _, batch := range s.aggregationBatches(s.compactor.take()) {
+		s.sendResultForFlush(batch)
+		s.invokeSinksInline(batch)
+	}
+}
@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql/aggregator"
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowCompactor(t *testing.T) {
+	assert.Nil(t, newWindowCompactor(types.Config{NeedWindow: true}), "disabled by default")
+
+	c := newWindowCompactor(types.Config{
+		NeedWindow:              true,
+		CompactUnchangedWindows: true,
+		SelectFields:            map[string]aggregator.AggregateType{"we": aggregator.WindowEnd},
+	})
+	require.NotNil(t, c)
+	keyOf := func(r map[string]any) string { return r["g"].(string) }
+	row := func(g string, v any, id string, we int) map[string]any {
+		return map[string]any{"g": g, "v": v, WindowIDField: id, "we": we}
+	}
+
+	assert.Empty(t, c.compact([]map[string]any{row("a", 1, "0_10", 10), row("b", 1, "0_10", 10)}, keyOf))
+	// b 缺席紧随其后的窗口：其区间已结束，立即输出；a 不变，继续暂存
+	out := c.compact([]map[string]any{row("a", 1, "10_20", 20)}, keyOf)
+	require.Len(t, out, 1)
+	assert.Equal(t, "b", out[0]["g"])
+	assert.Equal(t, "0_10", out[0][WindowIDField])
+
+	// a 的值变化：输出合并后的 a
+	out = c.compact([]map[string]any{row("a", 2, "20_30", 30)}, keyOf)
+	require.Len(t, out, 1)
+	assert.Equal(t, "0_20", out[0][WindowIDField])
+	assert.Equal(t, 20, out[0]["we"])
+	assert.Equal(t, 1, out[0]["v"])
+
+	// 无 window_id 的行原样通过
+	passthrough := map[string]any{"g": "c", "v": 1}
+	assert.Equal(t, []map[string]any{passthrough}, c.compact([]map[string]any{passthrough}, keyOf))
+
+	rest := c.take()
+	require.Len(t, rest, 1)
+	assert.Equal(t, "20_30", rest[0][WindowIDField])
+	assert.Empty(t, c.take())
+	assert.Nil(t, (*windowCompactor)(nil).take())
+}
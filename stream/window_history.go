@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// windowHistory 是最近 N 个窗口输出结果的环形缓冲（Config.WindowHistorySize），
+// 供下游短暂故障恢复后通过 ReplayLastWindows 重新投递。只保存在内存中，不做持久化。
+type windowHistory struct {
+	mu    sync.Mutex
+	buf   [][]map[string]any
+	next  int // 下一个写入位置
+	count int // 已保存的窗口数（≤ len(buf)）
+}
+
+// newWindowHistory 创建容量为 size 的环形缓冲；size ≤ 0 返回 nil（不保留历史）。
+func newWindowHistory(size int) *windowHistory {
+	if size <= 0 {
+		return nil
+	}
+	return &windowHistory{buf: make([][]map[string]any, size)}
+}
+
+// record 保存一个窗口的最终结果（行做浅拷贝，避免 sink 修改结果后影响重放）。
+func (h *windowHistory) record(results []map[string]any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf[h.next] = copyRows(results)
+	h.next = (h.next + 1) % len(h.buf)
+	if h.count < len(h.buf) {
+		h.count++
+	}
+}
+
+// last 按发出顺序（由旧到新）返回最近 n 个窗口的结果；n 大于已保存数量时返回全部。
+func (h *windowHistory) last(n int) [][]map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n > h.count {
+		n = h.count
+	}
+	out := make([][]map[string]any, 0, n)
+	for i := n; i > 0; i-- {
+		idx := (h.next - i + len(h.buf)) % len(h.buf)
+		out = append(out, h.buf[idx])
+	}
+	return out
+}
+
+func copyRows(rows []map[string]any) []map[string]any {
+	out := make([]map[string]any, len(rows))
+	for i, r := range rows {
+		out[i] = copyRow(r)
+	}
+	return out
+}
+
+// copyRow returns a shallow copy of row.
+func copyRow(row map[string]any) map[string]any {
+	c := make(map[string]any, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+// ReplayLastWindows re-dispatches the results of the last n emitted windows
+// (oldest first) to the registered sinks, e.g. after a downstream outage.
+// It requires Config.WindowHistorySize > 0; n is capped at the number of
+// retained windows. Replayed rows are not sent to GetResultsChan and are
+// not counted as new output. Returns the number of windows replayed.
+func (s *Stream) ReplayLastWindows(n int) int {
+	if s.windowHistory == nil || n <= 0 {
+		return 0
+	}
+	windows := s.windowHistory.last(n)
+	for _, results := range windows {
+		// 每次重放交给 sink 一份新拷贝，与首次投递一样按 EmitGranularity 切分
+		s.dispatchWindowToSinks(copyRows(results))
+	}
+	return len(windows)
+}
+
+// dispatchWindowToSinks 将一个窗口的结果按 EmitGranularity 切分后交给 sink。
+func (s *Stream) dispatchWindowToSinks(results []map[string]any) {
+	batches := [][]map[string]any{results}
+	if s.config.EmitGranularity == types.EmitPerGroup {
+		batches = s.splitByGroup(results)
+	}
+	for _, batch := range batches {
+		s.callSinksAsync(batch)
+	}
+}
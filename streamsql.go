@@ -17,9 +17,19 @@
 package streamsql
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/logger"
 	"github.com/rulego/streamsql/metrics"
 	"github.com/rulego/streamsql/rsql"
@@ -40,6 +50,20 @@ import (
 type Streamsql struct {
 	stream *stream.Stream
 
+	// All queries created by Execute in statement order; queries[0] == stream.
+	// More than one only when Execute received several ';'-separated statements.
+	queries []*stream.Stream
+
+	// Stages of an ExecutePipeline pipeline in data-flow order, nil otherwise.
+	// Emit feeds pipeline[0]; stream and queries[0] are the last stage.
+	pipeline []*stream.Stream
+
+	// streamMu guards stream/queries, which ReplaceSQL swaps; Emit holds the
+	// read lock for the whole call so no row lands on a retired query.
+	// replaceMu serializes ReplaceSQL calls.
+	streamMu  sync.RWMutex
+	replaceMu sync.Mutex
+
 	// Performance configuration mode
 	performanceMode string // "default", "high_performance", "low_latency", "custom"
 	customConfig    *types.PerformanceConfig
@@ -61,6 +85,82 @@ type Streamsql struct {
 
 	// 分析函数 PARTITION 分区数上限（≤0 用默认）。由 WithAnalyticMaxPartitions 设置。
 	analyticMaxPartitions int
+
+	// 聚合结果输出形态（wide/long）。由 WithOutputShape 设置。
+	outputShape types.OutputShape
+
+	// 窗口结果派发粒度（window/group）。由 WithEmitGranularity 设置。
+	emitGranularity types.EmitGranularity
+
+	// 滑动窗口同时打开窗口数上限（≤0 不限）及超限策略。由 WithMaxOpenWindows 设置。
+	maxOpenWindows   int
+	openWindowPolicy types.OpenWindowPolicy
+
+	// 处理时间滚动窗口按分组键独立计时。由 WithPerKeyWindowClock 设置。
+	perKeyWindowClock bool
+
+	// 事件时间窗口按分组键独立维护水位线。由 WithPerKeyWatermark 设置。
+	perKeyWatermark bool
+
+	// 被拒绝记录的死信目录。由 WithDeadLetterDir 设置。
+	deadLetterDir string
+
+	// 单次表达式求值时限（≤0 不限时）。由 WithExpressionTimeout 设置。
+	expressionTimeout time.Duration
+
+	// 窗口结果是否携带 __input_count/__filtered_count。由 WithQualityCounts 设置。
+	qualityCounts bool
+
+	// 窗口内行数少于该值的分组不输出（≤0 不过滤）。由 WithMinGroupCount 设置。
+	minGroupCount int
+
+	// 数值聚合遇到 NaN/Inf 时的处理策略（空为原样参与）。由 WithNonFinitePolicy 设置。
+	nonFinitePolicy types.NonFinitePolicy
+
+	// 缓冲型聚合（median/percentile/collect 等）每个分组最多缓冲的值个数（≤0 不限）及超限策略。
+	// 由 WithMaxAggregateBufferPerGroup 设置。
+	maxAggregateBuffer    int
+	aggregateBufferPolicy types.AggregateBufferPolicy
+
+	// 合并聚合值不变的相邻窗口结果。由 WithCompactUnchangedWindows 设置。
+	compactUnchangedWindows bool
+
+	// GROUP BY 字段为 NULL 时的分组策略（空同 separate_group）。由 WithGroupNullPolicy 设置。
+	groupNullPolicy types.GroupNullPolicy
+
+	// 非聚合投影字段求值出错时的处理策略（空同 nullify）。由 WithProjectionErrorPolicy 设置。
+	projectionErrorPolicy types.ProjectionErrorPolicy
+
+	// 非聚合投影表达式求值出错时代替该字段的值（nil 不代替）。由 WithExpressionFallback 设置。
+	expressionFallback any
+
+	// 非聚合结果行的批量派发间隔（≤0 逐行派发）。由 WithTransformFlushInterval 设置。
+	transformFlushInterval time.Duration
+
+	// 非窗口 GROUP BY 查询输出分组快照的间隔（≤0 逐条输出）。由 WithStateFlushInterval 设置。
+	stateFlushInterval time.Duration
+
+	// window_start_iso()/window_end_iso() 使用的时区名（空为 UTC）。由 WithTimeZone 设置。
+	timeZone string
+
+	// 保留最近 N 个窗口的输出供 ReplayLastWindows 重放（0 不保留）。由 WithWindowHistory 设置。
+	windowHistory int
+
+	// 结果主键列，输出行携带 __pk/__upsert。由 WithPrimaryKey 设置。
+	primaryKey []string
+
+	// Upper bound for CloseInput's drain/flush (≤0 uses the stream default). Set by WithCloseInputGrace.
+	closeInputGrace time.Duration
+
+	// Output-column change notification (OnSchemaChange). columns is the last
+	// announced column list; nil until a query is installed.
+	schemaMu        sync.Mutex
+	schemaListeners []func(columns []string)
+	columns         []string
+
+	// Idle-source notification (OnIdleSource) for event-time windows.
+	idleMu        sync.Mutex
+	idleListeners []func(lastEventTime time.Time)
 }
 
 // New creates a new StreamSQL instance.
@@ -102,12 +202,18 @@ func New(options ...Option) *Streamsql {
 //   - WHERE clause: Data filtering conditions
 //   - GROUP BY clause: Grouping fields and window functions
 //   - HAVING clause: Aggregate result filtering
-//   - LIMIT clause: Limit result count
+//   - LIMIT clause: Limit result count, with optional OFFSET to skip leading results
 //   - DISTINCT: Result deduplication
+//   - Comments: -- line comments and /* block comments */ are ignored
+//   - INSERT INTO <sink>: Route the results to the sinks registered by AddNamedSink
+//
+// Several statements separated by ';' create one query each, all fed by Emit.
+// Stream() and AddSink/ToChannel address the first query; use Queries() for the others.
 //
 // Window functions:
 //   - TumblingWindow('5s'): Tumbling window
 //   - SlidingWindow('30s', '10s'): Sliding window
+//   - HopWindow('30s', '10s'): Hopping window (same as SlidingWindow, window type "hop")
 //   - CountingWindow(100): Counting window
 //   - SessionWindow('5m'): Session window
 //
@@ -137,21 +243,327 @@ func New(options ...Option) *Streamsql {
 //	    LIMIT 100
 //	`)
 func (s *Streamsql) Execute(sql string) error {
+	return s.ExecuteWithParams(sql, nil)
+}
+
+// ExecuteWithParams is Execute with execution-time parameters for window
+// function arguments, so configurable window sizes need no SQL string
+// building. An unquoted identifier that is a whole window argument and a key
+// of params is replaced by its value, which is read like the literal it
+// stands for: a duration string such as "5s", a time.Duration, or a number
+// (seconds for time windows, rows for CountingWindow). Parameters take
+// precedence over fields of the same name, e.g. in SessionWindow(gap).
+// Identifiers elsewhere in the statement are not affected.
+//
+// Example:
+//
+//	err := ssql.ExecuteWithParams(
+//	    "SELECT deviceId, AVG(temperature) FROM stream GROUP BY deviceId, TumblingWindow(window_size)",
+//	    map[string]interface{}{"window_size": cfg.WindowSize}, // e.g. "5s"
+//	)
+func (s *Streamsql) ExecuteWithParams(sql string, params map[string]interface{}) error {
 	// Try to acquire execution lock using CAS operation
 	if !atomic.CompareAndSwapInt32(&s.executed, 0, 1) {
 		return fmt.Errorf("Execute() has already been called, create a new Streamsql instance for different queries")
 	}
 
-	// Parse SQL statement
-	config, condition, err := rsql.Parse(sql)
+	statements := rsql.SplitStatements(sql)
+	if len(statements) == 0 {
+		// Let the parser report the empty/invalid statement
+		statements = []string{sql}
+	}
+
+	queries := make([]*stream.Stream, 0, len(statements))
+	for i, statement := range statements {
+		streamInstance, fieldOrder, err := s.buildQuery(statement, params, s.queryDeadLetterDir("statement", i, len(statements)))
+		if err != nil {
+			for _, q := range queries {
+				q.Stop()
+			}
+			// Reset executed flag on error
+			atomic.StoreInt32(&s.executed, 0)
+			if len(statements) > 1 {
+				return fmt.Errorf("statement %d: %w", i+1, err)
+			}
+			return err
+		}
+		if i == 0 {
+			// Get field order information from parsing result
+			s.fieldOrder = fieldOrder
+		}
+		queries = append(queries, streamInstance)
+	}
+
+	s.streamMu.Lock()
+	s.stream = queries[0]
+	s.queries = queries
+	s.streamMu.Unlock()
+
+	// Start stream processing
+	for _, q := range queries {
+		q.Start()
+	}
+
+	s.updateSchema(s.fieldOrder)
+	return nil
+}
+
+// ExecutePipeline creates a pipeline of queries, one per statement, where the
+// results of each stage are the input of the next: Emit feeds the first
+// statement and the last one's results reach AddSink, ToChannel and the other
+// sink wrappers. A transform can thus feed an aggregation without a second
+// instance. Each element must be a single statement; its FROM name is not
+// checked. Queries returns every stage, e.g. to observe an intermediate one.
+//
+// Ordering: a stage forwards its result batches on its result goroutine, so
+// each stage receives rows in the order its predecessor emitted them. Window
+// stages without WITH (TIMESTAMP=...) use processing time on arrival at that
+// stage. EmitSync/EmitSyncRows and ReplaceSQL are not supported.
+//
+// Errors: an invalid statement fails ExecutePipeline with the number of its
+// stage and nothing is started. At runtime a row that fails in any stage is
+// handled by that stage (error sinks registered with AddErrorSink apply to all
+// stages) and is not forwarded; the other rows keep flowing. A full stage
+// input behaves as for Emit under the overflow strategy: "block" slows the
+// upstream stages down, "drop" drops the rows and counts them in that stage's
+// statistics. Stop, CloseInput, Drain and Pause act on every stage from first
+// to last, so CloseInput flushes each stage's open windows into the next.
+//
+// Example:
+//
+//	err := ssql.ExecutePipeline([]string{
+//	    "SELECT deviceId, temperature * 1.8 + 32 AS temp_f FROM stream WHERE temperature IS NOT NULL",
+//	    "SELECT deviceId, AVG(temp_f) AS avg_f FROM stream GROUP BY deviceId, TumblingWindow('5s')",
+//	})
+func (s *Streamsql) ExecutePipeline(statements []string) error {
+	if len(statements) == 0 {
+		return fmt.Errorf("ExecutePipeline requires at least one statement")
+	}
+	if !atomic.CompareAndSwapInt32(&s.executed, 0, 1) {
+		return fmt.Errorf("Execute() has already been called, create a new Streamsql instance for different queries")
+	}
+
+	stages := make([]*stream.Stream, 0, len(statements))
+	var fieldOrder []string
+	for i, statement := range statements {
+		st, order, err := s.buildQuery(statement, nil, s.queryDeadLetterDir("stage", i, len(statements)))
+		if err != nil {
+			for _, q := range stages {
+				q.Stop()
+			}
+			atomic.StoreInt32(&s.executed, 0)
+			return fmt.Errorf("pipeline stage %d: %w", i+1, err)
+		}
+		stages = append(stages, st)
+		fieldOrder = order
+	}
+	for i := 0; i < len(stages)-1; i++ {
+		next := stages[i+1]
+		// A sync sink runs on the stage's result goroutine and keeps batch order;
+		// rows are copied because the next stage may add computed keys in place.
+		stages[i].AddSyncSink(func(results []map[string]interface{}) {
+			batch := make([]map[string]interface{}, len(results))
+			for j, row := range results {
+				batch[j] = copyRow(row)
+			}
+			next.EmitMany(batch)
+		})
+	}
+
+	last := stages[len(stages)-1]
+	s.fieldOrder = fieldOrder
+	s.streamMu.Lock()
+	s.stream = last
+	s.queries = []*stream.Stream{last}
+	s.pipeline = stages
+	s.streamMu.Unlock()
+
+	// Start downstream stages first so no stage forwards into one not yet running.
+	for i := len(stages) - 1; i >= 0; i-- {
+		stages[i].Start()
+	}
+
+	s.updateSchema(fieldOrder)
+	return nil
+}
+
+// ReplaceSQL swaps the running query for a new single statement without
+// recreating the instance. Sinks, sync sinks and the ToChannel channel already
+// registered keep receiving results, as do tables registered for JOIN; rows
+// emitted before the call are finished by the old query and rows emitted after
+// it by the new one. When preserveWindow is true and the new query defines the
+// same window as the old one (type, size, grouping keys, time settings), the
+// open window keeps its buffered rows and is computed by the new SELECT when it
+// fires; otherwise the old query's open windows fire early, as on CloseInput.
+// OnSchemaChange listeners are notified when the output columns change.
+//
+// The new statement is fully validated before anything is touched; on error
+// the old query keeps running. Emit calls block only for the moment the input
+// is switched. Statistics restart with the new query, and a *stream.Stream
+// obtained from Stream() before the call is retired: call Stream() again.
+// Instances running several statements or a pipeline cannot be replaced.
+//
+// Example:
+//
+//	ssql.Execute("SELECT deviceId, AVG(temperature) AS avg FROM stream GROUP BY deviceId, TumblingWindow('1m')")
+//	// Later: also report the maximum, keeping the data of the current minute
+//	err := ssql.ReplaceSQL("SELECT deviceId, AVG(temperature) AS avg, MAX(temperature) AS max FROM stream GROUP BY deviceId, TumblingWindow('1m')", true)
+func (s *Streamsql) ReplaceSQL(sql string, preserveWindow bool) error {
+	return s.ReplaceSQLWithParams(sql, nil, preserveWindow)
+}
+
+// ReplaceSQLWithParams is ReplaceSQL with execution-time parameters for window
+// function arguments, resolved as in ExecuteWithParams. Parameters given to
+// ExecuteWithParams are not remembered: pass every parameter the new
+// statement references.
+//
+// Example:
+//
+//	err := ssql.ReplaceSQLWithParams(
+//	    "SELECT deviceId, AVG(temperature) FROM stream GROUP BY deviceId, TumblingWindow(window_size)",
+//	    map[string]interface{}{"window_size": "10s"}, false)
+func (s *Streamsql) ReplaceSQLWithParams(sql string, params map[string]interface{}, preserveWindow bool) error {
+	s.replaceMu.Lock()
+	defer s.replaceMu.Unlock()
+
+	old := s.current()
+	if old == nil {
+		return fmt.Errorf("Execute must be called before ReplaceSQL")
+	}
+	if len(s.Queries()) > 1 {
+		return fmt.Errorf("ReplaceSQL does not support instances running several statements or a pipeline")
+	}
+	if statements := rsql.SplitStatements(sql); len(statements) > 1 {
+		return fmt.Errorf("ReplaceSQL accepts a single statement, got %d", len(statements))
+	}
+
+	next, fieldOrder, err := s.buildQuery(sql, params, s.deadLetterDir)
+	if err != nil {
+		return err
+	}
+	_, err = old.HandOver(next, preserveWindow, s.closeInputGrace, func() {
+		s.streamMu.Lock()
+		s.stream = next
+		s.queries = []*stream.Stream{next}
+		s.streamMu.Unlock()
+	})
 	if err != nil {
-		// Reset executed flag on error
-		atomic.StoreInt32(&s.executed, 0)
-		return fmt.Errorf("SQL parsing failed: %w", err)
+		next.Stop()
+		return fmt.Errorf("failed to replace query: %w", err)
 	}
 
-	// Get field order information from parsing result
-	s.fieldOrder = config.FieldOrder
+	s.schemaMu.Lock()
+	s.fieldOrder = fieldOrder
+	s.schemaMu.Unlock()
+	s.updateSchema(fieldOrder)
+	return nil
+}
+
+// OnSchemaChange registers a callback invoked with the output column names, in
+// SELECT order, whenever the projected columns change: when Execute installs
+// the query and whenever a later reconfiguration yields a different column
+// list. Registering after Execute delivers the current columns immediately, so
+// late subscribers start in sync. Callbacks run synchronously on the goroutine
+// causing the change and should return quickly.
+//
+// Example:
+//
+//	ssql.OnSchemaChange(func(columns []string) {
+//	    csvWriter.Write(columns) // start a new header
+//	})
+func (s *Streamsql) OnSchemaChange(fn func(columns []string)) {
+	if fn == nil {
+		return
+	}
+	s.schemaMu.Lock()
+	s.schemaListeners = append(s.schemaListeners, fn)
+	current := s.columns
+	s.schemaMu.Unlock()
+	if current != nil {
+		fn(append([]string(nil), current...))
+	}
+}
+
+// updateSchema records the output columns of the active query and notifies
+// OnSchemaChange listeners when they differ from the previously announced ones.
+func (s *Streamsql) updateSchema(columns []string) {
+	s.schemaMu.Lock()
+	if s.columns != nil && equalColumns(s.columns, columns) {
+		s.schemaMu.Unlock()
+		return
+	}
+	s.columns = append([]string{}, columns...)
+	listeners := append([]func([]string){}, s.schemaListeners...)
+	s.schemaMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(append([]string(nil), columns...))
+	}
+}
+
+// OnIdleSource registers a callback invoked when an event-time window's source
+// goes idle: no event arrived within the IDLETIMEOUT set in the query's WITH
+// clause, so the watermark starts advancing by processing time. lastEventTime
+// is the newest event time seen before the idle period. The callback fires once
+// per idle period (the next event re-arms it), runs on the watermark goroutine
+// and should return quickly. Queries without IDLETIMEOUT never call it.
+//
+// Example:
+//
+//	ssql.OnIdleSource(func(lastEventTime time.Time) {
+//	    log.Printf("source idle since event %v", lastEventTime)
+//	})
+func (s *Streamsql) OnIdleSource(fn func(lastEventTime time.Time)) {
+	if fn == nil {
+		return
+	}
+	s.idleMu.Lock()
+	s.idleListeners = append(s.idleListeners, fn)
+	s.idleMu.Unlock()
+}
+
+// notifyIdleSource fans an idle-source event out to OnIdleSource listeners.
+func (s *Streamsql) notifyIdleSource(lastEventTime time.Time) {
+	s.idleMu.Lock()
+	listeners := append([]func(time.Time){}, s.idleListeners...)
+	s.idleMu.Unlock()
+	for _, fn := range listeners {
+		fn(lastEventTime)
+	}
+}
+
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// queryDeadLetterDir returns the dead-letter directory of the i-th of n
+// statements: the configured directory itself for a single statement, and a
+// subdirectory named after the statement (e.g. statement-2, stage-1)
+// otherwise, so that concurrently running queries never share a file.
+func (s *Streamsql) queryDeadLetterDir(kind string, i, n int) string {
+	if s.deadLetterDir == "" || n <= 1 {
+		return s.deadLetterDir
+	}
+	return filepath.Join(s.deadLetterDir, fmt.Sprintf("%s-%d", kind, i+1))
+}
+
+// buildQuery parses a single statement, resolving window parameters from params
+// (may be nil), and creates its (not yet started) stream processor writing
+// dead letters to deadLetterDir (empty disables them).
+func (s *Streamsql) buildQuery(sql string, params map[string]interface{}, deadLetterDir string) (*stream.Stream, []string, error) {
+	// Parse SQL statement
+	config, condition, err := rsql.ParseWithParams(sql, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SQL parsing failed: %w", err)
+	}
 
 	// Inject the per-instance logger into the stream pipeline.
 	config.Logger = s.log
@@ -159,6 +571,75 @@ func (s *Streamsql) Execute(sql string) error {
 	// 分析函数分区上限（≤0 时引擎用默认值）。
 	config.AnalyticMaxPartitions = s.analyticMaxPartitions
 
+	// 聚合结果输出形态（空值为 wide）。
+	config.OutputShape = s.outputShape
+
+	// 窗口结果派发粒度（空值为 window）。
+	config.EmitGranularity = s.emitGranularity
+
+	// 滑动窗口打开窗口数上限（≤0 不限）。
+	if s.maxOpenWindows > 0 {
+		config.WindowConfig.MaxOpenWindows = s.maxOpenWindows
+		config.WindowConfig.OpenWindowPolicy = s.openWindowPolicy
+	}
+
+	// 按分组键独立计时的滚动窗口。
+	config.WindowConfig.PerKeyClock = s.perKeyWindowClock
+
+	// 按分组键独立的事件时间水位线。
+	config.WindowConfig.PerKeyWatermark = s.perKeyWatermark
+
+	// 死信目录（空表示不持久化被拒绝的记录），多语句时每条语句各用一个子目录。
+	config.DeadLetterDir = deadLetterDir
+
+	// 表达式求值时限（≤0 不限时）。
+	config.ExpressionTimeout = s.expressionTimeout
+
+	// 数据质量计数列。
+	config.QualityCounts = s.qualityCounts
+
+	// 分组最小行数（≤0 不过滤）。
+	config.MinGroupCount = s.minGroupCount
+
+	// 数值聚合的 NaN/Inf 处理策略。
+	config.NonFinitePolicy = s.nonFinitePolicy
+
+	// 缓冲型聚合的每分组缓冲上限与超限策略。
+	config.MaxAggregateBufferPerGroup = s.maxAggregateBuffer
+	config.AggregateBufferPolicy = s.aggregateBufferPolicy
+
+	// 相邻窗口结果压缩。
+	config.CompactUnchangedWindows = s.compactUnchangedWindows
+
+	// 投影字段求值错误的处理策略。
+	config.ProjectionErrorPolicy = s.projectionErrorPolicy
+
+	// 投影表达式求值出错时的替代值。
+	config.ExpressionFallback = s.expressionFallback
+
+	// 非聚合结果行的批量派发间隔。
+	config.TransformFlushInterval = s.transformFlushInterval
+
+	// 非窗口 GROUP BY 的快照间隔；其他查询没有分组状态，不设置。
+	if !config.NeedWindow && config.Mode == types.ExecDirect && len(config.GroupFields) > 0 {
+		config.StateFlushInterval = s.stateFlushInterval
+	}
+
+	// GROUP BY 字段为 NULL 时的分组策略。
+	config.GroupNullPolicy = s.groupNullPolicy
+
+	// ISO 窗口边界的时区。
+	config.TimeZone = s.timeZone
+
+	// 窗口结果历史（用于下游故障恢复后重放）。
+	config.WindowHistorySize = s.windowHistory
+
+	// 结果主键（upsert 标记）。
+	config.PrimaryKey = s.primaryKey
+
+	// 事件时间窗口数据源空闲通知（回调时读取当前监听者，Execute 之后注册同样生效）。
+	config.WindowConfig.OnIdleSource = s.notifyIdleSource
+
 	// Create stream processor based on performance mode
 	var streamInstance *stream.Stream
 
@@ -178,24 +659,16 @@ func (s *Streamsql) Execute(sql string) error {
 	}
 
 	if err != nil {
-		// Reset executed flag on error
-		atomic.StoreInt32(&s.executed, 0)
-		return fmt.Errorf("failed to create stream processor: %w", err)
+		return nil, nil, fmt.Errorf("failed to create stream processor: %w", err)
 	}
 
-	s.stream = streamInstance
-
 	// Register filter condition
-	if err = s.stream.RegisterFilter(condition); err != nil {
-		// Reset executed flag on error
-		atomic.StoreInt32(&s.executed, 0)
-		return fmt.Errorf("failed to register filter condition: %w", err)
+	if err = streamInstance.RegisterFilter(condition); err != nil {
+		streamInstance.Stop()
+		return nil, nil, fmt.Errorf("failed to register filter condition: %w", err)
 	}
 
-	// Start stream processing
-	s.stream.Start()
-
-	return nil
+	return streamInstance, config.FieldOrder, nil
 }
 
 // Emit adds data to the stream processing pipeline.
@@ -221,19 +694,178 @@ func (s *Streamsql) Execute(sql string) error {
 //	    "page": "/home",
 //	})
 func (s *Streamsql) Emit(data map[string]interface{}) {
-	if s.stream == nil {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	in := s.input()
+	if in == nil {
+		return
+	}
+	if !s.validateRow(data) {
+		return
+	}
+	in.Emit(data)
+	s.emitToExtraQueries(data)
+}
+
+// emitToExtraQueries feeds a copy of data to every query after the first
+// (multi-statement Execute). The caller holds streamMu.
+func (s *Streamsql) emitToExtraQueries(data map[string]interface{}) {
+	for _, q := range s.extraQueries() {
+		q.Emit(copyRow(data))
+	}
+}
+
+// validateRow checks row against the schema (WithSchema); a failing row is
+// counted, logged (throttled) and dead-lettered. The caller holds streamMu.
+func (s *Streamsql) validateRow(row map[string]interface{}) bool {
+	if s.schemaValidator == nil {
+		return true
+	}
+	if err := s.schemaValidator.Validate(row); err != nil {
+		n := atomic.AddInt64(&s.schemaDropped, 1)
+		if n == 1 || n%1000 == 0 {
+			s.log.Warn("schema validation failed, dropping row (total %d): %v", n, err)
+		}
+		s.input().DeadLetter(row, "schema validation failed: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// EmitMany adds a batch of records to the stream processing pipeline in a
+// single channel operation. It is equivalent to calling Emit for each record in
+// order, but reduces channel contention for high-frequency or bursty producers.
+//
+// Records within the batch keep their order and each is assigned to windows as
+// if emitted individually. Rows failing schema validation (WithSchema) are
+// dropped individually; the rest of the batch is still enqueued. Under the drop
+// strategy a full buffer drops the whole batch.
+//
+// Example:
+//
+//	ssql.EmitMany([]map[string]interface{}{
+//	    {"deviceId": "sensor001", "temperature": 25.5},
+//	    {"deviceId": "sensor002", "temperature": 26.1},
+//	})
+func (s *Streamsql) EmitMany(data []map[string]interface{}) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	in := s.input()
+	if in == nil || len(data) == 0 {
 		return
 	}
 	if s.schemaValidator != nil {
-		if err := s.schemaValidator.Validate(data); err != nil {
-			n := atomic.AddInt64(&s.schemaDropped, 1)
-			if n == 1 || n%1000 == 0 {
-				s.log.Warn("schema validation failed, dropping row (total %d): %v", n, err)
+		valid := make([]map[string]interface{}, 0, len(data))
+		for _, row := range data {
+			if s.validateRow(row) {
+				valid = append(valid, row)
 			}
-			return
 		}
+		data = valid
+	}
+	in.EmitMany(data)
+	for _, q := range s.extraQueries() {
+		batch := make([]map[string]interface{}, len(data))
+		for i, row := range data {
+			batch[i] = copyRow(row)
+		}
+		q.EmitMany(batch)
 	}
-	s.stream.Emit(data)
+}
+
+// EmitBatch pushes records in order through the same path as Emit (schema
+// validation, timestamp extraction, WHERE, windows) and returns how many were
+// accepted. It is meant for loading historical data: under the "block"
+// overflow strategy (WithOverflowStrategy) it waits for buffer space for every
+// record, so batches larger than the buffer are paced by processing rather
+// than dropped. Under "drop" or "expand", or when a block times out, it stops
+// at the first record that does not fit and returns an error; rows[accepted:]
+// were not consumed and may be retried. Rows rejected by schema validation
+// count as accepted, as they are handled (dead-lettered), not retried.
+//
+// With several statements (multi-statement Execute) each record goes to the
+// statements in order and counts as accepted only when all of them took it.
+// The first statement that does not take a record stops the batch and its
+// error (prefixed with the statement number) is returned; that record has
+// already reached the statements before it, so retrying it delivers it to
+// those statements again.
+//
+// Example:
+//
+//	for len(rows) > 0 {
+//	    n, err := ssql.EmitBatch(rows)
+//	    rows = rows[n:]
+//	    if err != nil {
+//	        time.Sleep(10 * time.Millisecond)
+//	    }
+//	}
+func (s *Streamsql) EmitBatch(rows []map[string]interface{}) (accepted int, err error) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	in := s.input()
+	if in == nil {
+		return 0, fmt.Errorf("stream not initialized")
+	}
+	for _, row := range rows {
+		if !s.validateRow(row) {
+			accepted++ // dead-lettered, not retried
+			continue
+		}
+		if _, err := in.EmitBatch([]map[string]interface{}{row}); err != nil {
+			return accepted, err
+		}
+		for i, q := range s.extraQueries() {
+			if _, err := q.EmitBatch([]map[string]interface{}{copyRow(row)}); err != nil {
+				return accepted, fmt.Errorf("statement %d: %w", i+2, err)
+			}
+		}
+		accepted++
+	}
+	return accepted, nil
+}
+
+// current returns the active query (nil before Execute).
+func (s *Streamsql) current() *stream.Stream {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	return s.stream
+}
+
+// input returns the query Emit feeds: the first stage of a pipeline,
+// otherwise the first query (nil before Execute). The caller holds streamMu.
+func (s *Streamsql) input() *stream.Stream {
+	if len(s.pipeline) > 0 {
+		return s.pipeline[0]
+	}
+	return s.stream
+}
+
+// allQueries returns every query in data-flow order: the pipeline stages, or
+// the statements of Execute. The caller holds streamMu.
+func (s *Streamsql) allQueries() []*stream.Stream {
+	if len(s.pipeline) > 0 {
+		return s.pipeline
+	}
+	return s.queries
+}
+
+// extraQueries returns the queries after the first one (multi-statement Execute).
+// The caller holds streamMu.
+func (s *Streamsql) extraQueries() []*stream.Stream {
+	if len(s.queries) <= 1 {
+		return nil
+	}
+	return s.queries[1:]
+}
+
+// copyRow shallow-copies an input row so each query of a multi-statement
+// Execute owns its map (pipelines may inject computed keys in place).
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		cp[k] = v
+	}
+	return cp
 }
 
 // EmitSync processes data synchronously, returning results immediately.
@@ -245,7 +877,12 @@ func (s *Streamsql) Emit(data map[string]interface{}) {
 //
 // Returns:
 //   - map[string]interface{}: Processed result data, returns nil if filter conditions don't match
-//   - error: Processing error
+//     or the row falls outside OFFSET/LIMIT (rows are counted together with EmitSyncRows)
+//   - error: Processing error; stream.ErrPaused while the instance is paused
+//
+// With several statements (multi-statement Execute) the result is the first
+// query's; the other queries receive the record as with Emit and deliver their
+// results to their own sinks asynchronously.
 //
 // Examples:
 //
@@ -260,25 +897,71 @@ func (s *Streamsql) Emit(data map[string]interface{}) {
 //	    fmt.Printf("Processing result: %v\n", result)
 //	}
 func (s *Streamsql) EmitSync(data map[string]interface{}) (map[string]interface{}, error) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	if err := s.checkSync(data); err != nil {
+		return nil, err
+	}
+	// the copy is taken now, before ProcessSync may add computed keys to data
+	defer s.emitToExtraQueries(copyRow(data))
+	return s.stream.ProcessSync(data)
+}
+
+// EmitSyncRows processes data synchronously like EmitSync and returns every
+// projected row (after WHERE and SELECT) without going through channels.
+// A record removed by WHERE returns nil; a field using unnest() returns one
+// row per element, ordered by ORDER BY and limited by OFFSET/LIMIT, which count
+// the rows returned by EmitSync and EmitSyncRows together. Errors in
+// the WHERE condition or the projection are returned instead of being logged.
+// As with EmitSync, the rows are the first query's and the other statements
+// of a multi-statement Execute receive the record as with Emit.
+//
+// Example:
+//
+//	rows, err := ssql.EmitSyncRows(map[string]interface{}{"deviceId": "sensor001", "temperature": 25.5})
+//	if err != nil {
+//	    log.Printf("processing error: %v", err)
+//	}
+//	for _, row := range rows {
+//	    fmt.Println(row)
+//	}
+func (s *Streamsql) EmitSyncRows(data map[string]interface{}) ([]map[string]interface{}, error) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	if err := s.checkSync(data); err != nil {
+		return nil, err
+	}
+	// the copy is taken now, before ProcessSyncRows may add computed keys to data
+	defer s.emitToExtraQueries(copyRow(data))
+	return s.stream.ProcessSyncRows(data)
+}
+
+// checkSync rejects synchronous processing for queries that span several
+// records and validates data against the schema. Callers hold streamMu.
+func (s *Streamsql) checkSync(data map[string]interface{}) error {
 	if s.stream == nil {
-		return nil, fmt.Errorf("stream not initialized")
+		return fmt.Errorf("stream not initialized")
+	}
+	if len(s.pipeline) > 1 {
+		return fmt.Errorf("synchronous mode does not support pipelines, use Emit() method")
 	}
 
 	// Check if it's a non-aggregation query
 	if s.stream.IsAggregationQuery() {
-		return nil, fmt.Errorf("synchronous mode only supports non-aggregation queries, use Emit() method for aggregation queries")
+		return fmt.Errorf("synchronous mode only supports non-aggregation queries, use Emit() method for aggregation queries")
 	}
 	if s.stream.IsCEPQuery() {
-		return nil, fmt.Errorf("synchronous mode does not support MATCH_RECOGNIZE, use Emit() method")
+		return fmt.Errorf("synchronous mode does not support MATCH_RECOGNIZE, use Emit() method")
 	}
 
 	if s.schemaValidator != nil {
 		if err := s.schemaValidator.Validate(data); err != nil {
 			atomic.AddInt64(&s.schemaDropped, 1)
-			return nil, fmt.Errorf("schema validation failed: %w", err)
+			s.input().DeadLetter(data, "schema validation failed: "+err.Error())
+			return fmt.Errorf("schema validation failed: %w", err)
 		}
 	}
-	return s.stream.ProcessSync(data)
+	return nil
 }
 
 // SchemaDropped returns the count of rows dropped by schema validation.
@@ -288,20 +971,22 @@ func (s *Streamsql) SchemaDropped() int64 {
 
 // IsAggregationQuery checks if the current query is an aggregation query
 func (s *Streamsql) IsAggregationQuery() bool {
-	if s.stream == nil {
+	st := s.current()
+	if st == nil {
 		return false
 	}
-	return s.stream.IsAggregationQuery()
+	return st.IsAggregationQuery()
 }
 
 // IsCEPQuery reports whether the current query runs the MATCH_RECOGNIZE (CEP)
 // path. Components use it to route CEP queries to a dedicated node (Emit+AddSink),
 // since CEP — like aggregation — does not support EmitSync.
 func (s *Streamsql) IsCEPQuery() bool {
-	if s.stream == nil {
+	st := s.current()
+	if st == nil {
 		return false
 	}
-	return s.stream.IsCEPQuery()
+	return st.IsCEPQuery()
 }
 
 // Stream returns the underlying stream processor instance.
@@ -329,31 +1014,68 @@ func (s *Streamsql) IsCEPQuery() bool {
 //	        // Process result
 //	    }
 //	}()
+//
+// After ReplaceSQL the previously returned stream is retired; call Stream() again.
 func (s *Streamsql) Stream() *stream.Stream {
-	return s.stream
+	return s.current()
+}
+
+// Queries returns the stream processors created by Execute, one per statement
+// in the order they appeared. Use it to attach sinks to individual queries when
+// Execute was given several ';'-separated statements; Stream() and the
+// convenience wrappers (AddSink, ToChannel, ...) address the first query only.
+// After ExecutePipeline it returns the stages from first to last, and the
+// wrappers address the last stage.
+//
+// Example:
+//
+//	err := ssql.Execute(`
+//	    SELECT deviceId, temperature FROM stream WHERE temperature > 50;
+//	    SELECT AVG(temperature) AS avg_temp FROM stream GROUP BY TumblingWindow('5s');
+//	`)
+//	ssql.Queries()[1].AddSink(func(results []map[string]interface{}) { ... })
+func (s *Streamsql) Queries() []*stream.Stream {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	return s.allQueries()
 }
 
 // TriggerWindow manually triggers the current window to emit immediately,
 // bypassing its normal time/count trigger. Intended for tests that need a
 // window to fire deterministically, and as an explicit flush hook.
 func (s *Streamsql) TriggerWindow() {
-	if s.stream != nil && s.stream.Window != nil {
-		s.stream.Window.Trigger()
+	st := s.current()
+	if st != nil && st.Window != nil {
+		st.Window.Trigger()
 	}
 }
 
+// ReplayLastWindows re-dispatches the last n emitted window results (oldest
+// first) to the registered sinks, for recovery after a transient downstream
+// outage. It requires WithWindowHistory; n is capped at the retained history.
+// Returns the number of windows replayed.
+func (s *Streamsql) ReplayLastWindows(n int) int {
+	st := s.current()
+	if st != nil {
+		return st.ReplayLastWindows(n)
+	}
+	return 0
+}
+
 // GetStats returns stream processing statistics
 func (s *Streamsql) GetStats() map[string]int64 {
-	if s.stream != nil {
-		return s.stream.GetStats()
+	st := s.current()
+	if st != nil {
+		return st.GetStats()
 	}
 	return make(map[string]int64)
 }
 
 // GetDetailedStats returns detailed performance statistics
 func (s *Streamsql) GetDetailedStats() map[string]interface{} {
-	if s.stream != nil {
-		return s.stream.GetDetailedStats()
+	st := s.current()
+	if st != nil {
+		return st.GetDetailedStats()
 	}
 	return make(map[string]interface{})
 }
@@ -361,12 +1083,35 @@ func (s *Streamsql) GetDetailedStats() map[string]interface{} {
 // Metrics returns the underlying metrics registry, or nil before Execute.
 // Callers can inspect named counters/gauges/histograms directly via the registry.
 func (s *Streamsql) Metrics() *metrics.Registry {
-	if s.stream != nil {
-		return s.stream.MetricsRegistry()
+	st := s.current()
+	if st != nil {
+		return st.MetricsRegistry()
 	}
 	return nil
 }
 
+// PrometheusHandler serves the metrics of the running query in the Prometheus
+// text format: input/output/drop counters, buffer occupancy and utilization
+// gauges, window statistics and process memory. When Execute ran several
+// statements each query's samples carry a query="<index>" label. The handler
+// follows ReplaceSQL; mount it on an existing /metrics endpoint.
+//
+// Example:
+//
+//	http.Handle("/metrics", ssql.PrometheusHandler())
+func (s *Streamsql) PrometheusHandler() http.Handler {
+	return metrics.PrometheusHandler(stream.PrometheusNamespace, func(e *metrics.Exposition) {
+		queries := s.Queries()
+		if len(queries) == 1 {
+			queries[0].CollectPrometheus(e)
+			return
+		}
+		for i, q := range queries {
+			q.CollectPrometheus(e, metrics.Label{Name: "query", Value: strconv.Itoa(i)})
+		}
+	})
+}
+
 // Stop stops the stream processor and releases related resources.
 // After calling this method, the stream processor will stop receiving and processing new data.
 //
@@ -376,11 +1121,104 @@ func (s *Streamsql) Metrics() *metrics.Registry {
 //
 // Note: StreamSQL instance cannot be restarted after stopping, create a new instance.
 func (s *Streamsql) Stop() {
-	if s.stream != nil {
-		s.stream.Stop()
+	for _, q := range s.Queries() {
+		q.Stop()
+	}
+}
+
+// CloseInput signals that the producer has finished (end of a bounded/batch
+// input). Records already emitted are processed, all open windows are flushed —
+// including windows whose time/count trigger has not been reached — and
+// CloseInput blocks until the final results have been delivered to the sinks.
+// Emit calls after CloseInput are dropped.
+//
+// Unlike Stop, CloseInput does not tear the pipeline down: ToChannel results
+// remain readable and Stop must still be called to release resources. The wait
+// is bounded by WithCloseInputGrace; an error is returned when it elapses.
+//
+// Example:
+//
+//	for _, row := range rows {
+//	    ssql.Emit(row)
+//	}
+//	if err := ssql.CloseInput(); err != nil {
+//	    log.Printf("flush incomplete: %v", err)
+//	}
+//	ssql.Stop()
+func (s *Streamsql) CloseInput() error {
+	queries := s.Queries()
+	if len(queries) == 0 {
+		return fmt.Errorf("stream not initialized")
+	}
+	var firstErr error
+	for _, q := range queries {
+		if err := q.CloseInput(s.closeInputGrace); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Drain shuts the instance down gracefully instead of discarding in-flight
+// data as Stop does: Emit calls are dropped from now on, records already
+// emitted are processed, every open window fires one last time with the rows
+// it holds, and Drain waits for the sinks to finish before stopping the
+// pipeline. ctx bounds the wait; when it ends first the pipeline is stopped
+// anyway and the returned error wraps ctx.Err(), reporting an incomplete flush.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := ssql.Drain(ctx); err != nil {
+//	    log.Printf("drain incomplete: %v", err)
+//	}
+func (s *Streamsql) Drain(ctx context.Context) error {
+	queries := s.Queries()
+	if len(queries) == 0 {
+		return fmt.Errorf("stream not initialized")
+	}
+	var firstErr error
+	for _, q := range queries {
+		if err := q.Drain(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Pause temporarily stops processing for every query of the instance without
+// losing buffered input or window state, e.g. for maintenance. Emit keeps
+// queueing input (blocking or dropping per the overflow strategy once the
+// buffer is full), windows do not fire while paused, and EmitSync/EmitSyncRows
+// return stream.ErrPaused. See stream.Stream.Pause. No-op before Execute.
+//
+// Example:
+//
+//	ssql.Pause()
+//	defer ssql.Resume()
+//	reloadDeviceTable()
+func (s *Streamsql) Pause() {
+	for _, q := range s.Queries() {
+		q.Pause()
 	}
 }
 
+// Resume continues processing after Pause. Queued input is processed in order
+// and window timers account for the paused duration, so time-based windows
+// fire once their remaining time has elapsed instead of all at once.
+func (s *Streamsql) Resume() {
+	for _, q := range s.Queries() {
+		q.Resume()
+	}
+}
+
+// IsPaused reports whether the instance is paused.
+func (s *Streamsql) IsPaused() bool {
+	st := s.current()
+	return st != nil && st.IsPaused()
+}
+
 // AddSink directly adds result processing callback functions.
 // Convenience wrapper for Stream().AddSink() for cleaner API calls.
 //
@@ -404,8 +1242,115 @@ func (s *Streamsql) Stop() {
 //	    sendToQueue(results)
 //	})
 func (s *Streamsql) AddSink(sink func([]map[string]interface{})) {
-	if s.stream != nil {
-		s.stream.AddSink(sink)
+	st := s.current()
+	if st != nil {
+		st.AddSink(sink)
+	}
+}
+
+// AddNamedSink registers a sink under name for statements of the form
+// INSERT INTO <name> SELECT ...: each query delivers its results only to the
+// named sinks its INSERT INTO targets, so one Execute with several statements
+// can route each to its own destination. Sinks added with AddSink keep
+// receiving the first query's results whether or not it has an INSERT INTO.
+// Like AddSink, the callback runs asynchronously; call it after Execute.
+//
+// Example:
+//
+//	ssql.Execute(`INSERT INTO alertTopic SELECT deviceId, temperature FROM stream WHERE temperature > 40;
+//	              INSERT INTO archive SELECT * FROM stream`)
+//	ssql.AddNamedSink("alertTopic", sendAlert)
+//	ssql.AddNamedSink("archive", saveToDatabase)
+func (s *Streamsql) AddNamedSink(name string, sink func([]map[string]interface{})) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	for _, q := range s.queries {
+		q.AddNamedSink(name, sink)
+	}
+}
+
+// AddSampledSink adds a result callback that is invoked for every nth result
+// batch only, dropping the others; useful for debugging high-volume queries.
+// n <= 1 forwards every batch. Sampling is counted per callback and does not
+// affect sinks added with AddSink on the same stream. Like AddSink, the
+// callback runs asynchronously.
+//
+// Example:
+//
+//	// Log one batch in a hundred
+//	ssql.AddSampledSink(100, func(results []map[string]interface{}) {
+//	    log.Printf("sample: %v", results)
+//	})
+func (s *Streamsql) AddSampledSink(n int, sink func([]map[string]interface{})) {
+	st := s.current()
+	if st != nil {
+		st.AddSampledSink(n, sink)
+	}
+}
+
+// AddDedupSink adds a result callback that suppresses rows whose keyField value
+// was already delivered to it within ttl, e.g. to avoid re-sending the same
+// alert. Deduplication is per row, not per batch: each batch is forwarded with
+// its duplicate rows removed (including duplicates within the batch itself),
+// and skipped when no row remains. Rows without keyField pass through
+// un-deduplicated. ttl <= 0 forwards every row. Other sinks are unaffected.
+// Like AddSink, the callback runs asynchronously.
+//
+// Example:
+//
+//	// Alert at most once a minute per device
+//	ssql.AddDedupSink("deviceId", time.Minute, func(results []map[string]interface{}) {
+//	    sendAlerts(results)
+//	})
+func (s *Streamsql) AddDedupSink(keyField string, ttl time.Duration, sink func([]map[string]interface{})) {
+	st := s.current()
+	if st != nil {
+		st.AddDedupSink(keyField, ttl, sink)
+	}
+}
+
+// AddErrorSink registers a callback for input records whose processing failed
+// (WHERE evaluation, SELECT projection, JOIN enrichment or aggregate input
+// errors), receiving the record and the error so bad records can be routed to
+// a dead-letter queue. It applies to every query of the last Execute and is
+// kept across ReplaceSQL. Callbacks run asynchronously through a bounded queue
+// and never block the pipeline; errors arriving while the queue is full are
+// dropped and counted in GetStats as stream.ErrorSinkDropped.
+//
+// Example:
+//
+//	ssql.AddErrorSink(func(data map[string]interface{}, err error) {
+//	    dlq.Publish(data, err.Error())
+//	})
+func (s *Streamsql) AddErrorSink(sink func(data map[string]interface{}, err error)) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	for _, q := range s.allQueries() {
+		q.AddErrorSink(sink)
+	}
+}
+
+// OnBackpressure registers a callback for input overload signals, so an
+// overload can be alerted on before data loss becomes severe. The event
+// reports the overflow strategy, input buffer utilization and dropped record
+// counts. It fires when utilization reaches the buffer usage threshold
+// (PerformanceConfig.BufferConfig.UsageThreshold, 0.8 by default), when it
+// falls back to half of that, and when input records are dropped (at most once
+// per second, carrying the drops in between). It applies to every query of the
+// last Execute, including every pipeline stage, and is kept across ReplaceSQL.
+// The callback runs on the goroutine that detected the condition and should
+// return quickly.
+//
+// Example:
+//
+//	ssql.OnBackpressure(func(e stream.BackpressureEvent) {
+//	    log.Printf("%s: buffer %.0f%% full, %d dropped", e.Kind, e.Utilization*100, e.Dropped)
+//	})
+func (s *Streamsql) OnBackpressure(fn func(event stream.BackpressureEvent)) {
+	s.streamMu.RLock()
+	defer s.streamMu.RUnlock()
+	for _, q := range s.allQueries() {
+		q.OnBackpressure(fn)
 	}
 }
 
@@ -418,11 +1363,41 @@ func (s *Streamsql) AddSink(sink func([]map[string]interface{})) {
 // Note: Sync sinks are executed sequentially in the result processing goroutine.
 // Use this when order of execution matters.
 func (s *Streamsql) AddSyncSink(sink func([]map[string]interface{})) {
-	if s.stream != nil {
-		s.stream.AddSyncSink(sink)
+	st := s.current()
+	if st != nil {
+		st.AddSyncSink(sink)
 	}
 }
 
+// AddJSONSink adds a result callback that receives each result batch serialized
+// as one JSON array, so sinks forwarding to HTTP/MQ need not marshal themselves.
+// time.Time values are written as RFC3339 (with fractional seconds when
+// present), nested maps and slices as JSON objects and arrays; maps with
+// non-string keys are written with their keys formatted as strings and NaN/Inf
+// as null. A batch that still cannot be serialized is logged and skipped.
+//
+// Encoding buffers are reused across batches: jsonBytes is only valid during
+// the callback, copy it to retain it. Like AddSink, the callback runs
+// asynchronously and may be invoked concurrently.
+//
+// Example:
+//
+//	ssql.AddJSONSink(func(jsonBytes []byte) {
+//	    producer.Send(append([]byte(nil), jsonBytes...))
+//	})
+func (s *Streamsql) AddJSONSink(sink func(jsonBytes []byte)) {
+	s.AddSink(func(results []map[string]interface{}) {
+		enc := jsonEncoders.Get().(*jsonBatchEncoder)
+		defer jsonEncoders.Put(enc)
+		b, err := enc.encode(results)
+		if err != nil {
+			s.log.Error("AddJSONSink: failed to serialize result batch: %v", err)
+			return
+		}
+		sink(b)
+	})
+}
+
 // PrintTable prints results to console in table format, similar to database output.
 // Displays column names first, then data rows.
 //
@@ -453,7 +1428,10 @@ func (s *Streamsql) PrintTable() {
 // Parameters:
 //   - results: Result data of type []map[string]interface{}
 func (s *Streamsql) printTableFormat(results []map[string]interface{}) {
-	table.FormatTableData(results, s.fieldOrder)
+	s.schemaMu.Lock()
+	fieldOrder := s.fieldOrder
+	s.schemaMu.Unlock()
+	table.FormatTableData(results, fieldOrder)
 }
 
 // ToChannel returns result channel for asynchronous result retrieval.
@@ -481,8 +1459,9 @@ func (s *Streamsql) printTableFormat(results []map[string]interface{}) {
 //   - Consumer must continuously read from channel to prevent stream processing blocking
 //   - Channel transmits batch result data
 func (s *Streamsql) ToChannel() <-chan []map[string]interface{} {
-	if s.stream != nil {
-		return s.stream.GetResultsChan()
+	st := s.current()
+	if st != nil {
+		return st.GetResultsChan()
 	}
 	return nil
 }
@@ -501,34 +1480,130 @@ func (s *Streamsql) ToChannel() <-chan []map[string]interface{} {
 //	// Explicit composite key:
 //	ssql.RegisterTable("meta", rows, "deviceId", "tenant")
 func (s *Streamsql) RegisterTable(name string, rows []map[string]interface{}, keyFields ...string) (*stream.MemoryTableSource, error) {
-	if s.stream == nil {
+	st := s.current()
+	if st == nil {
 		return nil, fmt.Errorf("Execute must be called before RegisterTable")
 	}
 	if len(keyFields) == 0 {
-		derived, err := s.stream.JoinKeyFields(name)
+		derived, err := st.JoinKeyFields(name)
 		if err != nil {
 			return nil, err
 		}
 		keyFields = derived
 	}
-	return s.stream.RegisterMemoryTable(name, keyFields, rows)
+	return st.RegisterMemoryTable(name, keyFields, rows)
 }
 
 // RegisterTableSource registers a custom table source (file/DB/Redis/HTTP). The
 // implementation owns data loading, refresh, and cleanup; Lookup must be
 // concurrency-safe. Must be called after Execute.
 func (s *Streamsql) RegisterTableSource(src stream.TableSource) error {
-	if s.stream == nil {
+	st := s.current()
+	if st == nil {
 		return fmt.Errorf("Execute must be called before RegisterTableSource")
 	}
-	return s.stream.RegisterTableSource(src)
+	return st.RegisterTableSource(src)
+}
+
+// RegisterSet registers (or atomically replaces) a named string set for the
+// in_set(name, value) SQL function, e.g. WHERE in_set('allowlist', deviceId).
+// It may be called before or after Execute; rows processed after the call see
+// the new contents without reparsing SQL. Sets are process-wide, so this is a
+// package-level function rather than a Streamsql method: every instance sees
+// the same sets.
+//
+// Example:
+//
+//	streamsql.RegisterSet("allowlist", []string{"d1", "d2"})
+//	ssql.Execute("SELECT * FROM stream WHERE in_set('allowlist', deviceId)")
+//	// Later, without touching the query:
+//	streamsql.RegisterSet("allowlist", []string{"d2", "d3"})
+func RegisterSet(name string, values []string) {
+	functions.RegisterSet(name, values)
+}
+
+// UnregisterSet removes a set registered with RegisterSet and reports whether
+// it existed. in_set treats an unknown set as empty.
+func UnregisterSet(name string) bool {
+	return functions.UnregisterSet(name)
 }
 
 // UpsertTable adds or replaces a row in a previously registered in-memory table.
 // Only affects rows emitted after the call (tables are snapshots).
 func (s *Streamsql) UpsertTable(name string, row map[string]interface{}) error {
-	if s.stream == nil {
+	st := s.current()
+	if st == nil {
 		return fmt.Errorf("Execute must be called before UpsertTable")
 	}
-	return s.stream.UpsertTableRow(name, row)
+	return st.UpsertTableRow(name, row)
+}
+
+var jsonEncoders = sync.Pool{New: func() any { return newJSONBatchEncoder() }}
+
+// jsonBatchEncoder serializes result batches into a reused buffer.
+type jsonBatchEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+func newJSONBatchEncoder() *jsonBatchEncoder {
+	e := &jsonBatchEncoder{}
+	e.enc = json.NewEncoder(&e.buf)
+	e.enc.SetEscapeHTML(false)
+	return e
+}
+
+// encode returns the JSON for v (a result batch or a single row), without the
+// trailing newline the encoder writes. Values encoding/json rejects (non-string
+// map keys, NaN/Inf) are normalized and encoding retried. The bytes are only
+// valid until the next encode.
+func (e *jsonBatchEncoder) encode(v interface{}) ([]byte, error) {
+	e.buf.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		e.buf.Reset()
+		if err := e.enc.Encode(jsonSafe(v)); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonSafe copies v replacing what encoding/json cannot write: maps with
+// non-string keys get string keys, NaN/Inf become null.
+func jsonSafe(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []map[string]interface{}:
+		out := make([]interface{}, len(x))
+		for i, m := range x {
+			out[i] = jsonSafe(m)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[k] = jsonSafe(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[fmt.Sprint(k)] = jsonSafe(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, val := range x {
+			out[i] = jsonSafe(val)
+		}
+		return out
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return nil
+		}
+	}
+	return v
 }
@@ -107,6 +107,55 @@ func BenchmarkStreamSQL(b *testing.B) {
 	}
 }
 
+// BenchmarkEmitManyVsEmit 对比 EmitMany 批量入队与逐条 Emit 的入队吞吐
+func BenchmarkEmitManyVsEmit(b *testing.B) {
+	const batchSize = 100
+	sql := "SELECT deviceId, temperature FROM stream WHERE temperature > 20"
+	testData := generateOptimizedTestData(batchSize)
+
+	run := func(b *testing.B, emit func(ssql *Streamsql)) {
+		ssql := New(WithOverflowStrategy("block", 0))
+		defer ssql.Stop()
+		if err := ssql.Execute(sql); err != nil {
+			b.Fatalf("SQL执行失败: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			for {
+				select {
+				case <-ssql.Stream().GetResultsChan():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			emit(ssql)
+		}
+		elapsed := time.Since(start)
+		b.StopTimer()
+		b.ReportMetric(float64(b.N*batchSize)/elapsed.Seconds(), "records/sec")
+	}
+
+	b.Run("Emit", func(b *testing.B) {
+		run(b, func(ssql *Streamsql) {
+			for _, row := range testData {
+				ssql.Emit(row)
+			}
+		})
+	})
+	b.Run("EmitMany", func(b *testing.B) {
+		run(b, func(ssql *Streamsql) {
+			ssql.EmitMany(testData)
+		})
+	})
+}
+
 // BenchmarkConfigurationOptimized 优化后的配置对比基准测试
 func BenchmarkConfigurationOptimized(b *testing.B) {
 	configs := []struct {
@@ -23,14 +23,22 @@ package streamsql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"net/http/httptest"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/rulego/streamsql/functions"
+	"github.com/rulego/streamsql/stream"
 	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/utils/cast"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -423,6 +431,893 @@ func TestStreamSQLEmitSync(t *testing.T) {
 	})
 }
 
+// TestStreamSQLEmitMany 测试批量 Emit：批内保序、窗口归属正确、schema 逐行过滤
+func TestStreamSQLEmitMany(t *testing.T) {
+	t.Run("emit many with uninitialized stream", func(t *testing.T) {
+		ssql := New()
+		assert.NotPanics(t, func() {
+			ssql.EmitMany([]map[string]any{{"id": 1}})
+		})
+	})
+
+	t.Run("non-aggregation keeps batch order", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream WHERE id >= 0"))
+
+		var mu sync.Mutex
+		var got []any
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				got = append(got, r["id"])
+			}
+		})
+
+		batch := make([]map[string]any, 0, 100)
+		for i := 0; i < 100; i++ {
+			batch = append(batch, map[string]any{"id": i})
+		}
+		ssql.EmitMany(batch)
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(got) == 100
+		}, 2*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		for i, v := range got {
+			assert.Equal(t, i, v)
+		}
+		assert.Equal(t, int64(100), ssql.GetStats()["input_count"])
+	})
+
+	t.Run("aggregation assigns batch to window", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) as cnt, SUM(v) as total FROM stream GROUP BY deviceId, CountingWindow(4)"))
+
+		resultChan := make(chan []map[string]any, 4)
+		ssql.AddSink(func(results []map[string]any) {
+			resultChan <- results
+		})
+
+		ssql.EmitMany([]map[string]any{
+			{"deviceId": "a", "v": 1},
+			{"deviceId": "a", "v": 2},
+			{"deviceId": "a", "v": 3},
+			{"deviceId": "a", "v": 4},
+		})
+
+		select {
+		case results := <-resultChan:
+			require.Len(t, results, 1)
+			assert.Equal(t, float64(4), results[0]["cnt"])
+			assert.Equal(t, float64(10), results[0]["total"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("window did not fire for EmitMany batch")
+		}
+	})
+}
+
+// TestStreamSQLMultiStatement 测试带注释、多条 ';' 分隔语句的 Execute
+func TestStreamSQLMultiStatement(t *testing.T) {
+	t.Run("each statement becomes a query fed by Emit", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		err := ssql.Execute(`
+			-- 高温告警
+			SELECT deviceId, temperature FROM stream WHERE temperature > 50;
+			/* 全量透传，注释中的 ; 不分隔语句 */
+			SELECT deviceId FROM stream WHERE deviceId != 'a;--b';
+		`)
+		require.NoError(t, err)
+		require.Len(t, ssql.Queries(), 2)
+		assert.Same(t, ssql.Stream(), ssql.Queries()[0])
+
+		var mu sync.Mutex
+		var hot, all []any
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				hot = append(hot, r["deviceId"])
+			}
+		})
+		ssql.Queries()[1].AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				all = append(all, r["deviceId"])
+			}
+		})
+
+		ssql.Emit(map[string]any{"deviceId": "d1", "temperature": 60.0})
+		ssql.Emit(map[string]any{"deviceId": "d2", "temperature": 20.0})
+		ssql.Emit(map[string]any{"deviceId": "a;--b", "temperature": 70.0})
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(hot) == 2 && len(all) == 2
+		}, 2*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []any{"d1", "a;--b"}, hot)
+		assert.Equal(t, []any{"d1", "d2"}, all)
+	})
+
+	t.Run("EmitSync feeds every statement", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT v FROM stream; SELECT v * 2 AS w FROM stream"))
+
+		var mu sync.Mutex
+		var doubled []any
+		ssql.Queries()[1].AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				doubled = append(doubled, r["w"])
+			}
+		})
+
+		for i := 1; i <= 3; i++ {
+			var r map[string]any
+			var err error
+			if i == 3 {
+				var rows []map[string]any
+				rows, err = ssql.EmitSyncRows(map[string]any{"v": i})
+				require.Len(t, rows, 1)
+				r = rows[0]
+			} else {
+				r, err = ssql.EmitSync(map[string]any{"v": i})
+			}
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"v": i}, r, "the first statement's result is returned")
+		}
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(doubled) == 3
+		}, 2*time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ElementsMatch(t, []any{2.0, 4.0, 6.0}, doubled)
+	})
+
+	t.Run("single statement with trailing semicolon", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream -- 注释\n;"))
+		assert.Len(t, ssql.Queries(), 1)
+	})
+
+	t.Run("invalid statement fails the whole Execute", func(t *testing.T) {
+		ssql := New()
+		err := ssql.Execute("SELECT id FROM stream; SELECT FROM")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "statement 2")
+		assert.Nil(t, ssql.Stream())
+		// executed 标志已复位，可重新执行
+		require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+		ssql.Stop()
+	})
+}
+
+// TestStreamSQLInsertInto 测试 INSERT INTO 把各语句的结果路由到同名具名 sink
+func TestStreamSQLInsertInto(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(`
+		INSERT INTO alertTopic SELECT deviceId, temperature FROM stream WHERE temperature > 40;
+		INSERT INTO archive SELECT deviceId FROM stream
+	`))
+
+	var mu sync.Mutex
+	got := map[string][]any{}
+	record := func(name string) func([]map[string]any) {
+		return func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				got[name] = append(got[name], r["deviceId"])
+			}
+		}
+	}
+	ssql.AddNamedSink("alertTopic", record("alertTopic"))
+	ssql.AddNamedSink("archive", record("archive"))
+	ssql.AddNamedSink("unused", record("unused"))
+	// 未命名 sink 照常接收第一条语句的全部结果
+	ssql.AddSink(record("unnamed"))
+
+	ssql.Emit(map[string]any{"deviceId": "d1", "temperature": 45.0})
+	ssql.Emit(map[string]any{"deviceId": "d2", "temperature": 20.0})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got["alertTopic"]) == 1 && len(got["archive"]) == 2 && len(got["unnamed"]) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []any{"d1"}, got["alertTopic"])
+	assert.ElementsMatch(t, []any{"d1", "d2"}, got["archive"])
+	assert.Equal(t, []any{"d1"}, got["unnamed"])
+	assert.Empty(t, got["unused"])
+}
+
+// TestStreamSQLCloseInput 测试有界输入：CloseInput 冲刷未触发窗口并等待 sink 完成
+func TestStreamSQLCloseInput(t *testing.T) {
+	t.Run("uninitialized stream", func(t *testing.T) {
+		assert.Error(t, New().CloseInput())
+	})
+
+	t.Run("flushes tumbling window before its timer", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS cnt, SUM(v) AS total FROM stream GROUP BY deviceId, TumblingWindow('1h')"))
+
+		var mu sync.Mutex
+		got := map[string][2]float64{}
+		ssql.AddSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				got[r["deviceId"].(string)] = [2]float64{cast.ToFloat64(r["cnt"]), cast.ToFloat64(r["total"])}
+			}
+		})
+
+		for i := 1; i <= 4; i++ {
+			ssql.Emit(map[string]any{"deviceId": "a", "v": float64(i)})
+		}
+		ssql.Emit(map[string]any{"deviceId": "b", "v": 10.0})
+
+		require.NoError(t, ssql.CloseInput())
+		// CloseInput 返回时 sink 已收到最终窗口，无需等待
+		mu.Lock()
+		assert.Equal(t, map[string][2]float64{"a": {4, 10}, "b": {1, 10}}, got)
+		mu.Unlock()
+
+		// 关闭输入后的数据被丢弃
+		ssql.Emit(map[string]any{"deviceId": "c", "v": 1.0})
+		assert.Equal(t, int64(1), ssql.GetStats()["input_dropped_count"])
+	})
+
+	t.Run("counting window emits partial group", func(t *testing.T) {
+		ssql := New(WithCloseInputGrace(2 * time.Second))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT COUNT(*) AS cnt FROM stream GROUP BY CountingWindow(10)"))
+
+		var mu sync.Mutex
+		var counts []float64
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				counts = append(counts, cast.ToFloat64(r["cnt"]))
+			}
+		})
+		batch := make([]map[string]any, 13)
+		for i := range batch {
+			batch[i] = map[string]any{"id": i}
+		}
+		ssql.EmitMany(batch)
+
+		require.NoError(t, ssql.CloseInput())
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []float64{10, 3}, counts)
+	})
+
+	t.Run("non-aggregation drains queued rows", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+
+		var n int64
+		ssql.AddSink(func(results []map[string]any) {
+			atomic.AddInt64(&n, int64(len(results)))
+		})
+		for i := 0; i < 200; i++ {
+			ssql.Emit(map[string]any{"id": i})
+		}
+		require.NoError(t, ssql.CloseInput())
+		assert.Equal(t, int64(200), atomic.LoadInt64(&n))
+	})
+}
+
+// TestStreamSQLDrain 测试优雅关闭：Drain 冲刷未触发窗口、等待 sink 完成后停止流
+func TestStreamSQLDrain(t *testing.T) {
+	t.Run("uninitialized stream", func(t *testing.T) {
+		assert.Error(t, New().Drain(context.Background()))
+	})
+
+	t.Run("flushes open windows then stops", func(t *testing.T) {
+		ssql := New()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS cnt FROM stream GROUP BY deviceId, TumblingWindow('1h')"))
+
+		var mu sync.Mutex
+		got := map[string]float64{}
+		ssql.AddSink(func(results []map[string]any) {
+			time.Sleep(20 * time.Millisecond) // sink 较慢，Drain 仍需等待其完成
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				got[r["deviceId"].(string)] = cast.ToFloat64(r["cnt"])
+			}
+		})
+		for i := 0; i < 3; i++ {
+			ssql.Emit(map[string]any{"deviceId": "a"})
+		}
+		ssql.Emit(map[string]any{"deviceId": "b"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, ssql.Drain(ctx))
+		mu.Lock()
+		assert.Equal(t, map[string]float64{"a": 3, "b": 1}, got)
+		mu.Unlock()
+
+		// Drain 之后流已停止，不再接收数据
+		ssql.Emit(map[string]any{"deviceId": "c"})
+		assert.Equal(t, int64(1), ssql.GetStats()["input_dropped_count"])
+		assert.Error(t, ssql.Drain(ctx))
+	})
+
+	t.Run("deadline exceeded reports incomplete flush", func(t *testing.T) {
+		ssql := New()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+		ssql.AddSink(func(results []map[string]any) {
+			time.Sleep(300 * time.Millisecond)
+		})
+		ssql.Emit(map[string]any{"id": 1})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := ssql.Drain(ctx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), err.Error())
+	})
+}
+
+// TestStreamSQLEmitBatch 测试 EmitBatch：block 策略按处理速度背压，drop 策略返回已接收的行数
+func TestStreamSQLEmitBatch(t *testing.T) {
+	perf := func(strategy string, dataChannelSize int) Option {
+		config := types.DefaultPerformanceConfig()
+		config.BufferConfig.DataChannelSize = dataChannelSize
+		config.OverflowConfig.Strategy = strategy
+		return WithCustomPerformance(config)
+	}
+	rows := func(n int) []map[string]any {
+		out := make([]map[string]any, n)
+		for i := range out {
+			out[i] = map[string]any{"deviceId": fmt.Sprintf("d%d", i%3), "v": i}
+		}
+		return out
+	}
+
+	t.Run("uninitialized stream", func(t *testing.T) {
+		_, err := New().EmitBatch(rows(1))
+		assert.Error(t, err)
+	})
+
+	t.Run("block strategy accepts a batch larger than the buffer", func(t *testing.T) {
+		ssql := New(perf(types.OverflowStrategyBlock, 4))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS cnt FROM stream WHERE v >= 10 GROUP BY deviceId, TumblingWindow('1h')"))
+		var mu sync.Mutex
+		total := 0.0
+		ssql.AddSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				total += cast.ToFloat64(r["cnt"])
+			}
+		})
+
+		accepted, err := ssql.EmitBatch(rows(500))
+		require.NoError(t, err)
+		assert.Equal(t, 500, accepted)
+		require.NoError(t, ssql.CloseInput())
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, 490.0, total, "rows go through WHERE like Emit")
+		assert.Equal(t, int64(0), ssql.GetStats()["input_dropped_count"])
+	})
+
+	t.Run("drop strategy returns the accepted prefix", func(t *testing.T) {
+		ssql := New(perf(types.OverflowStrategyDrop, 4))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId FROM stream"))
+		release := make(chan struct{})
+		var once sync.Once
+		defer once.Do(func() { close(release) })
+		ssql.AddSyncSink(func([]map[string]any) { <-release }) // 阻塞处理 goroutine
+
+		batch := rows(50)
+		accepted, err := ssql.EmitBatch(batch)
+		require.Error(t, err)
+		assert.Less(t, accepted, len(batch))
+		assert.Greater(t, accepted, 0)
+		assert.Equal(t, int64(0), ssql.GetStats()["input_dropped_count"], "rejected rows are returned, not dropped")
+
+		once.Do(func() { close(release) })
+		require.Eventually(t, func() bool {
+			n, _ := ssql.EmitBatch(batch[accepted : accepted+1])
+			return n == 1
+		}, 2*time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("a later statement that drops the row stops the batch", func(t *testing.T) {
+		ssql := New(perf(types.OverflowStrategyDrop, 4))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId FROM stream; SELECT v FROM stream"))
+		release := make(chan struct{})
+		var once sync.Once
+		defer once.Do(func() { close(release) })
+		ssql.Queries()[1].AddSyncSink(func([]map[string]any) { <-release }) // 只阻塞第二条语句
+
+		batch := rows(50)
+		accepted, err := ssql.EmitBatch(batch)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "statement 2")
+		assert.Less(t, accepted, len(batch))
+		assert.Greater(t, accepted, 0)
+	})
+}
+
+// TestStreamSQLAggregationShards 测试分片聚合与单线程聚合结果一致
+func TestStreamSQLAggregationShards(t *testing.T) {
+	run := func(opts ...Option) map[string][2]float64 {
+		ssql := New(opts...)
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS cnt, SUM(v) AS total FROM stream GROUP BY deviceId, TumblingWindow('1h')"))
+
+		var mu sync.Mutex
+		got := map[string][2]float64{}
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				got[r["deviceId"].(string)] = [2]float64{cast.ToFloat64(r["cnt"]), cast.ToFloat64(r["total"])}
+			}
+		})
+		for i := 0; i < 500; i++ {
+			ssql.Emit(map[string]any{"deviceId": fmt.Sprintf("d%02d", i%50), "v": float64(i)})
+		}
+		require.NoError(t, ssql.CloseInput())
+		mu.Lock()
+		defer mu.Unlock()
+		return got
+	}
+
+	single := run()
+	require.Len(t, single, 50)
+	assert.Equal(t, single, run(WithAggregationShards(4)))
+}
+
+// TestStreamSQLInSet 测试运行时更新集合后 WHERE in_set 过滤随之变化
+func TestStreamSQLInSet(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	defer UnregisterSet("allowlist_test")
+	RegisterSet("allowlist_test", []string{"d1"})
+	require.NoError(t, ssql.Execute("SELECT deviceId FROM stream WHERE in_set('allowlist_test', deviceId)"))
+
+	pass := func(deviceId string) bool {
+		result, err := ssql.EmitSync(map[string]any{"deviceId": deviceId})
+		require.NoError(t, err)
+		return result != nil
+	}
+	assert.True(t, pass("d1"))
+	assert.False(t, pass("d2"))
+
+	RegisterSet("allowlist_test", []string{"d2", "d3"})
+	assert.False(t, pass("d1"))
+	assert.True(t, pass("d2"))
+	assert.True(t, pass("d3"))
+
+	// 集合为进程级：其他实例看到同一份内容
+	other := New()
+	defer other.Stop()
+	require.NoError(t, other.Execute("SELECT deviceId FROM stream WHERE in_set('allowlist_test', deviceId)"))
+	result, err := other.EmitSync(map[string]any{"deviceId": "d3"})
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+
+	// 删除后视为空集
+	assert.True(t, UnregisterSet("allowlist_test"))
+	assert.False(t, pass("d2"))
+}
+
+// TestStreamSQLOutputShape 测试多聚合查询的 wide 与 long 输出形态
+func TestStreamSQLOutputShape(t *testing.T) {
+	run := func(opts ...Option) []map[string]any {
+		ssql := New(opts...)
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS cnt, SUM(v) AS total, MAX(v) AS peak FROM stream GROUP BY deviceId, TumblingWindow('1h') ORDER BY deviceId"))
+
+		var mu sync.Mutex
+		var rows []map[string]any
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				delete(r, "window_id")
+				rows = append(rows, r)
+			}
+		})
+		ssql.Emit(map[string]any{"deviceId": "a", "v": 1.0})
+		ssql.Emit(map[string]any{"deviceId": "a", "v": 3.0})
+		ssql.Emit(map[string]any{"deviceId": "b", "v": 5.0})
+		require.NoError(t, ssql.CloseInput())
+		mu.Lock()
+		defer mu.Unlock()
+		return rows
+	}
+
+	assert.Equal(t, []map[string]any{
+		{"deviceId": "a", "cnt": 2.0, "total": 4.0, "peak": 3.0},
+		{"deviceId": "b", "cnt": 1.0, "total": 5.0, "peak": 5.0},
+	}, run(WithOutputShape(types.OutputShapeWide)))
+
+	assert.Equal(t, []map[string]any{
+		{"deviceId": "a", "metric": "cnt", "value": 2.0},
+		{"deviceId": "a", "metric": "total", "value": 4.0},
+		{"deviceId": "a", "metric": "peak", "value": 3.0},
+		{"deviceId": "b", "metric": "cnt", "value": 1.0},
+		{"deviceId": "b", "metric": "total", "value": 5.0},
+		{"deviceId": "b", "metric": "peak", "value": 5.0},
+	}, run(WithOutputShape(types.OutputShapeLong)))
+
+	assert.Error(t, New(WithOutputShape("tall")).Execute("SELECT COUNT(*) AS cnt FROM stream GROUP BY TumblingWindow('1h')"))
+}
+
+// TestStreamSQLOnSchemaChange 测试输出列变化时通知回调
+func TestStreamSQLOnSchemaChange(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+
+	var got [][]string
+	ssql.OnSchemaChange(func(columns []string) {
+		got = append(got, columns)
+	})
+	require.NoError(t, ssql.Execute("SELECT deviceId, temperature AS temp FROM stream"))
+	require.Equal(t, [][]string{{"deviceId", "temp"}}, got, "Execute 安装查询时通知初始列")
+
+	// 晚注册的订阅者立即收到当前列
+	var late []string
+	ssql.OnSchemaChange(func(columns []string) { late = columns })
+	assert.Equal(t, []string{"deviceId", "temp"}, late)
+
+	// 替换为列相同的查询不通知，列变化时通知所有订阅者
+	require.NoError(t, ssql.ReplaceSQL("SELECT deviceId, temperature * 2 AS temp FROM stream", false))
+	assert.Len(t, got, 1)
+	require.NoError(t, ssql.ReplaceSQL("SELECT deviceId, humidity, COUNT(*) AS cnt FROM stream GROUP BY deviceId, TumblingWindow('5s')", false))
+	require.Len(t, got, 2)
+	assert.Equal(t, []string{"deviceId", "humidity", "cnt"}, got[1])
+	assert.Equal(t, []string{"deviceId", "humidity", "cnt"}, late)
+
+	// 回调拿到的是副本：篡改后再次替换为同列查询仍不通知
+	got[1][0] = "mutated"
+	require.NoError(t, ssql.ReplaceSQL("SELECT deviceId, humidity, COUNT(*) AS cnt FROM stream GROUP BY deviceId, TumblingWindow('10s')", false))
+	assert.Len(t, got, 2)
+}
+
+// TestStreamSQLReplaceSQL 测试运行中替换查询：已注册的 sink 继续收到结果，窗口定义不变时可保留窗口内数据
+func TestStreamSQLReplaceSQL(t *testing.T) {
+	collect := func(ssql *Streamsql) func() []map[string]any {
+		var mu sync.Mutex
+		var rows []map[string]any
+		ssql.AddSyncSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			rows = append(rows, results...)
+		})
+		return func() []map[string]any {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]map[string]any(nil), rows...)
+		}
+	}
+
+	t.Run("sinks and channel survive the swap", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, temperature FROM stream WHERE temperature > 50"))
+		var columns [][]string
+		ssql.OnSchemaChange(func(c []string) { columns = append(columns, c) })
+		rows := collect(ssql)
+		ch := ssql.ToChannel()
+
+		ssql.Emit(map[string]any{"deviceId": "a", "temperature": 60})
+		require.NoError(t, ssql.ReplaceSQL("SELECT deviceId, temperature * 2 AS t2 FROM stream", false))
+		ssql.Emit(map[string]any{"deviceId": "b", "temperature": 10})
+
+		require.Eventually(t, func() bool { return len(rows()) == 2 }, 2*time.Second, 10*time.Millisecond)
+		got := rows()
+		assert.Equal(t, "a", got[0]["deviceId"], "替换前的行由旧查询处理")
+		assert.Equal(t, float64(60), cast.ToFloat64(got[0]["temperature"]))
+		assert.Equal(t, "b", got[1]["deviceId"])
+		assert.Equal(t, float64(20), cast.ToFloat64(got[1]["t2"]), "替换后的行由新查询处理")
+		assert.Equal(t, ch, ssql.ToChannel(), "ToChannel 通道不变")
+		assert.Equal(t, [][]string{{"deviceId", "temperature"}, {"deviceId", "t2"}}, columns)
+
+		r, err := ssql.EmitSync(map[string]any{"deviceId": "c", "temperature": 1})
+		require.NoError(t, err)
+		assert.Equal(t, float64(2), cast.ToFloat64(r["t2"]))
+	})
+
+	t.Run("same window keeps buffered rows", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT COUNT(*) AS cnt FROM stream GROUP BY CountingWindow(4)"))
+		rows := collect(ssql)
+
+		ssql.EmitMany([]map[string]any{{"v": 1}, {"v": 2}})
+		require.NoError(t, ssql.ReplaceSQL("SELECT COUNT(*) AS cnt, SUM(v) AS total FROM stream GROUP BY CountingWindow(4)", true))
+		ssql.EmitMany([]map[string]any{{"v": 3}, {"v": 4}})
+
+		require.Eventually(t, func() bool { return len(rows()) == 1 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, float64(4), cast.ToFloat64(rows()[0]["cnt"]))
+		assert.Equal(t, float64(10), cast.ToFloat64(rows()[0]["total"]), "新查询覆盖替换前缓存的行")
+	})
+
+	t.Run("changed window fires the old one early", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT COUNT(*) AS cnt FROM stream GROUP BY CountingWindow(4)"))
+		rows := collect(ssql)
+
+		ssql.EmitMany([]map[string]any{{"v": 1}, {"v": 2}})
+		require.NoError(t, ssql.ReplaceSQL("SELECT COUNT(*) AS cnt, SUM(v) AS total FROM stream GROUP BY CountingWindow(2)", true))
+		require.Len(t, rows(), 1, "窗口定义变化时旧窗口提前触发")
+		assert.Equal(t, float64(2), cast.ToFloat64(rows()[0]["cnt"]))
+		assert.NotContains(t, rows()[0], "total")
+
+		ssql.EmitMany([]map[string]any{{"v": 3}, {"v": 4}})
+		require.Eventually(t, func() bool { return len(rows()) == 2 }, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, float64(7), cast.ToFloat64(rows()[1]["total"]))
+	})
+
+	t.Run("errors keep the old query", func(t *testing.T) {
+		assert.Error(t, New().ReplaceSQL("SELECT * FROM stream", false), "Execute 之前")
+
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId FROM stream"))
+		old := ssql.Stream()
+		assert.Error(t, ssql.ReplaceSQL("SELEC deviceId FROM", false))
+		assert.Error(t, ssql.ReplaceSQL("SELECT a FROM stream; SELECT b FROM stream", false))
+		assert.Same(t, old, ssql.Stream())
+		r, err := ssql.EmitSync(map[string]any{"deviceId": "a"})
+		require.NoError(t, err)
+		assert.Equal(t, "a", r["deviceId"])
+
+		multi := New()
+		defer multi.Stop()
+		require.NoError(t, multi.Execute("SELECT a FROM stream; SELECT b FROM stream"))
+		assert.Error(t, multi.ReplaceSQL("SELECT c FROM stream", false))
+	})
+}
+
+// TestStreamSQLPrometheusHandler 测试 Prometheus 文本格式导出，多语句时按 query 标签区分
+func TestStreamSQLPrometheusHandler(t *testing.T) {
+	scrape := func(ssql *Streamsql) string {
+		rec := httptest.NewRecorder()
+		ssql.PrometheusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	ssql := New()
+	defer ssql.Stop()
+	assert.NotContains(t, scrape(ssql), "streamsql_input_total", "Execute 之前只有进程指标")
+	require.NoError(t, ssql.Execute("SELECT deviceId FROM stream"))
+	ssql.Emit(map[string]any{"deviceId": "a"})
+	assert.Contains(t, scrape(ssql), "streamsql_input_total 1\n")
+
+	multi := New()
+	defer multi.Stop()
+	require.NoError(t, multi.Execute("SELECT a FROM stream; SELECT b FROM stream"))
+	multi.Emit(map[string]any{"a": 1})
+	body := scrape(multi)
+	assert.Contains(t, body, `streamsql_input_total{query="0"} 1`)
+	assert.Contains(t, body, `streamsql_input_total{query="1"} 1`)
+}
+
+func TestStreamSQLAddJSONSink(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT * FROM stream"))
+	got := make(chan string, 1)
+	ssql.AddJSONSink(func(jsonBytes []byte) { got <- string(jsonBytes) })
+
+	ts := time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC)
+	ssql.Emit(map[string]any{"ts": ts, "meta": map[string]any{"site": "a<b", "tags": []any{"x", 1}}})
+	select {
+	case body := <-got:
+		assert.JSONEq(t, `[{"ts":"2025-03-01T08:30:00Z","meta":{"site":"a<b","tags":["x",1]}}]`, body)
+		assert.NotContains(t, body, "\n", "一个批次一个 JSON 数组，不带换行")
+	case <-time.After(2 * time.Second):
+		t.Fatal("JSON sink not called")
+	}
+
+	// encoding/json 不支持的值：非字符串键的 map、NaN
+	enc := newJSONBatchEncoder()
+	b, err := enc.encode([]map[string]any{{"m": map[any]any{1: "one"}, "v": math.NaN()}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"m":{"1":"one"},"v":null}]`, string(b))
+}
+
+// seenDevicesState 记录分组内收到的设备名，用于检测跨分组状态泄漏
+type seenDevicesState struct {
+	seen  map[string]bool
+	count int
+}
+
+func (s *seenDevicesState) Add(value any) {
+	s.seen[fmt.Sprint(value)] = true
+	s.count++
+}
+
+func (s *seenDevicesState) Result() any {
+	names := make([]string, 0, len(s.seen))
+	for name := range s.seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%s#%d", strings.Join(names, ","), s.count)
+}
+
+func (s *seenDevicesState) Clone() functions.AggregatorState {
+	clone := &seenDevicesState{seen: make(map[string]bool, len(s.seen)), count: s.count}
+	for k := range s.seen {
+		clone.seen[k] = true
+	}
+	return clone
+}
+
+// TestStreamSQLStatefulCustomAggregateIsolation 有状态自定义聚合在大量分组、并行分片下互不串扰
+func TestStreamSQLStatefulCustomAggregateIsolation(t *testing.T) {
+	require.NoError(t, functions.Register(functions.NewStatefulAggregator("seen_devices", "测试", "记录分组内的设备", 1, 1,
+		func() functions.AggregatorState { return &seenDevicesState{seen: make(map[string]bool)} })))
+	defer functions.Unregister("seen_devices")
+
+	const groups, perGroup = 300, 5
+	for _, shards := range []int{1, 8} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			// 阻塞溢出策略：输入缓冲满时不丢行，否则 -race 等慢环境下计数会少
+			ssql := New(WithAggregationShards(shards), WithOverflowStrategy(types.OverflowStrategyBlock, 5*time.Second))
+			defer ssql.Stop()
+			require.NoError(t, ssql.Execute("SELECT device, seen_devices(device) AS seen FROM stream GROUP BY device, TumblingWindow('10s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')"))
+
+			var mu sync.Mutex
+			results := make(map[string][]any)
+			total := 0
+			ssql.AddSink(func(rows []map[string]any) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, r := range rows {
+					device := r["device"].(string)
+					results[device] = append(results[device], r["seen"])
+					total++
+				}
+			})
+
+			base := time.Now().UnixMilli()/10000*10000 - 60000
+			// 两个窗口各一轮：第一窗口的状态不得带入第二窗口
+			for w := int64(0); w < 2; w++ {
+				for i := 0; i < perGroup; i++ {
+					for g := 0; g < groups; g++ {
+						ssql.Emit(map[string]any{"device": fmt.Sprintf("d%03d", g), "ts": base + w*10000 + int64(i)})
+					}
+				}
+			}
+			ssql.Emit(map[string]any{"device": "trigger", "ts": base + 30000})
+
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return total >= 2*groups
+			}, 5*time.Second, 20*time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			for g := 0; g < groups; g++ {
+				device := fmt.Sprintf("d%03d", g)
+				want := fmt.Sprintf("%s#%d", device, perGroup)
+				assert.Equal(t, []any{want, want}, results[device], device)
+			}
+		})
+	}
+}
+
+// TestStreamSQLPassThroughIntegerTypes 透传列保持输入的整数类型（不被转成 float64），
+// 覆盖直连路径（SELECT *、别名、嵌套字段、WHERE）与窗口路径（GROUP BY 键、first/last_value）。
+func TestStreamSQLPassThroughIntegerTypes(t *testing.T) {
+	input := func() map[string]any {
+		return map[string]any{
+			"device": "a", "humidity": 60, "n64": int64(7), "u8": uint8(3),
+			"info": map[string]any{"level": 2},
+		}
+	}
+
+	t.Run("直连路径", func(t *testing.T) {
+		for _, sql := range []string{
+			"SELECT * FROM stream",
+			"SELECT device, humidity, n64, u8 FROM stream",
+			"SELECT device, humidity, n64, u8 FROM stream WHERE humidity > 10",
+		} {
+			s := New()
+			require.NoError(t, s.Execute(sql))
+			r, err := s.EmitSync(input())
+			s.Stop()
+			require.NoError(t, err, sql)
+			assert.IsType(t, int(0), r["humidity"], sql)
+			assert.IsType(t, int64(0), r["n64"], sql)
+			assert.IsType(t, uint8(0), r["u8"], sql)
+		}
+
+		s := New()
+		defer s.Stop()
+		require.NoError(t, s.Execute("SELECT humidity AS h, info.level AS lvl, coalesce(humidity, 0) AS c FROM stream"))
+		r, err := s.EmitSync(input())
+		require.NoError(t, err)
+		assert.Equal(t, 60, r["h"])
+		assert.Equal(t, 2, r["lvl"])
+		assert.Equal(t, 60, r["c"])
+	})
+
+	t.Run("窗口路径", func(t *testing.T) {
+		for _, opts := range [][]Option{nil, {WithAggregationShards(4)}} {
+			s := New(opts...)
+			require.NoError(t, s.Execute("SELECT humidity, n64, first_value(u8) AS fu, last_value(n64) AS ln, COUNT(*) AS c "+
+				"FROM stream GROUP BY humidity, n64, TumblingWindow('1h')"))
+			ch := make(chan []map[string]any, 1)
+			s.AddSink(func(r []map[string]any) { ch <- r })
+			s.Emit(input())
+			time.Sleep(50 * time.Millisecond)
+			s.TriggerWindow()
+			select {
+			case rows := <-ch:
+				require.Len(t, rows, 1)
+				assert.Equal(t, 60, rows[0]["humidity"])
+				assert.Equal(t, int64(7), rows[0]["n64"])
+				assert.Equal(t, uint8(3), rows[0]["fu"])
+				assert.Equal(t, int64(7), rows[0]["ln"])
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for window result")
+			}
+			s.Stop()
+		}
+	})
+}
+
+// TestStreamSQLOnIdleSource 测试事件时间数据源空闲时回调，携带空闲前的最大事件时间
+func TestStreamSQLOnIdleSource(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	idle := make(chan time.Time, 4)
+	ssql.OnIdleSource(func(last time.Time) { idle <- last })
+	require.NoError(t, ssql.Execute("SELECT COUNT(*) AS c FROM stream GROUP BY TumblingWindow('1m') "+
+		"WITH (TIMESTAMP='ts', TIMEUNIT='ms', IDLETIMEOUT='200ms')"))
+
+	base := time.Now().UnixMilli()/10000*10000 - 60000
+	for _, ts := range []int64{base, base + 3000, base + 1000} {
+		ssql.Emit(map[string]any{"ts": ts})
+	}
+	select {
+	case last := <-idle:
+		assert.Equal(t, base+3000, last.UnixMilli())
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnIdleSource callback not invoked")
+	}
+}
+
 // TestStreamSQLCustomPerformanceConfig 测试自定义性能配置
 func TestStreamSQLCustomPerformanceConfig(t *testing.T) {
 	t.Run("custom performance config with nil config", func(t *testing.T) {
@@ -995,3 +1890,184 @@ func TestStreamData(t *testing.T) {
 	countMutex.Unlock()
 	assert.Equal(t, finalCount, int64(5))
 }
+
+func TestStreamSQLPauseResume(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT deviceId, count(*) AS c FROM stream GROUP BY deviceId, TumblingWindow('300ms')"))
+	results := make(chan []map[string]any, 4)
+	ssql.AddSink(func(rows []map[string]any) { results <- rows })
+
+	ssql.Pause()
+	assert.True(t, ssql.IsPaused())
+	for i := 0; i < 3; i++ {
+		ssql.Emit(map[string]any{"deviceId": "d1"})
+	}
+	// 暂停期间输入保留在缓冲中，窗口不触发
+	select {
+	case rows := <-results:
+		t.Fatalf("result while paused: %v", rows)
+	case <-time.After(700 * time.Millisecond):
+	}
+
+	ssql.Resume()
+	assert.False(t, ssql.IsPaused())
+	select {
+	case rows := <-results:
+		require.Len(t, rows, 1)
+		assert.Equal(t, float64(3), rows[0]["c"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("buffered input not processed after Resume")
+	}
+
+	direct := New()
+	defer direct.Stop()
+	require.NoError(t, direct.Execute("SELECT deviceId FROM stream"))
+	direct.Pause()
+	_, err := direct.EmitSync(map[string]any{"deviceId": "d1"})
+	assert.True(t, errors.Is(err, stream.ErrPaused), "EmitSync while paused: %v", err)
+	direct.Resume()
+	row, err := direct.EmitSync(map[string]any{"deviceId": "d1"})
+	require.NoError(t, err)
+	assert.Equal(t, "d1", row["deviceId"])
+}
+
+func TestStreamSQLExecuteWithParams(t *testing.T) {
+	ssql := New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.ExecuteWithParams(
+		"SELECT deviceId, count(*) AS c FROM stream GROUP BY deviceId, CountingWindow(batch)",
+		map[string]any{"batch": 3}))
+	results := make(chan []map[string]any, 4)
+	ssql.AddSink(func(rows []map[string]any) { results <- rows })
+
+	for i := 0; i < 3; i++ {
+		ssql.Emit(map[string]any{"deviceId": "d1"})
+	}
+	select {
+	case rows := <-results:
+		require.Len(t, rows, 1)
+		assert.Equal(t, float64(3), rows[0]["c"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("window sized by parameter did not fire")
+	}
+
+	// 替换查询时同样按参数解析窗口大小
+	require.NoError(t, ssql.ReplaceSQLWithParams(
+		"SELECT deviceId, count(*) AS c FROM stream GROUP BY deviceId, CountingWindow(batch)",
+		map[string]any{"batch": 2}, false))
+	for i := 0; i < 2; i++ {
+		ssql.Emit(map[string]any{"deviceId": "d1"})
+	}
+	select {
+	case rows := <-results:
+		require.Len(t, rows, 1)
+		assert.Equal(t, float64(2), rows[0]["c"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("replaced window sized by parameter did not fire")
+	}
+	assert.ErrorContains(t, ssql.ReplaceSQL("SELECT count(*) AS c FROM stream GROUP BY CountingWindow(batch)", false), "missing parameter batch")
+
+	// 参数缺失时解析失败，实例可以重新执行
+	bad := New()
+	defer bad.Stop()
+	assert.ErrorContains(t, bad.ExecuteWithParams("SELECT count(*) AS c FROM stream GROUP BY TumblingWindow(size)", nil), "missing parameter size")
+	assert.NoError(t, bad.ExecuteWithParams("SELECT count(*) AS c FROM stream GROUP BY TumblingWindow(size)", map[string]any{"size": "1s"}))
+}
+
+func TestStreamSQLExecutePipeline(t *testing.T) {
+	t.Run("transform_feeds_aggregation", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.ExecutePipeline([]string{
+			"SELECT deviceId, temperature * 2 AS t2 FROM stream WHERE temperature > 0",
+			"SELECT deviceId, SUM(t2) AS total, COUNT(*) AS n FROM stream GROUP BY deviceId, TumblingWindow('1h')",
+		}))
+		require.Len(t, ssql.Queries(), 2)
+		var got []map[string]any
+		ssql.AddSyncSink(func(rows []map[string]any) { got = append(got, rows...) })
+
+		for _, temp := range []float64{1, -5, 2, 3} {
+			ssql.Emit(map[string]any{"deviceId": "d1", "temperature": temp})
+		}
+		// CloseInput 按阶段顺序刷新：第一阶段的结果先进入第二阶段，再触发其窗口
+		require.NoError(t, ssql.CloseInput())
+		require.Len(t, got, 1)
+		assert.Equal(t, "d1", got[0]["deviceId"])
+		assert.Equal(t, float64(12), got[0]["total"])
+		assert.Equal(t, float64(3), got[0]["n"])
+	})
+
+	t.Run("order_is_kept_between_stages", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.ExecutePipeline([]string{
+			"SELECT id, id * 10 AS x FROM stream",
+			"SELECT id, x + 1 AS y FROM stream WHERE x >= 500",
+		}))
+		var ids []any
+		ssql.AddSyncSink(func(rows []map[string]any) {
+			for _, r := range rows {
+				ids = append(ids, r["id"])
+			}
+		})
+		want := make([]any, 0, 50)
+		for i := 0; i < 100; i++ {
+			ssql.Emit(map[string]any{"id": i})
+			if i >= 50 {
+				want = append(want, i)
+			}
+		}
+		require.NoError(t, ssql.CloseInput())
+		assert.Equal(t, want, ids)
+
+		_, err := ssql.EmitSync(map[string]any{"id": 1})
+		assert.Error(t, err)
+		assert.Error(t, ssql.ReplaceSQL("SELECT id FROM stream", false))
+	})
+
+	t.Run("invalid_stage", func(t *testing.T) {
+		ssql := New()
+		defer ssql.Stop()
+		err := ssql.ExecutePipeline([]string{"SELECT id FROM stream", "SELECT FROM"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "stage 2")
+		assert.Error(t, ssql.ExecutePipeline(nil))
+		// 失败后实例可重新执行
+		assert.NoError(t, ssql.ExecutePipeline([]string{"SELECT id FROM stream"}))
+	})
+}
+
+// TestStreamSQLOnBackpressure 测试输入积压越过高水位、丢弃与恢复时的背压回调
+func TestStreamSQLOnBackpressure(t *testing.T) {
+	ssql := New(WithBufferSizes(10, 100, 100))
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+	events := make(chan stream.BackpressureEvent, 16)
+	ssql.OnBackpressure(func(e stream.BackpressureEvent) { events <- e })
+	next := func() stream.BackpressureEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("no backpressure event")
+			return stream.BackpressureEvent{}
+		}
+	}
+
+	// 暂停消费，输入在缓冲区积压
+	ssql.Pause()
+	for i := 0; i < 12; i++ {
+		ssql.Emit(map[string]interface{}{"id": i})
+	}
+	e := next()
+	assert.Equal(t, stream.BackpressureHighWater, e.Kind)
+	assert.Equal(t, "drop", e.Strategy)
+	assert.GreaterOrEqual(t, e.Utilization, 0.8)
+	e = next()
+	assert.Equal(t, stream.BackpressureDrop, e.Kind)
+	assert.EqualValues(t, 1, e.Dropped)
+
+	ssql.Resume()
+	assert.Equal(t, stream.BackpressureRecovered, next().Kind)
+}
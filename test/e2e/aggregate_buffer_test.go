@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql"
+	"github.com/rulego/streamsql/stream"
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WithMaxAggregateBufferPerGroup：缓冲型聚合每个分组最多保留 N 个值，超出部分按策略
+// 丢弃或抽样，并计入 aggregate_buffer_dropped 指标。
+func TestMaxAggregateBufferPerGroup(t *testing.T) {
+	run := func(t *testing.T, policy types.AggregateBufferPolicy, n int) (map[string]any, map[string]int64) {
+		ssql := streamsql.New(streamsql.WithMaxAggregateBufferPerGroup(100, policy))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT median(v) AS med, collect(v) AS vals, count(*) AS c
+			FROM stream GROUP BY TumblingWindow('1h')`))
+		var got []map[string]any
+		ssql.AddSyncSink(func(r []map[string]any) { got = append(got, r...) })
+		for i := 0; i < n; i++ {
+			ssql.Emit(map[string]any{"v": i})
+		}
+		require.NoError(t, ssql.CloseInput())
+		require.Len(t, got, 1)
+		return got[0], ssql.GetStats()
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		row, stats := run(t, types.AggregateBufferDrop, 1000)
+		assert.EqualValues(t, 1000, row["c"], "count is not limited")
+		assert.Len(t, row["vals"], 100)
+		assert.Equal(t, 49.5, row["med"], "median of the first 100 values")
+		assert.EqualValues(t, 2*900, stats[stream.AggregateBufferDropped])
+	})
+
+	t.Run("approximate", func(t *testing.T) {
+		row, stats := run(t, types.AggregateBufferApproximate, 10000)
+		assert.EqualValues(t, 10000, row["c"])
+		assert.Len(t, row["vals"], 100)
+		assert.InDelta(t, 5000, row["med"], 2000, "median of a uniform sample")
+		assert.EqualValues(t, 2*9900, stats[stream.AggregateBufferDropped])
+	})
+}
+
+// count_distinct 的去重集合同样受缓冲上限约束：超出的新值丢弃，结果为下界。
+func TestMaxAggregateBufferPerGroupCountDistinct(t *testing.T) {
+	ssql := streamsql.New(streamsql.WithMaxAggregateBufferPerGroup(100, types.AggregateBufferDrop))
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(`SELECT COUNT(DISTINCT v) AS d FROM stream GROUP BY TumblingWindow('1h')`))
+	var got []map[string]any
+	ssql.AddSyncSink(func(r []map[string]any) { got = append(got, r...) })
+	for i := 0; i < 1000; i++ {
+		ssql.Emit(map[string]any{"v": i})
+	}
+	require.NoError(t, ssql.CloseInput())
+	require.Len(t, got, 1)
+	assert.EqualValues(t, 100, got[0]["d"])
+	assert.EqualValues(t, 900, ssql.GetStats()[stream.AggregateBufferDropped])
+}
@@ -304,3 +304,64 @@ func TestAnalytic_WhenTransition(t *testing.T) {
 	r3, _ := ssql.EmitSync(map[string]any{"value": 30})
 	assert.Equal(t, 25, r3["prev"], "满足 WHEN，上一个有效值 25")
 }
+
+// had_changed(col, tolerance)：与基准之差不超过 tolerance 视为未变化，基准只在报告变化时更新。
+func TestAnalytic_HadChangedTolerance(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute("SELECT had_changed(temperature, 0.01) OVER (PARTITION BY deviceId) AS chg FROM stream"))
+	defer ssql.Stop()
+
+	emit := func(id string, temp any) any {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "temperature": temp})
+		require.NoError(t, err)
+		return r["chg"]
+	}
+	assert.Equal(t, true, emit("a", 20.0), "首次视为变化")
+	assert.Equal(t, false, emit("a", 20.005), "低于容差的噪声不算变化")
+	assert.Equal(t, false, emit("a", 19.991), "与基准 20.0 之差仍在容差内")
+	assert.Equal(t, true, emit("a", 20.02), "超过容差")
+	assert.Equal(t, false, emit("a", 20.028), "基准已更新为 20.02")
+	assert.Equal(t, false, emit("a", 20.029))
+	assert.Equal(t, true, emit("a", 20.031), "缓慢漂移累计超过容差后报告")
+	assert.Equal(t, true, emit("b", 20.0), "分区各自维护基准")
+	assert.Equal(t, true, emit("a", nil), "NULL 视为变化")
+
+	// 原有 had_changed(ignoreNull, col) 形式不受影响：精确比较
+	exact := streamsql.New()
+	require.NoError(t, exact.Execute("SELECT had_changed(false, temperature) AS chg FROM stream"))
+	defer exact.Stop()
+	r, _ := exact.EmitSync(map[string]any{"temperature": 20.0})
+	assert.Equal(t, true, r["chg"])
+	r, _ = exact.EmitSync(map[string]any{"temperature": 20.005})
+	assert.Equal(t, true, r["chg"])
+}
+
+// had_changed 的参数是逐行求出的值：字段引用、表达式结果与引用字段的容差都按值比较，
+// 加引号的列名只是字符串常量。
+func TestAnalytic_HadChangedArgumentValues(t *testing.T) {
+	run := func(sql string, temps ...float64) []any {
+		ssql := streamsql.New()
+		require.NoError(t, ssql.Execute(sql))
+		defer ssql.Stop()
+		var got []any
+		for _, temp := range temps {
+			r, err := ssql.EmitSync(map[string]any{"temperature": temp, "tol": 0.01})
+			require.NoError(t, err)
+			got = append(got, r["chg"])
+		}
+		return got
+	}
+
+	// 字段引用
+	assert.Equal(t, []any{true, false, true},
+		run("SELECT had_changed(temperature, 0.01) AS chg FROM stream", 20, 20.005, 20.02))
+	// 表达式的值：放大 10 倍后 0.005 的噪声变成 0.05，超过容差 0.01
+	assert.Equal(t, []any{true, true, false},
+		run("SELECT had_changed(temperature * 10, 0.01) AS chg FROM stream", 20, 20.005, 20.0052))
+	// 容差同样可以引用字段
+	assert.Equal(t, []any{true, false, true},
+		run("SELECT had_changed(temperature, tol) AS chg FROM stream", 20, 20.005, 20.02))
+	// 加引号的是字符串常量而非列名：只有首条视为变化
+	assert.Equal(t, []any{true, false, false},
+		run("SELECT had_changed('temperature', 0.01) AS chg FROM stream", 20, 20.005, 20.5))
+}
@@ -340,6 +340,36 @@ func TestScenario_GroupBy_FunctionExpression(t *testing.T) {
 	}
 }
 
+// GROUP BY CASE 表达式：按计算出的温度档位分组。SELECT 项与 GROUP BY 项空白写法不同
+// （t>30 与 t > 30）仍解析为同一分组键，输出到别名 bucket；不带别名时以表达式文本为列名。
+// 无 ELSE 且无分支命中时分组键为 NULL。
+func TestScenario_GroupBy_CaseExpression(t *testing.T) {
+	in := []map[string]any{
+		{"t": 10}, {"t": 35}, {"t": 40}, {"t": 20}, {"t": 50},
+	}
+	got := runWindow(t,
+		`SELECT CASE WHEN t>30 THEN 'hot' ELSE 'cold' END AS bucket, count(*) AS c, max(t) AS m FROM stream
+		GROUP BY CASE WHEN t > 30 THEN 'hot' ELSE 'cold' END, CountingWindow(2)`, in)
+	byBucket := map[string]float64{}
+	for _, r := range got {
+		b, _ := r["bucket"].(string)
+		byBucket[b] = toFloatVal(r["m"])
+	}
+	if len(byBucket) != 2 || byBucket["cold"] != 20 || byBucket["hot"] != 40 {
+		t.Errorf("GROUP BY CASE: got %v, want {cold:20 hot:40}", byBucket)
+	}
+
+	const key = "CASE WHEN t > 30 THEN 'hot' END"
+	got = runWindow(t, `SELECT count(*) AS c FROM stream GROUP BY `+key+`, CountingWindow(2)`, in)
+	byKey := map[any]float64{}
+	for _, r := range got {
+		byKey[r[key]] = toFloatVal(r["c"])
+	}
+	if len(byKey) != 2 || byKey["hot"] != 2 || byKey[nil] != 2 {
+		t.Errorf("GROUP BY CASE without alias: got %v, want {hot:2 <nil>:2}", byKey)
+	}
+}
+
 // GROUP BY 时间函数表达式 hour(timestamp)。hour() 取 "YYYY-MM-DD HH:MM:SS" 串的小时。
 // CountingWindow(2) 按 key（小时值）计数：hour10 有 2 条→触发 c2，hour11 有 2 条→触发 c2。
 // 期望 {10:2, 11:2}。分组键保留原始类型，hour() 返回 int，故 h 列为数值 10/11。
@@ -0,0 +1,67 @@
+package e2e
+
+import (
+	"testing"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crossed_above/crossed_below 边沿触发：只在越过阈值的那条记录为 true，
+// 持续在阈值之上/之下不重复触发；按 PARTITION 各自保存前值。
+func TestAnalytic_ThresholdCrossing(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, crossed_above(temp, 30) OVER (PARTITION BY deviceId) AS up, `+
+			`crossed_below(temp, 30) OVER (PARTITION BY deviceId) AS down FROM stream`))
+	defer ssql.Stop()
+
+	emit := func(id string, temp any) (bool, bool) {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "temp": temp})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r["up"] == true, r["down"] == true
+	}
+
+	t.Run("上升与下降序列", func(t *testing.T) {
+		// 首条无前值不触发；30 不算高于阈值；31 上穿；持续高位不重复；29 下穿；30 再 31 上穿。
+		seq := []struct {
+			temp     any
+			up, down bool
+		}{
+			{25, false, false},
+			{30, false, false},
+			{31, true, false},
+			{35, false, false},
+			{40.5, false, false},
+			{29, false, true},
+			{20, false, false},
+			{30, false, false},
+			{31, true, false},
+		}
+		for i, s := range seq {
+			up, down := emit("a", s.temp)
+			assert.Equal(t, s.up, up, "第 %d 条 temp=%v crossed_above", i, s.temp)
+			assert.Equal(t, s.down, down, "第 %d 条 temp=%v crossed_below", i, s.temp)
+		}
+	})
+
+	t.Run("分区独立", func(t *testing.T) {
+		up, _ := emit("b", 50) // b 首条：无前值，不受 a 的状态影响
+		assert.False(t, up)
+		_, down := emit("b", 10)
+		assert.True(t, down)
+		up, _ = emit("a", 32) // a 上一条为 31，已在阈值之上
+		assert.False(t, up)
+	})
+
+	t.Run("空值不打断边沿", func(t *testing.T) {
+		emit("c", 10)
+		up, down := emit("c", nil)
+		assert.False(t, up)
+		assert.False(t, down)
+		up, _ = emit("c", 40) // 前值仍为 10
+		assert.True(t, up)
+	})
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// is_duplicate 标记窗口内重复出现的键：首次出现为 false，第二次及以后为 true；
+// 按 PARTITION 各自维护已见集合，窗口到期后重新记录。
+func TestAnalytic_IsDuplicate(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, msgId, is_duplicate(msgId, '300ms') OVER (PARTITION BY deviceId) AS dup FROM stream`))
+	defer ssql.Stop()
+
+	emit := func(id string, msgId any) bool {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "msgId": msgId})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, msgId, r["msgId"], "重复记录保留原始字段")
+		return r["dup"] == true
+	}
+
+	t.Run("第二次及以后出现标记为重复", func(t *testing.T) {
+		seq := []struct {
+			msgId any
+			dup   bool
+		}{
+			{"m1", false},
+			{"m2", false},
+			{"m1", true},
+			{"m1", true},
+			{"m3", false},
+			{"m2", true},
+		}
+		for i, s := range seq {
+			assert.Equal(t, s.dup, emit("a", s.msgId), "第 %d 条 msgId=%v", i, s.msgId)
+		}
+	})
+
+	t.Run("分区独立", func(t *testing.T) {
+		assert.False(t, emit("b", "m1"))
+		assert.True(t, emit("b", "m1"))
+		assert.True(t, emit("a", "m3"))
+	})
+
+	t.Run("空键不标记", func(t *testing.T) {
+		assert.False(t, emit("c", nil))
+		assert.False(t, emit("c", nil))
+	})
+
+	t.Run("窗口到期后重置", func(t *testing.T) {
+		time.Sleep(400 * time.Millisecond)
+		assert.False(t, emit("a", "m1"))
+		assert.True(t, emit("a", "m1"))
+	})
+}
+
+// 不带窗口长度时已见集合贯穿整个流。
+func TestAnalytic_IsDuplicateNoWindow(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(`SELECT is_duplicate(orderId) AS dup FROM stream`))
+	defer ssql.Stop()
+
+	var got []any
+	for _, id := range []any{1, 2, 1.0, 3, 2} {
+		r, err := ssql.EmitSync(map[string]any{"orderId": id})
+		require.NoError(t, err)
+		got = append(got, r["dup"])
+	}
+	assert.Equal(t, []any{false, false, true, false, true}, got)
+}
+
+// 窗口查询里 is_duplicate 只在本窗口结果行内判重，已见集合随每个窗口清空。
+func TestAnalytic_IsDuplicatePerWindow(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, count(*) AS c, is_duplicate(region) AS dup `+
+			`FROM stream GROUP BY region, deviceId, TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+	ch := make(chan []map[string]any, 4)
+	ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+	base := time.Now().UnixMilli() - 10000
+	base -= base % 1000
+	for _, w := range []int64{0, 1000} {
+		ssql.Emit(map[string]any{"ts": base + w, "region": "east", "deviceId": "d1"})
+		ssql.Emit(map[string]any{"ts": base + w + 10, "region": "east", "deviceId": "d2"})
+	}
+	ssql.Emit(map[string]any{"ts": base + 3000, "region": "east", "deviceId": "d1"}) // 推水位
+
+	for i := 0; i < 2; i++ {
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 2, "第 %d 个窗口", i)
+			var dup []any
+			for _, r := range rows {
+				dup = append(dup, r["dup"])
+			}
+			// 两行同属 east：每个窗口恰有一行是重复，上一窗口的键不带入
+			assert.ElementsMatch(t, []any{false, true}, dup, "第 %d 个窗口", i)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window results")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// window_position 返回记录在分组当前窗口内的到达序号（从 0 起），窗口到期后从 0 重新计数；
+// 按 PARTITION 各自计数。
+func TestAnalytic_WindowPosition(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, v, window_position('300ms') OVER (PARTITION BY deviceId) AS pos FROM stream`))
+	defer ssql.Stop()
+
+	emit := func(id string, v int) any {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "v": v})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, v, r["v"])
+		return r["pos"]
+	}
+
+	t.Run("按到达顺序从0计数", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, int64(i), emit("a", i), "第 %d 条", i)
+		}
+	})
+
+	t.Run("分区独立", func(t *testing.T) {
+		assert.Equal(t, int64(0), emit("b", 0))
+		assert.Equal(t, int64(4), emit("a", 4))
+		assert.Equal(t, int64(1), emit("b", 1))
+	})
+
+	t.Run("窗口到期后重置", func(t *testing.T) {
+		time.Sleep(400 * time.Millisecond)
+		assert.Equal(t, int64(0), emit("a", 5))
+		assert.Equal(t, int64(1), emit("a", 6))
+		assert.Equal(t, int64(0), emit("b", 2))
+	})
+}
+
+// 不带窗口长度时在整个流内递增；WHERE 可据此每 N 条抽样一条。
+func TestAnalytic_WindowPositionSampling(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(`SELECT v FROM stream WHERE mod(window_position(), 3) = 0`))
+	defer ssql.Stop()
+
+	var kept []any
+	for i := 0; i < 7; i++ {
+		r, err := ssql.EmitSync(map[string]any{"v": i})
+		require.NoError(t, err)
+		if r != nil {
+			kept = append(kept, r["v"])
+		}
+	}
+	assert.Equal(t, []any{0, 3, 6}, kept)
+}
+
+// 与 row_number 不同，window_position 只按到达顺序计数，不接受 ORDER BY。
+func TestAnalytic_WindowPositionRejectsOrderBy(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	assert.Error(t, ssql.Execute(`SELECT window_position() OVER (ORDER BY ts) AS pos FROM stream`))
+}
+
+// 窗口查询里 window_position 按每次窗口产出的结果行计数，每个窗口从 0 重新开始。
+func TestAnalytic_WindowPositionPerWindow(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, count(*) AS c, window_position() OVER (PARTITION BY region) AS pos `+
+			`FROM stream GROUP BY region, deviceId, TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+	ch := make(chan []map[string]any, 4)
+	ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+	base := time.Now().UnixMilli() - 10000
+	base -= base % 1000
+	for _, w := range []int64{0, 1000} {
+		ssql.Emit(map[string]any{"ts": base + w, "region": "east", "deviceId": "d1"})
+		ssql.Emit(map[string]any{"ts": base + w + 10, "region": "east", "deviceId": "d2"})
+	}
+	ssql.Emit(map[string]any{"ts": base + 3000, "region": "east", "deviceId": "d1"}) // 推水位
+
+	for i := 0; i < 2; i++ {
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 2, "第 %d 个窗口", i)
+			var pos []any
+			for _, r := range rows {
+				pos = append(pos, r["pos"])
+			}
+			assert.ElementsMatch(t, []any{int64(0), int64(1)}, pos, "第 %d 个窗口", i)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window results")
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// row_number/rank/dense_rank OVER (PARTITION BY ... ORDER BY ...)：每条行按排序键对照分区内
+// 已到达的全部行求名次，乱序到达的并列键得到相同名次，DESC 反向；每个分区独立编号。
+func TestAnalytic_RankingOver(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, temperature, `+
+			`row_number() OVER (PARTITION BY deviceId ORDER BY ts) AS rn, `+
+			`rank() OVER (PARTITION BY deviceId ORDER BY ts) AS rk, `+
+			`dense_rank() OVER (PARTITION BY deviceId ORDER BY ts) AS drk, `+
+			`rank() OVER (PARTITION BY deviceId ORDER BY ts DESC) AS rkd FROM stream`))
+	defer ssql.Stop()
+
+	emit := func(id string, ts int) map[string]any {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "ts": ts, "temperature": 20.0})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r
+	}
+
+	t.Run("乱序并列与跳号", func(t *testing.T) {
+		seq := []struct {
+			ts               int
+			rn, rk, drk, rkd int64
+		}{
+			{5, 1, 1, 1, 1}, // {5}
+			{2, 1, 1, 1, 2}, // {2,5}
+			{5, 3, 2, 2, 1}, // {2,5,5}：与非相邻的 5 并列
+			{3, 2, 2, 2, 3}, // {2,3,5,5}：插在中间
+			{9, 5, 5, 4, 1}, // {2,3,5,5,9}
+			{2, 2, 1, 1, 5}, // {2,2,3,5,5,9}
+		}
+		for i, s := range seq {
+			r := emit("a", s.ts)
+			assert.Equal(t, s.rn, r["rn"], "第 %d 条 row_number", i)
+			assert.Equal(t, s.rk, r["rk"], "第 %d 条 rank", i)
+			assert.Equal(t, s.drk, r["drk"], "第 %d 条 dense_rank", i)
+			assert.Equal(t, s.rkd, r["rkd"], "第 %d 条 rank DESC", i)
+			assert.Equal(t, 20.0, r["temperature"])
+		}
+	})
+
+	t.Run("分区独立", func(t *testing.T) {
+		r := emit("b", 9)
+		assert.Equal(t, int64(1), r["rn"])
+		assert.Equal(t, int64(1), r["rk"])
+		r = emit("a", 10)
+		assert.Equal(t, int64(7), r["rn"])
+		assert.Equal(t, int64(1), r["rkd"])
+	})
+}
+
+// OVER 的 ORDER BY 仅限排名函数；排名函数也不能用于 GROUP BY 窗口查询。
+func TestAnalytic_RankingOverRejected(t *testing.T) {
+	for _, sql := range []string{
+		`SELECT latest(temp) OVER (PARTITION BY deviceId ORDER BY ts) AS p FROM stream`,
+		`SELECT rank() OVER (ORDER BY ts) AS rk FROM stream GROUP BY TumblingWindow('1s')`,
+		`SELECT row_number() OVER (ORDER BY ts ROWS 3) AS rn FROM stream`,
+	} {
+		ssql := streamsql.New()
+		assert.Error(t, ssql.Execute(sql), sql)
+		ssql.Stop()
+	}
+}
+
+// ntile(n) 在窗口查询里按每次窗口产出的结果行分桶：组内按 OVER ORDER BY 排序，
+// 不能整除时靠前的桶多一行；PARTITION BY 各分区独立分桶。
+func TestAnalytic_NtileOverWindow(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(
+		`SELECT region, deviceId, avg(v) AS a, `+
+			`ntile(2) OVER (ORDER BY a DESC) AS q, `+
+			`ntile(3) OVER (PARTITION BY region ORDER BY a) AS rq `+
+			`FROM stream GROUP BY region, deviceId, TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+	ch := make(chan []map[string]any, 4)
+	ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+	base := time.Now().UnixMilli() - 5000
+	for _, in := range []struct {
+		region, id string
+		v          float64
+	}{
+		{"east", "d1", 10}, {"east", "d2", 50}, {"east", "d3", 30}, {"east", "d4", 20},
+		{"west", "d5", 40},
+	} {
+		ssql.Emit(map[string]any{"ts": base, "region": in.region, "deviceId": in.id, "v": in.v})
+	}
+	ssql.Emit(map[string]any{"ts": base + 2000, "region": "east", "deviceId": "d1", "v": 0.0}) // 推水位
+
+	select {
+	case rows := <-ch:
+		require.Len(t, rows, 5)
+		q := map[string]any{}
+		rq := map[string]any{}
+		for _, r := range rows {
+			q[r["deviceId"].(string)] = r["q"]
+			rq[r["deviceId"].(string)] = r["rq"]
+		}
+		// 降序 d2(50) d5(40) d3(30) | d4(20) d1(10)：5 行 2 桶 → 3+2
+		assert.Equal(t, map[string]any{"d2": int64(1), "d5": int64(1), "d3": int64(1), "d4": int64(2), "d1": int64(2)}, q)
+		// east 升序 d1 d4 | d3 | d2：4 行 3 桶 → 2+1+1；west 单行 → 1
+		assert.Equal(t, map[string]any{"d1": int64(1), "d4": int64(1), "d3": int64(2), "d2": int64(3), "d5": int64(1)}, rq)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for window results")
+	}
+}
+
+// ntile 需要整个分区，只能用于窗口查询的独立字段；桶数须为正整数常量。
+func TestAnalytic_NtileRejected(t *testing.T) {
+	for _, sql := range []string{
+		`SELECT deviceId, ntile(4) OVER (ORDER BY temp) AS q FROM stream`,
+		`SELECT deviceId, avg(temp) AS a, ntile(4) + 1 AS q FROM stream GROUP BY deviceId, TumblingWindow('1s')`,
+		`SELECT * FROM stream WHERE ntile(4) OVER (ORDER BY temp) = 1`,
+		`SELECT deviceId, avg(temp) AS a, ntile(0) OVER (ORDER BY a) AS q FROM stream GROUP BY deviceId, TumblingWindow('1s')`,
+		`SELECT deviceId, avg(temp) AS a, ntile(-1) OVER (ORDER BY a) AS q FROM stream GROUP BY deviceId, TumblingWindow('1s')`,
+		`SELECT deviceId, avg(temp) AS a, ntile(a) OVER (ORDER BY a) AS q FROM stream GROUP BY deviceId, TumblingWindow('1s')`,
+	} {
+		ssql := streamsql.New()
+		assert.Error(t, ssql.Execute(sql), sql)
+		ssql.Stop()
+	}
+}
+
+// rank 与 lag 共享带 ORDER BY 的命名窗口：rank 按 ts 排名，lag 忽略继承的 ORDER BY、按到达顺序取上一条。
+func TestAnalytic_NamedWindowRankAndLag(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT device, rank() OVER w, lag(x) OVER w FROM stream WINDOW w AS (PARTITION BY device ORDER BY ts)`))
+	defer ssql.Stop()
+
+	seq := []struct {
+		in   map[string]any
+		rank int64
+		lag  any
+	}{
+		{map[string]any{"device": "a", "ts": 5, "x": 1}, 1, nil},
+		{map[string]any{"device": "a", "ts": 2, "x": 2}, 1, 1},
+		{map[string]any{"device": "b", "ts": 1, "x": 3}, 1, nil},
+		{map[string]any{"device": "a", "ts": 9, "x": 4}, 3, 2},
+	}
+	for i, s := range seq {
+		r, err := ssql.EmitSync(s.in)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, s.rank, r["rank()"], "第 %d 条 rank", i)
+		assert.Equal(t, s.lag, r["lag(x)"], "第 %d 条 lag", i)
+	}
+}
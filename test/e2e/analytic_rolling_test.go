@@ -0,0 +1,56 @@
+package e2e
+
+import (
+	"math"
+	"testing"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rolling_stddev：最近 n 条记录的样本标准差，按 PARTITION 各持一个窗口，预热期返回 nil。
+func TestAnalytic_RollingStddev(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, rolling_stddev(v, 3) OVER (PARTITION BY deviceId) AS sd FROM stream`))
+	defer ssql.Stop()
+
+	emit := func(id string, v any) any {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "v": v})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r["sd"]
+	}
+	stddev := func(vals ...float64) float64 {
+		mean := 0.0
+		for _, v := range vals {
+			mean += v
+		}
+		mean /= float64(len(vals))
+		ss := 0.0
+		for _, v := range vals {
+			ss += (v - mean) * (v - mean)
+		}
+		return math.Sqrt(ss / float64(len(vals)-1))
+	}
+
+	seq := []float64{2, 4, 4, 5, 9, 7, 7.5, 1}
+	for i, v := range seq {
+		got := emit("a", v)
+		if i < 2 {
+			assert.Nil(t, got, "第 %d 条处于预热期", i)
+			continue
+		}
+		require.NotNil(t, got, "第 %d 条", i)
+		assert.InDelta(t, stddev(seq[i-2:i+1]...), got, 1e-9, "第 %d 条", i)
+	}
+
+	// 分区独立：b 的窗口从零开始预热
+	assert.Nil(t, emit("b", 100))
+	assert.Nil(t, emit("b", 200))
+	assert.InDelta(t, stddev(100, 200, 300), emit("b", 300), 1e-9)
+
+	// NULL 不进入窗口，返回当前窗口结果
+	assert.InDelta(t, stddev(7, 7.5, 1), emit("a", nil), 1e-9)
+}
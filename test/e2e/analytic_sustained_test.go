@@ -0,0 +1,78 @@
+package e2e
+
+import (
+	"testing"
+
+	streamsql "github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sustained_above/sustained_below 去抖告警：值持续越限满 duration（按事件时间）才为 true，
+// 短暂尖峰不触发；值回到阈值内即重新计时；按 PARTITION 各自计时。
+func TestAnalytic_SustainedThreshold(t *testing.T) {
+	ssql := streamsql.New()
+	require.NoError(t, ssql.Execute(
+		`SELECT deviceId, sustained_above(temp, 30, '10s') OVER (PARTITION BY deviceId) AS hot, `+
+			`sustained_below(temp, 5, '10s') OVER (PARTITION BY deviceId) AS cold FROM stream `+
+			`WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+	defer ssql.Stop()
+
+	const base = int64(1_700_000_000_000)
+	emit := func(id string, sec int64, temp any) (bool, bool) {
+		r, err := ssql.EmitSync(map[string]any{"deviceId": id, "ts": base + sec*1000, "temp": temp})
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		return r["hot"] == true, r["cold"] == true
+	}
+
+	t.Run("短暂尖峰不告警", func(t *testing.T) {
+		seq := []struct {
+			sec  int64
+			temp any
+		}{{0, 20}, {1, 45}, {5, 50}, {9, 48}, {10, 25}, {11, 40}, {20, 29}, {30, 30}}
+		for _, s := range seq {
+			hot, _ := emit("spike", s.sec, s.temp)
+			assert.False(t, hot, "t=%ds temp=%v", s.sec, s.temp)
+		}
+	})
+
+	t.Run("持续越限告警", func(t *testing.T) {
+		seq := []struct {
+			sec  int64
+			temp any
+			hot  bool
+		}{
+			{0, 31, false},
+			{5, 35, false},
+			{9, 40.5, false},
+			{10, 33, true}, // 自 t=0 起持续 10s
+			{15, 32, true},
+			{16, 30, false}, // 30 不算高于阈值，重新计时
+			{17, 31, false},
+			{27, 31, true},
+		}
+		for _, s := range seq {
+			hot, _ := emit("steady", s.sec, s.temp)
+			assert.Equal(t, s.hot, hot, "t=%ds temp=%v", s.sec, s.temp)
+		}
+	})
+
+	t.Run("空值打断持续", func(t *testing.T) {
+		emit("gap", 0, 40)
+		emit("gap", 5, nil)
+		hot, _ := emit("gap", 12, 40)
+		assert.False(t, hot)
+		hot, _ = emit("gap", 22, 40)
+		assert.True(t, hot)
+	})
+
+	t.Run("低于阈值与分区独立", func(t *testing.T) {
+		_, cold := emit("freezer", 0, 2)
+		assert.False(t, cold)
+		hot, _ := emit("other", 100, 50) // 另一设备首条越限，从 t=100s 计时
+		assert.False(t, hot)
+		_, cold = emit("freezer", 10, 4.5)
+		assert.True(t, cold)
+	})
+}
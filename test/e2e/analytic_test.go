@@ -41,6 +41,28 @@ func TestAnalytic_PartitionKeyTypeSafe(t *testing.T) {
 	assert.Equal(t, 100, r3["prev"])
 }
 
+// 命名窗口 WINDOW w AS (...) 被两个分析函数共享：与各自内联 OVER(...) 结果一致。
+func TestAnalytic_NamedWindowShared(t *testing.T) {
+	in := []map[string]any{
+		{"d": "a", "v": 1}, {"d": "b", "v": 10}, {"d": "a", "v": 2}, {"d": "b", "v": -5}, {"d": "a", "v": 3},
+	}
+	named := runDirect(t, `SELECT d, lag(v) OVER w AS prev, acc_sum(v) OVER w AS total
+FROM stream
+WINDOW w AS (PARTITION BY d WHEN v > 0)`, in)
+	inline := runDirect(t, `SELECT d, lag(v) OVER (PARTITION BY d WHEN v > 0) AS prev,
+acc_sum(v) OVER (PARTITION BY d WHEN v > 0) AS total FROM stream`, in)
+	require.Len(t, named, len(in))
+	assert.Equal(t, inline, named)
+
+	// 分区独立；b 的 -5 不满足 WHEN，状态不更新
+	totals := make([]float64, len(named))
+	for i, r := range named {
+		totals[i] = toFloatVal(r["total"])
+	}
+	assert.Equal(t, []float64{1, 10, 3, 10, 6}, totals)
+	assert.Equal(t, 2, named[4]["prev"])
+}
+
 // changed_cols(prefix, ignoreNull, expr...) 多列动态输出。
 // 仅输出变化列，列名 = prefix + 原列名。
 func TestAnalytic_ChangedColsMultiColumn(t *testing.T) {
@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBetweenOperator 测试 WHERE/HAVING/SELECT 中的 BETWEEN 与 NOT BETWEEN
+func TestBetweenOperator(t *testing.T) {
+	t.Parallel()
+
+	emitAll := func(t *testing.T, sql string, rows []map[string]any) []any {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(sql))
+		var ids []any
+		for _, row := range rows {
+			result, err := ssql.EmitSync(row)
+			require.NoError(t, err)
+			if result != nil {
+				ids = append(ids, result["deviceId"])
+			}
+		}
+		return ids
+	}
+	rows := []map[string]any{
+		{"deviceId": "d1", "temperature": 20, "sensor": map[string]any{"temperature": 35.0}},
+		{"deviceId": "d2", "temperature": 25.5, "sensor": map[string]any{"temperature": 25.0}},
+		{"deviceId": "d3", "temperature": 30.1, "sensor": map[string]any{"temperature": 30.0}},
+		{"deviceId": "d4", "temperature": nil},
+		{"deviceId": "d5"},
+	}
+
+	t.Run("WHERE 闭区间", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE temperature BETWEEN 20 AND 30", rows)
+		assert.Equal(t, []any{"d1", "d2"}, ids)
+	})
+
+	t.Run("NOT BETWEEN 对 NULL 为 false", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE temperature NOT BETWEEN 20 AND 30", rows)
+		assert.Equal(t, []any{"d3"}, ids)
+	})
+
+	t.Run("嵌套字段与其他条件组合", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE sensor.temperature BETWEEN 20 AND 30 AND deviceId != 'd2'", rows)
+		assert.Equal(t, []any{"d3"}, ids)
+	})
+
+	t.Run("字符串比较", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE deviceId BETWEEN 'd2' AND 'd4'", rows)
+		assert.Equal(t, []any{"d2", "d3", "d4"}, ids)
+	})
+
+	t.Run("SELECT 字段", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, temperature BETWEEN 20 AND 30 AS in_range FROM stream"))
+		result, err := ssql.EmitSync(map[string]any{"deviceId": "d1", "temperature": 25})
+		require.NoError(t, err)
+		assert.Equal(t, true, result["in_range"])
+		result, err = ssql.EmitSync(map[string]any{"deviceId": "d2", "temperature": 35})
+		require.NoError(t, err)
+		assert.Equal(t, false, result["in_range"])
+	})
+
+	t.Run("HAVING", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, AVG(temperature) AS avg_temp FROM stream GROUP BY deviceId, TumblingWindow('1h') HAVING avg_temp BETWEEN 20 AND 30"))
+		ch := make(chan []map[string]any, 1)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+		for _, row := range []map[string]any{
+			{"deviceId": "a", "temperature": 18.0}, {"deviceId": "a", "temperature": 24.0},
+			{"deviceId": "b", "temperature": 40.0},
+		} {
+			ssql.Emit(row)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ssql.TriggerWindow()
+		select {
+		case results := <-ch:
+			require.Len(t, results, 1)
+			assert.Equal(t, "a", results[0]["deviceId"])
+			assert.Equal(t, 21.0, results[0]["avg_temp"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+		}
+	})
+}
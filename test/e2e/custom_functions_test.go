@@ -12,6 +12,7 @@ import (
 	"github.com/rulego/streamsql/utils/cast"
 
 	"github.com/rulego/streamsql/aggregator"
+	"github.com/rulego/streamsql/condition"
 	"github.com/rulego/streamsql/expr"
 	"github.com/rulego/streamsql/functions"
 	"github.com/rulego/streamsql/rsql"
@@ -687,3 +688,28 @@ func TestDebugMultiParameterFunction(t *testing.T) {
 	_, _, err = stmt.ToStreamConfig()
 	assert.NoError(t, err)
 }
+
+// TestCustomConditionFunction 测试通过 condition.RegisterConditionFunction 注册的布尔函数
+// 可直接用于 WHERE，并与其他谓词组合。
+func TestCustomConditionFunction(t *testing.T) {
+	condition.RegisterConditionFunction("in_test_geofence", func(args ...interface{}) interface{} {
+		lat, lon := cast.ToFloat64(args[0]), cast.ToFloat64(args[1])
+		return lat >= 30 && lat <= 31 && lon >= 120 && lon <= 121
+	})
+
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	err := ssql.Execute("SELECT deviceId FROM stream WHERE in_test_geofence(lat, lon) AND speed > 10")
+	assert.NoError(t, err)
+
+	got, err := ssql.EmitSync(map[string]interface{}{"deviceId": "in", "lat": 30.5, "lon": 120.5, "speed": 20})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"deviceId": "in"}, got)
+
+	got, err = ssql.EmitSync(map[string]interface{}{"deviceId": "out", "lat": 40.0, "lon": 120.5, "speed": 20})
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	// 未注册的函数仍在解析期报错
+	assert.Error(t, streamsql.New().Execute("SELECT deviceId FROM stream WHERE not_registered_fence(lat, lon)"))
+}
@@ -388,6 +388,32 @@ func TestNewAggregateFunctionsInSQL(t *testing.T) {
 	//fmt.Println("新聚合函数测试完成")
 }
 
+// var/vars 接受表达式参数；单值窗口的 var 为 0，vars 无定义为 nil
+func TestVarianceWithExpressionArgument(t *testing.T) {
+	t.Parallel()
+	sql := "SELECT device, var(temperature*1.8+32) AS pv, vars(temperature*1.8+32) AS sv FROM stream GROUP BY device, TumblingWindow('200ms')"
+	got := runWindow(t, sql, []map[string]any{
+		{"device": "a", "temperature": 10.0},
+		{"device": "a", "temperature": 20.0},
+		{"device": "a", "temperature": 30.0},
+		{"device": "b", "temperature": 15.0},
+	})
+	require.Len(t, got, 2)
+	for _, row := range got {
+		switch row["device"] {
+		case "a":
+			// 华氏温度 50/68/86：总体方差 216，样本方差 324
+			assert.InDelta(t, 216.0, row["pv"], 1e-9)
+			assert.InDelta(t, 324.0, row["sv"], 1e-9)
+		case "b":
+			assert.Equal(t, 0.0, row["pv"])
+			assert.Nil(t, row["sv"])
+		default:
+			t.Fatalf("unexpected row %v", row)
+		}
+	}
+}
+
 func TestStatisticalAggregateFunctionsInSQL(t *testing.T) {
 	t.Parallel()
 	ssql := streamsql.New()
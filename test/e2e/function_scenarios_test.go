@@ -131,6 +131,14 @@ func TestFunctionScenarios_DateTime(t *testing.T) {
 		assertRows(t, "date_add", got, []map[string]any{{"d": "2024-01-16 10:00:00"}})
 	})
 
+	t.Run("date_trunc_date_add_unix", func(t *testing.T) {
+		t.Parallel()
+		// 1700000000000 = 2023-11-14 22:13:20 UTC；Unix 毫秒输入返回 Unix 毫秒
+		got := runDirect(t, `SELECT date_trunc('hour', ts) AS bucket, date_add(date_trunc('day', ts), 1, 'day') AS next_day FROM stream`,
+			[]map[string]any{{"ts": int64(1700000000000)}})
+		assertRows(t, "date_trunc", got, []map[string]any{{"bucket": int64(1699999200000), "next_day": int64(1700006400000)}})
+	})
+
 	t.Run("date_sub", func(t *testing.T) {
 		t.Parallel()
 		got := runDirect(t, `SELECT date_sub('2024-01-15 10:00:00', 1, 'month') AS d FROM stream`,
@@ -352,6 +360,104 @@ func TestFunctionScenarios_Bitwise(t *testing.T) {
 	numEq(t, "bitnot", got[0]["n"], -1)  // ^0 = -1
 }
 
+// ---------- Hash ----------
+
+func TestFunctionScenarios_Hash(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hash_and_crc32_digests", func(t *testing.T) {
+		t.Parallel()
+		got := runDirect(t,
+			`SELECT hash(id) AS h, hash(id, 'md5') AS m, crc32(id) AS c, hash(n) AS hn FROM stream`,
+			[]map[string]any{{"id": "hello", "n": 42}})
+		require.Len(t, got, 1)
+		assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", got[0]["h"])
+		assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", got[0]["m"])
+		assert.Equal(t, uint32(907060870), got[0]["c"])
+		// 数值按规范十进制序列化："42"
+		assert.Equal(t, "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049", got[0]["hn"])
+	})
+
+	t.Run("sample_by_crc32", func(t *testing.T) {
+		t.Parallel()
+		// crc32("hello")=907060870（偶），crc32("42")=841265288（偶），crc32("true")=4261170317（奇）
+		in := []map[string]any{{"id": "hello"}, {"id": "42"}, {"id": "true"}}
+		got := runDirect(t, `SELECT id FROM stream WHERE mod(crc32(id), 2) = 0`, in)
+		ids := make([]any, 0, len(got))
+		for _, r := range got {
+			ids = append(ids, r["id"])
+		}
+		assert.ElementsMatch(t, []any{"hello", "42"}, ids)
+	})
+}
+
+// ---------- Units ----------
+
+func TestFunctionScenarios_Units(t *testing.T) {
+	t.Parallel()
+
+	t.Run("convert_units", func(t *testing.T) {
+		t.Parallel()
+		got := runDirect(t,
+			`SELECT convert(tf, 'F', 'C') AS tc, convert(d, 'km', 'm') AS dm, convert(p, 'bar', 'kPa') AS pk FROM stream`,
+			[]map[string]any{{"tf": 212, "d": 1.5, "p": 2}})
+		require.Len(t, got, 1)
+		assert.InDelta(t, 100.0, got[0]["tc"], 1e-9)
+		assert.InDelta(t, 1500.0, got[0]["dm"], 1e-9)
+		assert.InDelta(t, 200.0, got[0]["pk"], 1e-9)
+	})
+
+	t.Run("convert_in_where", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{{"id": "a", "tf": 50}, {"id": "b", "tf": 104}}
+		got := runDirect(t, `SELECT id FROM stream WHERE convert(tf, 'F', 'C') > 30`, in)
+		require.Len(t, got, 1)
+		assert.Equal(t, "b", got[0]["id"])
+	})
+}
+
+// ---------- Geo ----------
+
+func TestFunctionScenarios_Geo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("geohash_known_values", func(t *testing.T) {
+		t.Parallel()
+		got := runDirect(t,
+			`SELECT geohash(lat, lon, 11) AS h11, geohash(lat, lon) AS h12, geohash(lat, lon, 0) AS bad FROM stream`,
+			[]map[string]any{{"lat": 57.64911, "lon": 10.40744}})
+		require.Len(t, got, 1)
+		assert.Equal(t, "u4pruydqqvj", got[0]["h11"])
+		assert.Len(t, got[0]["h12"], 12)
+		assert.Nil(t, got[0]["bad"], "precision out of range")
+	})
+
+	t.Run("group_by_geohash", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"lat": 39.92324, "lon": 116.3906, "v": 1},
+			{"lat": 39.92400, "lon": 116.3910, "v": 2},
+			{"lat": 31.23040, "lon": 121.4737, "v": 5},
+		}
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT geohash(lat, lon, 4) AS cell, count(*) AS c, sum(v) AS s
+			FROM stream GROUP BY geohash(lat, lon, 4), TumblingWindow('1h')`))
+		var got []map[string]any
+		ssql.AddSyncSink(func(r []map[string]any) { got = append(got, r...) })
+		for _, row := range in {
+			ssql.Emit(row)
+		}
+		require.NoError(t, ssql.CloseInput())
+		require.Len(t, got, 2)
+		byCell := map[any][2]float64{}
+		for _, r := range got {
+			byCell[r["cell"]] = [2]float64{toFloatVal(r["c"]), toFloatVal(r["s"])}
+		}
+		assert.Equal(t, map[any][2]float64{"wx4g": {2, 3}, "wtw3": {1, 5}}, byCell)
+	})
+}
+
 // ---------- String ----------
 
 func TestFunctionScenarios_String(t *testing.T) {
@@ -404,6 +510,13 @@ func TestFunctionScenarios_String(t *testing.T) {
 		assertRows(t, "regexp_substring", got, []map[string]any{{"s": "123"}})
 	})
 
+	t.Run("regexp_matches_replace", func(t *testing.T) {
+		t.Parallel()
+		got := runDirect(t, `SELECT regexp_replace(device, '[0-9]+', 'N') AS masked FROM stream WHERE regexp_matches(device, '^dev-[0-9]+$')`,
+			[]map[string]any{{"device": "dev-12"}, {"device": "gw-3"}, {"device": "dev-x"}, {"device": "dev-7"}})
+		assertRows(t, "regexp", got, []map[string]any{{"masked": "dev-N"}, {"masked": "dev-N"}})
+	})
+
 	t.Run("chr", func(t *testing.T) {
 		t.Parallel()
 		got := runDirect(t, `SELECT chr(65) AS c FROM stream`,
@@ -455,6 +568,28 @@ func TestFunctionScenarios_Conditional(t *testing.T) {
 		assertRows(t, "coalesce val", got2, []map[string]any{{"v": "real"}})
 	})
 
+	t.Run("coalesce_short_circuit", func(t *testing.T) {
+		t.Parallel()
+		// sqrt(-1) 会报错：首个参数非 NULL 时不求值，行照常输出
+		got := runDirect(t, `SELECT coalesce(a, b, sqrt(-1)) AS v, coalesce(a, b, 'default') AS d FROM stream`,
+			[]map[string]any{{"a": 1.5}, {"b": 2.5}})
+		assertRows(t, "coalesce short circuit", got, []map[string]any{
+			{"v": 1.5, "d": 1.5},
+			{"v": 2.5, "d": 2.5},
+		})
+	})
+
+	t.Run("nullif", func(t *testing.T) {
+		t.Parallel()
+		got := runDirect(t, `SELECT NULLIF(x, 0) AS v, coalesce(nullif(x, 0), -1) AS w FROM stream`,
+			[]map[string]any{{"x": 0.0}, {"x": 4.0}})
+		require.Len(t, got, 2)
+		assert.Nil(t, got[0]["v"])
+		numEq(t, "nullif fallback", got[0]["w"], -1)
+		numEq(t, "nullif kept", got[1]["v"], 4)
+		numEq(t, "nullif kept coalesce", got[1]["w"], 4)
+	})
+
 	t.Run("if_null", func(t *testing.T) {
 		t.Parallel()
 		got := runDirect(t, `SELECT if_null(x, 'fallback') AS v FROM stream`,
@@ -578,6 +713,287 @@ func TestFunctionScenarios_Aggregates(t *testing.T) {
 			t.Errorf("percentile(v, 0.5): got %v, want %v (p 参数应生效，index=floor(0.5*9)=4)", vals[0], want)
 		}
 	})
+
+	t.Run("iqr_known_quartiles", func(t *testing.T) {
+		t.Parallel()
+		in := make([]map[string]any, 0, 9)
+		for i := 1; i <= 9; i++ {
+			in = append(in, map[string]any{"g": "s", "v": float64(i * 10)})
+		}
+		got := runWindow(t, `SELECT iqr(v) AS q, median(v) AS m FROM stream GROUP BY g, CountingWindow(9)`, in)
+		// 10..90：Q1=sorted[floor(0.25*8)]=30，Q3=sorted[floor(0.75*8)]=70，IQR=40
+		vals := sortedFloatField(got, "q")
+		if len(vals) != 1 || vals[0] != 40.0 {
+			t.Errorf("iqr = %v, want [40]", vals)
+		}
+	})
+
+	t.Run("iqr_too_few_points_is_nil", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{{"g": "s", "v": 1.0}, {"g": "s", "v": 2.0}}
+		got := runWindow(t, `SELECT iqr(v) AS q FROM stream GROUP BY g, CountingWindow(2)`, in)
+		require.Len(t, got, 1)
+		assert.Nil(t, got[0]["q"])
+	})
+
+	t.Run("consecutive_diff_median_jitter", func(t *testing.T) {
+		t.Parallel()
+		seq := []float64{20, 21, 19, 24, 23, 23}
+		in := make([]map[string]any, 0, len(seq))
+		for _, v := range seq {
+			in = append(in, map[string]any{"g": "s", "v": v})
+		}
+		got := runWindow(t, `SELECT consecutive_diff_median(v) AS jitter FROM stream GROUP BY g, CountingWindow(6)`, in)
+		// 相邻差 1,2,5,1,0 -> 排序 0,1,1,2,5 -> 中位数 1
+		vals := sortedFloatField(got, "jitter")
+		if len(vals) != 1 || vals[0] != 1.0 {
+			t.Errorf("consecutive_diff_median = %v, want [1]", vals)
+		}
+	})
+
+	t.Run("time_in_state_event_time", func(t *testing.T) {
+		t.Parallel()
+		// 窗口 [base,base+10s)，按事件时间（乱序到达）：
+		// 0s ok, 1s error, 4s error, 5s ok, 7s error, 9s ok
+		// error 时长 = (1→4)+(4→5)+(7→9) = 3+1+2 = 6s；最后一个样本不延伸到窗口结束
+		base := time.Now().UnixMilli()/10000*10000 - 60000
+		in := []map[string]any{
+			{"g": "a", "ts": base, "status": "ok"},
+			{"g": "a", "ts": base + 5000, "status": "ok"},
+			{"g": "a", "ts": base + 1000, "status": "error"},
+			{"g": "a", "ts": base + 4000, "status": "error"},
+			{"g": "a", "ts": base + 9000, "status": "ok"},
+			{"g": "a", "ts": base + 7000, "status": "error"},
+			{"g": "z", "ts": base + 20000, "status": "ok"}, // 推水位触发
+		}
+		got := runWindow(t, `SELECT g, time_in_state(status, 'error') AS t FROM stream GROUP BY g, TumblingWindow('10s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`, in)
+		var rows []map[string]any
+		for _, r := range got {
+			if r["g"] == "a" {
+				rows = append(rows, r)
+			}
+		}
+		require.Len(t, rows, 1)
+		assert.Equal(t, 6.0, rows[0]["t"])
+	})
+
+	t.Run("window_delta_rate_event_time", func(t *testing.T) {
+		t.Parallel()
+		// 窗口 [base,base+10s)，乱序到达：a 首值 100@1s、末值 160@9s；b 只有一个值
+		base := time.Now().UnixMilli()/10000*10000 - 60000
+		in := []map[string]any{
+			{"g": "a", "ts": base + 5000, "v": 120},
+			{"g": "a", "ts": base + 9000, "v": 160},
+			{"g": "a", "ts": base + 1000, "v": 100},
+			{"g": "a", "ts": base + 7000, "v": 90},
+			{"g": "b", "ts": base + 2000, "v": 7},
+			// c 的计数器在窗口内重置：rate 为 0，window_rate 为负
+			{"g": "c", "ts": base + 1000, "v": 200},
+			{"g": "c", "ts": base + 6000, "v": 50},
+			{"g": "z", "ts": base + 20000, "v": 0}, // 推水位触发
+		}
+		got := runWindow(t, `SELECT g, window_delta(v) AS d, window_rate(v) AS r, rate(v) AS rt FROM stream GROUP BY g, TumblingWindow('10s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`, in)
+		rows := map[string]map[string]any{}
+		for _, r := range got {
+			if g, _ := r["g"].(string); g != "z" {
+				rows[g] = r
+			}
+		}
+		require.Len(t, rows, 3)
+		assert.Equal(t, 60.0, rows["a"]["d"])
+		assert.Equal(t, 6.0, rows["a"]["r"])
+		assert.Equal(t, 6.0, rows["a"]["rt"])
+		assert.Nil(t, rows["b"]["d"])
+		assert.Nil(t, rows["b"]["r"])
+		assert.Nil(t, rows["b"]["rt"])
+		assert.Equal(t, -15.0, rows["c"]["r"])
+		assert.Equal(t, 0.0, rows["c"]["rt"])
+	})
+
+	t.Run("trimmed_mean_drops_outliers", func(t *testing.T) {
+		t.Parallel()
+		seq := []float64{20, 21, 500, 19, 22, 20, -300, 21, 19, 18}
+		in := make([]map[string]any, 0, len(seq))
+		for _, v := range seq {
+			in = append(in, map[string]any{"g": "s", "v": v})
+		}
+		got := runWindow(t, `SELECT trimmed_mean(v, 0.1) AS tm, avg(v) AS a FROM stream GROUP BY g, CountingWindow(10)`, in)
+		require.Len(t, got, 1)
+		// 去掉 -300 与 500 后 18..22 共 8 个值，和 160 -> 20；普通均值被尖峰拉偏
+		assert.Equal(t, 20.0, got[0]["tm"])
+		assert.Equal(t, 36.0, got[0]["a"])
+	})
+
+	t.Run("max_row_min_row_return_extremum_rows", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"g": "a", "t": 21.5, "site": "north"},
+			{"g": "b", "t": 18.0, "site": "west"},
+			{"g": "a", "t": 30.2, "site": "south"},
+			{"g": "a", "t": 19.0, "site": "east"},
+			{"g": "b", "t": 25.0, "site": "center"},
+			{"g": "b", "t": 12.0, "site": "river"},
+		}
+		got := runWindow(t, `SELECT g, max_row(t) AS hot, min_row(t) AS cold, max(t) AS mx FROM stream GROUP BY g, CountingWindow(3)`, in)
+		require.Len(t, got, 2)
+		rows := map[string]map[string]any{}
+		for _, r := range got {
+			rows[r["g"].(string)] = r
+		}
+		hot := rows["a"]["hot"].(map[string]any)
+		assert.Equal(t, rows["a"]["mx"], hot["t"])
+		assert.Equal(t, "south", hot["site"])
+		assert.Equal(t, "east", rows["a"]["cold"].(map[string]any)["site"])
+		assert.Equal(t, "center", rows["b"]["hot"].(map[string]any)["site"])
+		assert.Equal(t, "river", rows["b"]["cold"].(map[string]any)["site"])
+	})
+
+	t.Run("top_k_returns_largest_values", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"g": "a", "v": 21.0}, {"g": "a", "v": 35.5}, {"g": "a", "v": 18.0}, {"g": "a", "v": 30.0},
+			{"g": "b", "v": 7.0},
+		}
+		got := runWindow(t, `SELECT g, top_k(v, 3) AS hottest FROM stream GROUP BY g, CountingWindow(4)`, in)
+		require.NotEmpty(t, got)
+		rows := map[string]map[string]any{}
+		for _, r := range got {
+			rows[r["g"].(string)] = r
+		}
+		assert.Equal(t, []any{35.5, 30.0, 21.0}, rows["a"]["hottest"])
+
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		assert.Error(t, ssql.Execute(`SELECT top_k(v, 0) AS t FROM stream GROUP BY CountingWindow(4)`))
+		assert.Error(t, ssql.Execute(`SELECT top_k(v, g) AS t FROM stream GROUP BY CountingWindow(4)`))
+	})
+
+	t.Run("histogram_buckets_with_underflow_and_overflow", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{}
+		for _, ms := range []float64{-1, 5, 12, 18, 30, 45} {
+			in = append(in, map[string]any{"g": "api", "ms": ms})
+		}
+		got := runWindow(t, `SELECT histogram(ms, 0, 40, 4) AS h FROM stream GROUP BY g, CountingWindow(6)`, in)
+		require.Len(t, got, 1)
+		buckets := got[0]["h"].([]any)
+		require.Len(t, buckets, 6)
+		counts := make([]int64, len(buckets))
+		for i, b := range buckets {
+			counts[i] = b.(map[string]any)["count"].(int64)
+		}
+		assert.Equal(t, []int64{1, 1, 2, 0, 1, 1}, counts)
+		assert.Equal(t, map[string]any{"from": 10.0, "to": 20.0, "count": int64(2)}, buckets[2])
+
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		assert.Error(t, ssql.Execute(`SELECT histogram(ms, 0, 40, 0) AS h FROM stream GROUP BY CountingWindow(6)`))
+	})
+
+	t.Run("wavg_weighted_average_with_expression_value", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"g": "s", "t": 10.0, "c": 1.0}, {"g": "s", "t": 20.0, "c": 3.0},
+			{"g": "s", "t": 99.0, "c": nil}, {"g": "s", "t": nil, "c": 5.0},
+			{"g": "z", "t": 5.0, "c": 0.0}, {"g": "z", "t": 6.0, "c": 0.0},
+			{"g": "z", "t": 7.0, "c": 0.0}, {"g": "z", "t": 8.0, "c": 0.0},
+		}
+		got := runWindow(t, `SELECT g, wavg(t, c) AS w, wavg(t*1.8+32, c) AS wf, wavg(t, c*2) AS w2 FROM stream GROUP BY g, CountingWindow(4)`, in)
+		require.NotEmpty(t, got)
+		rows := map[string]map[string]any{}
+		for _, r := range got {
+			rows[r["g"].(string)] = r
+		}
+		assert.InDelta(t, 17.5, rows["s"]["w"], 1e-9)
+		assert.InDelta(t, 63.5, rows["s"]["wf"], 1e-9)
+		assert.InDelta(t, 17.5, rows["s"]["w2"], 1e-9)
+		// 总权重为 0：NULL 而非除零
+		assert.Nil(t, rows["z"]["w"])
+	})
+
+	t.Run("trimmed_mean_invalid_fraction_rejected", func(t *testing.T) {
+		t.Parallel()
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		assert.Error(t, ssql.Execute(`SELECT trimmed_mean(v, 0.5) AS tm FROM stream GROUP BY g, CountingWindow(2)`))
+		assert.Error(t, ssql.Execute(`SELECT trimmed_mean(v, 0.7) AS tm FROM stream GROUP BY g, CountingWindow(2)`))
+		assert.Error(t, ssql.Execute(`SELECT trimmed_mean(v, f) AS tm FROM stream GROUP BY g, CountingWindow(2)`))
+	})
+
+	t.Run("consecutive_diff_median_single_value_is_nil", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{{"g": "s", "v": 1.0}}
+		got := runWindow(t, `SELECT consecutive_diff_median(v) AS jitter FROM stream GROUP BY g, CountingWindow(1)`, in)
+		require.Len(t, got, 1)
+		assert.Nil(t, got[0]["jitter"])
+	})
+
+	t.Run("value_counts_multi_category", func(t *testing.T) {
+		t.Parallel()
+		cats := []string{"red", "blue", "red", "green", "red", "blue"}
+		in := make([]map[string]any, 0, len(cats))
+		for _, c := range cats {
+			in = append(in, map[string]any{"g": "s", "c": c})
+		}
+		got := runWindow(t, `SELECT value_counts(c) AS dist, value_counts(c, 1) AS top FROM stream GROUP BY g, CountingWindow(6)`, in)
+		require.Len(t, got, 1)
+		assert.Equal(t, map[string]int{"red": 3, "blue": 2, "green": 1}, got[0]["dist"])
+		assert.Equal(t, map[string]int{"red": 3}, got[0]["top"])
+
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		assert.Error(t, ssql.Execute(`SELECT value_counts(c, 0) AS top FROM stream GROUP BY g, CountingWindow(6)`))
+		assert.Error(t, ssql.Execute(`SELECT value_counts(c, -2) AS top FROM stream GROUP BY g, CountingWindow(6)`))
+	})
+
+	t.Run("approx_count_distinct_per_group", func(t *testing.T) {
+		t.Parallel()
+		in := make([]map[string]any, 0, 60)
+		for i := 0; i < 30; i++ {
+			in = append(in, map[string]any{"g": "a", "id": i % 10}, map[string]any{"g": "b", "id": i})
+		}
+		got := runWindow(t, `SELECT g, approx_count_distinct(id) AS n FROM stream GROUP BY g, CountingWindow(30)`, in)
+		require.Len(t, got, 2)
+		byGroup := map[any]any{}
+		for _, r := range got {
+			byGroup[r["g"]] = r["n"]
+		}
+		assert.Equal(t, int64(10), byGroup["a"])
+		assert.Equal(t, int64(30), byGroup["b"])
+	})
+
+	t.Run("count_distinct_exact_per_window", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"g": "a", "id": 1}, {"g": "a", "id": 1}, {"g": "a", "id": nil}, {"g": "a", "id": 2},
+			{"g": "a", "id": 3}, {"g": "a", "id": 3}, {"g": "a", "id": 3}, {"g": "a", "id": 3},
+		}
+		got := runWindow(t, `SELECT g, COUNT(DISTINCT id) AS n, count(*) AS total FROM stream GROUP BY g, CountingWindow(4) HAVING COUNT(DISTINCT id) > 0`, in)
+		require.Len(t, got, 2)
+		// 每个窗口独立去重：第二个窗口只看到 3，不受第一个窗口的 1、2 影响
+		assert.ElementsMatch(t, []any{int64(2), int64(1)}, []any{got[0]["n"], got[1]["n"]})
+	})
+
+	t.Run("string_agg_separator_skips_null", func(t *testing.T) {
+		t.Parallel()
+		in := []map[string]any{
+			{"g": "s", "name": "a"}, {"g": "s", "name": nil}, {"g": "s"}, {"g": "s", "name": "b,c"}, {"g": "s", "name": 3},
+		}
+		got := runWindow(t, `SELECT string_agg(name, ' | ') AS piped, string_agg(name, ',') AS csv, string_agg(name) AS plain FROM stream GROUP BY g, CountingWindow(5)`, in)
+		require.Len(t, got, 1)
+		assert.Equal(t, "a | b,c | 3", got[0]["piped"])
+		assert.Equal(t, "a,b,c,3", got[0]["csv"])
+		assert.Equal(t, "a,b,c,3", got[0]["plain"])
+	})
+
+	t.Run("string_agg_non_constant_separator_rejected", func(t *testing.T) {
+		t.Parallel()
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		err := ssql.Execute(`SELECT string_agg(name, sep) AS s FROM stream GROUP BY g, CountingWindow(2)`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "separator must be a constant string")
+	})
 }
 
 // ---------- SQL feature: SELECT DISTINCT ----------
@@ -2,6 +2,7 @@ package e2e
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -555,6 +556,70 @@ func TestFunctionIntegrationAggregation(t *testing.T) {
 	})
 }
 
+// TestCollectPreservesOrderAndNulls collect() 按到达顺序收集，NULL 收集为 nil，
+// 下标与 count(*) 对齐。
+func TestCollectPreservesOrderAndNulls(t *testing.T) {
+	t.Parallel()
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT device, collect(temperature) as temps, count(*) as cnt FROM stream GROUP BY device, TumblingWindow('1s')"))
+
+	resultChan := make(chan []map[string]any, 4)
+	ssql.AddSink(func(result []map[string]any) { resultChan <- result })
+
+	for _, temp := range []any{30.0, 10.0, nil, 20.0, 10.0} {
+		ssql.Emit(map[string]any{"device": "sensor1", "temperature": temp})
+	}
+	time.Sleep(200 * time.Millisecond)
+	ssql.TriggerWindow()
+
+	select {
+	case rows := <-resultChan:
+		require.Len(t, rows, 1)
+		assert.Equal(t, []any{30.0, 10.0, nil, 20.0, 10.0}, rows[0]["temps"])
+		assert.Equal(t, 5.0, rows[0]["cnt"])
+	case <-time.After(3 * time.Second):
+		t.Fatal("测试超时，未收到结果")
+	}
+}
+
+// TestArrayAggKeepsDuplicates array_agg() 保留重复值、NULL 与到达顺序，可聚合表达式，
+// 与 deduplicate() 的去重结果对照。
+func TestArrayAggKeepsDuplicates(t *testing.T) {
+	t.Parallel()
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(`SELECT device, array_agg(a) AS vals, array_agg(a + b) AS sums,
+		deduplicate(a) AS uniq FROM stream GROUP BY device, TumblingWindow('1s')`))
+
+	resultChan := make(chan []map[string]any, 4)
+	ssql.AddSink(func(result []map[string]any) { resultChan <- result })
+
+	for _, row := range []map[string]any{
+		{"a": 2.0, "b": 1.0}, {"a": 1.0, "b": 1.0}, {"a": 2.0, "b": 1.0}, {"b": 1.0}, {"a": 1.0, "b": 3.0},
+	} {
+		row["device"] = "sensor1"
+		ssql.Emit(row)
+	}
+	time.Sleep(200 * time.Millisecond)
+	ssql.TriggerWindow()
+
+	select {
+	case rows := <-resultChan:
+		require.Len(t, rows, 1)
+		assert.Equal(t, []any{2.0, 1.0, 2.0, nil, 1.0}, rows[0]["vals"])
+		assert.Equal(t, []any{3.0, 2.0, 3.0, nil, 4.0}, rows[0]["sums"])
+		assert.Len(t, rows[0]["uniq"], 2)
+	case <-time.After(3 * time.Second):
+		t.Fatal("测试超时，未收到结果")
+	}
+
+	// 空结果序列化为 [] 而不是 null
+	empty, err := json.Marshal(functions.NewArrayAggFunction().New().Result())
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(empty))
+}
+
 // TestFunctionIntegrationMixed 测试混合函数场景
 func TestFunctionIntegrationMixed(t *testing.T) {
 	t.Parallel()
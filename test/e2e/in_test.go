@@ -0,0 +1,102 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInOperator 测试 WHERE/HAVING 中的 IN 与 NOT IN (list)
+func TestInOperator(t *testing.T) {
+	t.Parallel()
+
+	emitAll := func(t *testing.T, sql string, rows []map[string]any) []any {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(sql))
+		var ids []any
+		for _, row := range rows {
+			result, err := ssql.EmitSync(row)
+			require.NoError(t, err)
+			if result != nil {
+				ids = append(ids, result["deviceId"])
+			}
+		}
+		return ids
+	}
+	rows := []map[string]any{
+		{"deviceId": "a", "status": 1},
+		{"deviceId": "b", "status": 2.0},
+		{"deviceId": "c", "status": "3"},
+		{"deviceId": "d", "status": nil},
+		{"deviceId": "e"},
+	}
+
+	t.Run("字符串列表", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE deviceId IN ('a', 'c', 'x')", rows)
+		assert.Equal(t, []any{"a", "c"}, ids)
+	})
+
+	t.Run("NOT IN 数值列表，NULL 为 false", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE status NOT IN (1, 2)", rows)
+		assert.Equal(t, []any{"c"}, ids)
+	})
+
+	t.Run("混合类型列表按等值规则比较", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE status IN ('x', 3, 2)", rows)
+		assert.Equal(t, []any{"b", "c"}, ids)
+	})
+
+	t.Run("与其他条件组合", func(t *testing.T) {
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE deviceId IN ('a', 'b', 'c') AND status NOT IN (3)", rows)
+		assert.Equal(t, []any{"a", "b"}, ids)
+	})
+
+	t.Run("空列表", func(t *testing.T) {
+		assert.Empty(t, emitAll(t, "SELECT deviceId FROM stream WHERE deviceId IN ()", rows))
+		ids := emitAll(t, "SELECT deviceId FROM stream WHERE deviceId NOT IN ()", rows)
+		assert.Equal(t, []any{"a", "b", "c", "d", "e"}, ids)
+	})
+
+	having := func(t *testing.T, sql string) []map[string]any {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(sql))
+		ch := make(chan []map[string]any, 1)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+		for _, row := range []map[string]any{
+			{"deviceId": "a", "v": 1}, {"deviceId": "a", "v": 2},
+			{"deviceId": "b", "v": 1},
+			{"deviceId": "c", "v": 1}, {"deviceId": "c", "v": 1}, {"deviceId": "c", "v": 1},
+		} {
+			ssql.Emit(row)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ssql.TriggerWindow()
+		select {
+		case results := <-ch:
+			return results
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+		}
+		return nil
+	}
+
+	t.Run("HAVING", func(t *testing.T) {
+		results := having(t, "SELECT deviceId, count(*) AS cnt FROM stream GROUP BY deviceId, TumblingWindow('1h') HAVING cnt IN (1, 3)")
+		got := map[any]any{}
+		for _, r := range results {
+			got[r["deviceId"]] = r["cnt"]
+		}
+		assert.Equal(t, map[any]any{"b": 1.0, "c": 3.0}, got)
+	})
+
+	t.Run("HAVING 含 CASE", func(t *testing.T) {
+		results := having(t, "SELECT deviceId, count(*) AS cnt FROM stream GROUP BY deviceId, TumblingWindow('1h') HAVING CASE WHEN deviceId NOT IN ('a', 'b') THEN 1 ELSE 0 END")
+		require.Len(t, results, 1)
+		assert.Equal(t, "c", results[0]["deviceId"])
+	})
+}
@@ -411,3 +411,47 @@ func TestJoinAggregationMultipleTables(t *testing.T) {
 		t.Fatal("timeout waiting for multi-table aggregation")
 	}
 }
+
+// TestJoinAggregationRowPooling runs a JOIN over consecutive windows with
+// WithRowPooling: recycled JOIN working rows must not leak into later windows,
+// and result rows a sink keeps must stay unchanged.
+func TestJoinAggregationRowPooling(t *testing.T) {
+	t.Parallel()
+	ssql := streamsql.New(streamsql.WithRowPooling())
+	defer ssql.Stop()
+	sql := `SELECT m.location, SUM(temp) AS total, COUNT(*) AS cnt
+		FROM stream JOIN meta m ON deviceId = m.deviceId
+		GROUP BY m.location, CountingWindow(2)`
+	require.NoError(t, ssql.Execute(sql))
+	_, err := ssql.RegisterTable("meta", deviceMetaRows())
+	require.NoError(t, err)
+
+	ch := make(chan []map[string]any, 8)
+	ssql.AddSink(func(results []map[string]any) { ch <- results })
+
+	var inputs []map[string]any
+	var kept []map[string]any
+	for i := 1; i <= 6; i++ {
+		row := map[string]any{"deviceId": "d1", "temp": float64(i)}
+		inputs = append(inputs, row)
+		ssql.Emit(row)
+		if i%2 == 0 {
+			select {
+			case res := <-ch:
+				kept = append(kept, res...)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for window %d", i/2)
+			}
+		}
+	}
+
+	require.Len(t, kept, 3)
+	for i, row := range kept {
+		assert.Equal(t, "plantA", row["location"])
+		assert.EqualValues(t, 2, row["cnt"])
+		assert.InEpsilon(t, float64(4*i+3), row["total"], 0.0001)
+	}
+	for i, row := range inputs {
+		assert.Equal(t, map[string]any{"deviceId": "d1", "temp": float64(i + 1)}, row)
+	}
+}
@@ -0,0 +1,90 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 无窗口 GROUP BY：latest() 按分组键维护各自的最新值。
+func TestLatestPerKey(t *testing.T) {
+	t.Run("per_event", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, latest(temperature) AS t FROM stream GROUP BY deviceId"))
+
+		var got []map[string]any
+		for _, in := range []map[string]any{
+			{"deviceId": "a", "temperature": 1},
+			{"deviceId": "b", "temperature": 2},
+			{"deviceId": "a", "temperature": 3},
+			{"deviceId": "b", "temperature": nil},
+		} {
+			row, err := ssql.EmitSync(in)
+			require.NoError(t, err)
+			got = append(got, row)
+		}
+		assert.EqualValues(t, 1, got[0]["t"])
+		assert.EqualValues(t, 2, got[1]["t"])
+		assert.EqualValues(t, 3, got[2]["t"], "a keeps its own latest value")
+		assert.EqualValues(t, 2, got[3]["t"], "b ignores NULL and keeps 2, not a's 3")
+	})
+
+	t.Run("snapshot_interval", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithStateFlushInterval(100 * time.Millisecond))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, latest(temperature) AS t FROM stream GROUP BY deviceId"))
+		ch := make(chan []map[string]any, 8)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+		ssql.EmitMany([]map[string]any{
+			{"deviceId": "a", "temperature": 1},
+			{"deviceId": "b", "temperature": 2},
+			{"deviceId": "a", "temperature": 3},
+		})
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 2, "one row per key")
+			assert.Equal(t, "a", rows[0]["deviceId"])
+			assert.EqualValues(t, 3, rows[0]["t"])
+			assert.Equal(t, "b", rows[1]["deviceId"])
+			assert.EqualValues(t, 2, rows[1]["t"])
+		case <-time.After(2 * time.Second):
+			t.Fatal("snapshot was not emitted")
+		}
+
+		// 状态未变化时不输出快照
+		select {
+		case rows := <-ch:
+			t.Fatalf("unexpected snapshot without changes: %v", rows)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		_, err := ssql.EmitSync(map[string]any{"deviceId": "a", "temperature": 4})
+		assert.Error(t, err)
+	})
+
+	t.Run("close_input_emits_final_snapshot", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithStateFlushInterval(time.Hour))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT deviceId, latest(temperature) AS t FROM stream GROUP BY deviceId"))
+		var got []map[string]any
+		ssql.AddSyncSink(func(r []map[string]any) { got = r })
+
+		ssql.Emit(map[string]any{"deviceId": "a", "temperature": 1})
+		ssql.Emit(map[string]any{"deviceId": "a", "temperature": 5})
+		require.NoError(t, ssql.CloseInput())
+		require.Len(t, got, 1)
+		assert.EqualValues(t, 5, got[0]["t"])
+	})
+
+	t.Run("window_query_unaffected", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithStateFlushInterval(time.Second))
+		defer ssql.Stop()
+		// 窗口查询忽略该选项；无 GROUP BY 的非窗口查询同样不受影响
+		assert.NoError(t, ssql.Execute("SELECT deviceId, COUNT(*) AS c FROM stream GROUP BY deviceId, TumblingWindow('1s')"))
+	})
+}
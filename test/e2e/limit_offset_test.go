@@ -0,0 +1,78 @@
+package e2e
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runDirectOffset 执行非聚合查询，依次发送 i=0..n-1，返回 sink 收到的 i（升序）。
+func runDirectOffset(t *testing.T, sql string, n int) []int {
+	t.Helper()
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(sql))
+
+	var mu sync.Mutex
+	var got []int
+	ssql.AddSyncSink(func(rows []map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range rows {
+			got = append(got, r["i"].(int))
+		}
+	})
+	for i := 0; i < n; i++ {
+		ssql.Emit(map[string]any{"i": i})
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Ints(got)
+	return got
+}
+
+func TestLimitOffset_NonAggregation(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		sql  string
+		want []int
+	}{
+		{"LIMIT+OFFSET", "SELECT i FROM stream LIMIT 3 OFFSET 5", []int{5, 6, 7}},
+		{"仅 OFFSET", "SELECT i FROM stream OFFSET 7", []int{7, 8, 9}},
+		{"仅 LIMIT", "SELECT i FROM stream LIMIT 2", []int{0, 1}},
+		{"OFFSET 超过记录数", "SELECT i FROM stream LIMIT 3 OFFSET 20", nil},
+		{"OFFSET 按 WHERE 之后的结果计", "SELECT i FROM stream WHERE i >= 4 LIMIT 2 OFFSET 1", []int{5, 6}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, c.want, runDirectOffset(t, c.sql, 10))
+		})
+	}
+}
+
+// EmitSync 同样按 OFFSET/LIMIT 过滤：范围外的记录返回 nil，也不投递给 sink。
+func TestLimitOffset_EmitSync(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT i FROM stream WHERE i >= 1 LIMIT 2 OFFSET 1"))
+
+	var got []any
+	for i := 0; i < 6; i++ {
+		r, err := ssql.EmitSync(map[string]any{"i": i})
+		require.NoError(t, err)
+		if r != nil {
+			got = append(got, r["i"])
+		}
+	}
+	assert.Equal(t, []any{2, 3}, got)
+}
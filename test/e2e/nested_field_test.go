@@ -487,3 +487,42 @@ func TestArrayFieldAccess(t *testing.T) {
 		}
 	})
 }
+
+// TestNestedFieldOutputKeys 输出键：带别名的嵌套字段只用别名作键，不带别名的保留点号路径；
+// 直连、SELECT * 混合列与聚合路径一致。
+func TestNestedFieldOutputKeys(t *testing.T) {
+	t.Parallel()
+	in := []map[string]any{{
+		"temp":   21.5,
+		"device": map[string]any{"location": "A栋", "info": map[string]any{"name": "sensor-1"}},
+	}}
+	cases := []struct {
+		name, sql string
+		window    bool
+		want      map[string]any
+	}{
+		{"direct", "SELECT device.info.name as n, device.location FROM stream", false,
+			map[string]any{"n": "sensor-1", "device.location": "A栋"}},
+		{"select_star", "SELECT *, device.info.name as n, device.location FROM stream", false,
+			map[string]any{"n": "sensor-1", "device.location": "A栋", "temp": 21.5}},
+		{"aggregation", "SELECT device.info.name as n, device.location, max(temp) as m FROM stream GROUP BY device.info.name, device.location, CountingWindow(1)", true,
+			map[string]any{"n": "sensor-1", "device.location": "A栋", "m": 21.5}},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got []map[string]any
+			if tc.window {
+				got = runWindow(t, tc.sql, in)
+			} else {
+				got = runDirect(t, tc.sql, in)
+			}
+			require.Len(t, got, 1)
+			for k, v := range tc.want {
+				assert.Equal(t, v, got[0][k], "key %q", k)
+			}
+			assert.NotContains(t, got[0], "device.info.name", "带别名的嵌套字段不应以点号路径输出")
+		})
+	}
+}
@@ -265,6 +265,69 @@ func TestQuotedIdentifiersAndStringLiterals(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:        "双引号标识符（含空格或与关键字同名）",
+			sql:         `SELECT "device id", "count", 'device id' AS label, deviceType AS "device type" FROM stream WHERE deviceType = 'temperature'`,
+			testData:    []map[string]any{{"device id": "sensor001", "count": 3, "deviceType": "temperature"}},
+			expectedLen: 1,
+			validator: func(t *testing.T, results []map[string]any) {
+				if len(results) > 0 {
+					resultMap := results[0]
+					assert.Equal(t, "sensor001", resultMap["device id"])
+					assert.Equal(t, 3, resultMap["count"])
+					assert.Equal(t, "device id", resultMap["label"])
+					assert.Equal(t, "temperature", resultMap["device type"])
+					assert.NotContains(t, resultMap, `"device id"`)
+				}
+			},
+		},
+		{
+			name:        "双引号标识符：行中缺少该字段时为 NULL",
+			sql:         `SELECT deviceId, "device id" FROM stream`,
+			testData:    []map[string]any{{"deviceId": "sensor001"}},
+			expectedLen: 1,
+			validator: func(t *testing.T, results []map[string]any) {
+				if len(results) > 0 {
+					assert.Nil(t, results[0]["device id"])
+				}
+			},
+		},
+		{
+			name:        "WHERE中使用双引号标识符",
+			sql:         `SELECT deviceId FROM stream WHERE "device id" = 6 AND "count" > 2`,
+			testData:    []map[string]any{{"deviceId": "a", "device id": 6, "count": 3}, {"deviceId": "b", "device id": 5, "count": 3}, {"deviceId": "c", "device id": 6, "count": 1}},
+			expectedLen: 1,
+			validator: func(t *testing.T, results []map[string]any) {
+				if len(results) > 0 {
+					assert.Equal(t, "a", results[0]["deviceId"])
+				}
+			},
+		},
+		{
+			name:        "双引号标识符参与表达式与函数参数",
+			sql:         `SELECT "device id" + 1 AS next, upper("device name") AS name, concat("device name", '-', "count") AS tag FROM stream`,
+			testData:    []map[string]any{{"device id": 6, "device name": "ab", "count": 3}},
+			expectedLen: 1,
+			validator: func(t *testing.T, results []map[string]any) {
+				if len(results) > 0 {
+					assert.EqualValues(t, 7, results[0]["next"])
+					assert.Equal(t, "AB", results[0]["name"])
+					assert.Equal(t, "ab-3", results[0]["tag"])
+				}
+			},
+		},
+		{
+			name:        "GROUP BY与HAVING中使用双引号标识符",
+			sql:         `SELECT "device id", SUM("count") AS total FROM stream GROUP BY "device id", CountingWindow(2) HAVING SUM("count") > 2`,
+			testData:    []map[string]any{{"device id": "a", "count": 1}, {"device id": "a", "count": 2}},
+			expectedLen: 1,
+			validator: func(t *testing.T, results []map[string]any) {
+				if len(results) > 0 {
+					assert.Equal(t, "a", results[0]["device id"])
+					assert.EqualValues(t, 3, results[0]["total"])
+				}
+			},
+		},
 	}
 
 	// 执行所有测试用例
@@ -311,6 +374,32 @@ func TestStringConstantExpressions(t *testing.T) {
 			expectedLen: 2,
 			validator:   stringConstantValidator("aa"),
 		},
+		{
+			name:        "字符串常量与SELECT *组合",
+			sql:         `SELECT 'aaa' as test1, "aaa" as test2, * FROM stream WHERE deviceId LIKE 'sensor%'`,
+			testData:    testData,
+			expectedLen: 2,
+			validator: func(t *testing.T, results []map[string]any) {
+				for _, result := range results {
+					assert.Equal(t, "aaa", result["test1"])
+					assert.Equal(t, "aaa", result["test2"])
+					assert.NotNil(t, result["deviceType"])
+				}
+			},
+		},
+		{
+			name:        "数值和布尔常量作为字段",
+			sql:         "SELECT deviceId, 1 as one, 2.5 as half, true as flag FROM stream WHERE deviceId LIKE 'sensor%'",
+			testData:    testData,
+			expectedLen: 2,
+			validator: func(t *testing.T, results []map[string]any) {
+				for _, result := range results {
+					assert.EqualValues(t, 1, result["one"])
+					assert.Equal(t, 2.5, result["half"])
+					assert.Equal(t, true, result["flag"])
+				}
+			},
+		},
 	}
 
 	// 执行所有测试用例
@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamSelfJoin pairs rows of the same stream per key within a time interval.
+func TestStreamSelfJoin(t *testing.T) {
+	t.Parallel()
+	events := []map[string]any{
+		{"deviceId": "d1", "type": "start", "ts": 1000},
+		{"deviceId": "d2", "type": "start", "ts": 1500},
+		{"deviceId": "d3", "type": "end", "ts": 4000}, // arrives before its start
+		{"deviceId": "d1", "type": "end", "ts": 3000},
+		{"deviceId": "d3", "type": "start", "ts": 2000},
+		{"deviceId": "d2", "type": "end", "ts": 9000}, // 7500 after its start: out of range
+	}
+
+	t.Run("start_end_pairs", func(t *testing.T) {
+		sql := "SELECT a.deviceId, a.ts AS start_ts, b.ts AS end_ts FROM stream a JOIN stream b ON a.deviceId = b.deviceId " +
+			"WHERE a.type = 'start' AND b.type = 'end' AND b.ts - a.ts BETWEEN 0 AND 5000"
+		got := runWindow(t, sql, events)
+		assert.ElementsMatch(t, []map[string]any{
+			{"deviceId": "d1", "start_ts": 1000, "end_ts": 3000},
+			{"deviceId": "d3", "start_ts": 2000, "end_ts": 4000},
+		}, got)
+	})
+
+	t.Run("reversed_predicate", func(t *testing.T) {
+		// a.ts - b.ts BETWEEN -5000 AND 0 is the same interval written the other way round.
+		sql := "SELECT a.deviceId FROM stream a JOIN stream b ON b.deviceId = a.deviceId " +
+			"WHERE a.type = 'start' AND b.type = 'end' AND a.ts - b.ts BETWEEN -5000 AND 0"
+		got := runWindow(t, sql, events)
+		assert.ElementsMatch(t, []map[string]any{{"deviceId": "d1"}, {"deviceId": "d3"}}, got)
+	})
+
+	t.Run("parenthesized_predicate", func(t *testing.T) {
+		sql := "SELECT a.deviceId FROM stream a JOIN stream b ON a.deviceId = b.deviceId " +
+			"WHERE (a.type = 'start' AND b.type = 'end') AND (b.ts - a.ts BETWEEN 0 AND 5000)"
+		got := runWindow(t, sql, events)
+		assert.ElementsMatch(t, []map[string]any{{"deviceId": "d1"}, {"deviceId": "d3"}}, got)
+	})
+
+	t.Run("window_aggregate", func(t *testing.T) {
+		sql := "SELECT a.deviceId, COUNT(*) AS pairs FROM stream a JOIN stream b ON a.deviceId = b.deviceId " +
+			"WHERE a.type = 'start' AND b.type = 'end' AND b.ts - a.ts BETWEEN 0 AND 5000 " +
+			"GROUP BY a.deviceId, TumblingWindow('200ms')"
+		got := runWindow(t, sql, events)
+		require.Len(t, got, 2)
+		for _, row := range got {
+			assert.Contains(t, []any{"d1", "d3"}, row["deviceId"])
+			assert.EqualValues(t, 1, row["pairs"])
+		}
+	})
+}
+
+func TestStreamSelfJoinErrors(t *testing.T) {
+	t.Parallel()
+	for _, sql := range []string{
+		// no time-range predicate: the buffer would be unbounded
+		"SELECT a.deviceId FROM stream a JOIN stream b ON a.deviceId = b.deviceId",
+		// time predicate under OR does not bound the join
+		"SELECT a.deviceId FROM stream a JOIN stream b ON a.deviceId = b.deviceId WHERE b.ts - a.ts BETWEEN 0 AND 10 OR a.x = 1",
+		// sides need distinct aliases
+		"SELECT deviceId FROM stream JOIN stream ON deviceId = deviceId WHERE ts - ts BETWEEN 0 AND 10",
+		// ON must link the two sides
+		"SELECT a.deviceId FROM stream a JOIN stream b ON a.deviceId = a.id WHERE b.ts - a.ts BETWEEN 0 AND 10",
+	} {
+		ssql := streamsql.New()
+		assert.Error(t, ssql.Execute(sql), sql)
+		ssql.Stop()
+	}
+
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute("SELECT a.deviceId FROM stream a JOIN stream b ON a.deviceId = b.deviceId WHERE b.ts - a.ts BETWEEN 0 AND 10"))
+	_, err := ssql.EmitSync(map[string]any{"deviceId": "d1", "ts": 1})
+	assert.Error(t, err, "EmitSync cannot return the pairs of a self-join")
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/rulego/streamsql"
+	"github.com/rulego/streamsql/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -300,3 +301,98 @@ func TestEmitSyncPerformance(t *testing.T) {
 	assert.Less(t, duration, 1*time.Second, "性能应该足够好")
 	assert.Equal(t, int32(testCount), atomic.LoadInt32(&sinkCallCount), "所有数据都应触发AddSink")
 }
+
+// TestEmitSyncRows EmitSyncRows 同步返回 WHERE/SELECT 之后的全部结果行：
+// 过滤掉返回 nil，unnest 展开为多行，WHERE 与投影错误作为 error 返回
+func TestEmitSyncRows(t *testing.T) {
+	t.Parallel()
+	t.Run("FilterAndProject", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT device, temperature * 2 AS doubled FROM stream WHERE temperature > 20"))
+
+		var sinkRows int32
+		ssql.AddSink(func(results []map[string]any) { atomic.AddInt32(&sinkRows, int32(len(results))) })
+
+		rows, err := ssql.EmitSyncRows(map[string]any{"device": "d1", "temperature": 10.0})
+		require.NoError(t, err)
+		assert.Nil(t, rows, "被 WHERE 过滤的记录返回 nil")
+
+		rows, err = ssql.EmitSyncRows(map[string]any{"device": "d2", "temperature": 30.0})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "d2", rows[0]["device"])
+		assert.Equal(t, 60.0, rows[0]["doubled"])
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&sinkRows) == 1 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("UnnestOrderLimit", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT device, unnest(tags) AS tag FROM stream ORDER BY tag DESC LIMIT 4"))
+
+		rows, err := ssql.EmitSyncRows(map[string]any{"device": "d1", "tags": []any{"a", "c", "b"}})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, []any{"c", "b", "a"}, []any{rows[0]["tag"], rows[1]["tag"], rows[2]["tag"]})
+		assert.Equal(t, "d1", rows[0]["device"])
+
+		// LIMIT 跨调用计数：只剩 1 行额度
+		rows, err = ssql.EmitSyncRows(map[string]any{"device": "d2", "tags": []any{"x", "y"}})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "y", rows[0]["tag"])
+
+		rows, err = ssql.EmitSyncRows(map[string]any{"device": "d3", "tags": []any{"z"}})
+		require.NoError(t, err)
+		assert.Nil(t, rows)
+	})
+
+	t.Run("OffsetLimitSharedWithEmitSync", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT device, unnest(tags) AS tag FROM stream LIMIT 3 OFFSET 2"))
+
+		// 前 2 行被 OFFSET 跳过
+		rows, err := ssql.EmitSyncRows(map[string]any{"device": "d1", "tags": []any{"a", "b", "c"}})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "c", rows[0]["tag"])
+
+		// EmitSync 与 EmitSyncRows 共用计数
+		r, err := ssql.EmitSync(map[string]any{"device": "d2", "tags": []any{"x"}})
+		require.NoError(t, err)
+		assert.NotNil(t, r)
+
+		rows, err = ssql.EmitSyncRows(map[string]any{"device": "d3", "tags": []any{"y", "z"}})
+		require.NoError(t, err)
+		require.Len(t, rows, 1, "LIMIT 只剩 1 行额度")
+		assert.Equal(t, "y", rows[0]["tag"])
+
+		r, err = ssql.EmitSync(map[string]any{"device": "d4", "tags": []any{"w"}})
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT name FROM stream WHERE age > 18"))
+		rows, err := ssql.EmitSyncRows(map[string]any{"name": "a", "age": "unknown"})
+		assert.Error(t, err, "WHERE 求值错误应返回")
+		assert.Nil(t, rows)
+
+		failing := streamsql.New(streamsql.WithProjectionErrorPolicy(types.ProjectionErrorFail))
+		defer failing.Stop()
+		require.NoError(t, failing.Execute("SELECT device, sqrt(temperature) AS root FROM stream"))
+		rows, err = failing.EmitSyncRows(map[string]any{"device": "d1", "temperature": "hot"})
+		assert.Error(t, err, "投影错误应返回")
+		assert.Nil(t, rows)
+
+		agg := streamsql.New()
+		defer agg.Stop()
+		require.NoError(t, agg.Execute("SELECT device, COUNT(*) AS cnt FROM stream GROUP BY device, TumblingWindow('1s')"))
+		_, err = agg.EmitSyncRows(map[string]any{"device": "d1"})
+		assert.Error(t, err, "聚合查询不支持同步处理")
+	})
+}
@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WithTransformFlushInterval：非聚合结果行合并为批次，最早的行等待不超过间隔即输出。
+func TestTransformFlushInterval(t *testing.T) {
+	t.Run("sparse_rows_emit_within_interval", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithTransformFlushInterval(100 * time.Millisecond))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id, v * 2 AS dv FROM stream WHERE v > 0"))
+		ch := make(chan []map[string]any, 8)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+		// 输入缓慢：每行之间的间隔大于刷新间隔，每行都应在间隔内单独输出
+		for i := 1; i <= 3; i++ {
+			start := time.Now()
+			ssql.Emit(map[string]any{"id": i, "v": i})
+			select {
+			case rows := <-ch:
+				require.Len(t, rows, 1)
+				assert.Equal(t, i, rows[0]["id"])
+				assert.EqualValues(t, i*2, rows[0]["dv"])
+				assert.Less(t, time.Since(start), time.Second, "row must not wait for more input")
+			case <-time.After(2 * time.Second):
+				t.Fatalf("row %d was not flushed within the interval", i)
+			}
+		}
+	})
+
+	t.Run("burst_is_batched", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithTransformFlushInterval(200 * time.Millisecond))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+		ch := make(chan []map[string]any, 8)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+		ssql.EmitMany([]map[string]any{{"id": 1}, {"id": 2}, {"id": 3}})
+		select {
+		case rows := <-ch:
+			require.Len(t, rows, 3)
+			assert.Equal(t, []any{1, 2, 3}, []any{rows[0]["id"], rows[1]["id"], rows[2]["id"]})
+		case <-time.After(2 * time.Second):
+			t.Fatal("buffered rows were not flushed")
+		}
+	})
+
+	t.Run("close_input_flushes_pending_rows", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithTransformFlushInterval(time.Hour))
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute("SELECT id FROM stream"))
+		var got []map[string]any
+		ssql.AddSyncSink(func(r []map[string]any) { got = append(got, r...) })
+
+		ssql.Emit(map[string]any{"id": 1})
+		ssql.Emit(map[string]any{"id": 2})
+		require.NoError(t, ssql.CloseInput())
+		require.Len(t, got, 2)
+	})
+}
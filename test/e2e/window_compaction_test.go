@@ -0,0 +1,46 @@
+package e2e
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WithCompactUnchangedWindows：同一分组相邻窗口聚合值不变时合并为一条覆盖整个时间范围的结果，
+// 值变化时分别输出。
+func TestCompactUnchangedWindows(t *testing.T) {
+	ssql := streamsql.New(streamsql.WithCompactUnchangedWindows())
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(`SELECT device, avg(v) AS a, window_start() AS ws, window_end() AS we
+		FROM stream GROUP BY device, TumblingWindow('10s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+	var got []map[string]any
+	ssql.AddSyncSink(func(r []map[string]any) { got = append(got, r...) })
+
+	in := []map[string]any{
+		{"device": "a", "v": 1, "ts": 1000}, {"device": "b", "v": 1, "ts": 1000},
+		{"device": "a", "v": 1, "ts": 11000}, {"device": "b", "v": 2, "ts": 11000},
+		{"device": "a", "v": 1, "ts": 21000}, {"device": "b", "v": 2, "ts": 21000},
+		{"device": "a", "v": 5, "ts": 31000},
+	}
+	for _, row := range in {
+		ssql.Emit(row)
+	}
+	require.NoError(t, ssql.CloseInput())
+
+	ms := func(v any) int64 { return toInt64Val(v) / 1e6 }
+	var spans []string
+	for _, r := range got {
+		spans = append(spans, fmt.Sprintf("%v %v [%d,%d) %v", r["device"], r["a"], ms(r["ws"]), ms(r["we"]), r["window_id"]))
+	}
+	sort.Strings(spans)
+	assert.Equal(t, []string{
+		"a 1 [0,30000) 0_30000000000",
+		"a 5 [30000,40000) 30000000000_40000000000",
+		"b 1 [0,10000) 0_10000000000",
+		"b 2 [10000,30000) 10000000000_30000000000",
+	}, spans)
+}
@@ -0,0 +1,38 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CountingWindow(count, timeout)：流量停滞时未攒满的窗口也会在 timeout 后输出。
+func TestCountingWindowWithTimeout(t *testing.T) {
+	ssql := streamsql.New()
+	defer ssql.Stop()
+	require.NoError(t, ssql.Execute(`SELECT deviceId, count(*) AS c, window_fill_ratio() AS fill
+		FROM stream GROUP BY deviceId, CountingWindow(100, '200ms')`))
+	ch := make(chan []map[string]any, 4)
+	ssql.AddSink(func(r []map[string]any) { ch <- r })
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		ssql.Emit(map[string]any{"deviceId": "d1", "v": i})
+	}
+	select {
+	case results := <-ch:
+		require.Len(t, results, 1)
+		assert.Equal(t, "d1", results[0]["deviceId"])
+		assert.Equal(t, 3.0, results[0]["c"])
+		assert.Equal(t, 0.03, results[0]["fill"])
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial counting window did not fire after its timeout")
+	}
+
+	err := streamsql.New().Execute("SELECT count(*) FROM stream GROUP BY CountingWindow(10, 'soon')")
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package e2e
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// window_fill_ratio()：计数窗口为行数/窗口大小，时间窗口为触发时已过时长/窗口长度。
+func TestWindowFillRatio(t *testing.T) {
+	collect := func(ssql *streamsql.Streamsql) func() []map[string]any {
+		var mu sync.Mutex
+		var out []map[string]any
+		ssql.AddSink(func(r []map[string]any) {
+			mu.Lock()
+			out = append(out, r...)
+			mu.Unlock()
+		})
+		return func() []map[string]any {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]map[string]any(nil), out...)
+		}
+	}
+
+	t.Run("计数窗口满与部分", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT deviceId, count(*) AS c, window_fill_ratio() AS fill FROM stream GROUP BY deviceId, CountingWindow(4)`))
+		results := collect(ssql)
+		for i := 0; i < 4; i++ {
+			ssql.Emit(map[string]any{"deviceId": "full", "v": i})
+		}
+		ssql.Emit(map[string]any{"deviceId": "partial", "v": 1})
+		require.NoError(t, ssql.CloseInput())
+
+		byDevice := map[any]any{}
+		for _, r := range results() {
+			byDevice[r["deviceId"]] = r["fill"]
+		}
+		assert.Equal(t, 1.0, byDevice["full"])
+		assert.Equal(t, 0.25, byDevice["partial"], "CloseInput 提前输出的部分窗口")
+	})
+
+	t.Run("事件时间滚动窗口到期触发", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT count(*) AS c, window_fill_ratio() AS fill FROM stream
+			GROUP BY TumblingWindow('1s') WITH (TIMESTAMP='ts', TIMEUNIT='ms')`))
+		results := collect(ssql)
+		base := time.Now().Add(-10 * time.Second).Truncate(time.Second).UnixMilli()
+		ssql.Emit(map[string]any{"ts": base + 100})
+		ssql.Emit(map[string]any{"ts": base + 400})
+		ssql.Emit(map[string]any{"ts": base + 5000}) // 推进水位线，触发首个窗口
+
+		require.Eventually(t, func() bool { return len(results()) > 0 }, 3*time.Second, 20*time.Millisecond)
+		first := results()[0]
+		assert.EqualValues(t, 2, first["c"])
+		assert.Equal(t, 1.0, first["fill"])
+	})
+
+	t.Run("处理时间窗口提前触发", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT count(*) AS c, window_fill_ratio() AS fill FROM stream GROUP BY TumblingWindow('10s')`))
+		results := collect(ssql)
+		ssql.Emit(map[string]any{"v": 1})
+		time.Sleep(100 * time.Millisecond)
+		ssql.TriggerWindow()
+
+		require.Eventually(t, func() bool { return len(results()) > 0 }, 3*time.Second, 20*time.Millisecond)
+		fill, ok := results()[0]["fill"].(float64)
+		require.True(t, ok, "fill ratio should be a float64, got %v", results()[0]["fill"])
+		assert.Greater(t, fill, 0.0)
+		assert.Less(t, fill, 1.0)
+	})
+
+	t.Run("会话窗口无预期大小", func(t *testing.T) {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT deviceId, window_fill_ratio() AS fill FROM stream GROUP BY deviceId, SessionWindow('100ms')`))
+		results := collect(ssql)
+		ssql.Emit(map[string]any{"deviceId": "d1"})
+
+		require.Eventually(t, func() bool { return len(results()) > 0 }, 3*time.Second, 20*time.Millisecond)
+		assert.Nil(t, results()[0]["fill"])
+	})
+}
@@ -68,8 +68,8 @@ func TestWindowOverRejected(t *testing.T) {
 	}
 }
 
-// TestPerRowWindowFunctionsRejectedAtExecute：row_number()/lead() 已从注册表移除，
-// 引用须在 Execute 期（解析报未知函数）失败，而非静默返回 nil 或崩数据路径。
+// TestPerRowWindowFunctionsRejectedAtExecute：lead() 已从注册表移除，row_number() 仅支持
+// 非聚合查询的 OVER；在窗口查询里引用须在 Execute 期失败，而非静默返回 nil 或崩数据路径。
 // 回归"注册但未接线"的半成品。
 func TestPerRowWindowFunctionsRejectedAtExecute(t *testing.T) {
 	t.Parallel()
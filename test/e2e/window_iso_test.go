@@ -0,0 +1,64 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// window_start_iso()/window_end_iso()：RFC3339 字符串与数值窗口边界表示同一时刻，
+// 偏移量取自 WithTimeZone 配置的时区（默认 UTC）。
+func TestWindowISOBounds(t *testing.T) {
+	run := func(t *testing.T, opts ...streamsql.Option) map[string]any {
+		ssql := streamsql.New(opts...)
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT deviceId, window_start() AS ws, window_end() AS we,
+			window_start_iso() AS ws_iso, window_end_iso() AS we_iso
+			FROM stream GROUP BY deviceId, TumblingWindow('1s')`))
+		ch := make(chan []map[string]any, 1)
+		ssql.AddSink(func(r []map[string]any) { ch <- r })
+		ssql.Emit(map[string]any{"deviceId": "d1", "v": 1})
+		require.NoError(t, ssql.CloseInput())
+		select {
+		case results := <-ch:
+			require.Len(t, results, 1)
+			return results[0]
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+		}
+		return nil
+	}
+	assertSameInstant := func(t *testing.T, ns any, iso any, wantOffset int) {
+		s, ok := iso.(string)
+		require.True(t, ok, "ISO 边界应为字符串，实际 %T", iso)
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		require.NoError(t, err)
+		assert.Equal(t, ns, parsed.UnixNano(), s)
+		_, offset := parsed.Zone()
+		assert.Equal(t, wantOffset, offset, s)
+	}
+
+	t.Run("默认UTC", func(t *testing.T) {
+		r := run(t)
+		assertSameInstant(t, r["ws"], r["ws_iso"], 0)
+		assertSameInstant(t, r["we"], r["we_iso"], 0)
+		assert.Contains(t, r["ws_iso"], "Z")
+	})
+
+	t.Run("配置时区", func(t *testing.T) {
+		r := run(t, streamsql.WithTimeZone("Asia/Shanghai"))
+		assertSameInstant(t, r["ws"], r["ws_iso"], 8*3600)
+		assertSameInstant(t, r["we"], r["we_iso"], 8*3600)
+		assert.Contains(t, r["ws_iso"], "+08:00")
+	})
+
+	t.Run("无效时区", func(t *testing.T) {
+		ssql := streamsql.New(streamsql.WithTimeZone("Mars/Olympus"))
+		defer ssql.Stop()
+		err := ssql.Execute("SELECT count(*) AS c, window_start_iso() AS ws FROM stream GROUP BY TumblingWindow('1s')")
+		assert.ErrorContains(t, err, "invalid time zone")
+	})
+}
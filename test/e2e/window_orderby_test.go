@@ -142,6 +142,47 @@ func TestIntegration_OrderBy_MultiKey(t *testing.T) {
 	assert.Equal(t, "zzz", batch[2]["deviceId"])
 }
 
+// TestIntegration_OrderBy_AggExpression: ORDER BY may repeat the aggregate
+// expression instead of its alias.
+func TestIntegration_OrderBy_AggExpression(t *testing.T) {
+	t.Parallel()
+	sql := `SELECT deviceId, avg(temperature) AS m
+	        FROM stream
+	        GROUP BY deviceId, TumblingWindow('100ms')
+	        ORDER BY avg(temperature) DESC`
+	batch := runOrderByWindow(t, sql, []map[string]any{
+		{"deviceId": "d1", "temperature": 30.0},
+		{"deviceId": "d2", "temperature": 50.0},
+		{"deviceId": "d3", "temperature": 40.0},
+	}, 3)
+
+	assert.Equal(t, "d2", batch[0]["deviceId"])
+	assert.Equal(t, "d3", batch[1]["deviceId"])
+	assert.Equal(t, "d1", batch[2]["deviceId"])
+}
+
+// TestIntegration_OrderBy_CaseInsensitive: ORDER BY matches SELECT aliases and
+// expressions regardless of case.
+func TestIntegration_OrderBy_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+	rows := []map[string]any{
+		{"deviceId": "d1", "temperature": 30.0},
+		{"deviceId": "d2", "temperature": 50.0},
+		{"deviceId": "d3", "temperature": 40.0},
+	}
+	for _, orderBy := range []string{"MEAN DESC", "AVG(temperature) DESC"} {
+		sql := `SELECT deviceId, avg(temperature) AS mean
+		        FROM stream
+		        GROUP BY deviceId, TumblingWindow('100ms')
+		        ORDER BY ` + orderBy
+		batch := runOrderByWindow(t, sql, rows, 3)
+
+		assert.Equal(t, "d2", batch[0]["deviceId"], orderBy)
+		assert.Equal(t, "d3", batch[1]["deviceId"], orderBy)
+		assert.Equal(t, "d1", batch[2]["deviceId"], orderBy)
+	}
+}
+
 // TestIntegration_OrderBy_NonWindowNoCrash: ORDER BY on a non-aggregation query
 // must not break per-row processing (ordering a single-row batch is a no-op).
 func TestIntegration_OrderBy_NonWindowNoCrash(t *testing.T) {
@@ -0,0 +1,61 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPerKeyWatermark 设备时钟偏差很大时，各设备按自己的水位线触发窗口：
+// 时钟落后一小时的设备的数据不会被其他设备推高的水位线判为迟到而丢弃
+func TestPerKeyWatermark(t *testing.T) {
+	t.Parallel()
+	run := func(t *testing.T, opts ...streamsql.Option) map[string]float64 {
+		ssql := streamsql.New(opts...)
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(`SELECT deviceId, COUNT(*) AS cnt FROM stream
+			GROUP BY deviceId, TumblingWindow('1s')
+			WITH (TIMESTAMP='eventTime', TIMEUNIT='ms')`))
+		ch := make(chan []map[string]any, 8)
+		ssql.AddSink(func(results []map[string]any) { ch <- results })
+
+		now := time.Now().Truncate(time.Second).UnixMilli()
+		lag := now - int64(time.Hour/time.Millisecond)
+		for _, e := range []struct {
+			device string
+			ts     int64
+		}{
+			{"fast", now}, {"fast", now + 100},
+			{"slow", lag}, {"slow", lag + 100}, {"slow", lag + 200},
+			{"fast", now + 1500}, {"slow", lag + 1500},
+		} {
+			ssql.Emit(map[string]any{"deviceId": e.device, "eventTime": e.ts})
+		}
+
+		got := map[string]float64{}
+		deadline := time.After(2 * time.Second)
+		for {
+			select {
+			case rows := <-ch:
+				for _, row := range rows {
+					got[row["deviceId"].(string)] += row["cnt"].(float64)
+				}
+			case <-deadline:
+				return got
+			}
+		}
+	}
+
+	t.Run("per-key", func(t *testing.T) {
+		got := run(t, streamsql.WithPerKeyWatermark())
+		assert.Equal(t, map[string]float64{"fast": 2, "slow": 3}, got)
+	})
+	t.Run("global", func(t *testing.T) {
+		got := run(t)
+		assert.Equal(t, 2.0, got["fast"])
+		assert.Zero(t, got["slow"], "the lagging device's rows are late against the global watermark")
+	})
+}
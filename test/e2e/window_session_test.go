@@ -694,3 +694,41 @@ END:
 		}
 	}
 }
+
+// TestSQLSessionWindow_PerRowGap 会话间隔取自字段：不同速率的设备各按自己的间隔关闭会话
+func TestSQLSessionWindow_PerRowGap(t *testing.T) {
+	t.Parallel()
+	ssql := streamsql.New()
+	defer ssql.Stop()
+
+	err := ssql.Execute(`
+        SELECT deviceId, COUNT(*) as cnt
+        FROM stream
+        GROUP BY deviceId, SessionWindow(gap_ms / 1000, '1m')
+    `)
+	require.NoError(t, err)
+
+	ch := make(chan []map[string]any, 4)
+	ssql.AddSink(func(results []map[string]any) { ch <- results })
+
+	for i := 0; i < 3; i++ {
+		ssql.Emit(map[string]any{"deviceId": "fast", "gap_ms": 200})
+	}
+	ssql.Emit(map[string]any{"deviceId": "slow", "gap_ms": 30000})
+
+	select {
+	case res := <-ch:
+		require.Len(t, res, 1)
+		assert.Equal(t, "fast", res[0]["deviceId"])
+		assert.Equal(t, float64(3), res[0]["cnt"])
+	case <-time.After(3 * time.Second):
+		t.Fatal("间隔 200ms 的会话应该关闭")
+	}
+
+	// slow 的会话间隔为 30s，此时仍未关闭
+	select {
+	case res := <-ch:
+		t.Fatalf("slow 会话不应关闭: %v", res)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
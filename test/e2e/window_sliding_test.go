@@ -2,11 +2,15 @@ package e2e
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/rulego/streamsql"
+	"github.com/rulego/streamsql/stream"
+	"github.com/rulego/streamsql/window"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -1693,3 +1697,67 @@ END:
 		}
 	}
 }
+
+// TestSQLHopWindow_MatchesSlidingWindow 验证 HopWindow 与 SlidingWindow 输出一致
+// （含 window_id、window_start()/window_end() 等窗口边界），仅窗口类型标识不同（hop / sliding）；
+// 步长等于窗口大小时与 TumblingWindow 的窗口边界一致
+func TestSQLHopWindow_MatchesSlidingWindow(t *testing.T) {
+	t.Parallel()
+	run := func(windowFn string, opts ...streamsql.Option) ([]string, any) {
+		ssql := streamsql.New(opts...)
+		defer ssql.Stop()
+		err := ssql.Execute(`SELECT deviceId, COUNT(*) AS cnt, SUM(temperature) AS total,
+				window_start() AS ws, window_end() AS we, window_start_iso() AS ws_iso, window_end_iso() AS we_iso
+			FROM stream
+			GROUP BY deviceId, ` + windowFn + `
+			WITH (TIMESTAMP='ts', TIMEUNIT='ms')`)
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var rows []string
+		ssql.AddSink(func(results []map[string]any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				assert.Equal(t, fmt.Sprintf("%v_%v", r["ws"], r["we"]), r["window_id"], windowFn)
+				rows = append(rows, fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v|%v",
+					r["window_id"], r["ws"], r["we"], r["ws_iso"], r["we_iso"], r["deviceId"], r["cnt"], r["total"]))
+			}
+		})
+
+		base := int64(1700000000000)
+		for i := 0; i < 10; i++ {
+			ssql.Emit(map[string]any{"deviceId": "d1", "ts": base + int64(i*300), "temperature": float64(i)})
+		}
+		// 推进 watermark，使已开启的窗口全部触发
+		ssql.Emit(map[string]any{"deviceId": "d1", "ts": base + 10000, "temperature": 0.0})
+		time.Sleep(800 * time.Millisecond)
+
+		windowType := ssql.Stream().GetDetailedStats()[stream.WindowType]
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(rows)
+		return rows, windowType
+	}
+
+	for name, opts := range map[string][]streamsql.Option{
+		"shared_watermark":  nil,
+		"per_key_watermark": {streamsql.WithPerKeyWatermark()},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			slidingRows, slidingType := run("SlidingWindow('2s', '1s')", opts...)
+			hopRows, hopType := run("HopWindow('2s', '1s')", opts...)
+
+			require.NotEmpty(t, slidingRows)
+			assert.Equal(t, slidingRows, hopRows)
+			assert.Equal(t, window.TypeSliding, slidingType)
+			assert.Equal(t, window.TypeHop, hopType)
+
+			tumblingRows, _ := run("TumblingWindow('2s')", opts...)
+			hopTumblingRows, _ := run("HopWindow('2s', '2s')", opts...)
+			require.NotEmpty(t, tumblingRows)
+			assert.Equal(t, tumblingRows, hopTumblingRows)
+		})
+	}
+}
@@ -737,6 +737,61 @@ END:
 	t.Logf("总共触发了 %d 个窗口", windowResultsLen)
 }
 
+// TestSQLTumblingWindow_WindowWatermark window_watermark() 返回触发窗口的水位线：
+// 事件时间为推进到窗口结束之后的水位线，处理时间为触发时的系统时间
+func TestSQLTumblingWindow_WindowWatermark(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, sql string, emit func(ssql *streamsql.Streamsql)) map[string]any {
+		ssql := streamsql.New()
+		defer ssql.Stop()
+		require.NoError(t, ssql.Execute(sql))
+		ch := make(chan []map[string]any, 10)
+		ssql.AddSink(func(results []map[string]any) { ch <- results })
+		emit(ssql)
+		select {
+		case rows := <-ch:
+			require.NotEmpty(t, rows)
+			return rows[0]
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for window result")
+			return nil
+		}
+	}
+
+	t.Run("事件时间", func(t *testing.T) {
+		base := time.Now().Truncate(time.Second).Add(-5 * time.Second)
+		last := base.Add(1500 * time.Millisecond)
+		row := run(t, `
+			SELECT COUNT(*) AS cnt, window_end() AS end, window_watermark() AS wm
+			FROM stream
+			GROUP BY TumblingWindow('1s')
+			WITH (TIMESTAMP='ts', TIMEUNIT='ms')`,
+			func(ssql *streamsql.Streamsql) {
+				for _, ts := range []time.Time{base.Add(100 * time.Millisecond), base.Add(600 * time.Millisecond), last} {
+					ssql.Emit(map[string]any{"ts": ts.UnixMilli()})
+				}
+			})
+		assert.Equal(t, 2.0, row["cnt"])
+		wm, end := row["wm"].(int64), row["end"].(int64)
+		assert.GreaterOrEqual(t, wm, end, "水位线到达窗口结束才触发")
+		assert.LessOrEqual(t, wm, last.UnixNano(), "水位线不超过最大事件时间")
+	})
+
+	t.Run("处理时间", func(t *testing.T) {
+		row := run(t, `
+			SELECT COUNT(*) AS cnt, window_end() AS end, window_watermark() AS wm
+			FROM stream
+			GROUP BY TumblingWindow('300ms')`,
+			func(ssql *streamsql.Streamsql) {
+				ssql.Emit(map[string]any{"v": 1})
+			})
+		wm, end := row["wm"].(int64), row["end"].(int64)
+		assert.GreaterOrEqual(t, wm, end, "触发时间不早于窗口结束")
+		assert.Less(t, wm, end+int64(2*time.Second))
+	})
+}
+
 // TestSQLTumblingWindow_EventTimeWindowAlignment 测试事件时间窗口对齐到epoch
 func TestSQLTumblingWindow_EventTimeWindowAlignment(t *testing.T) {
 	t.Parallel()
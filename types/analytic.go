@@ -24,9 +24,13 @@ type AnalyticCall struct {
 }
 
 // OverSpec 描述分析函数的 OVER 子句。
-// 仅支持 PARTITION BY 和 WHEN，不支持 ORDER BY / ROWS frame（那是 Flink 模型）。
+// 支持 PARTITION BY、ORDER BY 和 WHEN，不支持 ROWS frame（那是 Flink 模型）。
+// 流式求值按到达顺序推进，ORDER BY 不重排数据，仅供 rank/dense_rank 判定并列；
+// 窗口查询里的 ntile 则按 ORDER BY（含方向）对窗口结果行排序后分桶。
 type OverSpec struct {
 	PartitionBy []string // 分区字段，状态按分区独立维护
+	OrderBy     []string // 排序键字段（ASC/DESC 不影响并列判定）
+	OrderDesc   []bool   // 与 OrderBy 一一对应，true 表示 DESC
 	When        string   // WHEN 条件表达式；满足才更新状态，否则复用旧值
 }
 
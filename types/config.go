@@ -48,8 +48,97 @@ type Config struct {
 
 	// Result control
 	Limit       int            `json:"limit"`
+	Offset      int            `json:"offset"` // OFFSET: rows skipped before LIMIT (non-aggregation: cumulative over the stream; windows: per emit batch)
 	Projections []Projection   `json:"projections"`
 	OrderBy     []OrderByField `json:"orderBy"` // ORDER BY sort keys, applied per emit batch
+	// OutputShape 聚合结果的输出形态：wide（默认，每个聚合一列）或 long
+	// （每个聚合一行 {分组列..., metric, value}）。在 LIMIT 之后、发送前重塑。
+	OutputShape OutputShape `json:"outputShape"`
+	// EmitGranularity 窗口结果的派发粒度：window（默认，一个窗口的所有分组合并为
+	// 一批）或 group（每个分组单独一批，便于按分组路由）。
+	EmitGranularity EmitGranularity `json:"emitGranularity"`
+
+	// DeadLetterDir 非空时，摄入校验失败（WithSchema）或事件时间戳无法解析而被丢弃的
+	// 记录连同失败原因追加写入该目录下的 dead_letter.jsonl，便于事后分析；空表示直接丢弃。
+	DeadLetterDir string `json:"deadLetterDir"`
+
+	// ExpressionTimeout >0 时，每次 SELECT 表达式、聚合输入表达式与 WHERE 的求值都在
+	// 该时限内完成，超时视为求值错误：该字段为 NULL（WHERE 超时则丢弃该行），记录写入
+	// 死信（见 DeadLetterDir）。
+	// 用于防止异常的自定义函数拖垮整条流水线；0 表示不限时。
+	ExpressionTimeout time.Duration `json:"expressionTimeout"`
+
+	// QualityCounts 为 true 时，窗口聚合结果的每个分组额外携带 __input_count
+	// （WHERE 之前的行数）与 __filtered_count（通过 WHERE、进入聚合的行数），
+	// 用于数据质量看板。被 WHERE 拒绝的行计入其分组的下一次窗口结果。
+	QualityCounts bool `json:"qualityCounts"`
+
+	// MinGroupCount >0 时，窗口内进入聚合的行数少于该值的分组不输出，用于压制
+	// 低样本噪声。与 HAVING 不同，它在投影与 HAVING 之前生效；全局窗口不适用。
+	MinGroupCount int `json:"minGroupCount"`
+
+	// NonFinitePolicy 数值聚合（sum/avg/min/max/stddev 等）遇到 NaN/±Inf 输入时的处理：
+	// skip 忽略该值；error 忽略该值并报告聚合错误；nullify 使该分组该聚合在本窗口输出 NULL。
+	// 空值（默认）原样参与聚合。count 不受影响。
+	NonFinitePolicy NonFinitePolicy `json:"nonFinitePolicy"`
+
+	// MaxAggregateBufferPerGroup >0 时，缓冲全部输入的聚合（median/percentile/iqr/stddev/
+	// var/trimmed_mean/consecutive_diff_median/collect/string_agg/time_in_state）每个分组每个窗口最多
+	// 保留该数量的值，防止超大窗口耗尽内存；超出部分按 AggregateBufferPolicy 处理并计入
+	// aggregate_buffer_dropped 指标。count_distinct/value_counts 的不同值集合同样最多保留该数量的不同值，
+	// 超出的新值一律丢弃（结果为下界）。0（默认）表示不限。
+	MaxAggregateBufferPerGroup int `json:"maxAggregateBufferPerGroup"`
+
+	// AggregateBufferPolicy 缓冲超限时的处理：空（默认）丢弃后续值，结果基于前 N 个值；
+	// approximate 以蓄水池抽样保留 N 个均匀样本，结果为近似值（collect/string_agg 不再保序）。
+	AggregateBufferPolicy AggregateBufferPolicy `json:"aggregateBufferPolicy"`
+
+	// GroupNullPolicy GROUP BY 字段为 NULL 或缺失的行如何分组：separate_group（默认）
+	// 归入键列为 NULL 的独立分组；drop 不参与聚合；coalesce_to:"<literal>" 以该字面量
+	// 代替 NULL 分组（与字段值等于该字面量的行合并）。可用 GroupNullCoalesceTo 构造；
+	// 全局窗口不适用。
+	GroupNullPolicy GroupNullPolicy `json:"groupNullPolicy"`
+
+	// ProjectionErrorPolicy 非聚合查询中 SELECT 字段求值出错（如函数参数非法）时的处理：
+	// nullify（默认）该字段为 NULL，其余字段照常输出；skip_row 丢弃该行；
+	// error 丢弃该行并报告错误（EmitSync 返回错误，异步路径记录日志）。
+	ProjectionErrorPolicy ProjectionErrorPolicy `json:"projectionErrorPolicy"`
+
+	// ExpressionFallback 非 nil 时，非聚合查询中 SELECT 表达式字段求值出错的行以该值代替
+	// 该字段，行照常输出，不再按 ProjectionErrorPolicy 处理。字段级的
+	// coalesce_expr(expr, fallback) 优先于此配置。
+	ExpressionFallback any `json:"expressionFallback,omitempty"`
+
+	// TransformFlushInterval >0 时，非聚合查询的结果行先在内部缓冲合并为批次，缓冲中
+	// 最早的行等待不超过该时长即整批派发（缓冲达到行数上限时立即派发），从而保证稀疏流
+	// 的最大输出延迟；CloseInput/Stop 时派发剩余的行。0（默认）表示逐行立即派发。
+	TransformFlushInterval time.Duration `json:"transformFlushInterval"`
+
+	// StateFlushInterval >0 时，非窗口 GROUP BY 查询（如 SELECT deviceId, latest(temperature)
+	// AS t FROM stream GROUP BY deviceId）不再逐条输出：每个分组只保留最新的结果行，每隔该
+	// 时长把全部分组的最新行作为一批快照派发（期间没有任何分组更新则跳过），ORDER BY 与
+	// OFFSET/LIMIT 按快照生效；CloseInput/Stop 时派发最后一次快照。0（默认）表示每条输入
+	// 立即输出其所在分组的更新行。
+	StateFlushInterval time.Duration `json:"stateFlushInterval"`
+
+	// CompactUnchangedWindows 为 true 时，同一分组在相邻窗口中的聚合值与上一条结果完全相同
+	// 则不另行输出，而是合并为一条覆盖多个窗口时间范围的结果（window_id 与 window_end()
+	// 等结束类窗口列随之延长）；值变化、分组在之后的窗口中缺席、CloseInput 或 Stop 时才输出。
+	// 适用于降采样存储；代价是结果最多延迟到值变化时才输出。全局窗口不适用。
+	CompactUnchangedWindows bool `json:"compactUnchangedWindows"`
+
+	// TimeZone window_start_iso()/window_end_iso() 格式化窗口边界所用的 IANA 时区名
+	// （如 "Asia/Shanghai"、"Local"），空表示 UTC。
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// WindowHistorySize >0 时，在内存环形缓冲中保留最近 N 个窗口的输出结果，
+	// 下游短暂故障恢复后可通过 ReplayLastWindows 重新投递给 sink；0 表示不保留。
+	WindowHistorySize int `json:"windowHistorySize"`
+
+	// PrimaryKey 声明结果的主键列（输出列名）。非空时每条输出行携带 __pk（主键列值
+	// 的 JSON 数组）与 __upsert（该主键此前已输出过，如 AllowedLateness 的迟到重发），
+	// 便于 sink 做幂等 upsert。窗口结果通常应包含 window_id。
+	PrimaryKey []string `json:"primaryKey"`
 
 	// JoinConfigs describes stream-table JOINs (v0.5: metadata enrichment).
 	// Empty means no JOIN. Each entry references a registered table source by
@@ -60,6 +149,15 @@ type Config struct {
 	// When set, stream fields can be qualified as "s.<field>" in SELECT/WHERE.
 	SourceAlias string `json:"sourceAlias"`
 
+	// IntervalJoin describes a stream self-join over a time interval
+	// ("FROM stream a JOIN stream b ON a.k = b.k WHERE b.ts - a.ts BETWEEN lo AND hi").
+	// nil means no self-join.
+	IntervalJoin *IntervalJoinConfig `json:"intervalJoin,omitempty"`
+
+	// InsertInto 是 INSERT INTO <name> SELECT ... 的目标具名 sink（Stream.AddNamedSink）。
+	// 非空时结果只派发给同名的具名 sink（及全部未命名 sink），空表示无目标。
+	InsertInto string `json:"insertInto,omitempty"`
+
 	// AnalyticFields 分析函数字段（带可选 OVER）。走直连路径，由
 	// 流级状态机逐条求值，不进聚合路径。空表示无分析函数。
 	AnalyticFields []AnalyticField `json:"analyticFields"`
@@ -95,6 +193,26 @@ type JoinOnPair struct {
 	TableField  string
 }
 
+// IntervalJoinConfig describes an interval self-join of a stream. Each row is
+// paired with every buffered row of the same key, in both roles, when
+// right.TimeField - left.TimeField lies within [Lower, Upper]. A joined row
+// exposes the two sides under LeftAlias and RightAlias.
+type IntervalJoinConfig struct {
+	LeftAlias  string            // FROM alias ("a")
+	RightAlias string            // JOIN alias ("b")
+	Keys       []IntervalJoinKey // equality predicates of the ON clause
+	TimeField  string            // numeric time field read on both sides
+	Lower      float64           // inclusive lower bound of right.TimeField - left.TimeField
+	Upper      float64           // inclusive upper bound of right.TimeField - left.TimeField
+}
+
+// IntervalJoinKey is one equality of a self-join ON clause: Left is read from
+// the left row, Right from the right row.
+type IntervalJoinKey struct {
+	Left  string
+	Right string
+}
+
 // OrderByField represents a single ORDER BY sort key.
 type OrderByField struct {
 	Expression string        `json:"expression"` // result column name (v0.5: must match an output field)
@@ -109,6 +227,90 @@ const (
 	SortDesc SortDirection = "DESC"
 )
 
+// OutputShape selects how aggregate results are laid out.
+type OutputShape string
+
+const (
+	// OutputShapeWide emits one row per group with one column per aggregate (default).
+	OutputShapeWide OutputShape = "wide"
+	// OutputShapeLong emits one row per group and aggregate: the group's
+	// non-aggregate columns plus "metric" (the aggregate's output name) and "value".
+	OutputShapeLong OutputShape = "long"
+)
+
+// EmitGranularity selects how a window's aggregate results are batched for
+// sinks and the result channel.
+type EmitGranularity string
+
+const (
+	// EmitPerWindow emits one batch per window holding every group (default).
+	EmitPerWindow EmitGranularity = "window"
+	// EmitPerGroup emits one batch per group, so each sink call carries the
+	// row(s) of a single GROUP BY key.
+	EmitPerGroup EmitGranularity = "group"
+)
+
+// NonFinitePolicy selects how numeric aggregates treat NaN/±Inf inputs
+// (re-exports aggregator.NonFinitePolicy).
+type NonFinitePolicy = aggregator.NonFinitePolicy
+
+const (
+	NonFinitePassThrough = aggregator.NonFinitePassThrough
+	NonFiniteSkip        = aggregator.NonFiniteSkip
+	NonFiniteError       = aggregator.NonFiniteError
+	NonFiniteNullify     = aggregator.NonFiniteNullify
+)
+
+// AggregateBufferPolicy selects what buffering aggregates do beyond
+// MaxAggregateBufferPerGroup (re-exports aggregator.AggregateBufferPolicy).
+type AggregateBufferPolicy = aggregator.AggregateBufferPolicy
+
+const (
+	AggregateBufferDrop        = aggregator.AggregateBufferDrop
+	AggregateBufferApproximate = aggregator.AggregateBufferApproximate
+)
+
+// GroupNullPolicy selects how rows with a NULL or missing GROUP BY field are
+// grouped (re-exports aggregator.GroupNullPolicy).
+type GroupNullPolicy = aggregator.GroupNullPolicy
+
+const (
+	GroupNullSeparate = aggregator.GroupNullSeparate
+	GroupNullDrop     = aggregator.GroupNullDrop
+)
+
+// GroupNullCoalesceTo returns the policy coalesce_to:"<literal>".
+func GroupNullCoalesceTo(literal string) GroupNullPolicy {
+	return aggregator.GroupNullCoalesceTo(literal)
+}
+
+// ProjectionErrorPolicy selects how a per-field evaluation error in a
+// non-aggregation projection is handled.
+type ProjectionErrorPolicy string
+
+const (
+	// ProjectionErrorNullify makes the failing field NULL and emits the row
+	// (default; the empty value behaves the same).
+	ProjectionErrorNullify ProjectionErrorPolicy = "nullify"
+	// ProjectionErrorSkipRow drops the row.
+	ProjectionErrorSkipRow ProjectionErrorPolicy = "skip_row"
+	// ProjectionErrorFail drops the row and reports the error: EmitSync returns
+	// it and the asynchronous path logs it.
+	ProjectionErrorFail ProjectionErrorPolicy = "error"
+)
+
+// OpenWindowPolicy selects how a sliding window enforces WindowConfig.MaxOpenWindows.
+type OpenWindowPolicy string
+
+const (
+	// OpenWindowCloseOldest force-closes the oldest open windows, emitting them
+	// early with the rows they hold so far (default).
+	OpenWindowCloseOldest OpenWindowPolicy = "close_oldest"
+	// OpenWindowReject drops the incoming row instead of opening another window;
+	// the row is reported through WindowConfig.OnDrop.
+	OpenWindowReject OpenWindowPolicy = "reject"
+)
+
 // TimeCharacteristic represents the time characteristic for window operations
 type TimeCharacteristic string
 
@@ -133,9 +335,19 @@ type WindowConfig struct {
 	AllowedLateness    time.Duration      `json:"allowedLateness"`    // Maximum allowed lateness for event time windows (default: 0, meaning no late data accepted after window closes)
 	IdleTimeout        time.Duration      `json:"idleTimeout"`        // Idle source timeout: when no data arrives within this duration, the watermark advances to (now - maxOutOfOrderness) so idle event-time windows can close. Default 0 disables it. Trade-off: a finite IdleTimeout (e.g. 60s) reaps idle state and closes windows promptly, but events arriving after an idle gap with an event-time behind the advanced watermark are dropped as late; keep IdleTimeout=0 if stale events on resume must not be lost (then idle event-time windows stay open until new data arrives).
 	CountStateTTL      time.Duration      `json:"countStateTtl"`      // Counting-window keyed state TTL: keys inactive longer than this are reaped (lazy, in the Start goroutine). Default 0 = disabled. Set via SQL STATETTL='24h'.
+	MaxOpenWindows     int                `json:"maxOpenWindows"`     // Sliding window: cap on concurrently open windows (started but not yet closed, including windows held open by AllowedLateness). Default 0 = unlimited. Small slides with large sizes otherwise keep size/slide windows open at once.
+	OpenWindowPolicy   OpenWindowPolicy   `json:"openWindowPolicy"`   // What to do when a row would exceed MaxOpenWindows: OpenWindowCloseOldest (default) or OpenWindowReject
 	GroupByKeys        []string           `json:"groupByKeys"`        // Multiple grouping keys for keyed windows
+	PerKeyClock        bool               `json:"perKeyClock"`        // Processing-time tumbling window with GroupByKeys: each key's window opens at its first row and fires on its own timer instead of the shared epoch-aligned clock; a key holds no state or timer between windows. Default false.
+	PerKeyWatermark    bool               `json:"perKeyWatermark"`    // Event-time tumbling/sliding window with GroupByKeys: each key keeps its own watermark (and idle-source detection), so a lagging key holds back only its own windows. Keys idle past IdleTimeout are forgotten once their windows fire. Default false.
 	PerformanceConfig  PerformanceConfig  `json:"performanceConfig"`  // Performance configuration
 	Callback           func([]Row)        `json:"-"`                  // Callback function (not serialized)
+	// OnDrop 在事件时间窗口因无法得到有效时间戳而丢弃行时回调（附原因），
+	// 供 stream 写入死信存储。nil 表示静默丢弃。
+	OnDrop func(data any, reason string) `json:"-"`
+	// OnIdleSource 在事件时间窗口的数据源空闲（IdleTimeout 内无数据、水位线改按处理时间推进）
+	// 时回调，参数为空闲前见到的最大事件时间；每个空闲期只回调一次。nil 表示不通知。
+	OnIdleSource func(lastEventTime time.Time) `json:"-"`
 
 	// Global-window: TriggerCondition is the TRIGGER WHEN predicate string
 	// (e.g. "COUNT(*) >= 1000"). SelectFields/FieldAlias mirror the SELECT
@@ -193,11 +405,25 @@ type PerformanceConfig struct {
 	OverflowConfig   OverflowConfig   `json:"overflowConfig"`   // overflow strategy configuration
 	WorkerConfig     WorkerConfig     `json:"workerConfig"`     // worker pool configuration
 	MonitoringConfig MonitoringConfig `json:"monitoringConfig"` // monitoring configuration
+	// MaxInFlightBatches >0 bounds how many result batches may be dispatched to
+	// the async sinks without every sink having finished them. Beyond it the
+	// "block" overflow strategy waits for a slot (up to BlockTimeout when >0)
+	// and the other strategies drop the batch for the async sinks. 0 is unbounded.
+	MaxInFlightBatches int `json:"maxInFlightBatches"`
+	// RowPooling recycles the row batches windows emit once the stream has
+	// aggregated them, reducing allocations per window fire. With a JOIN on a
+	// tumbling, counting or session window (no AllowedLateness) the per-row
+	// JOIN working maps are recycled too. Rows passed to Emit belong to the
+	// caller and are never pooled or modified, and the result rows handed to
+	// sinks are always fresh maps. Window callbacks get their own copy of each
+	// batch and may keep it. Off by default.
+	RowPooling bool `json:"rowPooling"`
 }
 
 // BufferConfig buffer configuration
 type BufferConfig struct {
 	DataChannelSize     int     `json:"dataChannelSize"`     // Data input buffer size
+	BatchChannelSize    int     `json:"batchChannelSize"`    // EmitMany batch buffer size, counted in batches (<=0 uses the default)
 	ResultChannelSize   int     `json:"resultChannelSize"`   // Result output buffer size
 	WindowOutputSize    int     `json:"windowOutputSize"`    // Window output buffer size
 	EnableDynamicResize bool    `json:"enableDynamicResize"` // Enable dynamic buffer resizing
@@ -226,6 +452,9 @@ type WorkerConfig struct {
 	SinkPoolSize     int `json:"sinkPoolSize"`     // Sink pool size
 	SinkWorkerCount  int `json:"sinkWorkerCount"`  // Sink worker count
 	MaxRetryRoutines int `json:"maxRetryRoutines"` // Maximum retry routines
+	// AggregationShards shards GROUP BY groups across this many parallel
+	// aggregators by group-key hash (≤1: single-threaded aggregation).
+	AggregationShards int `json:"aggregationShards"`
 }
 
 // MonitoringConfig monitoring configuration
@@ -264,6 +493,7 @@ func DefaultPerformanceConfig() PerformanceConfig {
 	return PerformanceConfig{
 		BufferConfig: BufferConfig{
 			DataChannelSize:     1000,
+			BatchChannelSize:    64,
 			ResultChannelSize:   100,
 			WindowOutputSize:    50,
 			EnableDynamicResize: false,
@@ -305,6 +535,7 @@ func DefaultPerformanceConfig() PerformanceConfig {
 func HighPerformanceConfig() PerformanceConfig {
 	config := DefaultPerformanceConfig()
 	config.BufferConfig.DataChannelSize = 5000
+	config.BufferConfig.BatchChannelSize = 256
 	config.BufferConfig.ResultChannelSize = 500
 	config.BufferConfig.WindowOutputSize = 200
 	config.BufferConfig.MaxBufferSize = 500000
@@ -320,6 +551,7 @@ func HighPerformanceConfig() PerformanceConfig {
 func LowLatencyConfig() PerformanceConfig {
 	config := DefaultPerformanceConfig()
 	config.BufferConfig.DataChannelSize = 100
+	config.BufferConfig.BatchChannelSize = 16
 	config.BufferConfig.ResultChannelSize = 50
 	config.BufferConfig.WindowOutputSize = 20
 	config.BufferConfig.UsageThreshold = 0.7
@@ -0,0 +1,244 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rulego/streamsql/aggregator"
+)
+
+// ConfigBuilder builds a Config step by step, as a programmatic alternative to
+// SQL. Methods record the first error they hit; Build returns it together with
+// contradictions found across calls (aggregations or GROUP BY without a window,
+// duplicate output names).
+//
+// Example:
+//
+//	config, err := types.NewConfigBuilder().
+//		TumblingWindow("5s").
+//		GroupBy("deviceId").
+//		Agg("temperature", aggregator.Avg, "avg_temp").
+//		Where("temperature > 0").
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	s, err := stream.NewStream(config)
+type ConfigBuilder struct {
+	config Config
+	// outputs lists output column names in declaration order, for the
+	// duplicate check and FieldOrder.
+	outputs []string
+	err     error
+}
+
+// NewConfigBuilder creates an empty builder: a non-window query that passes
+// every input field through unchanged.
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// TumblingWindow uses a tumbling window of the given size ("5s" or a time.Duration).
+func (b *ConfigBuilder) TumblingWindow(size any) *ConfigBuilder {
+	return b.timeWindow("tumbling", size)
+}
+
+// SlidingWindow uses a sliding window of the given size and slide.
+func (b *ConfigBuilder) SlidingWindow(size, slide any) *ConfigBuilder {
+	return b.timeWindow("sliding", size, slide)
+}
+
+// HopWindow uses a hopping window: a sliding window whose type is "hop".
+func (b *ConfigBuilder) HopWindow(size, slide any) *ConfigBuilder {
+	return b.timeWindow("hop", size, slide)
+}
+
+// SessionWindow uses a session window closed after the given gap of inactivity.
+func (b *ConfigBuilder) SessionWindow(gap any) *ConfigBuilder {
+	return b.timeWindow("session", gap)
+}
+
+// CountingWindow uses a window that fires every count rows per group.
+func (b *ConfigBuilder) CountingWindow(count int) *ConfigBuilder {
+	if count <= 0 {
+		return b.fail(fmt.Errorf("counting window size must be positive, got %d", count))
+	}
+	return b.setWindow("counting", []any{count})
+}
+
+// EventTime makes the window use the event timestamp in field tsProp, whose
+// numeric values are in timeUnit (e.g. time.Millisecond).
+func (b *ConfigBuilder) EventTime(tsProp string, timeUnit time.Duration) *ConfigBuilder {
+	if tsProp == "" {
+		return b.fail(fmt.Errorf("event time requires a timestamp field"))
+	}
+	b.config.WindowConfig.TsProp = tsProp
+	b.config.WindowConfig.TimeUnit = timeUnit
+	b.config.WindowConfig.TimeCharacteristic = EventTime
+	return b
+}
+
+// GroupBy adds grouping fields; they are also output columns.
+func (b *ConfigBuilder) GroupBy(fields ...string) *ConfigBuilder {
+	for _, f := range fields {
+		if f == "" {
+			return b.fail(fmt.Errorf("group by field must not be empty"))
+		}
+		b.config.GroupFields = append(b.config.GroupFields, f)
+		b.addOutput(f)
+	}
+	return b
+}
+
+// Agg adds an aggregation of field, output as alias (field itself when alias
+// is empty).
+func (b *ConfigBuilder) Agg(field string, aggType aggregator.AggregateType, alias string) *ConfigBuilder {
+	if field == "" || aggType == "" {
+		return b.fail(fmt.Errorf("aggregation requires a field and an aggregate type"))
+	}
+	if alias == "" {
+		alias = field
+	}
+	if b.config.SelectFields == nil {
+		b.config.SelectFields = make(map[string]aggregator.AggregateType)
+		b.config.FieldAlias = make(map[string]string)
+	}
+	b.config.SelectFields[alias] = aggType
+	b.config.FieldAlias[alias] = field
+	b.addOutput(alias)
+	return b
+}
+
+// Select adds fields projected as they are; "field:alias" renames the output.
+func (b *ConfigBuilder) Select(fields ...string) *ConfigBuilder {
+	for _, f := range fields {
+		if f == "" {
+			return b.fail(fmt.Errorf("select field must not be empty"))
+		}
+		b.config.SimpleFields = append(b.config.SimpleFields, f)
+		name := f
+		if i := strings.IndexByte(f, ':'); i >= 0 {
+			name = f[i+1:]
+		}
+		b.addOutput(name)
+	}
+	return b
+}
+
+// Where filters input rows by condition (same syntax as the SQL WHERE clause).
+func (b *ConfigBuilder) Where(condition string) *ConfigBuilder {
+	b.config.Where = condition
+	return b
+}
+
+// Having filters window results by condition over output columns.
+func (b *ConfigBuilder) Having(condition string) *ConfigBuilder {
+	b.config.Having = condition
+	return b
+}
+
+// Limit caps the number of result rows (per window for window queries).
+func (b *ConfigBuilder) Limit(n int) *ConfigBuilder {
+	if n < 0 {
+		return b.fail(fmt.Errorf("limit must not be negative, got %d", n))
+	}
+	b.config.Limit = n
+	return b
+}
+
+// Build validates the accumulated settings and returns the Config.
+func (b *ConfigBuilder) Build() (Config, error) {
+	if b.err != nil {
+		return Config{}, b.err
+	}
+	// Copy the collections so later builder calls do not change a built Config.
+	cfg := b.config
+	cfg.GroupFields = append([]string(nil), cfg.GroupFields...)
+	cfg.SimpleFields = append([]string(nil), cfg.SimpleFields...)
+	if cfg.SelectFields != nil {
+		cfg.SelectFields = make(map[string]aggregator.AggregateType, len(b.config.SelectFields))
+		cfg.FieldAlias = make(map[string]string, len(b.config.FieldAlias))
+		for alias, aggType := range b.config.SelectFields {
+			cfg.SelectFields[alias] = aggType
+			cfg.FieldAlias[alias] = b.config.FieldAlias[alias]
+		}
+	}
+	if !cfg.NeedWindow {
+		if len(cfg.SelectFields) > 0 {
+			return Config{}, fmt.Errorf("aggregations require a window")
+		}
+		if len(cfg.GroupFields) > 0 {
+			return Config{}, fmt.Errorf("GROUP BY requires a window")
+		}
+		if cfg.Having != "" {
+			return Config{}, fmt.Errorf("HAVING requires a window")
+		}
+		if cfg.WindowConfig.TsProp != "" {
+			return Config{}, fmt.Errorf("event time requires a window")
+		}
+	}
+	seen := make(map[string]bool, len(b.outputs))
+	for _, name := range b.outputs {
+		if seen[name] {
+			return Config{}, fmt.Errorf("duplicate output column %q", name)
+		}
+		seen[name] = true
+	}
+	if cfg.NeedWindow {
+		cfg.Mode = ExecWindow
+		cfg.WindowConfig.GroupByKeys = cfg.GroupFields
+		cfg.WindowConfig.SelectFields = cfg.SelectFields
+		cfg.WindowConfig.FieldAlias = cfg.FieldAlias
+		if cfg.WindowConfig.TimeCharacteristic == "" {
+			cfg.WindowConfig.TimeCharacteristic = ProcessingTime
+		}
+	}
+	cfg.FieldOrder = append([]string(nil), b.outputs...)
+	return cfg, nil
+}
+
+func (b *ConfigBuilder) timeWindow(windowType string, params ...any) *ConfigBuilder {
+	durations := make([]any, len(params))
+	for i, p := range params {
+		var d time.Duration
+		switch v := p.(type) {
+		case time.Duration:
+			d = v
+		case string:
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return b.fail(fmt.Errorf("invalid %s window duration %q: %w", windowType, v, err))
+			}
+			d = parsed
+		default:
+			return b.fail(fmt.Errorf("invalid %s window duration %v: want a string or time.Duration", windowType, p))
+		}
+		if d <= 0 {
+			return b.fail(fmt.Errorf("%s window duration must be positive, got %v", windowType, d))
+		}
+		durations[i] = d
+	}
+	return b.setWindow(windowType, durations)
+}
+
+func (b *ConfigBuilder) setWindow(windowType string, params []any) *ConfigBuilder {
+	if b.config.NeedWindow {
+		return b.fail(fmt.Errorf("only one window can be set, already have %s", b.config.WindowConfig.Type))
+	}
+	b.config.NeedWindow = true
+	b.config.WindowConfig.Type = windowType
+	b.config.WindowConfig.Params = params
+	return b
+}
+
+func (b *ConfigBuilder) addOutput(name string) {
+	b.outputs = append(b.outputs, name)
+}
+
+func (b *ConfigBuilder) fail(err error) *ConfigBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
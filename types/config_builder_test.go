@@ -0,0 +1,74 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/aggregator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigBuilder 验证链式构建出的窗口聚合配置
+func TestConfigBuilder(t *testing.T) {
+	b := NewConfigBuilder().
+		TumblingWindow("5s").
+		EventTime("ts", time.Millisecond).
+		GroupBy("deviceId").
+		Agg("temperature", aggregator.Avg, "avg_temp").
+		Agg("temperature", aggregator.Max, "").
+		Where("temperature > 0").
+		Having("avg_temp > 10")
+	config, err := b.Build()
+	require.NoError(t, err)
+
+	assert.True(t, config.NeedWindow)
+	assert.Equal(t, ExecWindow, config.Mode)
+	assert.Equal(t, "tumbling", config.WindowConfig.Type)
+	assert.Equal(t, []any{5 * time.Second}, config.WindowConfig.Params)
+	assert.Equal(t, EventTime, config.WindowConfig.TimeCharacteristic)
+	assert.Equal(t, []string{"deviceId"}, config.GroupFields)
+	assert.Equal(t, []string{"deviceId"}, config.WindowConfig.GroupByKeys)
+	assert.Equal(t, map[string]aggregator.AggregateType{"avg_temp": aggregator.Avg, "temperature": aggregator.Max}, config.SelectFields)
+	assert.Equal(t, map[string]string{"avg_temp": "temperature", "temperature": "temperature"}, config.FieldAlias)
+	assert.Equal(t, []string{"deviceId", "avg_temp", "temperature"}, config.FieldOrder)
+	assert.Equal(t, "temperature > 0", config.Where)
+	assert.Equal(t, "avg_temp > 10", config.Having)
+
+	// 构建后继续修改构建器不影响已构建的配置
+	b.Agg("humidity", aggregator.Sum, "hum")
+	assert.NotContains(t, config.SelectFields, "hum")
+}
+
+// TestConfigBuilder_Errors 验证矛盾与非法参数在 Build 时报错
+func TestConfigBuilder_Errors(t *testing.T) {
+	cases := []struct {
+		name    string
+		builder *ConfigBuilder
+		wantErr string
+	}{
+		{"agg without window", NewConfigBuilder().Agg("temperature", aggregator.Avg, "avg_temp"), "aggregations require a window"},
+		{"group by without window", NewConfigBuilder().GroupBy("deviceId").Select("deviceId:id"), "GROUP BY requires a window"},
+		{"having without window", NewConfigBuilder().Select("a").Having("a > 1"), "HAVING requires a window"},
+		{"duplicate alias", NewConfigBuilder().CountingWindow(10).Agg("a", aggregator.Sum, "x").Agg("b", aggregator.Sum, "x"), `duplicate output column "x"`},
+		{"alias clashes with group field", NewConfigBuilder().TumblingWindow("1s").GroupBy("deviceId").Agg("a", aggregator.Count, "deviceId"), `duplicate output column "deviceId"`},
+		{"bad duration", NewConfigBuilder().TumblingWindow("five seconds"), "invalid tumbling window duration"},
+		{"non-positive duration", NewConfigBuilder().SlidingWindow("10s", time.Duration(0)), "must be positive"},
+		{"two windows", NewConfigBuilder().TumblingWindow("1s").SessionWindow("1m"), "only one window"},
+		{"bad counting size", NewConfigBuilder().CountingWindow(0), "counting window size must be positive"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.builder.Build()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.wantErr)
+		})
+	}
+
+	// 非窗口查询：仅投影与过滤
+	config, err := NewConfigBuilder().Select("deviceId", "temperature:temp").Where("temperature > 0").Build()
+	require.NoError(t, err)
+	assert.False(t, config.NeedWindow)
+	assert.Equal(t, []string{"deviceId", "temperature:temp"}, config.SimpleFields)
+	assert.Equal(t, []string{"deviceId", "temp"}, config.FieldOrder)
+}
@@ -102,10 +102,12 @@ Unified configuration for all window types:
 Comprehensive performance tuning options (nested; see config.go for exact fields):
 
 	type PerformanceConfig struct {
-		BufferConfig     BufferConfig     // buffer sizes (data/result/window output)
-		OverflowConfig   OverflowConfig   // overflow strategy: "drop"/"block"/"expand"
-		WorkerConfig     WorkerConfig     // sink worker pool sizing
-		MonitoringConfig MonitoringConfig // monitoring & warning thresholds
+		BufferConfig       BufferConfig     // buffer sizes (data/result/window output)
+		OverflowConfig     OverflowConfig   // overflow strategy: "drop"/"block"/"expand"
+		WorkerConfig       WorkerConfig     // sink worker pool sizing
+		MonitoringConfig   MonitoringConfig // monitoring & warning thresholds
+		MaxInFlightBatches int              // bound on batches awaiting async sinks (0: unbounded)
+		RowPooling         bool             // recycle window row batches after aggregation (opt-in)
 	}
 
 # Field Management
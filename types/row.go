@@ -28,6 +28,15 @@ type Row struct {
 	Timestamp time.Time
 	Data      any
 	Slot      *TimeSlot
+	// Watermark is the watermark that fired the window emitting this row (the
+	// wall-clock trigger time for processing-time windows); zero until emitted.
+	Watermark time.Time
+}
+
+// Reset clears the row in place so a reused Row no longer references its data,
+// slot or times. The data a row pointed to is left untouched.
+func (r *Row) Reset() {
+	*r = Row{}
 }
 
 // GetTimestamp gets timestamp
@@ -230,3 +230,17 @@ func TestRowConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// TestRowReset Reset 清空行的全部字段，但不修改其引用的数据
+func TestRowReset(t *testing.T) {
+	now := time.Now()
+	data := map[string]any{"a": 1}
+	row := Row{Timestamp: now, Data: data, Slot: &TimeSlot{Start: &now, End: &now}, Watermark: now}
+	row.Reset()
+	if row.Data != nil || row.Slot != nil || !row.Timestamp.IsZero() || !row.Watermark.IsZero() {
+		t.Errorf("Reset left fields set: %+v", row)
+	}
+	if data["a"] != 1 {
+		t.Errorf("Reset modified the row data: %v", data)
+	}
+}
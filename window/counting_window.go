@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -42,6 +43,7 @@ type CountingWindow struct {
 	ctx         context.Context
 	cancelFunc  context.CancelFunc
 	triggerChan chan types.Row
+	flushChan   chan chan struct{} // Flush requests, served by the Start goroutine after pending rows
 	// keyedBuffer/keyedCount accumulate rows per group key until threshold is hit.
 	// No eviction: with high-cardinality GroupByKeys where each key receives fewer
 	// than `threshold` rows, these grow unbounded over long runs. Count windows
@@ -51,9 +53,36 @@ type CountingWindow struct {
 	keyedCount    map[string]int
 	lastActive    map[string]time.Time
 	countStateTTL time.Duration
-	sentCount     int64
-	droppedCount  int64
-	stopped       bool
+	// timeout > 0 (CountingWindow(count, timeout)) also fires a group's partial
+	// window that long after its first buffered row. Each group's timer sends
+	// its generation to timeoutChan; the Start goroutine fires the window only
+	// if the generation is still current under mu, so a window already fired
+	// by count (which bumps the generation) is never fired again by its timer.
+	timeout      time.Duration
+	timers       map[string]*countTimer
+	timeoutChan  chan countTimeout
+	sentCount    int64
+	droppedCount int64
+	stopped      bool
+
+	// pause freezes the time limits and state TTL (see Pauser); changed under mu
+	pause pauseClock
+}
+
+// countTimer is the pending time limit of one group's partial window.
+type countTimer struct {
+	timer    *time.Timer
+	gen      uint64
+	deadline time.Time
+	// frozen is the time left when the window was paused; Resume re-arms the
+	// timer with it (0 when not frozen)
+	frozen time.Duration
+}
+
+// countTimeout is sent by an expired countTimer to the Start goroutine.
+type countTimeout struct {
+	key string
+	gen uint64
 }
 
 func NewCountingWindow(config types.WindowConfig) (*CountingWindow, error) {
@@ -78,6 +107,17 @@ func NewCountingWindow(config types.WindowConfig) (*CountingWindow, error) {
 		return nil, fmt.Errorf("threshold must be a positive integer, got: %v", countVal)
 	}
 
+	// Optional second parameter: time limit for a partial window
+	var timeout time.Duration
+	if len(config.Params) > 1 {
+		var err error
+		timeout, err = cast.ToDurationE(config.Params[1])
+		if err != nil || timeout <= 0 {
+			cancel()
+			return nil, fmt.Errorf("counting window timeout must be a positive duration, got: %v", config.Params[1])
+		}
+	}
+
 	// Use unified performance config to get window output buffer size
 	bufferSize := 1000 // Default value
 	if config.PerformanceConfig.BufferConfig.WindowOutputSize > 0 {
@@ -92,10 +132,14 @@ func NewCountingWindow(config types.WindowConfig) (*CountingWindow, error) {
 		ctx:           ctx,
 		cancelFunc:    cancel,
 		triggerChan:   make(chan types.Row, bufferSize),
+		flushChan:     make(chan chan struct{}),
 		keyedBuffer:   make(map[string][]types.Row),
 		keyedCount:    make(map[string]int),
 		lastActive:    make(map[string]time.Time),
 		countStateTTL: config.CountStateTTL,
+		timeout:       timeout,
+		timers:        make(map[string]*countTimer),
+		timeoutChan:   make(chan countTimeout),
 	}
 
 	// Set callback if provided
@@ -158,9 +202,12 @@ func (cw *CountingWindow) Start() {
 				cw.keyedBuffer[key] = buf
 				cw.keyedCount[key] = len(buf)
 				cw.lastActive[key] = time.Now()
+				if len(buf) == 1 {
+					cw.startTimerLocked(key)
+				}
 				if cw.keyedCount[key] >= cw.threshold {
 					slot := cw.createSlot(buf[:cw.threshold])
-					data := make([]types.Row, cw.threshold)
+					data := newBatch(cw.threshold)[:cw.threshold]
 					copy(data, buf[:cw.threshold])
 					for i := range data {
 						data[i].Slot = slot
@@ -174,6 +221,11 @@ func (cw *CountingWindow) Start() {
 					}
 					cw.keyedCount[key] = len(cw.keyedBuffer[key])
 					delete(cw.lastActive, key) // window fired: key is no longer idle
+					// The time limit restarts with the next window
+					cw.stopTimerLocked(key)
+					if len(cw.keyedBuffer[key]) > 0 {
+						cw.startTimerLocked(key)
+					}
 					cw.mu.Unlock()
 
 					if cw.callback != nil {
@@ -185,8 +237,15 @@ func (cw *CountingWindow) Start() {
 					cw.mu.Unlock()
 				}
 
+			case ack := <-cw.flushChan:
+				cw.flushPending()
+				close(ack)
+			case to := <-cw.timeoutChan:
+				cw.fireTimeout(to)
 			case <-tickChan:
-				cw.reapIdleKeys(time.Now())
+				if !cw.pause.paused() {
+					cw.reapIdleKeys(time.Now())
+				}
 			case <-cw.ctx.Done():
 				return
 			}
@@ -195,6 +254,7 @@ func (cw *CountingWindow) Start() {
 }
 
 func (cw *CountingWindow) sendResult(data []types.Row) {
+	stampWatermark(data, nil)
 	strategy := cw.config.PerformanceConfig.OverflowConfig.Strategy
 	timeout := cw.config.PerformanceConfig.OverflowConfig.BlockTimeout
 
@@ -246,6 +306,195 @@ func (cw *CountingWindow) sendResult(data []types.Row) {
 	}
 }
 
+// Flush emits every group's partial buffer (fewer than threshold rows) as a
+// final window. Rows still queued by Add are buffered first, so a Flush after
+// the last Add covers all of them.
+func (cw *CountingWindow) Flush() {
+	ack := make(chan struct{})
+	select {
+	case cw.flushChan <- ack:
+	case <-cw.ctx.Done():
+		return
+	}
+	select {
+	case <-ack:
+	case <-cw.ctx.Done():
+	}
+}
+
+// flushPending runs on the Start goroutine: it buffers rows still in
+// triggerChan, then sends each non-empty group buffer in key order.
+func (cw *CountingWindow) flushPending() {
+	for drained := false; !drained; {
+		select {
+		case row := <-cw.triggerChan:
+			key := cw.getKey(row.Data)
+			cw.mu.Lock()
+			cw.keyedBuffer[key] = append(cw.keyedBuffer[key], row)
+			cw.mu.Unlock()
+		default:
+			drained = true
+		}
+	}
+
+	cw.mu.Lock()
+	keys := make([]string, 0, len(cw.keyedBuffer))
+	for key, buf := range cw.keyedBuffer {
+		if len(buf) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	results := make([][]types.Row, 0, len(keys))
+	for _, key := range keys {
+		buf := cw.keyedBuffer[key]
+		// A flushed buffer may exceed threshold only if queued rows were never
+		// processed; fire it in threshold-sized chunks like the normal path.
+		for len(buf) > 0 {
+			n := len(buf)
+			if n > cw.threshold {
+				n = cw.threshold
+			}
+			data := newBatch(n)[:n]
+			copy(data, buf[:n])
+			slot := cw.createSlot(data)
+			for i := range data {
+				data[i].Slot = slot
+			}
+			results = append(results, data)
+			buf = buf[n:]
+		}
+		cw.keyedBuffer[key] = make([]types.Row, 0, cw.threshold)
+		cw.keyedCount[key] = 0
+		delete(cw.lastActive, key)
+		cw.stopTimerLocked(key)
+	}
+	callback := cw.callback
+	cw.mu.Unlock()
+
+	for _, data := range results {
+		if callback != nil {
+			callback(data)
+		}
+		cw.sendResult(data)
+	}
+}
+
+// startTimerLocked starts the time limit of key's partial window. Caller holds
+// mu; no-op without a timeout.
+func (cw *CountingWindow) startTimerLocked(key string) {
+	if cw.timeout <= 0 {
+		return
+	}
+	cw.stopTimerLocked(key)
+	gen := uint64(1)
+	if t, ok := cw.timers[key]; ok {
+		gen = t.gen + 1
+	}
+	t := &countTimer{gen: gen}
+	cw.timers[key] = t
+	if cw.pause.paused() {
+		t.frozen = cw.timeout // armed by Resume
+		return
+	}
+	cw.armTimerLocked(key, t, cw.timeout)
+}
+
+// armTimerLocked starts t so that it expires after d. Caller holds mu.
+func (cw *CountingWindow) armTimerLocked(key string, t *countTimer, d time.Duration) {
+	to := countTimeout{key: key, gen: t.gen}
+	t.deadline = time.Now().Add(d)
+	t.timer = time.AfterFunc(d, func() {
+		select {
+		case cw.timeoutChan <- to:
+		case <-cw.ctx.Done():
+		}
+	})
+}
+
+// stopTimerLocked cancels key's pending time limit and invalidates a timeout
+// already in flight by bumping the generation. Caller holds mu.
+func (cw *CountingWindow) stopTimerLocked(key string) {
+	if t, ok := cw.timers[key]; ok && (t.timer != nil || t.frozen > 0) {
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
+		t.frozen = 0
+		t.gen++
+	}
+}
+
+// Pause implements Pauser: pending time limits keep the time they have left
+// and idle keys are not reaped until Resume.
+func (cw *CountingWindow) Pause() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if !cw.pause.pause() {
+		return
+	}
+	for _, t := range cw.timers {
+		if t.timer == nil {
+			continue
+		}
+		t.timer.Stop()
+		t.timer = nil
+		t.gen++ // invalidates a timeout already in flight
+		if t.frozen = time.Until(t.deadline); t.frozen <= 0 {
+			t.frozen = time.Nanosecond
+		}
+	}
+}
+
+// Resume implements Pauser: time limits restart with the time they had left
+// and the paused duration does not count towards the state TTL.
+func (cw *CountingWindow) Resume() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	d, ok := cw.pause.resume()
+	if !ok {
+		return
+	}
+	for key, t := range cw.timers {
+		if t.frozen > 0 {
+			cw.armTimerLocked(key, t, t.frozen)
+			t.frozen = 0
+		}
+	}
+	for key, last := range cw.lastActive {
+		cw.lastActive[key] = last.Add(d)
+	}
+}
+
+// fireTimeout runs on the Start goroutine when a group's time limit expires:
+// it emits the group's partial window unless the count path fired it first.
+func (cw *CountingWindow) fireTimeout(to countTimeout) {
+	cw.mu.Lock()
+	t, ok := cw.timers[to.key]
+	buf := cw.keyedBuffer[to.key]
+	if !ok || t.gen != to.gen || len(buf) == 0 {
+		cw.mu.Unlock()
+		return
+	}
+	t.timer = nil
+	data := newBatch(len(buf))[:len(buf)]
+	copy(data, buf)
+	slot := cw.createSlot(data)
+	for i := range data {
+		data[i].Slot = slot
+	}
+	cw.keyedBuffer[to.key] = make([]types.Row, 0, cw.threshold)
+	cw.keyedCount[to.key] = 0
+	delete(cw.lastActive, to.key)
+	callback := cw.callback
+	cw.mu.Unlock()
+
+	if callback != nil {
+		callback(data)
+	}
+	cw.sendResult(data)
+}
+
 func (cw *CountingWindow) Trigger() {
 	// Note: trigger logic has been merged into Start method to avoid data races
 	// This method is kept to satisfy Window interface requirements, but actual triggering is handled in Start method
@@ -259,6 +508,8 @@ func (cw *CountingWindow) reapIdleKeys(now time.Time) {
 	defer cw.mu.Unlock()
 	for key, last := range cw.lastActive {
 		if now.Sub(last) > cw.countStateTTL {
+			cw.stopTimerLocked(key)
+			delete(cw.timers, key)
 			delete(cw.keyedBuffer, key)
 			delete(cw.keyedCount, key)
 			delete(cw.lastActive, key)
@@ -271,6 +522,9 @@ func (cw *CountingWindow) Stop() {
 	stopped := cw.stopped
 	if !stopped {
 		cw.stopped = true
+		for key := range cw.timers {
+			cw.stopTimerLocked(key)
+		}
 	}
 	cw.mu.Unlock()
 
@@ -285,6 +539,10 @@ func (cw *CountingWindow) Reset() {
 	defer cw.mu.Unlock()
 
 	cw.dataBuffer = nil
+	for key := range cw.timers {
+		cw.stopTimerLocked(key)
+	}
+	cw.timers = make(map[string]*countTimer)
 	cw.keyedBuffer = make(map[string][]types.Row)
 	cw.keyedCount = make(map[string]int)
 	atomic.StoreInt64(&cw.sentCount, 0)
@@ -0,0 +1,92 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recvWindow(t *testing.T, cw *CountingWindow, within time.Duration) []types.Row {
+	t.Helper()
+	select {
+	case rows := <-cw.OutputChan():
+		return rows
+	case <-time.After(within):
+		t.Fatal("timeout waiting for counting window output")
+	}
+	return nil
+}
+
+// CountingWindow(count, timeout)：未攒满的分组在首条记录后 timeout 触发，攒满仍按条数触发。
+func TestCountingWindowTimeout_FiresPartialWindow(t *testing.T) {
+	cw, err := NewCountingWindow(types.WindowConfig{
+		Type:        TypeCounting,
+		Params:      []any{3, 80 * time.Millisecond},
+		GroupByKeys: []string{"deviceId"},
+	})
+	require.NoError(t, err)
+	cw.Start()
+	defer cw.Stop()
+
+	start := time.Now()
+	cw.Add(map[string]any{"deviceId": "a", "v": 1})
+	cw.Add(map[string]any{"deviceId": "a", "v": 2})
+	rows := recvWindow(t, cw, time.Second)
+	assert.Len(t, rows, 2)
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	require.NotNil(t, rows[0].Slot)
+
+	// 满 3 条立即按条数触发，不等待超时
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		cw.Add(map[string]any{"deviceId": "a", "v": i})
+	}
+	rows = recvWindow(t, cw, time.Second)
+	assert.Len(t, rows, 3)
+	assert.Less(t, time.Since(start), 80*time.Millisecond)
+
+	// 按条数触发后计时器已取消，不会再输出空窗口或重复窗口
+	select {
+	case rows := <-cw.OutputChan():
+		t.Fatalf("unexpected window after count trigger: %v", rows)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+// 计时器按分组独立：各分组自各自首条记录起计时；触发后余下记录重新计时。
+func TestCountingWindowTimeout_PerGroupAndRestart(t *testing.T) {
+	cw, err := NewCountingWindow(types.WindowConfig{
+		Type:        TypeCounting,
+		Params:      []any{2, "100ms"},
+		GroupByKeys: []string{"deviceId"},
+	})
+	require.NoError(t, err)
+	cw.Start()
+	defer cw.Stop()
+
+	cw.Add(map[string]any{"deviceId": "a", "v": 1})
+	time.Sleep(50 * time.Millisecond)
+	cw.Add(map[string]any{"deviceId": "b", "v": 1})
+
+	first := recvWindow(t, cw, time.Second)
+	second := recvWindow(t, cw, time.Second)
+	assert.Equal(t, "a", first[0].Data.(map[string]any)["deviceId"])
+	assert.Equal(t, "b", second[0].Data.(map[string]any)["deviceId"])
+
+	// 条数触发时带出余下记录：余下记录开始新的计时
+	cw.Add(map[string]any{"deviceId": "c", "v": 1})
+	cw.Add(map[string]any{"deviceId": "c", "v": 2})
+	assert.Len(t, recvWindow(t, cw, time.Second), 2)
+	cw.Add(map[string]any{"deviceId": "c", "v": 3})
+	assert.Len(t, recvWindow(t, cw, time.Second), 1)
+}
+
+func TestCountingWindowTimeout_InvalidParam(t *testing.T) {
+	for _, p := range []any{"abc", "-1s", 0} {
+		_, err := NewCountingWindow(types.WindowConfig{Type: TypeCounting, Params: []any{3, p}})
+		assert.Error(t, err, "timeout %v", p)
+	}
+}
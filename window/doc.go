@@ -259,6 +259,14 @@ Example:
 - Watermark = currentProcessingTime - maxOutOfOrderness = 00:08 - 1s = 00:07
 - Window [00:00 - 00:05) can trigger (watermark >= 00:05) and close
 
+## Per-Key Watermark
+With `PerKeyWatermark` set, an EventTime tumbling or sliding window with GroupByKeys
+keeps a separate window and watermark per group key (PerKeyWatermarkWindow):
+- Each key's windows fire on that key's own event time
+- A key with a lagging clock neither delays other keys nor has its rows dropped as late against them
+- idleTimeout applies per key: a silent key's windows close on processing time
+- An idle key is then forgotten, and its next row starts a fresh watermark
+
 # Performance Features
 
 • Memory Management - Efficient buffer management and garbage collection
@@ -155,3 +155,83 @@ func TestSlidingGetStatsAndResetStats(t *testing.T) {
 	assert.Equal(t, int64(0), stats["sentCount"])
 	assert.Equal(t, int64(0), stats["droppedCount"])
 }
+
+func newCappedSliding(t *testing.T, maxOpen int, policy types.OpenWindowPolicy, onDrop func(any, string)) *SlidingWindow {
+	t.Helper()
+	sw, err := NewSlidingWindow(types.WindowConfig{
+		Type:               TypeSliding,
+		Params:             []any{10 * time.Second, time.Second},
+		TsProp:             "ts",
+		TimeCharacteristic: types.EventTime,
+		WatermarkInterval:  20 * time.Millisecond,
+		MaxOpenWindows:     maxOpen,
+		OpenWindowPolicy:   policy,
+		OnDrop:             onDrop,
+	})
+	require.NoError(t, err)
+	return sw
+}
+
+// 10s/1s 滑动窗口每秒打开一个新窗口；不 Start，窗口只会因上限被关闭。
+func TestSlidingMaxOpenWindows(t *testing.T) {
+	base := alignWindowStart(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), time.Second)
+
+	t.Run("强制关闭最旧窗口", func(t *testing.T) {
+		sw := newCappedSliding(t, 3, "", nil)
+		defer sw.Stop()
+		for i := 0; i < 5; i++ {
+			sw.Add(etRow(base.Add(time.Duration(i)*time.Second), i))
+		}
+
+		// 第 4、5 行各使打开窗口数超过 3，依次提前关闭 [base,+10s) 与 [base+1s,+10s)，
+		// 被关闭的窗口包含触发关闭的那一行。
+		for i := 0; i < 2; i++ {
+			select {
+			case res := <-sw.OutputChan():
+				require.Len(t, res, 4)
+				assert.Equal(t, base.Add(time.Duration(i)*time.Second), *res[0].Slot.Start)
+			case <-time.After(time.Second):
+				t.Fatalf("expected force-closed window %d", i)
+			}
+		}
+		stats := sw.GetStats()
+		assert.Equal(t, int64(3), stats["openWindows"])
+		assert.Equal(t, int64(2), stats["forceClosedWindows"])
+		assert.Equal(t, int64(0), stats["rejectedRows"])
+	})
+
+	t.Run("拒绝新行", func(t *testing.T) {
+		var dropped []any
+		sw := newCappedSliding(t, 3, types.OpenWindowReject, func(data any, reason string) {
+			assert.Contains(t, reason, "open-window limit 3")
+			dropped = append(dropped, data.(map[string]any)["v"])
+		})
+		defer sw.Stop()
+		for i := 0; i < 5; i++ {
+			sw.Add(etRow(base.Add(time.Duration(i)*time.Second), i))
+		}
+		// 已打开窗口内的行仍被接收
+		sw.Add(etRow(base.Add(2500*time.Millisecond), 9))
+
+		assert.Equal(t, []any{3, 4}, dropped)
+		select {
+		case <-sw.OutputChan():
+			t.Fatal("reject policy must not close windows early")
+		case <-time.After(50 * time.Millisecond):
+		}
+		sw.mu.RLock()
+		assert.Len(t, sw.data, 4)
+		sw.mu.RUnlock()
+		stats := sw.GetStats()
+		assert.Equal(t, int64(3), stats["openWindows"])
+		assert.Equal(t, int64(2), stats["rejectedRows"])
+		assert.Equal(t, int64(0), stats["forceClosedWindows"])
+	})
+
+	t.Run("非法配置", func(t *testing.T) {
+		_, err := NewSlidingWindow(types.WindowConfig{Type: TypeSliding, Params: []any{time.Second, time.Second}, MaxOpenWindows: -1})
+		assert.Error(t, err)
+		_, err = NewSlidingWindow(types.WindowConfig{Type: TypeSliding, Params: []any{time.Second, time.Second}, OpenWindowPolicy: "drop"})
+		assert.Error(t, err)
+	})
+}
@@ -31,6 +31,9 @@ import (
 const (
 	TypeTumbling = "tumbling"
 	TypeSliding  = "sliding"
+	// TypeHop is a hopping window ('size','slide'). It shares the sliding
+	// implementation but keeps its own type string in the window config.
+	TypeHop      = "hop"
 	TypeCounting = "counting"
 	TypeSession  = "session"
 	TypeGlobal   = "global"
@@ -48,11 +51,23 @@ type Window interface {
 	GetStats() map[string]int64
 }
 
+// Flusher is implemented by windows that can emit their buffered, not-yet-fired
+// data on end of input (bounded/batch streams). Flush fires every open window
+// regardless of time/count triggers and returns once the results are handed to
+// OutputChan; the window keeps running and accepts later data as usual.
+type Flusher interface {
+	Flush()
+}
+
 func CreateWindow(config types.WindowConfig) (Window, error) {
+	if config.PerKeyWatermark && config.TimeCharacteristic == types.EventTime && len(config.GroupByKeys) > 0 &&
+		(config.Type == TypeTumbling || config.Type == TypeSliding || config.Type == TypeHop) {
+		return NewPerKeyWatermarkWindow(config)
+	}
 	switch config.Type {
 	case TypeTumbling:
 		return NewTumblingWindow(config)
-	case TypeSliding:
+	case TypeSliding, TypeHop:
 		return NewSlidingWindow(config)
 	case TypeCounting:
 		return NewCountingWindow(config)
@@ -66,7 +81,7 @@ func CreateWindow(config types.WindowConfig) (Window, error) {
 }
 
 func (cw *CountingWindow) SetCallback(callback func([]types.Row)) {
-	cw.callback = callback
+	cw.callback = ownedCallback(callback, cw.config.PerformanceConfig)
 }
 
 // GetTimestamp extracts timestamp from data, falling back to time.Now() when no
@@ -126,6 +141,14 @@ func extractTimestamp(data any, tsProp string, timeUnit time.Duration) (time.Tim
 	return cast.ConvertIntToTime(timestampInt, timeUnit), true
 }
 
+// reportUnplaceable hands an event-time row without a usable timestamp to the
+// OnDrop hook (e.g. the stream's dead-letter store) before it is dropped.
+func reportUnplaceable(config types.WindowConfig, data any) {
+	if config.OnDrop != nil {
+		config.OnDrop(data, fmt.Sprintf("no usable event timestamp in field %q", config.TsProp))
+	}
+}
+
 var tsWarnOnce sync.Once
 
 // warnUnplaceableTimestamp warns once that an event-time query is dropping
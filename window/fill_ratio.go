@@ -0,0 +1,66 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"github.com/rulego/streamsql/types"
+	"github.com/rulego/streamsql/utils/cast"
+)
+
+// FillRatio reports how full the window that emitted rows was when it fired,
+// in [0,1] (read by window_fill_ratio()):
+//   - counting: rows in the window / the configured count, below 1 only for a
+//     partial window flushed early (CloseInput, Stop);
+//   - tumbling/sliding: elapsed window time at the firing watermark / window
+//     size, 1 for a window fired at its end and lower for an early trigger.
+//
+// Session and global windows have no expected size; ok is false for them and
+// for an empty batch.
+func FillRatio(config types.WindowConfig, rows []types.Row) (ratio float64, ok bool) {
+	if len(rows) == 0 {
+		return 0, false
+	}
+	switch config.Type {
+	case TypeCounting:
+		if len(config.Params) == 0 {
+			return 0, false
+		}
+		threshold := cast.ToInt(config.Params[0])
+		if threshold <= 0 {
+			return 0, false
+		}
+		ratio = float64(len(rows)) / float64(threshold)
+	case TypeTumbling, TypeSliding, TypeHop:
+		slot := rows[0].Slot
+		if slot == nil || slot.Start == nil || slot.End == nil || !slot.End.After(*slot.Start) {
+			return 0, false
+		}
+		at := rows[0].Watermark
+		if at.IsZero() || at.After(*slot.End) {
+			at = *slot.End
+		}
+		ratio = float64(at.Sub(*slot.Start)) / float64(slot.End.Sub(*slot.Start))
+	default:
+		return 0, false
+	}
+	if ratio < 0 {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+	return ratio, true
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillRatio(t *testing.T) {
+	start := time.Unix(1000, 0)
+	end := start.Add(10 * time.Second)
+	slot := types.NewTimeSlot(&start, &end)
+	timed := func(at time.Time) []types.Row {
+		return []types.Row{{Timestamp: start, Slot: slot, Watermark: at}}
+	}
+	counting := types.WindowConfig{Type: TypeCounting, Params: []any{4}}
+	tumbling := types.WindowConfig{Type: TypeTumbling, Params: []any{10 * time.Second}}
+
+	ratio, ok := FillRatio(counting, make([]types.Row, 4))
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, ratio)
+	ratio, _ = FillRatio(counting, make([]types.Row, 1))
+	assert.Equal(t, 0.25, ratio, "flushed partial window")
+
+	ratio, ok = FillRatio(tumbling, timed(end.Add(time.Second)))
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, ratio, "watermark past the end is capped")
+	ratio, _ = FillRatio(tumbling, timed(start.Add(3*time.Second)))
+	assert.InDelta(t, 0.3, ratio, 1e-9, "early trigger")
+	ratio, _ = FillRatio(types.WindowConfig{Type: TypeSliding}, timed(start.Add(5*time.Second)))
+	assert.InDelta(t, 0.5, ratio, 1e-9)
+
+	for _, typ := range []string{TypeSession, TypeGlobal} {
+		_, ok = FillRatio(types.WindowConfig{Type: typ}, timed(end))
+		assert.False(t, ok, typ)
+	}
+	_, ok = FillRatio(counting, nil)
+	assert.False(t, ok, "empty batch")
+}
@@ -83,6 +83,9 @@ type GlobalWindow struct {
 	cancelFunc  context.CancelFunc
 	triggerChan chan types.Row
 
+	// pause suspends STATETTL reaping (see Pauser)
+	pause pauseClock
+
 	countStateTTL time.Duration
 
 	sentCount    int64
@@ -160,7 +163,7 @@ func NewGlobalWindow(config types.WindowConfig) (*GlobalWindow, error) {
 	}
 
 	if config.Callback != nil {
-		gw.callback = config.Callback
+		gw.callback = ownedCallback(config.Callback, config.PerformanceConfig)
 	}
 	return gw, nil
 }
@@ -434,7 +437,9 @@ func (gw *GlobalWindow) Start() {
 				}
 				gw.processRow(row)
 			case <-tickChan:
-				gw.reapIdleKeys(time.Now())
+				if !gw.pause.paused() {
+					gw.reapIdleKeys(time.Now())
+				}
 			case <-gw.ctx.Done():
 				return
 			}
@@ -573,6 +578,26 @@ func (gw *GlobalWindow) reapIdleKeys(now time.Time) {
 	}
 }
 
+// Pause implements Pauser: idle groups are not reaped while paused. The
+// window has no timers of its own; it fires on arriving rows only.
+func (gw *GlobalWindow) Pause() {
+	gw.pause.pause()
+}
+
+// Resume implements Pauser: the paused duration does not count towards the
+// state TTL.
+func (gw *GlobalWindow) Resume() {
+	d, ok := gw.pause.resume()
+	if !ok {
+		return
+	}
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	for _, gs := range gw.groups {
+		gs.lastActive = gs.lastActive.Add(d)
+	}
+}
+
 func (gw *GlobalWindow) Trigger() {
 	// Trigger logic is driven per-row inside processRow; nothing to do here.
 	// The method exists to satisfy the Window interface.
@@ -614,7 +639,7 @@ func (gw *GlobalWindow) OutputChan() <-chan []types.Row {
 func (gw *GlobalWindow) SetCallback(callback func([]types.Row)) {
 	gw.mu.Lock()
 	defer gw.mu.Unlock()
-	gw.callback = callback
+	gw.callback = ownedCallback(callback, gw.config.PerformanceConfig)
 }
 
 // getKeyAndValues extracts the group key and a map of group-by field values
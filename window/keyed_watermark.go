@@ -0,0 +1,471 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// Ensure PerKeyWatermarkWindow implements the Window interface
+var _ Window = (*PerKeyWatermarkWindow)(nil)
+
+// keyWatermarkWindow is an event-time window that PerKeyWatermarkWindow keeps
+// for one group key. Tumbling and sliding windows implement it.
+type keyWatermarkWindow interface {
+	Window
+	Flusher
+	Pauser
+	// shareOutput makes the window send its results to out.
+	shareOutput(out chan []types.Row)
+	// drained reports whether the window holds no rows, fired or not.
+	drained() bool
+	// watermarkPending reports whether a watermark update awaits advanceSharedClock.
+	watermarkPending() bool
+	// advanceSharedClock fires the windows the watermark has passed, first
+	// updating the watermark when tick is set; it replaces the window's own
+	// event-time goroutine and watermark ticker.
+	advanceSharedClock(tick bool)
+}
+
+// keyWatermarkEntry is one group key's window and when it last received a row.
+type keyWatermarkEntry struct {
+	win      keyWatermarkWindow
+	lastSeen int64 // processing time (UnixNano) of the key's last row
+	queued   int32 // 1 while the entry waits in PerKeyWatermarkWindow.pending
+}
+
+// PerKeyWatermarkWindow runs a separate event-time tumbling or sliding window,
+// each with its own watermark, for every group key (WindowConfig.PerKeyWatermark).
+// A key whose clock lags holds back only its own windows. With IdleTimeout set,
+// idle detection is per key too: a silent key's watermark advances by
+// processing time so its windows close, and once they have all fired the key is
+// forgotten; its next row starts over with a fresh watermark.
+//
+// The key windows have no goroutines or timers of their own: one clock
+// goroutine updates every key's watermark each WatermarkInterval, fires the
+// windows of keys whose rows advanced their watermark as soon as Add reports
+// them, and reaps idle keys.
+type PerKeyWatermarkWindow struct {
+	// config is the per-key window configuration (PerKeyWatermark cleared)
+	config types.WindowConfig
+	// mu protects keys, callback and started
+	mu   sync.RWMutex
+	keys map[string]*keyWatermarkEntry
+	// outputChan is shared by every key's window
+	outputChan chan []types.Row
+	callback   func([]types.Row)
+	started    bool
+	// ctx and wg control the clock goroutine
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	wg         sync.WaitGroup
+	// pending holds the entries whose watermark advanced on Add, for the clock
+	// goroutine woken through wake
+	pendingMu sync.Mutex
+	pending   []*keyWatermarkEntry
+	wake      chan struct{}
+	// Statistics of reaped keys, so GetStats stays monotonic
+	reapedSent    int64
+	reapedDropped int64
+	// pause is applied to every key's window and suspends the reaper (see Pauser)
+	pause pauseClock
+}
+
+// NewPerKeyWatermarkWindow creates a per-key watermark window for an event-time
+// tumbling or sliding window configuration with GroupByKeys.
+func NewPerKeyWatermarkWindow(config types.WindowConfig) (*PerKeyWatermarkWindow, error) {
+	if config.Type != TypeTumbling && config.Type != TypeSliding && config.Type != TypeHop {
+		return nil, fmt.Errorf("per-key watermark requires a tumbling or sliding window, got %s", config.Type)
+	}
+	if config.TimeCharacteristic != types.EventTime {
+		return nil, fmt.Errorf("per-key watermark requires an event-time window")
+	}
+	if len(config.GroupByKeys) == 0 {
+		return nil, fmt.Errorf("per-key watermark requires GROUP BY keys")
+	}
+
+	bufferSize := 1000 // Default value
+	if (config.PerformanceConfig != types.PerformanceConfig{}) {
+		bufferSize = config.PerformanceConfig.BufferConfig.WindowOutputSize
+	}
+
+	keyConfig := config
+	keyConfig.PerKeyWatermark = false
+	// A key's window sends to the shared channel; keep its own one minimal
+	keyConfig.PerformanceConfig.BufferConfig.WindowOutputSize = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &PerKeyWatermarkWindow{
+		config:     keyConfig,
+		keys:       make(map[string]*keyWatermarkEntry),
+		outputChan: make(chan []types.Row, bufferSize),
+		ctx:        ctx,
+		cancelFunc: cancel,
+		wake:       make(chan struct{}, 1),
+	}
+
+	// Validate the window parameters up front instead of on the first row
+	probe, err := w.newKeyWindow()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	probe.Stop()
+	return w, nil
+}
+
+// newKeyWindow creates one key's window.
+func (w *PerKeyWatermarkWindow) newKeyWindow() (keyWatermarkWindow, error) {
+	var win keyWatermarkWindow
+	var err error
+	if w.config.Type == TypeSliding || w.config.Type == TypeHop {
+		win, err = newSlidingWindow(w.config, true)
+	} else {
+		win, err = newTumblingWindow(w.config, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	win.shareOutput(w.outputChan)
+	return win, nil
+}
+
+// Add routes the row to its group key's window, creating it on the key's first row.
+func (w *PerKeyWatermarkWindow) Add(data any) {
+	key := extractSessionCompositeKey(data, w.config.GroupByKeys)
+	now := time.Now().UnixNano()
+
+	w.mu.RLock()
+	entry := w.keys[key]
+	if entry != nil {
+		atomic.StoreInt64(&entry.lastSeen, now)
+		entry.win.Add(data)
+		w.mu.RUnlock()
+		w.notifyWatermark(entry)
+		return
+	}
+	w.mu.RUnlock()
+
+	w.mu.Lock()
+	if entry = w.keys[key]; entry == nil {
+		win, err := w.newKeyWindow()
+		if err != nil {
+			return // parameters were validated by NewPerKeyWatermarkWindow
+		}
+		win.SetCallback(w.callback)
+		if w.started {
+			win.Start()
+		}
+		if w.pause.paused() {
+			win.Pause()
+		}
+		entry = &keyWatermarkEntry{win: win}
+		w.keys[key] = entry
+	}
+	atomic.StoreInt64(&entry.lastSeen, now)
+	entry.win.Add(data)
+	w.mu.Unlock()
+	w.notifyWatermark(entry)
+}
+
+// notifyWatermark queues entry for the clock goroutine when its last row
+// advanced the key's watermark.
+func (w *PerKeyWatermarkWindow) notifyWatermark(entry *keyWatermarkEntry) {
+	if !entry.win.watermarkPending() || !atomic.CompareAndSwapInt32(&entry.queued, 0, 1) {
+		return
+	}
+	w.pendingMu.Lock()
+	w.pending = append(w.pending, entry)
+	w.pendingMu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts every key's window and the clock goroutine.
+func (w *PerKeyWatermarkWindow) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return
+	}
+	w.started = true
+	for _, entry := range w.keys {
+		entry.win.Start()
+	}
+	interval := w.config.WatermarkInterval
+	if interval <= 0 {
+		interval = 200 * time.Millisecond // same default as a single window's watermark
+	}
+	w.wg.Add(1)
+	go w.clockLoop(w.ctx, interval, w.config.IdleTimeout)
+}
+
+// clockLoop drives every key's watermark: each interval it updates all of them
+// and fires what they have passed, in between it fires the keys queued by Add,
+// and with idle > 0 it forgets keys that have been idle longer than idle and
+// whose windows have all fired.
+func (w *PerKeyWatermarkWindow) clockLoop(ctx context.Context, interval, idle time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastReap := time.Now()
+	for {
+		select {
+		case <-w.wake:
+			w.advancePending()
+		case now := <-ticker.C:
+			w.advancePending()
+			for _, entry := range w.entries() {
+				entry.win.advanceSharedClock(true)
+			}
+			if idle > 0 && now.Sub(lastReap) >= idle && !w.pause.paused() {
+				lastReap = now
+				w.reapIdleKeys(idle)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// advancePending fires the windows of the entries queued by notifyWatermark.
+func (w *PerKeyWatermarkWindow) advancePending() {
+	w.pendingMu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.pendingMu.Unlock()
+	for _, entry := range pending {
+		atomic.StoreInt32(&entry.queued, 0)
+		entry.win.advanceSharedClock(false)
+	}
+}
+
+// entries returns the current key entries.
+func (w *PerKeyWatermarkWindow) entries() []*keyWatermarkEntry {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	entries := make([]*keyWatermarkEntry, 0, len(w.keys))
+	for _, entry := range w.keys {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Pause implements Pauser for every key's window and the idle-key reaping.
+func (w *PerKeyWatermarkWindow) Pause() {
+	if !w.pause.pause() {
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, entry := range w.keys {
+		entry.win.Pause()
+	}
+}
+
+// Resume implements Pauser; the paused duration does not count as key idle time.
+func (w *PerKeyWatermarkWindow) Resume() {
+	d, ok := w.pause.resume()
+	if !ok {
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, entry := range w.keys {
+		entry.win.Resume()
+		atomic.AddInt64(&entry.lastSeen, int64(d))
+	}
+}
+
+// reapIdleKeys stops and drops every drained key idle longer than idle.
+func (w *PerKeyWatermarkWindow) reapIdleKeys(idle time.Duration) {
+	cutoff := time.Now().Add(-idle).UnixNano()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, entry := range w.keys {
+		if atomic.LoadInt64(&entry.lastSeen) > cutoff || !entry.win.drained() {
+			continue
+		}
+		entry.win.Stop()
+		stats := entry.win.GetStats()
+		w.reapedSent += stats["sentCount"]
+		w.reapedDropped += stats["droppedCount"]
+		delete(w.keys, key)
+	}
+}
+
+// Stop stops every key's window and the clock goroutine.
+func (w *PerKeyWatermarkWindow) Stop() {
+	w.cancelFunc()
+	w.mu.RLock()
+	for _, entry := range w.keys {
+		entry.win.Stop()
+	}
+	w.mu.RUnlock()
+}
+
+// Reset stops and forgets every key's window; Start must be called again.
+func (w *PerKeyWatermarkWindow) Reset() {
+	w.cancelFunc()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, entry := range w.keys {
+		entry.win.Stop()
+	}
+	w.keys = make(map[string]*keyWatermarkEntry)
+	w.pendingMu.Lock()
+	w.pending = nil
+	w.pendingMu.Unlock()
+	w.started = false
+	w.ctx, w.cancelFunc = context.WithCancel(context.Background())
+}
+
+// Trigger forwards a manual trigger to every key's window.
+func (w *PerKeyWatermarkWindow) Trigger() {
+	for _, win := range w.sortedWindows() {
+		win.Trigger()
+	}
+}
+
+// Flush fires every key's buffered windows in key order.
+func (w *PerKeyWatermarkWindow) Flush() {
+	for _, win := range w.sortedWindows() {
+		win.Flush()
+	}
+}
+
+// sortedWindows returns the key windows ordered by key.
+func (w *PerKeyWatermarkWindow) sortedWindows() []keyWatermarkWindow {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	keys := make([]string, 0, len(w.keys))
+	for key := range w.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	wins := make([]keyWatermarkWindow, len(keys))
+	for i, key := range keys {
+		wins[i] = w.keys[key].win
+	}
+	return wins
+}
+
+// OutputChan returns the channel shared by every key's window
+func (w *PerKeyWatermarkWindow) OutputChan() <-chan []types.Row {
+	return w.outputChan
+}
+
+// SetCallback sets the callback of every current and future key window. It
+// runs on the clock goroutine, or on the caller's for Flush and Trigger, so it
+// may run concurrently with itself.
+func (w *PerKeyWatermarkWindow) SetCallback(callback func([]types.Row)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	for _, entry := range w.keys {
+		entry.win.SetCallback(callback)
+	}
+}
+
+// GetStats returns the statistics summed over all keys, plus the number of
+// keys currently tracked
+func (w *PerKeyWatermarkWindow) GetStats() map[string]int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	sent, dropped := w.reapedSent, w.reapedDropped
+	for _, entry := range w.keys {
+		stats := entry.win.GetStats()
+		sent += stats["sentCount"]
+		dropped += stats["droppedCount"]
+	}
+	return map[string]int64{
+		"sentCount":    sent,
+		"droppedCount": dropped,
+		"bufferSize":   int64(cap(w.outputChan)),
+		"bufferUsed":   int64(len(w.outputChan)),
+		"keys":         int64(len(w.keys)),
+	}
+}
+
+func (tw *TumblingWindow) shareOutput(out chan []types.Row) {
+	tw.outputChan = out
+}
+
+func (tw *TumblingWindow) drained() bool {
+	tw.mu.RLock()
+	defer tw.mu.RUnlock()
+	return len(tw.data) == 0 && len(tw.triggeredWindows) == 0
+}
+
+func (tw *TumblingWindow) watermarkPending() bool {
+	return tw.watermark != nil && tw.watermark.pending()
+}
+
+func (tw *TumblingWindow) advanceSharedClock(tick bool) {
+	tw.mu.RLock()
+	ready := tw.initialized && tw.ctx.Err() == nil
+	tw.mu.RUnlock()
+	if !ready || tw.watermark == nil {
+		return // like the event-time goroutine, wait for the first row
+	}
+	if tick {
+		tw.watermark.update()
+	}
+	if wt, ok := tw.watermark.latest(); ok {
+		tw.checkAndTriggerWindows(wt)
+	}
+}
+
+func (sw *SlidingWindow) shareOutput(out chan []types.Row) {
+	sw.outputChan = out
+}
+
+func (sw *SlidingWindow) drained() bool {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	return len(sw.data) == 0 && len(sw.triggeredWindows) == 0
+}
+
+func (sw *SlidingWindow) watermarkPending() bool {
+	return sw.watermark != nil && sw.watermark.pending()
+}
+
+func (sw *SlidingWindow) advanceSharedClock(tick bool) {
+	sw.mu.RLock()
+	ready := sw.initialized && sw.ctx.Err() == nil
+	sw.mu.RUnlock()
+	if !ready || sw.watermark == nil {
+		return // like the event-time goroutine, wait for the first row
+	}
+	if tick {
+		sw.watermark.update()
+	}
+	if wt, ok := sw.watermark.latest(); ok {
+		sw.checkAndTriggerWindows(wt)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keyedEtRow(device string, ts time.Time, v int) map[string]any {
+	return map[string]any{"device": device, "ts": ts, "v": v}
+}
+
+func perKeyWatermarkConfig(windowType string, params ...any) types.WindowConfig {
+	return types.WindowConfig{
+		Type:               windowType,
+		Params:             params,
+		TsProp:             "ts",
+		TimeCharacteristic: types.EventTime,
+		WatermarkInterval:  20 * time.Millisecond,
+		GroupByKeys:        []string{"device"},
+		PerKeyWatermark:    true,
+	}
+}
+
+// collectWindows reads n window results, keyed by device.
+func collectWindows(t *testing.T, out <-chan []types.Row, n int) map[string][][]types.Row {
+	t.Helper()
+	got := make(map[string][][]types.Row)
+	for i := 0; i < n; i++ {
+		select {
+		case rows := <-out:
+			require.NotEmpty(t, rows)
+			device := rows[0].Data.(map[string]any)["device"].(string)
+			got[device] = append(got[device], rows)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for window %d of %d", i+1, n)
+		}
+	}
+	return got
+}
+
+func TestCreateWindowPerKeyWatermark(t *testing.T) {
+	win, err := CreateWindow(perKeyWatermarkConfig(TypeTumbling, 2*time.Second))
+	require.NoError(t, err)
+	assert.IsType(t, &PerKeyWatermarkWindow{}, win)
+	win.Stop()
+
+	// 无分组键或处理时间窗口忽略该选项
+	cfg := perKeyWatermarkConfig(TypeTumbling, 2*time.Second)
+	cfg.GroupByKeys = nil
+	win, err = CreateWindow(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &TumblingWindow{}, win)
+	win.Stop()
+
+	cfg = perKeyWatermarkConfig(TypeSliding, 2*time.Second, time.Second)
+	cfg.TimeCharacteristic = types.ProcessingTime
+	win, err = CreateWindow(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &SlidingWindow{}, win)
+	win.Stop()
+
+	_, err = CreateWindow(perKeyWatermarkConfig(TypeTumbling, "bad"))
+	assert.Error(t, err)
+}
+
+// 时钟落后的设备不被其他设备推高的水位线判为迟到：各设备按自己的事件时间触发窗口
+func TestPerKeyWatermarkTumbling(t *testing.T) {
+	win, err := NewPerKeyWatermarkWindow(perKeyWatermarkConfig(TypeTumbling, 2*time.Second))
+	require.NoError(t, err)
+	win.Start()
+	defer win.Stop()
+
+	base := alignWindowStart(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), 2*time.Second)
+	lag := base.Add(-time.Hour)
+
+	win.Add(keyedEtRow("fast", base, 1))
+	win.Add(keyedEtRow("fast", base.Add(time.Second), 2))
+	win.Add(keyedEtRow("fast", base.Add(3*time.Second), 3)) // fires fast's [base, base+2s)
+	win.Add(keyedEtRow("slow", lag, 1))
+	win.Add(keyedEtRow("slow", lag.Add(500*time.Millisecond), 2))
+	win.Add(keyedEtRow("slow", lag.Add(2*time.Second), 3)) // fires slow's [lag, lag+2s)
+
+	got := collectWindows(t, win.OutputChan(), 2)
+	require.Len(t, got["fast"], 1)
+	require.Len(t, got["slow"], 1)
+	assert.Len(t, got["fast"][0], 2)
+	assert.Equal(t, base, *got["fast"][0][0].Slot.Start)
+	assert.Len(t, got["slow"][0], 2)
+	assert.Equal(t, lag, *got["slow"][0][0].Slot.Start)
+	// 各设备的结果记录自己的水位线
+	assert.True(t, got["slow"][0][0].Watermark.Before(got["fast"][0][0].Watermark))
+
+	win.Flush()
+	got = collectWindows(t, win.OutputChan(), 2)
+	assert.Len(t, got["fast"], 1)
+	assert.Len(t, got["slow"], 1)
+	assert.Equal(t, int64(4), win.GetStats()["sentCount"])
+	assert.Equal(t, int64(2), win.GetStats()["keys"])
+}
+
+func TestPerKeyWatermarkSliding(t *testing.T) {
+	win, err := NewPerKeyWatermarkWindow(perKeyWatermarkConfig(TypeSliding, 2*time.Second, time.Second))
+	require.NoError(t, err)
+	var callbacks int32
+	win.SetCallback(func([]types.Row) { atomic.AddInt32(&callbacks, 1) })
+	win.Start()
+	defer win.Stop()
+
+	base := alignWindowStart(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), 2*time.Second)
+	lag := base.Add(-time.Hour)
+	win.Add(keyedEtRow("fast", base, 1))
+	win.Add(keyedEtRow("fast", base.Add(2*time.Second), 2))
+	win.Add(keyedEtRow("slow", lag, 1))
+	win.Add(keyedEtRow("slow", lag.Add(2*time.Second), 2))
+
+	got := collectWindows(t, win.OutputChan(), 2)
+	assert.Len(t, got["fast"], 1)
+	assert.Len(t, got["slow"], 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&callbacks))
+}
+
+// 空闲检测按设备进行：静默设备的水位线按处理时间推进、窗口自行关闭，随后该设备被遗忘
+func TestPerKeyWatermarkIdleKey(t *testing.T) {
+	cfg := perKeyWatermarkConfig(TypeTumbling, time.Second)
+	cfg.IdleTimeout = 100 * time.Millisecond
+	var idled []time.Time
+	idleCh := make(chan time.Time, 4)
+	cfg.OnIdleSource = func(last time.Time) { idleCh <- last }
+	win, err := NewPerKeyWatermarkWindow(cfg)
+	require.NoError(t, err)
+	win.Start()
+	defer win.Stop()
+
+	base := alignWindowStart(time.Now().Add(-time.Minute), time.Second)
+	win.Add(keyedEtRow("quiet", base, 1))
+
+	got := collectWindows(t, win.OutputChan(), 1)
+	require.Len(t, got["quiet"], 1)
+	select {
+	case last := <-idleCh:
+		idled = append(idled, last)
+	case <-time.After(time.Second):
+		t.Fatal("per-key idle callback not invoked")
+	}
+	assert.Equal(t, []time.Time{base}, idled)
+
+	require.Eventually(t, func() bool { return win.GetStats()["keys"] == 0 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, int64(1), win.GetStats()["sentCount"], "reaped keys keep counting")
+
+	// 被遗忘的设备再次出现时重新开始
+	win.Add(keyedEtRow("quiet", base.Add(10*time.Second), 2))
+	assert.Equal(t, int64(1), win.GetStats()["keys"])
+}
+
+// TestPerKeyWatermarkSharedClock 所有分组键共用一个时钟协程，键的数量不增加协程
+func TestPerKeyWatermarkSharedClock(t *testing.T) {
+	for _, windowType := range []string{TypeTumbling, TypeSliding} {
+		t.Run(windowType, func(t *testing.T) {
+			params := []any{2 * time.Second}
+			if windowType == TypeSliding {
+				params = append(params, time.Second)
+			}
+			win, err := NewPerKeyWatermarkWindow(perKeyWatermarkConfig(windowType, params...))
+			require.NoError(t, err)
+			win.Start()
+			defer win.Stop()
+
+			before := runtime.NumGoroutine()
+			base := alignWindowStart(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), 2*time.Second)
+			const keys = 200
+			for i := 0; i < keys; i++ {
+				win.Add(keyedEtRow(fmt.Sprintf("d%03d", i), base, i))
+			}
+			assert.LessOrEqual(t, runtime.NumGoroutine(), before+2)
+			assert.Equal(t, int64(keys), win.GetStats()["keys"])
+
+			// 每个键的水位线仍独立推进并触发自己的窗口
+			win.Add(keyedEtRow("d007", base.Add(5*time.Second), 0))
+			got := collectWindows(t, win.OutputChan(), 1)
+			require.Len(t, got["d007"], 1)
+			assert.Equal(t, base, *got["d007"][0][0].Slot.Start)
+		})
+	}
+}
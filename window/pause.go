@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// Pauser is implemented by windows whose timers can be frozen (Stream.Pause).
+// Pause stops time-driven firing (processing-time ticks, session gaps, count
+// timeouts, idle detection and state TTL); buffered rows and open windows are
+// kept. Resume restarts it with every pending deadline moved later by the time
+// spent paused, so a window that had 2s left at Pause fires 2s after Resume
+// instead of all overdue windows firing at once. Processing-time window bounds
+// (window_start/window_end) of windows open across a pause are shifted by the
+// same amount. Both calls are idempotent; a manual Trigger or Flush still fires.
+type Pauser interface {
+	Pause()
+	Resume()
+}
+
+// pauseClock 记录窗口的暂停状态。零值为未暂停。
+type pauseClock struct {
+	mu       sync.Mutex
+	pausedAt time.Time
+	// resumedCh 暂停期间为未关闭的 channel，Resume 时关闭以唤醒等待者
+	resumedCh chan struct{}
+}
+
+// closedChan 供未暂停时的 resumed() 返回，接收立即成功
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// pause 进入暂停状态；已暂停时返回 false。
+func (c *pauseClock) pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pausedAt.IsZero() {
+		return false
+	}
+	c.pausedAt = time.Now()
+	c.resumedCh = make(chan struct{})
+	return true
+}
+
+// resume 退出暂停状态并返回暂停时长；未暂停时返回 false。
+func (c *pauseClock) resume() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pausedAt.IsZero() {
+		return 0, false
+	}
+	d := time.Since(c.pausedAt)
+	c.pausedAt = time.Time{}
+	close(c.resumedCh)
+	c.resumedCh = nil
+	return d, true
+}
+
+func (c *pauseClock) paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.pausedAt.IsZero()
+}
+
+// resumed 返回一个在未暂停时可立即接收、暂停时在 Resume 后才可接收的 channel。
+func (c *pauseClock) resumed() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumedCh == nil {
+		return closedChan
+	}
+	return c.resumedCh
+}
+
+// shiftSlot 返回后移 d 的新时间槽；已输出的行可能共享原槽，故不原地修改。
+func shiftSlot(slot *types.TimeSlot, d time.Duration) *types.TimeSlot {
+	if slot == nil || slot.Start == nil || slot.End == nil {
+		return slot
+	}
+	start, end := slot.Start.Add(d), slot.End.Add(d)
+	return types.NewTimeSlot(&start, &end)
+}
+
+// shiftRows 将缓冲行的时间戳后移 d，使其仍落在同样后移的窗口内。
+func shiftRows(rows []types.Row, d time.Duration) {
+	for i := range rows {
+		rows[i].Timestamp = rows[i].Timestamp.Add(d)
+	}
+}
+
+var (
+	_ Pauser = (*TumblingWindow)(nil)
+	_ Pauser = (*SlidingWindow)(nil)
+	_ Pauser = (*SessionWindow)(nil)
+	_ Pauser = (*CountingWindow)(nil)
+	_ Pauser = (*GlobalWindow)(nil)
+	_ Pauser = (*PerKeyWatermarkWindow)(nil)
+)
@@ -0,0 +1,112 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// awaitPausedWindow 暂停期间不应有输出；Resume 后窗口在剩余时间（约 remaining）后只触发一次
+func awaitPausedWindow(t *testing.T, w Window, pausedFor, remaining time.Duration) []types.Row {
+	t.Helper()
+	select {
+	case rows := <-w.OutputChan():
+		t.Fatalf("window fired while paused: %v", rows)
+	case <-time.After(pausedFor):
+	}
+	w.(Pauser).Resume()
+	resumedAt := time.Now()
+	var rows []types.Row
+	select {
+	case rows = <-w.OutputChan():
+	case <-time.After(remaining + time.Second):
+		t.Fatal("window did not fire after Resume")
+	}
+	elapsed := time.Since(resumedAt)
+	assert.GreaterOrEqual(t, elapsed, remaining/2, "fired before its remaining time elapsed")
+	return rows
+}
+
+func TestWindowPauseResume(t *testing.T) {
+	t.Run("tumbling", func(t *testing.T) {
+		tw, err := NewTumblingWindow(types.WindowConfig{Type: TypeTumbling, Params: []any{300 * time.Millisecond}})
+		require.NoError(t, err)
+		tw.Start()
+		defer tw.Stop()
+
+		tw.Add(map[string]any{"v": 1})
+		time.Sleep(100 * time.Millisecond)
+		// 处理时间窗口按纪元对齐，剩余时间取决于当前槽的结束时刻
+		tw.mu.RLock()
+		remaining := time.Until(*tw.currentSlot.End)
+		tw.mu.RUnlock()
+		tw.Pause()
+		rows := awaitPausedWindow(t, tw, 500*time.Millisecond, remaining)
+		require.Len(t, rows, 1)
+		// 窗口边界按暂停时长后移
+		assert.GreaterOrEqual(t, rows[0].Slot.End.Sub(*rows[0].Slot.Start), 300*time.Millisecond)
+		assert.WithinDuration(t, time.Now(), *rows[0].Slot.End, 100*time.Millisecond)
+
+		// 恢复后按原周期继续
+		tw.Add(map[string]any{"v": 2})
+		select {
+		case rows = <-tw.OutputChan():
+			assert.Equal(t, 2, rows[0].Data.(map[string]any)["v"])
+		case <-time.After(time.Second):
+			t.Fatal("next window did not fire")
+		}
+	})
+
+	t.Run("sliding", func(t *testing.T) {
+		sw, err := NewSlidingWindow(types.WindowConfig{Type: TypeSliding, Params: []any{300 * time.Millisecond, 300 * time.Millisecond}})
+		require.NoError(t, err)
+		sw.Start()
+		defer sw.Stop()
+
+		sw.Add(map[string]any{"v": 1})
+		time.Sleep(100 * time.Millisecond)
+		sw.Pause()
+		rows := awaitPausedWindow(t, sw, 500*time.Millisecond, 200*time.Millisecond)
+		require.Len(t, rows, 1)
+	})
+
+	t.Run("session", func(t *testing.T) {
+		sw, err := NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{300 * time.Millisecond}})
+		require.NoError(t, err)
+		sw.Start()
+		defer sw.Stop()
+
+		sw.Add(map[string]any{"v": 1})
+		time.Sleep(100 * time.Millisecond)
+		sw.Pause()
+		rows := awaitPausedWindow(t, sw, 600*time.Millisecond, 200*time.Millisecond)
+		require.Len(t, rows, 1)
+	})
+
+	t.Run("counting_timeout", func(t *testing.T) {
+		cw, err := NewCountingWindow(types.WindowConfig{Type: TypeCounting, Params: []any{10, 300 * time.Millisecond}})
+		require.NoError(t, err)
+		cw.Start()
+		defer cw.Stop()
+
+		cw.Add(map[string]any{"v": 1})
+		time.Sleep(100 * time.Millisecond)
+		cw.Pause()
+		rows := awaitPausedWindow(t, cw, 500*time.Millisecond, 200*time.Millisecond)
+		require.Len(t, rows, 1)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		tw, err := NewTumblingWindow(types.WindowConfig{Type: TypeTumbling, Params: []any{time.Second}})
+		require.NoError(t, err)
+		tw.Resume() // 未暂停时无操作
+		tw.Pause()
+		tw.Pause()
+		tw.Resume()
+		tw.Resume()
+		assert.False(t, tw.pause.paused())
+	})
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"sync"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// maxPooledBatch caps the capacity of batches kept for reuse, so one huge
+// window does not pin its memory in the pool.
+const maxPooledBatch = 64 * 1024
+
+// batchPool holds emitted row batches handed back with ReleaseBatch. Windows
+// draw the batches they emit from it; nothing is put back unless a consumer
+// releases a batch (PerformanceConfig.RowPooling), so without it every batch is
+// a fresh allocation as before.
+var batchPool sync.Pool
+
+// newBatch returns an empty batch with room for at least n rows, reusing a
+// released batch when one is large enough.
+func newBatch(n int) []types.Row {
+	if p, ok := batchPool.Get().(*[]types.Row); ok {
+		if cap(*p) >= n {
+			return (*p)[:0]
+		}
+	}
+	return make([]types.Row, 0, n)
+}
+
+// ownedCallback wraps callback so it gets its own copy of every batch when
+// RowPooling is on: the emitted batch itself goes back to the pool once the
+// stream has aggregated it, while a callback may keep what it is given.
+func ownedCallback(callback func([]types.Row), perf types.PerformanceConfig) func([]types.Row) {
+	if callback == nil || !perf.RowPooling {
+		return callback
+	}
+	return func(batch []types.Row) {
+		callback(append([]types.Row(nil), batch...))
+	}
+}
+
+// ReleaseBatch hands a batch received from OutputChan back for reuse by later
+// window fires. The caller must not touch batch or its rows afterwards; the
+// row data they referenced is not modified and stays valid. Batches handed to
+// callbacks are copies (see ownedCallback) and are never released.
+func ReleaseBatch(batch []types.Row) {
+	if cap(batch) == 0 || cap(batch) > maxPooledBatch {
+		return
+	}
+	for i := range batch {
+		batch[i].Reset()
+	}
+	batch = batch[:0]
+	batchPool.Put(&batch)
+}
@@ -0,0 +1,69 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseBatch(t *testing.T) {
+	now := time.Now()
+	data := map[string]any{"v": 1}
+	batch := newBatch(4)
+	assert.Len(t, batch, 0)
+	assert.GreaterOrEqual(t, cap(batch), 4)
+	batch = append(batch, types.Row{Data: data, Timestamp: now}, types.Row{Data: data, Timestamp: now})
+
+	ReleaseBatch(batch)
+	// Released rows no longer reference their data; the data itself is intact.
+	for _, r := range batch[:cap(batch)] {
+		assert.Equal(t, types.Row{}, r)
+	}
+	assert.Equal(t, map[string]any{"v": 1}, data)
+
+	// A reused batch always comes back empty, with the requested room.
+	next := newBatch(8)
+	assert.Len(t, next, 0)
+	assert.GreaterOrEqual(t, cap(next), 8)
+	ReleaseBatch(nil) // nothing to recycle
+}
+
+// TestRowPoolingCallbackKeepsBatch checks that with RowPooling a callback may
+// keep the batch it is given while the emitted batch is released and reused.
+func TestRowPoolingCallbackKeepsBatch(t *testing.T) {
+	perf := types.DefaultPerformanceConfig()
+	perf.RowPooling = true
+	var kept [][]types.Row
+	cw, err := NewCountingWindow(types.WindowConfig{
+		Params:            []any{2},
+		PerformanceConfig: perf,
+		Callback: func(batch []types.Row) {
+			kept = append(kept, batch)
+		},
+	})
+	assert.NoError(t, err)
+	go cw.Start()
+	defer cw.Stop()
+
+	for i := 1; i <= 6; i++ {
+		cw.Add(map[string]any{"v": i})
+		if i%2 == 0 {
+			select {
+			case batch := <-cw.OutputChan():
+				ReleaseBatch(batch)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for window batch")
+			}
+		}
+	}
+
+	assert.Len(t, kept, 3)
+	for i, batch := range kept {
+		if assert.Len(t, batch, 2) {
+			assert.Equal(t, map[string]any{"v": 2*i + 1}, batch[0].Data)
+			assert.Equal(t, map[string]any{"v": 2*i + 2}, batch[1].Data)
+		}
+	}
+}
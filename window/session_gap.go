@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rulego/streamsql/expr"
+	"github.com/rulego/streamsql/utils/cast"
+	"github.com/rulego/streamsql/utils/fieldpath"
+)
+
+// SessionGap is a SessionWindow parameter naming a per-row gap source: a field
+// reference or an arithmetic expression, written unquoted in SQL
+// (SessionWindow(gap_ms / 1000)). A plain string parameter is always a
+// duration literal and must parse as one.
+type SessionGap string
+
+// sessionGap resolves a session gap per row for SessionWindow(gap_expr), where
+// gap_expr is a field reference or an arithmetic expression instead of a
+// duration literal. Numeric results are seconds; string field values are
+// parsed as durations (e.g. "90s").
+type sessionGap struct {
+	source string
+	// field is set for a plain field path (nested paths allowed), looked up
+	// directly so string durations are preserved.
+	field string
+	// expression is set for anything else and evaluated against map rows.
+	expression *expr.Expression
+}
+
+// newSessionGap compiles a gap expression. It fails for expressions that do
+// not parse, so a typo surfaces when the window is created.
+func newSessionGap(source string) (*sessionGap, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("empty session gap expression")
+	}
+	if !strings.ContainsAny(source, " +-*/%()") {
+		return &sessionGap{source: source, field: source}, nil
+	}
+	e, err := expr.NewExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session gap expression %q: %w", source, err)
+	}
+	return &sessionGap{source: source, expression: e}, nil
+}
+
+// resolve returns the row's gap; ok is false when the gap is missing, NULL,
+// not a duration or not positive.
+func (g *sessionGap) resolve(data any) (time.Duration, bool) {
+	var val any
+	if g.field != "" {
+		v, found := fieldpath.GetNestedField(data, g.field)
+		if !found {
+			return 0, false
+		}
+		val = v
+	} else {
+		m, isMap := data.(map[string]any)
+		if !isMap {
+			return 0, false
+		}
+		v, isNull, err := g.expression.EvaluateValueWithNull(m)
+		if err != nil || isNull {
+			return 0, false
+		}
+		val = v
+	}
+	gap, ok := gapDuration(val)
+	return gap, ok && gap > 0
+}
+
+// gapDuration converts a gap value: numbers (and numeric strings) are
+// seconds, other strings are Go durations.
+func gapDuration(v any) (time.Duration, bool) {
+	switch g := v.(type) {
+	case nil:
+		return 0, false
+	case time.Duration:
+		return g, true
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(g), 64); err == nil {
+			return time.Duration(f * float64(time.Second)), true
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(g))
+		return d, err == nil
+	}
+	f, err := cast.ToFloat64E(v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(f * float64(time.Second)), true
+}
@@ -0,0 +1,97 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sessionKeys(sw *SessionWindow) map[string]time.Duration {
+	sw.mu.RLock()
+	defer sw.mu.RUnlock()
+	gaps := make(map[string]time.Duration, len(sw.sessionMap))
+	for k, s := range sw.sessionMap {
+		gaps[k] = s.gap
+	}
+	return gaps
+}
+
+// SessionWindow(gap) 按行取会话间隔：每个分组按自己的间隔独立关闭，
+// 同一会话内间隔不一致时以最新一行为准。
+func TestSessionWindow_PerRowGap(t *testing.T) {
+	sw, err := NewSessionWindow(types.WindowConfig{
+		Type:        TypeSession,
+		Params:      []any{SessionGap("gap")},
+		GroupByKeys: []string{"device"},
+	})
+	require.NoError(t, err)
+	sw.Start()
+	defer sw.Stop()
+
+	sw.Add(map[string]any{"device": "fast", "gap": 0.2})
+	sw.Add(map[string]any{"device": "slow", "gap": "5s"})
+	sw.Add(map[string]any{"device": "shrink", "gap": 10})
+	sw.Add(map[string]any{"device": "shrink", "gap": 0.3})
+	assert.Equal(t, map[string]time.Duration{
+		"fast":   200 * time.Millisecond,
+		"slow":   5 * time.Second,
+		"shrink": 300 * time.Millisecond,
+	}, sessionKeys(sw))
+
+	closed := map[string]int{}
+	deadline := time.After(3 * time.Second)
+	for len(closed) < 2 {
+		select {
+		case rows := <-sw.OutputChan():
+			closed[rows[0].Data.(map[string]any)["device"].(string)] = len(rows)
+		case <-deadline:
+			t.Fatalf("timeout, closed %v", closed)
+		}
+	}
+	assert.Equal(t, map[string]int{"fast": 1, "shrink": 2}, closed)
+	assert.Contains(t, sessionKeys(sw), "slow", "间隔更长的会话仍然打开")
+}
+
+func TestSessionWindow_GapExpressionAndFallback(t *testing.T) {
+	t.Run("表达式", func(t *testing.T) {
+		sw, err := NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{SessionGap("gap_ms / 1000")}})
+		require.NoError(t, err)
+		sw.Add(map[string]any{"gap_ms": 1500})
+		assert.Equal(t, 1500*time.Millisecond, sessionKeys(sw)["default"])
+	})
+
+	t.Run("无法解析的间隔丢弃并回调", func(t *testing.T) {
+		var reasons []string
+		sw, err := NewSessionWindow(types.WindowConfig{
+			Type:   TypeSession,
+			Params: []any{SessionGap("gap")},
+			OnDrop: func(data any, reason string) { reasons = append(reasons, reason) },
+		})
+		require.NoError(t, err)
+		sw.Add(map[string]any{"v": 1})
+		sw.Add(map[string]any{"gap": -1})
+		sw.Add(map[string]any{"gap": "soon"})
+		assert.Empty(t, sessionKeys(sw))
+		assert.Len(t, reasons, 3)
+	})
+
+	t.Run("默认间隔", func(t *testing.T) {
+		sw, err := NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{SessionGap("gap"), time.Minute}})
+		require.NoError(t, err)
+		sw.Add(map[string]any{"v": 1})
+		assert.Equal(t, time.Minute, sessionKeys(sw)["default"])
+	})
+
+	t.Run("非法参数", func(t *testing.T) {
+		_, err := NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{SessionGap("(gap")}})
+		assert.Error(t, err)
+		_, err = NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{SessionGap("gap"), "later"}})
+		assert.Error(t, err)
+		// 带引号的字符串是时长字面量，不会当作字段
+		_, err = NewSessionWindow(types.WindowConfig{Type: TypeSession, Params: []any{"5mins"}})
+		assert.Error(t, err)
+	})
+}
@@ -37,8 +37,12 @@ var _ Window = (*SessionWindow)(nil)
 type SessionWindow struct {
 	// config is the window configuration information
 	config types.WindowConfig
-	// timeout is the session timeout duration, session will close if no new events within this time
+	// timeout is the session timeout duration, session will close if no new events within this time.
+	// With a per-row gap it is the fallback gap for rows whose gap cannot be resolved (0 = none).
 	timeout time.Duration
+	// gap resolves the session gap from each row when the window was created
+	// with a field/expression instead of a duration literal; nil means constant timeout
+	gap *sessionGap
 	// mu is used to protect concurrent access to window data
 	mu sync.RWMutex
 	// sessionMap stores session data for different keys
@@ -60,6 +64,8 @@ type SessionWindow struct {
 	// Lock to protect ticker
 	tickerMu sync.Mutex
 	ticker   *time.Ticker
+	// pause suspends the processing-time expiry check (see Pauser)
+	pause pauseClock
 	// watermark for event time processing (only used for EventTime)
 	watermark *Watermark
 	// triggeredSessions stores sessions that have been triggered but are still open for late data (for EventTime with allowedLateness)
@@ -80,9 +86,17 @@ type session struct {
 	data       []types.Row
 	lastActive time.Time
 	slot       *types.TimeSlot
+	// gap is the gap of the latest row (by timestamp); it decides when the session closes
+	gap time.Duration
 }
 
-// NewSessionWindow creates a new session window instance
+// NewSessionWindow creates a new session window instance.
+// Params[0] is either a duration literal (constant gap) or a string naming a
+// field or expression that yields each row's gap (numbers are seconds, strings
+// durations). With a per-row gap, the optional Params[1] is the fallback gap
+// for rows whose gap is missing or invalid; without it such rows are dropped
+// (reported through OnDrop). When rows of one session carry different gaps, the
+// gap of the latest row decides when the session closes.
 func NewSessionWindow(config types.WindowConfig) (*SessionWindow, error) {
 	// Get timeout parameter from params array
 	if len(config.Params) == 0 {
@@ -93,12 +107,27 @@ func NewSessionWindow(config types.WindowConfig) (*SessionWindow, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	timeoutVal := config.Params[0]
-	timeout, err := cast.ToDurationE(timeoutVal)
-	if err != nil {
+	var timeout time.Duration
+	var err error
+	var gap *sessionGap
+	if gapSource, isGap := timeoutVal.(SessionGap); isGap {
+		// A field/expression resolved per row
+		gap, err = newSessionGap(string(gapSource))
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid timeout for session window: %v", err)
+		}
+		timeout = 0
+		if len(config.Params) > 1 {
+			if timeout, err = cast.ToDurationE(config.Params[1]); err != nil || timeout <= 0 {
+				cancel()
+				return nil, fmt.Errorf("invalid default gap for session window: %v", config.Params[1])
+			}
+		}
+	} else if timeout, err = cast.ToDurationE(timeoutVal); err != nil {
 		cancel()
 		return nil, fmt.Errorf("invalid timeout for session window: %v", err)
-	}
-	if timeout <= 0 {
+	} else if timeout <= 0 {
 		cancel()
 		return nil, fmt.Errorf("session window timeout must be positive, got: %v", timeout)
 	}
@@ -129,11 +158,13 @@ func NewSessionWindow(config types.WindowConfig) (*SessionWindow, error) {
 		idleTimeout := config.IdleTimeout
 		// Default: 0 means disabled, no idle source mechanism
 		watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+		watermark.SetOnIdle(config.OnIdleSource)
 	}
 
 	return &SessionWindow{
 		config:            config,
 		timeout:           timeout,
+		gap:               gap,
 		sessionMap:        make(map[string]*session),
 		outputChan:        make(chan []types.Row, bufferSize),
 		ctx:               ctx,
@@ -181,6 +212,7 @@ func (sw *SessionWindow) Add(data any) {
 	// For event time, update watermark and check for late data
 	if timeChar == types.EventTime {
 		if !tsOk {
+			reportUnplaceable(sw.config, data)
 			return // unplaceable event: drop instead of fake wall-clock time
 		}
 		if sw.watermark != nil {
@@ -206,6 +238,18 @@ func (sw *SessionWindow) Add(data any) {
 		row.Timestamp = timestamp
 	}
 
+	gap := sw.timeout
+	if sw.gap != nil {
+		if g, ok := sw.gap.resolve(data); ok {
+			gap = g
+		} else if gap <= 0 {
+			if sw.config.OnDrop != nil {
+				sw.config.OnDrop(data, fmt.Sprintf("no usable session gap from %q", sw.gap.source))
+			}
+			return
+		}
+	}
+
 	// Extract session key (supports multiple group by keys)
 	key := extractSessionCompositeKey(data, sw.config.GroupByKeys)
 
@@ -216,22 +260,25 @@ func (sw *SessionWindow) Add(data any) {
 		// Use the actual timestamp of the first data point as session start
 		// No alignment needed - session starts from when first data arrives
 		start := timestamp
-		end := start.Add(sw.timeout)
+		end := start.Add(gap)
 		slot := types.NewTimeSlot(&start, &end)
 
 		s = &session{
 			data:       []types.Row{},
 			lastActive: timestamp,
 			slot:       slot,
+			gap:        gap,
 		}
 		sw.sessionMap[key] = s
 	} else {
 		// Update session end time
 		if timestamp.After(s.lastActive) {
 			s.lastActive = timestamp
-			// Extend session end time
-			newEnd := timestamp.Add(sw.timeout)
-			if newEnd.After(*s.slot.End) {
+			s.gap = gap
+			// Extend session end time. A per-row gap follows the latest row, so
+			// a shorter gap may close the session earlier than before.
+			newEnd := timestamp.Add(gap)
+			if newEnd.After(*s.slot.End) || sw.gap != nil {
 				s.slot.End = &newEnd
 			}
 		}
@@ -277,7 +324,7 @@ func (sw *SessionWindow) startProcessingTime() {
 
 		// Periodically check expired sessions
 		sw.tickerMu.Lock()
-		sw.ticker = time.NewTicker(sw.timeout / 2)
+		sw.ticker = time.NewTicker(sw.checkInterval())
 		ticker := sw.ticker
 		sw.tickerMu.Unlock()
 
@@ -292,7 +339,9 @@ func (sw *SessionWindow) startProcessingTime() {
 		for {
 			select {
 			case <-ticker.C:
-				sw.checkExpiredSessions()
+				if !sw.pause.paused() {
+					sw.checkExpiredSessions()
+				}
 			case <-sw.ctx.Done():
 				return
 			}
@@ -367,6 +416,16 @@ func (sw *SessionWindow) Stop() {
 	sw.mu.Unlock()
 }
 
+// checkInterval is the processing-time expiry check period: half the gap, and
+// at most 500ms with per-row gaps, which may be shorter than the fallback.
+func (sw *SessionWindow) checkInterval() time.Duration {
+	interval := sw.timeout / 2
+	if sw.gap != nil && (interval <= 0 || interval > 500*time.Millisecond) {
+		interval = 500 * time.Millisecond
+	}
+	return interval
+}
+
 func (sw *SessionWindow) checkExpiredSessions() {
 	sw.mu.Lock()
 	now := time.Now()
@@ -381,6 +440,38 @@ func (sw *SessionWindow) checkExpiredSessions() {
 	sw.sendResults(resultsToSend, callback)
 }
 
+// Pause implements Pauser: processing-time sessions stop expiring and
+// idle-source detection is suspended.
+func (sw *SessionWindow) Pause() {
+	if sw.pause.pause() && sw.watermark != nil {
+		sw.watermark.pause()
+	}
+}
+
+// Resume implements Pauser: open processing-time sessions and their rows move
+// later by the paused duration, so a session closes once the gap it had left
+// at Pause elapses.
+func (sw *SessionWindow) Resume() {
+	d, ok := sw.pause.resume()
+	if !ok {
+		return
+	}
+	if sw.watermark != nil {
+		sw.watermark.resume(d)
+		return
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	for _, s := range sw.sessionMap {
+		s.lastActive = s.lastActive.Add(d)
+		s.slot = shiftSlot(s.slot, d)
+		shiftRows(s.data, d)
+	}
+	for _, info := range sw.triggeredSessions {
+		info.closeTime = info.closeTime.Add(d)
+	}
+}
+
 func (sw *SessionWindow) checkAndTriggerSessions(watermarkTime time.Time) {
 	sw.mu.Lock()
 	resultsToSend := sw.collectExpiredSessions(watermarkTime)
@@ -399,7 +490,7 @@ func (sw *SessionWindow) collectExpiredSessions(currentTime time.Time) [][]types
 		// For processing time, use lastActive + timeout
 		if s.slot.End != nil && !currentTime.Before(*s.slot.End) {
 			expiredKeys = append(expiredKeys, key)
-		} else if currentTime.Sub(s.lastActive) > sw.timeout {
+		} else if currentTime.Sub(s.lastActive) > s.gap {
 			expiredKeys = append(expiredKeys, key)
 		}
 	}
@@ -410,7 +501,7 @@ func (sw *SessionWindow) collectExpiredSessions(currentTime time.Time) [][]types
 	for _, key := range expiredKeys {
 		s := sw.sessionMap[key]
 		if len(s.data) > 0 {
-			result := make([]types.Row, len(s.data))
+			result := newBatch(len(s.data))[:len(s.data)]
 			copy(result, s.data)
 			resultsToSend = append(resultsToSend, result)
 
@@ -446,6 +537,7 @@ func (sw *SessionWindow) sendResults(resultsToSend [][]types.Row, callback func(
 }
 
 func (sw *SessionWindow) sendResult(data []types.Row) {
+	stampWatermark(data, sw.watermark)
 	strategy := sw.config.PerformanceConfig.OverflowConfig.Strategy
 	timeout := sw.config.PerformanceConfig.OverflowConfig.BlockTimeout
 
@@ -511,7 +603,7 @@ func (sw *SessionWindow) Trigger() {
 	for _, s := range sw.sessionMap {
 		if len(s.data) > 0 {
 			// Trigger session window
-			result := make([]types.Row, len(s.data))
+			result := newBatch(len(s.data))[:len(s.data)]
 			copy(result, s.data)
 			resultsToSend = append(resultsToSend, result)
 		}
@@ -526,6 +618,11 @@ func (sw *SessionWindow) Trigger() {
 	sw.sendResults(resultsToSend, callback)
 }
 
+// Flush closes every open session regardless of its gap timeout.
+func (sw *SessionWindow) Flush() {
+	sw.Trigger()
+}
+
 // Reset resets session window data
 func (sw *SessionWindow) Reset() {
 	// Cancel context and join the background goroutine before resetting state
@@ -563,6 +660,7 @@ func (sw *SessionWindow) Reset() {
 			}
 			idleTimeout := sw.config.IdleTimeout
 			sw.watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+			sw.watermark.SetOnIdle(sw.config.OnIdleSource)
 		}
 	}
 
@@ -582,7 +680,7 @@ func (sw *SessionWindow) OutputChan() <-chan []types.Row {
 func (sw *SessionWindow) SetCallback(callback func([]types.Row)) {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
-	sw.callback = callback
+	sw.callback = ownedCallback(callback, sw.config.PerformanceConfig)
 }
 
 // handleLateData absorbs a late event into a still-open triggered session:
@@ -608,7 +706,7 @@ func (sw *SessionWindow) triggerLateUpdateLocked(s *session) {
 	}
 
 	// Extract session data including late data
-	resultData := make([]types.Row, len(s.data))
+	resultData := newBatch(len(s.data))[:len(s.data)]
 	copy(resultData, s.data)
 
 	// Get callback reference before releasing lock
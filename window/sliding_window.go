@@ -81,20 +81,39 @@ type SlidingWindow struct {
 	initialized bool
 	// timerMu protects timer access
 	timerMu sync.Mutex
+	// realignTimer is set by Resume when the ticker was reset to the time left
+	// until the next slide; the next tick restores the slide period. Guarded by timerMu.
+	realignTimer bool
+	// pause freezes the processing-time timers (see Pauser)
+	pause pauseClock
 	// firstWindowStartTime records when first window started (processing time)
 	firstWindowStartTime time.Time
 	// watermark for event time processing (only used for EventTime)
 	watermark *Watermark
+	// sharedClock: the owner drives the watermark through advanceSharedClock,
+	// so the window starts no event-time goroutine (see PerKeyWatermarkWindow)
+	sharedClock bool
 	// triggeredWindows stores windows that have been triggered but are still open for late data (for EventTime with allowedLateness)
 	triggeredWindows map[string]*triggeredWindowInfo // key: window end time string
+	// latestTs is the newest row timestamp seen; with currentSlot it bounds the
+	// windows that have started but not yet closed (see openWindowsLocked).
+	latestTs time.Time
 	// Performance statistics
-	droppedCount int64 // Number of dropped results
-	sentCount    int64 // Number of successfully sent results
+	droppedCount     int64 // Number of dropped results
+	sentCount        int64 // Number of successfully sent results
+	forceClosedCount int64 // Windows closed early by OpenWindowCloseOldest
+	rejectedCount    int64 // Rows dropped by OpenWindowReject
 }
 
 // NewSlidingWindow creates a new sliding window instance
 // size parameter represents the total window size, slide represents the sliding interval
 func NewSlidingWindow(config types.WindowConfig) (*SlidingWindow, error) {
+	return newSlidingWindow(config, false)
+}
+
+// newSlidingWindow creates the window; with sharedClock its event-time watermark is
+// driven by the caller through advanceSharedClock instead of goroutines of its own.
+func newSlidingWindow(config types.WindowConfig, sharedClock bool) (*SlidingWindow, error) {
 	// Get size parameter from params array
 	if len(config.Params) < 1 {
 		return nil, fmt.Errorf("sliding window requires at least 'size' parameter")
@@ -123,6 +142,15 @@ func NewSlidingWindow(config types.WindowConfig) (*SlidingWindow, error) {
 		return nil, fmt.Errorf("sliding window slide must be positive, got: %v", slide)
 	}
 
+	if config.MaxOpenWindows < 0 {
+		return nil, fmt.Errorf("sliding window max open windows must not be negative, got: %d", config.MaxOpenWindows)
+	}
+	switch config.OpenWindowPolicy {
+	case "", types.OpenWindowCloseOldest, types.OpenWindowReject:
+	default:
+		return nil, fmt.Errorf("invalid open window policy %q: must be %q or %q", config.OpenWindowPolicy, types.OpenWindowCloseOldest, types.OpenWindowReject)
+	}
+
 	// Use unified performance config to get window output buffer size
 	bufferSize := 1000 // Default value
 	if (config.PerformanceConfig != types.PerformanceConfig{}) {
@@ -148,7 +176,12 @@ func NewSlidingWindow(config types.WindowConfig) (*SlidingWindow, error) {
 		}
 		idleTimeout := config.IdleTimeout
 		// Default: 0 means disabled, no idle source mechanism
-		watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+		if sharedClock {
+			watermark = newDrivenWatermark(maxOutOfOrderness, idleTimeout)
+		} else {
+			watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+		}
+		watermark.SetOnIdle(config.OnIdleSource)
 	}
 
 	// Create a cancellable context
@@ -164,6 +197,7 @@ func NewSlidingWindow(config types.WindowConfig) (*SlidingWindow, error) {
 		initChan:         make(chan struct{}),
 		initialized:      false,
 		watermark:        watermark,
+		sharedClock:      sharedClock,
 		triggeredWindows: make(map[string]*triggeredWindowInfo),
 	}, nil
 }
@@ -186,6 +220,7 @@ func (sw *SlidingWindow) Add(data any) {
 
 	if timeChar == types.EventTime {
 		if !tsOk {
+			reportUnplaceable(sw.config, data)
 			return // unplaceable event: drop instead of fake wall-clock time
 		}
 		if sw.watermark != nil {
@@ -224,11 +259,23 @@ func (sw *SlidingWindow) Add(data any) {
 		}
 		sw.initialized = true
 	}
+	// OpenWindowReject: refuse a row that would open more windows than allowed.
+	if sw.config.MaxOpenWindows > 0 && sw.config.OpenWindowPolicy == types.OpenWindowReject &&
+		sw.openWindowsLocked(eventTime) > sw.config.MaxOpenWindows {
+		atomic.AddInt64(&sw.rejectedCount, 1)
+		if sw.config.OnDrop != nil {
+			sw.config.OnDrop(data, fmt.Sprintf("sliding window open-window limit %d exceeded", sw.config.MaxOpenWindows))
+		}
+		return
+	}
 	row := types.Row{
 		Data:      data,
 		Timestamp: eventTime,
 	}
 	sw.data = append(sw.data, row)
+	if eventTime.After(sw.latestTs) {
+		sw.latestTs = eventTime
+	}
 	debugLogSliding("Add: added data, eventTime=%v, totalData=%d, currentSlot=[%v, %v), inWindow=%v",
 		eventTime.UnixMilli(), len(sw.data),
 		sw.currentSlot.Start.UnixMilli(), sw.currentSlot.End.UnixMilli(),
@@ -261,6 +308,64 @@ func (sw *SlidingWindow) Add(data any) {
 			sw.dropLastRow()
 		}
 	}
+
+	// OpenWindowCloseOldest: the row is already buffered, so the windows closed
+	// here include it.
+	if sw.config.MaxOpenWindows > 0 && sw.config.OpenWindowPolicy != types.OpenWindowReject {
+		for sw.openWindowsLocked(sw.latestTs) > sw.config.MaxOpenWindows {
+			if !sw.forceCloseOldestLocked() {
+				break
+			}
+		}
+	}
+}
+
+// openWindowsLocked counts the windows that are open once a row at ts is
+// accepted: windows held open for late data plus every window from currentSlot
+// up to the one starting at or before the newest timestamp. Caller holds sw.mu.
+func (sw *SlidingWindow) openWindowsLocked(ts time.Time) int {
+	open := len(sw.triggeredWindows)
+	if sw.currentSlot == nil {
+		return open
+	}
+	latest := sw.latestTs
+	if ts.After(latest) {
+		latest = ts
+	}
+	if len(sw.data) == 0 && !ts.After(sw.latestTs) {
+		return open // nothing buffered: no pending window holds data
+	}
+	if start := *sw.currentSlot.Start; !latest.Before(start) {
+		open += int(latest.Sub(start)/sw.slide) + 1
+	}
+	return open
+}
+
+// forceCloseOldestLocked closes the oldest open window: a window held open for
+// late data is simply released; otherwise currentSlot is emitted early and the
+// window advances. Returns false when there is nothing left to close. Caller
+// holds sw.mu; emitting releases and re-acquires it like checkAndTriggerWindows.
+func (sw *SlidingWindow) forceCloseOldestLocked() bool {
+	if len(sw.triggeredWindows) > 0 {
+		var oldestKey string
+		var oldestEnd time.Time
+		for key, info := range sw.triggeredWindows {
+			if oldestKey == "" || info.slot.End.Before(oldestEnd) {
+				oldestKey, oldestEnd = key, *info.slot.End
+			}
+		}
+		delete(sw.triggeredWindows, oldestKey)
+		atomic.AddInt64(&sw.forceClosedCount, 1)
+		return true
+	}
+	if sw.currentSlot == nil {
+		return false
+	}
+	slot := sw.currentSlot
+	sw.currentSlot = sw.NextSlot()
+	atomic.AddInt64(&sw.forceClosedCount, 1)
+	sw.triggerSpecificWindowLocked(slot)
+	return true
 }
 
 // dropLastRow removes the row just appended by the current Add call (the last
@@ -284,6 +389,9 @@ func (sw *SlidingWindow) Start() {
 
 	if timeChar == types.EventTime {
 		// Event time: trigger based on watermark
+		if sw.sharedClock {
+			return // driven through advanceSharedClock
+		}
 		sw.startEventTime()
 	} else {
 		// Processing time: trigger based on system clock
@@ -307,43 +415,42 @@ func (sw *SlidingWindow) startProcessingTime() {
 
 		// Wait for first window to end, then trigger it
 		// After initChan is closed, firstWindowStartTime should be set by Add()
-		sw.mu.RLock()
-		firstWindowStartTime := sw.firstWindowStartTime
-		sw.mu.RUnlock()
-
+		sw.mu.Lock()
 		// Verify that firstWindowStartTime is valid (not zero)
 		// If zero, it means Add() hasn't been called yet, which shouldn't happen
 		// but we handle it gracefully by waiting for window size
-		if firstWindowStartTime.IsZero() {
+		if sw.firstWindowStartTime.IsZero() {
 			// This shouldn't happen if Add() is called before Start(),
 			// but if it does, wait for window size from now
-			firstWindowStartTime = time.Now()
-		}
-
-		// Calculate time until first window ends (window size from processing time)
-		now := time.Now()
-		elapsed := now.Sub(firstWindowStartTime)
-		var waitDuration time.Duration
-		if elapsed < sw.size {
-			// Wait until window size time has passed
-			waitDuration = sw.size - elapsed
-		} else {
-			// First window already ended, trigger immediately
-			waitDuration = 0
+			sw.firstWindowStartTime = time.Now()
 		}
+		sw.mu.Unlock()
 
-		// Wait for first window to end
-		if waitDuration > 0 {
+		// Wait for first window to end (window size from processing time). The
+		// wait is recomputed after a Pause: Resume moves firstWindowStartTime
+		// later by the paused duration.
+		for {
 			select {
-			case <-time.After(waitDuration):
+			case <-sw.pause.resumed():
+			case <-sw.ctx.Done():
+				return
+			}
+			sw.mu.RLock()
+			waitDuration := sw.size - time.Since(sw.firstWindowStartTime)
+			sw.mu.RUnlock()
+			if waitDuration <= 0 {
+				if sw.pause.paused() {
+					continue
+				}
 				// First window ended, trigger it
 				sw.Trigger()
+				break
+			}
+			select {
+			case <-time.After(waitDuration):
 			case <-sw.ctx.Done():
 				return
 			}
-		} else {
-			// First window already ended, trigger immediately
-			sw.Trigger()
 		}
 
 		// Now start the sliding step timer for subsequent windows
@@ -371,6 +478,15 @@ func (sw *SlidingWindow) startProcessingTime() {
 			select {
 			// Trigger window when timer expires
 			case <-timer.C:
+				if sw.pause.paused() {
+					continue // stale tick from before Pause
+				}
+				sw.timerMu.Lock()
+				if sw.realignTimer {
+					timer.Reset(sw.slide)
+					sw.realignTimer = false
+				}
+				sw.timerMu.Unlock()
 				sw.Trigger()
 			// Stop timer and exit loop when context is cancelled
 			case <-sw.ctx.Done():
@@ -547,7 +663,7 @@ func (sw *SlidingWindow) extractWindowDataLocked(slot *types.TimeSlot) []types.R
 	}
 
 	// Extract current window data
-	resultData := make([]types.Row, 0)
+	resultData := newBatch(0)
 	for _, item := range sw.data {
 		if slot.Contains(item.Timestamp) {
 			item.Slot = slot
@@ -694,7 +810,94 @@ func (sw *SlidingWindow) Trigger() {
 	sw.sendResult(resultData)
 }
 
+// Pause implements Pauser: it stops the processing-time timers and
+// idle-source detection.
+func (sw *SlidingWindow) Pause() {
+	if !sw.pause.pause() {
+		return
+	}
+	if sw.watermark != nil {
+		sw.watermark.pause()
+	}
+	sw.timerMu.Lock()
+	if sw.timer != nil {
+		sw.timer.Stop()
+	}
+	sw.timerMu.Unlock()
+}
+
+// Resume implements Pauser: open processing-time windows and their rows move
+// later by the paused duration, and the next slide fires after the time it had
+// left at Pause.
+func (sw *SlidingWindow) Resume() {
+	d, ok := sw.pause.resume()
+	if !ok {
+		return
+	}
+	if sw.watermark != nil {
+		sw.watermark.resume(d)
+		return
+	}
+	sw.mu.Lock()
+	if sw.firstWindowStartTime.IsZero() {
+		sw.mu.Unlock()
+		return
+	}
+	sw.firstWindowStartTime = sw.firstWindowStartTime.Add(d)
+	sw.currentSlot = shiftSlot(sw.currentSlot, d)
+	shiftRows(sw.data, d)
+	if !sw.latestTs.IsZero() {
+		sw.latestTs = sw.latestTs.Add(d)
+	}
+	// Slides fire at firstWindowStartTime + size + k*slide
+	sinceFirst := time.Since(sw.firstWindowStartTime) - sw.size
+	sw.mu.Unlock()
+
+	sw.timerMu.Lock()
+	if sw.timer != nil && sinceFirst >= 0 {
+		remaining := sw.slide - sinceFirst%sw.slide
+		sw.timer.Reset(remaining)
+		sw.realignTimer = remaining != sw.slide
+	}
+	sw.timerMu.Unlock()
+}
+
+// Flush fires the current and every following window that still holds data,
+// ignoring the timer/watermark, so the tail of a bounded input is emitted.
+func (sw *SlidingWindow) Flush() {
+	sw.mu.Lock()
+	if !sw.initialized || sw.currentSlot == nil || len(sw.data) == 0 {
+		sw.mu.Unlock()
+		return
+	}
+
+	var latest time.Time
+	for _, item := range sw.data {
+		if item.Timestamp.After(latest) {
+			latest = item.Timestamp
+		}
+	}
+	var results [][]types.Row
+	for sw.currentSlot != nil && !sw.currentSlot.Start.After(latest) {
+		if resultData := sw.extractWindowDataLocked(sw.currentSlot); len(resultData) > 0 {
+			results = append(results, resultData)
+		}
+		sw.currentSlot = sw.NextSlot()
+	}
+	sw.data = sw.data[:0]
+	callback := sw.callback
+	sw.mu.Unlock()
+
+	for _, resultData := range results {
+		if callback != nil {
+			callback(resultData)
+		}
+		sw.sendResult(resultData)
+	}
+}
+
 func (sw *SlidingWindow) sendResult(data []types.Row) {
+	stampWatermark(data, sw.watermark)
 	strategy := sw.config.PerformanceConfig.OverflowConfig.Strategy
 	timeout := sw.config.PerformanceConfig.OverflowConfig.BlockTimeout
 
@@ -735,11 +938,17 @@ func (sw *SlidingWindow) sendResult(data []types.Row) {
 
 // GetStats returns window performance statistics
 func (sw *SlidingWindow) GetStats() map[string]int64 {
+	sw.mu.RLock()
+	openWindows := sw.openWindowsLocked(time.Time{})
+	sw.mu.RUnlock()
 	return map[string]int64{
-		"sentCount":    atomic.LoadInt64(&sw.sentCount),
-		"droppedCount": atomic.LoadInt64(&sw.droppedCount),
-		"bufferSize":   int64(cap(sw.outputChan)),
-		"bufferUsed":   int64(len(sw.outputChan)),
+		"sentCount":          atomic.LoadInt64(&sw.sentCount),
+		"droppedCount":       atomic.LoadInt64(&sw.droppedCount),
+		"bufferSize":         int64(cap(sw.outputChan)),
+		"bufferUsed":         int64(len(sw.outputChan)),
+		"openWindows":        int64(openWindows),
+		"forceClosedWindows": atomic.LoadInt64(&sw.forceClosedCount),
+		"rejectedRows":       atomic.LoadInt64(&sw.rejectedCount),
 	}
 }
 
@@ -747,6 +956,8 @@ func (sw *SlidingWindow) GetStats() map[string]int64 {
 func (sw *SlidingWindow) ResetStats() {
 	atomic.StoreInt64(&sw.sentCount, 0)
 	atomic.StoreInt64(&sw.droppedCount, 0)
+	atomic.StoreInt64(&sw.forceClosedCount, 0)
+	atomic.StoreInt64(&sw.rejectedCount, 0)
 }
 
 // Reset resets the sliding window and clears window data
@@ -786,7 +997,12 @@ func (sw *SlidingWindow) Reset() {
 				watermarkInterval = 200 * time.Millisecond
 			}
 			idleTimeout := sw.config.IdleTimeout
-			sw.watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+			if sw.sharedClock {
+				sw.watermark = newDrivenWatermark(maxOutOfOrderness, idleTimeout)
+			} else {
+				sw.watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+			}
+			sw.watermark.SetOnIdle(sw.config.OnIdleSource)
 		}
 	}
 
@@ -796,6 +1012,7 @@ func (sw *SlidingWindow) Reset() {
 	sw.initialized = false
 	sw.initChan = make(chan struct{})
 	sw.firstWindowStartTime = time.Time{}
+	sw.latestTs = time.Time{}
 	sw.triggeredWindows = make(map[string]*triggeredWindowInfo)
 
 	// Recreate context for next startup
@@ -811,7 +1028,7 @@ func (sw *SlidingWindow) OutputChan() <-chan []types.Row {
 func (sw *SlidingWindow) SetCallback(callback func([]types.Row)) {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
-	sw.callback = callback
+	sw.callback = ownedCallback(callback, sw.config.PerformanceConfig)
 }
 
 func (sw *SlidingWindow) NextSlot() *types.TimeSlot {
@@ -869,7 +1086,7 @@ func (sw *SlidingWindow) triggerLateUpdateLocked(slot *types.TimeSlot) {
 	}
 
 	// Collect all data for this window: original snapshot + late data from sw.data
-	resultData := make([]types.Row, 0)
+	resultData := newBatch(0)
 
 	// First, add original snapshot data (if exists)
 	if windowInfo != nil && len(windowInfo.snapshotData) > 0 {
@@ -936,6 +1153,7 @@ func (sw *SlidingWindow) triggerLateUpdateLocked(slot *types.TimeSlot) {
 	}
 
 	// Non-blocking send to output channel and update statistics
+	stampWatermark(resultData, sw.watermark)
 	var sent bool
 	select {
 	case sw.outputChan <- resultData:
@@ -134,3 +134,21 @@ func TestGetTimestamp(t *testing.T) {
 	assert.Equal(t, t_0, t_2)
 	assert.Equal(t, t_0, t_3)
 }
+
+// TestCreateWindow_Hop 验证 hop 类型复用滑动窗口实现，并保留自身的类型标识
+func TestCreateWindow_Hop(t *testing.T) {
+	win, err := CreateWindow(types.WindowConfig{
+		Type:   TypeHop,
+		Params: []any{2 * time.Second, time.Second},
+	})
+	assert.NoError(t, err)
+	sw, ok := win.(*SlidingWindow)
+	if assert.True(t, ok, "hop 窗口应由滑动窗口实现") {
+		assert.Equal(t, TypeHop, sw.config.Type)
+		assert.Equal(t, 2*time.Second, sw.size)
+		assert.Equal(t, time.Second, sw.slide)
+	}
+
+	_, err = CreateWindow(types.WindowConfig{Type: TypeHop, Params: []any{2 * time.Second}})
+	assert.Error(t, err, "缺少 slide 参数应报错")
+}
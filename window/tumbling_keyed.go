@@ -0,0 +1,121 @@
+/*
+ * Copyright 2025 The RuleGo Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package window
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+)
+
+// keyClock is one group key's open window under WindowConfig.PerKeyClock. It
+// opens at the key's first row and fires when its own timer expires; the key
+// is then forgotten, so an idle key holds neither rows nor a timer.
+type keyClock struct {
+	slot  *types.TimeSlot
+	rows  []types.Row
+	timer *time.Timer
+}
+
+// perKeyClock reports whether rows are windowed per group key with independent
+// processing-time clocks instead of the shared epoch-aligned ticker.
+func (tw *TumblingWindow) perKeyClock() bool {
+	return tw.config.PerKeyClock && len(tw.config.GroupByKeys) > 0 &&
+		tw.config.TimeCharacteristic != types.EventTime
+}
+
+// addKeyedLocked buffers a row in its key's window, opening the window and
+// arming its timer on the key's first row. Caller holds tw.mu.
+func (tw *TumblingWindow) addKeyedLocked(data any, ts time.Time) {
+	if tw.ctx.Err() != nil {
+		return // stopped: do not arm new timers
+	}
+	key := extractSessionCompositeKey(data, tw.config.GroupByKeys)
+	kc := tw.keyClocks[key]
+	if kc == nil {
+		start := time.Now()
+		end := start.Add(tw.size)
+		c := &keyClock{slot: types.NewTimeSlot(&start, &end), rows: newBatch(0)}
+		c.timer = time.AfterFunc(tw.size, func() { tw.fireKey(key, c) })
+		tw.keyClocks[key] = c
+		kc = c
+	}
+	kc.rows = append(kc.rows, types.Row{Data: data, Timestamp: ts})
+}
+
+// fireKey emits a key's window when its timer expires. A clock that was
+// already flushed, reset or replaced is ignored.
+func (tw *TumblingWindow) fireKey(key string, kc *keyClock) {
+	tw.mu.Lock()
+	if tw.keyClocks[key] != kc || tw.ctx.Err() != nil {
+		tw.mu.Unlock()
+		return
+	}
+	delete(tw.keyClocks, key)
+	resultData := kc.slotRows()
+	callback := tw.callback
+	tw.mu.Unlock()
+
+	if callback != nil {
+		callback(resultData)
+	}
+	tw.sendResult(resultData)
+}
+
+// flushKeyed fires every open key window in key order, ignoring their timers
+// (Flush and manual Trigger).
+func (tw *TumblingWindow) flushKeyed() {
+	tw.mu.Lock()
+	keys := make([]string, 0, len(tw.keyClocks))
+	for key := range tw.keyClocks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	results := make([][]types.Row, 0, len(keys))
+	for _, key := range keys {
+		kc := tw.keyClocks[key]
+		kc.timer.Stop()
+		results = append(results, kc.slotRows())
+	}
+	tw.keyClocks = make(map[string]*keyClock)
+	callback := tw.callback
+	tw.mu.Unlock()
+
+	for _, resultData := range results {
+		if callback != nil {
+			callback(resultData)
+		}
+		tw.sendResult(resultData)
+	}
+}
+
+// stopKeyClocksLocked stops and drops every key's timer and rows. Caller holds tw.mu.
+func (tw *TumblingWindow) stopKeyClocksLocked() {
+	for _, kc := range tw.keyClocks {
+		kc.timer.Stop()
+	}
+	tw.keyClocks = make(map[string]*keyClock)
+}
+
+// slotRows stamps the window's slot on its rows.
+func (kc *keyClock) slotRows() []types.Row {
+	for i := range kc.rows {
+		kc.rows[i].Slot = kc.slot
+	}
+	return kc.rows
+}
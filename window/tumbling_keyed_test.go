@@ -0,0 +1,99 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rulego/streamsql/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPerKeyTumbling(t *testing.T, size time.Duration) *TumblingWindow {
+	t.Helper()
+	tw, err := NewTumblingWindow(types.WindowConfig{
+		Type:        TypeTumbling,
+		Params:      []any{size},
+		GroupByKeys: []string{"device"},
+		PerKeyClock: true,
+	})
+	require.NoError(t, err)
+	return tw
+}
+
+func recvBatch(t *testing.T, tw *TumblingWindow) []types.Row {
+	t.Helper()
+	select {
+	case rows := <-tw.OutputChan():
+		return rows
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for window")
+		return nil
+	}
+}
+
+// 每个分组键的窗口从该键首条数据开始、各自独立触发，触发后不再保留状态。
+func TestTumblingWindow_PerKeyClock(t *testing.T) {
+	const size = 300 * time.Millisecond
+	tw := newPerKeyTumbling(t, size)
+	tw.Start()
+	defer tw.Stop()
+
+	tw.Add(map[string]any{"device": "a", "v": 1})
+	time.Sleep(150 * time.Millisecond)
+	tw.Add(map[string]any{"device": "b", "v": 10})
+	tw.Add(map[string]any{"device": "a", "v": 2})
+
+	first := recvBatch(t, tw)
+	require.Len(t, first, 2)
+	for _, r := range first {
+		assert.Equal(t, "a", r.Data.(map[string]any)["device"])
+	}
+
+	// b 的窗口在 a 触发后仍打开：新的 b 行落入同一窗口
+	tw.Add(map[string]any{"device": "b", "v": 11})
+	second := recvBatch(t, tw)
+	require.Len(t, second, 2)
+	for _, r := range second {
+		assert.Equal(t, "b", r.Data.(map[string]any)["device"])
+	}
+
+	slotA, slotB := first[0].Slot, second[0].Slot
+	assert.Equal(t, size, slotA.End.Sub(*slotA.Start))
+	assert.Equal(t, size, slotB.End.Sub(*slotB.Start))
+	assert.GreaterOrEqual(t, slotB.Start.Sub(*slotA.Start), 100*time.Millisecond, "b 的窗口应从 b 的首条数据开始")
+	assert.Same(t, first[0].Slot, first[1].Slot)
+
+	// 触发后的键不保留计时器与数据
+	tw.mu.RLock()
+	assert.Empty(t, tw.keyClocks)
+	tw.mu.RUnlock()
+
+	// a 再次到达时开启新窗口
+	tw.Add(map[string]any{"device": "a", "v": 3})
+	third := recvBatch(t, tw)
+	require.Len(t, third, 1)
+	assert.False(t, third[0].Slot.Start.Before(*slotA.End))
+}
+
+func TestTumblingWindow_PerKeyClockFlushAndStop(t *testing.T) {
+	tw := newPerKeyTumbling(t, time.Hour)
+	tw.Start()
+	defer tw.Stop()
+
+	tw.Add(map[string]any{"device": "b", "v": 1})
+	tw.Add(map[string]any{"device": "a", "v": 2})
+	tw.Flush()
+	assert.Equal(t, "a", recvBatch(t, tw)[0].Data.(map[string]any)["device"])
+	assert.Equal(t, "b", recvBatch(t, tw)[0].Data.(map[string]any)["device"])
+
+	tw.Add(map[string]any{"device": "c", "v": 3})
+	tw.Stop()
+	tw.mu.RLock()
+	assert.Empty(t, tw.keyClocks)
+	tw.mu.RUnlock()
+	tw.Add(map[string]any{"device": "c", "v": 4})
+	tw.mu.RLock()
+	assert.Empty(t, tw.keyClocks, "stopped window must not arm new timers")
+	tw.mu.RUnlock()
+}
@@ -81,10 +81,20 @@ type TumblingWindow struct {
 	initialized bool
 	// timerMu protects timer access
 	timerMu sync.Mutex
+	// realignTimer is set by Resume when the ticker was reset to the remaining
+	// time of the open window; the next tick restores the full period. Guarded by timerMu.
+	realignTimer bool
+	// pause freezes the processing-time ticker and per-key clocks (see Pauser)
+	pause pauseClock
 	// watermark for event time processing (only used for EventTime)
 	watermark *Watermark
+	// sharedClock: the owner drives the watermark through advanceSharedClock,
+	// so the window starts no event-time goroutine (see PerKeyWatermarkWindow)
+	sharedClock bool
 	// triggeredWindows stores windows that have been triggered but are still open for late data (for EventTime with allowedLateness)
 	triggeredWindows map[string]*triggeredWindowInfo // key: window end time string
+	// keyClocks holds each group key's open window when config.PerKeyClock is set
+	keyClocks map[string]*keyClock
 	// Performance statistics
 	droppedCount int64 // Number of dropped results
 	sentCount    int64 // Number of successfully sent results
@@ -93,6 +103,12 @@ type TumblingWindow struct {
 // NewTumblingWindow creates a new tumbling window instance
 // Parameter size is the time size of the window
 func NewTumblingWindow(config types.WindowConfig) (*TumblingWindow, error) {
+	return newTumblingWindow(config, false)
+}
+
+// newTumblingWindow creates the window; with sharedClock its event-time watermark is
+// driven by the caller through advanceSharedClock instead of goroutines of its own.
+func newTumblingWindow(config types.WindowConfig, sharedClock bool) (*TumblingWindow, error) {
 	// Create a cancellable context
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -138,7 +154,12 @@ func NewTumblingWindow(config types.WindowConfig) (*TumblingWindow, error) {
 		}
 		idleTimeout := config.IdleTimeout
 		// Default: 0 means disabled, no idle source mechanism
-		watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+		if sharedClock {
+			watermark = newDrivenWatermark(maxOutOfOrderness, idleTimeout)
+		} else {
+			watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+		}
+		watermark.SetOnIdle(config.OnIdleSource)
 	}
 
 	return &TumblingWindow{
@@ -150,7 +171,9 @@ func NewTumblingWindow(config types.WindowConfig) (*TumblingWindow, error) {
 		initChan:         make(chan struct{}),
 		initialized:      false,
 		watermark:        watermark,
+		sharedClock:      sharedClock,
 		triggeredWindows: make(map[string]*triggeredWindowInfo),
+		keyClocks:        make(map[string]*keyClock),
 	}, nil
 }
 
@@ -172,6 +195,7 @@ func (tw *TumblingWindow) Add(data any) {
 
 	if timeChar == types.EventTime {
 		if !tsOk {
+			reportUnplaceable(tw.config, data)
 			return // unplaceable event: drop instead of fake wall-clock time
 		}
 		if tw.watermark != nil {
@@ -181,6 +205,11 @@ func (tw *TumblingWindow) Add(data any) {
 		eventTime = time.Now()
 	}
 
+	if tw.perKeyClock() {
+		tw.addKeyedLocked(data, eventTime)
+		return
+	}
+
 	// Append data to window's data list first (needed for late data handling)
 	if !tw.initialized {
 		if timeChar == types.EventTime {
@@ -313,6 +342,7 @@ func (tw *TumblingWindow) Stop() {
 	// Ensure initChan is closed if it hasn't been closed yet
 	// This prevents Start() goroutine from blocking on initChan
 	tw.mu.Lock()
+	tw.stopKeyClocksLocked()
 	if !tw.initialized && tw.initChan != nil {
 		select {
 		case <-tw.initChan:
@@ -335,6 +365,9 @@ func (tw *TumblingWindow) Start() {
 
 	if timeChar == types.EventTime {
 		// Event time: trigger based on watermark
+		if tw.sharedClock {
+			return // driven through advanceSharedClock
+		}
 		tw.startEventTime()
 	} else {
 		// Processing time: trigger based on system clock
@@ -375,6 +408,15 @@ func (tw *TumblingWindow) startProcessingTime() {
 			select {
 			// Trigger window when timer expires
 			case <-timer.C:
+				if tw.pause.paused() {
+					continue // stale tick from before Pause
+				}
+				tw.timerMu.Lock()
+				if tw.realignTimer {
+					timer.Reset(tw.size)
+					tw.realignTimer = false
+				}
+				tw.timerMu.Unlock()
 				tw.Trigger()
 			// Stop timer and exit loop when context is cancelled
 			case <-tw.ctx.Done():
@@ -625,7 +667,7 @@ func (tw *TumblingWindow) extractLateUpdateDataLocked(slot *types.TimeSlot) []ty
 	}
 
 	// Collect all data for this window: original snapshot + late data from tw.data
-	resultData := make([]types.Row, 0)
+	resultData := newBatch(0)
 
 	// First, add original snapshot data (if exists)
 	if windowInfo != nil && len(windowInfo.snapshotData) > 0 {
@@ -683,7 +725,7 @@ func (tw *TumblingWindow) extractWindowDataLocked() []types.Row {
 	}
 
 	// Extract current window data
-	resultData := make([]types.Row, 0)
+	resultData := newBatch(0)
 	for _, item := range tw.data {
 		if tw.currentSlot.Contains(item.Timestamp) {
 			item.Slot = tw.currentSlot
@@ -710,6 +752,7 @@ func (tw *TumblingWindow) extractWindowDataLocked() []types.Row {
 }
 
 func (tw *TumblingWindow) sendResult(data []types.Row) {
+	stampWatermark(data, tw.watermark)
 	strategy := tw.config.PerformanceConfig.OverflowConfig.Strategy
 	timeout := tw.config.PerformanceConfig.OverflowConfig.BlockTimeout
 
@@ -760,6 +803,12 @@ func (tw *TumblingWindow) Trigger() {
 		timeChar = types.ProcessingTime
 	}
 
+	if tw.perKeyClock() {
+		// Per-key clocks: a manual trigger fires every key's open window
+		tw.flushKeyed()
+		return
+	}
+
 	tw.mu.Lock()
 
 	if !tw.initialized {
@@ -788,7 +837,7 @@ func (tw *TumblingWindow) Trigger() {
 	}
 
 	// Extract current window data
-	resultData := make([]types.Row, 0)
+	resultData := newBatch(0)
 	for _, item := range tw.data {
 		if tw.currentSlot.Contains(item.Timestamp) {
 			item.Slot = tw.currentSlot
@@ -824,6 +873,113 @@ func (tw *TumblingWindow) Trigger() {
 	tw.sendResult(resultData)
 }
 
+// Pause implements Pauser: it stops the processing-time ticker, every per-key
+// clock and idle-source detection.
+func (tw *TumblingWindow) Pause() {
+	if !tw.pause.pause() {
+		return
+	}
+	if tw.watermark != nil {
+		tw.watermark.pause()
+	}
+	tw.timerMu.Lock()
+	if tw.timer != nil {
+		tw.timer.Stop()
+	}
+	tw.timerMu.Unlock()
+	tw.mu.Lock()
+	for _, kc := range tw.keyClocks {
+		kc.timer.Stop()
+	}
+	tw.mu.Unlock()
+}
+
+// Resume implements Pauser: open processing-time windows and their rows move
+// later by the paused duration and fire once their remaining time elapses.
+func (tw *TumblingWindow) Resume() {
+	d, ok := tw.pause.resume()
+	if !ok {
+		return
+	}
+	if tw.watermark != nil {
+		tw.watermark.resume(d)
+	}
+	tw.mu.Lock()
+	now := time.Now()
+	var remaining time.Duration
+	if tw.watermark == nil && tw.currentSlot != nil {
+		tw.currentSlot = shiftSlot(tw.currentSlot, d)
+		shiftRows(tw.data, d)
+		remaining = tw.currentSlot.End.Sub(now)
+	}
+	for key, kc := range tw.keyClocks {
+		key, kc := key, kc
+		kc.slot = shiftSlot(kc.slot, d)
+		shiftRows(kc.rows, d)
+		kc.timer = time.AfterFunc(kc.slot.End.Sub(now), func() { tw.fireKey(key, kc) })
+	}
+	tw.mu.Unlock()
+
+	tw.timerMu.Lock()
+	if tw.timer != nil {
+		if remaining <= 0 {
+			remaining = time.Millisecond
+		}
+		tw.timer.Reset(remaining)
+		tw.realignTimer = remaining != tw.size
+	}
+	tw.timerMu.Unlock()
+}
+
+// Flush fires all buffered windows in slot order, ignoring the timer/watermark.
+// Rows of already-triggered windows kept only for late updates are discarded.
+func (tw *TumblingWindow) Flush() {
+	if tw.perKeyClock() {
+		tw.flushKeyed()
+		return
+	}
+	tw.mu.Lock()
+	if !tw.initialized || tw.currentSlot == nil || len(tw.data) == 0 {
+		tw.mu.Unlock()
+		return
+	}
+
+	var results [][]types.Row
+	for len(tw.data) > 0 {
+		// Skip empty slots: jump straight to the slot of the earliest pending row
+		var earliest time.Time
+		found := false
+		for _, item := range tw.data {
+			if item.Timestamp.Before(*tw.currentSlot.Start) {
+				continue
+			}
+			if !found || item.Timestamp.Before(earliest) {
+				earliest, found = item.Timestamp, true
+			}
+		}
+		if !found {
+			break
+		}
+		if !tw.currentSlot.Contains(earliest) {
+			tw.currentSlot = tw.createSlotFromStart(alignWindowStart(earliest, tw.size))
+		}
+		if resultData := tw.extractWindowDataLocked(); len(resultData) > 0 {
+			results = append(results, resultData)
+		}
+		tw.currentSlot = tw.NextSlot()
+	}
+	tw.data = tw.data[:0]
+	callback := tw.callback
+	tw.mu.Unlock()
+
+	for _, resultData := range results {
+		if callback != nil {
+			callback(resultData)
+		}
+		tw.sendResult(resultData)
+	}
+}
+
 // Reset resets tumbling window data
 func (tw *TumblingWindow) Reset() {
 	// First cancel context to stop all running goroutines
@@ -861,7 +1017,12 @@ func (tw *TumblingWindow) Reset() {
 				watermarkInterval = 200 * time.Millisecond
 			}
 			idleTimeout := tw.config.IdleTimeout
-			tw.watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+			if tw.sharedClock {
+				tw.watermark = newDrivenWatermark(maxOutOfOrderness, idleTimeout)
+			} else {
+				tw.watermark = NewWatermark(maxOutOfOrderness, watermarkInterval, idleTimeout)
+			}
+			tw.watermark.SetOnIdle(tw.config.OnIdleSource)
 		}
 	}
 
@@ -871,6 +1032,7 @@ func (tw *TumblingWindow) Reset() {
 	tw.initialized = false
 	tw.initChan = make(chan struct{})
 	tw.triggeredWindows = make(map[string]*triggeredWindowInfo)
+	tw.stopKeyClocksLocked()
 
 	// Recreate context for next startup
 	tw.ctx, tw.cancelFunc = context.WithCancel(context.Background())
@@ -885,7 +1047,7 @@ func (tw *TumblingWindow) OutputChan() <-chan []types.Row {
 func (tw *TumblingWindow) SetCallback(callback func([]types.Row)) {
 	tw.mu.Lock()
 	defer tw.mu.Unlock()
-	tw.callback = callback
+	tw.callback = ownedCallback(callback, tw.config.PerformanceConfig)
 }
 
 // GetStats returns window performance statistics
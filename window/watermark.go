@@ -20,6 +20,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/rulego/streamsql/types"
 )
 
 // maxFutureSlack bounds how far an event timestamp may run ahead of processing
@@ -50,6 +52,13 @@ type Watermark struct {
 	idleTimeout time.Duration
 	// lastEventTime is the time when the last event was received
 	lastEventTime time.Time
+	// idle is set while the source is idle (watermark driven by processing time)
+	// and cleared by the next event, so onIdle fires once per idle period
+	idle bool
+	// onIdle is called with maxEventTime when the source goes idle (may be nil)
+	onIdle func(lastEventTime time.Time)
+	// paused suspends idle detection while the stream is paused (see Pauser)
+	paused bool
 	// mu protects concurrent access
 	mu sync.RWMutex
 	// watermarkChan is a channel for watermark updates
@@ -62,9 +71,20 @@ type Watermark struct {
 
 // NewWatermark creates a new watermark manager
 func NewWatermark(maxOutOfOrderness time.Duration, updateInterval time.Duration, idleTimeout time.Duration) *Watermark {
+	wm := newDrivenWatermark(maxOutOfOrderness, idleTimeout)
+
+	// Start periodic watermark updates
+	go wm.updateLoop(updateInterval)
+
+	return wm
+}
+
+// newDrivenWatermark creates a watermark without its own update goroutine; the
+// owner calls update periodically instead (see PerKeyWatermarkWindow).
+func newDrivenWatermark(maxOutOfOrderness time.Duration, idleTimeout time.Duration) *Watermark {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	wm := &Watermark{
+	return &Watermark{
 		currentWatermark:  time.Time{},
 		maxEventTime:      time.Time{},
 		maxOutOfOrderness: maxOutOfOrderness,
@@ -74,11 +94,6 @@ func NewWatermark(maxOutOfOrderness time.Duration, updateInterval time.Duration,
 		ctx:               ctx,
 		cancelFunc:        cancel,
 	}
-
-	// Start periodic watermark updates
-	go wm.updateLoop(updateInterval)
-
-	return wm
 }
 
 // updateLoop periodically updates watermark based on max event time
@@ -96,24 +111,49 @@ func (wm *Watermark) updateLoop(interval time.Duration) {
 	}
 }
 
+// SetOnIdle registers fn to be called when the source goes idle, i.e. when
+// idleTimeout passes without events and the watermark starts advancing by
+// processing time. fn receives the newest event time seen before the idle
+// period and runs once per idle period on the watermark goroutine, outside the
+// watermark lock; it should return quickly. nil removes the callback.
+func (wm *Watermark) SetOnIdle(fn func(lastEventTime time.Time)) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.onIdle = fn
+}
+
 // update updates watermark based on current max event time
 // If idle timeout is configured and data source is idle, watermark advances based on processing time
 func (wm *Watermark) update() {
+	if fn, last := wm.advance(); fn != nil {
+		fn(last)
+	}
+}
+
+// advance performs one watermark update under the lock. When the source has
+// just gone idle it returns the idle callback and the event time to pass it,
+// so update can invoke it without holding the lock.
+func (wm *Watermark) advance() (func(time.Time), time.Time) {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
+	var fire func(time.Time)
 	if !wm.maxEventTime.IsZero() {
 		now := time.Now()
 		var newWatermark time.Time
 
 		// Check if data source is idle
-		if wm.idleTimeout > 0 && !wm.lastEventTime.IsZero() {
+		if wm.idleTimeout > 0 && !wm.lastEventTime.IsZero() && !wm.paused {
 			timeSinceLastEvent := now.Sub(wm.lastEventTime)
 			if timeSinceLastEvent > wm.idleTimeout {
 				// Data source is idle, advance watermark based on processing time
 				// Watermark = current processing time - max out of orderness
 				// This ensures windows can close even when no new data arrives
 				newWatermark = now.Add(-wm.maxOutOfOrderness)
+				if !wm.idle {
+					wm.idle = true
+					fire = wm.onIdle
+				}
 			} else {
 				// Normal update: based on max event time
 				newWatermark = wm.maxEventTime.Add(-wm.maxOutOfOrderness)
@@ -131,6 +171,7 @@ func (wm *Watermark) update() {
 		// never fire.
 		wm.sendWatermarkLocked()
 	}
+	return fire, wm.maxEventTime
 }
 
 // sendWatermarkLocked delivers currentWatermark if it is higher than the last
@@ -146,6 +187,25 @@ func (wm *Watermark) sendWatermarkLocked() {
 	}
 }
 
+// pause suspends idle detection: a paused stream receives no events, which
+// must not count as an idle source.
+func (wm *Watermark) pause() {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.paused = true
+}
+
+// resume re-enables idle detection, excluding the paused duration d from the
+// time since the last event.
+func (wm *Watermark) resume(d time.Duration) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.paused = false
+	if !wm.lastEventTime.IsZero() {
+		wm.lastEventTime = wm.lastEventTime.Add(d)
+	}
+}
+
 // UpdateEventTime updates the maximum event time seen
 func (wm *Watermark) UpdateEventTime(eventTime time.Time) {
 	wm.mu.Lock()
@@ -153,6 +213,7 @@ func (wm *Watermark) UpdateEventTime(eventTime time.Time) {
 
 	// Update last event time for idle detection
 	wm.lastEventTime = time.Now()
+	wm.idle = false
 
 	// Guard against far-future timestamps (corrupt data, e.g. year-2099
 	// garbage): ignore them for watermark bookkeeping entirely. The earlier
@@ -178,6 +239,24 @@ func (wm *Watermark) UpdateEventTime(eventTime time.Time) {
 	wm.sendWatermarkLocked()
 }
 
+// pending reports whether a watermark update is waiting in the channel.
+func (wm *Watermark) pending() bool {
+	return len(wm.watermarkChan) > 0
+}
+
+// latest drains the watermark updates delivered so far and returns the newest;
+// ok is false when none was waiting.
+func (wm *Watermark) latest() (t time.Time, ok bool) {
+	for {
+		select {
+		case t = <-wm.watermarkChan:
+			ok = true
+		default:
+			return t, ok
+		}
+	}
+}
+
 // GetCurrentWatermark returns the current watermark time
 func (wm *Watermark) GetCurrentWatermark() time.Time {
 	wm.mu.RLock()
@@ -202,6 +281,23 @@ func (wm *Watermark) IsEventTimeLate(eventTime time.Time) bool {
 	return !wm.currentWatermark.IsZero() && eventTime.Before(wm.currentWatermark)
 }
 
+// stampWatermark records on each emitted row the watermark its window fired
+// at (read by window_watermark()): the current watermark of an event-time
+// window, or the wall clock when there is none (processing time, or an
+// event-time window flushed before its watermark advanced).
+func stampWatermark(rows []types.Row, wm *Watermark) {
+	var at time.Time
+	if wm != nil {
+		at = wm.GetCurrentWatermark()
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for i := range rows {
+		rows[i].Watermark = at
+	}
+}
+
 // alignWindowStart aligns window start time to window boundaries
 // For event time windows, windows are aligned to epoch (00:00:00 UTC)
 //
@@ -119,6 +119,38 @@ func TestWatermarkIdleTimeout(t *testing.T) {
 	}, 500*time.Millisecond, 10*time.Millisecond)
 }
 
+func TestWatermarkOnIdle(t *testing.T) {
+	wm := NewWatermark(time.Second, 10*time.Millisecond, 60*time.Millisecond)
+	defer wm.Stop()
+	idle := make(chan time.Time, 4)
+	wm.SetOnIdle(func(last time.Time) { idle <- last })
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	wm.UpdateEventTime(base.Add(2 * time.Second))
+	wm.UpdateEventTime(base) // out-of-order: the newest event time is reported
+	select {
+	case last := <-idle:
+		assert.Equal(t, base.Add(2*time.Second), last)
+	case <-time.After(time.Second):
+		t.Fatal("idle callback not invoked")
+	}
+	// once per idle period
+	select {
+	case <-idle:
+		t.Fatal("idle callback fired twice for one idle period")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// a new event re-arms the callback
+	wm.UpdateEventTime(base.Add(5 * time.Second))
+	select {
+	case last := <-idle:
+		assert.Equal(t, base.Add(5*time.Second), last)
+	case <-time.After(time.Second):
+		t.Fatal("idle callback not re-armed by new event")
+	}
+}
+
 func TestWatermarkUpdateLoopNoEvents(t *testing.T) {
 	// with no events at all, update() must not move the watermark
 	wm := NewWatermark(time.Second, 20*time.Millisecond, 0)
@@ -228,3 +228,66 @@ func TestSessionTriggerEmpty(t *testing.T) {
 	defer sw.Stop()
 	assert.NotPanics(t, func() { sw.Trigger() })
 }
+
+// ---- Flusher: end-of-input flush of open windows ----
+
+func TestTumblingFlushEmitsOpenSlotsInOrder(t *testing.T) {
+	tw, err := NewTumblingWindow(types.WindowConfig{
+		Params:             []any{"10s"},
+		TsProp:             "ts",
+		TimeCharacteristic: types.EventTime,
+	})
+	require.NoError(t, err)
+	defer tw.Stop()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, off := range []time.Duration{0, 3 * time.Second, 12 * time.Second, 35 * time.Second} {
+		tw.Add(map[string]any{"ts": base.Add(off)})
+	}
+
+	tw.Flush()
+	var sizes []int
+	for len(tw.OutputChan()) > 0 {
+		batch := <-tw.OutputChan()
+		require.NotNil(t, batch[0].Slot)
+		sizes = append(sizes, len(batch))
+	}
+	assert.Equal(t, []int{2, 1, 1}, sizes, "每个有数据的 slot 各触发一次，空 slot 跳过")
+
+	tw.Flush()
+	assert.Equal(t, 0, len(tw.OutputChan()), "重复 Flush 无残留数据")
+}
+
+func TestCountingFlushEmitsPartialGroups(t *testing.T) {
+	cw, err := NewCountingWindow(types.WindowConfig{Params: []any{5}, GroupByKeys: []string{"k"}})
+	require.NoError(t, err)
+	cw.Start()
+	defer cw.Stop()
+	for _, k := range []string{"a", "b", "a"} {
+		cw.Add(map[string]any{"k": k})
+	}
+
+	cw.Flush()
+	require.Equal(t, 2, len(cw.OutputChan()))
+	assert.Len(t, <-cw.OutputChan(), 2, "key a")
+	assert.Len(t, <-cw.OutputChan(), 1, "key b")
+}
+
+func TestSlidingFlushEmitsRemainingWindows(t *testing.T) {
+	sw, err := NewSlidingWindow(types.WindowConfig{
+		Params:             []any{"10s", "5s"},
+		TsProp:             "ts",
+		TimeCharacteristic: types.EventTime,
+	})
+	require.NoError(t, err)
+	defer sw.Stop()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sw.Add(map[string]any{"ts": base.Add(1 * time.Second)})
+	sw.Add(map[string]any{"ts": base.Add(7 * time.Second)})
+
+	sw.Flush()
+	var sizes []int
+	for len(sw.OutputChan()) > 0 {
+		sizes = append(sizes, len(<-sw.OutputChan()))
+	}
+	assert.Equal(t, []int{2, 1}, sizes, "[0,10) 含两行，[5,15) 含一行")
+}